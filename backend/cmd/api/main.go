@@ -12,19 +12,57 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 
 	// Import the docs package to register Swagger docs
 	_ "github.com/nanayaw/fullstack/docs"
 
 	"github.com/nanayaw/fullstack/internal/config"
+	adminHandler "github.com/nanayaw/fullstack/internal/handler/admin"
+	apiKeyHandler "github.com/nanayaw/fullstack/internal/handler/apikey"
 	authHandler "github.com/nanayaw/fullstack/internal/handler/auth"
+	dataExportHandler "github.com/nanayaw/fullstack/internal/handler/dataexport"
+	devHandler "github.com/nanayaw/fullstack/internal/handler/dev"
+	appMiddleware "github.com/nanayaw/fullstack/internal/handler/middleware"
+	notificationHandler "github.com/nanayaw/fullstack/internal/handler/notification"
+	organizationHandler "github.com/nanayaw/fullstack/internal/handler/organization"
+	serviceAccountHandler "github.com/nanayaw/fullstack/internal/handler/serviceaccount"
 	userHandler "github.com/nanayaw/fullstack/internal/handler/user"
+	webhookHandler "github.com/nanayaw/fullstack/internal/handler/webhook"
+	"github.com/nanayaw/fullstack/internal/model"
+	apiKeyRepository "github.com/nanayaw/fullstack/internal/repository/apikey"
+	auditRepository "github.com/nanayaw/fullstack/internal/repository/audit"
+	dataExportRepository "github.com/nanayaw/fullstack/internal/repository/dataexport"
+	emailRepository "github.com/nanayaw/fullstack/internal/repository/email"
+	notificationRepository "github.com/nanayaw/fullstack/internal/repository/notification"
+	organizationRepository "github.com/nanayaw/fullstack/internal/repository/organization"
+	rbacRepository "github.com/nanayaw/fullstack/internal/repository/rbac"
+	securityRepository "github.com/nanayaw/fullstack/internal/repository/security"
+	serviceAccountRepository "github.com/nanayaw/fullstack/internal/repository/serviceaccount"
+	userRepository "github.com/nanayaw/fullstack/internal/repository/user"
+	userAdminRepository "github.com/nanayaw/fullstack/internal/repository/useradmin"
+	webhooksRepository "github.com/nanayaw/fullstack/internal/repository/webhooks"
 	"github.com/nanayaw/fullstack/internal/router"
+	"github.com/nanayaw/fullstack/internal/service/apikey"
+	"github.com/nanayaw/fullstack/internal/service/audit"
 	"github.com/nanayaw/fullstack/internal/service/auth"
 	"github.com/nanayaw/fullstack/internal/service/cache"
+	"github.com/nanayaw/fullstack/internal/service/dataexport"
 	"github.com/nanayaw/fullstack/internal/service/email"
+	"github.com/nanayaw/fullstack/internal/service/eventstream"
+	"github.com/nanayaw/fullstack/internal/service/jobs"
+	"github.com/nanayaw/fullstack/internal/service/notification"
+	"github.com/nanayaw/fullstack/internal/service/organization"
+	"github.com/nanayaw/fullstack/internal/service/rbac"
+	"github.com/nanayaw/fullstack/internal/service/security"
+	"github.com/nanayaw/fullstack/internal/service/serviceaccount"
 	"github.com/nanayaw/fullstack/internal/service/user"
+	"github.com/nanayaw/fullstack/internal/service/useradmin"
+	"github.com/nanayaw/fullstack/internal/service/webhooks"
+	"github.com/nanayaw/fullstack/pkg/database"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/nanayaw/fullstack/pkg/webhook"
 )
 
 // @title           Fullstack API
@@ -61,31 +99,189 @@ func main() {
 	// Initialize Echo
 	e := echo.New()
 
+	// Initialize database
+	db, err := database.NewTursoConnection(cfg.Database.URL, cfg.Database.AuthToken)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
 	// Initialize services
-	cacheService, err := cache.NewRedisService(&cfg.Redis)
+	cacheService, err := cache.NewService(&cfg.Redis)
 	if err != nil {
 		log.Fatalf("Failed to initialize cache service: %v", err)
 	}
 
-	emailService, err := email.NewEmailService(&cfg.Email)
+	// Emails are queued in the outbox and delivered in the background
+	sqlxDB := sqlx.NewDb(db.DB, "libsql")
+	outboxRepo := emailRepository.NewRepository(sqlxDB)
+	emailProvider, err := email.NewProvider(&cfg.Email, &cfg.App)
+	if err != nil {
+		log.Fatalf("Failed to initialize email provider: %v", err)
+	}
+	emailSuppressions := email.NewSuppressionList(outboxRepo, logger.DefaultLogger())
+
+	// Scheduled emails are dispatched by Upstash Workflow when it is configured,
+	// and polled in-process otherwise
+	var emailScheduler email.Scheduler
+	if upstashScheduler := email.NewUpstashScheduler(&cfg.Email); upstashScheduler != nil {
+		emailScheduler = upstashScheduler
+	}
+	scheduledEmails := email.NewScheduledEmails(outboxRepo, emailScheduler, logger.DefaultLogger())
+	scheduledEmailWorker := email.NewScheduledEmailWorker(scheduledEmails, &cfg.Email, logger.DefaultLogger())
+
+	// Optional emails are only sent to users who didn't opt out of them
+	notificationRepo := notificationRepository.NewRepository(sqlxDB)
+	notificationService := notification.NewService(notificationRepo, scheduledEmails, cfg, logger.DefaultLogger())
+
+	emailService, emailOutbox, outboxWorker, err := email.NewQueuedEmailService(&cfg.Email, &cfg.App, emailProvider, outboxRepo, emailSuppressions, notificationService, scheduledEmails, logger.DefaultLogger())
 	if err != nil {
 		log.Fatalf("Failed to initialize email service: %v", err)
 	}
 
-	// Initialize user service
-	userService := user.NewService(nil) // Replace with actual repository
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		outboxWorker.Run(workerCtx)
+	}()
+	scheduledWorkerDone := make(chan struct{})
+	go func() {
+		defer close(scheduledWorkerDone)
+		scheduledEmailWorker.Run(workerCtx)
+	}()
+
+	// Audit log entries and security events are streamed to the configured
+	// sinks, such as a SIEM, as they are recorded
+	eventSinks, err := eventstream.NewSinks(&cfg.EventStream)
+	if err != nil {
+		log.Fatalf("Failed to initialize event stream: %v", err)
+	}
+	eventStream := eventstream.NewStream(eventSinks, &cfg.EventStream, logger.DefaultLogger())
+	eventStreamDone := make(chan struct{})
+	go func() {
+		defer close(eventStreamDone)
+		eventStream.Run(workerCtx)
+	}()
+
+	// Every change is recorded in the audit log, whose entries are chained
+	// by their hashes so tampering with it can be detected
+	auditRepo := auditRepository.NewRepository(sqlxDB)
+	auditService := audit.NewService(eventstream.NewAuditRepository(auditRepo, eventStream), logger.DefaultLogger())
+
+	// Other services are notified of identity lifecycle events through the
+	// webhooks they register, delivered from a queue by a background worker
+	webhooksRepo := webhooksRepository.NewRepository(sqlxDB)
+	webhookService := webhooks.NewService(webhooksRepo, auditService, logger.DefaultLogger())
+	webhookWorker := webhooks.NewWorker(webhooksRepo, &cfg.Webhooks, logger.DefaultLogger())
+	webhookWorkerDone := make(chan struct{})
+	go func() {
+		defer close(webhookWorkerDone)
+		webhookWorker.Run(workerCtx)
+	}()
+
+	// Initialize user service, whose activity is read from the audit log
+	userService := user.NewService(userRepository.NewRepository(sqlxDB), auditService)
+	userService.SetWebhooks(webhookService)
+
+	// Initialize security service
+	securityRepo := eventstream.NewSecurityRepository(securityRepository.NewRepository(sqlxDB), eventStream)
+	securityService := security.NewService(securityRepo, emailService, cfg, logger.DefaultLogger())
+	securityService.SetWebhooks(webhookService)
+	securityService.SetUsers(userAdminRepository.NewRepository(sqlxDB))
+
+	// Users are authorized by the roles assigned to them
+	rbacService := rbac.NewService(rbacRepository.NewRepository(sqlxDB), cacheService, auditService, logger.DefaultLogger())
 
 	authService, err := auth.NewPasetoService(&cfg.Auth, nil, emailService, cacheService)
 	if err != nil {
 		log.Fatalf("Failed to initialize auth service: %v", err)
 	}
+	authService.SetAuthorizer(rbacService)
+	authService.SetWebhooks(webhookService)
+
+	// Users act in their personal account or in an organization they are a
+	// member of, which is kept in their tokens
+	orgService := organization.NewService(organizationRepository.NewRepository(sqlxDB), userAdminRepository.NewRepository(sqlxDB), emailService, &cfg.Auth, auditService, logger.DefaultLogger())
+	authService.SetMemberships(orgService)
+
+	// Scripts and CI authenticate with API keys in place of access tokens
+	apiKeyService := apikey.NewService(apiKeyRepository.NewRepository(sqlxDB), rbacService, auditService, logger.DefaultLogger())
+	authService.SetAPIKeys(apiKeyService)
+
+	// Admins manage accounts through the security and auth services, so every
+	// action is recorded in the user's security events
+	userAdminService := useradmin.NewService(userAdminRepository.NewRepository(sqlxDB), securityService, authService, auditService, logger.DefaultLogger())
+
+	// Backend jobs authenticate as service accounts with client credentials
+	serviceAccountService := serviceaccount.NewService(serviceAccountRepository.NewRepository(sqlxDB), authService, rbacService, auditService, logger.DefaultLogger())
+
+	// Users can export a copy of their data, built by a job and downloaded
+	// through a signed link emailed to them
+	dataExportService := dataexport.NewService(dataExportRepository.NewRepository(sqlxDB), userAdminRepository.NewRepository(sqlxDB), auditRepo, securityRepository.NewRepository(sqlxDB), emailService, &cfg.DataExport, auditService, logger.DefaultLogger())
+
+	// Render scheduled emails from the user's state when they are due
+	scheduledEmails.RegisterHandler(model.ScheduledEmailOnboardingReminder, func(ctx context.Context, scheduled *model.ScheduledEmail) error {
+		return authService.SendOnboardingReminder(ctx, scheduled.UserID)
+	})
+	scheduledEmails.RegisterHandler(model.ScheduledEmailSecurityDigest, func(ctx context.Context, scheduled *model.ScheduledEmail) error {
+		return securityService.SendSecurityDigest(ctx, scheduled.UserID, scheduled.Recipient)
+	})
+
+	// Periodic jobs run on one instance at a time, locked in the cache
+	jobRunner := jobs.NewRunner(cacheService, logger.DefaultLogger())
+	if err := jobRunner.Register("delete_expired_account_locks", time.Hour, securityService.DeleteExpiredAccountLocks); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobRunner.Register("delete_expired_invitations", time.Hour, orgService.DeleteExpiredInvitations); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobRunner.Register("process_data_exports", cfg.DataExport.PollInterval, dataExportService.ProcessPendingExports); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobRunner.Register("delete_expired_data_exports", time.Hour, dataExportService.DeleteExpiredExports); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		jobRunner.Run(workerCtx)
+	}()
 
 	// Initialize handlers
-	authHandler := authHandler.NewHandler(authService)
-	userHandler := userHandler.NewHandler(userService, authService)
+	authHandler := authHandler.NewHandler(authService, securityService)
+	userHandler := userHandler.NewHandler(userService, authService, securityService, emailSuppressions)
+	adminHandler := adminHandler.NewHandler(emailOutbox, emailSuppressions, rbacService, userAdminService, auditService, eventStream, webhookService)
+	apiKeyHandler := apiKeyHandler.NewHandler(apiKeyService)
+	notificationHandler := notificationHandler.NewHandler(notificationService)
+	organizationHandler := organizationHandler.NewHandler(orgService, authService)
+	serviceAccountHandler := serviceAccountHandler.NewHandler(serviceAccountService)
+	dataExportHandler := dataExportHandler.NewHandler(dataExportService)
+
+	// Delivery events are only accepted when a signing secret is configured
+	var emailWebhookVerifier *webhook.Verifier
+	if cfg.Email.WebhookSecret != "" {
+		emailWebhookVerifier, err = webhook.NewVerifier(cfg.Email.WebhookSecret)
+		if err != nil {
+			log.Fatalf("Failed to initialize email webhook: %v", err)
+		}
+	}
+	webhookHandler := webhookHandler.NewHandler(emailSuppressions, emailWebhookVerifier, scheduledEmails, cfg.Email.ScheduleCallbackToken)
+
+	// Captured emails can be read back through the dev mailbox in development
+	var mailboxHandler *devHandler.Handler
+	if capture, ok := emailProvider.(*email.CaptureService); ok && cfg.Environment == "development" {
+		mailboxHandler = devHandler.NewHandler(capture)
+	}
+
+	// Rate limit counters are kept in the cache, shared by every instance
+	rateLimiter, err := appMiddleware.NewRateLimiter(cacheService, &cfg.Security, logger.DefaultLogger())
+	if err != nil {
+		log.Fatalf("Failed to initialize rate limiter: %v", err)
+	}
 
 	// Initialize router
-	r := router.NewRouter(e, authHandler, userHandler, authService)
+	r := router.NewRouter(e, authHandler, userHandler, adminHandler, apiKeyHandler, notificationHandler, organizationHandler, serviceAccountHandler, dataExportHandler, webhookHandler, mailboxHandler, authService, rateLimiter, rbacService, orgService, auditService, cfg.Admin.APIKey)
 	r.SetupRoutes()
 	r.SetupTimeoutMiddleware(int(cfg.Server.ReadTimeout.Seconds()))
 
@@ -109,4 +305,33 @@ func main() {
 	if err := e.Shutdown(ctx); err != nil {
 		log.Fatal(err)
 	}
+
+	// Let the email and webhook workers finish the batch they are sending,
+	// the jobs they run and the event stream deliver the events it buffered
+	stopWorker()
+	select {
+	case <-workerDone:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the email outbox worker to stop")
+	}
+	select {
+	case <-scheduledWorkerDone:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the scheduled email worker to stop")
+	}
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the running jobs to stop")
+	}
+	select {
+	case <-webhookWorkerDone:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the webhook worker to stop")
+	}
+	select {
+	case <-eventStreamDone:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the event stream to stop")
+	}
 }
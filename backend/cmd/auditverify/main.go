@@ -0,0 +1,58 @@
+// Command auditverify checks the hash chain of the audit log, reporting the
+// first entry that was changed or removed since it was recorded.
+//
+// Usage:
+//
+//	go run ./cmd/auditverify
+//
+// It reads the database settings like the API server does. The command exits
+// with a non-zero status if the chain is broken, so it can run on a schedule.
+// It prints the sequence number and hash of the last entry: keeping them
+// somewhere else lets you tell whether entries were later removed from the
+// end of the log, which the chain alone can't show.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/config"
+	auditRepository "github.com/nanayaw/fullstack/internal/repository/audit"
+	"github.com/nanayaw/fullstack/internal/service/audit"
+	"github.com/nanayaw/fullstack/pkg/database"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+func main() {
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(2)
+	}
+
+	db, err := database.NewTursoConnection(cfg.Database.URL, cfg.Database.AuthToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect to database: %v\n", err)
+		os.Exit(2)
+	}
+	defer db.Close()
+
+	svc := audit.NewService(auditRepository.NewRepository(sqlx.NewDb(db.DB, "libsql")), logger.DefaultLogger())
+
+	result, err := svc.Verify(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to verify audit log: %v\n", err)
+		os.Exit(2)
+	}
+
+	if !result.Valid() {
+		fmt.Printf("audit log tampered with at entry %d (%s): %s\n", result.Failure.Sequence, result.Failure.EntryID, result.Failure.Reason)
+		fmt.Printf("%d entries verified before it\n", result.Checked)
+		os.Exit(1)
+	}
+
+	fmt.Printf("audit log intact: %d entries verified\n", result.Checked)
+	fmt.Printf("head: %d %s\n", result.HeadSequence, result.HeadHash)
+}
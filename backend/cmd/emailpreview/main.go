@@ -0,0 +1,259 @@
+// Command emailpreview renders every email template with fixture data so
+// template changes can be reviewed without sending real email.
+//
+// Usage:
+//
+//	go run ./cmd/emailpreview -out build/emails       # write files
+//	go run ./cmd/emailpreview -serve localhost:8025   # preview server
+//	go run ./cmd/emailpreview -send me@example.com    # send through the configured provider
+//
+// Files are written as <out>/<locale>/<name>.html, .txt and .subject, with an
+// index.html linking to all of them. The preview server renders on every
+// request, so edits to -templates overrides show up on reload.
+//
+// -template and -locale limit the output to one template or locale, and
+// -templates previews the overrides in a directory (see EMAIL_TEMPLATES_DIR).
+// -send loads the application configuration like the API server does and
+// delivers each email through the configured provider, with "[Preview]"
+// prepended to the subject.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/service/email"
+	"github.com/nanayaw/fullstack/pkg/email/templates"
+)
+
+// preview is one rendered email
+type preview struct {
+	Locale string
+	Name   string
+	Email  templates.EmailTemplate
+}
+
+func main() {
+	out := flag.String("out", "", "directory to write the rendered emails to")
+	serve := flag.String("serve", "", "address to serve previews on, e.g. localhost:8025")
+	send := flag.String("send", "", "address to send the rendered emails to")
+	overrideDir := flag.String("templates", "", "directory of template overrides to preview")
+	only := flag.String("template", "", "only render this template")
+	locale := flag.String("locale", "", "only render this locale")
+	flag.Parse()
+
+	if *out == "" && *serve == "" && *send == "" {
+		fmt.Fprintln(os.Stderr, "one of -out, -serve or -send is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	render := func() ([]preview, error) {
+		return renderAll(*overrideDir, *only, *locale)
+	}
+
+	// Fail early on template errors
+	previews, err := render()
+	if err != nil {
+		log.Fatalf("Failed to render emails: %v", err)
+	}
+
+	if *out != "" {
+		if err := writeFiles(*out, previews); err != nil {
+			log.Fatalf("Failed to write emails: %v", err)
+		}
+		log.Printf("Wrote %d emails to %s", len(previews), *out)
+	}
+
+	if *send != "" {
+		if err := sendAll(*send, previews); err != nil {
+			log.Fatalf("Failed to send emails: %v", err)
+		}
+		log.Printf("Sent %d emails to %s", len(previews), *send)
+	}
+
+	if *serve != "" {
+		log.Printf("Serving email previews on http://%s", *serve)
+		server := &http.Server{
+			Addr:              *serve,
+			Handler:           previewHandler(render),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		log.Fatal(server.ListenAndServe())
+	}
+}
+
+// renderAll renders the selected templates in the selected locales
+func renderAll(overrideDir, only, locale string) ([]preview, error) {
+	r, err := templates.NewRenderer(overrideDir)
+	if err != nil {
+		return nil, err
+	}
+
+	locales := r.Locales()
+	if locale != "" {
+		locales = []string{locale}
+	}
+	names := r.Names()
+	if only != "" {
+		names = []string{only}
+	}
+
+	var previews []preview
+	for _, l := range locales {
+		fixtures := templates.FixtureData(l)
+		for _, name := range names {
+			data, ok := fixtures[name]
+			if !ok {
+				return nil, fmt.Errorf("no fixture data for template %q", name)
+			}
+
+			rendered, err := r.Render(name, l, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", l, name, err)
+			}
+			previews = append(previews, preview{Locale: l, Name: name, Email: rendered})
+		}
+	}
+
+	return previews, nil
+}
+
+// writeFiles writes every preview and an index to dir
+func writeFiles(dir string, previews []preview) error {
+	for _, p := range previews {
+		localeDir := filepath.Join(dir, p.Locale)
+		if err := os.MkdirAll(localeDir, 0o755); err != nil {
+			return err
+		}
+
+		files := map[string]string{
+			p.Name + ".html":    p.Email.HTML,
+			p.Name + ".txt":     p.Email.Text,
+			p.Name + ".subject": p.Email.Subject,
+		}
+		for file, content := range files {
+			if err := os.WriteFile(filepath.Join(localeDir, file), []byte(content), 0o644); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return indexTemplate.Execute(f, previews)
+}
+
+// previewHandler serves an index of the previews and the rendered emails at
+// /<locale>/<name>.html and /<locale>/<name>.txt
+func previewHandler(render func() ([]preview, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		previews, err := render()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		path := strings.TrimPrefix(req.URL.Path, "/")
+		if path == "" || path == "index.html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := indexTemplate.Execute(w, previews); err != nil {
+				log.Printf("Failed to render index: %v", err)
+			}
+			return
+		}
+
+		for _, p := range previews {
+			switch path {
+			case p.Locale + "/" + p.Name + ".html":
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				fmt.Fprint(w, p.Email.HTML)
+				return
+			case p.Locale + "/" + p.Name + ".txt":
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				fmt.Fprintf(w, "Subject: %s\n\n%s", p.Email.Subject, p.Email.Text)
+				return
+			}
+		}
+
+		http.NotFound(w, req)
+	})
+}
+
+// sendAll delivers every preview to an address through the configured provider
+func sendAll(to string, previews []preview) error {
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	provider, err := email.NewProvider(&cfg.Email, &cfg.App)
+	if err != nil {
+		return err
+	}
+
+	from := cfg.Email.FromEmail
+	if cfg.Email.FromName != "" {
+		from = fmt.Sprintf("%s <%s>", cfg.Email.FromName, cfg.Email.FromEmail)
+	}
+
+	for _, p := range previews {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := provider.Send(ctx, &email.Message{
+			Template: p.Name,
+			From:     from,
+			To:       []string{to},
+			Subject:  fmt.Sprintf("[Preview] %s", p.Email.Subject),
+			HTML:     p.Email.HTML,
+			Text:     p.Email.Text,
+		})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%s/%s: %w", p.Locale, p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// indexTemplate lists the previews
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Email previews</title>
+  <style>
+    body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 2rem; color: #333; }
+    table { border-collapse: collapse; }
+    td, th { padding: 0.4rem 1rem; border-bottom: 1px solid #eee; text-align: left; }
+  </style>
+</head>
+<body>
+  <h1>Email previews</h1>
+  <table>
+    <tr><th>Locale</th><th>Template</th><th>Subject</th><th></th></tr>
+    {{range .}}
+    <tr>
+      <td>{{.Locale}}</td>
+      <td>{{.Name}}</td>
+      <td>{{.Email.Subject}}</td>
+      <td><a href="{{.Locale}}/{{.Name}}.html">HTML</a> · <a href="{{.Locale}}/{{.Name}}.txt">Text</a></td>
+    </tr>
+    {{end}}
+  </table>
+</body>
+</html>
+`))
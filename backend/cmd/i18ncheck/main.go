@@ -0,0 +1,62 @@
+// Command i18ncheck reports translations missing from each locale of the
+// message catalog, compared to the default locale.
+//
+// Usage:
+//
+//	go run ./cmd/i18ncheck [-dir pkg/i18n/locales]
+//
+// Missing keys would fall back to the default locale at runtime, mixing
+// languages in a single email, so the command exits with a non-zero status if
+// any shipped locale is missing a key.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nanayaw/fullstack/pkg/i18n"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory of locale files to check (defaults to the embedded catalog)")
+	flag.Parse()
+
+	catalog := i18n.Default()
+	if *dir != "" {
+		var err error
+		catalog, err = i18n.NewCatalog(os.DirFS(*dir), i18n.DefaultLocale)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load catalog: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	total := len(catalog.Keys(catalog.DefaultLocale()))
+	incomplete := false
+
+	for _, locale := range catalog.Locales() {
+		if locale == catalog.DefaultLocale() {
+			continue
+		}
+
+		missing := catalog.Missing(locale)
+		extra := catalog.Extra(locale)
+		fmt.Printf("%s: %d/%d translated\n", locale, total-len(missing), total)
+
+		for _, key := range missing {
+			fmt.Printf("  missing: %s\n", key)
+		}
+		for _, key := range extra {
+			fmt.Printf("  unknown: %s\n", key)
+		}
+
+		if len(missing) > 0 {
+			incomplete = true
+		}
+	}
+
+	if incomplete {
+		os.Exit(1)
+	}
+}
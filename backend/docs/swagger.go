@@ -14,3 +14,8 @@ package docs
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
+
+// @securityDefinitions.apikey AdminKey
+// @in header
+// @name X-Admin-Key
+// @description Admin API key (ADMIN_API_KEY).
@@ -21,6 +21,10 @@ type Config struct {
 	PASETO      PASETOConfig
 	Security    SecurityConfig
 	App         AppConfig
+	Admin       AdminConfig
+	EventStream EventStreamConfig
+	Webhooks    WebhooksConfig
+	DataExport  DataExportConfig
 }
 
 type ServerConfig struct {
@@ -39,6 +43,10 @@ type DatabaseConfig struct {
 
 type RedisConfig struct {
 	URL string `mapstructure:"REDIS_URL"`
+	// Backend of the cache service (redis or memory). The memory backend keeps
+	// rate limits, cached data and sessions in the API process, so it doesn't
+	// need Redis but can't be shared between instances.
+	Backend string `mapstructure:"CACHE_BACKEND"`
 }
 
 type AuthConfig struct {
@@ -51,24 +59,85 @@ type AuthConfig struct {
 	MaxLoginAttempts   int           `mapstructure:"AUTH_MAX_LOGIN_ATTEMPTS"`
 	LockoutDuration    time.Duration `mapstructure:"AUTH_LOCKOUT_DURATION"`
 	SessionMaxLifetime time.Duration `mapstructure:"AUTH_SESSION_MAX_LIFETIME"`
+	// Time after registration at which unverified users are reminded to verify their email
+	OnboardingReminderDelay time.Duration `mapstructure:"AUTH_ONBOARDING_REMINDER_DELAY"`
+	// Time between security digest emails, starting when the email is verified
+	SecurityDigestInterval time.Duration `mapstructure:"AUTH_SECURITY_DIGEST_INTERVAL"`
+	// Lifetime of the access tokens admins get to impersonate users
+	ImpersonationTTL time.Duration `mapstructure:"AUTH_IMPERSONATION_TTL"`
+	// Time before invitations to join an organization expire
+	InvitationTTL time.Duration `mapstructure:"AUTH_INVITATION_TTL"`
+	// Lifetime of the access tokens service accounts get with their client credentials
+	ServiceTokenTTL time.Duration `mapstructure:"AUTH_SERVICE_TOKEN_TTL"`
 }
 
 type EmailConfig struct {
+	// Provider used to deliver email (resend, upstash, smtp or capture). When
+	// empty, it is detected from the other settings.
+	Provider string `mapstructure:"EMAIL_PROVIDER"`
+
 	// Resend configuration
 	ResendAPIKey      string `mapstructure:"RESEND_API_KEY"`
 	FromEmail         string `mapstructure:"EMAIL_FROM_ADDRESS"`
 	FromName          string `mapstructure:"EMAIL_FROM_NAME"`
 	VerificationURL   string `mapstructure:"EMAIL_VERIFICATION_URL"`
 	PasswordResetURL  string `mapstructure:"EMAIL_PASSWORD_RESET_URL"`
+	AccountUnlockURL  string `mapstructure:"EMAIL_ACCOUNT_UNLOCK_URL"`
+	InvitationURL     string `mapstructure:"EMAIL_INVITATION_URL"`
+	DataExportURL     string `mapstructure:"EMAIL_DATA_EXPORT_URL"`
 	LoginNotification bool   `mapstructure:"EMAIL_LOGIN_NOTIFICATION"`
 
+	// Directory with template overrides (<name>.html, <name>.txt, <name>.subject)
+	TemplatesDir string `mapstructure:"EMAIL_TEMPLATES_DIR"`
+
+	// Outbox configuration: emails are queued in the database and delivered by a background worker
+	OutboxPollInterval   time.Duration `mapstructure:"EMAIL_OUTBOX_POLL_INTERVAL"`
+	OutboxBatchSize      int           `mapstructure:"EMAIL_OUTBOX_BATCH_SIZE"`
+	OutboxMaxAttempts    int           `mapstructure:"EMAIL_OUTBOX_MAX_ATTEMPTS"`
+	OutboxRetryBaseDelay time.Duration `mapstructure:"EMAIL_OUTBOX_RETRY_BASE_DELAY"`
+	OutboxRetryMaxDelay  time.Duration `mapstructure:"EMAIL_OUTBOX_RETRY_MAX_DELAY"`
+
 	// Upstash Workflow configuration
 	UpstashWorkflowURL   string `mapstructure:"UPSTASH_WORKFLOW_URL"`
 	UpstashWorkflowToken string `mapstructure:"UPSTASH_WORKFLOW_TOKEN"`
 
+	// SMTP configuration
+	SMTPHost     string `mapstructure:"EMAIL_SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"EMAIL_SMTP_PORT"`
+	SMTPUsername string `mapstructure:"EMAIL_SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"EMAIL_SMTP_PASSWORD"`
+	SMTPStartTLS bool   `mapstructure:"EMAIL_SMTP_STARTTLS"`
+
+	// DKIM signing for SMTP, enabled when a private key is configured
+	DKIMDomain         string `mapstructure:"EMAIL_DKIM_DOMAIN"`
+	DKIMSelector       string `mapstructure:"EMAIL_DKIM_SELECTOR"`
+	DKIMPrivateKeyPath string `mapstructure:"EMAIL_DKIM_PRIVATE_KEY_PATH"`
+
+	// Directory the capture provider writes emails to (in memory when empty)
+	CaptureDir string `mapstructure:"EMAIL_CAPTURE_DIR"`
+
+	// Signing secret of the delivery events webhook (whsec_...), disabled when empty
+	WebhookSecret string `mapstructure:"EMAIL_WEBHOOK_SECRET"`
+
+	// Unsubscribe links of the emails users can opt out of: UnsubscribeURL is
+	// the page linked from the email, and UnsubscribeAPIURL the one-click
+	// endpoint (RFC 8058) in its List-Unsubscribe header. Links carry a token
+	// signed with UnsubscribeSecret, and are left out when it is empty.
+	UnsubscribeURL    string `mapstructure:"EMAIL_UNSUBSCRIBE_URL"`
+	UnsubscribeAPIURL string `mapstructure:"EMAIL_UNSUBSCRIBE_API_URL"`
+	UnsubscribeSecret string `mapstructure:"EMAIL_UNSUBSCRIBE_SECRET"`
+
+	// Public URL of the scheduled emails webhook that Upstash Workflow calls when
+	// a scheduled email is due, and the token it must send back. Scheduled
+	// emails are only polled in-process when either is empty.
+	ScheduleCallbackURL   string `mapstructure:"EMAIL_SCHEDULE_CALLBACK_URL"`
+	ScheduleCallbackToken string `mapstructure:"EMAIL_SCHEDULE_CALLBACK_TOKEN"`
+
 	// TTL values (shared with AuthConfig)
 	VerificationTTL  time.Duration `mapstructure:"AUTH_VERIFICATION_TTL"`
 	PasswordResetTTL time.Duration `mapstructure:"AUTH_PASSWORD_RESET_TTL"`
+	InvitationTTL    time.Duration `mapstructure:"AUTH_INVITATION_TTL"`
+	DataExportTTL    time.Duration `mapstructure:"DATA_EXPORT_TTL"`
 }
 
 type OAuthConfig struct {
@@ -105,6 +174,8 @@ type SecurityConfig struct {
 	GlobalRateLimit int `mapstructure:"global_rate_limit"`
 	// Auth rate limit (login attempts per 15 minutes)
 	AuthRateLimit int `mapstructure:"auth_rate_limit"`
+	// Rate limit policies overriding the defaults, see ParseRateLimitPolicies
+	RateLimitPolicyOverrides string `mapstructure:"RATE_LIMIT_POLICIES"`
 }
 
 // AppConfig contains application-level configuration
@@ -113,12 +184,75 @@ type AppConfig struct {
 	Name string `mapstructure:"name"`
 	// Application URL
 	URL string `mapstructure:"url"`
+	// Support email address shown in emails (defaults to the email from address)
+	SupportEmail string `mapstructure:"support_email"`
 	// Environment (development, staging, production)
 	Environment string `mapstructure:"environment"`
 	// Debug mode
 	Debug bool `mapstructure:"debug"`
 }
 
+type AdminConfig struct {
+	// API key expected in the X-Admin-Key header of admin endpoints, which are disabled when it is empty
+	APIKey string `mapstructure:"ADMIN_API_KEY"`
+}
+
+// EventStreamConfig configures the sinks audit log entries and security
+// events are streamed to, such as a SIEM. Each sink is enabled by setting its
+// address, path or URL.
+type EventStreamConfig struct {
+	// Syslog server receiving RFC 5424 messages, e.g. siem.internal:6514
+	SyslogAddress string `mapstructure:"EVENT_STREAM_SYSLOG_ADDRESS"`
+	// Syslog transport: tcp or udp
+	SyslogNetwork string `mapstructure:"EVENT_STREAM_SYSLOG_NETWORK"`
+	// APP-NAME of the syslog messages
+	SyslogAppName string `mapstructure:"EVENT_STREAM_SYSLOG_APP_NAME"`
+
+	// File events are appended to as JSON lines
+	FilePath string `mapstructure:"EVENT_STREAM_FILE_PATH"`
+	// Size in bytes at which the file is rotated
+	FileMaxSize int64 `mapstructure:"EVENT_STREAM_FILE_MAX_SIZE"`
+	// Number of rotated files kept
+	FileMaxBackups int `mapstructure:"EVENT_STREAM_FILE_MAX_BACKUPS"`
+
+	// URL batches of events are posted to
+	WebhookURL string `mapstructure:"EVENT_STREAM_WEBHOOK_URL"`
+	// "whsec_" secret the webhook requests are signed with
+	WebhookSecret string `mapstructure:"EVENT_STREAM_WEBHOOK_SECRET"`
+
+	// Number of events buffered for each sink before new events are dropped
+	BufferSize int `mapstructure:"EVENT_STREAM_BUFFER_SIZE"`
+	// Maximum number of events written to a sink at once
+	BatchSize      int           `mapstructure:"EVENT_STREAM_BATCH_SIZE"`
+	MaxAttempts    int           `mapstructure:"EVENT_STREAM_MAX_ATTEMPTS"`
+	RetryBaseDelay time.Duration `mapstructure:"EVENT_STREAM_RETRY_BASE_DELAY"`
+	RetryMaxDelay  time.Duration `mapstructure:"EVENT_STREAM_RETRY_MAX_DELAY"`
+}
+
+// WebhooksConfig configures the delivery of identity lifecycle events to the
+// registered webhook endpoints
+type WebhooksConfig struct {
+	// Deliveries are queued in the database and sent by a background worker
+	PollInterval   time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	BatchSize      int           `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	MaxAttempts    int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	RetryBaseDelay time.Duration `mapstructure:"WEBHOOK_RETRY_BASE_DELAY"`
+	RetryMaxDelay  time.Duration `mapstructure:"WEBHOOK_RETRY_MAX_DELAY"`
+	// How long endpoints have to respond
+	Timeout time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+}
+
+// DataExportConfig configures the copies of their data users can export
+type DataExportConfig struct {
+	// Requested exports are built by a background job run this often
+	PollInterval time.Duration `mapstructure:"DATA_EXPORT_POLL_INTERVAL"`
+	// How long the emailed download link works, after which the archive is
+	// deleted
+	TTL time.Duration `mapstructure:"DATA_EXPORT_TTL"`
+	// Secret the download links are signed with
+	Secret string `mapstructure:"DATA_EXPORT_SECRET"`
+}
+
 func LoadConfig(path string) (*Config, error) {
 	config := &Config{}
 
@@ -229,6 +363,7 @@ func setDefaults() {
 
 	// Redis defaults
 	viper.SetDefault("REDIS_URL", "localhost:6379")
+	viper.SetDefault("CACHE_BACKEND", "redis")
 
 	// Auth defaults
 	viper.SetDefault("AUTH_ACCESS_TOKEN_TTL", "15m")
@@ -238,9 +373,44 @@ func setDefaults() {
 	viper.SetDefault("AUTH_MAX_LOGIN_ATTEMPTS", 5)
 	viper.SetDefault("AUTH_LOCKOUT_DURATION", "15m")
 	viper.SetDefault("AUTH_SESSION_MAX_LIFETIME", "30d")
+	viper.SetDefault("AUTH_ONBOARDING_REMINDER_DELAY", "72h")
+	viper.SetDefault("AUTH_SECURITY_DIGEST_INTERVAL", "168h")
+	viper.SetDefault("AUTH_IMPERSONATION_TTL", "10m")
+	viper.SetDefault("AUTH_INVITATION_TTL", "168h")
+	viper.SetDefault("AUTH_SERVICE_TOKEN_TTL", "1h")
 
 	// Email defaults
 	viper.SetDefault("EMAIL_LOGIN_NOTIFICATION", true)
+	viper.SetDefault("EMAIL_OUTBOX_POLL_INTERVAL", "5s")
+	viper.SetDefault("EMAIL_OUTBOX_BATCH_SIZE", 20)
+	viper.SetDefault("EMAIL_OUTBOX_MAX_ATTEMPTS", 10)
+	viper.SetDefault("EMAIL_OUTBOX_RETRY_BASE_DELAY", "30s")
+	viper.SetDefault("EMAIL_OUTBOX_RETRY_MAX_DELAY", "1h")
+	viper.SetDefault("EMAIL_SMTP_PORT", 587)
+	viper.SetDefault("EMAIL_SMTP_STARTTLS", true)
+
+	// Event stream defaults
+	viper.SetDefault("EVENT_STREAM_SYSLOG_NETWORK", "tcp")
+	viper.SetDefault("EVENT_STREAM_SYSLOG_APP_NAME", "fullstack")
+	viper.SetDefault("EVENT_STREAM_FILE_MAX_SIZE", 100<<20)
+	viper.SetDefault("EVENT_STREAM_FILE_MAX_BACKUPS", 5)
+	viper.SetDefault("EVENT_STREAM_BUFFER_SIZE", 10000)
+	viper.SetDefault("EVENT_STREAM_BATCH_SIZE", 100)
+	viper.SetDefault("EVENT_STREAM_MAX_ATTEMPTS", 5)
+	viper.SetDefault("EVENT_STREAM_RETRY_BASE_DELAY", "1s")
+	viper.SetDefault("EVENT_STREAM_RETRY_MAX_DELAY", "30s")
+
+	// Webhook defaults
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", "5s")
+	viper.SetDefault("WEBHOOK_BATCH_SIZE", 20)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_RETRY_BASE_DELAY", "30s")
+	viper.SetDefault("WEBHOOK_RETRY_MAX_DELAY", "1h")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+
+	// Data export defaults
+	viper.SetDefault("DATA_EXPORT_POLL_INTERVAL", "30s")
+	viper.SetDefault("DATA_EXPORT_TTL", "168h")
 
 	// Security defaults
 	viper.SetDefault("max_login_attempts", 5)
@@ -263,8 +433,8 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("database URL is required")
 	}
 
-	// Only require Redis URL in non-development environments
-	if config.Redis.URL == "" && config.Environment != "development" {
+	// Only require Redis URL in non-development environments, when Redis is used
+	if config.Redis.URL == "" && config.Environment != "development" && config.Redis.Backend != "memory" {
 		return fmt.Errorf("redis URL is required")
 	}
 
@@ -274,15 +444,23 @@ func validateConfig(config *Config) error {
 		fmt.Println("Using default Redis URL in development mode:", config.Redis.URL)
 	}
 
-	// Only require email API key in non-development environments
-	if config.Email.ResendAPIKey == "" && config.Environment != "development" {
-		return fmt.Errorf("email API key is required")
+	// Use the capture provider in development mode if no provider is configured
+	emailConfigured := config.Email.Provider != "" || config.Email.ResendAPIKey != "" ||
+		config.Email.SMTPHost != "" ||
+		(config.Email.UpstashWorkflowURL != "" && config.Email.UpstashWorkflowToken != "")
+	if !emailConfigured && config.Environment == "development" {
+		config.Email.Provider = "capture"
+		fmt.Println("Using capture email provider in development mode")
 	}
 
-	// Set a default email API key in development mode if it's empty
-	if config.Email.ResendAPIKey == "" && config.Environment == "development" {
-		config.Email.ResendAPIKey = "test_api_key"
-		fmt.Println("Using default email API key in development mode:", config.Email.ResendAPIKey)
+	// Only require an email provider in non-development environments
+	if !emailConfigured && config.Environment != "development" {
+		return fmt.Errorf("email provider is required")
+	}
+
+	// Never capture email in production, where it must actually be delivered
+	if config.Email.Provider == "capture" && config.Environment == "production" {
+		return fmt.Errorf("capture email provider cannot be used in production")
 	}
 
 	// Only require email from address in non-development environments
@@ -296,6 +474,21 @@ func validateConfig(config *Config) error {
 		fmt.Println("Using default email from address in development mode:", config.Email.FromEmail)
 	}
 
+	// Only require a data export secret in non-development environments
+	if config.DataExport.Secret == "" && config.Environment != "development" {
+		return fmt.Errorf("data export secret is required")
+	}
+
+	// Set a default data export secret in development mode if it's empty
+	if config.DataExport.Secret == "" && config.Environment == "development" {
+		config.DataExport.Secret = "development-data-export-secret"
+		fmt.Println("Using default data export secret in development mode")
+	}
+
+	if _, err := config.Security.RateLimitPolicies(); err != nil {
+		return err
+	}
+
 	// Skip PASETO key file checks in development mode
 	if config.Environment != "development" {
 		// Check if PASETO key files exist
@@ -327,7 +520,8 @@ func DefaultConfig() *Config {
 			MaxIdleConns: 25,
 		},
 		Redis: RedisConfig{
-			URL: "localhost:6379",
+			URL:     "localhost:6379",
+			Backend: "redis",
 		},
 		Auth: AuthConfig{
 			PublicKey:          "public_key",
@@ -339,6 +533,12 @@ func DefaultConfig() *Config {
 			MaxLoginAttempts:   5,
 			LockoutDuration:    15 * time.Minute,
 			SessionMaxLifetime: 30 * 24 * time.Hour,
+
+			OnboardingReminderDelay: 72 * time.Hour,
+			SecurityDigestInterval:  7 * 24 * time.Hour,
+			ImpersonationTTL:        10 * time.Minute,
+			InvitationTTL:           7 * 24 * time.Hour,
+			ServiceTokenTTL:         time.Hour,
 		},
 		Email: EmailConfig{
 			ResendAPIKey:         "resend_api_key",
@@ -346,9 +546,22 @@ func DefaultConfig() *Config {
 			FromName:             "Go+Next",
 			VerificationURL:      "http://localhost:3000/verify",
 			PasswordResetURL:     "http://localhost:3000/reset",
+			AccountUnlockURL:     "http://localhost:3000/auth/unlock-account",
+			InvitationURL:        "http://localhost:3000/accept-invitation",
+			DataExportURL:        "http://localhost:8080/api/v1/exports/download",
 			LoginNotification:    true,
+			OutboxPollInterval:   5 * time.Second,
+			OutboxBatchSize:      20,
+			OutboxMaxAttempts:    10,
+			OutboxRetryBaseDelay: 30 * time.Second,
+			OutboxRetryMaxDelay:  time.Hour,
 			UpstashWorkflowURL:   "https://api.upstash.com/workflows/workflow_id",
 			UpstashWorkflowToken: "upstash_workflow_token",
+			SMTPPort:             587,
+			SMTPStartTLS:         true,
+
+			UnsubscribeURL:    "http://localhost:3000/unsubscribe",
+			UnsubscribeAPIURL: "http://localhost:8080/api/v1/notifications/unsubscribe",
 		},
 		OAuth: OAuthConfig{
 			Google: struct {
@@ -384,10 +597,34 @@ func DefaultConfig() *Config {
 			AuthRateLimit:                     5,
 		},
 		App: AppConfig{
-			Name:        "Go+Next Fullstack App",
-			URL:         "http://localhost:3000",
-			Environment: "development",
-			Debug:       true,
+			Name:         "Go+Next Fullstack App",
+			URL:          "http://localhost:3000",
+			SupportEmail: "support@example.com",
+			Environment:  "development",
+			Debug:        true,
+		},
+		EventStream: EventStreamConfig{
+			SyslogNetwork:  "tcp",
+			SyslogAppName:  "fullstack",
+			FileMaxSize:    100 << 20,
+			FileMaxBackups: 5,
+			BufferSize:     10000,
+			BatchSize:      100,
+			MaxAttempts:    5,
+			RetryBaseDelay: time.Second,
+			RetryMaxDelay:  30 * time.Second,
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:   5 * time.Second,
+			BatchSize:      20,
+			MaxAttempts:    8,
+			RetryBaseDelay: 30 * time.Second,
+			RetryMaxDelay:  time.Hour,
+			Timeout:        10 * time.Second,
+		},
+		DataExport: DataExportConfig{
+			PollInterval: 30 * time.Second,
+			TTL:          7 * 24 * time.Hour,
 		},
 	}
 }
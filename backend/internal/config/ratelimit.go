@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRateLimitRoute is the route of the rate limit policy that applies to
+// the rate limited routes without a policy of their own
+const DefaultRateLimitRoute = "*"
+
+// What rate limited requests are counted by
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByUser   = "user"
+	RateLimitByAPIKey = "api_key"
+	RateLimitByEmail  = "email"
+)
+
+// Rate limiting algorithms, matching model.RateLimitSlidingWindow and
+// model.RateLimitTokenBucket
+const (
+	rateLimitSlidingWindow = "sliding_window"
+	rateLimitTokenBucket   = "token_bucket"
+)
+
+// RateLimitPolicy limits the requests to a route
+type RateLimitPolicy struct {
+	// Number of requests allowed in the window
+	Limit int
+	// Window of time the limit applies to
+	Window time.Duration
+	// What requests are counted by (ip, user, api_key or email). Requests
+	// without a user, API key or email are counted by IP address.
+	By string
+	// Rate limiting algorithm (sliding_window or token_bucket)
+	Algorithm string
+}
+
+// String formats the policy like it is configured in RATE_LIMIT_POLICIES
+func (p RateLimitPolicy) String() string {
+	return fmt.Sprintf("%d/%s:%s:%s", p.Limit, p.Window, p.By, p.Algorithm)
+}
+
+// RateLimitPolicies returns the rate limit policy of each route, keyed by
+// method and path pattern (e.g. "POST /api/v1/auth/login"), with
+// DefaultRateLimitRoute for every other route. The policies configured in
+// RateLimitPolicies override the defaults, which are derived from
+// GlobalRateLimit and AuthRateLimit.
+func (c *SecurityConfig) RateLimitPolicies() (map[string]RateLimitPolicy, error) {
+	authLimit := c.AuthRateLimit
+	if authLimit <= 0 {
+		authLimit = 5
+	}
+	globalLimit := c.GlobalRateLimit
+	if globalLimit <= 0 {
+		globalLimit = 100
+	}
+
+	policies := map[string]RateLimitPolicy{
+		DefaultRateLimitRoute:               {Limit: globalLimit, Window: time.Minute, By: RateLimitByUser, Algorithm: rateLimitTokenBucket},
+		"POST /api/v1/auth/login":           {Limit: authLimit, Window: 15 * time.Minute, By: RateLimitByEmail, Algorithm: rateLimitSlidingWindow},
+		"POST /api/v1/auth/register":        {Limit: 5, Window: time.Hour, By: RateLimitByIP, Algorithm: rateLimitSlidingWindow},
+		"POST /api/v1/auth/forgot-password": {Limit: 3, Window: time.Hour, By: RateLimitByEmail, Algorithm: rateLimitSlidingWindow},
+		"POST /api/v1/auth/reset-password":  {Limit: authLimit, Window: 15 * time.Minute, By: RateLimitByIP, Algorithm: rateLimitSlidingWindow},
+		"POST /api/v1/auth/verify-email":    {Limit: 10, Window: 15 * time.Minute, By: RateLimitByIP, Algorithm: rateLimitSlidingWindow},
+		"POST /api/v1/auth/unlock-account":  {Limit: authLimit, Window: 15 * time.Minute, By: RateLimitByIP, Algorithm: rateLimitSlidingWindow},
+		"POST /api/v1/auth/refresh":         {Limit: 30, Window: time.Minute, By: RateLimitByIP, Algorithm: rateLimitTokenBucket},
+		"POST /api/v1/auth/token":           {Limit: 30, Window: time.Minute, By: RateLimitByIP, Algorithm: rateLimitTokenBucket},
+		"POST /api/v1/users/me/export":      {Limit: 3, Window: 24 * time.Hour, By: RateLimitByUser, Algorithm: rateLimitSlidingWindow},
+	}
+
+	overrides, err := ParseRateLimitPolicies(c.RateLimitPolicyOverrides)
+	if err != nil {
+		return nil, err
+	}
+	for route, policy := range overrides {
+		policies[route] = policy
+	}
+
+	return policies, nil
+}
+
+// ParseRateLimitPolicies parses policies separated by semicolons, each written
+// as <route>=<limit>/<window>[:<by>[:<algorithm>]], e.g.
+// "POST /api/v1/auth/login=5/15m:email;*=100/1m:user:token_bucket". Requests
+// are counted by IP address with a sliding window by default.
+func ParseRateLimitPolicies(spec string) (map[string]RateLimitPolicy, error) {
+	policies := make(map[string]RateLimitPolicy)
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, rule, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !ok || route == "" {
+			return nil, fmt.Errorf("invalid rate limit policy %q: expected <route>=<limit>/<window>", entry)
+		}
+
+		policy, err := parseRateLimitPolicy(strings.TrimSpace(rule))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit policy %q: %w", entry, err)
+		}
+		policies[route] = policy
+	}
+
+	return policies, nil
+}
+
+// parseRateLimitPolicy parses <limit>/<window>[:<by>[:<algorithm>]]
+func parseRateLimitPolicy(rule string) (RateLimitPolicy, error) {
+	parts := strings.Split(rule, ":")
+	if len(parts) > 3 {
+		return RateLimitPolicy{}, fmt.Errorf("too many fields")
+	}
+
+	limit, window, ok := strings.Cut(parts[0], "/")
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf("expected <limit>/<window>")
+	}
+
+	policy := RateLimitPolicy{
+		By:        RateLimitByIP,
+		Algorithm: rateLimitSlidingWindow,
+	}
+
+	var err error
+	if policy.Limit, err = strconv.Atoi(limit); err != nil || policy.Limit < 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid limit %q", limit)
+	}
+	if policy.Window, err = time.ParseDuration(window); err != nil || policy.Window < time.Millisecond {
+		return RateLimitPolicy{}, fmt.Errorf("invalid window %q", window)
+	}
+
+	if len(parts) > 1 {
+		switch parts[1] {
+		case RateLimitByIP, RateLimitByUser, RateLimitByAPIKey, RateLimitByEmail:
+			policy.By = parts[1]
+		default:
+			return RateLimitPolicy{}, fmt.Errorf("unknown key %q", parts[1])
+		}
+	}
+	if len(parts) > 2 {
+		switch parts[2] {
+		case rateLimitSlidingWindow, rateLimitTokenBucket:
+			policy.Algorithm = parts[2]
+		default:
+			return RateLimitPolicy{}, fmt.Errorf("unknown algorithm %q", parts[2])
+		}
+	}
+
+	return policy, nil
+}
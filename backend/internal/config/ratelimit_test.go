@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	policies, err := ParseRateLimitPolicies(" POST  /api/v1/auth/login=5/15m:email ; *=100/1m:user:token_bucket;GET /api/v1/users/me=10/1s;")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]RateLimitPolicy{
+		"POST /api/v1/auth/login": {Limit: 5, Window: 15 * time.Minute, By: RateLimitByEmail, Algorithm: "sliding_window"},
+		"*":                       {Limit: 100, Window: time.Minute, By: RateLimitByUser, Algorithm: "token_bucket"},
+		"GET /api/v1/users/me":    {Limit: 10, Window: time.Second, By: RateLimitByIP, Algorithm: "sliding_window"},
+	}, policies)
+
+	for _, spec := range []string{
+		"POST /login",
+		"=5/1m",
+		"POST /login=5",
+		"POST /login=five/1m",
+		"POST /login=5/forever",
+		"POST /login=5/1m:cookie",
+		"POST /login=5/1m:ip:leaky_bucket",
+		"POST /login=5/1m:ip:token_bucket:extra",
+	} {
+		_, err := ParseRateLimitPolicies(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRateLimitPolicies(t *testing.T) {
+	cfg := &SecurityConfig{
+		GlobalRateLimit:          60,
+		AuthRateLimit:            3,
+		RateLimitPolicyOverrides: "POST /api/v1/auth/register=1/1h",
+	}
+
+	policies, err := cfg.RateLimitPolicies()
+	require.NoError(t, err)
+	assert.Equal(t, 60, policies[DefaultRateLimitRoute].Limit)
+	assert.Equal(t, 3, policies["POST /api/v1/auth/login"].Limit)
+	assert.Equal(t, RateLimitByEmail, policies["POST /api/v1/auth/login"].By)
+	assert.Equal(t, 1, policies["POST /api/v1/auth/register"].Limit)
+}
@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/audit"
+)
+
+// AuditLogs defines the interface for querying the audit log
+type AuditLogs interface {
+	ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) (*models.AuditLogPage, error)
+}
+
+// ListAuditLogs godoc
+// @Summary List audit log entries
+// @Description List the entries of the audit log, newest first, using cursor pagination
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param user_id query string false "Only include entries made by this user"
+// @Param service_account_id query string false "Only include entries made by this service account"
+// @Param action query string false "Only include entries with this action, such as organization.renamed"
+// @Param entity_type query string false "Only include entries about this type of entity"
+// @Param entity_id query string false "Only include entries about this entity"
+// @Param from query string false "Only include entries at or after this time (RFC 3339)"
+// @Param to query string false "Only include entries before this time (RFC 3339)"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Success 200 {object} AuditLogsResponse "Audit log entries"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/audit-logs [get]
+func (h *Handler) ListAuditLogs(c echo.Context) error {
+	filter, err := parseAuditLogFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+
+	// Call service
+	page, err := h.auditLogs.ListAuditLogs(c.Request().Context(), filter)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusBadRequest {
+			return c.JSON(http.StatusBadRequest, response.NewErrorResponse(appErr.Message))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list audit logs"))
+	}
+
+	// Convert to response model
+	items := make([]AuditLogItem, len(page.Entries))
+	for i, entry := range page.Entries {
+		items[i] = newAuditLogItem(entry)
+	}
+
+	resp := AuditLogsResponse{
+		Entries:    items,
+		NextCursor: page.NextCursor,
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// parseAuditLogFilter parses the query parameters of an audit log listing
+func parseAuditLogFilter(c echo.Context) (model.AuditLogFilter, error) {
+	filter := model.AuditLogFilter{
+		UserID:           c.QueryParam("user_id"),
+		ServiceAccountID: c.QueryParam("service_account_id"),
+		Action:           c.QueryParam("action"),
+		EntityType:       c.QueryParam("entity_type"),
+		EntityID:         c.QueryParam("entity_id"),
+		Limit:            audit.DefaultAuditLogPageSize,
+	}
+
+	for name, dest := range map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		if value := c.QueryParam(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New(name + " must be an RFC 3339 time")
+			}
+			*dest = t
+		}
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		after, err := audit.DecodeAuditLogCursor(cursor)
+		if err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+		filter.After = after
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > audit.MaxAuditLogPageSize {
+			return filter, errors.New("limit must be between 1 and 200")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// newAuditLogItem converts an audit log entry to its response model
+func newAuditLogItem(entry *models.AuditLog) AuditLogItem {
+	return AuditLogItem{
+		ID:               entry.ID,
+		Sequence:         entry.Sequence,
+		Actor:            entry.Actor,
+		UserID:           entry.UserID,
+		ServiceAccountID: entry.ServiceAccountID,
+		Action:           entry.Action,
+		EntityType:       entry.EntityType,
+		EntityID:         entry.EntityID,
+		Metadata:         rawMetadata(entry.Metadata),
+		RequestID:        entry.RequestID,
+		IPAddress:        entry.IPAddress,
+		CreatedAt:        entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Hash:             entry.Hash,
+	}
+}
+
+// rawMetadata returns the metadata of an entry as JSON. Entries made before
+// metadata was always a JSON object are returned as strings.
+func rawMetadata(metadata string) json.RawMessage {
+	if metadata == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(metadata)) {
+		return json.RawMessage(metadata)
+	}
+	encoded, _ := json.Marshal(metadata)
+	return encoded
+}
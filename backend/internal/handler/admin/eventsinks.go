@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// EventSinks defines the interface for reading the health of the sinks
+// events are streamed to
+type EventSinks interface {
+	Health() []model.EventSinkHealth
+}
+
+// ListEventSinks godoc
+// @Summary List event sinks
+// @Description Get the health of the sinks, such as a SIEM, that audit log entries and security events are streamed to
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Success 200 {object} EventSinksResponse "Event sinks"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Router /api/v1/admin/event-sinks [get]
+func (h *Handler) ListEventSinks(c echo.Context) error {
+	health := h.eventSinks.Health()
+
+	items := make([]EventSinkItem, len(health))
+	for i, sink := range health {
+		items[i] = EventSinkItem{
+			Name:                sink.Name,
+			Healthy:             sink.Healthy,
+			Buffered:            sink.Buffered,
+			Delivered:           sink.Delivered,
+			Dropped:             sink.Dropped,
+			ConsecutiveFailures: sink.ConsecutiveFailures,
+			LastError:           sink.LastError,
+			LastSuccessAt:       formatOptionalTime(sink.LastSuccessAt),
+			LastFailureAt:       formatOptionalTime(sink.LastFailureAt),
+		}
+	}
+
+	return c.JSON(http.StatusOK, EventSinksResponse{Sinks: items})
+}
+
+// formatOptionalTime formats a time as RFC 3339, or returns an empty string
+// when it is nil
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,361 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/email"
+)
+
+// EmailOutbox defines the interface for inspecting the email outbox
+type EmailOutbox interface {
+	ListEmails(ctx context.Context, filter model.OutboxFilter) ([]*model.OutboxEmail, error)
+	GetEmail(ctx context.Context, id string) (*model.OutboxEmail, error)
+	RetryEmail(ctx context.Context, id string) (*model.OutboxEmail, error)
+	Stats(ctx context.Context) (map[string]int, error)
+}
+
+// EmailSuppressions defines the interface for managing the email suppression list
+type EmailSuppressions interface {
+	ListSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, error)
+	RemoveSuppression(ctx context.Context, email string) error
+}
+
+// Handler handles admin requests
+type Handler struct {
+	emailOutbox       EmailOutbox
+	emailSuppressions EmailSuppressions
+	authorization     Authorization
+	users             Users
+	auditLogs         AuditLogs
+	eventSinks        EventSinks
+	webhooks          WebhookEndpoints
+}
+
+// NewHandler creates a new admin handler
+func NewHandler(emailOutbox EmailOutbox, emailSuppressions EmailSuppressions, authorization Authorization, users Users, auditLogs AuditLogs, eventSinks EventSinks, webhooks WebhookEndpoints) *Handler {
+	return &Handler{
+		emailOutbox:       emailOutbox,
+		emailSuppressions: emailSuppressions,
+		authorization:     authorization,
+		users:             users,
+		auditLogs:         auditLogs,
+		eventSinks:        eventSinks,
+		webhooks:          webhooks,
+	}
+}
+
+// ListOutboxEmails godoc
+// @Summary List outbox emails
+// @Description List queued, sent and dead-lettered emails, newest first
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param status query string false "Only include emails with this status (pending, sending, sent or dead)"
+// @Param recipient query string false "Only include emails sent to this address"
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Param offset query int false "Number of emails to skip"
+// @Success 200 {object} OutboxEmailsResponse "Outbox emails"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/emails [get]
+func (h *Handler) ListOutboxEmails(c echo.Context) error {
+	filter := model.OutboxFilter{
+		Status:    c.QueryParam("status"),
+		Recipient: c.QueryParam("recipient"),
+		Limit:     email.DefaultOutboxPageSize,
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+	filter.Limit, filter.Offset = limit, offset
+
+	// Call service
+	emails, err := h.emailOutbox.ListEmails(c.Request().Context(), filter)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusBadRequest {
+			return c.JSON(http.StatusBadRequest, response.NewErrorResponse(appErr.Message))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list emails"))
+	}
+
+	// Convert to response model
+	items := make([]OutboxEmailItem, len(emails))
+	for i, e := range emails {
+		items[i] = newOutboxEmailItem(e)
+	}
+
+	resp := OutboxEmailsResponse{
+		Emails: items,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetOutboxEmail godoc
+// @Summary Get an outbox email
+// @Description Get the delivery status of a single email
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Email ID"
+// @Success 200 {object} OutboxEmailItem "Outbox email"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Email not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/emails/{id} [get]
+func (h *Handler) GetOutboxEmail(c echo.Context) error {
+	e, err := h.emailOutbox.GetEmail(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusNotFound {
+			return c.JSON(http.StatusNotFound, response.NewErrorResponse("Email not found"))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get email"))
+	}
+
+	return c.JSON(http.StatusOK, newOutboxEmailItem(e))
+}
+
+// RetryOutboxEmail godoc
+// @Summary Retry a dead-lettered email
+// @Description Queue a dead-lettered email for immediate delivery with a fresh set of attempts
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Email ID"
+// @Success 200 {object} OutboxEmailItem "Email queued for delivery"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Email not found"
+// @Failure 409 {object} ErrorResponse "Email is not dead-lettered"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/emails/{id}/retry [post]
+func (h *Handler) RetryOutboxEmail(c echo.Context) error {
+	e, err := h.emailOutbox.RetryEmail(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			switch appErr.StatusCode {
+			case http.StatusNotFound:
+				return c.JSON(http.StatusNotFound, response.NewErrorResponse("Email not found"))
+			case http.StatusConflict:
+				return c.JSON(http.StatusConflict, response.NewErrorResponse(appErr.Message))
+			}
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to retry email"))
+	}
+
+	return c.JSON(http.StatusOK, newOutboxEmailItem(e))
+}
+
+// GetOutboxStats godoc
+// @Summary Get outbox statistics
+// @Description Get the number of outbox emails in each status
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Success 200 {object} OutboxStatsResponse "Outbox statistics"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/emails/stats [get]
+func (h *Handler) GetOutboxStats(c echo.Context) error {
+	counts, err := h.emailOutbox.Stats(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get outbox statistics"))
+	}
+
+	return c.JSON(http.StatusOK, OutboxStatsResponse{Counts: counts})
+}
+
+// ListEmailSuppressions godoc
+// @Summary List suppressed email addresses
+// @Description List addresses that are not emailed because they hard-bounced or complained, newest first
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Param offset query int false "Number of addresses to skip"
+// @Success 200 {object} EmailSuppressionsResponse "Suppressed addresses"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/emails/suppressions [get]
+func (h *Handler) ListEmailSuppressions(c echo.Context) error {
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+
+	// Call service
+	suppressions, err := h.emailSuppressions.ListSuppressions(c.Request().Context(), limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list suppressed addresses"))
+	}
+
+	// Convert to response model
+	items := make([]EmailSuppressionItem, len(suppressions))
+	for i, s := range suppressions {
+		items[i] = EmailSuppressionItem{
+			Email:     s.Email,
+			Reason:    s.Reason,
+			CreatedAt: s.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if s.Details != nil {
+			items[i].Details = *s.Details
+		}
+	}
+
+	resp := EmailSuppressionsResponse{
+		Suppressions: items,
+		Limit:        limit,
+		Offset:       offset,
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeleteEmailSuppression godoc
+// @Summary Remove a suppressed email address
+// @Description Send emails to an address again, e.g. after its owner fixed their mailbox
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param email path string true "Email address"
+// @Success 204 "Address removed from the suppression list"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Address not suppressed"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/emails/suppressions/{email} [delete]
+func (h *Handler) DeleteEmailSuppression(c echo.Context) error {
+	address, err := url.PathUnescape(c.Param("email"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid email address"))
+	}
+
+	if err := h.emailSuppressions.RemoveSuppression(c.Request().Context(), address); err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusNotFound {
+			return c.JSON(http.StatusNotFound, response.NewErrorResponse("Email address is not suppressed"))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to remove suppressed address"))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterRoutes registers all admin routes. requirePermission returns the
+// middleware letting through the users with a permission.
+func (h *Handler) RegisterRoutes(g *echo.Group, requirePermission func(permission string) echo.MiddlewareFunc) {
+	emails := requirePermission(model.PermissionEmailsManage)
+	g.GET("/emails", h.ListOutboxEmails, emails)
+	g.GET("/emails/stats", h.GetOutboxStats, emails)
+	g.GET("/emails/suppressions", h.ListEmailSuppressions, emails)
+	g.DELETE("/emails/suppressions/:email", h.DeleteEmailSuppression, emails)
+	g.GET("/emails/:id", h.GetOutboxEmail, emails)
+	g.POST("/emails/:id/retry", h.RetryOutboxEmail, emails)
+
+	readRoles := requirePermission(model.PermissionRolesRead)
+	manageRoles := requirePermission(model.PermissionRolesManage)
+	g.GET("/roles", h.ListRoles, readRoles)
+	g.GET("/permissions", h.ListPermissions, readRoles)
+	g.PUT("/roles/:role/permissions/:permission", h.GrantRolePermission, manageRoles)
+	g.DELETE("/roles/:role/permissions/:permission", h.RevokeRolePermission, manageRoles)
+	g.GET("/users/:id/roles", h.GetUserRoles, readRoles)
+	g.POST("/users/:id/roles", h.AssignUserRole, manageRoles)
+	g.DELETE("/users/:id/roles/:role", h.RevokeUserRole, manageRoles)
+
+	readUsers := requirePermission(model.PermissionUsersRead)
+	manageUsers := requirePermission(model.PermissionUsersManage)
+	g.GET("/users", h.ListUsers, readUsers)
+	g.GET("/users/:id", h.GetUser, readUsers)
+	g.POST("/users/:id/logout", h.ForceLogoutUser, manageUsers)
+	g.POST("/users/:id/lock", h.LockUser, manageUsers)
+	g.POST("/users/:id/unlock", h.UnlockUser, manageUsers)
+	g.POST("/users/:id/verification-email", h.ResendUserVerification, manageUsers)
+	g.POST("/users/:id/disable", h.DisableUser, manageUsers)
+	g.POST("/users/:id/enable", h.EnableUser, manageUsers)
+
+	impersonate := requirePermission(model.PermissionUsersImpersonate)
+	g.POST("/users/:id/impersonation", h.StartImpersonation, impersonate)
+	g.DELETE("/impersonations/:id", h.StopImpersonation, impersonate)
+
+	readAuditLogs := requirePermission(model.PermissionAuditLogsRead)
+	g.GET("/audit-logs", h.ListAuditLogs, readAuditLogs)
+	g.GET("/event-sinks", h.ListEventSinks, readAuditLogs)
+
+	webhooks := requirePermission(model.PermissionWebhooksManage)
+	g.POST("/webhooks", h.CreateWebhookEndpoint, webhooks)
+	g.GET("/webhooks", h.ListWebhookEndpoints, webhooks)
+	g.GET("/webhooks/:id", h.GetWebhookEndpoint, webhooks)
+	g.PATCH("/webhooks/:id", h.UpdateWebhookEndpoint, webhooks)
+	g.DELETE("/webhooks/:id", h.DeleteWebhookEndpoint, webhooks)
+	g.GET("/webhooks/:id/deliveries", h.ListWebhookDeliveries, webhooks)
+	g.GET("/webhooks/:id/deliveries/:delivery_id", h.GetWebhookDelivery, webhooks)
+	g.POST("/webhooks/:id/deliveries/:delivery_id/replay", h.ReplayWebhookDelivery, webhooks)
+}
+
+// parsePage parses the limit and offset query parameters
+func parsePage(c echo.Context) (int, int, error) {
+	limit, offset := email.DefaultOutboxPageSize, 0
+
+	if value := c.QueryParam("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > email.MaxOutboxPageSize {
+			return 0, 0, errors.New("limit must be between 1 and 200")
+		}
+		limit = n
+	}
+
+	if value := c.QueryParam("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// newOutboxEmailItem converts an outbox email to its response model
+func newOutboxEmailItem(e *model.OutboxEmail) OutboxEmailItem {
+	item := OutboxEmailItem{
+		ID:            e.ID,
+		Template:      e.Template,
+		To:            e.ToAddresses,
+		Subject:       e.Subject,
+		Status:        e.Status,
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt.UTC().Format(time.RFC3339),
+		CreatedAt:     e.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:     e.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+
+	if e.LastError != nil {
+		item.LastError = *e.LastError
+	}
+	if e.SentAt != nil {
+		item.SentAt = e.SentAt.UTC().Format(time.RFC3339)
+	}
+
+	return item
+}
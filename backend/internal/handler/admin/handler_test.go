@@ -0,0 +1,987 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// MockEmailOutbox is a mock implementation of the email outbox
+type MockEmailOutbox struct {
+	mock.Mock
+}
+
+// ListEmails mocks the ListEmails method
+func (m *MockEmailOutbox) ListEmails(ctx context.Context, filter model.OutboxFilter) ([]*model.OutboxEmail, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*model.OutboxEmail), args.Error(1)
+}
+
+// GetEmail mocks the GetEmail method
+func (m *MockEmailOutbox) GetEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.OutboxEmail), args.Error(1)
+}
+
+// RetryEmail mocks the RetryEmail method
+func (m *MockEmailOutbox) RetryEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.OutboxEmail), args.Error(1)
+}
+
+// Stats mocks the Stats method
+func (m *MockEmailOutbox) Stats(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+// MockEmailSuppressions is a mock implementation of the email suppression list
+type MockEmailSuppressions struct {
+	mock.Mock
+}
+
+// ListSuppressions mocks the ListSuppressions method
+func (m *MockEmailSuppressions) ListSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]*model.EmailSuppression), args.Error(1)
+}
+
+// RemoveSuppression mocks the RemoveSuppression method
+func (m *MockEmailSuppressions) RemoveSuppression(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+// TestListOutboxEmails tests the ListOutboxEmails handler
+func TestListOutboxEmails(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new admin handler with a mock outbox
+	mockOutbox := new(MockEmailOutbox)
+	handler := NewHandler(mockOutbox, new(MockEmailSuppressions), nil, nil, nil, nil, nil)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/emails?status=dead&limit=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set up expectations
+	lastError := "workflow API returned error status: 503"
+	createdAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockOutbox.On("ListEmails", mock.Anything, model.OutboxFilter{Status: "dead", Limit: 10}).Return([]*model.OutboxEmail{
+		{
+			ID:            "email-1",
+			Template:      "verification",
+			ToAddresses:   "user@example.com",
+			Subject:       "Verify your email address",
+			Status:        model.OutboxStatusDead,
+			Attempts:      10,
+			LastError:     &lastError,
+			NextAttemptAt: createdAt,
+			CreatedAt:     createdAt,
+			UpdatedAt:     createdAt,
+		},
+	}, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.ListOutboxEmails(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Parse the response
+		var resp OutboxEmailsResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		// Check the response
+		assert.Len(t, resp.Emails, 1)
+		assert.Equal(t, "email-1", resp.Emails[0].ID)
+		assert.Equal(t, "user@example.com", resp.Emails[0].To)
+		assert.Equal(t, lastError, resp.Emails[0].LastError)
+		assert.Equal(t, "2023-01-01T12:00:00Z", resp.Emails[0].CreatedAt)
+		assert.Equal(t, 10, resp.Limit)
+	}
+
+	// Verify expectations
+	mockOutbox.AssertExpectations(t)
+}
+
+// TestRetryOutboxEmail tests the RetryOutboxEmail handler
+func TestRetryOutboxEmail(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new admin handler with a mock outbox
+	mockOutbox := new(MockEmailOutbox)
+	handler := NewHandler(mockOutbox, new(MockEmailSuppressions), nil, nil, nil, nil, nil)
+
+	// Create a new HTTP request for an email that was already sent
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/emails/email-1/retry", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("email-1")
+
+	// Set up expectations
+	mockOutbox.On("RetryEmail", mock.Anything, "email-1").
+		Return(nil, apperrors.NewConflictError("only dead-lettered emails can be retried, email is sent"))
+
+	// Call the handler
+	if assert.NoError(t, handler.RetryOutboxEmail(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	}
+
+	// Verify expectations
+	mockOutbox.AssertExpectations(t)
+}
+
+// TestDeleteEmailSuppression tests the DeleteEmailSuppression handler
+func TestDeleteEmailSuppression(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new admin handler with a mock suppression list
+	mockSuppressions := new(MockEmailSuppressions)
+	handler := NewHandler(new(MockEmailOutbox), mockSuppressions, nil, nil, nil, nil, nil)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/emails/suppressions/user%40example.com", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("email")
+	c.SetParamValues("user%40example.com")
+
+	// Set up expectations
+	mockSuppressions.On("RemoveSuppression", mock.Anything, "user@example.com").Return(nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.DeleteEmailSuppression(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	// Verify expectations
+	mockSuppressions.AssertExpectations(t)
+}
+
+// MockAuthorization is a mock implementation of Authorization
+type MockAuthorization struct {
+	mock.Mock
+}
+
+// ListRoles mocks the ListRoles method
+func (m *MockAuthorization) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.Role), args.Error(1)
+}
+
+// ListPermissions mocks the ListPermissions method
+func (m *MockAuthorization) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.Permission), args.Error(1)
+}
+
+// GetAuthorization mocks the GetAuthorization method
+func (m *MockAuthorization) GetAuthorization(ctx context.Context, userID string) (*model.Authorization, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Authorization), args.Error(1)
+}
+
+// AssignRole mocks the AssignRole method
+func (m *MockAuthorization) AssignRole(ctx context.Context, userID, role, assignedBy string) error {
+	args := m.Called(ctx, userID, role, assignedBy)
+	return args.Error(0)
+}
+
+// RevokeRole mocks the RevokeRole method
+func (m *MockAuthorization) RevokeRole(ctx context.Context, userID, role, revokedBy string) error {
+	args := m.Called(ctx, userID, role, revokedBy)
+	return args.Error(0)
+}
+
+// GrantPermission mocks the GrantPermission method
+func (m *MockAuthorization) GrantPermission(ctx context.Context, role, permission, grantedBy string) error {
+	args := m.Called(ctx, role, permission, grantedBy)
+	return args.Error(0)
+}
+
+// RevokePermission mocks the RevokePermission method
+func (m *MockAuthorization) RevokePermission(ctx context.Context, role, permission, revokedBy string) error {
+	args := m.Called(ctx, role, permission, revokedBy)
+	return args.Error(0)
+}
+
+// TestListRoles tests the ListRoles handler
+func TestListRoles(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new admin handler with a mock authorization service
+	mockAuthz := new(MockAuthorization)
+	handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), mockAuthz, nil, nil, nil, nil)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/roles", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set up expectations
+	mockAuthz.On("ListRoles", mock.Anything).Return([]*model.Role{
+		{Name: model.RoleSupport, Description: "Support", Permissions: []string{model.PermissionUsersRead}},
+	}, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.ListRoles(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp RolesResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Len(t, resp.Roles, 1)
+		assert.Equal(t, model.RoleSupport, resp.Roles[0].Name)
+		assert.Equal(t, []string{model.PermissionUsersRead}, resp.Roles[0].Permissions)
+	}
+
+	// Verify expectations
+	mockAuthz.AssertExpectations(t)
+}
+
+// TestAssignUserRole tests the AssignUserRole handler
+func TestAssignUserRole(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/roles", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("user-1")
+		c.Set("user_id", "admin-1")
+		return c, rec
+	}
+
+	t.Run("assigns the role", func(t *testing.T) {
+		mockAuthz := new(MockAuthorization)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), mockAuthz, nil, nil, nil, nil)
+		c, rec := newContext(`{"role":"support"}`)
+
+		// The change is attributed to the admin making it
+		mockAuthz.On("AssignRole", mock.Anything, "user-1", model.RoleSupport, "user:admin-1").Return(nil)
+		mockAuthz.On("GetAuthorization", mock.Anything, "user-1").Return(&model.Authorization{
+			Roles:       []string{model.RoleSupport},
+			Permissions: []string{model.PermissionUsersRead},
+		}, nil)
+
+		if assert.NoError(t, handler.AssignUserRole(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var resp UserRolesResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "user-1", resp.UserID)
+			assert.Equal(t, []string{model.RoleSupport}, resp.Roles)
+		}
+		mockAuthz.AssertExpectations(t)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		mockAuthz := new(MockAuthorization)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), mockAuthz, nil, nil, nil, nil)
+		c, rec := newContext(`{"role":"owner"}`)
+
+		mockAuthz.On("AssignRole", mock.Anything, "user-1", "owner", "user:admin-1").Return(apperrors.NewNotFoundError("role not found"))
+
+		if assert.NoError(t, handler.AssignUserRole(c)) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+		mockAuthz.AssertExpectations(t)
+	})
+
+	t.Run("missing role", func(t *testing.T) {
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), new(MockAuthorization), nil, nil, nil, nil)
+		c, rec := newContext(`{}`)
+
+		if assert.NoError(t, handler.AssignUserRole(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+// TestRevokeUserRole tests the RevokeUserRole handler
+func TestRevokeUserRole(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	newContext := func(userID, role string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/users/"+userID+"/roles/"+role, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "role")
+		c.SetParamValues(userID, role)
+		c.Set("user_id", "admin-1")
+		return c, rec
+	}
+
+	t.Run("revokes the role", func(t *testing.T) {
+		mockAuthz := new(MockAuthorization)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), mockAuthz, nil, nil, nil, nil)
+		c, rec := newContext("user-1", model.RoleAdmin)
+
+		mockAuthz.On("RevokeRole", mock.Anything, "user-1", model.RoleAdmin, "user:admin-1").Return(nil)
+
+		if assert.NoError(t, handler.RevokeUserRole(c)) {
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+		}
+		mockAuthz.AssertExpectations(t)
+	})
+
+	t.Run("own admin role", func(t *testing.T) {
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), new(MockAuthorization), nil, nil, nil, nil)
+		c, rec := newContext("admin-1", model.RoleAdmin)
+
+		if assert.NoError(t, handler.RevokeUserRole(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+// MockUsers is a mock implementation of the user administration service
+type MockUsers struct {
+	mock.Mock
+}
+
+// SearchUsers mocks the SearchUsers method
+func (m *MockUsers) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.AdminUser, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.AdminUser), args.Error(1)
+}
+
+// GetUser mocks the GetUser method
+func (m *MockUsers) GetUser(ctx context.Context, userID string) (*model.UserDetail, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserDetail), args.Error(1)
+}
+
+// ForceLogout mocks the ForceLogout method
+func (m *MockUsers) ForceLogout(ctx context.Context, userID string, actor model.AdminActor) error {
+	args := m.Called(ctx, userID, actor)
+	return args.Error(0)
+}
+
+// LockUser mocks the LockUser method
+func (m *MockUsers) LockUser(ctx context.Context, userID string, until time.Time, reason string, actor model.AdminActor) error {
+	args := m.Called(ctx, userID, until, reason, actor)
+	return args.Error(0)
+}
+
+// UnlockUser mocks the UnlockUser method
+func (m *MockUsers) UnlockUser(ctx context.Context, userID string, actor model.AdminActor) error {
+	args := m.Called(ctx, userID, actor)
+	return args.Error(0)
+}
+
+// ResendVerification mocks the ResendVerification method
+func (m *MockUsers) ResendVerification(ctx context.Context, userID string, actor model.AdminActor) error {
+	args := m.Called(ctx, userID, actor)
+	return args.Error(0)
+}
+
+// DisableUser mocks the DisableUser method
+func (m *MockUsers) DisableUser(ctx context.Context, userID, reason string, actor model.AdminActor) error {
+	args := m.Called(ctx, userID, reason, actor)
+	return args.Error(0)
+}
+
+// EnableUser mocks the EnableUser method
+func (m *MockUsers) EnableUser(ctx context.Context, userID string, actor model.AdminActor) error {
+	args := m.Called(ctx, userID, actor)
+	return args.Error(0)
+}
+
+func (m *MockUsers) StartImpersonation(ctx context.Context, userID, reason string, actor model.AdminActor) (string, *model.Impersonation, error) {
+	args := m.Called(ctx, userID, reason, actor)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*model.Impersonation), args.Error(2)
+}
+
+func (m *MockUsers) StopImpersonation(ctx context.Context, id string, actor model.AdminActor) (*model.Impersonation, error) {
+	args := m.Called(ctx, id, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Impersonation), args.Error(1)
+}
+
+// TestListUsers tests the ListUsers handler
+func TestListUsers(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("filters users", func(t *testing.T) {
+		mockUsers := new(MockUsers)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, mockUsers, nil, nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users?q=john&verified=false&locked=true&created_after=2023-01-01T00:00:00Z&limit=10", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		verified, locked := false, true
+		createdFrom := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		lockedUntil := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+		mockUsers.On("SearchUsers", mock.Anything, model.UserFilter{
+			Query:       "john",
+			Verified:    &verified,
+			Locked:      &locked,
+			CreatedFrom: createdFrom,
+			Limit:       10,
+		}).Return([]*model.AdminUser{
+			{ID: "user-1", Email: "john@example.com", LockedUntil: &lockedUntil, CreatedAt: createdFrom},
+		}, nil)
+
+		if assert.NoError(t, handler.ListUsers(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var resp UsersResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Len(t, resp.Users, 1)
+			assert.Equal(t, "john@example.com", resp.Users[0].Email)
+			assert.Equal(t, "2023-01-02T00:00:00Z", resp.Users[0].LockedUntil)
+			assert.Equal(t, 10, resp.Limit)
+		}
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, new(MockUsers), nil, nil, nil)
+
+		for _, query := range []string{"verified=maybe", "created_before=yesterday", "limit=0"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users?"+query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if assert.NoError(t, handler.ListUsers(c)) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+			}
+		}
+	})
+}
+
+// TestLockUser tests the LockUser handler
+func TestLockUser(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	newContext := func(userID, body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/"+userID+"/lock", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("User-Agent", "admin-console")
+		req.RemoteAddr = "203.0.113.7:1234"
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(userID)
+		c.Set("user_id", "admin-1")
+		return c, rec
+	}
+
+	t.Run("locks the account", func(t *testing.T) {
+		mockUsers := new(MockUsers)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, mockUsers, nil, nil, nil)
+		c, rec := newContext("user-1", `{"until":"2030-01-01T00:00:00Z","reason":" chargeback "}`)
+
+		// The action is attributed to the admin taking it
+		actor := model.AdminActor{ID: "user:admin-1", UserID: "admin-1", IPAddress: "203.0.113.7", UserAgent: "admin-console"}
+		until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockUsers.On("LockUser", mock.Anything, "user-1", until, "chargeback", actor).Return(nil)
+
+		if assert.NoError(t, handler.LockUser(c)) {
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+		}
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockUsers := new(MockUsers)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, mockUsers, nil, nil, nil)
+		c, rec := newContext("user-2", `{"until":"2030-01-01T00:00:00Z","reason":"chargeback"}`)
+
+		mockUsers.On("LockUser", mock.Anything, "user-2", mock.Anything, "chargeback", mock.Anything).Return(apperrors.NewNotFoundError("user not found"))
+
+		if assert.NoError(t, handler.LockUser(c)) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("invalid until", func(t *testing.T) {
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, new(MockUsers), nil, nil, nil)
+		c, rec := newContext("user-1", `{"until":"tomorrow","reason":"chargeback"}`)
+
+		if assert.NoError(t, handler.LockUser(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("own account", func(t *testing.T) {
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, new(MockUsers), nil, nil, nil)
+		c, rec := newContext("admin-1", `{"until":"2030-01-01T00:00:00Z","reason":"chargeback"}`)
+
+		if assert.NoError(t, handler.LockUser(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+// TestEnableUser tests the EnableUser handler
+func TestEnableUser(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockUsers := new(MockUsers)
+	handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, mockUsers, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/enable", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("user-1")
+
+	// Requests made with the admin API key are attributed to it
+	mockUsers.On("EnableUser", mock.Anything, "user-1", mock.MatchedBy(func(actor model.AdminActor) bool {
+		return actor.ID == "admin_key"
+	})).Return(apperrors.NewConflictError("account is not disabled"))
+
+	if assert.NoError(t, handler.EnableUser(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	}
+	mockUsers.AssertExpectations(t)
+}
+
+// TestStartImpersonation tests the StartImpersonation handler
+func TestStartImpersonation(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockUsers := new(MockUsers)
+	handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, mockUsers, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/impersonation", strings.NewReader(`{"reason":" Support ticket "}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("user-1")
+	c.Set("user_id", "admin-1")
+
+	expiresAt := time.Date(2023, 1, 1, 12, 10, 0, 0, time.UTC)
+	mockUsers.On("StartImpersonation", mock.Anything, "user-1", "Support ticket", mock.MatchedBy(func(actor model.AdminActor) bool {
+		return actor.ID == "user:admin-1" && actor.UserID == "admin-1"
+	})).Return("token", &model.Impersonation{ID: "impersonation-1", UserID: "user-1", ActorID: "admin-1", ExpiresAt: expiresAt}, nil)
+
+	if assert.NoError(t, handler.StartImpersonation(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp ImpersonationResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "impersonation-1", resp.ID)
+		assert.Equal(t, "token", resp.AccessToken)
+		assert.Equal(t, "2023-01-01T12:10:00Z", resp.ExpiresAt)
+	}
+	mockUsers.AssertExpectations(t)
+}
+
+// TestStopImpersonation tests the StopImpersonation handler
+func TestStopImpersonation(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockUsers := new(MockUsers)
+	handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, mockUsers, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/impersonations/impersonation-1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("impersonation-1")
+
+	mockUsers.On("StopImpersonation", mock.Anything, "impersonation-1", mock.Anything).
+		Return(nil, apperrors.NewNotFoundError("impersonation not found"))
+
+	if assert.NoError(t, handler.StopImpersonation(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+	mockUsers.AssertExpectations(t)
+}
+
+// MockAuditLogs is a mock implementation of the audit log
+type MockAuditLogs struct {
+	mock.Mock
+}
+
+// ListAuditLogs mocks the ListAuditLogs method
+func (m *MockAuditLogs) ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) (*models.AuditLogPage, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditLogPage), args.Error(1)
+}
+
+// TestListAuditLogs tests the ListAuditLogs handler
+func TestListAuditLogs(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("filters entries", func(t *testing.T) {
+		mockAuditLogs := new(MockAuditLogs)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, mockAuditLogs, nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit-logs?action=organization.renamed&entity_id=org-1&from=2023-01-01T00:00:00Z&limit=10", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		createdAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+		mockAuditLogs.On("ListAuditLogs", mock.Anything, model.AuditLogFilter{
+			Action:   "organization.renamed",
+			EntityID: "org-1",
+			From:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			Limit:    10,
+		}).Return(&models.AuditLogPage{
+			Entries: []*models.AuditLog{
+				{ID: "log-1", Sequence: 7, Actor: "user:user-1", Action: "organization.renamed", Metadata: `{"changes":{"name":{"old":"Acme","new":"Acme Inc"}}}`, CreatedAt: createdAt},
+				{ID: "log-0", Sequence: 1, Action: "login", Metadata: "Login from Chrome on Windows", CreatedAt: createdAt},
+			},
+			NextCursor: "next",
+		}, nil)
+
+		if assert.NoError(t, handler.ListAuditLogs(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var resp AuditLogsResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Len(t, resp.Entries, 2)
+			assert.Equal(t, "user:user-1", resp.Entries[0].Actor)
+			assert.JSONEq(t, `{"changes":{"name":{"old":"Acme","new":"Acme Inc"}}}`, string(resp.Entries[0].Metadata))
+			assert.JSONEq(t, `"Login from Chrome on Windows"`, string(resp.Entries[1].Metadata))
+			assert.Equal(t, "next", resp.NextCursor)
+		}
+		mockAuditLogs.AssertExpectations(t)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, new(MockAuditLogs), nil, nil)
+
+		for _, query := range []string{"from=yesterday", "cursor=not-a-cursor", "limit=500"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit-logs?"+query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if assert.NoError(t, handler.ListAuditLogs(c)) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+			}
+		}
+	})
+}
+
+// MockEventSinks is a mock implementation of the EventSinks interface
+type MockEventSinks struct {
+	mock.Mock
+}
+
+// Health mocks the Health method
+func (m *MockEventSinks) Health() []model.EventSinkHealth {
+	args := m.Called()
+	return args.Get(0).([]model.EventSinkHealth)
+}
+
+func TestListEventSinks(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockSinks := new(MockEventSinks)
+	handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, nil, mockSinks, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/event-sinks", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	failedAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockSinks.On("Health").Return([]model.EventSinkHealth{
+		{Name: "file", Healthy: true, Delivered: 12},
+		{Name: "syslog", Healthy: false, Buffered: 3, ConsecutiveFailures: 2, LastError: "connection refused", LastFailureAt: &failedAt},
+	})
+
+	if assert.NoError(t, handler.ListEventSinks(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp EventSinksResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Len(t, resp.Sinks, 2)
+		assert.Equal(t, int64(12), resp.Sinks[0].Delivered)
+		assert.Empty(t, resp.Sinks[0].LastFailureAt)
+		assert.False(t, resp.Sinks[1].Healthy)
+		assert.Equal(t, "connection refused", resp.Sinks[1].LastError)
+		assert.Equal(t, "2023-01-01T12:00:00Z", resp.Sinks[1].LastFailureAt)
+	}
+	mockSinks.AssertExpectations(t)
+}
+
+// MockWebhookEndpoints is a mock implementation of the WebhookEndpoints interface
+type MockWebhookEndpoints struct {
+	mock.Mock
+}
+
+// CreateEndpoint mocks the CreateEndpoint method
+func (m *MockWebhookEndpoints) CreateEndpoint(ctx context.Context, input model.WebhookEndpointInput, actor model.AdminActor) (string, *model.WebhookEndpoint, error) {
+	args := m.Called(ctx, input, actor)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*model.WebhookEndpoint), args.Error(2)
+}
+
+// ListEndpoints mocks the ListEndpoints method
+func (m *MockWebhookEndpoints) ListEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.WebhookEndpoint), args.Error(1)
+}
+
+// GetEndpoint mocks the GetEndpoint method
+func (m *MockWebhookEndpoints) GetEndpoint(ctx context.Context, id string) (*model.WebhookEndpoint, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookEndpoint), args.Error(1)
+}
+
+// UpdateEndpoint mocks the UpdateEndpoint method
+func (m *MockWebhookEndpoints) UpdateEndpoint(ctx context.Context, id string, update model.WebhookEndpointUpdate, actor model.AdminActor) (*model.WebhookEndpoint, error) {
+	args := m.Called(ctx, id, update, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookEndpoint), args.Error(1)
+}
+
+// DeleteEndpoint mocks the DeleteEndpoint method
+func (m *MockWebhookEndpoints) DeleteEndpoint(ctx context.Context, id string, actor model.AdminActor) error {
+	args := m.Called(ctx, id, actor)
+	return args.Error(0)
+}
+
+// ListDeliveries mocks the ListDeliveries method
+func (m *MockWebhookEndpoints) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.WebhookDelivery), args.Error(1)
+}
+
+// GetDelivery mocks the GetDelivery method
+func (m *MockWebhookEndpoints) GetDelivery(ctx context.Context, endpointID, id string) (*model.WebhookDelivery, []*model.WebhookDeliveryAttempt, error) {
+	args := m.Called(ctx, endpointID, id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*model.WebhookDelivery), args.Get(1).([]*model.WebhookDeliveryAttempt), args.Error(2)
+}
+
+// ReplayDelivery mocks the ReplayDelivery method
+func (m *MockWebhookEndpoints) ReplayDelivery(ctx context.Context, endpointID, id string, actor model.AdminActor) (*model.WebhookDelivery, error) {
+	args := m.Called(ctx, endpointID, id, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookDelivery), args.Error(1)
+}
+
+// TestCreateWebhookEndpoint tests the CreateWebhookEndpoint handler
+func TestCreateWebhookEndpoint(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("returns the secret", func(t *testing.T) {
+		mockWebhooks := new(MockWebhookEndpoints)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, nil, nil, mockWebhooks)
+
+		body := `{"url":"https://crm.example.com/hooks","event_types":["user.registered","user.deleted"]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		createdAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+		mockWebhooks.On("CreateEndpoint", mock.Anything, model.WebhookEndpointInput{
+			URL:        "https://crm.example.com/hooks",
+			EventTypes: []string{model.WebhookEventUserRegistered, model.WebhookEventUserDeleted},
+		}, mock.Anything).Return("whsec_c2VjcmV0", &model.WebhookEndpoint{
+			ID:         "endpoint-1",
+			URL:        "https://crm.example.com/hooks",
+			Secret:     "whsec_c2VjcmV0",
+			EventTypes: []string{model.WebhookEventUserRegistered, model.WebhookEventUserDeleted},
+			Enabled:    true,
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+		}, nil)
+
+		if assert.NoError(t, handler.CreateWebhookEndpoint(c)) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			var resp CreateWebhookEndpointResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "endpoint-1", resp.ID)
+			assert.Equal(t, "whsec_c2VjcmV0", resp.Secret)
+			assert.True(t, resp.Enabled)
+			assert.Equal(t, "2023-01-01T12:00:00Z", resp.CreatedAt)
+		}
+		mockWebhooks.AssertExpectations(t)
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		mockWebhooks := new(MockWebhookEndpoints)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, nil, nil, mockWebhooks)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks", strings.NewReader(`{"url":"ftp://example.com"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockWebhooks.On("CreateEndpoint", mock.Anything, mock.Anything, mock.Anything).
+			Return("", nil, apperrors.NewValidationError("url must be an http or https URL"))
+
+		if assert.NoError(t, handler.CreateWebhookEndpoint(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "url must be an http or https URL")
+		}
+	})
+}
+
+// TestGetWebhookDelivery tests the GetWebhookDelivery handler
+func TestGetWebhookDelivery(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockWebhooks := new(MockWebhookEndpoints)
+	handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, nil, nil, mockWebhooks)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks/endpoint-1/deliveries/delivery-1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "delivery_id")
+	c.SetParamValues("endpoint-1", "delivery-1")
+
+	status, lastError := http.StatusServiceUnavailable, "endpoint responded with status 503"
+	mockWebhooks.On("GetDelivery", mock.Anything, "endpoint-1", "delivery-1").Return(&model.WebhookDelivery{
+		ID:             "delivery-1",
+		EndpointID:     "endpoint-1",
+		EventID:        "evt_1",
+		EventType:      model.WebhookEventUserDeleted,
+		Payload:        `{"id":"evt_1","type":"user.deleted","data":{"user_id":"user-1"}}`,
+		Status:         model.WebhookDeliveryDead,
+		Attempts:       1,
+		LastStatusCode: &status,
+		LastError:      &lastError,
+	}, []*model.WebhookDeliveryAttempt{
+		{Attempt: 1, StatusCode: &status, ResponseBody: "down", Error: &lastError, DurationMS: 12},
+	}, nil)
+
+	if assert.NoError(t, handler.GetWebhookDelivery(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct {
+			WebhookDeliveryItem
+			Payload  map[string]interface{}       `json:"payload"`
+			Attempts []WebhookDeliveryAttemptItem `json:"attempt_log"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, model.WebhookDeliveryDead, resp.Status)
+		assert.Equal(t, "user.deleted", resp.Payload["type"])
+		assert.Len(t, resp.Attempts, 1)
+		assert.Equal(t, lastError, resp.Attempts[0].Error)
+		assert.Equal(t, "down", resp.Attempts[0].ResponseBody)
+	}
+	mockWebhooks.AssertExpectations(t)
+}
+
+// TestReplayWebhookDelivery tests the ReplayWebhookDelivery handler
+func TestReplayWebhookDelivery(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	newContext := func() (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks/endpoint-1/deliveries/delivery-1/replay", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "delivery_id")
+		c.SetParamValues("endpoint-1", "delivery-1")
+		return c, rec
+	}
+
+	t.Run("queues a replay", func(t *testing.T) {
+		mockWebhooks := new(MockWebhookEndpoints)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, nil, nil, mockWebhooks)
+		c, rec := newContext()
+
+		original := "delivery-1"
+		mockWebhooks.On("ReplayDelivery", mock.Anything, "endpoint-1", "delivery-1", mock.Anything).Return(&model.WebhookDelivery{
+			ID:        "delivery-2",
+			EventID:   "evt_1",
+			EventType: model.WebhookEventUserDeleted,
+			Status:    model.WebhookDeliveryPending,
+			ReplayOf:  &original,
+		}, nil)
+
+		if assert.NoError(t, handler.ReplayWebhookDelivery(c)) {
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+
+			var resp WebhookDeliveryItem
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "delivery-2", resp.ID)
+			assert.Equal(t, "delivery-1", resp.ReplayOf)
+		}
+		mockWebhooks.AssertExpectations(t)
+	})
+
+	t.Run("still queued", func(t *testing.T) {
+		mockWebhooks := new(MockWebhookEndpoints)
+		handler := NewHandler(new(MockEmailOutbox), new(MockEmailSuppressions), nil, nil, nil, nil, mockWebhooks)
+		c, rec := newContext()
+
+		mockWebhooks.On("ReplayDelivery", mock.Anything, "endpoint-1", "delivery-1", mock.Anything).
+			Return(nil, apperrors.NewConflictError("only delivered or dead-lettered deliveries can be replayed, delivery is pending"))
+
+		if assert.NoError(t, handler.ReplayWebhookDelivery(c)) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+		}
+	})
+}
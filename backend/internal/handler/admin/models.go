@@ -0,0 +1,283 @@
+package admin
+
+import "encoding/json"
+
+// OutboxEmailItem represents an email in the outbox. Bodies are left out, as
+// they can contain verification and password reset tokens.
+type OutboxEmailItem struct {
+	ID            string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Template      string `json:"template" example:"verification"`
+	To            string `json:"to" example:"user@example.com"`
+	Subject       string `json:"subject" example:"Verify your email address for Go+Next Fullstack App"`
+	Status        string `json:"status" example:"dead"`
+	Attempts      int    `json:"attempts" example:"10"`
+	LastError     string `json:"last_error,omitempty" example:"workflow API returned error status: 503"`
+	NextAttemptAt string `json:"next_attempt_at" example:"2023-01-01T12:00:00Z"`
+	SentAt        string `json:"sent_at,omitempty" example:"2023-01-01T12:00:00Z"`
+	CreatedAt     string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	UpdatedAt     string `json:"updated_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// OutboxEmailsResponse represents a page of outbox emails
+type OutboxEmailsResponse struct {
+	Emails []OutboxEmailItem `json:"emails"`
+	Limit  int               `json:"limit" example:"50"`
+	Offset int               `json:"offset" example:"0"`
+}
+
+// OutboxStatsResponse represents the number of outbox emails in each status
+type OutboxStatsResponse struct {
+	Counts map[string]int `json:"counts"`
+}
+
+// EmailSuppressionItem represents an address on the email suppression list
+type EmailSuppressionItem struct {
+	Email     string `json:"email" example:"user@example.com"`
+	Reason    string `json:"reason" example:"bounce"`
+	Details   string `json:"details,omitempty" example:"Suppressed: Mailbox does not exist"`
+	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// EmailSuppressionsResponse represents a page of suppressed addresses
+type EmailSuppressionsResponse struct {
+	Suppressions []EmailSuppressionItem `json:"suppressions"`
+	Limit        int                    `json:"limit" example:"50"`
+	Offset       int                    `json:"offset" example:"0"`
+}
+
+// RoleItem represents a role and its permissions
+type RoleItem struct {
+	Name        string   `json:"name" example:"support"`
+	Description string   `json:"description" example:"Read-only access to users and their roles"`
+	Permissions []string `json:"permissions" example:"users:read,roles:read"`
+}
+
+// RolesResponse represents every role
+type RolesResponse struct {
+	Roles []RoleItem `json:"roles"`
+}
+
+// PermissionItem represents a permission
+type PermissionItem struct {
+	Name        string `json:"name" example:"users:read"`
+	Description string `json:"description" example:"Look up users"`
+}
+
+// PermissionsResponse represents every permission
+type PermissionsResponse struct {
+	Permissions []PermissionItem `json:"permissions"`
+}
+
+// UserRolesResponse represents the roles of a user and the permissions they grant
+type UserRolesResponse struct {
+	UserID      string   `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Roles       []string `json:"roles" example:"support"`
+	Permissions []string `json:"permissions" example:"users:read,roles:read"`
+}
+
+// AssignRoleRequest represents a request to assign a role to a user
+type AssignRoleRequest struct {
+	Role string `json:"role" example:"support"`
+}
+
+// UserItem represents a user and the state of their account
+type UserItem struct {
+	ID            string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email         string `json:"email" example:"user@example.com"`
+	FullName      string `json:"full_name" example:"John Doe"`
+	AvatarURL     string `json:"avatar_url,omitempty" example:"https://example.com/avatar.jpg"`
+	EmailVerified bool   `json:"email_verified" example:"true"`
+	Locale        string `json:"locale" example:"en"`
+	DisabledAt    string `json:"disabled_at,omitempty" example:"2023-01-01T12:00:00Z"`
+	LockedUntil   string `json:"locked_until,omitempty" example:"2023-01-01T12:00:00Z"`
+	LockReason    string `json:"lock_reason,omitempty" example:"Too many failed login attempts"`
+	CreatedAt     string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	UpdatedAt     string `json:"updated_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// UsersResponse represents a page of users
+type UsersResponse struct {
+	Users  []UserItem `json:"users"`
+	Limit  int        `json:"limit" example:"50"`
+	Offset int        `json:"offset" example:"0"`
+}
+
+// SessionItem represents a session a user is signed in with
+type SessionItem struct {
+	ID        string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	UserAgent string `json:"user_agent,omitempty" example:"Mozilla/5.0"`
+	ClientIP  string `json:"client_ip,omitempty" example:"203.0.113.7"`
+	ExpiresAt string `json:"expires_at" example:"2023-01-08T12:00:00Z"`
+	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// LinkedAccountItem represents an OAuth account linked to a user
+type LinkedAccountItem struct {
+	Provider       string `json:"provider" example:"github"`
+	ProviderUserID string `json:"provider_user_id" example:"583231"`
+	CreatedAt      string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// UserDetailResponse represents a user with their sessions and linked accounts
+type UserDetailResponse struct {
+	User           UserItem            `json:"user"`
+	Sessions       []SessionItem       `json:"sessions"`
+	LinkedAccounts []LinkedAccountItem `json:"linked_accounts"`
+}
+
+// LockUserRequest represents a request to lock a user's account
+type LockUserRequest struct {
+	Until  string `json:"until" example:"2023-01-02T12:00:00Z"`
+	Reason string `json:"reason" example:"Chargeback under investigation"`
+}
+
+// DisableUserRequest represents a request to disable a user's account
+type DisableUserRequest struct {
+	Reason string `json:"reason" example:"Spam"`
+}
+
+// StartImpersonationRequest represents a request to impersonate a user
+type StartImpersonationRequest struct {
+	Reason string `json:"reason" example:"Support ticket #1234"`
+}
+
+// ImpersonationResponse represents a started impersonation
+type ImpersonationResponse struct {
+	ID          string `json:"id" example:"1672574400000000000"`
+	UserID      string `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	AccessToken string `json:"access_token" example:"v2.local.Gdh5kiOTyyaQ3_bNykYDeYHO21Jg2..."`
+	ExpiresAt   string `json:"expires_at" example:"2023-01-01T12:10:00Z"`
+}
+
+// AuditLogItem represents an entry of the audit log
+type AuditLogItem struct {
+	ID               string          `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Sequence         int64           `json:"sequence" example:"1042"`
+	Actor            string          `json:"actor" example:"user:123e4567-e89b-12d3-a456-426614174000"`
+	UserID           string          `json:"user_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ServiceAccountID string          `json:"service_account_id,omitempty" example:"223e4567-e89b-12d3-a456-426614174000"`
+	Action           string          `json:"action" example:"organization.renamed"`
+	EntityType       string          `json:"entity_type" example:"organization"`
+	EntityID         string          `json:"entity_id" example:"323e4567-e89b-12d3-a456-426614174000"`
+	Metadata         json.RawMessage `json:"metadata" swaggertype:"object"`
+	RequestID        string          `json:"request_id,omitempty" example:"kPkNwGLHwqQlnVmSjaxnOZMnUGIgPSKt"`
+	IPAddress        string          `json:"ip_address,omitempty" example:"203.0.113.7"`
+	CreatedAt        string          `json:"created_at" example:"2023-01-01T12:00:00.123456Z"`
+	Hash             string          `json:"hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// AuditLogsResponse represents a page of audit log entries
+type AuditLogsResponse struct {
+	Entries    []AuditLogItem `json:"entries"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"MjAyMy0wMS0wMVQxMjowMDowMFp8MTIz"`
+}
+
+// EventSinkItem represents the health of a sink audit log entries and
+// security events are streamed to
+type EventSinkItem struct {
+	Name                string `json:"name" example:"syslog"`
+	Healthy             bool   `json:"healthy" example:"true"`
+	Buffered            int    `json:"buffered" example:"0"`
+	Delivered           int64  `json:"delivered" example:"18342"`
+	Dropped             int64  `json:"dropped" example:"0"`
+	ConsecutiveFailures int    `json:"consecutive_failures" example:"0"`
+	LastError           string `json:"last_error,omitempty" example:"failed to connect to syslog server: connection refused"`
+	LastSuccessAt       string `json:"last_success_at,omitempty" example:"2023-01-01T12:00:00Z"`
+	LastFailureAt       string `json:"last_failure_at,omitempty" example:"2023-01-01T11:58:00Z"`
+}
+
+// EventSinksResponse represents the health of every event sink
+type EventSinksResponse struct {
+	Sinks []EventSinkItem `json:"sinks"`
+}
+
+// CreateWebhookEndpointRequest represents a request to register a webhook
+// endpoint
+type CreateWebhookEndpointRequest struct {
+	URL         string   `json:"url" example:"https://crm.example.com/hooks/identity"`
+	EventTypes  []string `json:"event_types" example:"user.registered,user.deleted"`
+	Description string   `json:"description" example:"CRM sync"`
+	// "whsec_" secret to sign requests with, generated when empty
+	Secret string `json:"secret,omitempty" example:"whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"`
+}
+
+// UpdateWebhookEndpointRequest represents a request to change the settings
+// of a webhook endpoint. Settings that are left out are not changed.
+type UpdateWebhookEndpointRequest struct {
+	URL         *string  `json:"url" example:"https://crm.example.com/hooks/identity"`
+	EventTypes  []string `json:"event_types" example:"user.registered,user.deleted"`
+	Description *string  `json:"description" example:"CRM sync"`
+	Enabled     *bool    `json:"enabled" example:"false"`
+}
+
+// WebhookEndpointItem represents a webhook endpoint
+type WebhookEndpointItem struct {
+	ID          string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	URL         string   `json:"url" example:"https://crm.example.com/hooks/identity"`
+	EventTypes  []string `json:"event_types" example:"user.registered,user.deleted"`
+	Description string   `json:"description,omitempty" example:"CRM sync"`
+	Enabled     bool     `json:"enabled" example:"true"`
+	CreatedBy   string   `json:"created_by,omitempty" example:"223e4567-e89b-12d3-a456-426614174000"`
+	CreatedAt   string   `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	UpdatedAt   string   `json:"updated_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// CreateWebhookEndpointResponse represents a registered webhook endpoint
+// with its secret, which can't be read again
+type CreateWebhookEndpointResponse struct {
+	WebhookEndpointItem
+	Secret string `json:"secret" example:"whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"`
+}
+
+// WebhookEndpointsResponse represents a list of webhook endpoints
+type WebhookEndpointsResponse struct {
+	Endpoints []WebhookEndpointItem `json:"endpoints"`
+}
+
+// WebhookDeliveryItem represents an event queued for, or delivered to, a
+// webhook endpoint
+type WebhookDeliveryItem struct {
+	ID             string `json:"id" example:"323e4567-e89b-12d3-a456-426614174000"`
+	EventID        string `json:"event_id" example:"evt_2f1c9a7e5b3d4c8f9e0a1b2c3d4e5f60"`
+	EventType      string `json:"event_type" example:"user.registered"`
+	Status         string `json:"status" example:"dead"`
+	Attempts       int    `json:"attempts" example:"8"`
+	LastStatusCode *int   `json:"last_status_code,omitempty" example:"503"`
+	LastError      string `json:"last_error,omitempty" example:"endpoint responded with status 503"`
+	NextAttemptAt  string `json:"next_attempt_at" example:"2023-01-01T12:00:00Z"`
+	DeliveredAt    string `json:"delivered_at,omitempty" example:"2023-01-01T12:00:01Z"`
+	ReplayOf       string `json:"replay_of,omitempty" example:"423e4567-e89b-12d3-a456-426614174000"`
+	CreatedAt      string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	UpdatedAt      string `json:"updated_at" example:"2023-01-01T12:00:01Z"`
+}
+
+// WebhookDeliveriesResponse represents a page of the deliveries of a webhook
+// endpoint
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryItem `json:"deliveries"`
+	Limit      int                   `json:"limit" example:"50"`
+	Offset     int                   `json:"offset" example:"0"`
+}
+
+// WebhookDeliveryAttemptItem represents a request made to deliver an event
+type WebhookDeliveryAttemptItem struct {
+	Attempt      int    `json:"attempt" example:"1"`
+	StatusCode   *int   `json:"status_code,omitempty" example:"503"`
+	ResponseBody string `json:"response_body,omitempty" example:"Service Unavailable"`
+	Error        string `json:"error,omitempty" example:"endpoint responded with status 503"`
+	DurationMS   int64  `json:"duration_ms" example:"120"`
+	CreatedAt    string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// WebhookDeliveryResponse represents a delivery with the payload sent and
+// the attempts made
+type WebhookDeliveryResponse struct {
+	WebhookDeliveryItem
+	Payload  json.RawMessage              `json:"payload" swaggertype:"object"`
+	Attempts []WebhookDeliveryAttemptItem `json:"attempt_log"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"Email not found"`
+}
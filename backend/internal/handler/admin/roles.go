@@ -0,0 +1,252 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/middleware"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// Authorization defines the interface for managing roles and permissions
+type Authorization interface {
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+	GetAuthorization(ctx context.Context, userID string) (*model.Authorization, error)
+	AssignRole(ctx context.Context, userID, role, assignedBy string) error
+	RevokeRole(ctx context.Context, userID, role, revokedBy string) error
+	GrantPermission(ctx context.Context, role, permission, grantedBy string) error
+	RevokePermission(ctx context.Context, role, permission, revokedBy string) error
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List every role with its permissions
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Success 200 {object} RolesResponse "Roles"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/roles [get]
+func (h *Handler) ListRoles(c echo.Context) error {
+	roles, err := h.authorization.ListRoles(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list roles"))
+	}
+
+	items := make([]RoleItem, len(roles))
+	for i, role := range roles {
+		items[i] = RoleItem{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: role.Permissions,
+		}
+	}
+
+	return c.JSON(http.StatusOK, RolesResponse{Roles: items})
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description List every permission that can be granted to roles
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Success 200 {object} PermissionsResponse "Permissions"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/permissions [get]
+func (h *Handler) ListPermissions(c echo.Context) error {
+	permissions, err := h.authorization.ListPermissions(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list permissions"))
+	}
+
+	items := make([]PermissionItem, len(permissions))
+	for i, permission := range permissions {
+		items[i] = PermissionItem{
+			Name:        permission.Name,
+			Description: permission.Description,
+		}
+	}
+
+	return c.JSON(http.StatusOK, PermissionsResponse{Permissions: items})
+}
+
+// GetUserRoles godoc
+// @Summary Get a user's roles
+// @Description Get the roles of a user and the permissions they grant
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} UserRolesResponse "User roles"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/roles [get]
+func (h *Handler) GetUserRoles(c echo.Context) error {
+	userID := c.Param("id")
+
+	authz, err := h.authorization.GetAuthorization(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get user roles"))
+	}
+
+	return c.JSON(http.StatusOK, newUserRolesResponse(userID, authz))
+}
+
+// AssignUserRole godoc
+// @Summary Assign a role to a user
+// @Description Assign a role to a user. Assigning a role the user already has does nothing.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body AssignRoleRequest true "Role to assign"
+// @Success 200 {object} UserRolesResponse "User roles"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Role not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/roles [post]
+func (h *Handler) AssignUserRole(c echo.Context) error {
+	userID := c.Param("id")
+
+	var req AssignRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+	role := strings.TrimSpace(req.Role)
+	if role == "" {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("role is required"))
+	}
+
+	ctx := c.Request().Context()
+	if err := h.authorization.AssignRole(ctx, userID, role, adminActor(c)); err != nil {
+		return roleError(c, err, "Failed to assign role")
+	}
+
+	authz, err := h.authorization.GetAuthorization(ctx, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get user roles"))
+	}
+
+	return c.JSON(http.StatusOK, newUserRolesResponse(userID, authz))
+}
+
+// RevokeUserRole godoc
+// @Summary Revoke a role from a user
+// @Description Remove a role from a user. Admins can't revoke their own admin role.
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param role path string true "Role name"
+// @Success 204 "Role revoked"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User does not have the role"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/roles/{role} [delete]
+func (h *Handler) RevokeUserRole(c echo.Context) error {
+	userID := c.Param("id")
+	role := c.Param("role")
+
+	// Keep admins from locking themselves out of the admin API
+	if currentUser, _ := c.Get("user_id").(string); currentUser == userID && role == model.RoleAdmin {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("You can't revoke your own admin role"))
+	}
+
+	if err := h.authorization.RevokeRole(c.Request().Context(), userID, role, adminActor(c)); err != nil {
+		return roleError(c, err, "Failed to revoke role")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GrantRolePermission godoc
+// @Summary Grant a permission to a role
+// @Description Grant a permission to every user with a role. Granting a permission the role already has does nothing.
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param role path string true "Role name"
+// @Param permission path string true "Permission name"
+// @Success 204 "Permission granted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Role or permission not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/roles/{role}/permissions/{permission} [put]
+func (h *Handler) GrantRolePermission(c echo.Context) error {
+	if err := h.authorization.GrantPermission(c.Request().Context(), c.Param("role"), c.Param("permission"), adminActor(c)); err != nil {
+		return roleError(c, err, "Failed to grant permission")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeRolePermission godoc
+// @Summary Revoke a permission from a role
+// @Description Remove a permission from a role. The permissions of the admin role can't be revoked.
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param role path string true "Role name"
+// @Param permission path string true "Permission name"
+// @Success 204 "Permission revoked"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Role does not have the permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/roles/{role}/permissions/{permission} [delete]
+func (h *Handler) RevokeRolePermission(c echo.Context) error {
+	if err := h.authorization.RevokePermission(c.Request().Context(), c.Param("role"), c.Param("permission"), adminActor(c)); err != nil {
+		return roleError(c, err, "Failed to revoke permission")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// roleError responds to a failed role or permission change
+func roleError(c echo.Context, err error, message string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound:
+			return c.JSON(appErr.StatusCode, response.NewErrorResponse(appErr.Message))
+		}
+	}
+	return c.JSON(http.StatusInternalServerError, response.NewErrorResponse(message))
+}
+
+// adminActor identifies who made an admin request, for auditing
+func adminActor(c echo.Context) string {
+	return middleware.GetAdminActor(c).ID
+}
+
+// newUserRolesResponse converts a user's authorization to its response model
+func newUserRolesResponse(userID string, authz *model.Authorization) UserRolesResponse {
+	return UserRolesResponse{
+		UserID:      userID,
+		Roles:       authz.Roles,
+		Permissions: authz.Permissions,
+	}
+}
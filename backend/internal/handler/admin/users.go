@@ -0,0 +1,435 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/middleware"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/useradmin"
+)
+
+// Users defines the interface for finding users and managing their accounts
+type Users interface {
+	SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.AdminUser, error)
+	GetUser(ctx context.Context, userID string) (*model.UserDetail, error)
+	ForceLogout(ctx context.Context, userID string, actor model.AdminActor) error
+	LockUser(ctx context.Context, userID string, until time.Time, reason string, actor model.AdminActor) error
+	UnlockUser(ctx context.Context, userID string, actor model.AdminActor) error
+	ResendVerification(ctx context.Context, userID string, actor model.AdminActor) error
+	DisableUser(ctx context.Context, userID, reason string, actor model.AdminActor) error
+	EnableUser(ctx context.Context, userID string, actor model.AdminActor) error
+	StartImpersonation(ctx context.Context, userID, reason string, actor model.AdminActor) (string, *model.Impersonation, error)
+	StopImpersonation(ctx context.Context, id string, actor model.AdminActor) (*model.Impersonation, error)
+}
+
+// ListUsers godoc
+// @Summary Search users
+// @Description Search users by email or name, newest first
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param q query string false "Only include users whose email or name contains this text"
+// @Param verified query bool false "Only include users whose email is (or isn't) verified"
+// @Param locked query bool false "Only include users whose account is (or isn't) locked"
+// @Param disabled query bool false "Only include users whose account is (or isn't) disabled"
+// @Param created_after query string false "Only include users created at or after this time (RFC 3339)"
+// @Param created_before query string false "Only include users created before this time (RFC 3339)"
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Param offset query int false "Number of users to skip"
+// @Success 200 {object} UsersResponse "Users"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users [get]
+func (h *Handler) ListUsers(c echo.Context) error {
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+
+	users, err := h.users.SearchUsers(c.Request().Context(), filter)
+	if err != nil {
+		return userError(c, err, "Failed to search users")
+	}
+
+	items := make([]UserItem, len(users))
+	for i, u := range users {
+		items[i] = newUserItem(u)
+	}
+
+	return c.JSON(http.StatusOK, UsersResponse{
+		Users:  items,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	})
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Description Get a user with their active sessions and linked accounts
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} UserDetailResponse "User"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id} [get]
+func (h *Handler) GetUser(c echo.Context) error {
+	detail, err := h.users.GetUser(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return userError(c, err, "Failed to get user")
+	}
+
+	resp := UserDetailResponse{
+		User:           newUserItem(detail.User),
+		Sessions:       make([]SessionItem, len(detail.Sessions)),
+		LinkedAccounts: make([]LinkedAccountItem, len(detail.LinkedAccounts)),
+	}
+	for i, s := range detail.Sessions {
+		resp.Sessions[i] = SessionItem{
+			ID:        s.ID,
+			ExpiresAt: s.ExpiresAt.UTC().Format(time.RFC3339),
+			CreatedAt: s.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if s.UserAgent != nil {
+			resp.Sessions[i].UserAgent = *s.UserAgent
+		}
+		if s.ClientIP != nil {
+			resp.Sessions[i].ClientIP = *s.ClientIP
+		}
+	}
+	for i, a := range detail.LinkedAccounts {
+		resp.LinkedAccounts[i] = LinkedAccountItem{
+			Provider:       a.Provider,
+			ProviderUserID: a.ProviderUserID,
+			CreatedAt:      a.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ForceLogoutUser godoc
+// @Summary Sign a user out
+// @Description Revoke every access and refresh token of a user and delete their sessions
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 "User signed out"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/logout [post]
+func (h *Handler) ForceLogoutUser(c echo.Context) error {
+	if err := h.users.ForceLogout(c.Request().Context(), c.Param("id"), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to sign user out")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LockUser godoc
+// @Summary Lock a user's account
+// @Description Lock a user's account until a time and sign them out. Admins can't lock their own account.
+// @Tags admin
+// @Accept json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body LockUserRequest true "End and reason of the lock"
+// @Success 204 "Account locked"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/lock [post]
+func (h *Handler) LockUser(c echo.Context) error {
+	userID := c.Param("id")
+	if isCurrentUser(c, userID) {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("You can't lock your own account"))
+	}
+
+	var req LockUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+	until, err := time.Parse(time.RFC3339, req.Until)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("until must be an RFC 3339 time"))
+	}
+
+	if err := h.users.LockUser(c.Request().Context(), userID, until, strings.TrimSpace(req.Reason), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to lock account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user's account
+// @Description Unlock a locked account before its lock ends
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 "Account unlocked"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 409 {object} ErrorResponse "Account is not locked"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (h *Handler) UnlockUser(c echo.Context) error {
+	if err := h.users.UnlockUser(c.Request().Context(), c.Param("id"), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to unlock account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResendUserVerification godoc
+// @Summary Resend a verification email
+// @Description Send a new verification email to a user who hasn't verified their email
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 "Verification email sent"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 409 {object} ErrorResponse "Email is already verified"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/verification-email [post]
+func (h *Handler) ResendUserVerification(c echo.Context) error {
+	if err := h.users.ResendVerification(c.Request().Context(), c.Param("id"), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to send verification email")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DisableUser godoc
+// @Summary Disable a user's account
+// @Description Keep a user from signing in until their account is enabled again, and sign them out. Their data is kept. Admins can't disable their own account.
+// @Tags admin
+// @Accept json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body DisableUserRequest true "Reason for disabling the account"
+// @Success 204 "Account disabled"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 409 {object} ErrorResponse "Account is already disabled"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/disable [post]
+func (h *Handler) DisableUser(c echo.Context) error {
+	userID := c.Param("id")
+	if isCurrentUser(c, userID) {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("You can't disable your own account"))
+	}
+
+	var req DisableUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	if err := h.users.DisableUser(c.Request().Context(), userID, strings.TrimSpace(req.Reason), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to disable account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// EnableUser godoc
+// @Summary Enable a user's account
+// @Description Let a disabled user sign in again
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 "Account enabled"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 409 {object} ErrorResponse "Account is not disabled"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/enable [post]
+func (h *Handler) EnableUser(c echo.Context) error {
+	if err := h.users.EnableUser(c.Request().Context(), c.Param("id"), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to enable account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parseUserFilter parses the query parameters of a user search
+func parseUserFilter(c echo.Context) (model.UserFilter, error) {
+	filter := model.UserFilter{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+		Limit: useradmin.DefaultPageSize,
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Limit, filter.Offset = limit, offset
+
+	for name, dest := range map[string]**bool{
+		"verified": &filter.Verified,
+		"locked":   &filter.Locked,
+		"disabled": &filter.Disabled,
+	} {
+		if value := c.QueryParam(name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return filter, errors.New(name + " must be true or false")
+			}
+			*dest = &b
+		}
+	}
+
+	for name, dest := range map[string]*time.Time{
+		"created_after":  &filter.CreatedFrom,
+		"created_before": &filter.CreatedTo,
+	} {
+		if value := c.QueryParam(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New(name + " must be an RFC 3339 time")
+			}
+			*dest = t
+		}
+	}
+
+	return filter, nil
+}
+
+// StartImpersonation godoc
+// @Summary Impersonate a user
+// @Description Get a short-lived access token to act as a user, to see what they see. The token can't be refreshed, can't be used on the admin API or to change the user's credentials, and is recorded in the audit log and the user's security events. Only users can impersonate, not the admin API key.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body StartImpersonationRequest true "Reason for the impersonation"
+// @Success 201 {object} ImpersonationResponse "Impersonation started"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 409 {object} ErrorResponse "Account is disabled"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/users/{id}/impersonation [post]
+func (h *Handler) StartImpersonation(c echo.Context) error {
+	var req StartImpersonationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	token, impersonation, err := h.users.StartImpersonation(c.Request().Context(), c.Param("id"), strings.TrimSpace(req.Reason), newAdminActor(c))
+	if err != nil {
+		return userError(c, err, "Failed to start impersonation")
+	}
+
+	return c.JSON(http.StatusCreated, ImpersonationResponse{
+		ID:          impersonation.ID,
+		UserID:      impersonation.UserID,
+		AccessToken: token,
+		ExpiresAt:   impersonation.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// StopImpersonation godoc
+// @Summary Stop an impersonation
+// @Description Revoke the access token of an impersonation right away
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Impersonation ID"
+// @Success 204 "Impersonation stopped"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Impersonation not found or already ended"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/impersonations/{id} [delete]
+func (h *Handler) StopImpersonation(c echo.Context) error {
+	if _, err := h.users.StopImpersonation(c.Request().Context(), c.Param("id"), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to stop impersonation")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// userError responds to a failed user search or account change
+func userError(c echo.Context, err error, message string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+			return c.JSON(appErr.StatusCode, response.NewErrorResponse(appErr.Message))
+		}
+	}
+	return c.JSON(http.StatusInternalServerError, response.NewErrorResponse(message))
+}
+
+// isCurrentUser reports whether a request was made by the user it acts on
+func isCurrentUser(c echo.Context, userID string) bool {
+	currentUser, _ := c.Get("user_id").(string)
+	return currentUser == userID
+}
+
+// newAdminActor identifies who made an admin request, and from where
+func newAdminActor(c echo.Context) model.AdminActor {
+	return middleware.GetAdminActor(c)
+}
+
+// newUserItem converts a user to its response model
+func newUserItem(u *model.AdminUser) UserItem {
+	item := UserItem{
+		ID:            u.ID,
+		Email:         u.Email,
+		FullName:      u.FullName,
+		EmailVerified: u.EmailVerified,
+		Locale:        u.Locale,
+		CreatedAt:     u.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:     u.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+
+	if u.AvatarURL != nil {
+		item.AvatarURL = *u.AvatarURL
+	}
+	if u.DisabledAt != nil {
+		item.DisabledAt = u.DisabledAt.UTC().Format(time.RFC3339)
+	}
+	if u.LockedUntil != nil {
+		item.LockedUntil = u.LockedUntil.UTC().Format(time.RFC3339)
+	}
+	if u.LockReason != nil {
+		item.LockReason = *u.LockReason
+	}
+
+	return item
+}
@@ -0,0 +1,327 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// WebhookEndpoints defines the interface for managing the webhook endpoints
+// notified of identity lifecycle events, and their deliveries
+type WebhookEndpoints interface {
+	CreateEndpoint(ctx context.Context, input model.WebhookEndpointInput, actor model.AdminActor) (string, *model.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, id string) (*model.WebhookEndpoint, error)
+	UpdateEndpoint(ctx context.Context, id string, update model.WebhookEndpointUpdate, actor model.AdminActor) (*model.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id string, actor model.AdminActor) error
+	ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, endpointID, id string) (*model.WebhookDelivery, []*model.WebhookDeliveryAttempt, error)
+	ReplayDelivery(ctx context.Context, endpointID, id string, actor model.AdminActor) (*model.WebhookDelivery, error)
+}
+
+// CreateWebhookEndpoint godoc
+// @Summary Register a webhook endpoint
+// @Description Register a URL to be notified of identity lifecycle events (user.registered, user.verified, user.email_changed, user.deleted, user.locked, user.unlocked). The secret requests are signed with is only returned here.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param request body CreateWebhookEndpointRequest true "Endpoint settings"
+// @Success 201 {object} CreateWebhookEndpointResponse "Endpoint registered"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks [post]
+func (h *Handler) CreateWebhookEndpoint(c echo.Context) error {
+	var req CreateWebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	secret, endpoint, err := h.webhooks.CreateEndpoint(c.Request().Context(), model.WebhookEndpointInput{
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Secret:      req.Secret,
+	}, newAdminActor(c))
+	if err != nil {
+		return userError(c, err, "Failed to register webhook endpoint")
+	}
+
+	return c.JSON(http.StatusCreated, CreateWebhookEndpointResponse{
+		WebhookEndpointItem: newWebhookEndpointItem(endpoint),
+		Secret:              secret,
+	})
+}
+
+// ListWebhookEndpoints godoc
+// @Summary List webhook endpoints
+// @Description List the registered webhook endpoints, oldest first
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Success 200 {object} WebhookEndpointsResponse "Webhook endpoints"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks [get]
+func (h *Handler) ListWebhookEndpoints(c echo.Context) error {
+	endpoints, err := h.webhooks.ListEndpoints(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list webhook endpoints"))
+	}
+
+	items := make([]WebhookEndpointItem, len(endpoints))
+	for i, endpoint := range endpoints {
+		items[i] = newWebhookEndpointItem(endpoint)
+	}
+
+	return c.JSON(http.StatusOK, WebhookEndpointsResponse{Endpoints: items})
+}
+
+// GetWebhookEndpoint godoc
+// @Summary Get a webhook endpoint
+// @Description Get the settings of a webhook endpoint
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} WebhookEndpointItem "Webhook endpoint"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Webhook endpoint not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks/{id} [get]
+func (h *Handler) GetWebhookEndpoint(c echo.Context) error {
+	endpoint, err := h.webhooks.GetEndpoint(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return userError(c, err, "Failed to get webhook endpoint")
+	}
+
+	return c.JSON(http.StatusOK, newWebhookEndpointItem(endpoint))
+}
+
+// UpdateWebhookEndpoint godoc
+// @Summary Update a webhook endpoint
+// @Description Change the URL, event types, description of a webhook endpoint, or disable it. Queued deliveries to a disabled endpoint are dead-lettered.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param request body UpdateWebhookEndpointRequest true "Settings to change"
+// @Success 200 {object} WebhookEndpointItem "Webhook endpoint updated"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Webhook endpoint not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks/{id} [patch]
+func (h *Handler) UpdateWebhookEndpoint(c echo.Context) error {
+	var req UpdateWebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	endpoint, err := h.webhooks.UpdateEndpoint(c.Request().Context(), c.Param("id"), model.WebhookEndpointUpdate{
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+	}, newAdminActor(c))
+	if err != nil {
+		return userError(c, err, "Failed to update webhook endpoint")
+	}
+
+	return c.JSON(http.StatusOK, newWebhookEndpointItem(endpoint))
+}
+
+// DeleteWebhookEndpoint godoc
+// @Summary Delete a webhook endpoint
+// @Description Stop notifying a webhook endpoint, dropping its queued deliveries and delivery log
+// @Tags admin
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 204 "Webhook endpoint deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Webhook endpoint not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks/{id} [delete]
+func (h *Handler) DeleteWebhookEndpoint(c echo.Context) error {
+	if err := h.webhooks.DeleteEndpoint(c.Request().Context(), c.Param("id"), newAdminActor(c)); err != nil {
+		return userError(c, err, "Failed to delete webhook endpoint")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the events queued for, or delivered to, a webhook endpoint, newest first
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param status query string false "Only include deliveries with this status (pending, sending, delivered or dead)"
+// @Param event_type query string false "Only include deliveries of this event type"
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Param offset query int false "Number of deliveries to skip"
+// @Success 200 {object} WebhookDeliveriesResponse "Webhook deliveries"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Webhook endpoint not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks/{id}/deliveries [get]
+func (h *Handler) ListWebhookDeliveries(c echo.Context) error {
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+
+	filter := model.WebhookDeliveryFilter{
+		EndpointID: c.Param("id"),
+		Status:     c.QueryParam("status"),
+		EventType:  c.QueryParam("event_type"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(c.Request().Context(), filter)
+	if err != nil {
+		return userError(c, err, "Failed to list webhook deliveries")
+	}
+
+	items := make([]WebhookDeliveryItem, len(deliveries))
+	for i, delivery := range deliveries {
+		items[i] = newWebhookDeliveryItem(delivery)
+	}
+
+	return c.JSON(http.StatusOK, WebhookDeliveriesResponse{
+		Deliveries: items,
+		Limit:      limit,
+		Offset:     offset,
+	})
+}
+
+// GetWebhookDelivery godoc
+// @Summary Get a webhook delivery
+// @Description Get a delivery with the payload sent and the outcome of every attempt
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 200 {object} WebhookDeliveryResponse "Webhook delivery"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Webhook delivery not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks/{id}/deliveries/{delivery_id} [get]
+func (h *Handler) GetWebhookDelivery(c echo.Context) error {
+	delivery, attempts, err := h.webhooks.GetDelivery(c.Request().Context(), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		return userError(c, err, "Failed to get webhook delivery")
+	}
+
+	resp := WebhookDeliveryResponse{
+		WebhookDeliveryItem: newWebhookDeliveryItem(delivery),
+		Payload:             json.RawMessage(delivery.Payload),
+		Attempts:            make([]WebhookDeliveryAttemptItem, len(attempts)),
+	}
+	for i, a := range attempts {
+		resp.Attempts[i] = WebhookDeliveryAttemptItem{
+			Attempt:      a.Attempt,
+			StatusCode:   a.StatusCode,
+			ResponseBody: a.ResponseBody,
+			DurationMS:   a.DurationMS,
+			CreatedAt:    a.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if a.Error != nil {
+			resp.Attempts[i].Error = *a.Error
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary Replay a webhook delivery
+// @Description Queue a delivered or dead-lettered event for the endpoint again, with a fresh set of attempts. The replay keeps the event's webhook-id.
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} WebhookDeliveryItem "Replay queued"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Webhook delivery not found"
+// @Failure 409 {object} ErrorResponse "Delivery is still queued"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *Handler) ReplayWebhookDelivery(c echo.Context) error {
+	replay, err := h.webhooks.ReplayDelivery(c.Request().Context(), c.Param("id"), c.Param("delivery_id"), newAdminActor(c))
+	if err != nil {
+		return userError(c, err, "Failed to replay webhook delivery")
+	}
+
+	return c.JSON(http.StatusAccepted, newWebhookDeliveryItem(replay))
+}
+
+// newWebhookEndpointItem converts a webhook endpoint to its response model
+func newWebhookEndpointItem(e *model.WebhookEndpoint) WebhookEndpointItem {
+	item := WebhookEndpointItem{
+		ID:          e.ID,
+		URL:         e.URL,
+		EventTypes:  e.EventTypes,
+		Description: e.Description,
+		Enabled:     e.Enabled,
+		CreatedAt:   e.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:   e.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if e.CreatedBy != nil {
+		item.CreatedBy = *e.CreatedBy
+	}
+
+	return item
+}
+
+// newWebhookDeliveryItem converts a webhook delivery to its response model
+func newWebhookDeliveryItem(d *model.WebhookDelivery) WebhookDeliveryItem {
+	item := WebhookDeliveryItem{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		NextAttemptAt:  d.NextAttemptAt.UTC().Format(time.RFC3339),
+		DeliveredAt:    formatOptionalTime(d.DeliveredAt),
+		CreatedAt:      d.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:      d.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if d.LastError != nil {
+		item.LastError = *d.LastError
+	}
+	if d.ReplayOf != nil {
+		item.ReplayOf = *d.ReplayOf
+	}
+
+	return item
+}
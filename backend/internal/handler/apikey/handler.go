@@ -0,0 +1,138 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// APIKeys defines the interface for managing the API keys of users
+type APIKeys interface {
+	CreateAPIKey(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (string, *model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string) error
+}
+
+// Handler handles API key requests
+type Handler struct {
+	apiKeys APIKeys
+}
+
+// NewHandler creates a new API key handler
+func NewHandler(apiKeys APIKeys) *Handler {
+	return &Handler{
+		apiKeys: apiKeys,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key for scripts and CI, sent as "Authorization: Bearer <key>". Keys with read scope can only make GET requests; keys with write scope can make any request. Permissions of the user can be added as scopes for the admin API. The key is only returned once. Not available with an API key or while impersonating.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Key to create"
+// @Success 201 {object} CreateAPIKeyResponse "Created API key"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed with an API key or while impersonating"
+// @Failure 409 {object} ErrorResponse "Too many API keys"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/users/me/api-keys [post]
+func (h *Handler) CreateAPIKey(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	var req CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	secret, key, err := h.apiKeys.CreateAPIKey(c.Request().Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to create API key")
+	}
+
+	return c.JSON(http.StatusCreated, CreateAPIKeyResponse{
+		APIKeyItem: newAPIKeyItem(key),
+		Key:        secret,
+	})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of the current user, newest first, with when each was last used
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIKeysResponse "API keys"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/users/me/api-keys [get]
+func (h *Handler) ListAPIKeys(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	keys, err := h.apiKeys.ListAPIKeys(c.Request().Context(), userID)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to list API keys")
+	}
+
+	items := make([]APIKeyItem, len(keys))
+	for i, key := range keys {
+		items[i] = newAPIKeyItem(key)
+	}
+
+	return c.JSON(http.StatusOK, APIKeysResponse{APIKeys: items})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Delete an API key of the current user, so it stops working right away. Not available with an API key or while impersonating.
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 "API key revoked"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed with an API key or while impersonating"
+// @Failure 404 {object} ErrorResponse "API key not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/users/me/api-keys/{id} [delete]
+func (h *Handler) RevokeAPIKey(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	if err := h.apiKeys.RevokeAPIKey(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return apiKeyError(c, err, "Failed to revoke API key")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterRoutes registers the API key routes. Creating and revoking keys
+// runs the sensitive middleware, so it needs the user to be signed in.
+func (h *Handler) RegisterRoutes(g *echo.Group, sensitive echo.MiddlewareFunc) {
+	g.POST("", h.CreateAPIKey, sensitive)
+	g.GET("", h.ListAPIKeys)
+	g.DELETE("/:id", h.RevokeAPIKey, sensitive)
+}
+
+// apiKeyError responds with the error of an API key request, hiding
+// unexpected errors behind a generic message
+func apiKeyError(c echo.Context, err error, message string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+			return c.JSON(appErr.StatusCode, response.NewErrorResponse(appErr.Message))
+		}
+	}
+	return c.JSON(http.StatusInternalServerError, response.NewErrorResponse(message))
+}
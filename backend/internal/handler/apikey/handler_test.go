@@ -0,0 +1,147 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// MockAPIKeys is a mock implementation of the API key service
+type MockAPIKeys struct {
+	mock.Mock
+}
+
+// CreateAPIKey mocks the CreateAPIKey method
+func (m *MockAPIKeys) CreateAPIKey(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (string, *model.APIKey, error) {
+	args := m.Called(ctx, userID, name, scopes, expiresAt)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*model.APIKey), args.Error(2)
+}
+
+// ListAPIKeys mocks the ListAPIKeys method
+func (m *MockAPIKeys) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.APIKey), args.Error(1)
+}
+
+// RevokeAPIKey mocks the RevokeAPIKey method
+func (m *MockAPIKeys) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// TestCreateAPIKey tests the CreateAPIKey handler
+func TestCreateAPIKey(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("returns the key once", func(t *testing.T) {
+		mockKeys := new(MockAPIKeys)
+		handler := NewHandler(mockKeys)
+
+		body := `{"name":"CI","scopes":["read"],"expires_at":"2030-01-01T00:00:00Z"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/api-keys", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockKeys.On("CreateAPIKey", mock.Anything, "user-1", "CI", []string{"read"}, &expiresAt).Return("fsk_0123456789abcdef_secret", &model.APIKey{
+			ID:         "key-1",
+			UserID:     "user-1",
+			Name:       "CI",
+			Prefix:     "fsk_0123456789abcdef",
+			SecretHash: "secret-hash",
+			Scopes:     []string{"read"},
+			ExpiresAt:  &expiresAt,
+			CreatedAt:  time.Now(),
+		}, nil)
+
+		err := handler.CreateAPIKey(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "secret-hash")
+
+		var resp CreateAPIKeyResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "fsk_0123456789abcdef_secret", resp.Key)
+		assert.Equal(t, "fsk_0123456789abcdef", resp.Prefix)
+		assert.Equal(t, "2030-01-01T00:00:00Z", resp.ExpiresAt)
+		mockKeys.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid scopes", func(t *testing.T) {
+		mockKeys := new(MockAPIKeys)
+		handler := NewHandler(mockKeys)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/api-keys", strings.NewReader(`{"name":"CI","scopes":["admin"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		mockKeys.On("CreateAPIKey", mock.Anything, "user-1", "CI", []string{"admin"}, (*time.Time)(nil)).Return("", nil, apperrors.NewValidationError("invalid scope: admin"))
+
+		err := handler.CreateAPIKey(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid scope: admin")
+	})
+}
+
+// TestRevokeAPIKey tests the RevokeAPIKey handler
+func TestRevokeAPIKey(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "revokes the key", wantCode: http.StatusNoContent},
+		{name: "unknown key", err: apperrors.NewNotFoundError("API key not found"), wantCode: http.StatusNotFound},
+		{name: "unexpected error", err: assert.AnError, wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeys := new(MockAPIKeys)
+			handler := NewHandler(mockKeys)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/api-keys/key-1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("key-1")
+			c.Set("user_id", "user-1")
+
+			mockKeys.On("RevokeAPIKey", mock.Anything, "user-1", "key-1").Return(tt.err)
+
+			err := handler.RevokeAPIKey(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			mockKeys.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" validate:"required" example:"CI deploys"`
+	// read, write, or permissions of the user for the admin API
+	Scopes []string `json:"scopes" validate:"required" example:"read,write"`
+	// Omit for a key that doesn't expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
+
+// APIKeyItem represents an API key, without its secret
+type APIKeyItem struct {
+	ID         string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name       string   `json:"name" example:"CI deploys"`
+	Prefix     string   `json:"prefix" example:"fsk_1a2b3c4d5e6f7a8b"`
+	Scopes     []string `json:"scopes" example:"read,write"`
+	ExpiresAt  string   `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	LastUsedAt string   `json:"last_used_at,omitempty" example:"2023-01-02T12:00:00Z"`
+	CreatedAt  string   `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// CreateAPIKeyResponse represents a new API key, with the key itself
+type CreateAPIKeyResponse struct {
+	APIKeyItem
+	// Shown only once: store it somewhere safe
+	Key string `json:"key" example:"fsk_1a2b3c4d5e6f7a8b_9c0d..."`
+}
+
+// APIKeysResponse represents the API keys of a user
+type APIKeysResponse struct {
+	APIKeys []APIKeyItem `json:"api_keys"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"API key not found"`
+}
+
+// newAPIKeyItem converts an API key to its response model
+func newAPIKeyItem(key *model.APIKey) APIKeyItem {
+	item := APIKeyItem{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.ExpiresAt != nil {
+		item.ExpiresAt = key.ExpiresAt.Format(time.RFC3339)
+	}
+	if key.LastUsedAt != nil {
+		item.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+	}
+	return item
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"log"
 	"net/http"
 
@@ -8,17 +9,25 @@ import (
 	"github.com/nanayaw/fullstack/internal/handler/response"
 	"github.com/nanayaw/fullstack/internal/models"
 	"github.com/nanayaw/fullstack/internal/service/auth"
+	"github.com/nanayaw/fullstack/pkg/i18n"
 )
 
+// SecurityService defines the interface for the security service
+type SecurityService interface {
+	UnlockAccountWithToken(ctx context.Context, token, ipAddress, userAgent string) error
+}
+
 // Handler handles authentication-related requests
 type Handler struct {
-	authService auth.Service
+	authService     auth.Service
+	securityService SecurityService
 }
 
 // NewHandler creates a new auth handler
-func NewHandler(authService auth.Service) *Handler {
+func NewHandler(authService auth.Service, securityService SecurityService) *Handler {
 	return &Handler{
-		authService: authService,
+		authService:     authService,
+		securityService: securityService,
 	}
 }
 
@@ -44,11 +53,20 @@ func (h *Handler) Register(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
 	}
 
+	// Emails are sent in the requested locale if we support it, otherwise in
+	// the best match for the browser's languages
+	catalog := i18n.Default()
+	locale := i18n.Normalize(req.Locale)
+	if !catalog.Supports(locale) {
+		locale = catalog.Negotiate(c.Request().Header.Get("Accept-Language"))
+	}
+
 	// Convert to service model
 	createUserReq := &models.CreateUserRequest{
 		Email:    req.Email,
 		Password: req.Password,
 		FullName: req.FirstName + " " + req.LastName,
+		Locale:   locale,
 	}
 
 	// Call service
@@ -309,6 +327,42 @@ func (h *Handler) ResetPassword(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// UnlockAccount godoc
+// @Summary Unlock a locked account
+// @Description Unlock an account before its lock expires using the token from the account locked email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body UnlockAccountRequest true "Account unlock token"
+// @Success 200 {object} UnlockAccountResponse "Account unlocked"
+// @Failure 400 {object} ErrorResponse "Invalid token"
+// @Router /api/v1/auth/unlock-account [post]
+func (h *Handler) UnlockAccount(c echo.Context) error {
+	var req UnlockAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request format"))
+	}
+
+	// Validate request
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+
+	// Call service
+	err := h.securityService.UnlockAccountWithToken(c.Request().Context(), req.Token, c.RealIP(), c.Request().UserAgent())
+	if err != nil {
+		log.Printf("Error unlocking account: %v", err)
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid or expired unlock token"))
+	}
+
+	// Create response
+	resp := UnlockAccountResponse{
+		Message: "Your account has been unlocked",
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 // RegisterRoutes registers all auth routes
 func (h *Handler) RegisterRoutes(g *echo.Group) {
 	g.POST("/register", h.Register)
@@ -318,4 +372,5 @@ func (h *Handler) RegisterRoutes(g *echo.Group) {
 	g.POST("/verify-email", h.VerifyEmail)
 	g.POST("/forgot-password", h.ForgotPassword)
 	g.POST("/reset-password", h.ResetPassword)
+	g.POST("/unlock-account", h.UnlockAccount)
 }
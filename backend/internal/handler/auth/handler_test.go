@@ -105,6 +105,17 @@ func (m *MockAuthService) InvalidateAllSessions(ctx context.Context, userID stri
 	return args.Error(0)
 }
 
+// MockSecurityService is a mock implementation of the security service
+type MockSecurityService struct {
+	mock.Mock
+}
+
+// UnlockAccountWithToken mocks the UnlockAccountWithToken method
+func (m *MockSecurityService) UnlockAccountWithToken(ctx context.Context, token, ipAddress, userAgent string) error {
+	args := m.Called(ctx, token, ipAddress, userAgent)
+	return args.Error(0)
+}
+
 // TestRegister tests the Register handler
 func TestRegister(t *testing.T) {
 	// Create a new Echo instance
@@ -113,8 +124,8 @@ func TestRegister(t *testing.T) {
 	// Create a mock auth service
 	mockAuthService := new(MockAuthService)
 
-	// Create a new auth handler with the mock service
-	handler := NewHandler(mockAuthService)
+	// Create a new auth handler with the mock services
+	handler := NewHandler(mockAuthService, new(MockSecurityService))
 
 	// Create a request body
 	reqBody := RegisterRequest{
@@ -128,6 +139,7 @@ func TestRegister(t *testing.T) {
 	// Create a new HTTP request
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(jsonBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Accept-Language", "fr-CA,fr;q=0.9,en;q=0.8")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
@@ -140,7 +152,7 @@ func TestRegister(t *testing.T) {
 		UpdatedAt: time.Now(),
 	}
 	mockAuthService.On("Register", mock.Anything, mock.MatchedBy(func(req *models.CreateUserRequest) bool {
-		return req.Email == "test@example.com" && req.Password == "password123" && req.FullName == "John Doe"
+		return req.Email == "test@example.com" && req.Password == "password123" && req.FullName == "John Doe" && req.Locale == "fr"
 	})).Return(mockUser, nil)
 
 	// Mock the validator
@@ -174,8 +186,8 @@ func TestLogin(t *testing.T) {
 	// Create a mock auth service
 	mockAuthService := new(MockAuthService)
 
-	// Create a new auth handler with the mock service
-	handler := NewHandler(mockAuthService)
+	// Create a new auth handler with the mock services
+	handler := NewHandler(mockAuthService, new(MockSecurityService))
 
 	// Create a request body
 	reqBody := LoginRequest{
@@ -222,6 +234,53 @@ func TestLogin(t *testing.T) {
 	mockAuthService.AssertExpectations(t)
 }
 
+// TestUnlockAccount tests the UnlockAccount handler
+func TestUnlockAccount(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create mock services
+	mockAuthService := new(MockAuthService)
+	mockSecurityService := new(MockSecurityService)
+
+	// Create a new auth handler with the mock services
+	handler := NewHandler(mockAuthService, mockSecurityService)
+
+	// Create a request body
+	reqBody := UnlockAccountRequest{
+		Token: "unlock-token",
+	}
+	jsonBody, _ := json.Marshal(reqBody)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/unlock-account", bytes.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set up expectations
+	mockSecurityService.On("UnlockAccountWithToken", mock.Anything, "unlock-token", mock.Anything, mock.Anything).Return(nil)
+
+	// Mock the validator
+	e.Validator = &MockValidator{}
+
+	// Call the handler
+	if assert.NoError(t, handler.UnlockAccount(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Parse the response
+		var resp UnlockAccountResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		// Check the response
+		assert.Equal(t, "Your account has been unlocked", resp.Message)
+	}
+
+	// Verify expectations
+	mockSecurityService.AssertExpectations(t)
+}
+
 // MockValidator is a mock implementation of the validator
 type MockValidator struct{}
 
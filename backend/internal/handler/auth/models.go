@@ -6,6 +6,8 @@ type RegisterRequest struct {
 	Password  string `json:"password" validate:"required,min=8" example:"securepassword123"`
 	FirstName string `json:"first_name" validate:"required" example:"John"`
 	LastName  string `json:"last_name" validate:"required" example:"Doe"`
+	// Locale overrides the language negotiated from the Accept-Language header
+	Locale string `json:"locale,omitempty" example:"fr"`
 }
 
 // RegisterResponse represents the registration response
@@ -82,6 +84,16 @@ type ForgotPasswordResponse struct {
 	Message string `json:"message" example:"Password reset email sent"`
 }
 
+// UnlockAccountRequest represents the account unlock request
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required" example:"3f5a9c..."`
+}
+
+// UnlockAccountResponse represents the account unlock response
+type UnlockAccountResponse struct {
+	Message string `json:"message" example:"Your account has been unlocked"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error" example:"Invalid input"`
@@ -0,0 +1,105 @@
+package dataexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// DataExports defines the interface for exporting the data of users
+type DataExports interface {
+	RequestExport(ctx context.Context, userID string) (*model.DataExport, error)
+	DownloadExport(ctx context.Context, token string) (*model.DataExport, []byte, error)
+}
+
+// Handler handles data export requests
+type Handler struct {
+	dataExports DataExports
+}
+
+// NewHandler creates a new data export handler
+func NewHandler(dataExports DataExports) *Handler {
+	return &Handler{
+		dataExports: dataExports,
+	}
+}
+
+// RequestExport godoc
+// @Summary Export my data
+// @Description Start an export of the current user's data: their profile, sessions, linked OAuth accounts, audit log entries, security events and login attempts, as a ZIP of JSON files. The user is emailed a link to download it once it is ready. Not available with an API key or while impersonating.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} DataExportResponse "Export started"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed with an API key or while impersonating"
+// @Failure 409 {object} ErrorResponse "An export is already being prepared"
+// @Failure 429 {object} ErrorResponse "Too many exports"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/users/me/export [post]
+func (h *Handler) RequestExport(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	export, err := h.dataExports.RequestExport(c.Request().Context(), userID)
+	if err != nil {
+		return dataExportError(c, err, "Failed to start data export")
+	}
+
+	return c.JSON(http.StatusAccepted, newDataExportResponse(export))
+}
+
+// DownloadExport godoc
+// @Summary Download a data export
+// @Description Download the ZIP archive of a data export with the token of the link emailed to the user. Links expire, after which the archive is deleted.
+// @Tags users
+// @Produce application/zip
+// @Param token query string true "Download token"
+// @Success 200 {file} file "ZIP archive of JSON files"
+// @Failure 400 {object} ErrorResponse "Invalid or expired download link"
+// @Failure 404 {object} ErrorResponse "Data export not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/exports/download [get]
+func (h *Handler) DownloadExport(c echo.Context) error {
+	export, archive, err := h.dataExports.DownloadExport(c.Request().Context(), c.QueryParam("token"))
+	if err != nil {
+		return dataExportError(c, err, "Failed to download data export")
+	}
+
+	filename := fmt.Sprintf("data-export-%s.zip", export.CreatedAt.UTC().Format("2006-01-02"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Blob(http.StatusOK, "application/zip", archive)
+}
+
+// RegisterRoutes registers the route starting an export, under the current
+// user's routes. It runs the sensitive middleware, so it needs the user to
+// be signed in.
+func (h *Handler) RegisterRoutes(g *echo.Group, sensitive echo.MiddlewareFunc) {
+	g.POST("", h.RequestExport, sensitive)
+}
+
+// RegisterDownloadRoutes registers the route download links point to, which
+// are authenticated by their token
+func (h *Handler) RegisterDownloadRoutes(g *echo.Group) {
+	g.GET("/download", h.DownloadExport)
+}
+
+// dataExportError responds with the error of a data export request, hiding
+// unexpected errors behind a generic message
+func dataExportError(c echo.Context, err error, message string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+			return c.JSON(appErr.StatusCode, response.NewErrorResponse(appErr.Message))
+		}
+	}
+	return c.JSON(http.StatusInternalServerError, response.NewErrorResponse(message))
+}
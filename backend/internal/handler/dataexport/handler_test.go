@@ -0,0 +1,139 @@
+package dataexport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// MockDataExports is a mock implementation of the data export service
+type MockDataExports struct {
+	mock.Mock
+}
+
+// RequestExport mocks the RequestExport method
+func (m *MockDataExports) RequestExport(ctx context.Context, userID string) (*model.DataExport, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DataExport), args.Error(1)
+}
+
+// DownloadExport mocks the DownloadExport method
+func (m *MockDataExports) DownloadExport(ctx context.Context, token string) (*model.DataExport, []byte, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*model.DataExport), args.Get(1).([]byte), args.Error(2)
+}
+
+// TestRequestExport tests the RequestExport handler
+func TestRequestExport(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("starts an export", func(t *testing.T) {
+		mockExports := new(MockDataExports)
+		handler := NewHandler(mockExports)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/export", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		mockExports.On("RequestExport", mock.Anything, "user-1").Return(&model.DataExport{
+			ID:        "export-1",
+			UserID:    "user-1",
+			Status:    model.DataExportPending,
+			CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}, nil)
+
+		err := handler.RequestExport(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		var resp DataExportResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "export-1", resp.ID)
+		assert.Equal(t, model.DataExportPending, resp.Status)
+		assert.Equal(t, "2024-01-01T12:00:00Z", resp.CreatedAt)
+		mockExports.AssertExpectations(t)
+	})
+
+	t.Run("rejects a second export while one is being prepared", func(t *testing.T) {
+		mockExports := new(MockDataExports)
+		handler := NewHandler(mockExports)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/export", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		mockExports.On("RequestExport", mock.Anything, "user-1").Return(nil, apperrors.NewConflictError("a data export is already being prepared"))
+
+		err := handler.RequestExport(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "already being prepared")
+	})
+}
+
+// TestDownloadExport tests the DownloadExport handler
+func TestDownloadExport(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("returns the archive", func(t *testing.T) {
+		mockExports := new(MockDataExports)
+		handler := NewHandler(mockExports)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/exports/download?token=signed-token", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockExports.On("DownloadExport", mock.Anything, "signed-token").Return(&model.DataExport{
+			ID:        "export-1",
+			Status:    model.DataExportReady,
+			CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}, []byte("PK archive"), nil)
+
+		err := handler.DownloadExport(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, `attachment; filename="data-export-2024-01-01.zip"`, rec.Header().Get(echo.HeaderContentDisposition))
+		assert.Equal(t, "PK archive", rec.Body.String())
+	})
+
+	t.Run("rejects an expired link", func(t *testing.T) {
+		mockExports := new(MockDataExports)
+		handler := NewHandler(mockExports)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/exports/download?token=expired-token", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockExports.On("DownloadExport", mock.Anything, "expired-token").Return(nil, nil, apperrors.NewValidationError("download link has expired"))
+
+		err := handler.DownloadExport(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "download link has expired")
+	})
+}
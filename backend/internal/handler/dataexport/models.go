@@ -0,0 +1,30 @@
+package dataexport
+
+import (
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// DataExportResponse represents a data export the user requested
+type DataExportResponse struct {
+	ID        string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Status    string `json:"status" example:"pending"`
+	Message   string `json:"message" example:"Your data export is being prepared. We'll email you a download link when it's ready."`
+	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"invalid download link"`
+}
+
+// newDataExportResponse converts a requested data export to its response model
+func newDataExportResponse(export *model.DataExport) DataExportResponse {
+	return DataExportResponse{
+		ID:        export.ID,
+		Status:    export.Status,
+		Message:   "Your data export is being prepared. We'll email you a download link when it's ready.",
+		CreatedAt: export.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,113 @@
+package dev
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/service/email"
+)
+
+// Mailbox defines the interface for reading captured emails
+type Mailbox interface {
+	Messages(recipient string) ([]*email.CapturedEmail, error)
+	Message(id string) (*email.CapturedEmail, error)
+	Clear() error
+}
+
+// Handler handles development-only requests. Its routes must never be
+// registered outside development, as captured emails contain live tokens.
+type Handler struct {
+	mailbox Mailbox
+}
+
+// NewHandler creates a new dev handler
+func NewHandler(mailbox Mailbox) *Handler {
+	return &Handler{
+		mailbox: mailbox,
+	}
+}
+
+// ListMailbox godoc
+// @Summary List captured emails
+// @Description List the emails stored by the capture provider, newest first (development only)
+// @Tags dev
+// @Produce json
+// @Param to query string false "Only include emails sent to this address"
+// @Success 200 {object} MailboxResponse "Captured emails"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /dev/mailbox [get]
+func (h *Handler) ListMailbox(c echo.Context) error {
+	messages, err := h.mailbox.Messages(c.QueryParam("to"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to list captured emails"))
+	}
+
+	// Convert to response model
+	items := make([]CapturedEmailItem, len(messages))
+	for i, m := range messages {
+		items[i] = newCapturedEmailItem(m)
+	}
+
+	return c.JSON(http.StatusOK, MailboxResponse{Emails: items})
+}
+
+// GetMailboxEmail godoc
+// @Summary Get a captured email
+// @Description Get a single email stored by the capture provider (development only)
+// @Tags dev
+// @Produce json
+// @Param id path string true "Email ID"
+// @Success 200 {object} CapturedEmailItem "Captured email"
+// @Failure 404 {object} ErrorResponse "Email not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /dev/mailbox/{id} [get]
+func (h *Handler) GetMailboxEmail(c echo.Context) error {
+	m, err := h.mailbox.Message(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get captured email"))
+	}
+	if m == nil {
+		return c.JSON(http.StatusNotFound, response.NewErrorResponse("Email not found"))
+	}
+
+	return c.JSON(http.StatusOK, newCapturedEmailItem(m))
+}
+
+// ClearMailbox godoc
+// @Summary Delete captured emails
+// @Description Delete every email stored by the capture provider (development only)
+// @Tags dev
+// @Success 204 "Mailbox cleared"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /dev/mailbox [delete]
+func (h *Handler) ClearMailbox(c echo.Context) error {
+	if err := h.mailbox.Clear(); err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to clear captured emails"))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterRoutes registers all dev routes
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/mailbox", h.ListMailbox)
+	g.GET("/mailbox/:id", h.GetMailboxEmail)
+	g.DELETE("/mailbox", h.ClearMailbox)
+}
+
+// newCapturedEmailItem converts a captured email to its response model
+func newCapturedEmailItem(m *email.CapturedEmail) CapturedEmailItem {
+	return CapturedEmailItem{
+		ID:        m.ID,
+		Template:  m.Template,
+		From:      m.From,
+		To:        m.To,
+		Subject:   m.Subject,
+		HTML:      m.HTML,
+		Text:      m.Text,
+		Links:     m.Links,
+		CreatedAt: m.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
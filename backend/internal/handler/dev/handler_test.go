@@ -0,0 +1,114 @@
+package dev
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/nanayaw/fullstack/internal/service/email"
+)
+
+// MockMailbox is a mock implementation of the mailbox
+type MockMailbox struct {
+	mock.Mock
+}
+
+// Messages mocks the Messages method
+func (m *MockMailbox) Messages(recipient string) ([]*email.CapturedEmail, error) {
+	args := m.Called(recipient)
+	return args.Get(0).([]*email.CapturedEmail), args.Error(1)
+}
+
+// Message mocks the Message method
+func (m *MockMailbox) Message(id string) (*email.CapturedEmail, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*email.CapturedEmail), args.Error(1)
+}
+
+// Clear mocks the Clear method
+func (m *MockMailbox) Clear() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// TestListMailbox tests the ListMailbox handler
+func TestListMailbox(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new dev handler with a mock mailbox
+	mockMailbox := new(MockMailbox)
+	handler := NewHandler(mockMailbox)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/dev/mailbox?to=user@example.com", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set up expectations
+	mockMailbox.On("Messages", "user@example.com").Return([]*email.CapturedEmail{
+		{
+			ID:        "email-1",
+			Template:  "verification",
+			To:        []string{"user@example.com"},
+			Subject:   "Verify your email address",
+			Links:     []string{"http://localhost:3000/verify?token=abc123"},
+			CreatedAt: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.ListMailbox(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Parse the response
+		var resp MailboxResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		// Check the response
+		assert.Len(t, resp.Emails, 1)
+		assert.Equal(t, "email-1", resp.Emails[0].ID)
+		assert.Equal(t, []string{"http://localhost:3000/verify?token=abc123"}, resp.Emails[0].Links)
+	}
+
+	// Verify expectations
+	mockMailbox.AssertExpectations(t)
+}
+
+// TestGetMailboxEmailNotFound tests the GetMailboxEmail handler with an unknown ID
+func TestGetMailboxEmailNotFound(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new dev handler with a mock mailbox
+	mockMailbox := new(MockMailbox)
+	handler := NewHandler(mockMailbox)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/dev/mailbox/unknown", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("unknown")
+
+	// Set up expectations
+	mockMailbox.On("Message", "unknown").Return(nil, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.GetMailboxEmail(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	// Verify expectations
+	mockMailbox.AssertExpectations(t)
+}
@@ -0,0 +1,24 @@
+package dev
+
+// CapturedEmailItem represents an email stored by the capture provider
+type CapturedEmailItem struct {
+	ID        string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Template  string   `json:"template" example:"verification"`
+	From      string   `json:"from" example:"Go+Next <noreply@example.com>"`
+	To        []string `json:"to" example:"user@example.com"`
+	Subject   string   `json:"subject" example:"Verify your email address for Go+Next Fullstack App"`
+	HTML      string   `json:"html"`
+	Text      string   `json:"text"`
+	Links     []string `json:"links" example:"http://localhost:3000/verify?token=abc123"`
+	CreatedAt string   `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// MailboxResponse represents the captured emails
+type MailboxResponse struct {
+	Emails []CapturedEmailItem `json:"emails"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"Email not found"`
+}
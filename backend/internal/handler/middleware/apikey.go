@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// DenyAPIKeys creates a middleware that rejects requests made with an API
+// key, for actions that need the user to sign in such as creating more keys.
+// It requires the auth middleware to run first.
+func DenyAPIKeys() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if IsAPIKey(c) {
+				return echo.NewHTTPError(http.StatusForbidden, "not allowed with an API key")
+			}
+			return next(c)
+		}
+	}
+}
+
+// Sensitive creates a middleware for actions only the signed-in user may
+// take, rejecting admins impersonating them, API keys and service accounts.
+// It requires the auth middleware to run first.
+func Sensitive() echo.MiddlewareFunc {
+	denyImpersonation := DenyImpersonation()
+	denyAPIKeys := DenyAPIKeys()
+	denyServiceAccounts := DenyServiceAccounts()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return denyServiceAccounts(denyImpersonation(denyAPIKeys(next)))
+	}
+}
+
+// IsAPIKey reports whether a request is made with an API key
+func IsAPIKey(c echo.Context) bool {
+	session, _ := c.Get("session").(*models.Session)
+	return session != nil && session.APIKeyID != ""
+}
+
+// hasScope reports whether the API key of a session has a scope. Keys with
+// write scope also have read scope.
+func hasScope(session *models.Session, scope string) bool {
+	key := model.APIKey{Scopes: session.Scopes}
+	return key.HasScope(scope)
+}
+
+// methodScope is the API key scope needed for a request method
+func methodScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return model.APIKeyScopeRead
+	default:
+		return model.APIKeyScopeWrite
+	}
+}
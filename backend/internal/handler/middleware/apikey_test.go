@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthMiddlewareAPIKeyScopes(t *testing.T) {
+	e := echo.New()
+	g := e.Group("/api/v1/users", AuthMiddleware(fakeAuthService{}))
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}
+	g.GET("/me", ok)
+	g.PUT("/me", ok)
+	g.DELETE("/me", ok, Sensitive())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"read key reading", http.MethodGet, "/api/v1/users/me", "apikey:user-1:read", http.StatusNoContent},
+		{"read key writing", http.MethodPut, "/api/v1/users/me", "apikey:user-1:read", http.StatusForbidden},
+		{"write key reading", http.MethodGet, "/api/v1/users/me", "apikey:user-1:write", http.StatusNoContent},
+		{"write key writing", http.MethodPut, "/api/v1/users/me", "apikey:user-1:write", http.StatusNoContent},
+		{"key with only a permission scope", http.MethodGet, "/api/v1/users/me", "apikey:user-1:users:read", http.StatusForbidden},
+		{"key on a sensitive route", http.MethodDelete, "/api/v1/users/me", "apikey:user-1:write", http.StatusForbidden},
+		{"impersonation on a sensitive route", http.MethodDelete, "/api/v1/users/me", "impersonated:user-1", http.StatusForbidden},
+		{"access token on a sensitive route", http.MethodDelete, "/api/v1/users/me", "user-1", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
@@ -5,11 +5,19 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/service/auth"
 )
 
-// AuthMiddleware creates a middleware for authentication
-func AuthMiddleware(authService auth.Service) echo.MiddlewareFunc {
+// AuthMiddleware creates a middleware for authentication with an access
+// token or an API key, which both resolve to the session of their user. Only
+// users are let through unless principals lists the model.Principal types
+// allowed, such as service accounts with their access tokens.
+func AuthMiddleware(authService auth.Service, principals ...string) echo.MiddlewareFunc {
+	if len(principals) == 0 {
+		principals = []string{model.PrincipalUser}
+	}
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Extract token from Authorization header
@@ -33,9 +41,26 @@ func AuthMiddleware(authService auth.Service) echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
 			}
 
+			principal := newPrincipal(session)
+			if !allowsPrincipal(principals, principal.Type) {
+				return echo.NewHTTPError(http.StatusForbidden, "not allowed for service accounts")
+			}
+
+			// API keys are limited to reading unless they have write scope
+			if session.APIKeyID != "" && !hasScope(session, methodScope(c.Request().Method)) {
+				return echo.NewHTTPError(http.StatusForbidden, "API key is missing scope: "+methodScope(c.Request().Method))
+			}
+
 			// Set user information in context
-			c.Set("user_id", session.UserID)
+			if principal.IsService() {
+				c.Set(ServiceAccountContextKey, principal.ID)
+				setAuditActor(c, "service_account:"+principal.ID, "", principal.ID)
+			} else {
+				c.Set("user_id", session.UserID)
+				setAuditActor(c, "user:"+session.UserID, session.UserID, "")
+			}
 			c.Set("session", session)
+			c.Set(PrincipalContextKey, principal)
 
 			// Call next handler
 			return next(c)
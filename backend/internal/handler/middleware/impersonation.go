@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// DenyImpersonation creates a middleware that rejects requests made by an
+// admin impersonating a user, for actions only the user may take such as
+// changing their password or deleting their account. It requires the auth
+// middleware to run first.
+func DenyImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if IsImpersonating(c) {
+				return echo.NewHTTPError(http.StatusForbidden, "not allowed while impersonating a user")
+			}
+			return next(c)
+		}
+	}
+}
+
+// IsImpersonating reports whether a request is made by an admin impersonating
+// a user
+func IsImpersonating(c echo.Context) bool {
+	session, _ := c.Get("session").(*models.Session)
+	return session != nil && session.ImpersonatorID != ""
+}
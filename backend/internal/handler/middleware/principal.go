@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// PrincipalContextKey is set to the *model.Principal who made an
+// authenticated request
+const PrincipalContextKey = "principal"
+
+// ServiceAccountContextKey is set to the ID of the service account that made
+// a request, in place of "user_id" for users
+const ServiceAccountContextKey = "service_account_id"
+
+// GetPrincipal returns who made a request, or nil if it isn't authenticated
+func GetPrincipal(c echo.Context) *model.Principal {
+	principal, _ := c.Get(PrincipalContextKey).(*model.Principal)
+	return principal
+}
+
+// IsServiceAccount reports whether a request is made by a service account
+func IsServiceAccount(c echo.Context) bool {
+	principal := GetPrincipal(c)
+	return principal != nil && principal.IsService()
+}
+
+// DenyServiceAccounts creates a middleware that rejects requests made by a
+// service account, for actions only people may take. It requires the auth
+// middleware to run first.
+func DenyServiceAccounts() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if IsServiceAccount(c) {
+				return echo.NewHTTPError(http.StatusForbidden, "not allowed for service accounts")
+			}
+			return next(c)
+		}
+	}
+}
+
+// newPrincipal returns who a session belongs to
+func newPrincipal(session *models.Session) *model.Principal {
+	if session.ServiceAccountID != "" {
+		return &model.Principal{
+			Type:           model.PrincipalService,
+			ID:             session.ServiceAccountID,
+			OrganizationID: session.OrgID,
+			OrgRole:        session.OrgRole,
+			Scopes:         session.Permissions,
+		}
+	}
+
+	return &model.Principal{
+		Type:           model.PrincipalUser,
+		ID:             session.UserID,
+		OrganizationID: session.OrgID,
+		OrgRole:        session.OrgRole,
+		Scopes:         session.Scopes,
+	}
+}
+
+// allowsPrincipal reports whether a principal type is among those allowed
+func allowsPrincipal(allowed []string, principalType string) bool {
+	for _, t := range allowed {
+		if t == principalType {
+			return true
+		}
+	}
+	return false
+}
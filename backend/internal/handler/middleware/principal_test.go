@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+func TestAuthMiddlewarePrincipals(t *testing.T) {
+	e := echo.New()
+	whoami := func(c echo.Context) error {
+		principal := GetPrincipal(c)
+		userID, _ := c.Get("user_id").(string)
+		return c.String(http.StatusOK, principal.Type+":"+principal.ID+":"+userID)
+	}
+	e.GET("/users", whoami, AuthMiddleware(fakeAuthService{}))
+	e.GET("/any", whoami, AuthMiddleware(fakeAuthService{}, model.PrincipalUser, model.PrincipalService))
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+		body  string
+	}{
+		{"user on user routes", "/users", "user-1", http.StatusOK, "user:user-1:user-1"},
+		{"service account on user routes", "/users", "service:sa-1:users:read", http.StatusForbidden, ""},
+		{"user on shared routes", "/any", "user-1", http.StatusOK, "user:user-1:user-1"},
+		{"service account on shared routes", "/any", "service:sa-1:users:read", http.StatusOK, "service:sa-1:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareServiceAccount(t *testing.T) {
+	e := echo.New()
+	auth := AuthMiddleware(fakeAuthService{}, model.PrincipalUser, model.PrincipalService)
+	g := e.Group("/api/v1/orgs/:orgID", auth, TenantMiddleware(fakeMemberships{}, logger.DefaultLogger()))
+	g.GET("/members", func(c echo.Context) error {
+		membership := c.Get(MembershipContextKey).(*model.Membership)
+		assert.True(t, membership.ServiceAccount)
+		return c.String(http.StatusOK, membership.UserID+":"+membership.Role)
+	})
+	g.DELETE("", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, Sensitive())
+
+	// Service accounts act in their organization with their role
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orgs/"+testOrgID+"/members", nil)
+	req.Header.Set("Authorization", "Bearer orgservice:sa-1:member")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "sa-1:member", rec.Body.String())
+
+	// Other organizations don't exist for them
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/orgs/0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b/members", nil)
+	req.Header.Set("Authorization", "Bearer orgservice:sa-1:member")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Neither do organizations for service accounts of the admins
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/orgs/"+testOrgID+"/members", nil)
+	req.Header.Set("Authorization", "Bearer service:sa-2:users:read")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Sensitive actions are for people only
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/orgs/"+testOrgID, nil)
+	req.Header.Set("Authorization", "Bearer orgservice:sa-1:admin")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+// fakeAuditRecorder remembers the entries recorded, failing when err is set
+type fakeAuditRecorder struct {
+	entries []*models.AuditLog
+	err     error
+}
+
+func (f *fakeAuditRecorder) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.entries = append(f.entries, log)
+	return nil
+}
+
+func TestAuditRequests(t *testing.T) {
+	audit := &fakeAuditRecorder{}
+
+	e := echo.New()
+	e.Use(echoMiddleware.RequestID(), AuditRequests(audit, logger.DefaultLogger()))
+	e.POST("/login", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	g := e.Group("", AuthMiddleware(fakeAuthService{}, model.PrincipalUser, model.PrincipalService))
+	g.GET("/items", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	g.POST("/items/:id", func(c echo.Context) error {
+		// Services find who made the request in its context
+		auditCtx := model.AuditContextFrom(c.Request().Context())
+		return c.String(http.StatusCreated, auditCtx.Actor)
+	})
+	g.DELETE("/items/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "item not found")
+	})
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Reads and requests failing without credentials aren't recorded
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/items", "service:sa-1:users:read").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/items/1", "").Code)
+	assert.Empty(t, audit.entries)
+
+	// Changes are, whether they succeed or not
+	rec := serve(http.MethodPost, "/items/1", "user-1")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "user:user-1", rec.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/items/2", "service:sa-1:users:read").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/login", "").Code)
+	require.Len(t, audit.entries, 3)
+
+	assert.Equal(t, "user:user-1", audit.entries[0].Actor)
+	assert.Equal(t, "user-1", audit.entries[0].UserID)
+	assert.Equal(t, "POST /items/:id", audit.entries[0].EntityID)
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), audit.entries[0].RequestID)
+	assert.Contains(t, audit.entries[0].Metadata, `"status":201`)
+
+	assert.Equal(t, "service_account:sa-1", audit.entries[1].Actor)
+	assert.Equal(t, "sa-1", audit.entries[1].ServiceAccountID)
+	assert.Contains(t, audit.entries[1].Metadata, `"status":404`)
+
+	assert.Equal(t, model.AuditActorAnonymous, audit.entries[2].Actor)
+
+	// Audit failures don't fail the request, which is already done
+	audit.err = errors.New("database is down")
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/items/1", "service:sa-1:users:read").Code)
+}
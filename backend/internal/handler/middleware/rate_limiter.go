@@ -1,183 +1,181 @@
 package middleware
 
 import (
-	"net"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
-	"sync"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/config"
 	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/pkg/logger"
 )
 
-// RateLimiterConfig contains configuration for the rate limiter
-type RateLimiterConfig struct {
-	// Max number of requests allowed in the time window
-	Limit int
-	// Time window for rate limiting
-	Window time.Duration
-	// Message to return when rate limit is exceeded
-	Message string
-	// Optional function to get a custom key for rate limiting (e.g., user ID, API key)
-	// If nil, client IP will be used
-	KeyFunc func(echo.Context) string
-	// Routes to exclude from rate limiting
-	ExcludedRoutes []string
-}
-
-// DefaultRateLimiterConfig returns a default configuration for the rate limiter
-func DefaultRateLimiterConfig() RateLimiterConfig {
-	return RateLimiterConfig{
-		Limit:   100,
-		Window:  time.Minute,
-		Message: "Rate limit exceeded. Please try again later.",
-		KeyFunc: func(c echo.Context) string {
-			// Get client IP by default
-			ip, _, err := net.SplitHostPort(c.Request().RemoteAddr)
-			if err != nil {
-				return c.Request().RemoteAddr
-			}
-			return ip
-		},
-		ExcludedRoutes: []string{},
-	}
-}
+// maxRateLimitBodySize is the size of the request body read to find the email
+// address of email rate limits
+const maxRateLimitBodySize = 64 << 10
 
-// AuthRateLimiterConfig returns a configuration for authentication endpoints
-func AuthRateLimiterConfig() RateLimiterConfig {
-	config := DefaultRateLimiterConfig()
-	config.Limit = 5
-	config.Window = time.Minute * 15
-	config.Message = "Too many login attempts. Please try again later."
-	return config
+// RateLimitCache stores the rate limit counters, shared by every API instance
+type RateLimitCache interface {
+	SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error)
+	TokenBucketRateLimit(ctx context.Context, key string, capacity int, window time.Duration) (*model.RateLimitResult, error)
 }
 
-// client represents a client with request count and reset time
-type client struct {
-	count      int
-	lastAccess time.Time
-	resetTime  time.Time
-}
-
-// RateLimiter implements a simple in-memory rate limiter
+// RateLimiter limits requests with the policies of their route
 type RateLimiter struct {
-	mu      sync.RWMutex
-	clients map[string]*client
-	config  RateLimiterConfig
+	cache    RateLimitCache
+	policies map[string]config.RateLimitPolicy
+	enabled  bool
+	log      logger.Logger
 }
 
-// NewRateLimiter creates a new rate limiter with the given configuration
-func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
-	limiter := &RateLimiter{
-		clients: make(map[string]*client),
-		config:  config,
+// NewRateLimiter creates a new rate limiter with the rate limit policies of
+// the security configuration
+func NewRateLimiter(cache RateLimitCache, cfg *config.SecurityConfig, log logger.Logger) (*RateLimiter, error) {
+	policies, err := cfg.RateLimitPolicies()
+	if err != nil {
+		return nil, err
 	}
 
-	// Start a goroutine to clean up expired clients
-	go limiter.cleanupLoop()
-
-	return limiter
+	return &RateLimiter{
+		cache:    cache,
+		policies: policies,
+		enabled:  cfg.EnableRateLimiting,
+		log:      log,
+	}, nil
 }
 
-// cleanupLoop periodically removes expired clients
-func (rl *RateLimiter) cleanupLoop() {
-	ticker := time.NewTicker(rl.config.Window)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.cleanup()
-	}
-}
-
-// cleanup removes expired clients
-func (rl *RateLimiter) cleanup() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	for key, c := range rl.clients {
-		if now.After(c.resetTime) {
-			delete(rl.clients, key)
-		}
-	}
-}
-
-// isExcluded checks if the current route is excluded from rate limiting
-func (rl *RateLimiter) isExcluded(path string) bool {
-	for _, route := range rl.config.ExcludedRoutes {
-		if route == path {
-			return true
-		}
-	}
-	return false
-}
-
-// Middleware returns an Echo middleware function for rate limiting
+// Middleware returns an Echo middleware that limits requests with the policy
+// of their route, or the default policy. Requests over the limit get a 429
+// response with a Retry-After header, and every limited response carries the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers. User and API key rate limits require the auth middleware to run
+// first.
 func (rl *RateLimiter) Middleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Skip rate limiting for excluded routes
-			if rl.isExcluded(c.Path()) {
+			if !rl.enabled {
 				return next(c)
 			}
 
-			// Get client key
-			key := rl.config.KeyFunc(c)
+			route := c.Request().Method + " " + c.Path()
+			policy, ok := rl.policies[route]
+			if !ok {
+				route = config.DefaultRateLimitRoute
+				policy, ok = rl.policies[route]
+				if !ok {
+					return next(c)
+				}
+			}
 
-			rl.mu.Lock()
-			now := time.Now()
-			cl, exists := rl.clients[key]
+			key := fmt.Sprintf("ratelimit:%s:%s", route, rl.clientKey(c, policy.By))
 
-			// If client doesn't exist or window has expired, create a new entry
-			if !exists || now.After(cl.resetTime) {
-				rl.clients[key] = &client{
-					count:      1,
-					lastAccess: now,
-					resetTime:  now.Add(rl.config.Window),
-				}
-				rl.mu.Unlock()
+			var result *model.RateLimitResult
+			var err error
+			switch policy.Algorithm {
+			case model.RateLimitTokenBucket:
+				result, err = rl.cache.TokenBucketRateLimit(c.Request().Context(), key, policy.Limit, policy.Window)
+			default:
+				result, err = rl.cache.SlidingWindowRateLimit(c.Request().Context(), key, policy.Limit, policy.Window)
+			}
+			if err != nil {
+				// Don't turn a cache outage into an API outage
+				rl.log.Error("Failed to check rate limit", "route", route, "error", err)
 				return next(c)
 			}
 
-			// Update last access time
-			cl.lastAccess = now
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(max(result.Remaining, 0)))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
 
-			// Check if rate limit is exceeded
-			if cl.count >= rl.config.Limit {
-				rl.mu.Unlock()
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				return c.JSON(http.StatusTooManyRequests, response.NewErrorResponse("Rate limit exceeded. Please try again later."))
+			}
 
-				// Set rate limit headers
-				c.Response().Header().Set("X-RateLimit-Limit", string(rune(rl.config.Limit)))
-				c.Response().Header().Set("X-RateLimit-Remaining", "0")
-				c.Response().Header().Set("X-RateLimit-Reset", string(rune(cl.resetTime.Unix())))
-				c.Response().Header().Set("Retry-After", string(rune(int(cl.resetTime.Sub(now).Seconds()))))
+			return next(c)
+		}
+	}
+}
 
-				return c.JSON(http.StatusTooManyRequests, response.ErrorResponse{
-					Error: rl.config.Message,
-				})
-			}
+// clientKey returns what the request is counted by, falling back to the
+// client IP address when the request has no user, service account, API key
+// or email
+func (rl *RateLimiter) clientKey(c echo.Context, by string) string {
+	switch by {
+	case config.RateLimitByUser:
+		if userID, ok := c.Get("user_id").(string); ok && userID != "" {
+			return "user:" + userID
+		}
+		if serviceAccountID, ok := c.Get(ServiceAccountContextKey).(string); ok && serviceAccountID != "" {
+			return "service_account:" + serviceAccountID
+		}
+	case config.RateLimitByAPIKey:
+		// Counted by the ID of the key the request authenticated with, so the
+		// cache never holds the key itself
+		if session, _ := c.Get("session").(*models.Session); session != nil && session.APIKeyID != "" {
+			return "api_key:" + session.APIKeyID
+		}
+	case config.RateLimitByEmail:
+		if email := requestEmail(c); email != "" {
+			return "email:" + email
+		}
+	}
+	return "ip:" + c.RealIP()
+}
 
-			// Increment request count
-			cl.count++
-			remaining := rl.config.Limit - cl.count
-			rl.mu.Unlock()
+// requestEmail returns the normalized email address of a JSON or form request,
+// leaving the body for the handler to read
+func requestEmail(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
 
-			// Set rate limit headers
-			c.Response().Header().Set("X-RateLimit-Limit", string(rune(rl.config.Limit)))
-			c.Response().Header().Set("X-RateLimit-Remaining", string(rune(remaining)))
-			c.Response().Header().Set("X-RateLimit-Reset", string(rune(cl.resetTime.Unix())))
+	body := req.Body
+	data, err := io.ReadAll(io.LimitReader(body, maxRateLimitBodySize))
+	// The handler reads the whole body, including what wasn't read here
+	req.Body = readCloser{io.MultiReader(bytes.NewReader(data), body), body}
+	if err != nil {
+		return ""
+	}
 
-			return next(c)
+	var email string
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationForm) {
+		if values, err := url.ParseQuery(string(data)); err == nil {
+			email = values.Get("email")
+		}
+	} else {
+		var payload struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal(data, &payload) == nil {
+			email = payload.Email
 		}
 	}
+
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
-// RateLimit returns a middleware function with default configuration
-func RateLimit() echo.MiddlewareFunc {
-	return NewRateLimiter(DefaultRateLimiterConfig()).Middleware()
+// readCloser reads from a reader and closes a closer
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
-// AuthRateLimit returns a middleware function for authentication endpoints
-func AuthRateLimit() echo.MiddlewareFunc {
-	return NewRateLimiter(AuthRateLimiterConfig()).Middleware()
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
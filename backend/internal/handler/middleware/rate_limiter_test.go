@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/cache"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// failingRateLimitCache is a rate limit cache that is always down
+type failingRateLimitCache struct{}
+
+func (failingRateLimitCache) SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingRateLimitCache) TokenBucketRateLimit(ctx context.Context, key string, capacity int, window time.Duration) (*model.RateLimitResult, error) {
+	return nil, errors.New("connection refused")
+}
+
+// newTestRateLimitServer creates an Echo instance with rate limited routes
+func newTestRateLimitServer(t *testing.T, rateLimitCache RateLimitCache, cfg *config.SecurityConfig) *echo.Echo {
+	limiter, err := NewRateLimiter(rateLimitCache, cfg, logger.DefaultLogger())
+	require.NoError(t, err)
+
+	e := echo.New()
+	g := e.Group("/api/v1/auth", limiter.Middleware())
+	g.POST("/login", func(c echo.Context) error {
+		// The handler still gets the whole body
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	})
+	g.POST("/logout", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	return e
+}
+
+func login(e *echo.Echo, email, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"`+email+`","password":"secret"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRealIP, ip)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("limits logins by email", func(t *testing.T) {
+		memory := cache.NewMemoryService()
+		defer memory.Close()
+		e := newTestRateLimitServer(t, memory, &config.SecurityConfig{EnableRateLimiting: true, AuthRateLimit: 2})
+
+		rec := login(e, "user@example.com", "1.1.1.1")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"email":"user@example.com","password":"secret"}`, rec.Body.String())
+		assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "900", rec.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=900", rec.Header().Get("RateLimit-Policy"))
+
+		// Changing IP address doesn't help, and emails are normalized
+		rec = login(e, "User@Example.com ", "2.2.2.2")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+		rec = login(e, "user@example.com", "3.3.3.3")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "900", rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), "Rate limit exceeded")
+
+		// Other users aren't affected
+		rec = login(e, "other@example.com", "3.3.3.3")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("uses the default policy for other routes", func(t *testing.T) {
+		memory := cache.NewMemoryService()
+		defer memory.Close()
+		e := newTestRateLimitServer(t, memory, &config.SecurityConfig{
+			EnableRateLimiting:       true,
+			RateLimitPolicyOverrides: "*=1/1m",
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		e := newTestRateLimitServer(t, failingRateLimitCache{}, &config.SecurityConfig{EnableRateLimiting: false})
+
+		rec := login(e, "user@example.com", "1.1.1.1")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("lets requests through when the cache is down", func(t *testing.T) {
+		e := newTestRateLimitServer(t, failingRateLimitCache{}, &config.SecurityConfig{EnableRateLimiting: true})
+
+		rec := login(e, "user@example.com", "1.1.1.1")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestRateLimiterClientKey(t *testing.T) {
+	limiter := &RateLimiter{}
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRealIP, "1.1.1.1")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	assert.Equal(t, "ip:1.1.1.1", limiter.clientKey(c, config.RateLimitByIP))
+	// Without a user, requests are counted by IP address
+	assert.Equal(t, "ip:1.1.1.1", limiter.clientKey(c, config.RateLimitByUser))
+	c.Set("user_id", "user-1")
+	assert.Equal(t, "user:user-1", limiter.clientKey(c, config.RateLimitByUser))
+
+	// Without an API key, requests are counted by IP address
+	assert.Equal(t, "ip:1.1.1.1", limiter.clientKey(c, config.RateLimitByAPIKey))
+	c.Set("session", &models.Session{ID: "key-1", UserID: "user-1", APIKeyID: "key-1"})
+	assert.Equal(t, "api_key:key-1", limiter.clientKey(c, config.RateLimitByAPIKey))
+}
+
+func TestRateLimiterAPIKey(t *testing.T) {
+	memory := cache.NewMemoryService()
+	defer memory.Close()
+	limiter, err := NewRateLimiter(memory, &config.SecurityConfig{
+		EnableRateLimiting:       true,
+		RateLimitPolicyOverrides: "GET /api/v1/users/me=1/1m:api_key",
+	}, logger.DefaultLogger())
+	require.NoError(t, err)
+
+	e := echo.New()
+	g := e.Group("/api/v1/users", AuthMiddleware(fakeAuthService{}), limiter.Middleware())
+	g.GET("/me", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	get := func(token, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(echo.HeaderXRealIP, ip)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	key := model.APIKeyPrefix + "0123456789abcdef_" + strings.Repeat("a", 64)
+	otherKey := model.APIKeyPrefix + "fedcba9876543210_" + strings.Repeat("b", 64)
+
+	assert.Equal(t, http.StatusNoContent, get(key, "1.1.1.1").Code)
+	// Changing IP address doesn't help
+	assert.Equal(t, http.StatusTooManyRequests, get(key, "2.2.2.2").Code)
+	// Other keys from the same address aren't affected
+	assert.Equal(t, http.StatusNoContent, get(otherKey, "2.2.2.2").Code)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// PermissionChecker checks the permissions of users
+type PermissionChecker interface {
+	Can(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// RequirePermission creates a middleware that only lets through users with a
+// permission, and requests authenticated with the admin API key. It requires
+// the auth or admin auth middleware to run first. Permissions are checked
+// with the checker rather than the token claims, so revocations take effect
+// right away. Requests made with an API key also need the permission among
+// the key's scopes. Service accounts have no roles and only get the
+// permissions they were given.
+func RequirePermission(checker PermissionChecker, permission string, log logger.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if adminKey, _ := c.Get(adminKeyContextKey).(bool); adminKey {
+				return next(c)
+			}
+
+			if principal := GetPrincipal(c); principal != nil && principal.IsService() {
+				if !principal.HasScope(permission) {
+					return echo.NewHTTPError(http.StatusForbidden, "missing permission: "+permission)
+				}
+				return next(c)
+			}
+
+			userID, _ := c.Get("user_id").(string)
+			if userID == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+			}
+
+			// API keys also need the permission among their scopes
+			if session, _ := c.Get("session").(*models.Session); session != nil && session.APIKeyID != "" && !hasScope(session, permission) {
+				return echo.NewHTTPError(http.StatusForbidden, "API key is missing scope: "+permission)
+			}
+
+			ok, err := checker.Can(c.Request().Context(), userID, permission)
+			if err != nil {
+				log.Error("Failed to check permission", "user_id", userID, "permission", permission, "error", err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to check permissions")
+			}
+			if !ok {
+				return echo.NewHTTPError(http.StatusForbidden, "missing permission: "+permission)
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/auth"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// fakePermissionChecker grants the permissions listed for each user
+type fakePermissionChecker map[string][]string
+
+func (f fakePermissionChecker) Can(ctx context.Context, userID, permission string) (bool, error) {
+	if userID == "broken" {
+		return false, errors.New("database is down")
+	}
+	for _, p := range f[userID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeAuthService accepts access tokens named after their user. "impersonated:"
+// tokens are impersonation tokens of the admin-user, and "apikey:<user>:<scopes>"
+// tokens are API keys with comma-separated scopes. "service:<id>:<scopes>"
+// tokens are service accounts of the admins with comma-separated scopes, and
+// "orgservice:<id>:<role>" tokens service accounts of the test organization.
+type fakeAuthService struct {
+	auth.Service
+}
+
+func (fakeAuthService) ValidateSession(ctx context.Context, token string) (*models.Session, error) {
+	if token == "invalid" {
+		return nil, errors.New("invalid token")
+	}
+	if userID, ok := strings.CutPrefix(token, "impersonated:"); ok {
+		return &models.Session{ID: "session-1", UserID: userID, ImpersonatorID: "admin-user"}, nil
+	}
+	if key, ok := strings.CutPrefix(token, "apikey:"); ok {
+		userID, scopes, _ := strings.Cut(key, ":")
+		return &models.Session{ID: "key-1", UserID: userID, APIKeyID: "key-1", Scopes: strings.Split(scopes, ",")}, nil
+	}
+	if strings.HasPrefix(token, model.APIKeyPrefix) {
+		// fsk_<id>_<secret>, with both scopes
+		prefix := token[:strings.LastIndex(token, "_")]
+		return &models.Session{ID: "key-" + prefix, UserID: "user-1", APIKeyID: "key-" + prefix, Scopes: []string{model.APIKeyScopeRead, model.APIKeyScopeWrite}}, nil
+	}
+	if account, ok := strings.CutPrefix(token, "service:"); ok {
+		id, scopes, _ := strings.Cut(account, ":")
+		return &models.Session{ID: "token-1", ServiceAccountID: id, Permissions: strings.Split(scopes, ",")}, nil
+	}
+	if account, ok := strings.CutPrefix(token, "orgservice:"); ok {
+		id, role, _ := strings.Cut(account, ":")
+		return &models.Session{ID: "token-1", ServiceAccountID: id, OrgID: testOrgID, OrgRole: role}, nil
+	}
+	return &models.Session{ID: "session-1", UserID: token}, nil
+}
+
+// newTestAdminServer creates an Echo instance with an admin route requiring
+// the roles:manage permission
+func newTestAdminServer(apiKey string) *echo.Echo {
+	checker := fakePermissionChecker{
+		"admin-user":   {model.PermissionRolesManage},
+		"support-user": {model.PermissionUsersRead},
+	}
+
+	e := echo.New()
+	g := e.Group("/api/v1/admin", AdminAuthMiddleware(apiKey, fakeAuthService{}))
+	g.POST("/users/:id/roles", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, RequirePermission(checker, model.PermissionRolesManage, logger.DefaultLogger()))
+	return e
+}
+
+func TestRequirePermission(t *testing.T) {
+	e := newTestAdminServer("secret-key")
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"admin API key", AdminKeyHeader, "secret-key", http.StatusNoContent},
+		{"wrong admin API key", AdminKeyHeader, "wrong-key", http.StatusForbidden},
+		{"user with the permission", "Authorization", "Bearer admin-user", http.StatusNoContent},
+		{"user without the permission", "Authorization", "Bearer support-user", http.StatusForbidden},
+		{"impersonating a user with the permission", "Authorization", "Bearer impersonated:admin-user", http.StatusForbidden},
+		{"API key with the permission scope", "Authorization", "Bearer apikey:admin-user:write,roles:manage", http.StatusNoContent},
+		{"API key without the permission scope", "Authorization", "Bearer apikey:admin-user:write", http.StatusForbidden},
+		{"service account with the permission", "Authorization", "Bearer service:sa-1:users:read,roles:manage", http.StatusNoContent},
+		{"service account without the permission", "Authorization", "Bearer service:sa-1:users:read", http.StatusForbidden},
+		{"invalid access token", "Authorization", "Bearer invalid", http.StatusUnauthorized},
+		{"permission check failure", "Authorization", "Bearer broken", http.StatusInternalServerError},
+		{"no credentials", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/roles", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAdminAuthMiddlewareWithoutKey(t *testing.T) {
+	e := newTestAdminServer("")
+
+	// The admin API key is disabled, users still get in with their permissions
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/roles", nil)
+	req.Header.Set(AdminKeyHeader, "")
+	req.Header.Set("Authorization", "Bearer admin-user")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/roles", nil)
+	req.Header.Set(AdminKeyHeader, "anything")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// MembershipContextKey is set to the *model.Membership of the user in the
+// organization of tenant-scoped requests
+const MembershipContextKey = "membership"
+
+// MembershipLoader gets the memberships of users in organizations
+type MembershipLoader interface {
+	// GetMembership gets the membership of a user in an organization, or nil if they aren't a member
+	GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error)
+}
+
+// TenantMiddleware creates a middleware for routes scoped to the organization
+// in their :orgID parameter. It loads the user's membership of the
+// organization into the context, and rejects users who aren't members as if
+// the organization didn't exist. It requires the auth middleware to run first.
+// Memberships are loaded on every request rather than read from the token
+// claims, so removed members lose access right away. Service accounts are
+// only let into the organization owning them, with the role they were given.
+func TenantMiddleware(memberships MembershipLoader, log logger.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			orgID := c.Param("orgID")
+
+			if principal := GetPrincipal(c); principal != nil && principal.IsService() {
+				if principal.OrganizationID == "" || principal.OrganizationID != orgID {
+					return echo.NewHTTPError(http.StatusNotFound, "organization not found")
+				}
+				c.Set(MembershipContextKey, &model.Membership{
+					OrganizationID: orgID,
+					UserID:         principal.ID,
+					Role:           principal.OrgRole,
+					ServiceAccount: true,
+				})
+				return next(c)
+			}
+
+			userID, _ := c.Get("user_id").(string)
+			if userID == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+			}
+
+			if _, err := uuid.Parse(orgID); err != nil {
+				return echo.NewHTTPError(http.StatusNotFound, "organization not found")
+			}
+
+			membership, err := memberships.GetMembership(c.Request().Context(), orgID, userID)
+			if err != nil {
+				log.Error("Failed to get membership", "organization_id", orgID, "user_id", userID, "error", err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to check membership")
+			}
+			if membership == nil {
+				return echo.NewHTTPError(http.StatusNotFound, "organization not found")
+			}
+
+			c.Set(MembershipContextKey, membership)
+			return next(c)
+		}
+	}
+}
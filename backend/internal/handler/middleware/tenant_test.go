@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const testOrgID = "8b7e4c1a-2f3d-4e5f-9a6b-7c8d9e0f1a2b"
+
+// fakeMemberships makes admin-user an admin and member-user a member of the
+// test organization
+type fakeMemberships struct{}
+
+func (fakeMemberships) GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	if userID == "broken" {
+		return nil, errors.New("database is down")
+	}
+	roles := map[string]string{"admin-user": model.OrgRoleAdmin, "member-user": model.OrgRoleMember}
+	if role, ok := roles[userID]; ok && orgID == testOrgID {
+		return &model.Membership{OrganizationID: orgID, UserID: userID, Role: role}, nil
+	}
+	return nil, nil
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	e := echo.New()
+	g := e.Group("/api/v1/orgs/:orgID", AuthMiddleware(fakeAuthService{}), TenantMiddleware(fakeMemberships{}, logger.DefaultLogger()))
+	g.GET("/members", func(c echo.Context) error {
+		membership := c.Get(MembershipContextKey).(*model.Membership)
+		return c.String(http.StatusOK, membership.Role)
+	})
+
+	tests := []struct {
+		name  string
+		orgID string
+		token string
+		want  int
+		role  string
+	}{
+		{"admin", testOrgID, "admin-user", http.StatusOK, model.OrgRoleAdmin},
+		{"member", testOrgID, "member-user", http.StatusOK, model.OrgRoleMember},
+		{"not a member", testOrgID, "other-user", http.StatusNotFound, ""},
+		{"other organization", "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b", "admin-user", http.StatusNotFound, ""},
+		{"invalid organization ID", "acme", "admin-user", http.StatusNotFound, ""},
+		{"membership check failure", testOrgID, "broken", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/orgs/"+tt.orgID+"/members", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.role != "" {
+				assert.Equal(t, tt.role, rec.Body.String())
+			}
+		})
+	}
+}
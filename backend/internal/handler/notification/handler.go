@@ -0,0 +1,191 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// NotificationService defines the interface for the notification preferences service
+type NotificationService interface {
+	GetPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error)
+	UpdatePreferences(ctx context.Context, userID string, updates []*model.NotificationPreference) ([]*model.NotificationPreference, error)
+	UnsubscribeCategory(token string) (string, error)
+	Unsubscribe(ctx context.Context, token string) (*model.NotificationPreference, error)
+}
+
+// Handler handles notification preference requests
+type Handler struct {
+	notificationService NotificationService
+}
+
+// NewHandler creates a new notification handler
+func NewHandler(notificationService NotificationService) *Handler {
+	return &Handler{
+		notificationService: notificationService,
+	}
+}
+
+// GetPreferences godoc
+// @Summary Get notification preferences
+// @Description Get whether the current user receives each category of notifications on each channel
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} PreferencesResponse "Notification preferences"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/notifications/preferences [get]
+func (h *Handler) GetPreferences(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	prefs, err := h.notificationService.GetPreferences(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get notification preferences"))
+	}
+
+	return c.JSON(http.StatusOK, newPreferencesResponse(prefs))
+}
+
+// UpdatePreferences godoc
+// @Summary Update notification preferences
+// @Description Turn categories of notifications on or off for the current user. Account notifications can't be turned off.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdatePreferencesRequest true "Preferences to change"
+// @Success 200 {object} PreferencesResponse "Updated notification preferences"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/notifications/preferences [put]
+func (h *Handler) UpdatePreferences(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	var req UpdatePreferencesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	updates := make([]*model.NotificationPreference, len(req.Preferences))
+	for i, update := range req.Preferences {
+		if update.Enabled == nil {
+			return c.JSON(http.StatusBadRequest, response.NewErrorResponse("enabled is required"))
+		}
+		updates[i] = &model.NotificationPreference{
+			Category: update.Category,
+			Channel:  update.Channel,
+			Enabled:  *update.Enabled,
+		}
+	}
+
+	prefs, err := h.notificationService.UpdatePreferences(c.Request().Context(), userID, updates)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusBadRequest {
+			return c.JSON(http.StatusBadRequest, response.NewErrorResponse(appErr.Message))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to update notification preferences"))
+	}
+
+	return c.JSON(http.StatusOK, newPreferencesResponse(prefs))
+}
+
+// GetUnsubscribe godoc
+// @Summary Check an unsubscribe link
+// @Description Get the notification category an unsubscribe link turns off, without turning it off, so the unsubscribe page can ask for confirmation
+// @Tags notifications
+// @Produce json
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {object} UnsubscribeResponse "Notification category of the link"
+// @Failure 400 {object} ErrorResponse "Invalid unsubscribe token"
+// @Router /api/v1/notifications/unsubscribe [get]
+func (h *Handler) GetUnsubscribe(c echo.Context) error {
+	category, err := h.notificationService.UnsubscribeCategory(c.QueryParam("token"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid unsubscribe token"))
+	}
+
+	return c.JSON(http.StatusOK, UnsubscribeResponse{
+		Category: category,
+		Channel:  model.NotificationChannelEmail,
+		Enabled:  true,
+	})
+}
+
+// Unsubscribe godoc
+// @Summary Unsubscribe from emails
+// @Description Turn off the email notifications of an unsubscribe link. Mail clients call it with a List-Unsubscribe=One-Click form body (RFC 8058); the link's token authenticates the request.
+// @Tags notifications
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {object} UnsubscribeResponse "Notification category turned off"
+// @Failure 400 {object} ErrorResponse "Invalid unsubscribe token"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/notifications/unsubscribe [post]
+func (h *Handler) Unsubscribe(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		token = c.FormValue("token")
+	}
+
+	pref, err := h.notificationService.Unsubscribe(c.Request().Context(), token)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			switch appErr.StatusCode {
+			case http.StatusBadRequest:
+				return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid unsubscribe token"))
+			case http.StatusNotFound:
+				return c.JSON(http.StatusNotFound, response.NewErrorResponse("User not found"))
+			}
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to unsubscribe"))
+	}
+
+	return c.JSON(http.StatusOK, UnsubscribeResponse{
+		Category: pref.Category,
+		Channel:  pref.Channel,
+		Enabled:  pref.Enabled,
+	})
+}
+
+// RegisterRoutes registers the notification routes. Preferences require the
+// auth middleware, while unsubscribe links are authenticated by their token.
+func (h *Handler) RegisterRoutes(g *echo.Group, auth echo.MiddlewareFunc) {
+	g.GET("/preferences", h.GetPreferences, auth)
+	g.PUT("/preferences", h.UpdatePreferences, auth)
+	g.GET("/unsubscribe", h.GetUnsubscribe)
+	g.POST("/unsubscribe", h.Unsubscribe)
+}
+
+// newPreferencesResponse converts notification preferences to their response model
+func newPreferencesResponse(prefs []*model.NotificationPreference) PreferencesResponse {
+	items := make([]PreferenceItem, len(prefs))
+	for i, pref := range prefs {
+		items[i] = PreferenceItem{
+			Category: pref.Category,
+			Channel:  pref.Channel,
+			Enabled:  pref.Enabled,
+			Required: pref.Required,
+		}
+		if pref.UpdatedAt != nil {
+			items[i].UpdatedAt = pref.UpdatedAt.Format(time.RFC3339)
+		}
+	}
+
+	return PreferencesResponse{
+		Preferences: items,
+	}
+}
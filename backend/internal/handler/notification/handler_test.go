@@ -0,0 +1,195 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// MockNotificationService is a mock implementation of the notification service
+type MockNotificationService struct {
+	mock.Mock
+}
+
+// GetPreferences mocks the GetPreferences method
+func (m *MockNotificationService) GetPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NotificationPreference), args.Error(1)
+}
+
+// UpdatePreferences mocks the UpdatePreferences method
+func (m *MockNotificationService) UpdatePreferences(ctx context.Context, userID string, updates []*model.NotificationPreference) ([]*model.NotificationPreference, error) {
+	args := m.Called(ctx, userID, updates)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NotificationPreference), args.Error(1)
+}
+
+// UnsubscribeCategory mocks the UnsubscribeCategory method
+func (m *MockNotificationService) UnsubscribeCategory(token string) (string, error) {
+	args := m.Called(token)
+	return args.String(0), args.Error(1)
+}
+
+// Unsubscribe mocks the Unsubscribe method
+func (m *MockNotificationService) Unsubscribe(ctx context.Context, token string) (*model.NotificationPreference, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.NotificationPreference), args.Error(1)
+}
+
+// TestUpdatePreferences tests the UpdatePreferences handler
+func TestUpdatePreferences(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new notification handler with a mock service
+	mockService := new(MockNotificationService)
+	handler := NewHandler(mockService)
+
+	t.Run("updates preferences", func(t *testing.T) {
+		// Create a new HTTP request
+		body := `{"preferences":[{"category":"security_digest","channel":"email","enabled":false}]}`
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/notifications/preferences", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		// Set up expectations
+		updates := []*model.NotificationPreference{
+			{Category: model.NotificationCategorySecurityDigest, Channel: model.NotificationChannelEmail, Enabled: false},
+		}
+		mockService.On("UpdatePreferences", mock.Anything, "user-1", updates).Return([]*model.NotificationPreference{
+			{Category: model.NotificationCategoryAccount, Channel: model.NotificationChannelEmail, Enabled: true, Required: true},
+			{Category: model.NotificationCategorySecurityDigest, Channel: model.NotificationChannelEmail, Enabled: false},
+		}, nil).Once()
+
+		// Call the handler
+		err := handler.UpdatePreferences(c)
+
+		// Assert the response
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp PreferencesResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Len(t, resp.Preferences, 2)
+		assert.True(t, resp.Preferences[0].Required)
+		assert.False(t, resp.Preferences[1].Enabled)
+	})
+
+	t.Run("rejects turning off account emails", func(t *testing.T) {
+		// Create a new HTTP request
+		body := `{"preferences":[{"category":"account","channel":"email","enabled":false}]}`
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/notifications/preferences", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		// Set up expectations
+		mockService.On("UpdatePreferences", mock.Anything, "user-1", mock.Anything).
+			Return(nil, apperrors.NewValidationError("account notifications can't be turned off")).Once()
+
+		// Call the handler
+		err := handler.UpdatePreferences(c)
+
+		// Assert the response
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "account notifications can't be turned off")
+	})
+
+	t.Run("requires enabled", func(t *testing.T) {
+		// Create a new HTTP request
+		body := `{"preferences":[{"category":"security_digest","channel":"email"}]}`
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/notifications/preferences", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		// Call the handler
+		err := handler.UpdatePreferences(c)
+
+		// Assert the response
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	mockService.AssertExpectations(t)
+}
+
+// TestUnsubscribe tests the one-click Unsubscribe handler
+func TestUnsubscribe(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new notification handler with a mock service
+	mockService := new(MockNotificationService)
+	handler := NewHandler(mockService)
+
+	t.Run("unsubscribes with a one-click POST", func(t *testing.T) {
+		// Create a new HTTP request, as sent by mail clients (RFC 8058)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/notifications/unsubscribe?token=valid-token", strings.NewReader("List-Unsubscribe=One-Click"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		// Set up expectations
+		mockService.On("Unsubscribe", mock.Anything, "valid-token").Return(&model.NotificationPreference{
+			Category: model.NotificationCategorySecurityDigest,
+			Channel:  model.NotificationChannelEmail,
+			Enabled:  false,
+		}, nil).Once()
+
+		// Call the handler
+		err := handler.Unsubscribe(c)
+
+		// Assert the response
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp UnsubscribeResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, model.NotificationCategorySecurityDigest, resp.Category)
+		assert.False(t, resp.Enabled)
+	})
+
+	t.Run("rejects invalid tokens", func(t *testing.T) {
+		// Create a new HTTP request
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/notifications/unsubscribe?token=forged", strings.NewReader("List-Unsubscribe=One-Click"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		// Set up expectations
+		mockService.On("Unsubscribe", mock.Anything, "forged").Return(nil, apperrors.NewValidationError("invalid unsubscribe token")).Once()
+
+		// Call the handler
+		err := handler.Unsubscribe(c)
+
+		// Assert the response
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	mockService.AssertExpectations(t)
+}
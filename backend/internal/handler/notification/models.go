@@ -0,0 +1,40 @@
+package notification
+
+// PreferencesResponse represents a user's notification preferences
+type PreferencesResponse struct {
+	Preferences []PreferenceItem `json:"preferences"`
+}
+
+// PreferenceItem represents whether a user receives the notifications of a category on a channel
+type PreferenceItem struct {
+	Category string `json:"category" example:"security_digest"`
+	Channel  string `json:"channel" example:"email"`
+	Enabled  bool   `json:"enabled" example:"true"`
+	// Set for categories that can't be turned off
+	Required  bool   `json:"required" example:"false"`
+	UpdatedAt string `json:"updated_at,omitempty" example:"2023-01-01T12:00:00Z"`
+}
+
+// UpdatePreferencesRequest represents a notification preferences update request
+type UpdatePreferencesRequest struct {
+	Preferences []PreferenceUpdate `json:"preferences" validate:"required"`
+}
+
+// PreferenceUpdate represents a change to a single notification preference
+type PreferenceUpdate struct {
+	Category string `json:"category" validate:"required" example:"security_digest"`
+	Channel  string `json:"channel" validate:"required" example:"email"`
+	Enabled  *bool  `json:"enabled" validate:"required" example:"false"`
+}
+
+// UnsubscribeResponse represents the notification category an unsubscribe link is for
+type UnsubscribeResponse struct {
+	Category string `json:"category" example:"security_digest"`
+	Channel  string `json:"channel" example:"email"`
+	Enabled  bool   `json:"enabled" example:"false"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"Invalid unsubscribe token"`
+}
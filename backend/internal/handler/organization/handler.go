@@ -0,0 +1,455 @@
+package organization
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/middleware"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// Organizations defines the interface for managing organizations and their members
+type Organizations interface {
+	CreateOrganization(ctx context.Context, userID, name string) (*model.Organization, error)
+	ListOrganizations(ctx context.Context, userID string) ([]*model.UserOrganization, error)
+	GetOrganization(ctx context.Context, orgID string) (*model.Organization, error)
+	RenameOrganization(ctx context.Context, actor *model.Membership, name string) (*model.Organization, error)
+	DeleteOrganization(ctx context.Context, actor *model.Membership) error
+	ListMembers(ctx context.Context, orgID string) ([]*model.Member, error)
+	ChangeMemberRole(ctx context.Context, actor *model.Membership, userID, role string) error
+	RemoveMember(ctx context.Context, actor *model.Membership, userID string) error
+	InviteMember(ctx context.Context, actor *model.Membership, email, role string) (*model.Invitation, error)
+	ListInvitations(ctx context.Context, actor *model.Membership) ([]*model.Invitation, error)
+	RevokeInvitation(ctx context.Context, actor *model.Membership, id string) error
+	AcceptInvitation(ctx context.Context, userID, token string) (*model.UserOrganization, error)
+}
+
+// Switcher defines the interface for issuing tokens scoped to an organization
+type Switcher interface {
+	SwitchOrganization(ctx context.Context, userID, orgID string) (*models.RefreshTokenResponse, error)
+}
+
+// Handler handles organization requests
+type Handler struct {
+	orgs     Organizations
+	switcher Switcher
+}
+
+// NewHandler creates a new organization handler
+func NewHandler(orgs Organizations, switcher Switcher) *Handler {
+	return &Handler{
+		orgs:     orgs,
+		switcher: switcher,
+	}
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create an organization owned by the current user
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateOrganizationRequest true "Organization to create"
+// @Success 201 {object} OrganizationResponse "Created organization"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs [post]
+func (h *Handler) CreateOrganization(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	var req CreateOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	org, err := h.orgs.CreateOrganization(c.Request().Context(), userID, req.Name)
+	if err != nil {
+		return orgError(c, err, "Failed to create organization")
+	}
+
+	return c.JSON(http.StatusCreated, newOrganizationResponse(org, model.OrgRoleOwner))
+}
+
+// ListOrganizations godoc
+// @Summary List organizations
+// @Description List the organizations the current user is a member of, with their role in each
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} OrganizationsResponse "Organizations"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs [get]
+func (h *Handler) ListOrganizations(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	orgs, err := h.orgs.ListOrganizations(c.Request().Context(), userID)
+	if err != nil {
+		return orgError(c, err, "Failed to list organizations")
+	}
+
+	items := make([]OrganizationResponse, len(orgs))
+	for i, org := range orgs {
+		items[i] = newOrganizationResponse(&org.Organization, org.Role)
+	}
+
+	return c.JSON(http.StatusOK, OrganizationsResponse{Organizations: items})
+}
+
+// SwitchOrganization godoc
+// @Summary Switch organization
+// @Description Issue tokens for acting in an organization the current user is a member of, or in their personal account when org_id is empty. Not available with an API key or while impersonating.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SwitchOrganizationRequest true "Organization to act in"
+// @Success 200 {object} TokenResponse "Tokens for the organization"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed with an API key or while impersonating"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/switch [post]
+func (h *Handler) SwitchOrganization(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	var req SwitchOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	tokens, err := h.switcher.SwitchOrganization(c.Request().Context(), userID, req.OrgID)
+	if err != nil {
+		return orgError(c, err, "Failed to switch organization")
+	}
+
+	return c.JSON(http.StatusOK, TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Join an organization with the token of an invitation sent to the current user's email address. Not available with an API key or while impersonating.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AcceptInvitationRequest true "Invitation token"
+// @Success 200 {object} OrganizationResponse "Joined organization"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Invitation was sent to another email address"
+// @Failure 404 {object} ErrorResponse "Invitation not found or expired"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/invitations/accept [post]
+func (h *Handler) AcceptInvitation(c echo.Context) error {
+	// Get user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	var req AcceptInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	org, err := h.orgs.AcceptInvitation(c.Request().Context(), userID, req.Token)
+	if err != nil {
+		return orgError(c, err, "Failed to accept invitation")
+	}
+
+	return c.JSON(http.StatusOK, newOrganizationResponse(&org.Organization, org.Role))
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Get an organization the current user is a member of
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Success 200 {object} OrganizationResponse "Organization"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID} [get]
+func (h *Handler) GetOrganization(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	org, err := h.orgs.GetOrganization(c.Request().Context(), membership.OrganizationID)
+	if err != nil {
+		return orgError(c, err, "Failed to get organization")
+	}
+
+	return c.JSON(http.StatusOK, newOrganizationResponse(org, membership.Role))
+}
+
+// RenameOrganization godoc
+// @Summary Rename an organization
+// @Description Rename an organization. Needs the admin role.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param request body RenameOrganizationRequest true "New name"
+// @Success 200 {object} OrganizationResponse "Renamed organization"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID} [patch]
+func (h *Handler) RenameOrganization(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	var req RenameOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	org, err := h.orgs.RenameOrganization(c.Request().Context(), membership, req.Name)
+	if err != nil {
+		return orgError(c, err, "Failed to rename organization")
+	}
+
+	return c.JSON(http.StatusOK, newOrganizationResponse(org, membership.Role))
+}
+
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Description Delete an organization with its memberships and invitations. Needs the owner role, and isn't available with an API key or while impersonating.
+// @Tags organizations
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Success 204 "Organization deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID} [delete]
+func (h *Handler) DeleteOrganization(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	if err := h.orgs.DeleteOrganization(c.Request().Context(), membership); err != nil {
+		return orgError(c, err, "Failed to delete organization")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListMembers godoc
+// @Summary List members
+// @Description List the members of an organization, oldest first
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Success 200 {object} MembersResponse "Members"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/members [get]
+func (h *Handler) ListMembers(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	members, err := h.orgs.ListMembers(c.Request().Context(), membership.OrganizationID)
+	if err != nil {
+		return orgError(c, err, "Failed to list members")
+	}
+
+	items := make([]MemberItem, len(members))
+	for i, member := range members {
+		items[i] = newMemberItem(member)
+	}
+
+	return c.JSON(http.StatusOK, MembersResponse{Members: items})
+}
+
+// ChangeMemberRole godoc
+// @Summary Change a member's role
+// @Description Change the role of a member. Needs the admin role, and only owners can make or unmake owners. The last owner can't be demoted.
+// @Tags organizations
+// @Accept json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param userID path string true "User ID"
+// @Param request body ChangeRoleRequest true "New role (owner, admin or member)"
+// @Success 204 "Role changed"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Member not found"
+// @Failure 409 {object} ErrorResponse "Last owner"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/members/{userID} [patch]
+func (h *Handler) ChangeMemberRole(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	var req ChangeRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	if err := h.orgs.ChangeMemberRole(c.Request().Context(), membership, c.Param("userID"), req.Role); err != nil {
+		return orgError(c, err, "Failed to change member role")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveMember godoc
+// @Summary Remove a member
+// @Description Remove a member from an organization. Members can always leave; removing others needs the admin role, and only owners can remove owners. The last owner can't leave.
+// @Tags organizations
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param userID path string true "User ID"
+// @Success 204 "Member removed"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Member not found"
+// @Failure 409 {object} ErrorResponse "Last owner"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/members/{userID} [delete]
+func (h *Handler) RemoveMember(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	if err := h.orgs.RemoveMember(c.Request().Context(), membership, c.Param("userID")); err != nil {
+		return orgError(c, err, "Failed to remove member")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListInvitations godoc
+// @Summary List invitations
+// @Description List the pending invitations of an organization, newest first. Needs the admin role.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Success 200 {object} InvitationsResponse "Pending invitations"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/invitations [get]
+func (h *Handler) ListInvitations(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	invitations, err := h.orgs.ListInvitations(c.Request().Context(), membership)
+	if err != nil {
+		return orgError(c, err, "Failed to list invitations")
+	}
+
+	items := make([]InvitationItem, len(invitations))
+	for i, invitation := range invitations {
+		items[i] = newInvitationItem(invitation)
+	}
+
+	return c.JSON(http.StatusOK, InvitationsResponse{Invitations: items})
+}
+
+// InviteMember godoc
+// @Summary Invite a member
+// @Description Email someone an invitation to join an organization as an admin or member. Needs the admin role. Inviting an address again replaces its pending invitation.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param request body InviteMemberRequest true "Address and role to invite"
+// @Success 201 {object} InvitationItem "Invitation"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 409 {object} ErrorResponse "Already a member"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/invitations [post]
+func (h *Handler) InviteMember(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	var req InviteMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	invitation, err := h.orgs.InviteMember(c.Request().Context(), membership, req.Email, req.Role)
+	if err != nil {
+		return orgError(c, err, "Failed to invite member")
+	}
+
+	return c.JSON(http.StatusCreated, newInvitationItem(invitation))
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Delete a pending invitation, so its link stops working. Needs the admin role.
+// @Tags organizations
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param id path string true "Invitation ID"
+// @Success 204 "Invitation revoked"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing role"
+// @Failure 404 {object} ErrorResponse "Invitation not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/invitations/{id} [delete]
+func (h *Handler) RevokeInvitation(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	if err := h.orgs.RevokeInvitation(c.Request().Context(), membership, c.Param("id")); err != nil {
+		return orgError(c, err, "Failed to revoke invitation")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterRoutes registers the organization routes. Routes of a single
+// organization require the tenant middleware, and routes that act on behalf
+// of the user beyond the organization require the sensitive middleware.
+// Service accounts can only use the routes of their own organization.
+func (h *Handler) RegisterRoutes(g *echo.Group, tenant, sensitive echo.MiddlewareFunc) {
+	people := middleware.DenyServiceAccounts()
+	g.POST("", h.CreateOrganization, people)
+	g.GET("", h.ListOrganizations, people)
+	g.POST("/switch", h.SwitchOrganization, sensitive)
+	g.POST("/invitations/accept", h.AcceptInvitation, sensitive)
+
+	org := g.Group("/:orgID", tenant)
+	org.GET("", h.GetOrganization)
+	org.PATCH("", h.RenameOrganization)
+	org.DELETE("", h.DeleteOrganization, sensitive)
+	org.GET("/members", h.ListMembers)
+	org.PATCH("/members/:userID", h.ChangeMemberRole)
+	org.DELETE("/members/:userID", h.RemoveMember)
+	org.GET("/invitations", h.ListInvitations)
+	org.POST("/invitations", h.InviteMember)
+	org.DELETE("/invitations/:id", h.RevokeInvitation)
+}
+
+// orgError responds with the error of an organization request, hiding
+// unexpected errors behind a generic message
+func orgError(c echo.Context, err error, message string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.StatusCode {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict:
+			return c.JSON(appErr.StatusCode, response.NewErrorResponse(appErr.Message))
+		}
+	}
+	return c.JSON(http.StatusInternalServerError, response.NewErrorResponse(message))
+}
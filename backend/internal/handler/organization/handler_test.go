@@ -0,0 +1,317 @@
+package organization
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/middleware"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// MockOrganizations is a mock implementation of the organization service
+type MockOrganizations struct {
+	mock.Mock
+}
+
+// CreateOrganization mocks the CreateOrganization method
+func (m *MockOrganizations) CreateOrganization(ctx context.Context, userID, name string) (*model.Organization, error) {
+	args := m.Called(ctx, userID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Organization), args.Error(1)
+}
+
+// ListOrganizations mocks the ListOrganizations method
+func (m *MockOrganizations) ListOrganizations(ctx context.Context, userID string) ([]*model.UserOrganization, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.UserOrganization), args.Error(1)
+}
+
+// GetOrganization mocks the GetOrganization method
+func (m *MockOrganizations) GetOrganization(ctx context.Context, orgID string) (*model.Organization, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Organization), args.Error(1)
+}
+
+// RenameOrganization mocks the RenameOrganization method
+func (m *MockOrganizations) RenameOrganization(ctx context.Context, actor *model.Membership, name string) (*model.Organization, error) {
+	args := m.Called(ctx, actor, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Organization), args.Error(1)
+}
+
+// DeleteOrganization mocks the DeleteOrganization method
+func (m *MockOrganizations) DeleteOrganization(ctx context.Context, actor *model.Membership) error {
+	args := m.Called(ctx, actor)
+	return args.Error(0)
+}
+
+// ListMembers mocks the ListMembers method
+func (m *MockOrganizations) ListMembers(ctx context.Context, orgID string) ([]*model.Member, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Member), args.Error(1)
+}
+
+// ChangeMemberRole mocks the ChangeMemberRole method
+func (m *MockOrganizations) ChangeMemberRole(ctx context.Context, actor *model.Membership, userID, role string) error {
+	args := m.Called(ctx, actor, userID, role)
+	return args.Error(0)
+}
+
+// RemoveMember mocks the RemoveMember method
+func (m *MockOrganizations) RemoveMember(ctx context.Context, actor *model.Membership, userID string) error {
+	args := m.Called(ctx, actor, userID)
+	return args.Error(0)
+}
+
+// InviteMember mocks the InviteMember method
+func (m *MockOrganizations) InviteMember(ctx context.Context, actor *model.Membership, email, role string) (*model.Invitation, error) {
+	args := m.Called(ctx, actor, email, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invitation), args.Error(1)
+}
+
+// ListInvitations mocks the ListInvitations method
+func (m *MockOrganizations) ListInvitations(ctx context.Context, actor *model.Membership) ([]*model.Invitation, error) {
+	args := m.Called(ctx, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Invitation), args.Error(1)
+}
+
+// RevokeInvitation mocks the RevokeInvitation method
+func (m *MockOrganizations) RevokeInvitation(ctx context.Context, actor *model.Membership, id string) error {
+	args := m.Called(ctx, actor, id)
+	return args.Error(0)
+}
+
+// AcceptInvitation mocks the AcceptInvitation method
+func (m *MockOrganizations) AcceptInvitation(ctx context.Context, userID, token string) (*model.UserOrganization, error) {
+	args := m.Called(ctx, userID, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserOrganization), args.Error(1)
+}
+
+// MockSwitcher is a mock implementation of the organization switcher
+type MockSwitcher struct {
+	mock.Mock
+}
+
+// SwitchOrganization mocks the SwitchOrganization method
+func (m *MockSwitcher) SwitchOrganization(ctx context.Context, userID, orgID string) (*models.RefreshTokenResponse, error) {
+	args := m.Called(ctx, userID, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshTokenResponse), args.Error(1)
+}
+
+// TestCreateOrganization tests the CreateOrganization handler
+func TestCreateOrganization(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockOrgs := new(MockOrganizations)
+	handler := NewHandler(mockOrgs, new(MockSwitcher))
+
+	t.Run("creates an organization owned by the user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs", strings.NewReader(`{"name":"Acme"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		now := time.Now()
+		mockOrgs.On("CreateOrganization", mock.Anything, "user-1", "Acme").Return(&model.Organization{
+			ID: "org-1", Name: "Acme", CreatedAt: now, UpdatedAt: now,
+		}, nil).Once()
+
+		err := handler.CreateOrganization(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp OrganizationResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "org-1", resp.ID)
+		assert.Equal(t, model.OrgRoleOwner, resp.Role)
+	})
+
+	t.Run("rejects an invalid name", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs", strings.NewReader(`{"name":""}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		mockOrgs.On("CreateOrganization", mock.Anything, "user-1", "").Return(nil, apperrors.NewValidationError("name is required")).Once()
+
+		err := handler.CreateOrganization(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "name is required")
+	})
+
+	mockOrgs.AssertExpectations(t)
+}
+
+// TestChangeMemberRole tests the ChangeMemberRole handler
+func TestChangeMemberRole(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	membership := &model.Membership{OrganizationID: "org-1", UserID: "user-1", Role: model.OrgRoleAdmin}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "changes the role", wantCode: http.StatusNoContent},
+		{name: "missing role", err: apperrors.NewAuthorizationError("requires the owner role"), wantCode: http.StatusForbidden},
+		{name: "last owner", err: apperrors.NewConflictError("an organization needs an owner"), wantCode: http.StatusConflict},
+		{name: "unexpected error", err: assert.AnError, wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgs := new(MockOrganizations)
+			handler := NewHandler(mockOrgs, new(MockSwitcher))
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/orgs/org-1/members/user-2", strings.NewReader(`{"role":"owner"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("orgID", "userID")
+			c.SetParamValues("org-1", "user-2")
+			c.Set(middleware.MembershipContextKey, membership)
+
+			mockOrgs.On("ChangeMemberRole", mock.Anything, membership, "user-2", model.OrgRoleOwner).Return(tt.err)
+
+			err := handler.ChangeMemberRole(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			mockOrgs.AssertExpectations(t)
+		})
+	}
+}
+
+// TestInviteMember tests the InviteMember handler
+func TestInviteMember(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	mockOrgs := new(MockOrganizations)
+	handler := NewHandler(mockOrgs, new(MockSwitcher))
+	membership := &model.Membership{OrganizationID: "org-1", UserID: "user-1", Role: model.OrgRoleOwner}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs/org-1/invitations", strings.NewReader(`{"email":"new@example.com","role":"member"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(middleware.MembershipContextKey, membership)
+
+	invitedBy := "user-1"
+	mockOrgs.On("InviteMember", mock.Anything, membership, "new@example.com", model.OrgRoleMember).Return(&model.Invitation{
+		ID:             "inv-1",
+		OrganizationID: "org-1",
+		Email:          "new@example.com",
+		Role:           model.OrgRoleMember,
+		TokenHash:      "secret-hash",
+		InvitedBy:      &invitedBy,
+		ExpiresAt:      time.Now().Add(time.Hour),
+		CreatedAt:      time.Now(),
+	}, nil)
+
+	err := handler.InviteMember(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+
+	var resp InvitationItem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "inv-1", resp.ID)
+	assert.Equal(t, "user-1", resp.InvitedBy)
+	mockOrgs.AssertExpectations(t)
+}
+
+// TestSwitchOrganization tests the SwitchOrganization handler
+func TestSwitchOrganization(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("issues tokens for the organization", func(t *testing.T) {
+		mockSwitcher := new(MockSwitcher)
+		handler := NewHandler(new(MockOrganizations), mockSwitcher)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs/switch", strings.NewReader(`{"org_id":"org-1"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		mockSwitcher.On("SwitchOrganization", mock.Anything, "user-1", "org-1").Return(&models.RefreshTokenResponse{
+			AccessToken: "access", RefreshToken: "refresh",
+		}, nil)
+
+		err := handler.SwitchOrganization(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp TokenResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "access", resp.AccessToken)
+		assert.Equal(t, "refresh", resp.RefreshToken)
+		mockSwitcher.AssertExpectations(t)
+	})
+
+	t.Run("rejects organizations the user isn't a member of", func(t *testing.T) {
+		mockSwitcher := new(MockSwitcher)
+		handler := NewHandler(new(MockOrganizations), mockSwitcher)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs/switch", strings.NewReader(`{"org_id":"org-2"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "user-1")
+
+		mockSwitcher.On("SwitchOrganization", mock.Anything, "user-1", "org-2").Return(nil, apperrors.NewNotFoundError("organization not found"))
+
+		err := handler.SwitchOrganization(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,131 @@
+package organization
+
+import (
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// CreateOrganizationRequest represents a request to create an organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required" example:"Acme"`
+}
+
+// RenameOrganizationRequest represents a request to rename an organization
+type RenameOrganizationRequest struct {
+	Name string `json:"name" validate:"required" example:"Acme Corporation"`
+}
+
+// SwitchOrganizationRequest represents a request to act in another organization
+type SwitchOrganizationRequest struct {
+	// Leave empty to act in the personal account
+	OrgID string `json:"org_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+// ChangeRoleRequest represents a request to change the role of a member
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required" example:"admin"`
+}
+
+// InviteMemberRequest represents a request to invite someone to an organization
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email" example:"user@example.com"`
+	Role  string `json:"role" validate:"required" example:"member"`
+}
+
+// AcceptInvitationRequest represents a request to accept an invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// OrganizationResponse represents an organization
+type OrganizationResponse struct {
+	ID   string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name string `json:"name" example:"Acme"`
+	// Role of the current user in the organization
+	Role      string `json:"role,omitempty" example:"owner"`
+	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	UpdatedAt string `json:"updated_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// OrganizationsResponse represents the organizations of the current user
+type OrganizationsResponse struct {
+	Organizations []OrganizationResponse `json:"organizations"`
+}
+
+// MemberItem represents a member of an organization
+type MemberItem struct {
+	UserID    string `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email     string `json:"email" example:"user@example.com"`
+	FullName  string `json:"full_name" example:"John Doe"`
+	Role      string `json:"role" example:"member"`
+	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// MembersResponse represents the members of an organization
+type MembersResponse struct {
+	Members []MemberItem `json:"members"`
+}
+
+// InvitationItem represents a pending invitation to an organization
+type InvitationItem struct {
+	ID        string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email     string `json:"email" example:"user@example.com"`
+	Role      string `json:"role" example:"member"`
+	InvitedBy string `json:"invited_by,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ExpiresAt string `json:"expires_at" example:"2023-01-08T12:00:00Z"`
+	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// InvitationsResponse represents the pending invitations of an organization
+type InvitationsResponse struct {
+	Invitations []InvitationItem `json:"invitations"`
+}
+
+// TokenResponse represents the tokens issued for another organization
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"Organization not found"`
+}
+
+// newOrganizationResponse converts an organization to its response model
+func newOrganizationResponse(org *model.Organization, role string) OrganizationResponse {
+	return OrganizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Role:      role,
+		CreatedAt: org.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: org.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// newMemberItem converts a member to its response model
+func newMemberItem(member *model.Member) MemberItem {
+	return MemberItem{
+		UserID:    member.UserID,
+		Email:     member.Email,
+		FullName:  member.FullName,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// newInvitationItem converts an invitation to its response model
+func newInvitationItem(invitation *model.Invitation) InvitationItem {
+	item := InvitationItem{
+		ID:        invitation.ID,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt.Format(time.RFC3339),
+		CreatedAt: invitation.CreatedAt.Format(time.RFC3339),
+	}
+	if invitation.InvitedBy != nil {
+		item.InvitedBy = *invitation.InvitedBy
+	}
+	return item
+}
@@ -0,0 +1,358 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/middleware"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// grantTypeClientCredentials is the only grant type of the token endpoint
+const grantTypeClientCredentials = "client_credentials"
+
+// ServiceAccounts defines the interface for managing service accounts and
+// issuing their access tokens
+type ServiceAccounts interface {
+	CreateAdminServiceAccount(ctx context.Context, name string, scopes []string, actor model.AdminActor) (string, *model.ServiceAccount, error)
+	ListAdminServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error)
+	RotateAdminServiceAccountSecret(ctx context.Context, id string, actor model.AdminActor) (string, error)
+	DeleteAdminServiceAccount(ctx context.Context, id string, actor model.AdminActor) error
+
+	CreateOrganizationServiceAccount(ctx context.Context, actor *model.Membership, name, role string) (string, *model.ServiceAccount, error)
+	ListOrganizationServiceAccounts(ctx context.Context, actor *model.Membership) ([]*model.ServiceAccount, error)
+	RotateOrganizationServiceAccountSecret(ctx context.Context, actor *model.Membership, id string) (string, error)
+	DeleteOrganizationServiceAccount(ctx context.Context, actor *model.Membership, id string) error
+
+	IssueToken(ctx context.Context, clientID, clientSecret, ipAddress string) (*model.ServiceToken, error)
+}
+
+// Handler handles service account requests
+type Handler struct {
+	serviceAccounts ServiceAccounts
+}
+
+// NewHandler creates a new service account handler
+func NewHandler(serviceAccounts ServiceAccounts) *Handler {
+	return &Handler{
+		serviceAccounts: serviceAccounts,
+	}
+}
+
+// IssueToken godoc
+// @Summary Get a service account access token
+// @Description Exchange the client ID and secret of a service account for a short-lived access token, with the OAuth 2.0 client credentials grant. Credentials are sent as form parameters or with HTTP Basic authentication. The token is sent as "Authorization: Bearer <token>" and can't be refreshed: request a new one when it expires.
+// @Tags service-accounts
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic authentication"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic authentication"
+// @Success 200 {object} TokenResponse "Access token"
+// @Failure 400 {object} TokenErrorResponse "invalid_request or unsupported_grant_type"
+// @Failure 401 {object} TokenErrorResponse "invalid_client"
+// @Failure 429 {object} ErrorResponse "Too many requests"
+// @Failure 500 {object} TokenErrorResponse "server_error"
+// @Router /api/v1/auth/token [post]
+func (h *Handler) IssueToken(c echo.Context) error {
+	// Tokens must not be cached (RFC 6749 section 5.1)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	grantType := c.FormValue("grant_type")
+	if grantType == "" {
+		return c.JSON(http.StatusBadRequest, TokenErrorResponse{Error: "invalid_request"})
+	}
+	if grantType != grantTypeClientCredentials {
+		return c.JSON(http.StatusBadRequest, TokenErrorResponse{Error: "unsupported_grant_type"})
+	}
+
+	clientID, clientSecret, basic := c.Request().BasicAuth()
+	if !basic {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		return c.JSON(http.StatusBadRequest, TokenErrorResponse{Error: "invalid_request"})
+	}
+
+	token, err := h.serviceAccounts.IssueToken(c.Request().Context(), clientID, clientSecret, c.RealIP())
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusUnauthorized {
+			if basic {
+				c.Response().Header().Set("WWW-Authenticate", `Basic realm="token"`)
+			}
+			return c.JSON(http.StatusUnauthorized, TokenErrorResponse{Error: "invalid_client"})
+		}
+		return c.JSON(http.StatusInternalServerError, TokenErrorResponse{Error: "server_error"})
+	}
+
+	return c.JSON(http.StatusOK, TokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(token.ExpiresAt).Round(time.Second).Seconds()),
+		Scope:       strings.Join(token.Scopes, " "),
+	})
+}
+
+// CreateAdminServiceAccount godoc
+// @Summary Create a service account for the admin API
+// @Description Create a service account acting on the admin API with permissions as its scopes. Users can only give permissions they have. The client secret is only returned once. Not available to service accounts.
+// @Tags service-accounts
+// @Accept json
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param request body CreateAdminServiceAccountRequest true "Service account to create"
+// @Success 201 {object} CreateServiceAccountResponse "Created service account"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/service-accounts [post]
+func (h *Handler) CreateAdminServiceAccount(c echo.Context) error {
+	var req CreateAdminServiceAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	secret, account, err := h.serviceAccounts.CreateAdminServiceAccount(c.Request().Context(), req.Name, req.Scopes, middleware.GetAdminActor(c))
+	if err != nil {
+		return serviceAccountError(c, err, "Failed to create service account")
+	}
+
+	return c.JSON(http.StatusCreated, CreateServiceAccountResponse{
+		ServiceAccountItem: newServiceAccountItem(account),
+		ClientSecret:       secret,
+	})
+}
+
+// ListAdminServiceAccounts godoc
+// @Summary List service accounts of the admin API
+// @Description List the service accounts acting on the admin API, by name
+// @Tags service-accounts
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Success 200 {object} ServiceAccountsResponse "Service accounts"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/service-accounts [get]
+func (h *Handler) ListAdminServiceAccounts(c echo.Context) error {
+	accounts, err := h.serviceAccounts.ListAdminServiceAccounts(c.Request().Context())
+	if err != nil {
+		return serviceAccountError(c, err, "Failed to list service accounts")
+	}
+
+	return c.JSON(http.StatusOK, newServiceAccountsResponse(accounts))
+}
+
+// RotateAdminServiceAccountSecret godoc
+// @Summary Rotate the secret of a service account of the admin API
+// @Description Replace the client secret of a service account, revoking the access tokens issued with the old one. The new secret is only returned once. Not available to service accounts.
+// @Tags service-accounts
+// @Produce json
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Success 200 {object} ClientSecretResponse "New client secret"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Service account not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/service-accounts/{id}/secret [post]
+func (h *Handler) RotateAdminServiceAccountSecret(c echo.Context) error {
+	secret, err := h.serviceAccounts.RotateAdminServiceAccountSecret(c.Request().Context(), c.Param("id"), middleware.GetAdminActor(c))
+	if err != nil {
+		return serviceAccountError(c, err, "Failed to rotate service account secret")
+	}
+
+	return c.JSON(http.StatusOK, ClientSecretResponse{ClientSecret: secret})
+}
+
+// DeleteAdminServiceAccount godoc
+// @Summary Delete a service account of the admin API
+// @Description Delete a service account, revoking its access tokens. Not available to service accounts.
+// @Tags service-accounts
+// @Security AdminKey
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Success 204 "Service account deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Missing permission"
+// @Failure 404 {object} ErrorResponse "Service account not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/admin/service-accounts/{id} [delete]
+func (h *Handler) DeleteAdminServiceAccount(c echo.Context) error {
+	if err := h.serviceAccounts.DeleteAdminServiceAccount(c.Request().Context(), c.Param("id"), middleware.GetAdminActor(c)); err != nil {
+		return serviceAccountError(c, err, "Failed to delete service account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateOrganizationServiceAccount godoc
+// @Summary Create a service account for an organization
+// @Description Create a service account acting in the organization with a role. Requires the admin role. The client secret is only returned once. Not available with an API key, while impersonating or to service accounts.
+// @Tags service-accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param request body CreateOrganizationServiceAccountRequest true "Service account to create"
+// @Success 201 {object} CreateServiceAccountResponse "Created service account"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Requires the admin role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/service-accounts [post]
+func (h *Handler) CreateOrganizationServiceAccount(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	var req CreateOrganizationServiceAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid request"))
+	}
+
+	secret, account, err := h.serviceAccounts.CreateOrganizationServiceAccount(c.Request().Context(), membership, req.Name, req.Role)
+	if err != nil {
+		return serviceAccountError(c, err, "Failed to create service account")
+	}
+
+	return c.JSON(http.StatusCreated, CreateServiceAccountResponse{
+		ServiceAccountItem: newServiceAccountItem(account),
+		ClientSecret:       secret,
+	})
+}
+
+// ListOrganizationServiceAccounts godoc
+// @Summary List service accounts of an organization
+// @Description List the service accounts of the organization, by name. Requires the admin role.
+// @Tags service-accounts
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Success 200 {object} ServiceAccountsResponse "Service accounts"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Requires the admin role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/service-accounts [get]
+func (h *Handler) ListOrganizationServiceAccounts(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	accounts, err := h.serviceAccounts.ListOrganizationServiceAccounts(c.Request().Context(), membership)
+	if err != nil {
+		return serviceAccountError(c, err, "Failed to list service accounts")
+	}
+
+	return c.JSON(http.StatusOK, newServiceAccountsResponse(accounts))
+}
+
+// RotateOrganizationServiceAccountSecret godoc
+// @Summary Rotate the secret of a service account of an organization
+// @Description Replace the client secret of a service account, revoking the access tokens issued with the old one. Requires the admin role. The new secret is only returned once. Not available with an API key, while impersonating or to service accounts.
+// @Tags service-accounts
+// @Produce json
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param id path string true "Service account ID"
+// @Success 200 {object} ClientSecretResponse "New client secret"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Requires the admin role"
+// @Failure 404 {object} ErrorResponse "Organization or service account not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/service-accounts/{id}/secret [post]
+func (h *Handler) RotateOrganizationServiceAccountSecret(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	secret, err := h.serviceAccounts.RotateOrganizationServiceAccountSecret(c.Request().Context(), membership, c.Param("id"))
+	if err != nil {
+		return serviceAccountError(c, err, "Failed to rotate service account secret")
+	}
+
+	return c.JSON(http.StatusOK, ClientSecretResponse{ClientSecret: secret})
+}
+
+// DeleteOrganizationServiceAccount godoc
+// @Summary Delete a service account of an organization
+// @Description Delete a service account, revoking its access tokens. Requires the admin role. Not available with an API key, while impersonating or to service accounts.
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgID path string true "Organization ID"
+// @Param id path string true "Service account ID"
+// @Success 204 "Service account deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Requires the admin role"
+// @Failure 404 {object} ErrorResponse "Organization or service account not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/orgs/{orgID}/service-accounts/{id} [delete]
+func (h *Handler) DeleteOrganizationServiceAccount(c echo.Context) error {
+	membership := c.Get(middleware.MembershipContextKey).(*model.Membership)
+
+	if err := h.serviceAccounts.DeleteOrganizationServiceAccount(c.Request().Context(), membership, c.Param("id")); err != nil {
+		return serviceAccountError(c, err, "Failed to delete service account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterTokenRoutes registers the token endpoint of the client credentials
+// grant, on the auth routes
+func (h *Handler) RegisterTokenRoutes(g *echo.Group) {
+	g.POST("/token", h.IssueToken)
+}
+
+// RegisterAdminRoutes registers the routes managing the service accounts of
+// the admin API, which need the service_accounts:manage permission. Service
+// accounts can't manage service accounts, so a leaked secret can't be used to
+// make more.
+func (h *Handler) RegisterAdminRoutes(g *echo.Group, requirePermission func(permission string) echo.MiddlewareFunc) {
+	manage := requirePermission(model.PermissionServiceAccountsManage)
+	people := middleware.DenyServiceAccounts()
+
+	g.POST("/service-accounts", h.CreateAdminServiceAccount, manage, people)
+	g.GET("/service-accounts", h.ListAdminServiceAccounts, manage)
+	g.POST("/service-accounts/:id/secret", h.RotateAdminServiceAccountSecret, manage, people)
+	g.DELETE("/service-accounts/:id", h.DeleteAdminServiceAccount, manage, people)
+}
+
+// RegisterOrganizationRoutes registers the routes managing the service
+// accounts of an organization, on a group running the tenant middleware.
+// Changes run the sensitive middleware, so they need a signed-in person.
+func (h *Handler) RegisterOrganizationRoutes(g *echo.Group, sensitive echo.MiddlewareFunc) {
+	g.POST("", h.CreateOrganizationServiceAccount, sensitive)
+	g.GET("", h.ListOrganizationServiceAccounts)
+	g.POST("/:id/secret", h.RotateOrganizationServiceAccountSecret, sensitive)
+	g.DELETE("/:id", h.DeleteOrganizationServiceAccount, sensitive)
+}
+
+// newServiceAccountsResponse converts service accounts to their response model
+func newServiceAccountsResponse(accounts []*model.ServiceAccount) ServiceAccountsResponse {
+	items := make([]ServiceAccountItem, len(accounts))
+	for i, account := range accounts {
+		items[i] = newServiceAccountItem(account)
+	}
+	return ServiceAccountsResponse{ServiceAccounts: items}
+}
+
+// serviceAccountError responds with the error of a service account request,
+// hiding unexpected errors behind a generic message
+func serviceAccountError(c echo.Context, err error, message string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.StatusCode {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict:
+			return c.JSON(appErr.StatusCode, response.NewErrorResponse(appErr.Message))
+		}
+	}
+	return c.JSON(http.StatusInternalServerError, response.NewErrorResponse(message))
+}
@@ -0,0 +1,265 @@
+package serviceaccount
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/handler/middleware"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// MockServiceAccounts is a mock implementation of the service account service
+type MockServiceAccounts struct {
+	mock.Mock
+}
+
+// CreateAdminServiceAccount mocks the CreateAdminServiceAccount method
+func (m *MockServiceAccounts) CreateAdminServiceAccount(ctx context.Context, name string, scopes []string, actor model.AdminActor) (string, *model.ServiceAccount, error) {
+	args := m.Called(ctx, name, scopes, actor)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*model.ServiceAccount), args.Error(2)
+}
+
+// ListAdminServiceAccounts mocks the ListAdminServiceAccounts method
+func (m *MockServiceAccounts) ListAdminServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ServiceAccount), args.Error(1)
+}
+
+// RotateAdminServiceAccountSecret mocks the RotateAdminServiceAccountSecret method
+func (m *MockServiceAccounts) RotateAdminServiceAccountSecret(ctx context.Context, id string, actor model.AdminActor) (string, error) {
+	args := m.Called(ctx, id, actor)
+	return args.String(0), args.Error(1)
+}
+
+// DeleteAdminServiceAccount mocks the DeleteAdminServiceAccount method
+func (m *MockServiceAccounts) DeleteAdminServiceAccount(ctx context.Context, id string, actor model.AdminActor) error {
+	args := m.Called(ctx, id, actor)
+	return args.Error(0)
+}
+
+// CreateOrganizationServiceAccount mocks the CreateOrganizationServiceAccount method
+func (m *MockServiceAccounts) CreateOrganizationServiceAccount(ctx context.Context, actor *model.Membership, name, role string) (string, *model.ServiceAccount, error) {
+	args := m.Called(ctx, actor, name, role)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*model.ServiceAccount), args.Error(2)
+}
+
+// ListOrganizationServiceAccounts mocks the ListOrganizationServiceAccounts method
+func (m *MockServiceAccounts) ListOrganizationServiceAccounts(ctx context.Context, actor *model.Membership) ([]*model.ServiceAccount, error) {
+	args := m.Called(ctx, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ServiceAccount), args.Error(1)
+}
+
+// RotateOrganizationServiceAccountSecret mocks the RotateOrganizationServiceAccountSecret method
+func (m *MockServiceAccounts) RotateOrganizationServiceAccountSecret(ctx context.Context, actor *model.Membership, id string) (string, error) {
+	args := m.Called(ctx, actor, id)
+	return args.String(0), args.Error(1)
+}
+
+// DeleteOrganizationServiceAccount mocks the DeleteOrganizationServiceAccount method
+func (m *MockServiceAccounts) DeleteOrganizationServiceAccount(ctx context.Context, actor *model.Membership, id string) error {
+	args := m.Called(ctx, actor, id)
+	return args.Error(0)
+}
+
+// IssueToken mocks the IssueToken method
+func (m *MockServiceAccounts) IssueToken(ctx context.Context, clientID, clientSecret, ipAddress string) (*model.ServiceToken, error) {
+	args := m.Called(ctx, clientID, clientSecret, ipAddress)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ServiceToken), args.Error(1)
+}
+
+// newTokenRequest creates a token request with form parameters
+func newTokenRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/token", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.RemoteAddr = "203.0.113.7:1234"
+	return req
+}
+
+// TestIssueToken tests the IssueToken handler
+func TestIssueToken(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	token := &model.ServiceToken{
+		AccessToken: "v2.public.token",
+		ExpiresAt:   time.Now().Add(time.Hour),
+		Scopes:      []string{model.PermissionUsersRead, model.PermissionRolesManage},
+	}
+
+	t.Run("issues a token for form credentials", func(t *testing.T) {
+		mockAccounts := new(MockServiceAccounts)
+		handler := NewHandler(mockAccounts)
+
+		req := newTokenRequest(url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {"sa_0123456789abcdef"},
+			"client_secret": {"secret"},
+		})
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockAccounts.On("IssueToken", mock.Anything, "sa_0123456789abcdef", "secret", "203.0.113.7").Return(token, nil)
+
+		err := handler.IssueToken(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+		var resp TokenResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "v2.public.token", resp.AccessToken)
+		assert.Equal(t, "Bearer", resp.TokenType)
+		assert.Equal(t, 3600, resp.ExpiresIn)
+		assert.Equal(t, "users:read roles:manage", resp.Scope)
+		mockAccounts.AssertExpectations(t)
+	})
+
+	t.Run("issues a token for basic credentials", func(t *testing.T) {
+		mockAccounts := new(MockServiceAccounts)
+		handler := NewHandler(mockAccounts)
+
+		req := newTokenRequest(url.Values{"grant_type": {"client_credentials"}})
+		req.SetBasicAuth("sa_0123456789abcdef", "secret")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockAccounts.On("IssueToken", mock.Anything, "sa_0123456789abcdef", "secret", "203.0.113.7").Return(token, nil)
+
+		err := handler.IssueToken(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockAccounts.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid credentials", func(t *testing.T) {
+		mockAccounts := new(MockServiceAccounts)
+		handler := NewHandler(mockAccounts)
+
+		req := newTokenRequest(url.Values{"grant_type": {"client_credentials"}})
+		req.SetBasicAuth("sa_0123456789abcdef", "wrong")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockAccounts.On("IssueToken", mock.Anything, "sa_0123456789abcdef", "wrong", "203.0.113.7").Return(nil, apperrors.NewAuthenticationError("invalid client credentials"))
+
+		err := handler.IssueToken(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.JSONEq(t, `{"error":"invalid_client"}`, rec.Body.String())
+		assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("rejects other grant types and missing credentials", func(t *testing.T) {
+		handler := NewHandler(new(MockServiceAccounts))
+
+		tests := []struct {
+			form url.Values
+			want string
+		}{
+			{url.Values{}, "invalid_request"},
+			{url.Values{"grant_type": {"password"}}, "unsupported_grant_type"},
+			{url.Values{"grant_type": {"client_credentials"}, "client_id": {"sa_0123456789abcdef"}}, "invalid_request"},
+		}
+
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			c := e.NewContext(newTokenRequest(tt.form), rec)
+
+			err := handler.IssueToken(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, `{"error":"`+tt.want+`"}`, rec.Body.String())
+		}
+	})
+}
+
+// TestCreateOrganizationServiceAccount tests the CreateOrganizationServiceAccount handler
+func TestCreateOrganizationServiceAccount(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	t.Run("returns the secret once", func(t *testing.T) {
+		mockAccounts := new(MockServiceAccounts)
+		handler := NewHandler(mockAccounts)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs/org-1/service-accounts", strings.NewReader(`{"name":"Import","role":"member"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		membership := &model.Membership{OrganizationID: "org-1", UserID: "user-1", Role: model.OrgRoleAdmin}
+		c.Set(middleware.MembershipContextKey, membership)
+
+		orgID := "org-1"
+		mockAccounts.On("CreateOrganizationServiceAccount", mock.Anything, membership, "Import", "member").Return("secret", &model.ServiceAccount{
+			ID:             "sa-1",
+			Name:           "Import",
+			OrganizationID: &orgID,
+			Role:           model.OrgRoleMember,
+			ClientID:       "sa_0123456789abcdef",
+			SecretHash:     "secret-hash",
+			CreatedAt:      time.Now(),
+		}, nil)
+
+		err := handler.CreateOrganizationServiceAccount(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "secret-hash")
+
+		var resp CreateServiceAccountResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "secret", resp.ClientSecret)
+		assert.Equal(t, "sa_0123456789abcdef", resp.ClientID)
+		assert.Equal(t, "org-1", resp.OrganizationID)
+		mockAccounts.AssertExpectations(t)
+	})
+
+	t.Run("requires the admin role", func(t *testing.T) {
+		mockAccounts := new(MockServiceAccounts)
+		handler := NewHandler(mockAccounts)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orgs/org-1/service-accounts", strings.NewReader(`{"name":"Import","role":"member"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		membership := &model.Membership{OrganizationID: "org-1", UserID: "user-2", Role: model.OrgRoleMember}
+		c.Set(middleware.MembershipContextKey, membership)
+
+		mockAccounts.On("CreateOrganizationServiceAccount", mock.Anything, membership, "Import", "member").Return("", nil, apperrors.NewAuthorizationError("requires the admin role"))
+
+		err := handler.CreateOrganizationServiceAccount(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
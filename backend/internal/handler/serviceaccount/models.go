@@ -0,0 +1,93 @@
+package serviceaccount
+
+import (
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// CreateAdminServiceAccountRequest represents a request to create a service
+// account for the admin API
+type CreateAdminServiceAccountRequest struct {
+	Name string `json:"name" validate:"required" example:"Billing sync"`
+	// Permissions the service account is given, among those of the admin
+	Scopes []string `json:"scopes" validate:"required" example:"users:read"`
+}
+
+// CreateOrganizationServiceAccountRequest represents a request to create a
+// service account for an organization
+type CreateOrganizationServiceAccountRequest struct {
+	Name string `json:"name" validate:"required" example:"Nightly import"`
+	// admin or member
+	Role string `json:"role" validate:"required" example:"member"`
+}
+
+// ServiceAccountItem represents a service account, without its secret
+type ServiceAccountItem struct {
+	ID             string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name           string   `json:"name" example:"Nightly import"`
+	ClientID       string   `json:"client_id" example:"sa_1a2b3c4d5e6f7a8b"`
+	OrganizationID string   `json:"organization_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Role           string   `json:"role,omitempty" example:"member"`
+	Scopes         []string `json:"scopes,omitempty" example:"users:read"`
+	LastUsedAt     string   `json:"last_used_at,omitempty" example:"2023-01-02T12:00:00Z"`
+	CreatedAt      string   `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// CreateServiceAccountResponse represents a new service account, with its
+// client secret
+type CreateServiceAccountResponse struct {
+	ServiceAccountItem
+	// Shown only once: store it somewhere safe
+	ClientSecret string `json:"client_secret" example:"9c0d..."`
+}
+
+// ServiceAccountsResponse represents a list of service accounts
+type ServiceAccountsResponse struct {
+	ServiceAccounts []ServiceAccountItem `json:"service_accounts"`
+}
+
+// ClientSecretResponse represents a new client secret
+type ClientSecretResponse struct {
+	// Shown only once: store it somewhere safe
+	ClientSecret string `json:"client_secret" example:"9c0d..."`
+}
+
+// TokenResponse represents an access token issued with the client
+// credentials grant (RFC 6749 section 5.1)
+type TokenResponse struct {
+	AccessToken string `json:"access_token" example:"v2.public.eyJ..."`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" example:"3600"`
+	Scope       string `json:"scope,omitempty" example:"users:read"`
+}
+
+// TokenErrorResponse represents an error of the token endpoint (RFC 6749
+// section 5.2)
+type TokenErrorResponse struct {
+	Error string `json:"error" example:"invalid_client"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"service account not found"`
+}
+
+// newServiceAccountItem converts a service account to its response model
+func newServiceAccountItem(account *model.ServiceAccount) ServiceAccountItem {
+	item := ServiceAccountItem{
+		ID:        account.ID,
+		Name:      account.Name,
+		ClientID:  account.ClientID,
+		Role:      account.Role,
+		Scopes:    account.Scopes,
+		CreatedAt: account.CreatedAt.Format(time.RFC3339),
+	}
+	if account.OrganizationID != nil {
+		item.OrganizationID = *account.OrganizationID
+	}
+	if account.LastUsedAt != nil {
+		item.LastUsedAt = account.LastUsedAt.Format(time.RFC3339)
+	}
+	return item
+}
@@ -1,14 +1,21 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
 	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/models"
 	"github.com/nanayaw/fullstack/internal/service/auth"
+	"github.com/nanayaw/fullstack/internal/service/security"
 )
 
 // UserService defines the interface for user service
@@ -20,17 +27,32 @@ type UserService interface {
 	UpdateProfile(ctx echo.Context, userID string, req *models.UpdateUserRequest) (*models.User, error)
 }
 
+// SecurityService defines the interface for the security service
+type SecurityService interface {
+	ListSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) (*model.SecurityEventPage, error)
+	ReportUnrecognizedActivity(ctx context.Context, userID, eventID, ipAddress, userAgent string) error
+}
+
+// EmailSuppressions defines the interface for looking up undeliverable email addresses
+type EmailSuppressions interface {
+	GetSuppression(ctx context.Context, email string) (*model.EmailSuppression, error)
+}
+
 // Handler handles user-related requests
 type Handler struct {
-	userService UserService
-	authService auth.Service
+	userService       UserService
+	authService       auth.Service
+	securityService   SecurityService
+	emailSuppressions EmailSuppressions
 }
 
 // NewHandler creates a new user handler
-func NewHandler(userService UserService, authService auth.Service) *Handler {
+func NewHandler(userService UserService, authService auth.Service, securityService SecurityService, emailSuppressions EmailSuppressions) *Handler {
 	return &Handler{
-		userService: userService,
-		authService: authService,
+		userService:       userService,
+		authService:       authService,
+		securityService:   securityService,
+		emailSuppressions: emailSuppressions,
 	}
 }
 
@@ -45,7 +67,12 @@ func (h *Handler) GetUser(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get user information"))
 	}
 
-	return c.JSON(http.StatusOK, user)
+	resp := CurrentUserResponse{User: user}
+	if session, ok := c.Get("session").(*models.Session); ok {
+		resp.ImpersonatedBy = session.ImpersonatorID
+	}
+
+	return c.JSON(http.StatusOK, resp)
 }
 
 // UpdateUser handles updating user information
@@ -160,20 +187,7 @@ func (h *Handler) GetProfile(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get user profile"))
 	}
 
-	// Split full name into first and last name
-	firstName, lastName := splitFullName(user.FullName)
-
-	// Create response
-	resp := UserProfileResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: firstName,
-		LastName:  lastName,
-		CreatedAt: user.CreatedAt.Format(http.TimeFormat),
-		UpdatedAt: user.UpdatedAt.Format(http.TimeFormat),
-	}
-
-	return c.JSON(http.StatusOK, resp)
+	return c.JSON(http.StatusOK, h.newProfileResponse(c.Request().Context(), user))
 }
 
 // UpdateProfile godoc
@@ -215,20 +229,7 @@ func (h *Handler) UpdateProfile(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to update profile"))
 	}
 
-	// Split full name into first and last name
-	firstName, lastName := splitFullName(user.FullName)
-
-	// Create response
-	resp := UserProfileResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: firstName,
-		LastName:  lastName,
-		CreatedAt: user.CreatedAt.Format(http.TimeFormat),
-		UpdatedAt: user.UpdatedAt.Format(http.TimeFormat),
-	}
-
-	return c.JSON(http.StatusOK, resp)
+	return c.JSON(http.StatusOK, h.newProfileResponse(c.Request().Context(), user))
 }
 
 // ChangePassword godoc
@@ -309,16 +310,155 @@ func (h *Handler) DeleteAccount(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
-// RegisterRoutes registers all user routes
-func (h *Handler) RegisterRoutes(g *echo.Group) {
+// ListSecurityEvents godoc
+// @Summary List security events
+// @Description List the current user's security events, newest first, using cursor pagination
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param event_type query string false "Comma-separated event types to include"
+// @Param from query string false "Only include events at or after this time (RFC 3339)"
+// @Param to query string false "Only include events before this time (RFC 3339)"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Success 200 {object} SecurityEventsResponse "Security events"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/users/me/security-events [get]
+func (h *Handler) ListSecurityEvents(c echo.Context) error {
+	// Extract user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+
+	filter, err := parseSecurityEventFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse(err.Error()))
+	}
+
+	// Call service
+	page, err := h.securityService.ListSecurityEvents(c.Request().Context(), userID, filter)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusBadRequest {
+			return c.JSON(http.StatusBadRequest, response.NewErrorResponse(appErr.Message))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get security events"))
+	}
+
+	// Convert to response model
+	events := make([]SecurityEventItem, len(page.Events))
+	for i, event := range page.Events {
+		events[i] = SecurityEventItem{
+			ID:          event.ID,
+			EventType:   event.EventType,
+			IPAddress:   event.IPAddress,
+			UserAgent:   event.UserAgent,
+			Location:    event.Location,
+			Description: event.Description,
+			CreatedAt:   event.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	resp := SecurityEventsResponse{
+		Events:     events,
+		NextCursor: page.NextCursor,
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ReportSecurityEvent godoc
+// @Summary Report a security event as unrecognized
+// @Description Flag one of the current user's security events as "this wasn't me". The account is locked, all sessions are revoked and a password reset email is sent.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Security event ID"
+// @Success 200 {object} ReportSecurityEventResponse "Event reported"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Event not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/users/me/security-events/{id}/report [post]
+func (h *Handler) ReportSecurityEvent(c echo.Context) error {
+	// Extract user ID from context (set by auth middleware)
+	userID := c.Get("user_id").(string)
+	ctx := c.Request().Context()
+
+	// Get the user's email for the password reset
+	user, err := h.userService.GetUser(c, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to get user information"))
+	}
+
+	// Call service
+	err = h.securityService.ReportUnrecognizedActivity(ctx, userID, c.Param("id"), c.RealIP(), c.Request().UserAgent())
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusNotFound {
+			return c.JSON(http.StatusNotFound, response.NewErrorResponse("Security event not found"))
+		}
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to report security event"))
+	}
+
+	// The account is locked at this point, so log errors but still report success
+	if err := h.authService.InvalidateAllSessions(ctx, userID); err != nil {
+		log.Printf("Error invalidating sessions for user %s: %v", userID, err)
+	}
+
+	if err := h.authService.SendPasswordResetEmail(ctx, user.Email); err != nil {
+		log.Printf("Error sending password reset email for user %s: %v", userID, err)
+	}
+
+	resp := ReportSecurityEventResponse{
+		Message: "Your account has been locked and a password reset link has been sent to your email",
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RegisterRoutes registers all user routes. Routes only the user may use,
+// such as changing their password, run the sensitive middleware, which keeps
+// out admins impersonating the user and API keys.
+func (h *Handler) RegisterRoutes(g *echo.Group, sensitive echo.MiddlewareFunc) {
 	g.GET("/me", h.GetUser)
-	g.PUT("/me", h.UpdateUser)
-	g.DELETE("/me", h.DeleteUser)
+	g.PUT("/me", h.UpdateUser, sensitive)
+	g.DELETE("/me", h.DeleteUser, sensitive)
 	g.GET("/me/activity", h.GetUserActivity)
+	g.GET("/me/security-events", h.ListSecurityEvents)
+	g.POST("/me/security-events/:id/report", h.ReportSecurityEvent, sensitive)
 	g.GET("/profile", h.GetProfile)
 	g.PUT("/profile", h.UpdateProfile)
-	g.POST("/change-password", h.ChangePassword)
-	g.DELETE("/account", h.DeleteAccount)
+	g.POST("/change-password", h.ChangePassword, sensitive)
+	g.DELETE("/account", h.DeleteAccount, sensitive)
+}
+
+// newProfileResponse converts a user to a profile response, flagging an email
+// address that was suppressed after bounces or complaints
+func (h *Handler) newProfileResponse(ctx context.Context, user *models.User) UserProfileResponse {
+	// Split full name into first and last name
+	firstName, lastName := splitFullName(user.FullName)
+
+	resp := UserProfileResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: firstName,
+		LastName:  lastName,
+		CreatedAt: user.CreatedAt.Format(http.TimeFormat),
+		UpdatedAt: user.UpdatedAt.Format(http.TimeFormat),
+	}
+
+	// The profile is still useful without the flag, so don't fail on errors
+	suppression, err := h.emailSuppressions.GetSuppression(ctx, user.Email)
+	if err != nil {
+		log.Printf("Error checking email suppression for user %s: %v", user.ID, err)
+	} else if suppression != nil {
+		resp.EmailUndeliverable = true
+		resp.EmailUndeliverableReason = suppression.Reason
+	}
+
+	return resp
 }
 
 // Helper function to split full name into first and last name
@@ -345,3 +485,51 @@ func splitFullName(fullName string) (string, string) {
 
 	return firstName, lastName
 }
+
+// parseSecurityEventFilter builds a security event filter from query parameters
+func parseSecurityEventFilter(c echo.Context) (model.SecurityEventFilter, error) {
+	var filter model.SecurityEventFilter
+
+	for _, value := range c.QueryParams()["event_type"] {
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				filter.EventTypes = append(filter.EventTypes, eventType)
+			}
+		}
+	}
+
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, errors.New("from must be an RFC 3339 timestamp")
+		}
+		filter.From = t
+	}
+
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, errors.New("to must be an RFC 3339 timestamp")
+		}
+		filter.To = t
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		after, err := security.DecodeSecurityEventCursor(cursor)
+		if err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+		filter.After = after
+	}
+
+	filter.Limit = security.DefaultSecurityEventPageSize
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > security.MaxSecurityEventPageSize {
+			return filter, errors.New("limit must be between 1 and 100")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
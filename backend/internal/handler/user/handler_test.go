@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -13,7 +14,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/models"
+	userService "github.com/nanayaw/fullstack/internal/service/user"
 )
 
 // MockUserService is a mock implementation of the user service
@@ -140,6 +144,87 @@ func (m *MockAuthService) InvalidateAllSessions(ctx context.Context, userID stri
 	return args.Error(0)
 }
 
+// MockSecurityService is a mock implementation of the security service
+type MockSecurityService struct {
+	mock.Mock
+}
+
+// ListSecurityEvents mocks the ListSecurityEvents method
+func (m *MockSecurityService) ListSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) (*model.SecurityEventPage, error) {
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).(*model.SecurityEventPage), args.Error(1)
+}
+
+// ReportUnrecognizedActivity mocks the ReportUnrecognizedActivity method
+func (m *MockSecurityService) ReportUnrecognizedActivity(ctx context.Context, userID, eventID, ipAddress, userAgent string) error {
+	args := m.Called(ctx, userID, eventID, ipAddress, userAgent)
+	return args.Error(0)
+}
+
+// MockEmailSuppressions is a mock implementation of the email suppression list
+type MockEmailSuppressions struct {
+	mock.Mock
+}
+
+// GetSuppression mocks the GetSuppression method
+func (m *MockEmailSuppressions) GetSuppression(ctx context.Context, email string) (*model.EmailSuppression, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.EmailSuppression), args.Error(1)
+}
+
+// fakeUserRepository is an in-memory user repository, used to test handlers
+// through the real user service
+type fakeUserRepository struct {
+	users map[string]*models.User
+}
+
+// GetUser gets a user by ID, or nil if there is none
+func (r *fakeUserRepository) GetUser(ctx context.Context, id string) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// UpdateUser updates the fields of a user set in req
+func (r *fakeUserRepository) UpdateUser(ctx context.Context, id string, req *models.UpdateUserRequest) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	if req.FullName != nil {
+		user.FullName = *req.FullName
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// DeleteUser deletes a user
+func (r *fakeUserRepository) DeleteUser(ctx context.Context, id string) error {
+	delete(r.users, id)
+	return nil
+}
+
+// fakeWebhooks records the webhook events dispatched
+type fakeWebhooks struct {
+	events []string
+	data   []map[string]interface{}
+}
+
+// Dispatch records an event
+func (w *fakeWebhooks) Dispatch(ctx context.Context, eventType string, data map[string]interface{}) {
+	w.events = append(w.events, eventType)
+	w.data = append(w.data, data)
+}
+
 // TestGetUser tests the GetUser handler
 func TestGetUser(t *testing.T) {
 	// Create a new Echo instance
@@ -150,7 +235,7 @@ func TestGetUser(t *testing.T) {
 	mockAuthService := new(MockAuthService)
 
 	// Create a new user handler with the mock services
-	handler := NewHandler(mockUserService, mockAuthService)
+	handler := NewHandler(mockUserService, mockAuthService, new(MockSecurityService), new(MockEmailSuppressions))
 
 	// Create a new HTTP request
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
@@ -189,6 +274,96 @@ func TestGetUser(t *testing.T) {
 	mockUserService.AssertExpectations(t)
 }
 
+// TestGetUserImpersonated tests the GetUser handler for an admin impersonating the user
+func TestGetUserImpersonated(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create mock services
+	mockUserService := new(MockUserService)
+
+	// Create a new user handler with the mock services
+	handler := NewHandler(mockUserService, new(MockAuthService), new(MockSecurityService), new(MockEmailSuppressions))
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set the impersonation session in context
+	c.Set("user_id", "123")
+	c.Set("session", &models.Session{ID: "session-1", UserID: "123", ImpersonatorID: "admin-1"})
+
+	// Set up expectations
+	mockUserService.On("GetUser", mock.Anything, "123").Return(&models.User{ID: "123", Email: "test@example.com"}, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.GetUser(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Parse the response
+		var resp CurrentUserResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		// Check the response
+		assert.Equal(t, "123", resp.ID)
+		assert.Equal(t, "admin-1", resp.ImpersonatedBy)
+	}
+}
+
+// TestGetProfileUndeliverableEmail tests the GetProfile handler for a user whose email bounced
+func TestGetProfileUndeliverableEmail(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockSuppressions := new(MockEmailSuppressions)
+
+	// Create a new user handler with the mock services
+	handler := NewHandler(mockUserService, new(MockAuthService), new(MockSecurityService), mockSuppressions)
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/profile", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set user ID in context
+	c.Set("user_id", "123")
+
+	// Set up expectations
+	mockUser := &models.User{
+		ID:       "123",
+		Email:    "test@example.com",
+		FullName: "John Doe",
+	}
+	mockUserService.On("GetUser", mock.Anything, "123").Return(mockUser, nil)
+	mockSuppressions.On("GetSuppression", mock.Anything, "test@example.com").Return(&model.EmailSuppression{
+		Email:  "test@example.com",
+		Reason: model.SuppressionReasonBounce,
+	}, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.GetProfile(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Parse the response
+		var resp UserProfileResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		// Check the response
+		assert.Equal(t, "John", resp.FirstName)
+		assert.True(t, resp.EmailUndeliverable)
+		assert.Equal(t, model.SuppressionReasonBounce, resp.EmailUndeliverableReason)
+	}
+
+	// Verify expectations
+	mockUserService.AssertExpectations(t)
+	mockSuppressions.AssertExpectations(t)
+}
+
 // TestUpdateUser tests the UpdateUser handler
 func TestUpdateUser(t *testing.T) {
 	// Create a new Echo instance
@@ -199,7 +374,7 @@ func TestUpdateUser(t *testing.T) {
 	mockAuthService := new(MockAuthService)
 
 	// Create a new user handler with the mock services
-	handler := NewHandler(mockUserService, mockAuthService)
+	handler := NewHandler(mockUserService, mockAuthService, new(MockSecurityService), new(MockEmailSuppressions))
 
 	// Create a request body
 	fullName := "Jane Doe"
@@ -251,6 +426,265 @@ func TestUpdateUser(t *testing.T) {
 	mockUserService.AssertExpectations(t)
 }
 
+// TestListSecurityEvents tests the ListSecurityEvents handler
+func TestListSecurityEvents(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockAuthService := new(MockAuthService)
+	mockSecurityService := new(MockSecurityService)
+
+	// Create a new user handler with the mock services
+	handler := NewHandler(mockUserService, mockAuthService, mockSecurityService, new(MockEmailSuppressions))
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/security-events?event_type=new_device_login,password_changed&limit=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Set user ID in context
+	c.Set("user_id", "123")
+
+	// Set up expectations
+	createdAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockPage := &model.SecurityEventPage{
+		Events: []*model.SecurityEvent{
+			{
+				ID:          "evt-1",
+				UserID:      "123",
+				EventType:   model.EventNewDeviceLogin,
+				IPAddress:   "203.0.113.10",
+				UserAgent:   "Mozilla/5.0",
+				Location:    "Accra, Ghana",
+				Description: "Login from a new device",
+				CreatedAt:   createdAt,
+			},
+		},
+		NextCursor: "next",
+	}
+	mockSecurityService.On("ListSecurityEvents", mock.Anything, "123", mock.MatchedBy(func(filter model.SecurityEventFilter) bool {
+		return filter.Limit == 10 &&
+			len(filter.EventTypes) == 2 &&
+			filter.EventTypes[0] == model.EventNewDeviceLogin &&
+			filter.EventTypes[1] == model.EventPasswordChanged
+	})).Return(mockPage, nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.ListSecurityEvents(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Parse the response
+		var resp SecurityEventsResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		// Check the response
+		assert.Len(t, resp.Events, 1)
+		assert.Equal(t, "evt-1", resp.Events[0].ID)
+		assert.Equal(t, model.EventNewDeviceLogin, resp.Events[0].EventType)
+		assert.Equal(t, "2023-01-01T12:00:00Z", resp.Events[0].CreatedAt)
+		assert.Equal(t, "next", resp.NextCursor)
+	}
+
+	// Verify expectations
+	mockSecurityService.AssertExpectations(t)
+}
+
+// TestReportSecurityEvent tests the ReportSecurityEvent handler
+func TestReportSecurityEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		reportErr error
+		wantCode  int
+		wantReset bool
+	}{
+		{"reported", nil, http.StatusOK, true},
+		{"unknown event", apperrors.NewNotFoundError("security event not found"), http.StatusNotFound, false},
+		{"service error", errors.New("database is down"), http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create a new Echo instance
+			e := echo.New()
+
+			// Create mock services
+			mockUserService := new(MockUserService)
+			mockAuthService := new(MockAuthService)
+			mockSecurityService := new(MockSecurityService)
+
+			// Create a new user handler with the mock services
+			handler := NewHandler(mockUserService, mockAuthService, mockSecurityService, new(MockEmailSuppressions))
+
+			// Create a new HTTP request
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/security-events/evt-1/report", nil)
+			req.Header.Set(echo.HeaderXRealIP, "203.0.113.10")
+			req.Header.Set("User-Agent", "Mozilla/5.0")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("evt-1")
+
+			// Set user ID in context
+			c.Set("user_id", "123")
+
+			// Set up expectations
+			mockUser := &models.User{ID: "123", Email: "test@example.com"}
+			mockUserService.On("GetUser", mock.Anything, "123").Return(mockUser, nil)
+			mockSecurityService.On("ReportUnrecognizedActivity", mock.Anything, "123", "evt-1", "203.0.113.10", "Mozilla/5.0").Return(tt.reportErr)
+			if tt.wantReset {
+				mockAuthService.On("InvalidateAllSessions", mock.Anything, "123").Return(nil)
+				mockAuthService.On("SendPasswordResetEmail", mock.Anything, "test@example.com").Return(nil)
+			}
+
+			// Call the handler
+			if assert.NoError(t, handler.ReportSecurityEvent(c)) {
+				assert.Equal(t, tt.wantCode, rec.Code)
+			}
+
+			// Verify expectations
+			mockSecurityService.AssertExpectations(t)
+			mockAuthService.AssertExpectations(t)
+			if !tt.wantReset {
+				// Sessions are kept and no reset is sent when nothing was reported
+				mockAuthService.AssertNotCalled(t, "InvalidateAllSessions", mock.Anything, mock.Anything)
+				mockAuthService.AssertNotCalled(t, "SendPasswordResetEmail", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// TestReportSecurityEventResetsAccountEmail tests that the password reset
+// goes to the email address the user has in the repository
+func TestReportSecurityEventResetsAccountEmail(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create the user service with a repository and mock the other services
+	repo := &fakeUserRepository{users: map[string]*models.User{
+		"123": {ID: "123", Email: "ama@example.org", FullName: "Ama Mensah"},
+	}}
+	mockAuthService := new(MockAuthService)
+	mockSecurityService := new(MockSecurityService)
+
+	// Create a new user handler with the services
+	handler := NewHandler(userService.NewService(repo, nil), mockAuthService, mockSecurityService, new(MockEmailSuppressions))
+
+	// Create a new HTTP request
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/security-events/evt-1/report", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("evt-1")
+
+	// Set user ID in context
+	c.Set("user_id", "123")
+
+	// Set up expectations
+	mockSecurityService.On("ReportUnrecognizedActivity", mock.Anything, "123", "evt-1", mock.Anything, mock.Anything).Return(nil)
+	mockAuthService.On("InvalidateAllSessions", mock.Anything, "123").Return(nil)
+	mockAuthService.On("SendPasswordResetEmail", mock.Anything, mock.Anything).Return(nil)
+
+	// Call the handler
+	if assert.NoError(t, handler.ReportSecurityEvent(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Verify expectations
+	mockSecurityService.AssertExpectations(t)
+	mockAuthService.AssertCalled(t, "SendPasswordResetEmail", mock.Anything, "ama@example.org")
+	mockAuthService.AssertNotCalled(t, "SendPasswordResetEmail", mock.Anything, "user@example.com")
+}
+
+// TestUserWebhooks tests that changing the email address of an account and
+// deleting it notify webhooks, through the real user service
+func TestUserWebhooks(t *testing.T) {
+	newHandler := func() (*Handler, *MockAuthService, *fakeWebhooks) {
+		repo := &fakeUserRepository{users: map[string]*models.User{
+			"123": {ID: "123", Email: "ama@example.org", FullName: "Ama Mensah"},
+		}}
+		webhooks := &fakeWebhooks{}
+		users := userService.NewService(repo, nil)
+		users.SetWebhooks(webhooks)
+		mockAuthService := new(MockAuthService)
+		return NewHandler(users, mockAuthService, new(MockSecurityService), new(MockEmailSuppressions)), mockAuthService, webhooks
+	}
+
+	t.Run("email changed", func(t *testing.T) {
+		handler, _, webhooks := newHandler()
+
+		// Create a new Echo instance
+		e := echo.New()
+		e.Validator = &MockValidator{}
+
+		// Create a new HTTP request
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/users/me", bytes.NewReader([]byte(`{"email":"ama@example.com"}`)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "123")
+
+		// Call the handler
+		if assert.NoError(t, handler.UpdateUser(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+
+		assert.Equal(t, []string{model.WebhookEventUserEmailChanged}, webhooks.events)
+		assert.Equal(t, map[string]interface{}{
+			"user_id":        "123",
+			"email":          "ama@example.com",
+			"previous_email": "ama@example.org",
+		}, webhooks.data[0])
+	})
+
+	t.Run("name changed", func(t *testing.T) {
+		handler, _, webhooks := newHandler()
+
+		// Create a new Echo instance
+		e := echo.New()
+		e.Validator = &MockValidator{}
+
+		// Create a new HTTP request
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/users/me", bytes.NewReader([]byte(`{"fullName":"Ama Owusu"}`)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "123")
+
+		// Call the handler
+		if assert.NoError(t, handler.UpdateUser(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+
+		assert.Empty(t, webhooks.events)
+	})
+
+	t.Run("account deleted", func(t *testing.T) {
+		handler, mockAuthService, webhooks := newHandler()
+		mockAuthService.On("InvalidateAllSessions", mock.Anything, "123").Return(nil)
+
+		// Create a new Echo instance
+		e := echo.New()
+
+		// Create a new HTTP request
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/account", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_id", "123")
+
+		// Call the handler
+		if assert.NoError(t, handler.DeleteAccount(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+
+		assert.Equal(t, []string{model.WebhookEventUserDeleted}, webhooks.events)
+		assert.Equal(t, map[string]interface{}{"user_id": "123"}, webhooks.data[0])
+		mockAuthService.AssertExpectations(t)
+	})
+}
+
 // MockValidator is a mock implementation of the validator
 type MockValidator struct{}
 
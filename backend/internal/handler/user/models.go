@@ -1,13 +1,19 @@
 package user
 
+import "github.com/nanayaw/fullstack/internal/models"
+
 // UserProfileResponse represents a user profile
 type UserProfileResponse struct {
 	ID        string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Email     string `json:"email" example:"user@example.com"`
 	FirstName string `json:"first_name" example:"John"`
 	LastName  string `json:"last_name" example:"Doe"`
-	CreatedAt string `json:"created_at" example:"2023-01-01T12:00:00Z"`
-	UpdatedAt string `json:"updated_at" example:"2023-01-02T12:00:00Z"`
+	// Set when emails to the address bounced or were reported as spam, so no
+	// more emails are sent to it
+	EmailUndeliverable       bool   `json:"email_undeliverable" example:"false"`
+	EmailUndeliverableReason string `json:"email_undeliverable_reason,omitempty" example:"bounce"`
+	CreatedAt                string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	UpdatedAt                string `json:"updated_at" example:"2023-01-02T12:00:00Z"`
 }
 
 // UpdateProfileRequest represents a profile update request
@@ -54,6 +60,35 @@ type PaginationInfo struct {
 	TotalPages  int `json:"total_pages" example:"5"`
 }
 
+// CurrentUserResponse represents the signed in user
+type CurrentUserResponse struct {
+	*models.User
+	// ID of the admin impersonating the user, if any
+	ImpersonatedBy string `json:"impersonatedBy,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+// SecurityEventsResponse represents a page of security events
+type SecurityEventsResponse struct {
+	Events     []SecurityEventItem `json:"events"`
+	NextCursor string              `json:"next_cursor,omitempty" example:"MjAyMy0wMS0wMVQxMjowMDowMFp8MTIz"`
+}
+
+// SecurityEventItem represents a single security event
+type SecurityEventItem struct {
+	ID          string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventType   string `json:"event_type" example:"new_device_login"`
+	IPAddress   string `json:"ip_address" example:"203.0.113.10"`
+	UserAgent   string `json:"user_agent" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64)"`
+	Location    string `json:"location" example:"Accra, Ghana"`
+	Description string `json:"description" example:"Login from Accra, Ghana"`
+	CreatedAt   string `json:"created_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// ReportSecurityEventResponse represents a security event report response
+type ReportSecurityEventResponse struct {
+	Message string `json:"message" example:"Your account has been locked and a password reset link has been sent to your email"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error" example:"Invalid input"`
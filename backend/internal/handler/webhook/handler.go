@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/handler/response"
+	"github.com/nanayaw/fullstack/internal/service/email"
+	"github.com/nanayaw/fullstack/pkg/webhook"
+)
+
+// maxPayloadSize is the largest webhook payload that is accepted
+const maxPayloadSize = 1 << 20
+
+// DeliveryEventHandler defines the interface for handling email delivery events
+type DeliveryEventHandler interface {
+	HandleDeliveryEvent(ctx context.Context, eventID string, event *email.DeliveryEvent) error
+}
+
+// ScheduledEmailDispatcher defines the interface for sending scheduled emails that are due
+type ScheduledEmailDispatcher interface {
+	Dispatch(ctx context.Context, id string) error
+}
+
+// Handler handles webhooks sent by third-party services
+type Handler struct {
+	deliveryEvents  DeliveryEventHandler
+	verifier        *webhook.Verifier
+	scheduledEmails ScheduledEmailDispatcher
+	callbackToken   string
+}
+
+// NewHandler creates a new webhook handler. Delivery events are rejected if
+// verifier is nil, as unsigned events could be used to suppress any address.
+// Scheduled email callbacks are rejected if callbackToken is empty.
+func NewHandler(deliveryEvents DeliveryEventHandler, verifier *webhook.Verifier, scheduledEmails ScheduledEmailDispatcher, callbackToken string) *Handler {
+	return &Handler{
+		deliveryEvents:  deliveryEvents,
+		verifier:        verifier,
+		scheduledEmails: scheduledEmails,
+		callbackToken:   callbackToken,
+	}
+}
+
+// HandleEmailEvent godoc
+// @Summary Receive email delivery events
+// @Description Receive signed delivery events from the email provider. Recipients of hard-bounced and complained-about emails are added to the suppression list.
+// @Tags webhooks
+// @Accept json
+// @Param svix-id header string true "Event ID"
+// @Param svix-timestamp header string true "Event timestamp (Unix seconds)"
+// @Param svix-signature header string true "Event signatures"
+// @Success 204 "Event processed"
+// @Failure 400 {object} ErrorResponse "Invalid payload"
+// @Failure 401 {object} ErrorResponse "Invalid signature"
+// @Failure 403 {object} ErrorResponse "Webhook disabled"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/webhooks/email [post]
+func (h *Handler) HandleEmailEvent(c echo.Context) error {
+	if h.verifier == nil {
+		return c.JSON(http.StatusForbidden, response.NewErrorResponse("Email webhook is disabled"))
+	}
+
+	// The signature covers the raw body, so read it before decoding
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxPayloadSize+1))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Failed to read payload"))
+	}
+	if len(body) > maxPayloadSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, response.NewErrorResponse("Payload too large"))
+	}
+
+	if err := h.verifier.Verify(c.Request().Header, body); err != nil {
+		return c.JSON(http.StatusUnauthorized, response.NewErrorResponse("Invalid signature"))
+	}
+
+	var event email.DeliveryEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid payload"))
+	}
+
+	eventID := c.Request().Header.Get("svix-id")
+	if eventID == "" {
+		eventID = c.Request().Header.Get("webhook-id")
+	}
+
+	// Failures are retried by the provider
+	if err := h.deliveryEvents.HandleDeliveryEvent(c.Request().Context(), eventID, &event); err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to process event"))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleScheduledEmail godoc
+// @Summary Send a scheduled email
+// @Description Called by Upstash Workflow when a scheduled email is due. Emails that are not due, were cancelled or were already sent are ignored, so the callback can safely be retried.
+// @Tags webhooks
+// @Accept json
+// @Param Authorization header string true "Bearer callback token"
+// @Param request body ScheduledEmailCallback true "Scheduled email"
+// @Success 204 "Callback processed"
+// @Failure 400 {object} ErrorResponse "Invalid payload"
+// @Failure 401 {object} ErrorResponse "Invalid token"
+// @Failure 403 {object} ErrorResponse "Webhook disabled"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /api/v1/webhooks/scheduled-emails [post]
+func (h *Handler) HandleScheduledEmail(c echo.Context) error {
+	if h.scheduledEmails == nil || h.callbackToken == "" {
+		return c.JSON(http.StatusForbidden, response.NewErrorResponse("Scheduled emails webhook is disabled"))
+	}
+
+	token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.callbackToken)) != 1 {
+		return c.JSON(http.StatusUnauthorized, response.NewErrorResponse("Invalid token"))
+	}
+
+	var req ScheduledEmailCallback
+	if err := json.NewDecoder(io.LimitReader(c.Request().Body, maxPayloadSize)).Decode(&req); err != nil || req.ScheduledEmailID == "" {
+		return c.JSON(http.StatusBadRequest, response.NewErrorResponse("Invalid payload"))
+	}
+
+	// Failures are retried by the workflow
+	if err := h.scheduledEmails.Dispatch(c.Request().Context(), req.ScheduledEmailID); err != nil {
+		return c.JSON(http.StatusInternalServerError, response.NewErrorResponse("Failed to send scheduled email"))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterRoutes registers all webhook routes
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.POST("/email", h.HandleEmailEvent)
+	g.POST("/scheduled-emails", h.HandleScheduledEmail)
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nanayaw/fullstack/internal/service/email"
+	"github.com/nanayaw/fullstack/pkg/webhook"
+)
+
+// MockDeliveryEventHandler is a mock implementation of the delivery event handler
+type MockDeliveryEventHandler struct {
+	mock.Mock
+}
+
+// HandleDeliveryEvent mocks the HandleDeliveryEvent method
+func (m *MockDeliveryEventHandler) HandleDeliveryEvent(ctx context.Context, eventID string, event *email.DeliveryEvent) error {
+	args := m.Called(ctx, eventID, event)
+	return args.Error(0)
+}
+
+// MockScheduledEmailDispatcher is a mock implementation of the scheduled email dispatcher
+type MockScheduledEmailDispatcher struct {
+	mock.Mock
+}
+
+// Dispatch mocks the Dispatch method
+func (m *MockScheduledEmailDispatcher) Dispatch(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// TestHandleEmailEvent tests the HandleEmailEvent handler
+func TestHandleEmailEvent(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new webhook handler with a mock event handler
+	verifier, err := webhook.NewVerifier("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+	require.NoError(t, err)
+	mockEvents := new(MockDeliveryEventHandler)
+	handler := NewHandler(mockEvents, verifier, nil, "")
+
+	body := `{"type":"email.bounced","data":{"email_id":"re_1","to":["user@example.com"],"bounce":{"type":"Permanent","message":"Mailbox does not exist"}}}`
+	now := time.Now()
+
+	// Set up expectations
+	mockEvents.On("HandleDeliveryEvent", mock.Anything, "msg_1", mock.MatchedBy(func(event *email.DeliveryEvent) bool {
+		return event.Type == email.EventTypeBounced && event.Data.To[0] == "user@example.com"
+	})).Return(nil)
+
+	// Correctly signed request
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/email", strings.NewReader(body))
+	req.Header.Set("svix-id", "msg_1")
+	req.Header.Set("svix-timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("svix-signature", verifier.Sign("msg_1", now, []byte(body)))
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, handler.HandleEmailEvent(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	// Request signed with another secret
+	other, err := webhook.NewVerifier("whsec_" + "c2VjcmV0")
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/email", strings.NewReader(body))
+	req.Header.Set("svix-id", "msg_2")
+	req.Header.Set("svix-timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("svix-signature", other.Sign("msg_2", now, []byte(body)))
+	rec = httptest.NewRecorder()
+
+	if assert.NoError(t, handler.HandleEmailEvent(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// Verify expectations
+	mockEvents.AssertExpectations(t)
+}
+
+// TestHandleScheduledEmail tests the HandleScheduledEmail handler
+func TestHandleScheduledEmail(t *testing.T) {
+	// Create a new Echo instance
+	e := echo.New()
+
+	// Create a new webhook handler with a mock dispatcher
+	mockDispatcher := new(MockScheduledEmailDispatcher)
+	handler := NewHandler(nil, nil, mockDispatcher, "callback-token")
+
+	// Set up expectations
+	mockDispatcher.On("Dispatch", mock.Anything, "sched_1").Return(nil)
+
+	body := `{"scheduled_email_id":"sched_1"}`
+
+	// Request with the callback token
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/scheduled-emails", strings.NewReader(body))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer callback-token")
+	rec := httptest.NewRecorder()
+
+	if assert.NoError(t, handler.HandleScheduledEmail(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	// Request with another token
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/scheduled-emails", strings.NewReader(body))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer wrong-token")
+	rec = httptest.NewRecorder()
+
+	if assert.NoError(t, handler.HandleScheduledEmail(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// Disabled when no token is configured
+	disabled := NewHandler(nil, nil, mockDispatcher, "")
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/scheduled-emails", strings.NewReader(body))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer ")
+	rec = httptest.NewRecorder()
+
+	if assert.NoError(t, disabled.HandleScheduledEmail(e.NewContext(req, rec))) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	}
+
+	// Verify expectations
+	mockDispatcher.AssertNumberOfCalls(t, "Dispatch", 1)
+}
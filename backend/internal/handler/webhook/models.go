@@ -0,0 +1,11 @@
+package webhook
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error" example:"Invalid signature"`
+}
+
+// ScheduledEmailCallback is sent by Upstash Workflow when a scheduled email is due
+type ScheduledEmailCallback struct {
+	ScheduledEmailID string `json:"scheduled_email_id" example:"6f1c2a9e-3d4b-4c5d-8e9f-0a1b2c3d4e5f"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so they can be told apart from access tokens
+const APIKeyPrefix = "fsk_"
+
+// Scopes of API keys. Keys can also be given the permissions of their user,
+// which they need on top of the permission itself to use the admin API.
+const (
+	// APIKeyScopeRead allows requests that don't change anything
+	APIKeyScopeRead = "read"
+	// APIKeyScopeWrite allows every request, including those with read scope
+	APIKeyScopeWrite = "write"
+)
+
+// APIKey is a long-lived credential users create for scripts and CI. Only a
+// hash of its secret is stored; the prefix identifies it.
+type APIKey struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	SecretHash string     `json:"-" db:"secret_hash"`
+	Scopes     []string   `json:"scopes" db:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// HasScope reports whether the key has a scope. Keys with write scope also
+// have read scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || (scope == APIKeyScopeRead && s == APIKeyScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key expired at a time
+func (k *APIKey) Expired(at time.Time) bool {
+	return k.ExpiresAt != nil && !at.Before(*k.ExpiresAt)
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than an access token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// AuditActorAnonymous is the actor of requests made without credentials
+const AuditActorAnonymous = "anonymous"
+
+// AuditContext describes the request a change is made in, for the audit
+// log. It is carried by the request context so services deep down can
+// record who made a change without it being passed along.
+type AuditContext struct {
+	// "user:<id>", "service_account:<id>", "admin_key" or "anonymous"
+	Actor            string
+	UserID           string
+	ServiceAccountID string
+	RequestID        string
+	IPAddress        string
+	UserAgent        string
+}
+
+// auditContextKey is the context key of the AuditContext of a request
+type auditContextKey struct{}
+
+// WithAuditContext returns a copy of ctx carrying an audit context
+func WithAuditContext(ctx context.Context, audit *AuditContext) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, audit)
+}
+
+// AuditContextFrom returns the audit context carried by ctx, or nil if there
+// is none, such as in background jobs
+func AuditContextFrom(ctx context.Context) *AuditContext {
+	audit, _ := ctx.Value(auditContextKey{}).(*AuditContext)
+	return audit
+}
+
+// AuditEvent is a change a service records in the audit log. Who made it
+// and from where is taken from the audit context of the request.
+type AuditEvent struct {
+	// What happened, such as "organization.renamed"
+	Action     string
+	EntityType string
+	EntityID   string
+	// Fields of the entity that changed, if any
+	Changes map[string]AuditChange
+	// Anything else worth knowing about the change
+	Metadata map[string]interface{}
+}
+
+// AuditChange is the value of a field before and after a change
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditDiff returns the fields whose values differ between before and
+// after. Fields missing from either side are compared with nil.
+func AuditDiff(before, after map[string]interface{}) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+	for field, old := range before {
+		if value := after[field]; !reflect.DeepEqual(old, value) {
+			changes[field] = AuditChange{Old: old, New: value}
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok && value != nil {
+			changes[field] = AuditChange{Old: nil, New: value}
+		}
+	}
+	return changes
+}
+
+// AuditLogFilter narrows down a listing of audit log entries
+type AuditLogFilter struct {
+	// Only return entries made by this user (any user if empty)
+	UserID string
+	// Only return entries made by this service account (any if empty)
+	ServiceAccountID string
+	// Only return entries with this action (any action if empty)
+	Action string
+	// Only return entries about this entity (any entity if empty)
+	EntityType string
+	EntityID   string
+	// Only return entries created at or after this time (ignored if zero)
+	From time.Time
+	// Only return entries created before this time (ignored if zero)
+	To time.Time
+	// Only return entries older than this position (first page if nil)
+	After *AuditLogCursor
+	// Maximum number of entries to return
+	Limit int
+}
+
+// AuditLogCursor identifies a position in a listing of audit log entries,
+// which are ordered from newest to oldest
+type AuditLogCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// AuditVerification is the result of checking the hash chain of the audit log
+type AuditVerification struct {
+	// Number of entries checked
+	Checked int64
+	// Sequence number and hash of the last entry, which can be kept
+	// elsewhere to detect entries removed from the end of the log
+	HeadSequence int64
+	HeadHash     string
+	// The first entry found tampered with, if any
+	Failure *AuditVerificationFailure
+}
+
+// Valid reports whether no tampering was found
+func (v *AuditVerification) Valid() bool {
+	return v.Failure == nil
+}
+
+// AuditVerificationFailure describes where the hash chain of the audit log
+// breaks
+type AuditVerificationFailure struct {
+	Sequence int64
+	EntryID  string
+	Reason   string
+}
@@ -0,0 +1,22 @@
+package model
+
+// CacheStats counts the lookups of a cache since the API started
+type CacheStats struct {
+	// Lookups answered by the cache, including NegativeHits
+	Hits int64 `json:"hits"`
+	// Lookups answered by the cache that the data doesn't exist
+	NegativeHits int64 `json:"negative_hits"`
+	// Lookups that went to the database
+	Misses int64 `json:"misses"`
+	// Cache reads and writes that failed, falling back to the database
+	Errors int64 `json:"errors"`
+}
+
+// HitRatio returns the share of lookups answered by the cache
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
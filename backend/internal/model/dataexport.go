@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Statuses of a data export
+const (
+	// DataExportPending is an export waiting for the export job to build it
+	DataExportPending = "pending"
+	// DataExportReady is an export whose archive can be downloaded
+	DataExportReady = "ready"
+	// DataExportFailed is an export whose archive couldn't be built
+	DataExportFailed = "failed"
+)
+
+// DataExport is a copy of a user's data they requested, built in the
+// background as a ZIP of JSON files and downloaded through an emailed link
+type DataExport struct {
+	ID     string `json:"id" db:"id"`
+	UserID string `json:"user_id" db:"user_id"`
+	Status string `json:"status" db:"status"`
+	// Size of the archive in bytes, once it is built
+	SizeBytes int64   `json:"size_bytes" db:"size_bytes"`
+	Error     *string `json:"error,omitempty" db:"error"`
+	// When the download link stops working and the archive is deleted
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"time"
+)
+
+// Email outbox statuses
+const (
+	// OutboxStatusPending emails are waiting for their next delivery attempt
+	OutboxStatusPending = "pending"
+	// OutboxStatusSending emails have been claimed by a worker
+	OutboxStatusSending = "sending"
+	// OutboxStatusSent emails were accepted by the email provider
+	OutboxStatusSent = "sent"
+	// OutboxStatusDead emails ran out of delivery attempts and need an operator to retry them
+	OutboxStatusDead = "dead"
+)
+
+// OutboxEmail is a rendered email waiting in, or delivered from, the email outbox
+type OutboxEmail struct {
+	ID string `json:"id" db:"id"`
+	// Sent to the provider with every attempt so retries never deliver twice
+	IdempotencyKey string `json:"idempotency_key" db:"idempotency_key"`
+	// Template the email was rendered from, for operators
+	Template    string `json:"template" db:"template"`
+	FromAddress string `json:"from_address" db:"from_address"`
+	// Comma-separated recipient addresses
+	ToAddresses   string     `json:"to_addresses" db:"to_addresses"`
+	Subject       string     `json:"subject" db:"subject"`
+	HTMLBody      string     `json:"-" db:"html_body"`
+	TextBody      string     `json:"-" db:"text_body"`
+	Headers       string     `json:"-" db:"headers"` // JSON object of extra headers, such as List-Unsubscribe
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LockedUntil   *time.Time `json:"-" db:"locked_until"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// OutboxFilter narrows down a listing of outbox emails
+type OutboxFilter struct {
+	// Only return emails with this status (all statuses if empty)
+	Status string
+	// Only return emails sent to this address (all recipients if empty)
+	Recipient string
+	// Maximum number of emails to return
+	Limit int
+	// Number of emails to skip
+	Offset int
+}
+
+// Email suppression reasons
+const (
+	// SuppressionReasonBounce addresses hard-bounced
+	SuppressionReasonBounce = "bounce"
+	// SuppressionReasonComplaint recipients marked an email as spam
+	SuppressionReasonComplaint = "complaint"
+)
+
+// EmailSuppression is an address that no email is sent to, because it is
+// undeliverable or its owner complained
+type EmailSuppression struct {
+	ID string `json:"id" db:"id"`
+	// Lowercased email address
+	Email  string `json:"email" db:"email"`
+	Reason string `json:"reason" db:"reason"`
+	// Provider details, such as the bounce message
+	Details *string `json:"details,omitempty" db:"details"`
+	// ID of the provider event that caused the suppression
+	EventID   *string   `json:"event_id,omitempty" db:"event_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Scheduled email kinds, which determine how the email is rendered when it is due
+const (
+	// ScheduledEmailOnboardingReminder reminds users who haven't verified their email address
+	ScheduledEmailOnboardingReminder = "onboarding_reminder"
+	// ScheduledEmailSecurityDigest summarizes a week of security events, and reschedules itself
+	ScheduledEmailSecurityDigest = "security_digest"
+)
+
+// Scheduled email statuses
+const (
+	// ScheduledEmailStatusScheduled emails are waiting for their send time
+	ScheduledEmailStatusScheduled = "scheduled"
+	// ScheduledEmailStatusSending emails have been claimed for dispatch
+	ScheduledEmailStatusSending = "sending"
+	// ScheduledEmailStatusSent emails were dispatched
+	ScheduledEmailStatusSent = "sent"
+	// ScheduledEmailStatusCancelled emails were cancelled before their send time
+	ScheduledEmailStatusCancelled = "cancelled"
+	// ScheduledEmailStatusFailed emails could not be dispatched
+	ScheduledEmailStatusFailed = "failed"
+)
+
+// ScheduledEmail is an email to send to a user at a later time. It is only
+// rendered when due, so it reflects the user's state at that time.
+type ScheduledEmail struct {
+	ID        string `json:"id" db:"id"`
+	UserID    string `json:"user_id" db:"user_id"`
+	Kind      string `json:"kind" db:"kind"`
+	Recipient string `json:"recipient" db:"recipient"`
+	// Locale the email is rendered in (see pkg/i18n)
+	Locale      string     `json:"locale" db:"locale"`
+	SendAt      time.Time  `json:"send_at" db:"send_at"`
+	Status      string     `json:"status" db:"status"`
+	LastError   *string    `json:"last_error,omitempty" db:"last_error"`
+	LockedUntil *time.Time `json:"-" db:"locked_until"`
+	SentAt      *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
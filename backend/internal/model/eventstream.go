@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Kinds of streamed events
+const (
+	StreamEventAuditLog      = "audit_log"
+	StreamEventSecurityEvent = "security_event"
+)
+
+// StreamEvent is a recorded audit log entry or security event, as exported
+// to the event sinks of a SIEM
+type StreamEvent struct {
+	// ID of the audit log entry or security event
+	ID string `json:"id"`
+	// StreamEventAuditLog or StreamEventSecurityEvent
+	Kind string `json:"kind"`
+	// Audit log action or security event type, such as organization.renamed
+	// or login_failed
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	// Remaining fields of the entry or event
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// EventSinkHealth reports how delivery to an event sink is going
+type EventSinkHealth struct {
+	Name string `json:"name"`
+	// Whether the last delivery to the sink succeeded
+	Healthy bool `json:"healthy"`
+	// Events waiting to be delivered
+	Buffered int `json:"buffered"`
+	// Events delivered since the sink was started
+	Delivered int64 `json:"delivered"`
+	// Events lost because the buffer was full or delivery kept failing
+	Dropped             int64      `json:"dropped"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+)
+
+// Notification channels
+const (
+	// NotificationChannelEmail notifications are sent by email
+	NotificationChannelEmail = "email"
+)
+
+// Notification categories
+const (
+	// NotificationCategoryAccount covers email verification, password and
+	// account security emails, which users can't opt out of
+	NotificationCategoryAccount = "account"
+	// NotificationCategoryLoginAlerts notifies users of logins to their account
+	NotificationCategoryLoginAlerts = "login_alerts"
+	// NotificationCategorySecurityDigest is the periodic summary of security events
+	NotificationCategorySecurityDigest = "security_digest"
+	// NotificationCategoryOnboarding reminds new users to finish setting up their account
+	NotificationCategoryOnboarding = "onboarding"
+)
+
+// NotificationChannels lists every notification channel
+var NotificationChannels = []string{
+	NotificationChannelEmail,
+}
+
+// NotificationCategories lists every notification category, in display order
+var NotificationCategories = []string{
+	NotificationCategoryAccount,
+	NotificationCategoryLoginAlerts,
+	NotificationCategorySecurityDigest,
+	NotificationCategoryOnboarding,
+}
+
+// IsRequiredNotificationCategory reports whether notifications of a category
+// are always sent, whatever the user's preferences
+func IsRequiredNotificationCategory(category string) bool {
+	return category == NotificationCategoryAccount
+}
+
+// NotificationPreference is whether a user receives the notifications of a
+// category on a channel. Notifications are enabled unless the user turned
+// them off, so only changed preferences are stored.
+type NotificationPreference struct {
+	UserID   string `json:"-" db:"user_id"`
+	Category string `json:"category" db:"category"`
+	Channel  string `json:"channel" db:"channel"`
+	Enabled  bool   `json:"enabled" db:"enabled"`
+	// Set for categories that can't be turned off
+	Required  bool       `json:"required" db:"-"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
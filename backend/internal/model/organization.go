@@ -0,0 +1,83 @@
+package model
+
+import (
+	"time"
+)
+
+// Roles of organization members, from most to least privileged
+const (
+	// OrgRoleOwner can do everything, including deleting the organization
+	// and managing other owners
+	OrgRoleOwner = "owner"
+	// OrgRoleAdmin manages the organization's members and invitations
+	OrgRoleAdmin = "admin"
+	// OrgRoleMember can see the organization and its members
+	OrgRoleMember = "member"
+)
+
+// orgRoleRanks orders organization roles by privilege
+var orgRoleRanks = map[string]int{
+	OrgRoleOwner:  3,
+	OrgRoleAdmin:  2,
+	OrgRoleMember: 1,
+}
+
+// IsOrgRole reports whether role is an organization role
+func IsOrgRole(role string) bool {
+	_, ok := orgRoleRanks[role]
+	return ok
+}
+
+// OrgRoleAtLeast reports whether role is as privileged as min
+func OrgRoleAtLeast(role, min string) bool {
+	rank, ok := orgRoleRanks[role]
+	return ok && rank >= orgRoleRanks[min]
+}
+
+// Organization is a team of users sharing an account
+type Organization struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UserOrganization is an organization a user is a member of, with their role
+type UserOrganization struct {
+	Organization
+	Role string `json:"role" db:"role"`
+}
+
+// Membership is the role of a user in an organization. Service accounts
+// owned by an organization act in it with a membership made up from their
+// access token, whose UserID is the ID of the service account.
+type Membership struct {
+	OrganizationID string    `json:"organization_id" db:"organization_id"`
+	UserID         string    `json:"user_id" db:"user_id"`
+	Role           string    `json:"role" db:"role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	ServiceAccount bool      `json:"-" db:"-"`
+}
+
+// Member is a user of an organization
+type Member struct {
+	UserID    string    `json:"user_id" db:"user_id"`
+	Email     string    `json:"email" db:"email"`
+	FullName  string    `json:"full_name" db:"full_name"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Invitation invites someone to join an organization by email. Only the hash
+// of its token is stored; the token itself is in the link emailed to them.
+type Invitation struct {
+	ID             string     `json:"id" db:"id"`
+	OrganizationID string     `json:"organization_id" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Role           string     `json:"role" db:"role"`
+	TokenHash      string     `json:"-" db:"token_hash"`
+	InvitedBy      *string    `json:"invited_by,omitempty" db:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// Rate limiting algorithms
+const (
+	// RateLimitSlidingWindow allows a number of requests in any window of time,
+	// counting every request in the window
+	RateLimitSlidingWindow = "sliding_window"
+	// RateLimitTokenBucket allows bursts of up to a number of requests, then
+	// requests at the rate the bucket refills
+	RateLimitTokenBucket = "token_bucket"
+)
+
+// RateLimitResult is the outcome of a rate limited request
+type RateLimitResult struct {
+	// Allowed is false when the request is over the limit
+	Allowed bool
+	// Limit is the number of requests allowed in the window
+	Limit int
+	// Remaining is the number of requests still allowed right now
+	Remaining int
+	// ResetAfter is how long until the full limit is available again
+	ResetAfter time.Duration
+	// RetryAfter is how long until another request is allowed, zero while
+	// Remaining is above zero
+	RetryAfter time.Duration
+}
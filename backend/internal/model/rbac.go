@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+)
+
+// Built-in roles, created by the RBAC migration
+const (
+	// RoleAdmin has every permission
+	RoleAdmin = "admin"
+	// RoleSupport can look up users and their roles, but not change them
+	RoleSupport = "support"
+)
+
+// Permissions checked by the API
+const (
+	// PermissionUsersRead allows looking up users
+	PermissionUsersRead = "users:read"
+	// PermissionUsersManage allows changing users and their accounts
+	PermissionUsersManage = "users:manage"
+	// PermissionUsersImpersonate allows signing in as another user
+	PermissionUsersImpersonate = "users:impersonate"
+	// PermissionRolesRead allows listing roles and who has them
+	PermissionRolesRead = "roles:read"
+	// PermissionRolesManage allows assigning roles and permissions
+	PermissionRolesManage = "roles:manage"
+	// PermissionEmailsManage allows inspecting and retrying outgoing emails
+	PermissionEmailsManage = "emails:manage"
+	// PermissionServiceAccountsManage allows managing the service accounts of the admins
+	PermissionServiceAccountsManage = "service_accounts:manage"
+	// PermissionAuditLogsRead allows reading the audit log
+	PermissionAuditLogsRead = "audit_logs:read"
+	// PermissionWebhooksManage allows registering webhook endpoints and
+	// replaying their deliveries
+	PermissionWebhooksManage = "webhooks:manage"
+)
+
+// Role is a named set of permissions assigned to users
+type Role struct {
+	ID          string    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Permissions []string  `json:"permissions" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Permission is an action that roles can be allowed to take
+type Permission struct {
+	ID          string `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// Authorization is what a user is allowed to do: their roles and the
+// permissions of those roles
+type Authorization struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// HasRole reports whether the user has a role
+func (a *Authorization) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the user has a permission
+func (a *Authorization) Can(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -35,6 +35,36 @@ type AccountLock struct {
 	UnlockAt  time.Time `json:"unlock_at" db:"unlock_at"`
 	Reason    string    `json:"reason" db:"reason"`
 	CreatedBy string    `json:"created_by" db:"created_by"`
+	// SHA-256 hash of the token emailed to the user to unlock the account early
+	UnlockTokenHash *string `json:"-" db:"unlock_token_hash"`
+}
+
+// SecurityEventFilter narrows down a listing of a user's security events
+type SecurityEventFilter struct {
+	// Only return events of these types (all types if empty)
+	EventTypes []string
+	// Only return events created at or after this time (ignored if zero)
+	From time.Time
+	// Only return events created before this time (ignored if zero)
+	To time.Time
+	// Only return events older than this position (first page if nil)
+	After *SecurityEventCursor
+	// Maximum number of events to return
+	Limit int
+}
+
+// SecurityEventCursor identifies a position in a listing of security events,
+// which are ordered from newest to oldest
+type SecurityEventCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// SecurityEventPage is a page of security events
+type SecurityEventPage struct {
+	Events []*SecurityEvent
+	// NextCursor is the opaque cursor for the next page, empty on the last page
+	NextCursor string
 }
 
 // SecurityEventTypes defines constants for different types of security events
@@ -63,7 +93,20 @@ const (
 
 	// Suspicious activity
 	EventSuspiciousActivity = "suspicious_activity"
+	EventActivityReported   = "activity_reported"
 
 	// Admin actions
-	EventAdminAction = "admin_action"
+	EventAdminAction          = "admin_action"
+	EventImpersonationStarted = "impersonation_started"
+	EventImpersonationStopped = "impersonation_stopped"
 )
+
+// SecurityDigest summarizes a user's security events over a period
+type SecurityDigest struct {
+	From time.Time
+	To   time.Time
+	// Number of events of each type in the period
+	Counts map[string]int
+	// Most recent events worth reviewing, newest first
+	Events []*SecurityEvent
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// Kinds of principals requests are made by
+const (
+	// PrincipalUser is a person signed in with an access token or API key
+	PrincipalUser = "user"
+	// PrincipalService is a service account signed in with its client credentials
+	PrincipalService = "service"
+)
+
+// Principal is who a request is made by
+type Principal struct {
+	// PrincipalUser or PrincipalService
+	Type string
+	// ID of the user or service account
+	ID string
+	// Organization the principal acts in and its role there, if any
+	OrganizationID string
+	OrgRole        string
+	// Permissions a service account was given, which it is limited to
+	Scopes []string
+}
+
+// IsService reports whether the principal is a service account
+func (p *Principal) IsService() bool {
+	return p.Type == PrincipalService
+}
+
+// HasScope reports whether the principal was given a scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceAccountClientIDPrefix starts the client ID of every service account
+const ServiceAccountClientIDPrefix = "sa_"
+
+// ServiceAccount is a non-human identity for backend jobs, signing in with a
+// client ID and secret. Service accounts are owned by an organization, where
+// they act with a role, or by the admins, where they act with the permissions
+// they were given. Only a hash of the secret is stored.
+type ServiceAccount struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// Organization owning the account, nil for accounts owned by the admins
+	OrganizationID *string `json:"organization_id,omitempty" db:"organization_id"`
+	// Role in the owning organization
+	Role       string     `json:"role,omitempty" db:"role"`
+	Scopes     []string   `json:"scopes" db:"-"`
+	ClientID   string     `json:"client_id" db:"client_id"`
+	SecretHash string     `json:"-" db:"secret_hash"`
+	CreatedBy  *string    `json:"created_by,omitempty" db:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ServiceToken is an access token issued to a service account
+type ServiceToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	Scopes      []string
+}
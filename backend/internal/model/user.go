@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+)
+
+// AdminUser is a user as seen by admins, with the state of their account
+type AdminUser struct {
+	ID            string     `json:"id" db:"id"`
+	Email         string     `json:"email" db:"email"`
+	FullName      string     `json:"full_name" db:"full_name"`
+	AvatarURL     *string    `json:"avatar_url,omitempty" db:"avatar_url"`
+	EmailVerified bool       `json:"email_verified" db:"email_verified"`
+	Locale        string     `json:"locale" db:"locale"`
+	DisabledAt    *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+	// End and reason of the account lock, if the account is locked
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	LockReason  *string    `json:"lock_reason,omitempty" db:"lock_reason"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// UserSession is a session a user is signed in with
+type UserSession struct {
+	ID        string    `json:"id" db:"id"`
+	UserAgent *string   `json:"user_agent,omitempty" db:"user_agent"`
+	ClientIP  *string   `json:"client_ip,omitempty" db:"client_ip"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LinkedAccount is an OAuth provider account a user signs in with
+type LinkedAccount struct {
+	Provider       string    `json:"provider" db:"provider"`
+	ProviderUserID string    `json:"provider_user_id" db:"provider_user_id"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserDetail is a user with their sessions and linked accounts
+type UserDetail struct {
+	User           *AdminUser       `json:"user"`
+	Sessions       []*UserSession   `json:"sessions"`
+	LinkedAccounts []*LinkedAccount `json:"linked_accounts"`
+}
+
+// UserFilter narrows down a search of users
+type UserFilter struct {
+	// Only return users whose email or name contains this text (all users if empty)
+	Query string
+	// Only return users whose email is (or isn't) verified (ignored if nil)
+	Verified *bool
+	// Only return users whose account is (or isn't) locked (ignored if nil)
+	Locked *bool
+	// Only return users whose account is (or isn't) disabled (ignored if nil)
+	Disabled *bool
+	// Only return users created at or after this time (ignored if zero)
+	CreatedFrom time.Time
+	// Only return users created before this time (ignored if zero)
+	CreatedTo time.Time
+	// Maximum number of users to return
+	Limit int
+	// Number of users to skip
+	Offset int
+}
+
+// AdminActor identifies who made an admin request, for auditing
+type AdminActor struct {
+	// "user:<id>" for users with admin permissions, "service_account:<id>" for
+	// service accounts, "admin_key" for the admin API key
+	ID string
+	// ID of the user, empty for service accounts and the admin API key
+	UserID    string
+	IPAddress string
+	UserAgent string
+}
+
+// Impersonation is an admin signed in as another user, with an access token
+// that can't be refreshed
+type Impersonation struct {
+	// ID of the impersonation's access token
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// ID of the admin impersonating the user
+	ActorID   string    `json:"actor_id"`
+	Reason    string    `json:"reason"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
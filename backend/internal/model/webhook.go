@@ -0,0 +1,144 @@
+package model
+
+import "time"
+
+// Identity lifecycle events sent to webhook endpoints
+const (
+	// WebhookEventUserRegistered is sent when a user signs up
+	WebhookEventUserRegistered = "user.registered"
+	// WebhookEventUserVerified is sent when a user verifies their email address
+	WebhookEventUserVerified = "user.verified"
+	// WebhookEventUserEmailChanged is sent when a user's email address changes
+	WebhookEventUserEmailChanged = "user.email_changed"
+	// WebhookEventUserDeleted is sent when a user deletes their account
+	WebhookEventUserDeleted = "user.deleted"
+	// WebhookEventUserLocked is sent when a user's account is locked
+	WebhookEventUserLocked = "user.locked"
+	// WebhookEventUserUnlocked is sent when a user's account is unlocked
+	WebhookEventUserUnlocked = "user.unlocked"
+)
+
+// WebhookEventTypes are the events webhook endpoints can subscribe to
+var WebhookEventTypes = []string{
+	WebhookEventUserRegistered,
+	WebhookEventUserVerified,
+	WebhookEventUserEmailChanged,
+	WebhookEventUserDeleted,
+	WebhookEventUserLocked,
+	WebhookEventUserUnlocked,
+}
+
+// WebhookEndpoint is a URL another service registered to be notified of
+// identity lifecycle events. Requests to it are signed with its secret.
+type WebhookEndpoint struct {
+	ID  string `json:"id" db:"id"`
+	URL string `json:"url" db:"url"`
+	// "whsec_" secret the requests are signed with
+	Secret      string    `json:"-" db:"secret"`
+	EventTypes  []string  `json:"event_types" db:"-"`
+	Description string    `json:"description" db:"description"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	CreatedBy   *string   `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribes reports whether the endpoint is sent an event type
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEndpointInput holds the settings of a new webhook endpoint
+type WebhookEndpointInput struct {
+	URL         string
+	EventTypes  []string
+	Description string
+	// "whsec_" secret to sign requests with, generated when empty
+	Secret string
+}
+
+// WebhookEndpointUpdate holds the settings of a webhook endpoint to change,
+// leaving those that are nil as they are
+type WebhookEndpointUpdate struct {
+	URL         *string
+	EventTypes  []string
+	Description *string
+	Enabled     *bool
+}
+
+// WebhookEvent is the payload of a webhook request
+type WebhookEvent struct {
+	// Sent as the webhook-id header, the same for every delivery of the event
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Webhook delivery statuses
+const (
+	// WebhookDeliveryPending deliveries are waiting for their next attempt
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliverySending deliveries have been claimed by a worker
+	WebhookDeliverySending = "sending"
+	// WebhookDeliveryDelivered deliveries were accepted by the endpoint
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryDead deliveries ran out of attempts and can be replayed
+	WebhookDeliveryDead = "dead"
+)
+
+// WebhookDelivery is an event queued for, or delivered to, an endpoint
+type WebhookDelivery struct {
+	ID         string `json:"id" db:"id"`
+	EndpointID string `json:"endpoint_id" db:"endpoint_id"`
+	EventID    string `json:"event_id" db:"event_id"`
+	EventType  string `json:"event_type" db:"event_type"`
+	// JSON encoded WebhookEvent, sent as is with every attempt
+	Payload  string `json:"payload" db:"payload"`
+	Status   string `json:"status" db:"status"`
+	Attempts int    `json:"attempts" db:"attempts"`
+	// HTTP status of the last attempt, if the endpoint responded
+	LastStatusCode *int       `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      *string    `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LockedUntil    *time.Time `json:"-" db:"locked_until"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	// Delivery this one replays, if any
+	ReplayOf  *string   `json:"replay_of,omitempty" db:"replay_of"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDeliveryAttempt is the outcome of a request made to deliver an
+// event, kept in the delivery log
+type WebhookDeliveryAttempt struct {
+	ID         string `json:"id" db:"id"`
+	DeliveryID string `json:"delivery_id" db:"delivery_id"`
+	// Attempt number, starting at 1
+	Attempt int `json:"attempt" db:"attempt"`
+	// HTTP status of the response, nil if there was none
+	StatusCode *int `json:"status_code,omitempty" db:"status_code"`
+	// Start of the response body, for debugging
+	ResponseBody string    `json:"response_body,omitempty" db:"response_body"`
+	Error        *string   `json:"error,omitempty" db:"error"`
+	DurationMS   int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookDeliveryFilter narrows down a listing of an endpoint's deliveries
+type WebhookDeliveryFilter struct {
+	EndpointID string
+	// Only return deliveries with this status (all statuses if empty)
+	Status string
+	// Only return deliveries of this event type (all types if empty)
+	EventType string
+	// Maximum number of deliveries to return
+	Limit int
+	// Number of deliveries to skip
+	Offset int
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// AuditLog is an entry of the audit log. Entries form a hash chain: each
+// one's hash covers its content and the hash of the entry before it, so
+// changing or removing an entry breaks the chain from there on.
+type AuditLog struct {
+	ID string `json:"id"`
+	// Position in the hash chain, starting at 1
+	Sequence int64  `json:"sequence"`
+	UserID   string `json:"userId"`
+	// Service account that acted, if any
+	ServiceAccountID string `json:"serviceAccountId,omitempty"`
+	// "user:<id>", "service_account:<id>", "admin_key" or "anonymous"
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	EntityType string `json:"entityType"`
+	EntityID   string `json:"entityId"`
+	// JSON object, with the fields that changed under "changes"
+	Metadata  string    `json:"metadata"`
+	RequestID string    `json:"requestId,omitempty"`
+	IPAddress string    `json:"ipAddress,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	PrevHash  string    `json:"prevHash"`
+	Hash      string    `json:"hash"`
+}
+
+// ComputeHash returns the hash of the entry, chained to PrevHash. It is the
+// SHA-256 of the JSON encoding of every field but Hash, with the creation
+// time in UTC at the microsecond precision the database keeps.
+func (l *AuditLog) ComputeHash() string {
+	content, _ := json.Marshal(struct {
+		Sequence         int64  `json:"sequence"`
+		PrevHash         string `json:"prev_hash"`
+		ID               string `json:"id"`
+		UserID           string `json:"user_id"`
+		ServiceAccountID string `json:"service_account_id"`
+		Actor            string `json:"actor"`
+		Action           string `json:"action"`
+		EntityType       string `json:"entity_type"`
+		EntityID         string `json:"entity_id"`
+		Metadata         string `json:"metadata"`
+		RequestID        string `json:"request_id"`
+		IPAddress        string `json:"ip_address"`
+		CreatedAt        string `json:"created_at"`
+	}{
+		Sequence:         l.Sequence,
+		PrevHash:         l.PrevHash,
+		ID:               l.ID,
+		UserID:           l.UserID,
+		ServiceAccountID: l.ServiceAccountID,
+		Actor:            l.Actor,
+		Action:           l.Action,
+		EntityType:       l.EntityType,
+		EntityID:         l.EntityID,
+		Metadata:         l.Metadata,
+		RequestID:        l.RequestID,
+		IPAddress:        l.IPAddress,
+		CreatedAt:        l.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditLogPage is a page of audit log entries
+type AuditLogPage struct {
+	Entries []*AuditLog `json:"entries"`
+	// Cursor of the next page, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
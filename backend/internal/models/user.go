@@ -11,8 +11,11 @@ type User struct {
 	FullName      string    `json:"fullName"`
 	AvatarURL     string    `json:"avatarUrl"`
 	EmailVerified bool      `json:"emailVerified"`
+	Locale        string    `json:"locale"`
 	CreatedAt     time.Time `json:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt"`
+	// When an admin disabled the account, which can't sign in until enabled
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
 }
 
 type Session struct {
@@ -24,6 +27,20 @@ type Session struct {
 	IsBlocked    bool      `json:"isBlocked"`
 	ExpiresAt    time.Time `json:"expiresAt"`
 	CreatedAt    time.Time `json:"createdAt"`
+	// Claims of the access token the session was validated from
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Admin signed in as the user, for impersonation tokens
+	ImpersonatorID string `json:"impersonatorId,omitempty"`
+	// Organization the user switched to and their role in it, if any
+	OrgID   string `json:"orgId,omitempty"`
+	OrgRole string `json:"orgRole,omitempty"`
+	// API key the request was made with and its scopes, if any
+	APIKeyID string   `json:"apiKeyId,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// Service account the access token was issued to, in which case there is
+	// no user and Permissions are the scopes the account was given
+	ServiceAccountID string `json:"serviceAccountId,omitempty"`
 }
 
 type OAuthAccount struct {
@@ -47,22 +64,13 @@ type VerificationToken struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
-type AuditLog struct {
-	ID         string    `json:"id"`
-	UserID     string    `json:"userId"`
-	Action     string    `json:"action"`
-	EntityType string    `json:"entityType"`
-	EntityID   string    `json:"entityId"`
-	Metadata   string    `json:"metadata"`
-	CreatedAt  time.Time `json:"createdAt"`
-}
-
 // Request/Response models
 type CreateUserRequest struct {
 	Email     string `json:"email" validate:"required,email"`
 	Password  string `json:"password" validate:"required,min=8"`
 	FullName  string `json:"fullName" validate:"required"`
 	AvatarURL string `json:"avatarUrl"`
+	Locale    string `json:"locale"`
 }
 
 type UpdateUserRequest struct {
@@ -70,6 +78,7 @@ type UpdateUserRequest struct {
 	Password  *string `json:"password" validate:"omitempty,min=8"`
 	FullName  *string `json:"fullName"`
 	AvatarURL *string `json:"avatarUrl"`
+	Locale    *string `json:"locale"`
 }
 
 type LoginRequest struct {
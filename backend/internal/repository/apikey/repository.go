@@ -0,0 +1,152 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// apiKeyColumns are the columns of an apiKeyRow
+const apiKeyColumns = `id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, created_at`
+
+// lastUsedResolution is how stale the last use of a key may get, so keys used
+// for every request don't write to the database every time
+const lastUsedResolution = time.Minute
+
+// apiKeyRow is an API key as stored, with its scopes separated by spaces
+type apiKeyRow struct {
+	model.APIKey
+	Scopes string `db:"scopes"`
+}
+
+// toAPIKey converts a stored API key to its model
+func (row *apiKeyRow) toAPIKey() *model.APIKey {
+	key := row.APIKey
+	key.Scopes = strings.Fields(row.Scopes)
+	return &key
+}
+
+// Repository implements the apikey.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new API key repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateAPIKey stores an API key, filling in its ID and creation time
+func (r *Repository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, secret_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	row := r.db.QueryRowxContext(ctx, query,
+		key.UserID, key.Name, key.Prefix, key.SecretHash, strings.Join(key.Scopes, " "), key.ExpiresAt,
+	)
+	if err := row.Scan(&key.ID, &key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// CountAPIKeys counts the API keys of a user
+func (r *Repository) CountAPIKeys(ctx context.Context, userID string) (int, error) {
+	query := `SELECT COUNT(*) FROM api_keys WHERE user_id = $1`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListAPIKeys gets the API keys of a user, newest first
+func (r *Repository) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+	`
+
+	var rows []apiKeyRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	keys := make([]*model.APIKey, len(rows))
+	for i := range rows {
+		keys[i] = rows[i].toAPIKey()
+	}
+
+	return keys, nil
+}
+
+// GetActiveAPIKey gets the API key with a prefix, or nil if there is none or
+// its user is disabled or locked out
+func (r *Repository) GetActiveAPIKey(ctx context.Context, prefix string) (*model.APIKey, error) {
+	query := `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.secret_hash, k.scopes, k.expires_at, k.last_used_at, k.created_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1
+			AND u.disabled_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM account_locks l WHERE l.user_id = k.user_id AND l.unlock_at > NOW()
+			)
+	`
+
+	var row apiKeyRow
+	if err := r.db.GetContext(ctx, &row, query, prefix); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return row.toAPIKey(), nil
+}
+
+// TouchAPIKey records that an API key was just used
+func (r *Repository) TouchAPIKey(ctx context.Context, id string) error {
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - $2 * INTERVAL '1 second')
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, int(lastUsedResolution.Seconds())); err != nil {
+		return fmt.Errorf("failed to update API key last use: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAPIKey deletes an API key of a user and reports whether there was one
+func (r *Repository) DeleteAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	query := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	return deleted > 0, nil
+}
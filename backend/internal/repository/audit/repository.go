@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// chainLockID is the advisory lock serializing appends to the hash chain
+const chainLockID = 7310594281
+
+// auditLogColumns are the columns of an audit log entry, in auditLogRow order
+const auditLogColumns = `
+	id, COALESCE(sequence, 0) AS sequence, COALESCE(user_id, '') AS user_id,
+	COALESCE(service_account_id, '') AS service_account_id, actor, action,
+	entity_type, entity_id, COALESCE(metadata, '') AS metadata, request_id,
+	ip_address, created_at, prev_hash, hash
+`
+
+// auditLogRow is an audit log entry as stored
+type auditLogRow struct {
+	ID               string    `db:"id"`
+	Sequence         int64     `db:"sequence"`
+	UserID           string    `db:"user_id"`
+	ServiceAccountID string    `db:"service_account_id"`
+	Actor            string    `db:"actor"`
+	Action           string    `db:"action"`
+	EntityType       string    `db:"entity_type"`
+	EntityID         string    `db:"entity_id"`
+	Metadata         string    `db:"metadata"`
+	RequestID        string    `db:"request_id"`
+	IPAddress        string    `db:"ip_address"`
+	CreatedAt        time.Time `db:"created_at"`
+	PrevHash         string    `db:"prev_hash"`
+	Hash             string    `db:"hash"`
+}
+
+// toAuditLog converts a row to its model
+func (row *auditLogRow) toAuditLog() *models.AuditLog {
+	return &models.AuditLog{
+		ID:               row.ID,
+		Sequence:         row.Sequence,
+		UserID:           row.UserID,
+		ServiceAccountID: row.ServiceAccountID,
+		Actor:            row.Actor,
+		Action:           row.Action,
+		EntityType:       row.EntityType,
+		EntityID:         row.EntityID,
+		Metadata:         row.Metadata,
+		RequestID:        row.RequestID,
+		IPAddress:        row.IPAddress,
+		CreatedAt:        row.CreatedAt,
+		PrevHash:         row.PrevHash,
+		Hash:             row.Hash,
+	}
+}
+
+// Repository stores audit log entries
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new audit log repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateAuditLog appends an entry to the audit log, filling in its ID and
+// creation time when they are missing, and chaining it to the last entry:
+// its sequence, previous hash and hash are set. Appends are serialized with
+// an advisory lock so the chain never forks. Entries made without a user,
+// such as those of service accounts, have no user ID.
+func (r *Repository) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	if log.ID == "" {
+		log.ID = uuid.New().String()
+	}
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	// Hash what the database keeps
+	log.CreatedAt = log.CreatedAt.UTC().Truncate(time.Microsecond)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockID); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+
+	var last struct {
+		Sequence int64  `db:"sequence"`
+		Hash     string `db:"hash"`
+	}
+	err = tx.GetContext(ctx, &last, `
+		SELECT sequence, hash FROM audit_logs
+		WHERE sequence IS NOT NULL
+		ORDER BY sequence DESC
+		LIMIT 1
+	`)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get last audit log: %w", err)
+	}
+
+	log.Sequence = last.Sequence + 1
+	log.PrevHash = last.Hash
+	log.Hash = log.ComputeHash()
+
+	query := `
+		INSERT INTO audit_logs (
+			id, sequence, user_id, service_account_id, actor, action, entity_type,
+			entity_id, metadata, request_id, ip_address, created_at, prev_hash, hash
+		) VALUES (
+			$1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+		)
+	`
+
+	_, err = tx.ExecContext(ctx, query,
+		log.ID, log.Sequence, log.UserID, log.ServiceAccountID, log.Actor, log.Action, log.EntityType,
+		log.EntityID, log.Metadata, log.RequestID, log.IPAddress, log.CreatedAt, log.PrevHash, log.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditLogs gets a page of the entries made by a user, newest first
+func (r *Repository) GetAuditLogs(ctx context.Context, userID string, offset, limit int) ([]*models.AuditLog, error) {
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.selectAuditLogs(ctx, "failed to get audit logs", query, userID, limit, offset)
+}
+
+// CountAuditLogs counts the entries made by a user
+func (r *Repository) CountAuditLogs(ctx context.Context, userID string) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM audit_logs WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+	return count, nil
+}
+
+// ListAuditLogs gets a page of entries using keyset pagination. Entries are
+// ordered from newest to oldest, with the entry ID as a tie-breaker.
+func (r *Repository) ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*models.AuditLog, error) {
+	var conditions []string
+	var args []interface{}
+
+	equal := map[string]string{
+		"user_id":            filter.UserID,
+		"service_account_id": filter.ServiceAccountID,
+		"action":             filter.Action,
+		"entity_type":        filter.EntityType,
+		"entity_id":          filter.EntityID,
+	}
+	for _, column := range []string{"user_id", "service_account_id", "action", "entity_type", "entity_id"} {
+		if value := equal[column]; value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		createdAt, id := len(args)-1, len(args)
+		conditions = append(conditions, fmt.Sprintf(
+			"(created_at < $%d OR (created_at = $%d AND id < $%d))", createdAt, createdAt, id,
+		))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM audit_logs
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, auditLogColumns, where, len(args))
+
+	return r.selectAuditLogs(ctx, "failed to list audit logs", query, args...)
+}
+
+// ListAuditLogsBySequence gets the entries of the hash chain after a
+// sequence number, in order
+func (r *Repository) ListAuditLogsBySequence(ctx context.Context, after int64, limit int) ([]*models.AuditLog, error) {
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		WHERE sequence > $1
+		ORDER BY sequence
+		LIMIT $2
+	`
+
+	return r.selectAuditLogs(ctx, "failed to list audit logs", query, after, limit)
+}
+
+// selectAuditLogs runs a query selecting audit log entries
+func (r *Repository) selectAuditLogs(ctx context.Context, message, query string, args ...interface{}) ([]*models.AuditLog, error) {
+	var rows []auditLogRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("%s: %w", message, err)
+	}
+
+	logs := make([]*models.AuditLog, len(rows))
+	for i := range rows {
+		logs[i] = rows[i].toAuditLog()
+	}
+	return logs, nil
+}
@@ -0,0 +1,374 @@
+// Package cached provides repositories that keep frequent lookups in the cache
+// service, in front of the database.
+package cached
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/repository"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/nanayaw/fullstack/pkg/singleflight"
+)
+
+const (
+	// keyVersion is part of every cache key. Bump it when the cached data
+	// changes shape, so entries written by older versions are never read.
+	keyVersion = "v1"
+
+	// userTTL is how long users are cached
+	userTTL = 5 * time.Minute
+	// sessionTTL is how long sessions are cached, kept short since every
+	// authenticated request reads them
+	sessionTTL = time.Minute
+	// negativeTTL is how long lookups of missing users and sessions are cached
+	negativeTTL = 30 * time.Second
+)
+
+// entry is a cached lookup, which found Value or nothing
+type entry[T any] struct {
+	Missing bool `json:"missing,omitempty"`
+	Value   *T   `json:"value,omitempty"`
+}
+
+// cachedUser is a cached user, including the fields left out of its JSON
+type cachedUser struct {
+	models.User
+	PasswordHash string `json:"passwordHash"`
+}
+
+// cachedSession is a cached session, including the fields left out of its
+// JSON and when it was read from the database
+type cachedSession struct {
+	models.Session
+	RefreshToken string    `json:"refreshToken"`
+	LoadedAt     time.Time `json:"loadedAt"`
+}
+
+// idRef is the ID of the user of an email address or the session of a token
+type idRef struct {
+	ID string `json:"id"`
+}
+
+// UserRepository is a UserRepository that caches users and sessions. Lookups
+// of users and sessions that don't exist are cached too, and concurrent misses
+// for the same key are collapsed into one database query. Writes invalidate
+// the affected entries, including password changes, which are user updates.
+// Other operations go straight to the wrapped repository.
+type UserRepository struct {
+	repository.UserRepository
+	cache service.CacheService
+	group singleflight.Group
+	log   logger.Logger
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	errors       atomic.Int64
+}
+
+// NewUserRepository creates a new UserRepository caching the lookups of next
+func NewUserRepository(next repository.UserRepository, cache service.CacheService, log logger.Logger) *UserRepository {
+	return &UserRepository{
+		UserRepository: next,
+		cache:          cache,
+		log:            log,
+	}
+}
+
+// Stats returns the hits and misses of the cache since it was created
+func (r *UserRepository) Stats() model.CacheStats {
+	return model.CacheStats{
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		Errors:       r.errors.Load(),
+	}
+}
+
+// GetUserByID returns the user with an ID, or nil if there is none
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	cached, err := getOrLoad(r, ctx, userKey(id), userTTL, nil, func() (*cachedUser, error) {
+		user, err := r.UserRepository.GetUserByID(ctx, id)
+		if err != nil || user == nil {
+			return nil, err
+		}
+		return &cachedUser{User: *user, PasswordHash: user.PasswordHash}, nil
+	})
+	if err != nil || cached == nil {
+		return nil, err
+	}
+
+	user := cached.User
+	user.PasswordHash = cached.PasswordHash
+	return &user, nil
+}
+
+// GetUserByEmail returns the user with an email address, or nil if there is
+// none. Email addresses are cached as the ID of their user, so the user itself
+// is only cached once.
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var found *models.User
+	ref, err := getOrLoad(r, ctx, userEmailKey(email), userTTL, nil, func() (*idRef, error) {
+		user, err := r.UserRepository.GetUserByEmail(ctx, email)
+		if err != nil || user == nil {
+			return nil, err
+		}
+		found = user
+		return &idRef{ID: user.ID}, nil
+	})
+	if err != nil || ref == nil {
+		return nil, err
+	}
+	if found != nil {
+		return found, nil
+	}
+
+	user, err := r.GetUserByID(ctx, ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The user may have changed email address since it was cached
+	if user == nil || normalizeEmail(user.Email) != normalizeEmail(email) {
+		if err := r.cache.InvalidateCache(ctx, userEmailKey(email)); err != nil {
+			r.cacheError("Failed to invalidate cached email address", err)
+		}
+		return r.UserRepository.GetUserByEmail(ctx, email)
+	}
+	return user, nil
+}
+
+// CreateUser creates a user, forgetting that it was missing
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if err := r.UserRepository.CreateUser(ctx, user); err != nil {
+		return err
+	}
+	r.invalidate(ctx, userKey(user.ID), userEmailKey(user.Email))
+	return nil
+}
+
+// UpdateUser updates a user, including their password, and invalidates it
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	if err := r.UserRepository.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	// A cached previous email address is detected when it is looked up
+	r.invalidate(ctx, userKey(user.ID), userEmailKey(user.Email))
+	return nil
+}
+
+// DeleteUser deletes a user and invalidates it
+func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
+	if err := r.UserRepository.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, userKey(id))
+	return nil
+}
+
+// GetSessionByID returns the session with an ID, or nil if there is none
+func (r *UserRepository) GetSessionByID(ctx context.Context, id string) (*models.Session, error) {
+	cached, err := getOrLoad(r, ctx, sessionKey(id), sessionTTL, r.sessionValid(ctx), func() (*cachedSession, error) {
+		loadedAt := time.Now()
+		session, err := r.UserRepository.GetSessionByID(ctx, id)
+		if err != nil || session == nil {
+			return nil, err
+		}
+		return newCachedSession(session, loadedAt), nil
+	})
+	if err != nil || cached == nil {
+		return nil, err
+	}
+
+	session := cached.Session
+	session.RefreshToken = cached.RefreshToken
+	return &session, nil
+}
+
+// GetSessionByToken returns the session of a token, or nil if there is none.
+// Tokens are cached as the ID of their session, so they are hashed in keys
+// and sessions are only cached once.
+func (r *UserRepository) GetSessionByToken(ctx context.Context, token string) (*models.Session, error) {
+	var found *models.Session
+	ref, err := getOrLoad(r, ctx, sessionTokenKey(token), sessionTTL, nil, func() (*idRef, error) {
+		session, err := r.UserRepository.GetSessionByToken(ctx, token)
+		if err != nil || session == nil {
+			return nil, err
+		}
+		found = session
+		return &idRef{ID: session.ID}, nil
+	})
+	if err != nil || ref == nil {
+		return nil, err
+	}
+	if found != nil {
+		return found, nil
+	}
+
+	return r.GetSessionByID(ctx, ref.ID)
+}
+
+// CreateSession creates a session, forgetting that it was missing
+func (r *UserRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	if err := r.UserRepository.CreateSession(ctx, session); err != nil {
+		return err
+	}
+	r.invalidate(ctx, sessionKey(session.ID), sessionTokenKey(session.RefreshToken))
+	return nil
+}
+
+// DeleteSession deletes a session and invalidates it
+func (r *UserRepository) DeleteSession(ctx context.Context, id string) error {
+	if err := r.UserRepository.DeleteSession(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, sessionKey(id))
+	return nil
+}
+
+// BlockSession blocks a session and invalidates it
+func (r *UserRepository) BlockSession(ctx context.Context, id string) error {
+	if err := r.UserRepository.BlockSession(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, sessionKey(id))
+	return nil
+}
+
+// DeleteUserSessions deletes the sessions of a user. Their IDs aren't known,
+// so the sessions of the user read from the database before now are marked
+// stale instead of being invalidated one by one.
+func (r *UserRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	if err := r.UserRepository.DeleteUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	// Cached sessions are stale once they are older than sessionTTL anyway
+	ttl := int((2 * sessionTTL).Seconds())
+	if err := r.cache.CacheData(ctx, sessionsRevokedKey(userID), time.Now(), ttl); err != nil {
+		// Unlike other invalidations, callers must know deleted sessions may
+		// still be accepted
+		r.cacheError("Failed to revoke cached sessions", err)
+		return fmt.Errorf("failed to revoke cached sessions: %w", err)
+	}
+	return nil
+}
+
+// sessionValid returns a function reporting whether a cached session was read
+// after the sessions of its user were last deleted
+func (r *UserRepository) sessionValid(ctx context.Context) func(*cachedSession) bool {
+	return func(cached *cachedSession) bool {
+		var revokedAt time.Time
+		if err := r.cache.GetCachedData(ctx, sessionsRevokedKey(cached.UserID), &revokedAt); err != nil {
+			r.cacheError("Failed to get revoked sessions", err)
+			return false
+		}
+		return cached.LoadedAt.After(revokedAt)
+	}
+}
+
+// invalidate removes keys from the cache. The database was already written,
+// so failures are logged rather than returned, and entries expire in time.
+func (r *UserRepository) invalidate(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		r.group.Forget(key)
+		if err := r.cache.InvalidateCache(ctx, key); err != nil {
+			r.cacheError("Failed to invalidate cache", err, "key", key)
+		}
+	}
+}
+
+// cacheError counts and logs a failed cache operation
+func (r *UserRepository) cacheError(msg string, err error, keysAndValues ...interface{}) {
+	r.errors.Add(1)
+	r.log.Error(msg, append(keysAndValues, "error", err)...)
+}
+
+// getOrLoad returns the value cached at key if valid is nil or accepts it, or
+// loads, caches and returns it. A nil value means there is no data, which is
+// cached for negativeTTL. Concurrent loads of a key are collapsed into one, and
+// the database is used alone when the cache fails.
+func getOrLoad[T any](r *UserRepository, ctx context.Context, key string, ttl time.Duration, valid func(*T) bool, load func() (*T, error)) (*T, error) {
+	var cached entry[T]
+	if err := r.cache.GetCachedData(ctx, key, &cached); err != nil {
+		r.cacheError("Failed to get cached data", err, "key", key)
+	} else if cached.Missing {
+		r.hits.Add(1)
+		r.negativeHits.Add(1)
+		return nil, nil
+	} else if cached.Value != nil && (valid == nil || valid(cached.Value)) {
+		r.hits.Add(1)
+		return cached.Value, nil
+	}
+	r.misses.Add(1)
+
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		e, entryTTL := entry[T]{Missing: value == nil, Value: value}, ttl
+		if value == nil {
+			entryTTL = negativeTTL
+		}
+		if err := r.cache.CacheData(ctx, key, e, int(entryTTL.Seconds())); err != nil {
+			r.cacheError("Failed to cache data", err, "key", key)
+		}
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := v.(*T)
+	if value == nil {
+		return nil, nil
+	}
+	// Callers sharing a load get their own copy
+	copied := *value
+	return &copied, nil
+}
+
+// newCachedSession returns the cached form of a session read at loadedAt
+func newCachedSession(session *models.Session, loadedAt time.Time) *cachedSession {
+	return &cachedSession{
+		Session:      *session,
+		RefreshToken: session.RefreshToken,
+		LoadedAt:     loadedAt,
+	}
+}
+
+func userKey(id string) string {
+	return fmt.Sprintf("repo:%s:user:%s", keyVersion, id)
+}
+
+func userEmailKey(email string) string {
+	return fmt.Sprintf("repo:%s:user_email:%s", keyVersion, normalizeEmail(email))
+}
+
+// normalizeEmail returns the form of an email address used in keys
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func sessionKey(id string) string {
+	return fmt.Sprintf("repo:%s:session:%s", keyVersion, id)
+}
+
+func sessionTokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("repo:%s:session_token:%s", keyVersion, hex.EncodeToString(hash[:]))
+}
+
+func sessionsRevokedKey(userID string) string {
+	return fmt.Sprintf("repo:%s:sessions_revoked:%s", keyVersion, userID)
+}
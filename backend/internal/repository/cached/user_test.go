@@ -0,0 +1,278 @@
+package cached
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/repository"
+	"github.com/nanayaw/fullstack/internal/service/cache"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// fakeRepository is an in-memory UserRepository counting its queries. Only
+// the operations that are cached are implemented.
+type fakeRepository struct {
+	repository.UserRepository
+
+	mu       sync.Mutex
+	users    map[string]models.User
+	sessions map[string]models.Session
+	queries  atomic.Int32
+	// Held by queries, so tests can make them wait
+	block sync.RWMutex
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		users:    make(map[string]models.User),
+		sessions: make(map[string]models.Session),
+	}
+}
+
+func (f *fakeRepository) query() func() {
+	f.queries.Add(1)
+	f.block.RLock()
+	f.mu.Lock()
+	return func() {
+		f.mu.Unlock()
+		f.block.RUnlock()
+	}
+}
+
+func (f *fakeRepository) CreateUser(ctx context.Context, user *models.User) error {
+	defer f.query()()
+	f.users[user.ID] = *user
+	return nil
+}
+
+func (f *fakeRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	defer f.query()()
+	user, ok := f.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (f *fakeRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	defer f.query()()
+	for _, user := range f.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	defer f.query()()
+	f.users[user.ID] = *user
+	return nil
+}
+
+func (f *fakeRepository) DeleteUser(ctx context.Context, id string) error {
+	defer f.query()()
+	delete(f.users, id)
+	return nil
+}
+
+func (f *fakeRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	defer f.query()()
+	f.sessions[session.ID] = *session
+	return nil
+}
+
+func (f *fakeRepository) GetSessionByID(ctx context.Context, id string) (*models.Session, error) {
+	defer f.query()()
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (f *fakeRepository) GetSessionByToken(ctx context.Context, token string) (*models.Session, error) {
+	defer f.query()()
+	for _, session := range f.sessions {
+		if session.RefreshToken == token {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) BlockSession(ctx context.Context, id string) error {
+	defer f.query()()
+	session := f.sessions[id]
+	session.IsBlocked = true
+	f.sessions[id] = session
+	return nil
+}
+
+func (f *fakeRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	defer f.query()()
+	for id, session := range f.sessions {
+		if session.UserID == userID {
+			delete(f.sessions, id)
+		}
+	}
+	return nil
+}
+
+func newTestRepository(t *testing.T) (*UserRepository, *fakeRepository) {
+	memory := cache.NewMemoryService()
+	t.Cleanup(func() { memory.Close() })
+
+	db := newFakeRepository()
+	return NewUserRepository(db, memory, logger.DefaultLogger()), db
+}
+
+func TestUserRepositoryUsers(t *testing.T) {
+	ctx := context.Background()
+	r, db := newTestRepository(t)
+
+	user := &models.User{ID: "user-1", Email: "user@example.com", PasswordHash: "hash", FullName: "Jane Doe"}
+	require.NoError(t, r.CreateUser(ctx, user))
+
+	// The first lookup reads the database, the next ones the cache
+	for i := 0; i < 3; i++ {
+		got, err := r.GetUserByID(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, user, got)
+	}
+	assert.Equal(t, int32(2), db.queries.Load())
+
+	// Email lookups reuse the cached user
+	got, err := r.GetUserByEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", got.PasswordHash)
+	got, err = r.GetUserByEmail(ctx, "USER@example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", got.ID)
+	assert.Equal(t, int32(3), db.queries.Load())
+
+	// Password changes are seen right away
+	user.PasswordHash = "new-hash"
+	require.NoError(t, r.UpdateUser(ctx, user))
+	got, err = r.GetUserByID(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", got.PasswordHash)
+
+	// So are email changes, even through the previous address
+	user.Email = "new@example.com"
+	require.NoError(t, r.UpdateUser(ctx, user))
+	got, err = r.GetUserByEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	got, err = r.GetUserByEmail(ctx, "new@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", got.ID)
+
+	// And deletions
+	require.NoError(t, r.DeleteUser(ctx, "user-1"))
+	got, err = r.GetUserByID(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	stats := r.Stats()
+	assert.Equal(t, int64(4), stats.Hits)
+	assert.Zero(t, stats.Errors)
+	assert.Greater(t, stats.Misses, int64(0))
+}
+
+func TestUserRepositoryNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	r, db := newTestRepository(t)
+
+	for i := 0; i < 3; i++ {
+		got, err := r.GetUserByID(ctx, "missing")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	}
+	assert.Equal(t, int32(1), db.queries.Load())
+	assert.Equal(t, int64(2), r.Stats().NegativeHits)
+
+	// Creating the user forgets it was missing
+	require.NoError(t, r.CreateUser(ctx, &models.User{ID: "missing", Email: "missing@example.com"}))
+	got, err := r.GetUserByID(ctx, "missing")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "missing@example.com", got.Email)
+}
+
+func TestUserRepositorySingleflight(t *testing.T) {
+	ctx := context.Background()
+	r, db := newTestRepository(t)
+	db.users["user-1"] = models.User{ID: "user-1", Email: "user@example.com"}
+
+	// Hold the database while the lookups pile up
+	db.block.Lock()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := r.GetUserByID(ctx, "user-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", got.ID)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	db.block.Unlock()
+	wg.Wait()
+
+	assert.Equal(t, int32(1), db.queries.Load())
+}
+
+func TestUserRepositorySessions(t *testing.T) {
+	ctx := context.Background()
+	r, db := newTestRepository(t)
+
+	for _, session := range []*models.Session{
+		{ID: "session-1", UserID: "user-1", RefreshToken: "token-1"},
+		{ID: "session-2", UserID: "user-1", RefreshToken: "token-2"},
+		{ID: "session-3", UserID: "user-2", RefreshToken: "token-3"},
+	} {
+		require.NoError(t, r.CreateSession(ctx, session))
+	}
+
+	got, err := r.GetSessionByToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.Equal(t, "session-1", got.ID)
+	got, err = r.GetSessionByToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", got.RefreshToken)
+	for _, id := range []string{"session-2", "session-3"} {
+		_, err := r.GetSessionByID(ctx, id)
+		require.NoError(t, err)
+	}
+	queries := db.queries.Load()
+
+	// Blocking a session is seen through its token
+	require.NoError(t, r.BlockSession(ctx, "session-1"))
+	got, err = r.GetSessionByToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.True(t, got.IsBlocked)
+
+	// Deleting the sessions of a user invalidates all of them, and only them
+	require.NoError(t, r.DeleteUserSessions(ctx, "user-1"))
+	got, err = r.GetSessionByID(ctx, "session-2")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	got, err = r.GetSessionByToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	queries = db.queries.Load()
+	got, err = r.GetSessionByID(ctx, "session-3")
+	require.NoError(t, err)
+	assert.Equal(t, "session-3", got.ID)
+	assert.Equal(t, queries, db.queries.Load())
+}
@@ -0,0 +1,153 @@
+package dataexport
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// exportColumns lists the columns selected for an export, leaving out its
+// archive
+const exportColumns = `id, user_id, status, size_bytes, error, expires_at, created_at, completed_at`
+
+// Repository implements the dataexport.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new data export repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateExport adds a pending export for a user, or returns nil if the user
+// already has one pending
+func (r *Repository) CreateExport(ctx context.Context, userID string) (*model.DataExport, error) {
+	query := `
+		INSERT INTO data_exports (user_id, status)
+		SELECT $1, $2
+		WHERE NOT EXISTS (SELECT 1 FROM data_exports WHERE user_id = $1 AND status = $2)
+		RETURNING ` + exportColumns
+
+	var export model.DataExport
+	if err := r.db.GetContext(ctx, &export, query, userID, model.DataExportPending); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return &export, nil
+}
+
+// GetExport gets an export by ID, or nil if there is none
+func (r *Repository) GetExport(ctx context.Context, id string) (*model.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE id = $1`
+
+	var export model.DataExport
+	if err := r.db.GetContext(ctx, &export, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	return &export, nil
+}
+
+// GetArchive gets the archive of a ready export, or nil if there is none
+func (r *Repository) GetArchive(ctx context.Context, id string) ([]byte, error) {
+	query := `SELECT archive FROM data_exports WHERE id = $1 AND status = $2`
+
+	var archive []byte
+	if err := r.db.GetContext(ctx, &archive, query, id, model.DataExportReady); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data export archive: %w", err)
+	}
+
+	return archive, nil
+}
+
+// ListPendingExports gets up to limit pending exports, oldest first
+func (r *Repository) ListPendingExports(ctx context.Context, limit int) ([]*model.DataExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM data_exports
+		WHERE status = $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	exports := []*model.DataExport{}
+	if err := r.db.SelectContext(ctx, &exports, query, model.DataExportPending, limit); err != nil {
+		return nil, fmt.Errorf("failed to list pending data exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// MarkExportReady stores the archive of a pending export, which can be
+// downloaded until expiresAt
+func (r *Repository) MarkExportReady(ctx context.Context, id string, archive []byte, expiresAt, now time.Time) error {
+	query := `
+		UPDATE data_exports
+		SET status = $1, archive = $2, size_bytes = $3, expires_at = $4, completed_at = $5
+		WHERE id = $6 AND status = $7
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		model.DataExportReady, archive, len(archive), expiresAt, now, id, model.DataExportPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export as ready: %w", err)
+	}
+
+	return nil
+}
+
+// MarkExportFailed records why the archive of a pending export couldn't be
+// built
+func (r *Repository) MarkExportFailed(ctx context.Context, id, message string, now time.Time) error {
+	query := `
+		UPDATE data_exports
+		SET status = $1, error = $2, completed_at = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.DataExportFailed, message, now, id, model.DataExportPending)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export as failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredExports deletes the exports that expired before the given
+// time, and the failed exports completed before it, returning how many there
+// were
+func (r *Repository) DeleteExpiredExports(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM data_exports
+		WHERE expires_at < $1 OR (status = $2 AND completed_at < $1)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before, model.DataExportFailed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,538 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// outboxColumns lists the columns selected for an outbox email
+const outboxColumns = `
+	id, idempotency_key, template, from_address, to_addresses, subject, html_body, text_body, headers,
+	status, attempts, last_error, next_attempt_at, locked_until, sent_at, created_at, updated_at
+`
+
+// Repository implements the email.OutboxRepository,
+// email.SuppressionRepository and email.ScheduledEmailRepository interfaces
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new email outbox repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// EnqueueEmail adds an email to the outbox. An email with the same idempotency
+// key as one already in the outbox is ignored.
+func (r *Repository) EnqueueEmail(ctx context.Context, email *model.OutboxEmail) error {
+	if email.ID == "" {
+		email.ID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO email_outbox (
+			id, idempotency_key, template, from_address, to_addresses, subject, html_body, text_body, headers,
+			status, attempts, next_attempt_at, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+		)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		email.ID,
+		email.IdempotencyKey,
+		email.Template,
+		email.FromAddress,
+		email.ToAddresses,
+		email.Subject,
+		email.HTMLBody,
+		email.TextBody,
+		email.Headers,
+		email.Status,
+		email.Attempts,
+		email.NextAttemptAt,
+		email.CreatedAt,
+		email.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to enqueue email: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueEmails claims up to limit emails that are due for delivery until
+// lockedUntil, counting the claim as a delivery attempt. Emails whose previous
+// claim expired without a result (e.g. the worker crashed) are claimed again.
+// Emails claimed concurrently by another worker are skipped.
+func (r *Repository) ClaimDueEmails(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.OutboxEmail, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM email_outbox
+		WHERE (status = $1 AND next_attempt_at <= $2)
+			OR (status = $3 AND locked_until <= $2)
+		ORDER BY next_attempt_at ASC
+		LIMIT $4
+	`
+
+	var candidates []*model.OutboxEmail
+	err := r.db.SelectContext(ctx, &candidates, query, model.OutboxStatusPending, now, model.OutboxStatusSending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due emails: %w", err)
+	}
+
+	// Every claim increments attempts, so it doubles as a version number that
+	// stops two workers from claiming the same email
+	claim := `
+		UPDATE email_outbox
+		SET status = $1, attempts = attempts + 1, locked_until = $2, updated_at = $3
+		WHERE id = $4 AND status = $5 AND attempts = $6
+	`
+
+	claimed := make([]*model.OutboxEmail, 0, len(candidates))
+	for _, email := range candidates {
+		result, err := r.db.ExecContext(ctx, claim, model.OutboxStatusSending, lockedUntil, now, email.ID, email.Status, email.Attempts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim email: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			continue
+		}
+
+		email.Status = model.OutboxStatusSending
+		email.Attempts++
+		email.LockedUntil = &lockedUntil
+		email.UpdatedAt = now
+		claimed = append(claimed, email)
+	}
+
+	return claimed, nil
+}
+
+// MarkEmailSent records that a claimed email was accepted by the provider
+func (r *Repository) MarkEmailSent(ctx context.Context, id string, sentAt time.Time) error {
+	query := `
+		UPDATE email_outbox
+		SET status = $1, sent_at = $2, locked_until = NULL, last_error = NULL, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.OutboxStatusSent, sentAt, id, model.OutboxStatusSending)
+	if err != nil {
+		return fmt.Errorf("failed to mark email as sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkEmailFailed records a failed delivery attempt and schedules the next one
+func (r *Repository) MarkEmailFailed(ctx context.Context, id, lastError string, nextAttemptAt, now time.Time) error {
+	query := `
+		UPDATE email_outbox
+		SET status = $1, last_error = $2, next_attempt_at = $3, locked_until = NULL, updated_at = $4
+		WHERE id = $5 AND status = $6
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.OutboxStatusPending, lastError, nextAttemptAt, now, id, model.OutboxStatusSending)
+	if err != nil {
+		return fmt.Errorf("failed to mark email as failed: %w", err)
+	}
+
+	return nil
+}
+
+// MarkEmailDead dead-letters a claimed email after its last failed attempt
+func (r *Repository) MarkEmailDead(ctx context.Context, id, lastError string, now time.Time) error {
+	query := `
+		UPDATE email_outbox
+		SET status = $1, last_error = $2, locked_until = NULL, updated_at = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.OutboxStatusDead, lastError, now, id, model.OutboxStatusSending)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter email: %w", err)
+	}
+
+	return nil
+}
+
+// RetryEmail moves a dead-lettered email back to the queue with a fresh set of
+// attempts. It reports whether the email was dead-lettered.
+func (r *Repository) RetryEmail(ctx context.Context, id string, now time.Time) (bool, error) {
+	query := `
+		UPDATE email_outbox
+		SET status = $1, attempts = 0, next_attempt_at = $2, locked_until = NULL, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, model.OutboxStatusPending, now, id, model.OutboxStatusDead)
+	if err != nil {
+		return false, fmt.Errorf("failed to retry email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// GetEmail gets a single outbox email. It returns nil if the email does not exist.
+func (r *Repository) GetEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM email_outbox
+		WHERE id = $1
+	`
+
+	var email model.OutboxEmail
+	err := r.db.GetContext(ctx, &email, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get email: %w", err)
+	}
+
+	return &email, nil
+}
+
+// ListEmails gets a filtered page of outbox emails, newest first
+func (r *Repository) ListEmails(ctx context.Context, filter model.OutboxFilter) ([]*model.OutboxEmail, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if filter.Recipient != "" {
+		args = append(args, "%"+filter.Recipient+"%")
+		conditions = append(conditions, fmt.Sprintf("to_addresses LIKE $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM email_outbox
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, outboxColumns, where, len(args)-1, len(args))
+
+	var emails []*model.OutboxEmail
+	err := r.db.SelectContext(ctx, &emails, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list emails: %w", err)
+	}
+
+	return emails, nil
+}
+
+// CountEmailsByStatus counts the outbox emails in each status
+func (r *Repository) CountEmailsByStatus(ctx context.Context) (map[string]int, error) {
+	query := `
+		SELECT status, COUNT(*) AS count
+		FROM email_outbox
+		GROUP BY status
+	`
+
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count emails: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+// suppressionColumns lists the columns selected for an email suppression
+const suppressionColumns = `id, email, reason, details, event_id, created_at`
+
+// AddSuppression adds an address to the suppression list. An address that is
+// already suppressed keeps its original reason.
+func (r *Repository) AddSuppression(ctx context.Context, suppression *model.EmailSuppression) error {
+	if suppression.ID == "" {
+		suppression.ID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO email_suppressions (id, email, reason, details, event_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (email) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		suppression.ID,
+		suppression.Email,
+		suppression.Reason,
+		suppression.Details,
+		suppression.EventID,
+		suppression.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to add email suppression: %w", err)
+	}
+
+	return nil
+}
+
+// GetSuppression gets the suppression of an address. It returns nil if the
+// address is not suppressed.
+func (r *Repository) GetSuppression(ctx context.Context, email string) (*model.EmailSuppression, error) {
+	query := `
+		SELECT ` + suppressionColumns + `
+		FROM email_suppressions
+		WHERE email = $1
+	`
+
+	var suppression model.EmailSuppression
+	err := r.db.GetContext(ctx, &suppression, query, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get email suppression: %w", err)
+	}
+
+	return &suppression, nil
+}
+
+// ListSuppressions gets a page of suppressed addresses, newest first
+func (r *Repository) ListSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, error) {
+	query := `
+		SELECT ` + suppressionColumns + `
+		FROM email_suppressions
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	var suppressions []*model.EmailSuppression
+	err := r.db.SelectContext(ctx, &suppressions, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email suppressions: %w", err)
+	}
+
+	return suppressions, nil
+}
+
+// DeleteSuppression removes an address from the suppression list. It reports
+// whether the address was suppressed.
+func (r *Repository) DeleteSuppression(ctx context.Context, email string) (bool, error) {
+	query := `DELETE FROM email_suppressions WHERE email = $1`
+
+	result, err := r.db.ExecContext(ctx, query, email)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete email suppression: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// scheduledEmailColumns lists the columns selected for a scheduled email
+const scheduledEmailColumns = `
+	id, user_id, kind, recipient, locale, send_at, status, last_error, locked_until, sent_at, created_at, updated_at
+`
+
+// CreateScheduledEmail adds a scheduled email
+func (r *Repository) CreateScheduledEmail(ctx context.Context, email *model.ScheduledEmail) error {
+	if email.ID == "" {
+		email.ID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO scheduled_emails (
+			id, user_id, kind, recipient, locale, send_at, status, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		email.ID,
+		email.UserID,
+		email.Kind,
+		email.Recipient,
+		email.Locale,
+		email.SendAt,
+		email.Status,
+		email.CreatedAt,
+		email.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled email: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueScheduledEmails claims up to limit scheduled emails that were due at
+// or before dueBefore until lockedUntil. Emails whose previous claim expired
+// without a result are claimed again, and emails claimed concurrently by
+// another worker are skipped.
+func (r *Repository) ClaimDueScheduledEmails(ctx context.Context, dueBefore, now, lockedUntil time.Time, limit int) ([]*model.ScheduledEmail, error) {
+	query := `
+		SELECT ` + scheduledEmailColumns + `
+		FROM scheduled_emails
+		WHERE (status = $1 AND send_at <= $2)
+			OR (status = $3 AND locked_until <= $4)
+		ORDER BY send_at ASC
+		LIMIT $5
+	`
+
+	var candidates []*model.ScheduledEmail
+	err := r.db.SelectContext(ctx, &candidates, query, model.ScheduledEmailStatusScheduled, dueBefore, model.ScheduledEmailStatusSending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due scheduled emails: %w", err)
+	}
+
+	claimed := make([]*model.ScheduledEmail, 0, len(candidates))
+	for _, candidate := range candidates {
+		email, err := r.ClaimScheduledEmail(ctx, candidate.ID, now, lockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		if email != nil {
+			claimed = append(claimed, email)
+		}
+	}
+
+	return claimed, nil
+}
+
+// ClaimScheduledEmail claims a single scheduled email until lockedUntil if it
+// is due at now. It returns nil if the email does not exist, is not due yet,
+// was cancelled or already sent, or is claimed by another worker.
+func (r *Repository) ClaimScheduledEmail(ctx context.Context, id string, now, lockedUntil time.Time) (*model.ScheduledEmail, error) {
+	// The conditions are checked again by the update itself, so only one of
+	// several concurrent claims succeeds
+	query := `
+		UPDATE scheduled_emails
+		SET status = $1, locked_until = $2, updated_at = $3
+		WHERE id = $4
+			AND ((status = $5 AND send_at <= $3) OR (status = $1 AND locked_until <= $3))
+		RETURNING ` + scheduledEmailColumns
+
+	var email model.ScheduledEmail
+	err := r.db.GetContext(ctx, &email, query, model.ScheduledEmailStatusSending, lockedUntil, now, id, model.ScheduledEmailStatusScheduled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim scheduled email: %w", err)
+	}
+
+	return &email, nil
+}
+
+// MarkScheduledEmailSent records that a claimed scheduled email was sent
+func (r *Repository) MarkScheduledEmailSent(ctx context.Context, id string, sentAt time.Time) error {
+	query := `
+		UPDATE scheduled_emails
+		SET status = $1, sent_at = $2, locked_until = NULL, last_error = NULL, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.ScheduledEmailStatusSent, sentAt, id, model.ScheduledEmailStatusSending)
+	if err != nil {
+		return fmt.Errorf("failed to mark scheduled email as sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkScheduledEmailFailed records that a claimed scheduled email could not be sent
+func (r *Repository) MarkScheduledEmailFailed(ctx context.Context, id, lastError string, now time.Time) error {
+	query := `
+		UPDATE scheduled_emails
+		SET status = $1, last_error = $2, locked_until = NULL, updated_at = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.ScheduledEmailStatusFailed, lastError, now, id, model.ScheduledEmailStatusSending)
+	if err != nil {
+		return fmt.Errorf("failed to mark scheduled email as failed: %w", err)
+	}
+
+	return nil
+}
+
+// CancelScheduledEmails cancels a user's scheduled emails of the given kinds,
+// or of every kind if none are given, and returns the number cancelled. Emails
+// that are already being sent are not cancelled.
+func (r *Repository) CancelScheduledEmails(ctx context.Context, userID string, kinds []string, now time.Time) (int, error) {
+	args := []interface{}{model.ScheduledEmailStatusCancelled, now, userID, model.ScheduledEmailStatusScheduled}
+	conditions := []string{"user_id = $3", "status = $4"}
+
+	if len(kinds) > 0 {
+		placeholders := make([]string, len(kinds))
+		for i, kind := range kinds {
+			args = append(args, kind)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf("kind IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE scheduled_emails
+		SET status = $1, updated_at = $2
+		WHERE %s
+	`, strings.Join(conditions, " AND "))
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel scheduled emails: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,104 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// Repository implements the notification.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new notification preferences repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// ListPreferences gets the notification preferences a user changed
+func (r *Repository) ListPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error) {
+	query := `
+		SELECT user_id, category, channel, enabled, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	var prefs []*model.NotificationPreference
+	err := r.db.SelectContext(ctx, &prefs, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// SetPreference creates or updates a notification preference
+func (r *Repository) SetPreference(ctx context.Context, pref *model.NotificationPreference) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, category, channel, enabled, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, category, channel) DO UPDATE
+		SET enabled = excluded.enabled, updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, pref.UserID, pref.Category, pref.Channel, pref.Enabled, pref.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set notification preference: %w", err)
+	}
+
+	return nil
+}
+
+// GetPreferenceByEmail gets the ID of the user with an email address, and
+// whether they receive notifications of a category on a channel. The user ID
+// is empty if no user has the address.
+func (r *Repository) GetPreferenceByEmail(ctx context.Context, email, category, channel string) (string, bool, error) {
+	query := `
+		SELECT u.id AS user_id, COALESCE(p.enabled, TRUE) AS enabled
+		FROM users u
+		LEFT JOIN notification_preferences p
+			ON p.user_id = u.id AND p.category = $2 AND p.channel = $3
+		WHERE u.email = $1
+	`
+
+	var row struct {
+		UserID  string `db:"user_id"`
+		Enabled bool   `db:"enabled"`
+	}
+	err := r.db.GetContext(ctx, &row, query, email, category, channel)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", true, nil
+		}
+		return "", false, fmt.Errorf("failed to get notification preference: %w", err)
+	}
+
+	return row.UserID, row.Enabled, nil
+}
+
+// GetUserEmail gets a user's email address. It returns an empty string if the
+// user does not exist.
+func (r *Repository) GetUserEmail(ctx context.Context, userID string) (string, error) {
+	query := `
+		SELECT email
+		FROM users
+		WHERE id = $1
+	`
+
+	var email string
+	err := r.db.GetContext(ctx, &email, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get user email: %w", err)
+	}
+
+	return email, nil
+}
@@ -0,0 +1,308 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// invitationColumns are the columns of an Invitation
+const invitationColumns = `id, organization_id, email, role, token_hash, invited_by, expires_at, accepted_at, created_at`
+
+// Repository implements the organization.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new organization repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateOrganization creates an organization owned by a user, filling in its
+// ID and creation time
+func (r *Repository) CreateOrganization(ctx context.Context, org *model.Organization, ownerID string) error {
+	// The owner's membership is created in the same statement, so there are no
+	// organizations without an owner
+	query := `
+		WITH org AS (
+			INSERT INTO organizations (name) VALUES ($1)
+			RETURNING id, name, created_at, updated_at
+		), owner AS (
+			INSERT INTO memberships (organization_id, user_id, role)
+			SELECT id, $2, $3 FROM org
+		)
+		SELECT id, name, created_at, updated_at FROM org
+	`
+
+	if err := r.db.GetContext(ctx, org, query, org.Name, ownerID, model.OrgRoleOwner); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrganization gets an organization by ID, or nil if there is none
+func (r *Repository) GetOrganization(ctx context.Context, id string) (*model.Organization, error) {
+	query := `SELECT id, name, created_at, updated_at FROM organizations WHERE id = $1`
+
+	var org model.Organization
+	if err := r.db.GetContext(ctx, &org, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// RenameOrganization renames an organization and reports whether it exists
+func (r *Repository) RenameOrganization(ctx context.Context, id, name string) (bool, error) {
+	query := `UPDATE organizations SET name = $1, updated_at = NOW() WHERE id = $2`
+
+	return r.execUpdate(ctx, "failed to rename organization", query, name, id)
+}
+
+// DeleteOrganization deletes an organization with its memberships and
+// invitations, and reports whether it existed
+func (r *Repository) DeleteOrganization(ctx context.Context, id string) (bool, error) {
+	query := `DELETE FROM organizations WHERE id = $1`
+
+	return r.execUpdate(ctx, "failed to delete organization", query, id)
+}
+
+// ListUserOrganizations gets the organizations a user is a member of, by name
+func (r *Repository) ListUserOrganizations(ctx context.Context, userID string) ([]*model.UserOrganization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_at, o.updated_at, m.role
+		FROM memberships m
+		JOIN organizations o ON o.id = m.organization_id
+		WHERE m.user_id = $1
+		ORDER BY o.name, o.id
+	`
+
+	orgs := []*model.UserOrganization{}
+	if err := r.db.SelectContext(ctx, &orgs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list user organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// GetMembership gets the membership of a user in an organization, or nil if
+// they aren't a member
+func (r *Repository) GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	query := `
+		SELECT organization_id, user_id, role, created_at
+		FROM memberships
+		WHERE organization_id = $1 AND user_id = $2
+	`
+
+	var membership model.Membership
+	if err := r.db.GetContext(ctx, &membership, query, orgID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+
+	return &membership, nil
+}
+
+// ListMembers gets the members of an organization, oldest first
+func (r *Repository) ListMembers(ctx context.Context, orgID string) ([]*model.Member, error) {
+	query := `
+		SELECT m.user_id, u.email, u.full_name, m.role, m.created_at
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at, m.user_id
+	`
+
+	members := []*model.Member{}
+	if err := r.db.SelectContext(ctx, &members, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	return members, nil
+}
+
+// IsMember reports whether the user with an email address is a member of an
+// organization
+func (r *Repository) IsMember(ctx context.Context, orgID, email string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM memberships m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.organization_id = $1 AND LOWER(u.email) = LOWER($2)
+		)
+	`
+
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, orgID, email); err != nil {
+		return false, fmt.Errorf("failed to check membership: %w", err)
+	}
+
+	return exists, nil
+}
+
+// CountOwners counts the owners of an organization
+func (r *Repository) CountOwners(ctx context.Context, orgID string) (int, error) {
+	query := `SELECT COUNT(*) FROM memberships WHERE organization_id = $1 AND role = $2`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, orgID, model.OrgRoleOwner); err != nil {
+		return 0, fmt.Errorf("failed to count owners: %w", err)
+	}
+
+	return count, nil
+}
+
+// SetMemberRole changes the role of a member and reports whether they are one
+func (r *Repository) SetMemberRole(ctx context.Context, orgID, userID, role string) (bool, error) {
+	query := `UPDATE memberships SET role = $1 WHERE organization_id = $2 AND user_id = $3`
+
+	return r.execUpdate(ctx, "failed to change member role", query, role, orgID, userID)
+}
+
+// DeleteMembership removes a user from an organization and reports whether
+// they were a member
+func (r *Repository) DeleteMembership(ctx context.Context, orgID, userID string) (bool, error) {
+	query := `DELETE FROM memberships WHERE organization_id = $1 AND user_id = $2`
+
+	return r.execUpdate(ctx, "failed to delete membership", query, orgID, userID)
+}
+
+// CreateInvitation stores an invitation, filling in its ID and creation time.
+// It replaces the pending invitation of the same address to the organization,
+// if there is one, so inviting someone again sends them a new link.
+func (r *Repository) CreateInvitation(ctx context.Context, invitation *model.Invitation) error {
+	query := `
+		INSERT INTO organization_invitations (organization_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (organization_id, email) WHERE accepted_at IS NULL
+		DO UPDATE SET
+			role = EXCLUDED.role,
+			token_hash = EXCLUDED.token_hash,
+			invited_by = EXCLUDED.invited_by,
+			expires_at = EXCLUDED.expires_at,
+			created_at = NOW()
+		RETURNING id, created_at
+	`
+
+	row := r.db.QueryRowxContext(ctx, query,
+		invitation.OrganizationID, invitation.Email, invitation.Role, invitation.TokenHash, invitation.InvitedBy, invitation.ExpiresAt,
+	)
+	if err := row.Scan(&invitation.ID, &invitation.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+// ListInvitations gets the pending invitations of an organization, newest first
+func (r *Repository) ListInvitations(ctx context.Context, orgID string) ([]*model.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE organization_id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC, id
+	`
+
+	invitations := []*model.Invitation{}
+	if err := r.db.SelectContext(ctx, &invitations, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// GetInvitationByToken gets the pending invitation with a token hash, or nil
+// if there is none or it expired
+func (r *Repository) GetInvitationByToken(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+	`
+
+	var invitation model.Invitation
+	if err := r.db.GetContext(ctx, &invitation, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// DeleteInvitation deletes a pending invitation of an organization and
+// reports whether there was one
+func (r *Repository) DeleteInvitation(ctx context.Context, orgID, id string) (bool, error) {
+	query := `
+		DELETE FROM organization_invitations
+		WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL
+	`
+
+	return r.execUpdate(ctx, "failed to delete invitation", query, id, orgID)
+}
+
+// AcceptInvitation adds a user to the organization of a pending invitation
+// with its role, and reports whether the invitation was still pending. Users
+// who are already members keep their role.
+func (r *Repository) AcceptInvitation(ctx context.Context, id, userID string) (bool, error) {
+	query := `
+		WITH accepted AS (
+			UPDATE organization_invitations SET accepted_at = NOW()
+			WHERE id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+			RETURNING organization_id, role
+		), joined AS (
+			INSERT INTO memberships (organization_id, user_id, role)
+			SELECT organization_id, $2, role FROM accepted
+			ON CONFLICT (organization_id, user_id) DO NOTHING
+		)
+		SELECT COUNT(*) FROM accepted
+	`
+
+	var accepted int
+	if err := r.db.GetContext(ctx, &accepted, query, id, userID); err != nil {
+		return false, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	return accepted > 0, nil
+}
+
+// DeleteExpiredInvitations deletes invitations that expired without being
+// accepted, and returns how many there were
+func (r *Repository) DeleteExpiredInvitations(ctx context.Context) (int64, error) {
+	query := `DELETE FROM organization_invitations WHERE accepted_at IS NULL AND expires_at <= NOW()`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired invitations: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// execUpdate runs an update or delete and reports whether it changed any row
+func (r *Repository) execUpdate(ctx context.Context, message, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	return changed > 0, nil
+}
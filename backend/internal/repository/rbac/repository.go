@@ -0,0 +1,202 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// Repository implements the rbac.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new roles and permissions repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// ListRoles gets every role with its permissions
+func (r *Repository) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	query := `SELECT id, name, description, created_at FROM roles ORDER BY name`
+
+	var roles []*model.Role
+	if err := r.db.SelectContext(ctx, &roles, query); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	query = `
+		SELECT rp.role_id, p.name
+		FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		ORDER BY p.name
+	`
+
+	var grants []struct {
+		RoleID     string `db:"role_id"`
+		Permission string `db:"name"`
+	}
+	if err := r.db.SelectContext(ctx, &grants, query); err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+
+	byID := make(map[string]*model.Role, len(roles))
+	for _, role := range roles {
+		role.Permissions = []string{}
+		byID[role.ID] = role
+	}
+	for _, grant := range grants {
+		if role, ok := byID[grant.RoleID]; ok {
+			role.Permissions = append(role.Permissions, grant.Permission)
+		}
+	}
+
+	return roles, nil
+}
+
+// GetRole gets a role by name, without its permissions, or nil if there is none
+func (r *Repository) GetRole(ctx context.Context, name string) (*model.Role, error) {
+	query := `SELECT id, name, description, created_at FROM roles WHERE name = $1`
+
+	var role model.Role
+	if err := r.db.GetContext(ctx, &role, query, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	return &role, nil
+}
+
+// ListPermissions gets every permission
+func (r *Repository) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	query := `SELECT id, name, description FROM permissions ORDER BY name`
+
+	var permissions []*model.Permission
+	if err := r.db.SelectContext(ctx, &permissions, query); err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+// GetPermission gets a permission by name, or nil if there is none
+func (r *Repository) GetPermission(ctx context.Context, name string) (*model.Permission, error) {
+	query := `SELECT id, name, description FROM permissions WHERE name = $1`
+
+	var permission model.Permission
+	if err := r.db.GetContext(ctx, &permission, query, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get permission: %w", err)
+	}
+
+	return &permission, nil
+}
+
+// GetUserAuthorization gets the roles of a user and the permissions they grant
+func (r *Repository) GetUserAuthorization(ctx context.Context, userID string) (*model.Authorization, error) {
+	authz := &model.Authorization{Roles: []string{}, Permissions: []string{}}
+
+	query := `
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY r.name
+	`
+	if err := r.db.SelectContext(ctx, &authz.Roles, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	query = `
+		SELECT DISTINCT p.name
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE ur.user_id = $1
+		ORDER BY p.name
+	`
+	if err := r.db.SelectContext(ctx, &authz.Permissions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user permissions: %w", err)
+	}
+
+	return authz, nil
+}
+
+// AssignRole assigns an existing role to a user, doing nothing if they
+// already have it
+func (r *Repository) AssignRole(ctx context.Context, userID, role, assignedBy string) error {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, assigned_by)
+		SELECT $1, id, $3 FROM roles WHERE name = $2
+		ON CONFLICT (user_id, role_id) DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, role, assignedBy); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeRole removes a role from a user and reports whether they had it
+func (r *Repository) RevokeRole(ctx context.Context, userID, role string) (bool, error) {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)
+	`
+
+	return r.execDelete(ctx, "failed to revoke role", query, userID, role)
+}
+
+// GrantPermission grants an existing permission to an existing role, doing
+// nothing if the role already has it
+func (r *Repository) GrantPermission(ctx context.Context, role, permission string) error {
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT r.id, p.id FROM roles r, permissions p
+		WHERE r.name = $1 AND p.name = $2
+		ON CONFLICT (role_id, permission_id) DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, role, permission); err != nil {
+		return fmt.Errorf("failed to grant permission: %w", err)
+	}
+
+	return nil
+}
+
+// RevokePermission removes a permission from a role and reports whether the
+// role had it
+func (r *Repository) RevokePermission(ctx context.Context, role, permission string) (bool, error) {
+	query := `
+		DELETE FROM role_permissions
+		WHERE role_id = (SELECT id FROM roles WHERE name = $1)
+		AND permission_id = (SELECT id FROM permissions WHERE name = $2)
+	`
+
+	return r.execDelete(ctx, "failed to revoke permission", query, role, permission)
+}
+
+// execDelete runs a delete query and reports whether it deleted any row
+func (r *Repository) execDelete(ctx context.Context, message, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	return deleted > 0, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -75,6 +76,24 @@ func (r *Repository) GetRecentLoginAttempts(ctx context.Context, userID string,
 	return attempts, nil
 }
 
+// ListLoginAttempts gets every login attempt of a user, newest first
+func (r *Repository) ListLoginAttempts(ctx context.Context, userID string) ([]*model.LoginAttempt, error) {
+	query := `
+		SELECT id, user_id, ip_address, user_agent, location, successful, attempted_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY attempted_at DESC, id DESC
+	`
+
+	attempts := []*model.LoginAttempt{}
+	err := r.db.SelectContext(ctx, &attempts, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
 // LockAccount locks a user account
 func (r *Repository) LockAccount(ctx context.Context, userID string, until time.Time, reason string) error {
 	// First check if the account is already locked
@@ -88,7 +107,7 @@ func (r *Repository) LockAccount(ctx context.Context, userID string, until time.
 	if exists {
 		query := `
 			UPDATE account_locks
-			SET unlock_at = $1, reason = $2, locked_at = NOW()
+			SET unlock_at = $1, reason = $2, locked_at = NOW(), unlock_token_hash = NULL
 			WHERE user_id = $3
 		`
 
@@ -138,6 +157,57 @@ func (r *Repository) UnlockAccount(ctx context.Context, userID string) error {
 	return nil
 }
 
+// SetUnlockToken stores the hash of a token that can be used to unlock a user account early
+func (r *Repository) SetUnlockToken(ctx context.Context, userID, tokenHash string) error {
+	query := `UPDATE account_locks SET unlock_token_hash = $1 WHERE user_id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, tokenHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set unlock token: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockAccountByToken removes an active account lock matching the token hash
+// and returns the ID of the unlocked user, or an empty string if no lock matched
+func (r *Repository) UnlockAccountByToken(ctx context.Context, tokenHash string) (string, error) {
+	query := `
+		DELETE FROM account_locks
+		WHERE unlock_token_hash = $1 AND unlock_at > NOW()
+		RETURNING user_id
+	`
+
+	var userID string
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	return userID, nil
+}
+
+// DeleteExpiredAccountLocks deletes the account locks that ended before a time
+// and returns how many were deleted
+func (r *Repository) DeleteExpiredAccountLocks(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM account_locks WHERE unlock_at <= $1`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired account locks: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired account locks: %w", err)
+	}
+
+	return deleted, nil
+}
+
 // IsAccountLocked checks if a user account is locked
 func (r *Repository) IsAccountLocked(ctx context.Context, userID string) (bool, time.Time, string, error) {
 	query := `
@@ -214,3 +284,103 @@ func (r *Repository) GetUserSecurityEvents(ctx context.Context, userID string, l
 
 	return events, nil
 }
+
+// ListUserSecurityEvents gets a page of security events for a user using keyset pagination.
+// Events are ordered from newest to oldest, with the event ID as a tie-breaker.
+func (r *Repository) ListUserSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) ([]*model.SecurityEvent, error) {
+	var conditions []string
+	args := []interface{}{userID}
+	conditions = append(conditions, "user_id = $1")
+
+	if len(filter.EventTypes) > 0 {
+		placeholders := make([]string, len(filter.EventTypes))
+		for i, eventType := range filter.EventTypes {
+			args = append(args, eventType)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf("event_type IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		createdAt, id := len(args)-1, len(args)
+		conditions = append(conditions, fmt.Sprintf(
+			"(created_at < $%d OR (created_at = $%d AND id < $%d))", createdAt, createdAt, id,
+		))
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+		SELECT id, user_id, event_type, ip_address, user_agent, location, description, created_at
+		FROM security_events
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args))
+
+	var events []*model.SecurityEvent
+	err := r.db.SelectContext(ctx, &events, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user security events: %w", err)
+	}
+
+	return events, nil
+}
+
+// CountUserSecurityEventsByType counts a user's security events of each type
+// created at or after from and before to
+func (r *Repository) CountUserSecurityEventsByType(ctx context.Context, userID string, from, to time.Time) (map[string]int, error) {
+	query := `
+		SELECT event_type, COUNT(*) AS count
+		FROM security_events
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY event_type
+	`
+
+	var rows []struct {
+		EventType string `db:"event_type"`
+		Count     int    `db:"count"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count user security events: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.EventType] = row.Count
+	}
+
+	return counts, nil
+}
+
+// GetSecurityEvent gets a single security event belonging to a user.
+// It returns nil if the event does not exist or belongs to another user.
+func (r *Repository) GetSecurityEvent(ctx context.Context, userID, eventID string) (*model.SecurityEvent, error) {
+	query := `
+		SELECT id, user_id, event_type, ip_address, user_agent, location, description, created_at
+		FROM security_events
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var event model.SecurityEvent
+	err := r.db.GetContext(ctx, &event, query, eventID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get security event: %w", err)
+	}
+
+	return &event, nil
+}
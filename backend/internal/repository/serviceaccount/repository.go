@@ -0,0 +1,152 @@
+package serviceaccount
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// serviceAccountColumns are the columns of a serviceAccountRow
+const serviceAccountColumns = `id, name, organization_id, role, scopes, client_id, secret_hash, created_by, last_used_at, created_at, updated_at`
+
+// serviceAccountRow is a service account as stored, with its scopes
+// separated by spaces
+type serviceAccountRow struct {
+	model.ServiceAccount
+	Scopes string `db:"scopes"`
+}
+
+// toServiceAccount converts a stored service account to its model
+func (row *serviceAccountRow) toServiceAccount() *model.ServiceAccount {
+	account := row.ServiceAccount
+	account.Scopes = strings.Fields(row.Scopes)
+	return &account
+}
+
+// Repository implements the serviceaccount.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new service account repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateServiceAccount stores a service account, filling in its ID and
+// creation time
+func (r *Repository) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error {
+	query := `
+		INSERT INTO service_accounts (name, organization_id, role, scopes, client_id, secret_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+
+	row := r.db.QueryRowxContext(ctx, query,
+		account.Name, account.OrganizationID, account.Role, strings.Join(account.Scopes, " "),
+		account.ClientID, account.SecretHash, account.CreatedBy,
+	)
+	if err := row.Scan(&account.ID, &account.CreatedAt, &account.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	return nil
+}
+
+// GetServiceAccount gets a service account by ID, or nil if there is none
+func (r *Repository) GetServiceAccount(ctx context.Context, id string) (*model.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts WHERE id = $1`
+
+	return r.getServiceAccount(ctx, query, id)
+}
+
+// GetServiceAccountByClientID gets a service account by client ID, or nil if
+// there is none
+func (r *Repository) GetServiceAccountByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts WHERE client_id = $1`
+
+	return r.getServiceAccount(ctx, query, clientID)
+}
+
+// ListServiceAccounts gets the service accounts of an organization, or those
+// of the admins when orgID is empty, by name
+func (r *Repository) ListServiceAccounts(ctx context.Context, orgID string) ([]*model.ServiceAccount, error) {
+	query := `
+		SELECT ` + serviceAccountColumns + `
+		FROM service_accounts
+		WHERE organization_id IS NOT DISTINCT FROM NULLIF($1, '')::UUID
+		ORDER BY name, id
+	`
+
+	var rows []serviceAccountRow
+	if err := r.db.SelectContext(ctx, &rows, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+
+	accounts := make([]*model.ServiceAccount, len(rows))
+	for i := range rows {
+		accounts[i] = rows[i].toServiceAccount()
+	}
+
+	return accounts, nil
+}
+
+// SetServiceAccountSecret replaces the secret of a service account and
+// reports whether it exists
+func (r *Repository) SetServiceAccountSecret(ctx context.Context, id, secretHash string) (bool, error) {
+	query := `UPDATE service_accounts SET secret_hash = $1, updated_at = NOW() WHERE id = $2`
+
+	return r.execUpdate(ctx, "failed to set service account secret", query, secretHash, id)
+}
+
+// TouchServiceAccount records that a service account just signed in
+func (r *Repository) TouchServiceAccount(ctx context.Context, id string) error {
+	query := `UPDATE service_accounts SET last_used_at = NOW() WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update service account last use: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteServiceAccount deletes a service account and reports whether it existed
+func (r *Repository) DeleteServiceAccount(ctx context.Context, id string) (bool, error) {
+	query := `DELETE FROM service_accounts WHERE id = $1`
+
+	return r.execUpdate(ctx, "failed to delete service account", query, id)
+}
+
+// getServiceAccount gets the service account a query selects, or nil if there is none
+func (r *Repository) getServiceAccount(ctx context.Context, query string, args ...interface{}) (*model.ServiceAccount, error) {
+	var row serviceAccountRow
+	if err := r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get service account: %w", err)
+	}
+
+	return row.toServiceAccount(), nil
+}
+
+// execUpdate runs an update or delete and reports whether it changed any row
+func (r *Repository) execUpdate(ctx context.Context, message, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	return changed > 0, nil
+}
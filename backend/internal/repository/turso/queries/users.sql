@@ -4,8 +4,9 @@ INSERT INTO users (
     email,
     password_hash,
     full_name,
-    avatar_url
-) VALUES (?, ?, ?, ?, ?)
+    avatar_url,
+    locale
+) VALUES (?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetUserByID :one
@@ -23,6 +24,7 @@ SET
     full_name = COALESCE(?, full_name),
     avatar_url = COALESCE(?, avatar_url),
     email_verified = COALESCE(?, email_verified),
+    locale = COALESCE(?, locale),
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
 RETURNING *;
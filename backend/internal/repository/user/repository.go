@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/models"
+)
+
+// userColumns are the columns of a User, in the order they are scanned
+const userColumns = `id, email, full_name, COALESCE(avatar_url, ''), email_verified, locale,
+	disabled_at, created_at, updated_at`
+
+// Repository implements the user.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new user repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// GetUser gets a user by ID, or nil if there is none
+func (r *Repository) GetUser(ctx context.Context, id string) (*models.User, error) {
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE id = $1`, userColumns)
+
+	user, err := scanUser(r.db.QueryRowxContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser updates the fields of a user set in req, or returns nil if there
+// is no such user. Passwords are changed through the auth service, which
+// hashes them, so req.Password is ignored.
+func (r *Repository) UpdateUser(ctx context.Context, id string, req *models.UpdateUserRequest) (*models.User, error) {
+	query := fmt.Sprintf(`
+		UPDATE users
+		SET
+			email = COALESCE($2, email),
+			full_name = COALESCE($3, full_name),
+			avatar_url = COALESCE($4, avatar_url),
+			locale = COALESCE($5, locale),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING %s
+	`, userColumns)
+
+	user, err := scanUser(r.db.QueryRowxContext(ctx, query, id, req.Email, req.FullName, req.AvatarURL, req.Locale))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeleteUser deletes a user
+func (r *Repository) DeleteUser(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+// scanUser scans the userColumns of row into a User, or returns nil if there
+// was no row
+func scanUser(row *sqlx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Email, &user.FullName, &user.AvatarURL, &user.EmailVerified, &user.Locale,
+		&user.DisabledAt, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
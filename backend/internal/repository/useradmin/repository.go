@@ -0,0 +1,181 @@
+package useradmin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// userColumns are the columns of an AdminUser, selected from users u joined
+// with their active account lock l
+const userColumns = `u.id, u.email, u.full_name, u.avatar_url, u.email_verified, u.locale,
+	u.disabled_at, l.unlock_at AS locked_until, l.reason AS lock_reason, u.created_at, u.updated_at`
+
+// userTables joins users with their account lock, if it hasn't ended
+const userTables = `users u LEFT JOIN account_locks l ON l.user_id = u.id AND l.unlock_at > NOW()`
+
+// Repository implements the useradmin.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new user administration repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// SearchUsers gets a filtered page of users, newest first
+func (r *Repository) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.AdminUser, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Query != "" {
+		args = append(args, "%"+strings.ToLower(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(LOWER(u.email) LIKE $%d OR LOWER(u.full_name) LIKE $%d)", len(args), len(args)))
+	}
+
+	if filter.Verified != nil {
+		args = append(args, *filter.Verified)
+		conditions = append(conditions, fmt.Sprintf("u.email_verified = $%d", len(args)))
+	}
+
+	if filter.Locked != nil {
+		if *filter.Locked {
+			conditions = append(conditions, "l.user_id IS NOT NULL")
+		} else {
+			conditions = append(conditions, "l.user_id IS NULL")
+		}
+	}
+
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			conditions = append(conditions, "u.disabled_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "u.disabled_at IS NULL")
+		}
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		args = append(args, filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("u.created_at >= $%d", len(args)))
+	}
+
+	if !filter.CreatedTo.IsZero() {
+		args = append(args, filter.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("u.created_at < $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY u.created_at DESC, u.id DESC
+		LIMIT $%d OFFSET $%d
+	`, userColumns, userTables, where, len(args)-1, len(args))
+
+	var users []*model.AdminUser
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetUser gets a user by ID, or nil if there is none
+func (r *Repository) GetUser(ctx context.Context, id string) (*model.AdminUser, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE u.id = $1`, userColumns, userTables)
+
+	var user model.AdminUser
+	if err := r.db.GetContext(ctx, &user, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// ListUserSessions gets the sessions of a user that are neither blocked nor
+// expired, newest first
+func (r *Repository) ListUserSessions(ctx context.Context, userID string) ([]*model.UserSession, error) {
+	query := `
+		SELECT id, user_agent, client_ip, expires_at, created_at
+		FROM sessions
+		WHERE user_id = $1 AND is_blocked = FALSE AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	sessions := []*model.UserSession{}
+	if err := r.db.SelectContext(ctx, &sessions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list user sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// ListLinkedAccounts gets the OAuth accounts linked to a user
+func (r *Repository) ListLinkedAccounts(ctx context.Context, userID string) ([]*model.LinkedAccount, error) {
+	query := `
+		SELECT provider, provider_user_id, created_at
+		FROM oauth_accounts
+		WHERE user_id = $1
+		ORDER BY provider
+	`
+
+	accounts := []*model.LinkedAccount{}
+	if err := r.db.SelectContext(ctx, &accounts, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list linked accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// DeleteUserSessions deletes every session of a user and returns how many
+// there were
+func (r *Repository) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted session count: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// SetUserDisabled disables or enables a user and reports whether that changed
+// anything
+func (r *Repository) SetUserDisabled(ctx context.Context, userID string, disabled bool) (bool, error) {
+	query := `UPDATE users SET disabled_at = NOW(), updated_at = NOW() WHERE id = $1 AND disabled_at IS NULL`
+	if !disabled {
+		query = `UPDATE users SET disabled_at = NULL, updated_at = NOW() WHERE id = $1 AND disabled_at IS NOT NULL`
+	}
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get updated user count: %w", err)
+	}
+
+	return updated > 0, nil
+}
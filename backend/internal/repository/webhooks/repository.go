@@ -0,0 +1,370 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// endpointColumns are the columns of an endpointRow
+const endpointColumns = `id, url, secret, event_types, description, enabled, created_by, created_at, updated_at`
+
+// deliveryColumns lists the columns selected for a delivery
+const deliveryColumns = `
+	id, endpoint_id, event_id, event_type, payload, status, attempts, last_status_code, last_error,
+	next_attempt_at, locked_until, delivered_at, replay_of, created_at, updated_at
+`
+
+// attemptColumns lists the columns selected for a delivery attempt
+const attemptColumns = `id, delivery_id, attempt, status_code, response_body, error, duration_ms, created_at`
+
+// endpointRow is a webhook endpoint as stored, with its event types
+// separated by spaces
+type endpointRow struct {
+	model.WebhookEndpoint
+	EventTypes string `db:"event_types"`
+}
+
+// toEndpoint converts a stored endpoint to its model
+func (row *endpointRow) toEndpoint() *model.WebhookEndpoint {
+	endpoint := row.WebhookEndpoint
+	endpoint.EventTypes = strings.Fields(row.EventTypes)
+	return &endpoint
+}
+
+// Repository implements the webhooks.Repository interface
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new webhook repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateEndpoint stores a webhook endpoint, filling in its ID and creation
+// time
+func (r *Repository) CreateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (url, secret, event_types, description, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	row := r.db.QueryRowxContext(ctx, query,
+		endpoint.URL, endpoint.Secret, strings.Join(endpoint.EventTypes, " "),
+		endpoint.Description, endpoint.Enabled, endpoint.CreatedBy,
+	)
+	if err := row.Scan(&endpoint.ID, &endpoint.CreatedAt, &endpoint.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	return nil
+}
+
+// GetEndpoint gets a webhook endpoint by ID, or nil if there is none
+func (r *Repository) GetEndpoint(ctx context.Context, id string) (*model.WebhookEndpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE id = $1`
+
+	var row endpointRow
+	if err := r.db.GetContext(ctx, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	return row.toEndpoint(), nil
+}
+
+// ListEndpoints gets every webhook endpoint, oldest first
+func (r *Repository) ListEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints ORDER BY created_at, id`
+
+	var rows []endpointRow
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	endpoints := make([]*model.WebhookEndpoint, len(rows))
+	for i := range rows {
+		endpoints[i] = rows[i].toEndpoint()
+	}
+
+	return endpoints, nil
+}
+
+// UpdateEndpoint saves the settings of a webhook endpoint and reports
+// whether it exists
+func (r *Repository) UpdateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) (bool, error) {
+	query := `
+		UPDATE webhook_endpoints
+		SET url = $1, secret = $2, event_types = $3, description = $4, enabled = $5, updated_at = $6
+		WHERE id = $7
+	`
+
+	return r.execUpdate(ctx, "failed to update webhook endpoint", query,
+		endpoint.URL, endpoint.Secret, strings.Join(endpoint.EventTypes, " "),
+		endpoint.Description, endpoint.Enabled, endpoint.UpdatedAt, endpoint.ID,
+	)
+}
+
+// DeleteEndpoint deletes a webhook endpoint with its deliveries and reports
+// whether it existed
+func (r *Repository) DeleteEndpoint(ctx context.Context, id string) (bool, error) {
+	query := `DELETE FROM webhook_endpoints WHERE id = $1`
+
+	return r.execUpdate(ctx, "failed to delete webhook endpoint", query, id)
+}
+
+// EnqueueDeliveries adds deliveries to the queue, all or none of them
+func (r *Repository) EnqueueDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_deliveries (
+			id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+			replay_of, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+	`
+
+	for _, delivery := range deliveries {
+		if delivery.ID == "" {
+			delivery.ID = uuid.New().String()
+		}
+
+		_, err := tx.ExecContext(ctx, query,
+			delivery.ID, delivery.EndpointID, delivery.EventID, delivery.EventType, delivery.Payload,
+			delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.ReplayOf,
+			delivery.CreatedAt, delivery.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries claims up to limit deliveries that are due until
+// lockedUntil, counting the claim as an attempt. Deliveries whose previous
+// claim expired without a result (e.g. the worker crashed) are claimed again.
+// Deliveries claimed concurrently by another worker are skipped.
+func (r *Repository) ClaimDueDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE (status = $1 AND next_attempt_at <= $2)
+			OR (status = $3 AND locked_until <= $2)
+		ORDER BY next_attempt_at ASC
+		LIMIT $4
+	`
+
+	var candidates []*model.WebhookDelivery
+	err := r.db.SelectContext(ctx, &candidates, query, model.WebhookDeliveryPending, now, model.WebhookDeliverySending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+
+	// Every claim increments attempts, so it doubles as a version number that
+	// stops two workers from claiming the same delivery
+	claim := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, locked_until = $2, updated_at = $3
+		WHERE id = $4 AND status = $5 AND attempts = $6
+	`
+
+	claimed := make([]*model.WebhookDelivery, 0, len(candidates))
+	for _, delivery := range candidates {
+		ok, err := r.execUpdate(ctx, "failed to claim webhook delivery", claim,
+			model.WebhookDeliverySending, lockedUntil, now, delivery.ID, delivery.Status, delivery.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		delivery.Status = model.WebhookDeliverySending
+		delivery.Attempts++
+		delivery.LockedUntil = &lockedUntil
+		delivery.UpdatedAt = now
+		claimed = append(claimed, delivery)
+	}
+
+	return claimed, nil
+}
+
+// RecordDeliveryAttempt adds an attempt to the delivery log
+func (r *Repository) RecordDeliveryAttempt(ctx context.Context, attempt *model.WebhookDeliveryAttempt) error {
+	if attempt.ID == "" {
+		attempt.ID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO webhook_delivery_attempts (
+			id, delivery_id, attempt, status_code, response_body, error, duration_ms, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		attempt.ID, attempt.DeliveryID, attempt.Attempt, attempt.StatusCode,
+		attempt.ResponseBody, attempt.Error, attempt.DurationMS, attempt.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDeliveryDelivered records that a claimed delivery was accepted by its
+// endpoint
+func (r *Repository) MarkDeliveryDelivered(ctx context.Context, id string, statusCode int, now time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, last_status_code = $2, last_error = NULL, delivered_at = $3, locked_until = NULL, updated_at = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.WebhookDeliveryDelivered, statusCode, now, id, model.WebhookDeliverySending)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery as delivered: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDeliveryFailed records a failed attempt and schedules the next one
+func (r *Repository) MarkDeliveryFailed(ctx context.Context, id string, statusCode *int, lastError string, nextAttemptAt, now time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, last_status_code = $2, last_error = $3, next_attempt_at = $4, locked_until = NULL, updated_at = $5
+		WHERE id = $6 AND status = $7
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.WebhookDeliveryPending, statusCode, lastError, nextAttemptAt, now, id, model.WebhookDeliverySending)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery as failed: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDeliveryDead dead-letters a claimed delivery after its last failed
+// attempt
+func (r *Repository) MarkDeliveryDead(ctx context.Context, id string, statusCode *int, lastError string, now time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, last_status_code = $2, last_error = $3, locked_until = NULL, updated_at = $4
+		WHERE id = $5 AND status = $6
+	`
+
+	_, err := r.db.ExecContext(ctx, query, model.WebhookDeliveryDead, statusCode, lastError, now, id, model.WebhookDeliverySending)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// GetDelivery gets a webhook delivery by ID, or nil if there is none
+func (r *Repository) GetDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	var delivery model.WebhookDelivery
+	if err := r.db.GetContext(ctx, &delivery, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries gets a filtered page of the deliveries of an endpoint,
+// newest first
+func (r *Repository) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, error) {
+	conditions := []string{"endpoint_id = $1"}
+	args := []interface{}{filter.EndpointID}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if filter.EventType != "" {
+		args = append(args, filter.EventType)
+		conditions = append(conditions, fmt.Sprintf("event_type = $%d", len(args)))
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, deliveryColumns, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	var deliveries []*model.WebhookDelivery
+	if err := r.db.SelectContext(ctx, &deliveries, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ListDeliveryAttempts gets the attempts made for a delivery, in order
+func (r *Repository) ListDeliveryAttempts(ctx context.Context, deliveryID string) ([]*model.WebhookDeliveryAttempt, error) {
+	query := `
+		SELECT ` + attemptColumns + `
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempt, created_at
+	`
+
+	var attempts []*model.WebhookDeliveryAttempt
+	if err := r.db.SelectContext(ctx, &attempts, query, deliveryID); err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// execUpdate runs an update or delete and reports whether it changed any row
+func (r *Repository) execUpdate(ctx context.Context, message, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", message, err)
+	}
+
+	return changed > 0, nil
+}
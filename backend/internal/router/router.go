@@ -8,27 +8,63 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 
+	adminHandler "github.com/nanayaw/fullstack/internal/handler/admin"
+	apiKeyHandler "github.com/nanayaw/fullstack/internal/handler/apikey"
 	authHandler "github.com/nanayaw/fullstack/internal/handler/auth"
+	dataExportHandler "github.com/nanayaw/fullstack/internal/handler/dataexport"
+	devHandler "github.com/nanayaw/fullstack/internal/handler/dev"
 	appMiddleware "github.com/nanayaw/fullstack/internal/handler/middleware"
+	notificationHandler "github.com/nanayaw/fullstack/internal/handler/notification"
+	organizationHandler "github.com/nanayaw/fullstack/internal/handler/organization"
+	serviceAccountHandler "github.com/nanayaw/fullstack/internal/handler/serviceaccount"
 	userHandler "github.com/nanayaw/fullstack/internal/handler/user"
+	webhookHandler "github.com/nanayaw/fullstack/internal/handler/webhook"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/service/auth"
+	"github.com/nanayaw/fullstack/pkg/logger"
 )
 
 // Router handles all the routes for the application
 type Router struct {
-	Echo        *echo.Echo
-	AuthHandler *authHandler.Handler
-	UserHandler *userHandler.Handler
-	AuthService auth.Service
+	Echo                  *echo.Echo
+	AuthHandler           *authHandler.Handler
+	UserHandler           *userHandler.Handler
+	AdminHandler          *adminHandler.Handler
+	APIKeyHandler         *apiKeyHandler.Handler
+	NotificationHandler   *notificationHandler.Handler
+	OrganizationHandler   *organizationHandler.Handler
+	ServiceAccountHandler *serviceAccountHandler.Handler
+	DataExportHandler     *dataExportHandler.Handler
+	WebhookHandler        *webhookHandler.Handler
+	DevHandler            *devHandler.Handler
+	AuthService           auth.Service
+	RateLimiter           *appMiddleware.RateLimiter
+	Authorizer            appMiddleware.PermissionChecker
+	Memberships           appMiddleware.MembershipLoader
+	AuditLog              appMiddleware.AuditRecorder
+	AdminAPIKey           string
 }
 
 // NewRouter creates a new router
-func NewRouter(e *echo.Echo, authHandler *authHandler.Handler, userHandler *userHandler.Handler, authService auth.Service) *Router {
+func NewRouter(e *echo.Echo, authHandler *authHandler.Handler, userHandler *userHandler.Handler, adminHandler *adminHandler.Handler, apiKeyHandler *apiKeyHandler.Handler, notificationHandler *notificationHandler.Handler, organizationHandler *organizationHandler.Handler, serviceAccountHandler *serviceAccountHandler.Handler, dataExportHandler *dataExportHandler.Handler, webhookHandler *webhookHandler.Handler, devHandler *devHandler.Handler, authService auth.Service, rateLimiter *appMiddleware.RateLimiter, authorizer appMiddleware.PermissionChecker, memberships appMiddleware.MembershipLoader, auditLog appMiddleware.AuditRecorder, adminAPIKey string) *Router {
 	return &Router{
-		Echo:        e,
-		AuthHandler: authHandler,
-		UserHandler: userHandler,
-		AuthService: authService,
+		Echo:                  e,
+		AuthHandler:           authHandler,
+		UserHandler:           userHandler,
+		AdminHandler:          adminHandler,
+		APIKeyHandler:         apiKeyHandler,
+		NotificationHandler:   notificationHandler,
+		OrganizationHandler:   organizationHandler,
+		ServiceAccountHandler: serviceAccountHandler,
+		DataExportHandler:     dataExportHandler,
+		WebhookHandler:        webhookHandler,
+		DevHandler:            devHandler,
+		AuthService:           authService,
+		RateLimiter:           rateLimiter,
+		Authorizer:            authorizer,
+		Memberships:           memberships,
+		AuditLog:              auditLog,
+		AdminAPIKey:           adminAPIKey,
 	}
 }
 
@@ -40,6 +76,9 @@ func (r *Router) SetupRoutes() {
 	r.Echo.Use(middleware.CORS())
 	r.Echo.Use(middleware.RequestID())
 
+	// Every state-changing request is recorded in the audit log
+	r.Echo.Use(appMiddleware.AuditRequests(r.AuditLog, logger.DefaultLogger()))
+
 	// API v1 group
 	v1 := r.Echo.Group("/api/v1")
 
@@ -51,14 +90,59 @@ func (r *Router) SetupRoutes() {
 		})
 	})
 
-	// Auth routes
+	// Requests are limited with the rate limit policy of their route
+	rateLimit := r.RateLimiter.Middleware()
+
+	// Auth routes, including the token endpoint of service accounts
 	auth := v1.Group("/auth")
+	auth.Use(rateLimit)
 	r.AuthHandler.RegisterRoutes(auth)
+	r.ServiceAccountHandler.RegisterTokenRoutes(auth)
 
-	// User routes
+	// User routes, limited per user once authenticated
 	users := v1.Group("/users")
-	users.Use(appMiddleware.AuthMiddleware(r.AuthService))
-	r.UserHandler.RegisterRoutes(users)
+	users.Use(appMiddleware.AuthMiddleware(r.AuthService), rateLimit)
+	r.UserHandler.RegisterRoutes(users, appMiddleware.Sensitive())
+	r.APIKeyHandler.RegisterRoutes(users.Group("/me/api-keys"), appMiddleware.Sensitive())
+	r.DataExportHandler.RegisterRoutes(users.Group("/me/export"), appMiddleware.Sensitive())
+
+	// Data export routes, where download links are authenticated by their token
+	exports := v1.Group("/exports")
+	exports.Use(rateLimit)
+	r.DataExportHandler.RegisterDownloadRoutes(exports)
+
+	// Notification routes, where unsubscribe links are authenticated by their token
+	notifications := v1.Group("/notifications")
+	notifications.Use(rateLimit)
+	r.NotificationHandler.RegisterRoutes(notifications, appMiddleware.AuthMiddleware(r.AuthService))
+
+	// Organization routes, where routes of a single organization require
+	// membership of it. Service accounts of the organization can use them too.
+	orgs := v1.Group("/orgs")
+	orgs.Use(appMiddleware.AuthMiddleware(r.AuthService, model.PrincipalUser, model.PrincipalService), rateLimit)
+	tenant := appMiddleware.TenantMiddleware(r.Memberships, logger.DefaultLogger())
+	r.OrganizationHandler.RegisterRoutes(orgs, tenant, appMiddleware.Sensitive())
+	r.ServiceAccountHandler.RegisterOrganizationRoutes(orgs.Group("/:orgID/service-accounts", tenant), appMiddleware.Sensitive())
+
+	// Admin routes, for operators with the admin API key and for users and
+	// service accounts with the permission of each route
+	admin := v1.Group("/admin")
+	admin.Use(appMiddleware.AdminAuthMiddleware(r.AdminAPIKey, r.AuthService))
+	requirePermission := func(permission string) echo.MiddlewareFunc {
+		return appMiddleware.RequirePermission(r.Authorizer, permission, logger.DefaultLogger())
+	}
+	r.AdminHandler.RegisterRoutes(admin, requirePermission)
+	r.ServiceAccountHandler.RegisterAdminRoutes(admin, requirePermission)
+
+	// Webhook routes, authenticated by their signatures
+	webhooks := v1.Group("/webhooks")
+	r.WebhookHandler.RegisterRoutes(webhooks)
+
+	// Dev routes, only present when a dev handler is given
+	if r.DevHandler != nil {
+		dev := r.Echo.Group("/dev")
+		r.DevHandler.RegisterRoutes(dev)
+	}
 
 	// Swagger documentation
 	r.Echo.GET("/swagger/*", echoSwagger.WrapHandler)
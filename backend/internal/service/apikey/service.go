@@ -0,0 +1,253 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// maxAPIKeys is how many API keys a user can have
+	maxAPIKeys = 25
+	// maxNameLength is the longest API key name, in characters
+	maxNameLength = 100
+	// idLength is the number of random bytes identifying a key in its prefix
+	idLength = 8
+	// secretLength is the number of random bytes of a key's secret
+	secretLength = 32
+)
+
+// Repository defines the interface for API key database operations
+type Repository interface {
+	// CreateAPIKey stores an API key, filling in its ID and creation time
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+
+	// CountAPIKeys counts the API keys of a user
+	CountAPIKeys(ctx context.Context, userID string) (int, error)
+
+	// ListAPIKeys gets the API keys of a user, newest first
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+
+	// GetActiveAPIKey gets the API key with a prefix, or nil if there is none
+	// or its user is disabled or locked out
+	GetActiveAPIKey(ctx context.Context, prefix string) (*model.APIKey, error)
+
+	// TouchAPIKey records that an API key was just used
+	TouchAPIKey(ctx context.Context, id string) error
+
+	// DeleteAPIKey deletes an API key of a user and reports whether there was one
+	DeleteAPIKey(ctx context.Context, userID, id string) (bool, error)
+}
+
+// PermissionChecker checks the permissions of users
+type PermissionChecker interface {
+	Can(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// Service manages the API keys users create for programmatic access. A key
+// is its prefix, which identifies it, followed by a secret of which only a
+// hash is stored: fsk_<id>_<secret>.
+type Service struct {
+	repo        Repository
+	permissions PermissionChecker
+	audit       service.AuditLog
+	logger      logger.Logger
+}
+
+// NewService creates a new API key service. Keys can only be given the
+// permissions their user has according to permissions.
+func NewService(repo Repository, permissions PermissionChecker, audit service.AuditLog, log logger.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		permissions: permissions,
+		audit:       audit,
+		logger:      log,
+	}
+}
+
+// CreateAPIKey creates an API key for a user, and returns it with the key
+// itself, which can't be read again. Keys without an expiry time never
+// expire.
+func (s *Service) CreateAPIKey(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (string, *model.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.NewValidationError("name is required")
+	}
+	if len([]rune(name)) > maxNameLength {
+		return "", nil, errors.NewValidationError(fmt.Sprintf("name must be at most %d characters", maxNameLength))
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, errors.NewValidationError("expires_at must be in the future")
+	}
+	scopes, err := s.validateScopes(ctx, userID, scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	count, err := s.repo.CountAPIKeys(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if count >= maxAPIKeys {
+		return "", nil, errors.NewConflictError(fmt.Sprintf("users can have at most %d API keys", maxAPIKeys))
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	key := &model.APIKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: hashSecret(secret),
+		Scopes:     scopes,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return "", nil, err
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     "api_key.created",
+		EntityType: "api_key",
+		EntityID:   key.ID,
+		Metadata: map[string]interface{}{
+			"user_id":    userID,
+			"name":       name,
+			"prefix":     prefix,
+			"scopes":     scopes,
+			"expires_at": expiresAt,
+		},
+	})
+	s.logger.Info("API key created", "user_id", userID, "api_key_id", key.ID, "scopes", strings.Join(scopes, " "))
+	return prefix + "_" + secret, key, nil
+}
+
+// ListAPIKeys gets the API keys of a user
+func (s *Service) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey deletes an API key of a user, so it stops working right away
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	ok, err := s.repo.DeleteAPIKey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.NewNotFoundError("API key not found")
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     "api_key.revoked",
+		EntityType: "api_key",
+		EntityID:   id,
+		Metadata:   map[string]interface{}{"user_id": userID},
+	})
+	s.logger.Info("API key revoked", "user_id", userID, "api_key_id", id)
+	return nil
+}
+
+// ValidateAPIKey gets the API key a request was made with, failing if it
+// doesn't exist, expired or its user can't sign in, and records its use
+func (s *Service) ValidateAPIKey(ctx context.Context, apiKey string) (*model.APIKey, error) {
+	prefix, secret, ok := parseKey(apiKey)
+	if !ok {
+		return nil, errors.NewAuthenticationError("invalid API key")
+	}
+
+	key, err := s.repo.GetActiveAPIKey(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, errors.NewAuthenticationError("invalid API key")
+	}
+	if key.Expired(time.Now()) {
+		return nil, errors.NewAuthenticationError("API key expired")
+	}
+
+	// The request goes ahead when the last use can't be recorded
+	if err := s.repo.TouchAPIKey(ctx, key.ID); err != nil {
+		s.logger.Warn("Failed to record API key use", "api_key_id", key.ID, "error", err)
+	}
+
+	return key, nil
+}
+
+// validateScopes checks that scopes are known and that the user has the
+// permissions among them, and removes duplicates
+func (s *Service) validateScopes(ctx context.Context, userID string, scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.NewValidationError("at least one scope is required")
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		if scope != model.APIKeyScopeRead && scope != model.APIKeyScopeWrite {
+			ok, err := s.permissions.Can(ctx, userID, scope)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check permission: %w", err)
+			}
+			if !ok {
+				return nil, errors.NewValidationError("invalid scope: " + scope)
+			}
+		}
+		valid = append(valid, scope)
+	}
+
+	return valid, nil
+}
+
+// generateKey generates the prefix and secret of a new API key
+func generateKey() (string, string, error) {
+	id := make([]byte, idLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	return model.APIKeyPrefix + hex.EncodeToString(id), hex.EncodeToString(secret), nil
+}
+
+// parseKey splits an API key into its prefix and secret
+func parseKey(apiKey string) (string, string, bool) {
+	if !model.IsAPIKey(apiKey) {
+		return "", "", false
+	}
+	i := strings.LastIndex(apiKey, "_")
+	if i < len(model.APIKeyPrefix) {
+		return "", "", false
+	}
+	prefix, secret := apiKey[:i], apiKey[i+1:]
+	if len(prefix) != len(model.APIKeyPrefix)+2*idLength || len(secret) != 2*secretLength {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+// hashSecret hashes the secret of an API key for storage
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
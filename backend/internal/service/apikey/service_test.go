@@ -0,0 +1,176 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository
+type fakeRepository struct {
+	keys    map[string]*model.APIKey
+	touched []string
+}
+
+func (f *fakeRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.ID = "key-" + key.Prefix
+	key.CreatedAt = time.Now()
+	f.keys[key.ID] = key
+	return nil
+}
+
+func (f *fakeRepository) CountAPIKeys(ctx context.Context, userID string) (int, error) {
+	count := 0
+	for _, key := range f.keys {
+		if key.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeRepository) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	keys := []*model.APIKey{}
+	for _, key := range f.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeRepository) GetActiveAPIKey(ctx context.Context, prefix string) (*model.APIKey, error) {
+	for _, key := range f.keys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) TouchAPIKey(ctx context.Context, id string) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+func (f *fakeRepository) DeleteAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	key := f.keys[id]
+	if key == nil || key.UserID != userID {
+		return false, nil
+	}
+	delete(f.keys, id)
+	return true, nil
+}
+
+// fakePermissionChecker grants the permissions listed for each user
+type fakePermissionChecker map[string][]string
+
+func (f fakePermissionChecker) Can(ctx context.Context, userID, permission string) (bool, error) {
+	for _, p := range f[userID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newTestService() (*Service, *fakeRepository) {
+	repo := &fakeRepository{keys: map[string]*model.APIKey{}}
+	permissions := fakePermissionChecker{"user-1": {model.PermissionUsersRead}}
+	return NewService(repo, permissions, &servicetest.AuditLog{}, logger.DefaultLogger()), repo
+}
+
+func TestCreateAndValidateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestService()
+
+	secret, key, err := svc.CreateAPIKey(ctx, "user-1", " CI ", []string{"read", "read", model.PermissionUsersRead}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, key.Prefix+"_"))
+	assert.Equal(t, "CI", key.Name)
+	assert.Equal(t, []string{"read", model.PermissionUsersRead}, key.Scopes)
+	assert.Equal(t, hashSecret(strings.TrimPrefix(secret, key.Prefix+"_")), key.SecretHash)
+
+	validated, err := svc.ValidateAPIKey(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", validated.UserID)
+	assert.Equal(t, []string{key.ID}, repo.touched)
+
+	// Keys with the wrong secret or no secret are rejected
+	_, err = svc.ValidateAPIKey(ctx, key.Prefix+"_"+strings.Repeat("0", 2*secretLength))
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+	_, err = svc.ValidateAPIKey(ctx, key.Prefix)
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+
+	// Revoked keys stop working
+	require.NoError(t, svc.RevokeAPIKey(ctx, "user-1", key.ID))
+	_, err = svc.ValidateAPIKey(ctx, secret)
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+	servicetest.AssertStatus(t, svc.RevokeAPIKey(ctx, "user-1", key.ID), http.StatusNotFound)
+
+	assert.Equal(t, []string{"api_key.created", "api_key.revoked"}, svc.audit.(*servicetest.AuditLog).Actions())
+}
+
+func TestValidateExpiredAPIKey(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestService()
+
+	expiresAt := time.Now().Add(time.Hour)
+	secret, key, err := svc.CreateAPIKey(ctx, "user-1", "CI", []string{"write"}, &expiresAt)
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	repo.keys[key.ID].ExpiresAt = &past
+
+	_, err = svc.ValidateAPIKey(ctx, secret)
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+	assert.Empty(t, repo.touched)
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		keyName   string
+		scopes    []string
+		expiresAt *time.Time
+	}{
+		{"no name", " ", []string{"read"}, nil},
+		{"no scopes", "CI", nil, nil},
+		{"unknown scope", "CI", []string{"admin"}, nil},
+		{"permission the user doesn't have", "CI", []string{model.PermissionRolesManage}, nil},
+		{"expired", "CI", []string{"read"}, &past},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService()
+
+			_, _, err := svc.CreateAPIKey(ctx, "user-1", tt.keyName, tt.scopes, tt.expiresAt)
+			servicetest.AssertStatus(t, err, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestCreateAPIKeyLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService()
+
+	for i := 0; i < maxAPIKeys; i++ {
+		_, _, err := svc.CreateAPIKey(ctx, "user-1", "CI", []string{"read"}, nil)
+		require.NoError(t, err)
+	}
+
+	_, _, err := svc.CreateAPIKey(ctx, "user-1", "CI", []string{"read"}, nil)
+	servicetest.AssertStatus(t, err, http.StatusConflict)
+}
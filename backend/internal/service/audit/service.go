@@ -0,0 +1,267 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// DefaultAuditLogPageSize is used when no page size is requested
+	DefaultAuditLogPageSize = 50
+	// MaxAuditLogPageSize is the largest page size that can be requested
+	MaxAuditLogPageSize = 200
+
+	// ActorSystem is the actor of changes made outside of any request, such
+	// as by background jobs
+	ActorSystem = "system"
+
+	// verifyBatchSize is how many entries are read at a time when verifying
+	// the hash chain
+	verifyBatchSize = 500
+)
+
+// Repository defines the interface for audit log storage
+type Repository interface {
+	// CreateAuditLog appends an entry to the hash chain
+	CreateAuditLog(ctx context.Context, log *models.AuditLog) error
+
+	// GetAuditLogs gets a page of the entries made by a user, newest first
+	GetAuditLogs(ctx context.Context, userID string, offset, limit int) ([]*models.AuditLog, error)
+
+	// CountAuditLogs counts the entries made by a user
+	CountAuditLogs(ctx context.Context, userID string) (int, error)
+
+	// ListAuditLogs gets a filtered page of entries, newest first
+	ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*models.AuditLog, error)
+
+	// ListAuditLogsBySequence gets the entries of the hash chain after a
+	// sequence number, in order
+	ListAuditLogsBySequence(ctx context.Context, after int64, limit int) ([]*models.AuditLog, error)
+}
+
+// Service records and queries the audit log
+type Service struct {
+	repo   Repository
+	logger logger.Logger
+}
+
+// NewService creates a new audit service
+func NewService(repo Repository, log logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: log,
+	}
+}
+
+// CreateAuditLog appends an entry to the audit log. Who acted and the
+// request they acted in are taken from the audit context of ctx when the
+// entry doesn't say.
+func (s *Service) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	s.enrich(ctx, log)
+
+	if err := s.repo.CreateAuditLog(ctx, log); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+// Record appends a change made by a service to the audit log. The fields
+// that changed are kept in the metadata under "changes". Recording happens
+// after the change is made, so failures are only logged.
+func (s *Service) Record(ctx context.Context, event *model.AuditEvent) {
+	metadata := make(map[string]interface{}, len(event.Metadata)+1)
+	for key, value := range event.Metadata {
+		metadata[key] = value
+	}
+	if len(event.Changes) > 0 {
+		metadata["changes"] = event.Changes
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		s.logger.Error("Failed to encode audit metadata", "action", event.Action, "error", err)
+		return
+	}
+
+	entry := &models.AuditLog{
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Metadata:   string(encoded),
+	}
+	if err := s.CreateAuditLog(ctx, entry); err != nil {
+		s.logger.Error("Failed to audit change", "action", event.Action, "entity_type", event.EntityType, "entity_id", event.EntityID, "error", err)
+	}
+}
+
+// GetUserActivity returns a page of the entries made by a user, newest
+// first, with the number of entries they made
+func (s *Service) GetUserActivity(ctx context.Context, userID string, page, pageSize int) ([]*models.AuditLog, int, error) {
+	logs, err := s.repo.GetAuditLogs(ctx, userID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get user activity: %w", err)
+	}
+
+	total, err := s.repo.CountAuditLogs(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get user activity: %w", err)
+	}
+
+	return logs, total, nil
+}
+
+// ListAuditLogs returns a filtered page of the audit log, newest first
+func (s *Service) ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) (*models.AuditLogPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditLogPageSize
+	}
+	if filter.Limit > MaxAuditLogPageSize {
+		filter.Limit = MaxAuditLogPageSize
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, errors.NewValidationError("from must be before to")
+	}
+
+	// Fetch one extra entry to find out whether there is another page
+	pageSize := filter.Limit
+	filter.Limit++
+
+	logs, err := s.repo.ListAuditLogs(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	page := &models.AuditLogPage{Entries: logs}
+	if len(logs) > pageSize {
+		page.Entries = logs[:pageSize]
+		last := page.Entries[pageSize-1]
+		page.NextCursor = EncodeAuditLogCursor(&model.AuditLogCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	return page, nil
+}
+
+// Verify walks the hash chain of the audit log from its first entry,
+// checking that no entry is missing and that every entry still hashes to
+// what was recorded. It stops at the first entry found tampered with.
+func (s *Service) Verify(ctx context.Context) (*model.AuditVerification, error) {
+	result := &model.AuditVerification{}
+
+	for {
+		logs, err := s.repo.ListAuditLogsBySequence(ctx, result.HeadSequence, verifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify audit log: %w", err)
+		}
+
+		for _, log := range logs {
+			if reason := checkLink(log, result.HeadSequence, result.HeadHash); reason != "" {
+				result.Failure = &model.AuditVerificationFailure{
+					Sequence: log.Sequence,
+					EntryID:  log.ID,
+					Reason:   reason,
+				}
+				return result, nil
+			}
+
+			result.Checked++
+			result.HeadSequence = log.Sequence
+			result.HeadHash = log.Hash
+		}
+
+		if len(logs) < verifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// checkLink returns why an entry doesn't follow the entry with a sequence
+// number and hash in the chain, or an empty string if it does
+func checkLink(log *models.AuditLog, sequence int64, hash string) string {
+	switch {
+	case log.Sequence != sequence+1:
+		return fmt.Sprintf("entries %d to %d are missing", sequence+1, log.Sequence-1)
+	case log.PrevHash != hash:
+		return "previous hash does not match the previous entry"
+	case log.ComputeHash() != log.Hash:
+		return "hash does not match the entry"
+	}
+	return ""
+}
+
+// enrich fills in who made an entry and from where
+func (s *Service) enrich(ctx context.Context, log *models.AuditLog) {
+	audit := model.AuditContextFrom(ctx)
+	if audit == nil {
+		audit = &model.AuditContext{Actor: ActorSystem}
+	}
+
+	if log.UserID == "" && log.ServiceAccountID == "" {
+		log.UserID = audit.UserID
+		log.ServiceAccountID = audit.ServiceAccountID
+	}
+
+	if log.Actor == "" {
+		switch {
+		case audit.Actor != "" && audit.Actor != model.AuditActorAnonymous:
+			log.Actor = audit.Actor
+		case log.UserID != "":
+			log.Actor = "user:" + log.UserID
+		case log.ServiceAccountID != "":
+			log.Actor = "service_account:" + log.ServiceAccountID
+		case audit.Actor != "":
+			log.Actor = audit.Actor
+		default:
+			log.Actor = model.AuditActorAnonymous
+		}
+	}
+
+	if log.RequestID == "" {
+		log.RequestID = audit.RequestID
+	}
+	if log.IPAddress == "" {
+		log.IPAddress = audit.IPAddress
+	}
+}
+
+// EncodeAuditLogCursor encodes a cursor into an opaque string for clients
+func EncodeAuditLogCursor(cursor *model.AuditLogCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeAuditLogCursor decodes a cursor previously returned by EncodeAuditLogCursor
+func DecodeAuditLogCursor(encoded string) (*model.AuditLogCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.NewValidationError("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.NewValidationError("invalid cursor")
+	}
+
+	return &model.AuditLogCursor{
+		CreatedAt: createdAt,
+		ID:        parts[1],
+	}, nil
+}
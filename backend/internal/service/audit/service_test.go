@@ -0,0 +1,262 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository chaining entries like the
+// database one
+type fakeRepository struct {
+	logs []*models.AuditLog
+}
+
+func (f *fakeRepository) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	log.ID = fmt.Sprintf("log-%d", len(f.logs)+1)
+	log.CreatedAt = time.Date(2026, 1, 1, 0, 0, len(f.logs), 0, time.UTC)
+	log.Sequence = int64(len(f.logs) + 1)
+	if len(f.logs) > 0 {
+		log.PrevHash = f.logs[len(f.logs)-1].Hash
+	}
+	log.Hash = log.ComputeHash()
+	f.logs = append(f.logs, log)
+	return nil
+}
+
+func (f *fakeRepository) GetAuditLogs(ctx context.Context, userID string, offset, limit int) ([]*models.AuditLog, error) {
+	logs := f.newestFirst(func(log *models.AuditLog) bool { return log.UserID == userID })
+	if offset > len(logs) {
+		offset = len(logs)
+	}
+	logs = logs[offset:]
+	if len(logs) > limit {
+		logs = logs[:limit]
+	}
+	return logs, nil
+}
+
+func (f *fakeRepository) CountAuditLogs(ctx context.Context, userID string) (int, error) {
+	return len(f.newestFirst(func(log *models.AuditLog) bool { return log.UserID == userID })), nil
+}
+
+func (f *fakeRepository) ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*models.AuditLog, error) {
+	logs := f.newestFirst(func(log *models.AuditLog) bool {
+		if filter.Action != "" && log.Action != filter.Action {
+			return false
+		}
+		return filter.After == nil || log.CreatedAt.Before(filter.After.CreatedAt)
+	})
+	if len(logs) > filter.Limit {
+		logs = logs[:filter.Limit]
+	}
+	return logs, nil
+}
+
+func (f *fakeRepository) ListAuditLogsBySequence(ctx context.Context, after int64, limit int) ([]*models.AuditLog, error) {
+	var logs []*models.AuditLog
+	for _, log := range f.logs {
+		if log.Sequence > after && len(logs) < limit {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// newestFirst returns the entries matching a predicate, newest first
+func (f *fakeRepository) newestFirst(match func(*models.AuditLog) bool) []*models.AuditLog {
+	var logs []*models.AuditLog
+	for _, log := range f.logs {
+		if match(log) {
+			logs = append(logs, log)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].CreatedAt.After(logs[j].CreatedAt) })
+	return logs
+}
+
+func newTestService() (*Service, *fakeRepository) {
+	repo := &fakeRepository{}
+	return NewService(repo, logger.DefaultLogger()), repo
+}
+
+func TestRecordTakesActorFromContext(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := model.WithAuditContext(context.Background(), &model.AuditContext{
+		Actor:     "user:user-1",
+		UserID:    "user-1",
+		RequestID: "req-1",
+		IPAddress: "203.0.113.7",
+	})
+
+	svc.Record(ctx, &model.AuditEvent{
+		Action:     "organization.renamed",
+		EntityType: "organization",
+		EntityID:   "org-1",
+		Changes:    model.AuditDiff(map[string]interface{}{"name": "Acme"}, map[string]interface{}{"name": "Acme Inc"}),
+		Metadata:   map[string]interface{}{"slug": "acme"},
+	})
+
+	require.Len(t, repo.logs, 1)
+	entry := repo.logs[0]
+	assert.Equal(t, "user:user-1", entry.Actor)
+	assert.Equal(t, "user-1", entry.UserID)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "203.0.113.7", entry.IPAddress)
+	assert.JSONEq(t, `{"slug":"acme","changes":{"name":{"old":"Acme","new":"Acme Inc"}}}`, entry.Metadata)
+}
+
+func TestCreateAuditLogActor(t *testing.T) {
+	svc, repo := newTestService()
+
+	// Outside of requests, changes are made by the system
+	require.NoError(t, svc.CreateAuditLog(context.Background(), &models.AuditLog{Action: "jobs.cleanup"}))
+	// Entries naming who acted keep them
+	anonymous := model.WithAuditContext(context.Background(), &model.AuditContext{Actor: model.AuditActorAnonymous})
+	require.NoError(t, svc.CreateAuditLog(anonymous, &models.AuditLog{ServiceAccountID: "sa-1", Action: "service_account.token_issued"}))
+	require.NoError(t, svc.CreateAuditLog(anonymous, &models.AuditLog{Action: "user.registered"}))
+
+	assert.Equal(t, ActorSystem, repo.logs[0].Actor)
+	assert.Equal(t, "service_account:sa-1", repo.logs[1].Actor)
+	assert.Equal(t, model.AuditActorAnonymous, repo.logs[2].Actor)
+}
+
+func TestGetUserActivity(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.CreateAuditLog(ctx, &models.AuditLog{UserID: "user-1", Action: fmt.Sprintf("action-%d", i)}))
+	}
+	require.NoError(t, repo.CreateAuditLog(ctx, &models.AuditLog{UserID: "user-2", Action: "other"}))
+
+	logs, total, err := svc.GetUserActivity(ctx, "user-1", 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "action-0", logs[0].Action)
+}
+
+func TestListAuditLogsPaginates(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.CreateAuditLog(ctx, &models.AuditLog{Action: "organization.created"}))
+	}
+
+	page, err := svc.ListAuditLogs(ctx, model.AuditLogFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, page.Entries, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	after, err := DecodeAuditLogCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, page.Entries[1].ID, after.ID)
+
+	page, err = svc.ListAuditLogs(ctx, model.AuditLogFilter{After: after, Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, page.Entries, 1)
+	assert.Empty(t, page.NextCursor)
+
+	now := time.Now()
+	_, err = svc.ListAuditLogs(ctx, model.AuditLogFilter{From: now, To: now.Add(-time.Hour)})
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+
+	_, err = DecodeAuditLogCursor("not a cursor")
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	newChain := func(t *testing.T) (*Service, *fakeRepository) {
+		svc, repo := newTestService()
+		for i := 0; i < 5; i++ {
+			svc.Record(ctx, &model.AuditEvent{Action: "api.request", EntityType: "route", EntityID: fmt.Sprintf("POST /%d", i)})
+		}
+		require.Len(t, repo.logs, 5)
+		return svc, repo
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		svc, repo := newChain(t)
+
+		result, err := svc.Verify(ctx)
+		require.NoError(t, err)
+		assert.True(t, result.Valid())
+		assert.Equal(t, int64(5), result.Checked)
+		assert.Equal(t, int64(5), result.HeadSequence)
+		assert.Equal(t, repo.logs[4].Hash, result.HeadHash)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		svc, _ := newTestService()
+
+		result, err := svc.Verify(ctx)
+		require.NoError(t, err)
+		assert.True(t, result.Valid())
+		assert.Zero(t, result.Checked)
+	})
+
+	t.Run("edited entry", func(t *testing.T) {
+		svc, repo := newChain(t)
+		repo.logs[2].Metadata = `{"forged":true}`
+
+		result, err := svc.Verify(ctx)
+		require.NoError(t, err)
+		require.False(t, result.Valid())
+		assert.Equal(t, int64(3), result.Failure.Sequence)
+		assert.Equal(t, "hash does not match the entry", result.Failure.Reason)
+		assert.Equal(t, int64(2), result.Checked)
+	})
+
+	t.Run("edited entry with recomputed hash", func(t *testing.T) {
+		svc, repo := newChain(t)
+		repo.logs[2].Actor = "user:someone-else"
+		repo.logs[2].Hash = repo.logs[2].ComputeHash()
+
+		result, err := svc.Verify(ctx)
+		require.NoError(t, err)
+		require.False(t, result.Valid())
+		assert.Equal(t, int64(4), result.Failure.Sequence)
+		assert.Equal(t, "previous hash does not match the previous entry", result.Failure.Reason)
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		svc, repo := newChain(t)
+		repo.logs = append(repo.logs[:1], repo.logs[2:]...)
+
+		result, err := svc.Verify(ctx)
+		require.NoError(t, err)
+		require.False(t, result.Valid())
+		assert.Equal(t, int64(3), result.Failure.Sequence)
+		assert.Equal(t, "entries 2 to 2 are missing", result.Failure.Reason)
+	})
+}
+
+func TestAuditDiff(t *testing.T) {
+	changes := model.AuditDiff(
+		map[string]interface{}{"name": "Acme", "role": "member", "slug": "acme"},
+		map[string]interface{}{"name": "Acme", "role": "admin", "plan": "pro"},
+	)
+
+	encoded, err := json.Marshal(changes)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"role": {"old": "member", "new": "admin"},
+		"slug": {"old": "acme", "new": null},
+		"plan": {"old": null, "new": "pro"}
+	}`, string(encoded))
+}
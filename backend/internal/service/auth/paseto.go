@@ -9,8 +9,10 @@ import (
 
 	"github.com/nanayaw/fullstack/internal/config"
 	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/models"
 	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/i18n"
 	"github.com/o1egl/paseto/v2"
 )
 
@@ -20,15 +22,63 @@ type TokenClaims struct {
 	IssuedAt  time.Time `json:"iat"`
 	ExpiresAt time.Time `json:"exp"`
 	Type      string    `json:"type"`
+	// Roles and permissions of the user when an access token was issued, for
+	// clients to adapt their interface. The API checks permissions with the
+	// authorization service, so revocations take effect before tokens expire.
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Admin acting as the subject, for impersonation tokens (RFC 8693)
+	Actor *ActorClaim `json:"act,omitempty"`
+	// Organization the user switched to and their role in it, empty for their
+	// personal account. Refresh tokens carry the organization so refreshed
+	// access tokens stay in it.
+	OrgID   string `json:"org,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	// model.PrincipalService for access tokens of service accounts, whose
+	// subject is the service account and permissions the scopes it was given.
+	// Empty for users.
+	Principal string `json:"pty,omitempty"`
+}
+
+// ActorClaim identifies who is acting on behalf of a token's subject
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
+
+// Authorizer gets the roles and permissions of users for their access tokens
+type Authorizer interface {
+	GetAuthorization(ctx context.Context, userID string) (*model.Authorization, error)
+}
+
+// Memberships gets the memberships of users in organizations
+type Memberships interface {
+	// GetMembership gets the membership of a user in an organization, or nil if they aren't a member
+	GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error)
+}
+
+// APIKeys validates the API keys users create for programmatic access
+type APIKeys interface {
+	// ValidateAPIKey gets the API key a request was made with, failing if it isn't valid
+	ValidateAPIKey(ctx context.Context, apiKey string) (*model.APIKey, error)
+}
+
+// Webhooks notifies the webhook endpoints of other services of identity
+// lifecycle events
+type Webhooks interface {
+	Dispatch(ctx context.Context, eventType string, data map[string]interface{})
 }
 
 type PasetoService struct {
-	publicKey  ed25519.PublicKey
-	privateKey ed25519.PrivateKey
-	config     *config.AuthConfig
-	userSvc    service.UserService
-	emailSvc   service.EmailService
-	cacheSvc   service.CacheService
+	publicKey   ed25519.PublicKey
+	privateKey  ed25519.PrivateKey
+	config      *config.AuthConfig
+	userSvc     service.UserService
+	emailSvc    service.EmailService
+	cacheSvc    service.CacheService
+	authz       Authorizer
+	memberships Memberships
+	apiKeys     APIKeys
+	webhooks    Webhooks
 }
 
 func NewPasetoService(
@@ -58,6 +108,30 @@ func NewPasetoService(
 	}, nil
 }
 
+// SetAuthorizer sets the authorizer whose roles and permissions are issued in
+// access tokens. Access tokens carry no roles or permissions without one.
+func (s *PasetoService) SetAuthorizer(authz Authorizer) {
+	s.authz = authz
+}
+
+// SetMemberships sets the memberships users can switch organizations with.
+// Users can't switch organizations without them.
+func (s *PasetoService) SetMemberships(memberships Memberships) {
+	s.memberships = memberships
+}
+
+// SetAPIKeys sets the API keys accepted in place of access tokens. Only
+// access tokens are accepted without them.
+func (s *PasetoService) SetAPIKeys(apiKeys APIKeys) {
+	s.apiKeys = apiKeys
+}
+
+// SetWebhooks sets the webhooks notified when users register and verify
+// their email. No webhooks are sent without them.
+func (s *PasetoService) SetWebhooks(webhooks Webhooks) {
+	s.webhooks = webhooks
+}
+
 func (s *PasetoService) Register(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
 	// Create user
 	user, err := s.userSvc.CreateUser(ctx, req)
@@ -65,6 +139,12 @@ func (s *PasetoService) Register(ctx context.Context, req *models.CreateUserRequ
 		return nil, err
 	}
 
+	// Send emails in the language chosen at registration
+	if req.Locale != "" {
+		ctx = i18n.WithLocale(ctx, req.Locale)
+	}
+	ctx = withUserLocale(ctx, user)
+
 	// Send verification email
 	if err := s.SendVerificationEmail(ctx, user.ID); err != nil {
 		// Log error but don't fail registration
@@ -77,6 +157,17 @@ func (s *PasetoService) Register(ctx context.Context, req *models.CreateUserRequ
 		fmt.Printf("failed to send welcome email: %v\n", err)
 	}
 
+	// Remind the user to verify their email if they haven't by then
+	if s.config.OnboardingReminderDelay > 0 {
+		sendAt := time.Now().Add(s.config.OnboardingReminderDelay)
+		if err := s.emailSvc.ScheduleEmail(ctx, user.ID, model.ScheduledEmailOnboardingReminder, user.Email, sendAt); err != nil {
+			// Log error but don't fail registration
+			fmt.Printf("failed to schedule onboarding reminder email: %v\n", err)
+		}
+	}
+
+	s.dispatch(ctx, model.WebhookEventUserRegistered, user)
+
 	return user, nil
 }
 
@@ -99,8 +190,12 @@ func (s *PasetoService) Login(ctx context.Context, req *models.LoginRequest) (*m
 
 	// TODO: Verify password hash
 
+	if user.DisabledAt != nil {
+		return nil, errors.NewAuthorizationError("account is disabled")
+	}
+
 	// Generate tokens
-	accessToken, err := s.generateToken(user.ID, "access", s.config.AccessTokenTTL)
+	accessToken, err := s.generateAccessToken(ctx, user.ID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -138,14 +233,26 @@ func (s *PasetoService) RefreshToken(ctx context.Context, req *models.RefreshTok
 	if err != nil || userID == "" {
 		return nil, errors.NewAuthenticationError("invalid or expired token")
 	}
+	if err := s.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	// Stay in the organization the user switched to, unless they left it
+	var membership *model.Membership
+	if claims.OrgID != "" && s.memberships != nil {
+		membership, err = s.memberships.GetMembership(ctx, claims.OrgID, claims.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get membership: %w", err)
+		}
+	}
 
 	// Generate new tokens
-	accessToken, err := s.generateToken(claims.Subject, "access", s.config.AccessTokenTTL)
+	accessToken, err := s.generateAccessToken(ctx, claims.Subject, membership)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.generateToken(claims.Subject, "refresh", s.config.RefreshTokenTTL)
+	refreshToken, err := s.generateRefreshToken(claims.Subject, membership)
 	if err != nil {
 		return nil, err
 	}
@@ -171,6 +278,10 @@ func (s *PasetoService) Logout(ctx context.Context, sessionID string) error {
 }
 
 func (s *PasetoService) ValidateSession(ctx context.Context, token string) (*models.Session, error) {
+	if model.IsAPIKey(token) {
+		return s.validateAPIKey(ctx, token)
+	}
+
 	claims, err := s.validateToken(token)
 	if err != nil {
 		return nil, err
@@ -179,17 +290,222 @@ func (s *PasetoService) ValidateSession(ctx context.Context, token string) (*mod
 	if claims.Type != "access" {
 		return nil, errors.NewAuthenticationError("invalid token type")
 	}
+	if err := s.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Principal == model.PrincipalService {
+		return &models.Session{
+			ID:               claims.ID,
+			ServiceAccountID: claims.Subject,
+			ExpiresAt:        claims.ExpiresAt,
+			CreatedAt:        claims.IssuedAt,
+			Permissions:      claims.Permissions,
+			OrgID:            claims.OrgID,
+			OrgRole:          claims.OrgRole,
+		}, nil
+	}
+
+	session := &models.Session{
+		ID:          claims.ID,
+		UserID:      claims.Subject,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		OrgID:       claims.OrgID,
+		OrgRole:     claims.OrgRole,
+	}
+
+	// Impersonation tokens stop working as soon as the impersonation is stopped
+	if claims.Actor != nil {
+		impersonation, err := s.GetImpersonation(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if impersonation == nil || impersonation.UserID != claims.Subject || impersonation.ActorID != claims.Actor.Subject {
+			return nil, errors.NewAuthenticationError("impersonation ended")
+		}
+		session.ImpersonatorID = claims.Actor.Subject
+	}
+
+	return session, nil
+}
+
+// validateAPIKey resolves an API key to a session of its user, limited to the
+// scopes of the key
+func (s *PasetoService) validateAPIKey(ctx context.Context, apiKey string) (*models.Session, error) {
+	if s.apiKeys == nil {
+		return nil, errors.NewAuthenticationError("invalid token")
+	}
+
+	key, err := s.apiKeys.ValidateAPIKey(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		CreatedAt: key.CreatedAt,
+		APIKeyID:  key.ID,
+		Scopes:    key.Scopes,
+	}
+	if key.ExpiresAt != nil {
+		session.ExpiresAt = *key.ExpiresAt
+	}
+
+	return session, nil
+}
 
-	return &models.Session{
-		ID:     claims.ID,
-		UserID: claims.Subject,
+// IssueServiceToken issues an access token for a service account, acting in
+// its organization with its role or with the permissions it was given. The
+// token can't be refreshed: service accounts sign in again with their client
+// credentials. Invalidating all sessions of the service account revokes it.
+func (s *PasetoService) IssueServiceToken(ctx context.Context, account *model.ServiceAccount) (*model.ServiceToken, error) {
+	claims := newTokenClaims(account.ID, "access", s.config.ServiceTokenTTL)
+	claims.Principal = model.PrincipalService
+	claims.Permissions = account.Scopes
+	if account.OrganizationID != nil {
+		claims.OrgID = *account.OrganizationID
+		claims.OrgRole = account.Role
+	}
+
+	token, err := s.signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ServiceToken{
+		AccessToken: token,
+		ExpiresAt:   claims.ExpiresAt,
+		Scopes:      account.Scopes,
 	}, nil
 }
 
+// SwitchOrganization issues new tokens for a user acting in an organization
+// they are a member of, or in their personal account when orgID is empty.
+// Tokens issued before keep the organization they were issued for.
+func (s *PasetoService) SwitchOrganization(ctx context.Context, userID, orgID string) (*models.RefreshTokenResponse, error) {
+	var membership *model.Membership
+	if orgID != "" {
+		if s.memberships == nil {
+			return nil, errors.NewNotFoundError("organization not found")
+		}
+
+		var err error
+		membership, err = s.memberships.GetMembership(ctx, orgID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get membership: %w", err)
+		}
+		if membership == nil {
+			return nil, errors.NewNotFoundError("organization not found")
+		}
+	}
+
+	accessToken, err := s.generateAccessToken(ctx, userID, membership)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.generateRefreshToken(userID, membership)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cacheSvc.StoreSession(ctx, refreshToken, userID, s.config.RefreshTokenTTL); err != nil {
+		return nil, err
+	}
+
+	return &models.RefreshTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// StartImpersonation issues a short-lived access token letting an admin act as
+// a user. The token can't be refreshed, and stops working when it expires or
+// the impersonation is stopped.
+func (s *PasetoService) StartImpersonation(ctx context.Context, userID, actorID, reason string) (string, *model.Impersonation, error) {
+	claims := newTokenClaims(userID, "access", s.config.ImpersonationTTL)
+	claims.Actor = &ActorClaim{Subject: actorID}
+	if s.authz != nil {
+		authz, err := s.authz.GetAuthorization(ctx, userID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get user authorization: %w", err)
+		}
+		claims.Roles = authz.Roles
+		claims.Permissions = authz.Permissions
+	}
+
+	token, err := s.signToken(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	impersonation := &model.Impersonation{
+		ID:        claims.ID,
+		UserID:    userID,
+		ActorID:   actorID,
+		Reason:    reason,
+		StartedAt: claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}
+	if err := s.cacheSvc.CacheData(ctx, impersonationKey(claims.ID), impersonation, int(s.config.ImpersonationTTL.Seconds())); err != nil {
+		return "", nil, fmt.Errorf("failed to store impersonation: %w", err)
+	}
+
+	return token, impersonation, nil
+}
+
+// GetImpersonation gets an impersonation that hasn't ended, or nil if there is none
+func (s *PasetoService) GetImpersonation(ctx context.Context, id string) (*model.Impersonation, error) {
+	var impersonation *model.Impersonation
+	if err := s.cacheSvc.GetCachedData(ctx, impersonationKey(id), &impersonation); err != nil {
+		return nil, fmt.Errorf("failed to get impersonation: %w", err)
+	}
+	return impersonation, nil
+}
+
+// StopImpersonation ends an impersonation, revoking its access token
+func (s *PasetoService) StopImpersonation(ctx context.Context, id string) (*model.Impersonation, error) {
+	impersonation, err := s.GetImpersonation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if impersonation == nil {
+		return nil, errors.NewNotFoundError("impersonation not found or already ended")
+	}
+
+	if err := s.cacheSvc.InvalidateCache(ctx, impersonationKey(id)); err != nil {
+		return nil, fmt.Errorf("failed to stop impersonation: %w", err)
+	}
+
+	return impersonation, nil
+}
+
+// InvalidateAllSessions signs a user out everywhere. Refresh tokens are only
+// indexed by token, so rather than deleting them this revokes every access and
+// refresh token issued to the user until now.
 func (s *PasetoService) InvalidateAllSessions(ctx context.Context, userID string) error {
-	// Invalidate all sessions for user
-	pattern := fmt.Sprintf("session:*:%s", userID)
-	return s.cacheSvc.InvalidateCachePattern(ctx, pattern)
+	// Refresh tokens live the longest, so remember the revocation as long as
+	// one issued just before it could be used
+	revokedAt := time.Now()
+	if err := s.cacheSvc.CacheData(ctx, sessionsRevokedKey(userID), revokedAt, int(s.config.RefreshTokenTTL.Seconds())); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// checkRevoked returns an authentication error if the token was issued before
+// all sessions of its user were invalidated
+func (s *PasetoService) checkRevoked(ctx context.Context, claims *TokenClaims) error {
+	var revokedAt *time.Time
+	if err := s.cacheSvc.GetCachedData(ctx, sessionsRevokedKey(claims.Subject), &revokedAt); err != nil {
+		return fmt.Errorf("failed to check revoked sessions: %w", err)
+	}
+	if revokedAt != nil && !claims.IssuedAt.After(*revokedAt) {
+		return errors.NewAuthenticationError("token revoked")
+	}
+	return nil
 }
 
 func (s *PasetoService) SendVerificationEmail(ctx context.Context, userID string) error {
@@ -206,7 +522,7 @@ func (s *PasetoService) SendVerificationEmail(ctx context.Context, userID string
 	}
 
 	// Send email
-	if err := s.emailSvc.SendVerificationEmail(ctx, user.Email, token); err != nil {
+	if err := s.emailSvc.SendVerificationEmail(withUserLocale(ctx, user), user.Email, token); err != nil {
 		return fmt.Errorf("failed to send verification email: %w", err)
 	}
 
@@ -214,16 +530,66 @@ func (s *PasetoService) SendVerificationEmail(ctx context.Context, userID string
 }
 
 // Helper functions
+
+// sessionsRevokedKey returns the cache key of when all sessions of a user were
+// last invalidated
+func sessionsRevokedKey(userID string) string {
+	return fmt.Sprintf("auth:sessions_revoked:%s", userID)
+}
+
+// impersonationKey returns the cache key of an impersonation
+func impersonationKey(id string) string {
+	return fmt.Sprintf("auth:impersonation:%s", id)
+}
+
 func (s *PasetoService) generateToken(subject, tokenType string, expiration time.Duration) (string, error) {
+	return s.signToken(newTokenClaims(subject, tokenType, expiration))
+}
+
+// generateAccessToken generates an access token carrying the user's roles and
+// permissions, and their membership of the active organization if there is one
+func (s *PasetoService) generateAccessToken(ctx context.Context, userID string, membership *model.Membership) (string, error) {
+	claims := newTokenClaims(userID, "access", s.config.AccessTokenTTL)
+	if membership != nil {
+		claims.OrgID = membership.OrganizationID
+		claims.OrgRole = membership.Role
+	}
+	if s.authz != nil {
+		authz, err := s.authz.GetAuthorization(ctx, userID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get user authorization: %w", err)
+		}
+		claims.Roles = authz.Roles
+		claims.Permissions = authz.Permissions
+	}
+
+	return s.signToken(claims)
+}
+
+// generateRefreshToken generates a refresh token, carrying the active
+// organization if there is one
+func (s *PasetoService) generateRefreshToken(userID string, membership *model.Membership) (string, error) {
+	claims := newTokenClaims(userID, "refresh", s.config.RefreshTokenTTL)
+	if membership != nil {
+		claims.OrgID = membership.OrganizationID
+	}
+
+	return s.signToken(claims)
+}
+
+// newTokenClaims returns the claims of a new token expiring after expiration
+func newTokenClaims(subject, tokenType string, expiration time.Duration) TokenClaims {
 	now := time.Now()
-	claims := TokenClaims{
+	return TokenClaims{
 		ID:        generateUUID(),
 		Subject:   subject,
 		IssuedAt:  now,
 		ExpiresAt: now.Add(expiration),
 		Type:      tokenType,
 	}
+}
 
+func (s *PasetoService) signToken(claims TokenClaims) (string, error) {
 	token, err := paseto.NewV2().Sign(s.privateKey, claims, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
@@ -268,10 +634,58 @@ func (s *PasetoService) VerifyEmail(ctx context.Context, req *models.VerifyEmail
 	updateReq := &models.UpdateUserRequest{
 		// Set email as verified in the database
 	}
-	_, err = s.userSvc.UpdateUser(ctx, claims.Subject, updateReq)
+	user, err := s.userSvc.UpdateUser(ctx, claims.Subject, updateReq)
+	if err != nil {
+		return err
+	}
+
+	// The reminder is no longer needed, and verified users get the security digest
+	if err := s.emailSvc.CancelScheduledEmails(ctx, user.ID, model.ScheduledEmailOnboardingReminder); err != nil {
+		fmt.Printf("failed to cancel onboarding reminder email: %v\n", err)
+	}
+	if s.config.SecurityDigestInterval > 0 {
+		sendAt := time.Now().Add(s.config.SecurityDigestInterval)
+		if err := s.emailSvc.ScheduleEmail(withUserLocale(ctx, user), user.ID, model.ScheduledEmailSecurityDigest, user.Email, sendAt); err != nil {
+			fmt.Printf("failed to schedule security digest email: %v\n", err)
+		}
+	}
+
+	s.dispatch(ctx, model.WebhookEventUserVerified, user)
+
+	return nil
+}
+
+// dispatch notifies webhooks of an event about a user, if there are any
+func (s *PasetoService) dispatch(ctx context.Context, eventType string, user *models.User) {
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Dispatch(ctx, eventType, map[string]interface{}{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
+}
+
+// SendOnboardingReminder sends the scheduled reminder to verify their email
+// address to a user, with a new verification link. Nothing is sent if the
+// user verified their email in the meantime.
+func (s *PasetoService) SendOnboardingReminder(ctx context.Context, userID string) error {
+	user, err := s.userSvc.GetUser(ctx, userID)
 	if err != nil {
 		return err
 	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	token, err := s.generateToken(userID, "verification", s.config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.emailSvc.SendOnboardingReminderEmail(withUserLocale(ctx, user), user.Email, user.FullName, token); err != nil {
+		return fmt.Errorf("failed to send onboarding reminder email: %w", err)
+	}
 
 	return nil
 }
@@ -291,7 +705,7 @@ func (s *PasetoService) SendPasswordResetEmail(ctx context.Context, email string
 	}
 
 	// Send email
-	if err := s.emailSvc.SendPasswordResetEmail(ctx, user.Email, token); err != nil {
+	if err := s.emailSvc.SendPasswordResetEmail(withUserLocale(ctx, user), user.Email, token); err != nil {
 		return fmt.Errorf("failed to send password reset email: %w", err)
 	}
 
@@ -393,9 +807,12 @@ func (s *PasetoService) HandleOAuthLogin(ctx context.Context, req *models.OAuthL
 			return nil, err
 		}
 	}
+	if user.DisabledAt != nil {
+		return nil, errors.NewAuthorizationError("account is disabled")
+	}
 
 	// Generate tokens
-	accessToken, err := s.generateToken(user.ID, "access", s.config.AccessTokenTTL)
+	accessToken, err := s.generateAccessToken(ctx, user.ID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -428,3 +845,12 @@ func (s *PasetoService) UnlinkOAuthAccount(ctx context.Context, userID string, p
 	// This would require removing OAuth provider and ID from the database
 	return nil
 }
+
+// withUserLocale renders emails to user in their preferred locale, keeping the
+// locale already carried by ctx if they don't have one
+func withUserLocale(ctx context.Context, user *models.User) context.Context {
+	if user.Locale == "" {
+		return ctx
+	}
+	return i18n.WithLocale(ctx, user.Locale)
+}
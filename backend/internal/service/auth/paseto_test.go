@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -146,6 +149,46 @@ func (m *mockEmailService) SendPasswordResetEmail(ctx context.Context, to, token
 	return args.Error(0)
 }
 
+func (m *mockEmailService) SendAccountLockedEmail(ctx context.Context, to string, unlockTime time.Time, failedAttempts int, unlockToken string) error {
+	args := m.Called(ctx, to, unlockTime, failedAttempts, unlockToken)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) SendSuspiciousActivityEmail(ctx context.Context, to, activityType, deviceInfo, location, ipAddress string) error {
+	args := m.Called(ctx, to, activityType, deviceInfo, location, ipAddress)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) SendOnboardingReminderEmail(ctx context.Context, to, userName, token string) error {
+	args := m.Called(ctx, to, userName, token)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) SendSecurityDigestEmail(ctx context.Context, to string, digest *model.SecurityDigest) error {
+	args := m.Called(ctx, to, digest)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) SendOrganizationInvitationEmail(ctx context.Context, to, organizationName, inviterName, token string) error {
+	args := m.Called(ctx, to, organizationName, inviterName, token)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) SendDataExportReadyEmail(ctx context.Context, to, token string) error {
+	args := m.Called(ctx, to, token)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) ScheduleEmail(ctx context.Context, userID, kind, to string, sendAt time.Time) error {
+	args := m.Called(ctx, userID, kind, to, sendAt)
+	return args.Error(0)
+}
+
+func (m *mockEmailService) CancelScheduledEmails(ctx context.Context, userID string, kinds ...string) error {
+	args := m.Called(ctx, userID, kinds)
+	return args.Error(0)
+}
+
 // Add the missing ValidateEmailAddress method
 func (m *mockEmailService) ValidateEmailAddress(email string) bool {
 	args := m.Called(email)
@@ -163,6 +206,22 @@ func (m *mockCacheService) CheckRateLimit(ctx context.Context, key string, limit
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockCacheService) SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error) {
+	args := m.Called(ctx, key, limit, window)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RateLimitResult), args.Error(1)
+}
+
+func (m *mockCacheService) TokenBucketRateLimit(ctx context.Context, key string, capacity int, window time.Duration) (*model.RateLimitResult, error) {
+	args := m.Called(ctx, key, capacity, window)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RateLimitResult), args.Error(1)
+}
+
 func (m *mockCacheService) StoreSession(ctx context.Context, sessionID, userID string, expiration time.Duration) error {
 	args := m.Called(ctx, sessionID, userID, expiration)
 	return args.Error(0)
@@ -211,6 +270,15 @@ func (m *mockCacheService) InvalidateSession(ctx context.Context, sessionID stri
 
 // Implement other CacheService methods...
 
+// mockWebhooks is a mock implementation of Webhooks
+type mockWebhooks struct {
+	mock.Mock
+}
+
+func (m *mockWebhooks) Dispatch(ctx context.Context, eventType string, data map[string]interface{}) {
+	m.Called(ctx, eventType, data)
+}
+
 // Helper function to create a test config with keys
 func createTestConfig() *config.AuthConfig {
 	// Generate a test key pair
@@ -221,9 +289,12 @@ func createTestConfig() *config.AuthConfig {
 		PasswordResetTTL: time.Hour * 24,
 		AccessTokenTTL:   time.Minute * 15,
 		RefreshTokenTTL:  time.Hour * 24 * 7,
+		ServiceTokenTTL:  time.Hour,
 		MaxLoginAttempts: 5,
 		PrivateKey:       hex.EncodeToString(privateKey),
 		PublicKey:        hex.EncodeToString(publicKey),
+
+		OnboardingReminderDelay: time.Hour * 72,
 	}
 }
 
@@ -237,6 +308,8 @@ func TestPasetoService_Register(t *testing.T) {
 
 	service, err := NewPasetoService(cfg, userSvc, emailSvc, cacheSvc)
 	assert.NoError(t, err)
+	webhooks := new(mockWebhooks)
+	service.SetWebhooks(webhooks)
 
 	// Test data
 	req := &models.CreateUserRequest{
@@ -256,6 +329,14 @@ func TestPasetoService_Register(t *testing.T) {
 	userSvc.On("GetUser", mock.Anything, user.ID).Return(user, nil)
 	emailSvc.On("SendVerificationEmail", mock.Anything, user.Email, mock.AnythingOfType("string")).Return(nil)
 	emailSvc.On("SendWelcomeEmail", mock.Anything, user.Email, user.FullName).Return(nil)
+	emailSvc.On("ScheduleEmail", mock.Anything, user.ID, model.ScheduledEmailOnboardingReminder, user.Email,
+		mock.MatchedBy(func(sendAt time.Time) bool {
+			return sendAt.After(time.Now().Add(71 * time.Hour))
+		})).Return(nil)
+	webhooks.On("Dispatch", mock.Anything, model.WebhookEventUserRegistered, map[string]interface{}{
+		"user_id": user.ID,
+		"email":   user.Email,
+	}).Return()
 
 	// Execute
 	result, err := service.Register(context.Background(), req)
@@ -265,6 +346,37 @@ func TestPasetoService_Register(t *testing.T) {
 	assert.Equal(t, user, result)
 	userSvc.AssertExpectations(t)
 	emailSvc.AssertExpectations(t)
+	webhooks.AssertExpectations(t)
+}
+
+func TestPasetoService_SendOnboardingReminder(t *testing.T) {
+	t.Run("unverified user", func(t *testing.T) {
+		userSvc := new(mockUserService)
+		emailSvc := new(mockEmailService)
+		service, err := NewPasetoService(createTestConfig(), userSvc, emailSvc, new(mockCacheService))
+		assert.NoError(t, err)
+
+		user := &models.User{ID: "user123", Email: "test@example.com", FullName: "Test User"}
+		userSvc.On("GetUser", mock.Anything, user.ID).Return(user, nil)
+		emailSvc.On("SendOnboardingReminderEmail", mock.Anything, user.Email, user.FullName, mock.AnythingOfType("string")).Return(nil)
+
+		assert.NoError(t, service.SendOnboardingReminder(context.Background(), user.ID))
+		emailSvc.AssertExpectations(t)
+	})
+
+	t.Run("verified user", func(t *testing.T) {
+		userSvc := new(mockUserService)
+		emailSvc := new(mockEmailService)
+		service, err := NewPasetoService(createTestConfig(), userSvc, emailSvc, new(mockCacheService))
+		assert.NoError(t, err)
+
+		user := &models.User{ID: "user123", Email: "test@example.com", EmailVerified: true}
+		userSvc.On("GetUser", mock.Anything, user.ID).Return(user, nil)
+
+		// The user verified their email since the reminder was scheduled
+		assert.NoError(t, service.SendOnboardingReminder(context.Background(), user.ID))
+		emailSvc.AssertNotCalled(t, "SendOnboardingReminderEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestPasetoService_Login(t *testing.T) {
@@ -330,6 +442,7 @@ func TestPasetoService_RefreshToken(t *testing.T) {
 
 	// Setup expectations
 	cacheSvc.On("GetSession", mock.Anything, oldRefreshToken).Return(userID, nil)
+	cacheSvc.On("GetCachedData", mock.Anything, "auth:sessions_revoked:"+userID, mock.Anything).Return(nil)
 	cacheSvc.On("InvalidateSession", mock.Anything, oldRefreshToken).Return(nil)
 	cacheSvc.On("StoreSession", mock.Anything, mock.AnythingOfType("string"), userID, cfg.RefreshTokenTTL).Return(nil)
 
@@ -361,6 +474,8 @@ func TestPasetoService_ValidateSession(t *testing.T) {
 	token, err := service.generateToken(userID, "access", cfg.AccessTokenTTL)
 	assert.NoError(t, err)
 
+	cacheSvc.On("GetCachedData", mock.Anything, "auth:sessions_revoked:"+userID, mock.Anything).Return(nil)
+
 	// Execute
 	session, err := service.ValidateSession(context.Background(), token)
 
@@ -369,3 +484,261 @@ func TestPasetoService_ValidateSession(t *testing.T) {
 	assert.NotNil(t, session)
 	assert.Equal(t, userID, session.UserID)
 }
+
+// mockAuthorizer is a mock implementation of Authorizer
+type mockAuthorizer struct {
+	mock.Mock
+}
+
+func (m *mockAuthorizer) GetAuthorization(ctx context.Context, userID string) (*model.Authorization, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Authorization), args.Error(1)
+}
+
+func TestPasetoService_AccessTokenClaims(t *testing.T) {
+	// Setup
+	cfg := createTestConfig()
+	cacheSvc := new(mockCacheService)
+	cacheSvc.On("GetCachedData", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	service, err := NewPasetoService(cfg, new(mockUserService), new(mockEmailService), cacheSvc)
+	assert.NoError(t, err)
+
+	authz := new(mockAuthorizer)
+	service.SetAuthorizer(authz)
+
+	authz.On("GetAuthorization", mock.Anything, "user123").Return(&model.Authorization{
+		Roles:       []string{model.RoleSupport},
+		Permissions: []string{model.PermissionRolesRead, model.PermissionUsersRead},
+	}, nil)
+
+	// Execute
+	token, err := service.generateAccessToken(context.Background(), "user123", nil)
+	assert.NoError(t, err)
+	session, err := service.ValidateSession(context.Background(), token)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "user123", session.UserID)
+	assert.Equal(t, []string{model.RoleSupport}, session.Roles)
+	assert.Equal(t, []string{model.PermissionRolesRead, model.PermissionUsersRead}, session.Permissions)
+	authz.AssertExpectations(t)
+
+	// Tokens aren't issued when the roles can't be read
+	authz.On("GetAuthorization", mock.Anything, "user456").Return(nil, assert.AnError)
+	_, err = service.generateAccessToken(context.Background(), "user456", nil)
+	assert.Error(t, err)
+}
+
+func TestPasetoService_InvalidateAllSessions(t *testing.T) {
+	// Setup
+	cfg := createTestConfig()
+	cacheSvc := cache.NewMemoryService()
+	defer cacheSvc.Close()
+
+	service, err := NewPasetoService(cfg, new(mockUserService), new(mockEmailService), cacheSvc)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	accessToken, err := service.generateAccessToken(ctx, "user123", nil)
+	assert.NoError(t, err)
+	refreshToken, err := service.generateToken("user123", "refresh", cfg.RefreshTokenTTL)
+	assert.NoError(t, err)
+	assert.NoError(t, cacheSvc.StoreSession(ctx, refreshToken, "user123", cfg.RefreshTokenTTL))
+	otherToken, err := service.generateAccessToken(ctx, "user456", nil)
+	assert.NoError(t, err)
+
+	// Execute
+	assert.NoError(t, service.InvalidateAllSessions(ctx, "user123"))
+
+	// Assert
+	_, err = service.ValidateSession(ctx, accessToken)
+	assert.Error(t, err)
+	_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: refreshToken})
+	assert.Error(t, err)
+
+	// Other users and tokens issued afterwards are still valid
+	_, err = service.ValidateSession(ctx, otherToken)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	accessToken, err = service.generateAccessToken(ctx, "user123", nil)
+	assert.NoError(t, err)
+	_, err = service.ValidateSession(ctx, accessToken)
+	assert.NoError(t, err)
+}
+
+func TestPasetoService_Impersonation(t *testing.T) {
+	// Setup
+	cfg := createTestConfig()
+	cfg.ImpersonationTTL = 10 * time.Minute
+	cacheSvc := cache.NewMemoryService()
+	defer cacheSvc.Close()
+
+	service, err := NewPasetoService(cfg, new(mockUserService), new(mockEmailService), cacheSvc)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Execute
+	token, impersonation, err := service.StartImpersonation(ctx, "user123", "admin456", "Support ticket")
+	assert.NoError(t, err)
+
+	// Assert
+	session, err := service.ValidateSession(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user123", session.UserID)
+	assert.Equal(t, "admin456", session.ImpersonatorID)
+
+	// The token can't be refreshed
+	_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: token})
+	assert.Error(t, err)
+
+	// Stopping the impersonation revokes the token
+	stopped, err := service.StopImpersonation(ctx, impersonation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Support ticket", stopped.Reason)
+	_, err = service.ValidateSession(ctx, token)
+	assert.Error(t, err)
+	_, err = service.StopImpersonation(ctx, impersonation.ID)
+	assert.Error(t, err)
+}
+
+// fakeMemberships is an in-memory Memberships keyed by organization and user
+type fakeMemberships map[string]*model.Membership
+
+func (f fakeMemberships) GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	return f[orgID+"/"+userID], nil
+}
+
+func TestPasetoService_SwitchOrganization(t *testing.T) {
+	// Setup
+	cfg := createTestConfig()
+	cacheSvc := cache.NewMemoryService()
+	defer cacheSvc.Close()
+
+	service, err := NewPasetoService(cfg, new(mockUserService), new(mockEmailService), cacheSvc)
+	assert.NoError(t, err)
+	memberships := fakeMemberships{
+		"org1/user123": {OrganizationID: "org1", UserID: "user123", Role: model.OrgRoleAdmin},
+	}
+	service.SetMemberships(memberships)
+
+	ctx := context.Background()
+
+	// Execute
+	tokens, err := service.SwitchOrganization(ctx, "user123", "org1")
+	assert.NoError(t, err)
+
+	// Assert
+	session, err := service.ValidateSession(ctx, tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "org1", session.OrgID)
+	assert.Equal(t, model.OrgRoleAdmin, session.OrgRole)
+
+	// The organization is kept when the tokens are refreshed
+	refreshed, err := service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	assert.NoError(t, err)
+	session, err = service.ValidateSession(ctx, refreshed.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "org1", session.OrgID)
+
+	// Users who left the organization are back in their personal account
+	delete(memberships, "org1/user123")
+	refreshed, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+	assert.NoError(t, err)
+	session, err = service.ValidateSession(ctx, refreshed.AccessToken)
+	assert.NoError(t, err)
+	assert.Empty(t, session.OrgID)
+
+	// Users can't switch to organizations they aren't a member of
+	_, err = service.SwitchOrganization(ctx, "user123", "org2")
+	assert.Error(t, err)
+
+	// Switching to no organization is the personal account
+	tokens, err = service.SwitchOrganization(ctx, "user123", "")
+	assert.NoError(t, err)
+	session, err = service.ValidateSession(ctx, tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Empty(t, session.OrgID)
+}
+
+// fakeAPIKeys accepts the API keys it has, by key
+type fakeAPIKeys map[string]*model.APIKey
+
+func (f fakeAPIKeys) ValidateAPIKey(ctx context.Context, apiKey string) (*model.APIKey, error) {
+	key, ok := f[apiKey]
+	if !ok {
+		return nil, errors.NewAuthenticationError("invalid API key")
+	}
+	return key, nil
+}
+
+func TestPasetoService_ValidateSessionWithAPIKey(t *testing.T) {
+	// Setup
+	service, err := NewPasetoService(createTestConfig(), new(mockUserService), new(mockEmailService), new(mockCacheService))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	apiKey := model.APIKeyPrefix + "0123456789abcdef_secret"
+
+	// API keys aren't accepted without an API key validator
+	_, err = service.ValidateSession(ctx, apiKey)
+	assert.Error(t, err)
+
+	service.SetAPIKeys(fakeAPIKeys{
+		apiKey: {ID: "key1", UserID: "user123", Scopes: []string{model.APIKeyScopeRead}},
+	})
+
+	// Execute
+	session, err := service.ValidateSession(ctx, apiKey)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "user123", session.UserID)
+	assert.Equal(t, "key1", session.APIKeyID)
+	assert.Equal(t, []string{model.APIKeyScopeRead}, session.Scopes)
+
+	_, err = service.ValidateSession(ctx, model.APIKeyPrefix+"unknown")
+	assert.Error(t, err)
+}
+
+func TestPasetoService_IssueServiceToken(t *testing.T) {
+	// Setup
+	cacheSvc := cache.NewMemoryService()
+	defer cacheSvc.Close()
+
+	service, err := NewPasetoService(createTestConfig(), new(mockUserService), new(mockEmailService), cacheSvc)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	orgID := "org1"
+	account := &model.ServiceAccount{ID: "sa1", OrganizationID: &orgID, Role: model.OrgRoleMember}
+
+	// Execute
+	token, err := service.IssueServiceToken(ctx, account)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute)
+
+	// Assert
+	session, err := service.ValidateSession(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "sa1", session.ServiceAccountID)
+	assert.Empty(t, session.UserID)
+	assert.Equal(t, "org1", session.OrgID)
+	assert.Equal(t, model.OrgRoleMember, session.OrgRole)
+
+	// Admin service accounts carry their scopes as permissions
+	token, err = service.IssueServiceToken(ctx, &model.ServiceAccount{ID: "sa2", Scopes: []string{model.PermissionUsersRead}})
+	assert.NoError(t, err)
+	session, err = service.ValidateSession(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{model.PermissionUsersRead}, session.Permissions)
+	assert.Empty(t, session.OrgID)
+
+	// Tokens stop working once the service account's sessions are invalidated
+	assert.NoError(t, service.InvalidateAllSessions(ctx, "sa2"))
+	_, err = service.ValidateSession(ctx, token.AccessToken)
+	assert.Error(t, err)
+}
@@ -0,0 +1,472 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock that only moves when advanced
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestMemoryService creates a MemoryService running on a fake clock
+func newTestMemoryService(t *testing.T) (*MemoryService, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryService()
+	s.now = clock.Now
+	t.Cleanup(func() { s.Close() })
+	return s, clock
+}
+
+func TestMemoryService(t *testing.T) {
+	testCacheService(t, func(t *testing.T) (service.LockingCacheService, func(time.Duration)) {
+		s, clock := newTestMemoryService(t)
+		return s, clock.Advance
+	})
+}
+
+// TestRedisService runs the contract tests against the Redis server at
+// REDIS_TEST_URL. The server is flushed before every test.
+func TestRedisService(t *testing.T) {
+	url := os.Getenv("REDIS_TEST_URL")
+	if url == "" {
+		t.Skip("REDIS_TEST_URL is not set")
+	}
+
+	testCacheService(t, func(t *testing.T) (service.LockingCacheService, func(time.Duration)) {
+		s, err := NewRedisService(&config.RedisConfig{URL: url})
+		require.NoError(t, err)
+		require.NoError(t, s.FlushAll(context.Background()))
+		return s, time.Sleep
+	})
+}
+
+// testCacheService checks that a cache service behaves like the CacheService
+// contract expects. newService returns an empty service and a function that
+// lets time pass for it.
+func testCacheService(t *testing.T, newService func(t *testing.T) (service.LockingCacheService, func(time.Duration))) {
+	ctx := context.Background()
+
+	t.Run("rate limit", func(t *testing.T) {
+		s, advance := newService(t)
+
+		for i := 0; i < 3; i++ {
+			allowed, err := s.CheckRateLimit(ctx, "rate:login:1.2.3.4", 3, 1)
+			require.NoError(t, err)
+			assert.True(t, allowed, "request %d", i+1)
+		}
+		allowed, err := s.CheckRateLimit(ctx, "rate:login:1.2.3.4", 3, 1)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		// Other keys have their own limit
+		allowed, err = s.CheckRateLimit(ctx, "rate:login:5.6.7.8", 3, 1)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		// The limit resets once the window has passed
+		advance(1100 * time.Millisecond)
+		allowed, err = s.CheckRateLimit(ctx, "rate:login:1.2.3.4", 3, 1)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("reset rate limit", func(t *testing.T) {
+		s, _ := newService(t)
+
+		allowed, err := s.CheckRateLimit(ctx, "rate:reset", 1, 60)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		allowed, err = s.CheckRateLimit(ctx, "rate:reset", 1, 60)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		require.NoError(t, s.ResetRateLimit(ctx, "rate:reset"))
+		allowed, err = s.CheckRateLimit(ctx, "rate:reset", 1, 60)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("rate limit replaces fixed window counters", func(t *testing.T) {
+		s, _ := newService(t)
+
+		require.NoError(t, s.CacheData(ctx, "rate:counter", 5, 60))
+		allowed, err := s.CheckRateLimit(ctx, "rate:counter", 1, 60)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("sliding window", func(t *testing.T) {
+		s, advance := newService(t)
+		key := "rate:sliding"
+
+		result, err := s.SlidingWindowRateLimit(ctx, key, 2, time.Second)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2, result.Limit)
+		assert.Equal(t, 1, result.Remaining)
+		assert.InDelta(t, time.Second, result.ResetAfter, float64(50*time.Millisecond))
+		assert.Zero(t, result.RetryAfter)
+
+		advance(500 * time.Millisecond)
+		result, err = s.SlidingWindowRateLimit(ctx, key, 2, time.Second)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		// Another request is allowed when the first leaves the window, and
+		// the full limit once the second does
+		assert.InDelta(t, 500*time.Millisecond, result.RetryAfter, float64(50*time.Millisecond))
+		assert.InDelta(t, time.Second, result.ResetAfter, float64(50*time.Millisecond))
+
+		result, err = s.SlidingWindowRateLimit(ctx, key, 2, time.Second)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.InDelta(t, 500*time.Millisecond, result.RetryAfter, float64(50*time.Millisecond))
+
+		// Unlike a fixed window, the window slides, and denied requests don't
+		// push it back
+		advance(600 * time.Millisecond)
+		result, err = s.SlidingWindowRateLimit(ctx, key, 2, time.Second)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+
+		result, err = s.SlidingWindowRateLimit(ctx, key, 2, time.Second)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	})
+
+	t.Run("token bucket", func(t *testing.T) {
+		s, advance := newService(t)
+		key := "rate:bucket"
+
+		// The bucket starts full, so a burst of its capacity is allowed
+		for i := 0; i < 4; i++ {
+			result, err := s.TokenBucketRateLimit(ctx, key, 4, 2*time.Second)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "request %d", i+1)
+			assert.Equal(t, 4, result.Limit)
+			assert.Equal(t, 3-i, result.Remaining)
+		}
+
+		result, err := s.TokenBucketRateLimit(ctx, key, 4, 2*time.Second)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		// One token is added every 500ms, and the bucket is full after 2s
+		assert.InDelta(t, 500*time.Millisecond, result.RetryAfter, float64(50*time.Millisecond))
+		assert.InDelta(t, 2*time.Second, result.ResetAfter, float64(50*time.Millisecond))
+
+		advance(550 * time.Millisecond)
+		result, err = s.TokenBucketRateLimit(ctx, key, 4, 2*time.Second)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		result, err = s.TokenBucketRateLimit(ctx, key, 4, 2*time.Second)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+
+		// Reset refills the bucket
+		require.NoError(t, s.ResetRateLimit(ctx, key))
+		result, err = s.TokenBucketRateLimit(ctx, key, 4, 2*time.Second)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Remaining)
+	})
+
+	t.Run("rate limit algorithms don't share keys", func(t *testing.T) {
+		s, _ := newService(t)
+
+		_, err := s.TokenBucketRateLimit(ctx, "rate:shared", 4, time.Second)
+		require.NoError(t, err)
+		_, err = s.SlidingWindowRateLimit(ctx, "rate:shared", 4, time.Second)
+		assert.Error(t, err)
+	})
+
+	t.Run("rate limit that allows nothing", func(t *testing.T) {
+		s, _ := newService(t)
+
+		result, err := s.SlidingWindowRateLimit(ctx, "rate:none", 0, time.Second)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+
+		_, err = s.TokenBucketRateLimit(ctx, "rate:none", 1, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("cache data", func(t *testing.T) {
+		s, advance := newService(t)
+
+		type user struct {
+			ID    string `json:"id"`
+			Email string `json:"email"`
+		}
+		require.NoError(t, s.CacheData(ctx, "user:1", user{ID: "1", Email: "user@example.com"}, 1))
+		require.NoError(t, s.CacheData(ctx, "user:2", user{ID: "2", Email: "other@example.com"}, 0))
+
+		var got user
+		require.NoError(t, s.GetCachedData(ctx, "user:1", &got))
+		assert.Equal(t, user{ID: "1", Email: "user@example.com"}, got)
+
+		// Misses leave dest untouched
+		missed := user{ID: "unchanged"}
+		require.NoError(t, s.GetCachedData(ctx, "user:missing", &missed))
+		assert.Equal(t, user{ID: "unchanged"}, missed)
+
+		// Data expires after its TTL, unless it has none
+		advance(1100 * time.Millisecond)
+		missed = user{ID: "unchanged"}
+		require.NoError(t, s.GetCachedData(ctx, "user:1", &missed))
+		assert.Equal(t, user{ID: "unchanged"}, missed)
+
+		got = user{}
+		require.NoError(t, s.GetCachedData(ctx, "user:2", &got))
+		assert.Equal(t, "2", got.ID)
+
+		// Cached data that doesn't fit dest is an error
+		var wrong []int
+		assert.Error(t, s.GetCachedData(ctx, "user:2", &wrong))
+	})
+
+	t.Run("invalidate cache", func(t *testing.T) {
+		s, _ := newService(t)
+
+		require.NoError(t, s.CacheData(ctx, "user:1", "one", 60))
+		require.NoError(t, s.InvalidateCache(ctx, "user:1"))
+		require.NoError(t, s.InvalidateCache(ctx, "user:missing"))
+
+		got := "unchanged"
+		require.NoError(t, s.GetCachedData(ctx, "user:1", &got))
+		assert.Equal(t, "unchanged", got)
+	})
+
+	t.Run("invalidate cache pattern", func(t *testing.T) {
+		s, _ := newService(t)
+
+		keys := []string{"user:1", "user:2", "user:10", "users:list", "org:1", "user:1:profile"}
+		for _, key := range keys {
+			require.NoError(t, s.CacheData(ctx, key, key, 60))
+		}
+
+		require.NoError(t, s.InvalidateCachePattern(ctx, "user:?"))
+		assertCached(t, s, map[string]bool{
+			"user:1": false, "user:2": false, "user:10": true, "users:list": true, "org:1": true, "user:1:profile": true,
+		})
+
+		require.NoError(t, s.InvalidateCachePattern(ctx, "user*"))
+		assertCached(t, s, map[string]bool{
+			"user:10": false, "users:list": false, "org:1": true, "user:1:profile": false,
+		})
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		s, advance := newService(t)
+
+		require.NoError(t, s.StoreSession(ctx, "session-1", "user-1", time.Second))
+		require.NoError(t, s.StoreSession(ctx, "session-2", "user-2", time.Minute))
+
+		userID, err := s.GetSession(ctx, "session-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+
+		// Sessions share the keyspace under the session: prefix
+		require.NoError(t, s.InvalidateCachePattern(ctx, "session:session-2"))
+		userID, err = s.GetSession(ctx, "session-2")
+		require.NoError(t, err)
+		assert.Empty(t, userID)
+
+		// Sessions expire
+		advance(1100 * time.Millisecond)
+		userID, err = s.GetSession(ctx, "session-1")
+		require.NoError(t, err)
+		assert.Empty(t, userID)
+
+		require.NoError(t, s.StoreSession(ctx, "session-3", "user-3", time.Minute))
+		require.NoError(t, s.InvalidateSession(ctx, "session-3"))
+		userID, err = s.GetSession(ctx, "session-3")
+		require.NoError(t, err)
+		assert.Empty(t, userID)
+	})
+
+	t.Run("locks", func(t *testing.T) {
+		s, _ := newService(t)
+
+		first, err := s.TryLock(ctx, "job:cleanup", time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, first)
+		assert.Equal(t, "job:cleanup", first.Key())
+
+		// The key is locked until the lock is released
+		other, err := s.TryLock(ctx, "job:cleanup", time.Second)
+		require.NoError(t, err)
+		assert.Nil(t, other)
+		other, err = s.TryLock(ctx, "job:digest", time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		require.NoError(t, other.Release(ctx))
+
+		require.NoError(t, first.Release(ctx))
+		require.NoError(t, first.Release(ctx))
+
+		// Every lock gets a greater fencing token
+		second, err := s.TryLock(ctx, "job:cleanup", time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, second)
+		assert.Greater(t, second.Token(), first.Token())
+		require.NoError(t, second.Release(ctx))
+
+		_, err = s.TryLock(ctx, "job:cleanup", time.Millisecond)
+		assert.Error(t, err)
+	})
+
+	t.Run("lost locks", func(t *testing.T) {
+		s, _ := newService(t)
+
+		first, err := s.TryLock(ctx, "job:cleanup", 300*time.Millisecond)
+		require.NoError(t, err)
+		require.NotNil(t, first)
+
+		// Someone takes over the lock after it disappeared
+		require.NoError(t, s.InvalidateCache(ctx, "lock:job:cleanup"))
+		second, err := s.TryLock(ctx, "job:cleanup", 300*time.Millisecond)
+		require.NoError(t, err)
+		require.NotNil(t, second)
+		assert.Greater(t, second.Token(), first.Token())
+
+		// The first holder finds out when renewing its lock
+		assert.Eventually(t, func() bool {
+			select {
+			case <-first.Lost():
+				return true
+			default:
+				return false
+			}
+		}, time.Second, 10*time.Millisecond)
+
+		// And releasing it leaves the key to the new holder
+		require.NoError(t, first.Release(ctx))
+		other, err := s.TryLock(ctx, "job:cleanup", 300*time.Millisecond)
+		require.NoError(t, err)
+		assert.Nil(t, other)
+
+		select {
+		case <-second.Lost():
+			t.Fatal("second lock was lost")
+		default:
+		}
+		require.NoError(t, second.Release(ctx))
+	})
+}
+
+// assertCached asserts whether each key is cached, with its name as value
+func assertCached(t *testing.T, s service.CacheService, keys map[string]bool) {
+	t.Helper()
+	for key, cached := range keys {
+		var got string
+		require.NoError(t, s.GetCachedData(context.Background(), key, &got))
+		if cached {
+			assert.Equal(t, key, got, key)
+		} else {
+			assert.Empty(t, got, key)
+		}
+	}
+}
+
+func TestMemoryServiceSweep(t *testing.T) {
+	s, clock := newTestMemoryService(t)
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, s.CacheData(ctx, fmt.Sprintf("expiring:%d", i), i, 1))
+	}
+	require.NoError(t, s.CacheData(ctx, "kept", "kept", 0))
+
+	clock.Advance(2 * time.Second)
+	s.sweep()
+
+	count := 0
+	for _, shard := range s.shards {
+		count += len(shard.items)
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestMemoryServiceConcurrency(t *testing.T) {
+	s, _ := newTestMemoryService(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	allowed := make(chan bool, 200)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.CheckRateLimit(ctx, "rate:concurrent", 50, 60)
+			assert.NoError(t, err)
+			allowed <- ok
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	assert.Equal(t, 50, count)
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "users:1", false},
+		{"*:profile", "user:1:profile", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"session:*", "session:abc", true},
+		{"", "", true},
+		{"", "a", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchPattern(tt.pattern, tt.key), "%q %q", tt.pattern, tt.key)
+	}
+}
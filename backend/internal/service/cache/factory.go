@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/service"
+)
+
+// Cache backends that can be selected with CACHE_BACKEND
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// NewService creates the cache service selected by the configuration. Redis is
+// used when no backend is configured. Locks of the memory backend only
+// exclude the tasks of a single process.
+func NewService(cfg *config.RedisConfig) (service.LockingCacheService, error) {
+	switch cfg.Backend {
+	case BackendRedis, "":
+		return NewRedisService(cfg)
+	case BackendMemory:
+		return NewMemoryService(), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", cfg.Backend)
+	}
+}
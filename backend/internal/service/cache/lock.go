@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/service"
+)
+
+// minLockTTL is the shortest TTL a lock can have, so it can be renewed
+// several times before it expires
+const minLockTTL = 10 * time.Millisecond
+
+// lockKey returns the key holding the fencing token of the current holder of
+// a lock
+func lockKey(key string) string {
+	return "lock:" + key
+}
+
+// lockFenceKey returns the key counting the fencing tokens of a lock. It never
+// expires, so tokens keep growing after a lock is released.
+func lockFenceKey(key string) string {
+	return "lock_fence:" + key
+}
+
+// lockStore is the storage of locks, implemented by every cache service
+type lockStore interface {
+	// acquireLock locks a key for ttl and returns its fencing token, or zero if
+	// the key is already locked
+	acquireLock(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// renewLock extends the lock with the token to ttl, reporting false when
+	// the lock isn't held with the token anymore
+	renewLock(ctx context.Context, key string, token int64, ttl time.Duration) (bool, error)
+	// releaseLock releases the lock if it is still held with the token
+	releaseLock(ctx context.Context, key string, token int64) error
+}
+
+// tryLock locks a key of a store and starts renewing the lock
+func tryLock(ctx context.Context, store lockStore, key string, ttl time.Duration) (service.Lock, error) {
+	if ttl < minLockTTL {
+		return nil, fmt.Errorf("invalid lock TTL: %s", ttl)
+	}
+
+	token, err := store.acquireLock(ctx, key, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if token == 0 {
+		return nil, nil
+	}
+
+	l := &heldLock{
+		store: store,
+		key:   key,
+		token: token,
+		ttl:   ttl,
+		lost:  make(chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go l.renewLoop()
+
+	return l, nil
+}
+
+// heldLock is a lock renewed every third of its TTL. It is lost when a
+// renewal finds another holder, or when it couldn't be renewed for a whole
+// TTL, after which it may have expired.
+type heldLock struct {
+	store lockStore
+	key   string
+	token int64
+	ttl   time.Duration
+	lost  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (l *heldLock) Key() string {
+	return l.key
+}
+
+func (l *heldLock) Token() int64 {
+	return l.token
+}
+
+func (l *heldLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing the lock and releases it. Releasing a lock that was
+// lost, or releasing it twice, leaves the key to its current holder.
+func (l *heldLock) Release(ctx context.Context) error {
+	l.once.Do(func() {
+		close(l.stop)
+	})
+	<-l.done
+
+	if err := l.store.releaseLock(ctx, l.key, l.token); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// renewLoop renews the lock until it is released or lost
+func (l *heldLock) renewLoop() {
+	defer close(l.done)
+
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		held, err := l.store.renewLock(ctx, l.key, l.token, l.ttl)
+		cancel()
+
+		switch {
+		case err == nil && held:
+			renewed = time.Now()
+		case err == nil, time.Since(renewed) >= l.ttl:
+			// Someone else holds the lock, or it may have expired
+			close(l.lost)
+			return
+		}
+	}
+}
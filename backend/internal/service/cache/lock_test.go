@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLockStore is a lock store whose renewals can be made to fail
+type fakeLockStore struct {
+	mu       sync.Mutex
+	renewals int
+	renewErr error
+	released bool
+}
+
+func (f *fakeLockStore) acquireLock(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeLockStore) renewLock(ctx context.Context, key string, token int64, ttl time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.renewals++
+	return f.renewErr == nil, f.renewErr
+}
+
+func (f *fakeLockStore) releaseLock(ctx context.Context, key string, token int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = true
+	return nil
+}
+
+func (f *fakeLockStore) setRenewErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.renewErr = err
+}
+
+func TestHeldLockRenewal(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeLockStore{}
+
+	lock, err := tryLock(ctx, store, "job", 60*time.Millisecond)
+	require.NoError(t, err)
+
+	// The lock is renewed every third of its TTL while it is held
+	time.Sleep(200 * time.Millisecond)
+	store.mu.Lock()
+	assert.GreaterOrEqual(t, store.renewals, 3)
+	store.mu.Unlock()
+
+	// Failed renewals are retried until the lock may have expired
+	store.setRenewErr(errors.New("connection refused"))
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock wasn't lost")
+	}
+
+	require.NoError(t, lock.Release(ctx))
+	assert.True(t, store.released)
+}
+
+func TestHeldLockRelease(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeLockStore{}
+
+	lock, err := tryLock(ctx, store, "job", 30*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release(ctx))
+
+	// Released locks are not renewed anymore
+	store.mu.Lock()
+	renewals := store.renewals
+	store.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	store.mu.Lock()
+	assert.Equal(t, renewals, store.renewals)
+	store.mu.Unlock()
+
+	select {
+	case <-lock.Lost():
+		t.Fatal("released lock was lost")
+	default:
+	}
+}
@@ -0,0 +1,526 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
+)
+
+const (
+	// memoryShards is the number of independently locked maps a MemoryService
+	// spreads its keys over, so concurrent requests rarely wait on each other
+	memoryShards = 32
+	// memorySweepInterval is how often expired keys are removed. Expired keys
+	// are never returned, sweeping only frees their memory.
+	memorySweepInterval = time.Minute
+)
+
+// memoryItem is a value stored by a MemoryService. Like a Redis key, it holds
+// either a value, the log of a sliding window or a token bucket.
+type memoryItem struct {
+	value  []byte
+	log    []time.Time
+	bucket *memoryBucket
+	// Zero when the item never expires
+	expiresAt time.Time
+}
+
+// memoryBucket is the state of a token bucket
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// expired reports whether the item has expired at now
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+// memoryShard is one of the maps of a MemoryService
+type memoryShard struct {
+	mu    sync.Mutex
+	items map[string]*memoryItem
+}
+
+// MemoryService implements the CacheService interface in process memory. It
+// behaves like RedisService, keys included, but is not shared between
+// processes, so it only suits a single API instance, development and tests.
+type MemoryService struct {
+	shards [memoryShards]*memoryShard
+	now    func() time.Time
+	stop   chan struct{}
+	once   sync.Once
+
+	// Serializes lock operations, which span the shards of two keys
+	locks sync.Mutex
+}
+
+// NewMemoryService creates a new MemoryService, which sweeps expired keys in
+// the background until it is closed
+func NewMemoryService() *MemoryService {
+	s := &MemoryService{
+		now:  time.Now,
+		stop: make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{items: make(map[string]*memoryItem)}
+	}
+
+	go s.sweepLoop(memorySweepInterval)
+
+	return s
+}
+
+// Rate limiting
+func (s *MemoryService) CheckRateLimit(ctx context.Context, key string, limit int, duration int) (bool, error) {
+	result, err := s.SlidingWindowRateLimit(ctx, key, limit, time.Duration(duration)*time.Second)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// SlidingWindowRateLimit allows up to limit requests in any window of time,
+// like RedisService.SlidingWindowRateLimit
+func (s *MemoryService) SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error) {
+	if result, err := checkRateLimitArgs(limit, window); result != nil || err != nil {
+		return result, err
+	}
+
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := s.now()
+	item := shard.get(key, now)
+	if item != nil && item.bucket != nil {
+		return nil, fmt.Errorf("failed to check rate limit: key holds a token bucket")
+	}
+	var log []time.Time
+	if item != nil {
+		log = item.log
+	}
+
+	// Drop the requests that left the window
+	start := 0
+	for start < len(log) && !log[start].Add(window).After(now) {
+		start++
+	}
+	log = log[start:]
+
+	result := &model.RateLimitResult{Limit: limit}
+	if len(log) < limit {
+		log = append(log, now)
+		result.Allowed = true
+	}
+	result.Remaining = limit - len(log)
+
+	if len(log) == 0 {
+		shard.delete(key)
+		return result, nil
+	}
+	result.ResetAfter = log[len(log)-1].Add(window).Sub(now)
+	if len(log) >= limit {
+		result.RetryAfter = log[0].Add(window).Sub(now)
+	}
+
+	shard.items[key] = &memoryItem{
+		log:       log,
+		expiresAt: now.Add(result.ResetAfter),
+	}
+
+	return result, nil
+}
+
+// TokenBucketRateLimit allows bursts of up to capacity requests, refilling
+// capacity requests every window, like RedisService.TokenBucketRateLimit
+func (s *MemoryService) TokenBucketRateLimit(ctx context.Context, key string, capacity int, window time.Duration) (*model.RateLimitResult, error) {
+	if result, err := checkRateLimitArgs(capacity, window); result != nil || err != nil {
+		return result, err
+	}
+
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := s.now()
+	item := shard.get(key, now)
+	if item != nil && item.log != nil {
+		return nil, fmt.Errorf("failed to check rate limit: key holds a sliding window")
+	}
+
+	// Tokens per nanosecond
+	rate := float64(capacity) / float64(window)
+	tokens := float64(capacity)
+	if item != nil && item.bucket != nil {
+		elapsed := now.Sub(item.bucket.updatedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(float64(capacity), item.bucket.tokens+float64(elapsed)*rate)
+	}
+
+	result := &model.RateLimitResult{Limit: capacity}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = time.Duration(math.Ceil((float64(capacity) - tokens) / rate))
+	if tokens < 1 {
+		result.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+	}
+
+	shard.items[key] = &memoryItem{
+		bucket:    &memoryBucket{tokens: tokens, updatedAt: now},
+		expiresAt: now.Add(max(result.ResetAfter, time.Millisecond)),
+	}
+
+	return result, nil
+}
+
+func (s *MemoryService) ResetRateLimit(ctx context.Context, key string) error {
+	s.delete(key)
+	return nil
+}
+
+// Caching
+func (s *MemoryService) CacheData(ctx context.Context, key string, data interface{}, ttl int) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	s.set(key, jsonData, time.Duration(ttl)*time.Second)
+	return nil
+}
+
+func (s *MemoryService) GetCachedData(ctx context.Context, key string, dest interface{}) error {
+	data, ok := s.get(key)
+	if !ok {
+		return nil // Key doesn't exist
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal cached data: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MemoryService) InvalidateCache(ctx context.Context, key string) error {
+	s.delete(key)
+	return nil
+}
+
+// InvalidateCachePattern deletes the keys matching a Redis glob pattern
+func (s *MemoryService) InvalidateCachePattern(ctx context.Context, pattern string) error {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		for key := range shard.items {
+			if matchPattern(pattern, key) {
+				delete(shard.items, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return nil
+}
+
+// Session management
+func (s *MemoryService) StoreSession(ctx context.Context, sessionID string, userID string, expiration time.Duration) error {
+	s.set(fmt.Sprintf("session:%s", sessionID), []byte(userID), expiration)
+	return nil
+}
+
+func (s *MemoryService) GetSession(ctx context.Context, sessionID string) (string, error) {
+	userID, ok := s.get(fmt.Sprintf("session:%s", sessionID))
+	if !ok {
+		return "", nil
+	}
+	return string(userID), nil
+}
+
+func (s *MemoryService) InvalidateSession(ctx context.Context, sessionID string) error {
+	s.delete(fmt.Sprintf("session:%s", sessionID))
+	return nil
+}
+
+// Locking
+
+// TryLock locks a key for ttl, renewing the lock until it is released, like
+// RedisService.TryLock
+func (s *MemoryService) TryLock(ctx context.Context, key string, ttl time.Duration) (service.Lock, error) {
+	return tryLock(ctx, s, key, ttl)
+}
+
+func (s *MemoryService) acquireLock(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.locks.Lock()
+	defer s.locks.Unlock()
+
+	if _, ok := s.get(lockKey(key)); ok {
+		return 0, nil
+	}
+
+	var token int64
+	if fence, ok := s.get(lockFenceKey(key)); ok {
+		last, err := strconv.ParseInt(string(fence), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid fencing token: %w", err)
+		}
+		token = last
+	}
+	token++
+
+	value := []byte(strconv.FormatInt(token, 10))
+	s.set(lockFenceKey(key), value, 0)
+	s.set(lockKey(key), value, ttl)
+
+	return token, nil
+}
+
+func (s *MemoryService) renewLock(ctx context.Context, key string, token int64, ttl time.Duration) (bool, error) {
+	s.locks.Lock()
+	defer s.locks.Unlock()
+
+	value, ok := s.get(lockKey(key))
+	if !ok || string(value) != strconv.FormatInt(token, 10) {
+		return false, nil
+	}
+	s.set(lockKey(key), value, ttl)
+
+	return true, nil
+}
+
+func (s *MemoryService) releaseLock(ctx context.Context, key string, token int64) error {
+	s.locks.Lock()
+	defer s.locks.Unlock()
+
+	if value, ok := s.get(lockKey(key)); ok && string(value) == strconv.FormatInt(token, 10) {
+		s.delete(lockKey(key))
+	}
+
+	return nil
+}
+
+// Cache operations
+func (s *MemoryService) FlushAll(ctx context.Context) error {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		shard.items = make(map[string]*memoryItem)
+		shard.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *MemoryService) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops sweeping expired keys
+func (s *MemoryService) Close() error {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	return nil
+}
+
+// shard returns the shard that holds a key
+func (s *MemoryService) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%memoryShards]
+}
+
+// get returns the value of a key that has not expired
+func (s *MemoryService) get(key string) ([]byte, bool) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item := shard.get(key, s.now())
+	if item == nil {
+		return nil, false
+	}
+	return item.value, true
+}
+
+// set stores the value of a key, which never expires if ttl is not positive
+func (s *MemoryService) set(key string, value []byte, ttl time.Duration) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.items[key] = &memoryItem{
+		value:     value,
+		expiresAt: expiresAt(s.now(), ttl),
+	}
+}
+
+// delete removes a key
+func (s *MemoryService) delete(key string) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	delete(shard.items, key)
+}
+
+// sweepLoop sweeps expired keys every interval until the service is closed
+func (s *MemoryService) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+// sweep removes expired keys, one shard at a time so requests are never
+// blocked on the whole cache
+func (s *MemoryService) sweep() {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		now := s.now()
+		for key, item := range shard.items {
+			if item.expired(now) {
+				delete(shard.items, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// get returns the item of a key that has not expired, deleting it if it has.
+// The shard must be locked.
+func (sh *memoryShard) get(key string, now time.Time) *memoryItem {
+	item, ok := sh.items[key]
+	if !ok {
+		return nil
+	}
+	if item.expired(now) {
+		delete(sh.items, key)
+		return nil
+	}
+	return item
+}
+
+// delete removes a key. The shard must be locked.
+func (sh *memoryShard) delete(key string) {
+	delete(sh.items, key)
+}
+
+// expiresAt returns when an item stored at now with a TTL expires, or the zero
+// time if the TTL is not positive, in which case it never expires like a key
+// set without an expiration in Redis
+func expiresAt(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// matchPattern reports whether key matches a Redis glob pattern, supporting
+// *, ?, [abc], [^abc], [a-z] and \ escapes like Redis's SCAN MATCH
+func matchPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Collapse consecutive stars, then try every split of the key
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchPattern(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			rest, ok := matchClass(pattern[1:], key[0])
+			if !ok {
+				return false
+			}
+			key = key[1:]
+			pattern = rest
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass matches c against the character class at the start of pattern,
+// just after its '[', and returns the pattern after the class
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == c {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+
+	// Like Redis, an unterminated class runs to the end of the pattern
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return pattern, matched != negate
+}
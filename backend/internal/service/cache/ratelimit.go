@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// checkRateLimitArgs checks the limit and window of a rate limit. It returns
+// the result of every request when nothing is ever allowed.
+func checkRateLimitArgs(limit int, window time.Duration) (*model.RateLimitResult, error) {
+	if window < time.Millisecond {
+		return nil, fmt.Errorf("invalid rate limit window: %s", window)
+	}
+	if limit <= 0 {
+		return &model.RateLimitResult{
+			Allowed:    false,
+			Limit:      limit,
+			ResetAfter: window,
+			RetryAfter: window,
+		}, nil
+	}
+	return nil, nil
+}
@@ -7,9 +7,121 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
 )
 
+// slidingWindowScript logs the time of every allowed request in a sorted set,
+// and allows a request when fewer than the limit were made in the window. It
+// uses the clock of the Redis server, so every API instance counts alike.
+var slidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+-- Counters left by the fixed window rate limiter are replaced
+if redis.call('TYPE', key).ok == 'string' then
+	redis.call('DEL', key)
+end
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+
+local reset_after = 0
+local retry_after = 0
+if count > 0 then
+	local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+	reset_after = tonumber(newest[2]) + window - now
+	redis.call('PEXPIRE', key, reset_after)
+	if count >= limit then
+		local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+		retry_after = tonumber(oldest[2]) + window - now
+	end
+end
+
+return {allowed, limit - count, reset_after, retry_after}
+`)
+
+// tokenBucketScript keeps the tokens left in a bucket that refills with
+// capacity tokens every window, and allows a request when it can take a token
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+if redis.call('TYPE', key).ok == 'string' then
+	redis.call('DEL', key)
+end
+
+local rate = capacity / window
+local state = redis.call('HMGET', key, 'tokens', 'updated_at')
+local tokens = tonumber(state[1])
+local updated_at = tonumber(state[2])
+if tokens == nil or updated_at == nil then
+	tokens = capacity
+	updated_at = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - updated_at) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+local reset_after = math.ceil((capacity - tokens) / rate)
+local retry_after = 0
+if tokens < 1 then
+	retry_after = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', key, 'tokens', string.format('%.6f', tokens), 'updated_at', string.format('%d', now))
+redis.call('PEXPIRE', key, math.max(reset_after, 1))
+
+return {allowed, math.floor(tokens), reset_after, retry_after}
+`)
+
+// acquireLockScript locks a key that isn't locked with the next fencing token
+// of the key, and returns the token, or 0 when the key is already locked
+var acquireLockScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], token, 'PX', ARGV[1])
+return token
+`)
+
+// renewLockScript extends a lock that is still held with a fencing token
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript deletes a lock that is still held with a fencing token
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
 type RedisService struct {
 	client *redis.Client
 }
@@ -35,20 +147,55 @@ func NewRedisService(cfg *config.RedisConfig) (*RedisService, error) {
 
 // Rate limiting
 func (s *RedisService) CheckRateLimit(ctx context.Context, key string, limit int, duration int) (bool, error) {
-	pipe := s.client.Pipeline()
+	result, err := s.SlidingWindowRateLimit(ctx, key, limit, time.Duration(duration)*time.Second)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
 
-	// Increment counter
-	incr := pipe.Incr(ctx, key)
-	// Set expiration if key is new
-	pipe.Expire(ctx, key, time.Duration(duration)*time.Second)
+// SlidingWindowRateLimit allows up to limit requests in any window of time.
+// Denied requests aren't counted, so clients that keep retrying get through
+// as soon as earlier requests leave the window.
+func (s *RedisService) SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error) {
+	if result, err := checkRateLimitArgs(limit, window); result != nil || err != nil {
+		return result, err
+	}
 
-	_, err := pipe.Exec(ctx)
+	// Every request needs its own member in the sorted set
+	return s.runRateLimitScript(ctx, slidingWindowScript, key, limit, window, uuid.NewString())
+}
+
+// TokenBucketRateLimit allows bursts of up to capacity requests, refilling
+// capacity requests every window
+func (s *RedisService) TokenBucketRateLimit(ctx context.Context, key string, capacity int, window time.Duration) (*model.RateLimitResult, error) {
+	if result, err := checkRateLimitArgs(capacity, window); result != nil || err != nil {
+		return result, err
+	}
+
+	return s.runRateLimitScript(ctx, tokenBucketScript, key, capacity, window)
+}
+
+// runRateLimitScript runs a rate limiting script, which returns whether the
+// request is allowed, the remaining requests, and how many milliseconds until
+// the limit resets and another request is allowed
+func (s *RedisService) runRateLimitScript(ctx context.Context, script *redis.Script, key string, limit int, window time.Duration, args ...interface{}) (*model.RateLimitResult, error) {
+	args = append([]interface{}{limit, window.Milliseconds()}, args...)
+	values, err := script.Run(ctx, s.client, []string{key}, args...).Int64Slice()
 	if err != nil {
-		return false, fmt.Errorf("failed to check rate limit: %w", err)
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("failed to check rate limit: unexpected script result %v", values)
 	}
 
-	count := incr.Val()
-	return count <= int64(limit), nil
+	return &model.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(values[1]),
+		ResetAfter: time.Duration(values[2]) * time.Millisecond,
+		RetryAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
 }
 
 func (s *RedisService) ResetRateLimit(ctx context.Context, key string) error {
@@ -134,6 +281,30 @@ func (s *RedisService) InvalidateSession(ctx context.Context, sessionID string)
 	return nil
 }
 
+// Locking
+
+// TryLock locks a key for ttl with SET NX semantics, renewing the lock until it
+// is released. Every lock of a key gets a greater fencing token.
+func (s *RedisService) TryLock(ctx context.Context, key string, ttl time.Duration) (service.Lock, error) {
+	return tryLock(ctx, s, key, ttl)
+}
+
+func (s *RedisService) acquireLock(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return acquireLockScript.Run(ctx, s.client, []string{lockKey(key), lockFenceKey(key)}, ttl.Milliseconds()).Int64()
+}
+
+func (s *RedisService) renewLock(ctx context.Context, key string, token int64, ttl time.Duration) (bool, error) {
+	renewed, err := renewLockScript.Run(ctx, s.client, []string{lockKey(key)}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+func (s *RedisService) releaseLock(ctx context.Context, key string, token int64) error {
+	return releaseLockScript.Run(ctx, s.client, []string{lockKey(key)}, token).Err()
+}
+
 // Cache operations
 func (s *RedisService) FlushAll(ctx context.Context) error {
 	if err := s.client.FlushAll(ctx).Err(); err != nil {
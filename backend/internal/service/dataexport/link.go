@@ -0,0 +1,60 @@
+package dataexport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+)
+
+// DownloadToken returns the token of the link to download an export, which
+// works until expiresAt. Changing the secret revokes every link.
+func (s *Service) DownloadToken(exportID string, expiresAt time.Time) string {
+	payload := exportID + ":" + strconv.FormatInt(expiresAt.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// parseDownloadToken verifies a download token and returns the ID of the
+// export it was issued for, as long as it hasn't expired
+func (s *Service) parseDownloadToken(token string, now time.Time) (string, error) {
+	invalid := errors.NewValidationError("invalid download link")
+
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.sign(string(payload))) {
+		return "", invalid
+	}
+
+	exportID, expires, ok := strings.Cut(string(payload), ":")
+	if !ok || exportID == "" {
+		return "", invalid
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", invalid
+	}
+	if !now.Before(time.Unix(expiresAt, 0)) {
+		return "", errors.NewValidationError("download link has expired")
+	}
+
+	return exportID, nil
+}
+
+// sign returns the HMAC of a download token payload
+func (s *Service) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("data_export:" + payload))
+	return mac.Sum(nil)
+}
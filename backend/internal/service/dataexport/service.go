@@ -0,0 +1,355 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// defaultTTL is how long download links work when no TTL is configured
+	defaultTTL = 7 * 24 * time.Hour
+	// exportBatchSize is the number of pending exports built in one run of
+	// the export job
+	exportBatchSize = 10
+	// pageSize is the number of audit log entries and security events read
+	// at once while gathering a user's data
+	pageSize = 500
+)
+
+// Repository defines the interface for data export database operations
+type Repository interface {
+	// CreateExport adds a pending export for a user, or returns nil if the user already has one pending
+	CreateExport(ctx context.Context, userID string) (*model.DataExport, error)
+
+	// GetExport gets an export by ID, or nil if there is none
+	GetExport(ctx context.Context, id string) (*model.DataExport, error)
+
+	// GetArchive gets the archive of a ready export, or nil if there is none
+	GetArchive(ctx context.Context, id string) ([]byte, error)
+
+	// ListPendingExports gets up to limit pending exports, oldest first
+	ListPendingExports(ctx context.Context, limit int) ([]*model.DataExport, error)
+
+	// MarkExportReady stores the archive of a pending export, which can be downloaded until expiresAt
+	MarkExportReady(ctx context.Context, id string, archive []byte, expiresAt, now time.Time) error
+
+	// MarkExportFailed records why the archive of a pending export couldn't be built
+	MarkExportFailed(ctx context.Context, id, message string, now time.Time) error
+
+	// DeleteExpiredExports deletes the exports that expired, and the failed exports, before a time
+	DeleteExpiredExports(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Users defines the interface for reading a user's account
+type Users interface {
+	// GetUser gets a user by ID, or nil if there is none
+	GetUser(ctx context.Context, id string) (*model.AdminUser, error)
+
+	// ListUserSessions gets the active sessions of a user
+	ListUserSessions(ctx context.Context, userID string) ([]*model.UserSession, error)
+
+	// ListLinkedAccounts gets the OAuth accounts linked to a user
+	ListLinkedAccounts(ctx context.Context, userID string) ([]*model.LinkedAccount, error)
+}
+
+// AuditLogs defines the interface for reading the audit log
+type AuditLogs interface {
+	// ListAuditLogs gets a filtered page of entries, newest first
+	ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*models.AuditLog, error)
+}
+
+// SecurityRecords defines the interface for reading a user's security events
+// and login attempts
+type SecurityRecords interface {
+	// ListUserSecurityEvents gets a filtered page of security events for a user
+	ListUserSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) ([]*model.SecurityEvent, error)
+
+	// ListLoginAttempts gets every login attempt of a user, newest first
+	ListLoginAttempts(ctx context.Context, userID string) ([]*model.LoginAttempt, error)
+}
+
+// Service lets users export a copy of their data. Exports are built in the
+// background by ProcessPendingExports, which emails the user a signed link
+// to download the archive until it expires.
+type Service struct {
+	repo      Repository
+	users     Users
+	auditLogs AuditLogs
+	security  SecurityRecords
+	emailSvc  service.EmailService
+	audit     service.AuditLog
+	ttl       time.Duration
+	secret    []byte
+	logger    logger.Logger
+}
+
+// NewService creates a new data export service
+func NewService(repo Repository, users Users, auditLogs AuditLogs, security SecurityRecords, emailSvc service.EmailService, cfg *config.DataExportConfig, audit service.AuditLog, log logger.Logger) *Service {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &Service{
+		repo:      repo,
+		users:     users,
+		auditLogs: auditLogs,
+		security:  security,
+		emailSvc:  emailSvc,
+		audit:     audit,
+		ttl:       ttl,
+		secret:    []byte(cfg.Secret),
+		logger:    log,
+	}
+}
+
+// RequestExport starts an export of a user's data. Users can only have one
+// export being built at a time.
+func (s *Service) RequestExport(ctx context.Context, userID string) (*model.DataExport, error) {
+	export, err := s.repo.CreateExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, errors.NewConflictError("a data export is already being prepared")
+	}
+
+	s.record(ctx, "data_export.requested", export, nil)
+	return export, nil
+}
+
+// ProcessPendingExports builds the archives of a batch of pending exports
+// and emails their download links. It is run periodically as a job.
+func (s *Service) ProcessPendingExports(ctx context.Context) error {
+	exports, err := s.repo.ListPendingExports(ctx, exportBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := s.process(ctx, export); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DownloadExport returns an export and its archive from the token of a
+// download link
+func (s *Service) DownloadExport(ctx context.Context, token string) (*model.DataExport, []byte, error) {
+	exportID, err := s.parseDownloadToken(token, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	export, err := s.repo.GetExport(ctx, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export == nil || export.Status != model.DataExportReady {
+		return nil, nil, errors.NewNotFoundError("data export not found")
+	}
+
+	archive, err := s.repo.GetArchive(ctx, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if archive == nil {
+		return nil, nil, errors.NewNotFoundError("data export not found")
+	}
+
+	s.record(ctx, "data_export.downloaded", export, nil)
+	return export, archive, nil
+}
+
+// DeleteExpiredExports deletes the archives whose download links expired,
+// along with failed exports
+func (s *Service) DeleteExpiredExports(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredExports(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.logger.Info("Deleted expired data exports", "count", deleted)
+	}
+	return nil
+}
+
+// process builds the archive of a pending export and emails its download
+// link. Only failures to record the outcome are returned, so one export
+// failing doesn't hold up the others.
+func (s *Service) process(ctx context.Context, export *model.DataExport) error {
+	log := s.logger.With("data_export_id", export.ID, "user_id", export.UserID)
+
+	user, archive, err := s.buildArchive(ctx, export.UserID)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Stopped before the archive was built, the export stays pending
+			return ctx.Err()
+		}
+
+		log.Error("Failed to build data export", "error", err)
+		if err := s.repo.MarkExportFailed(ctx, export.ID, err.Error(), time.Now()); err != nil {
+			return err
+		}
+		s.record(ctx, "data_export.failed", export, nil)
+		return nil
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+	if err := s.repo.MarkExportReady(ctx, export.ID, archive, expiresAt, now); err != nil {
+		return err
+	}
+	s.record(ctx, "data_export.completed", export, map[string]interface{}{
+		"size_bytes": len(archive),
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+
+	// The link is sent in the user's language
+	emailCtx := i18n.WithLocale(ctx, user.Locale)
+	if err := s.emailSvc.SendDataExportReadyEmail(emailCtx, user.Email, s.DownloadToken(export.ID, expiresAt)); err != nil {
+		log.Error("Failed to send data export email", "error", err)
+	}
+
+	return nil
+}
+
+// buildArchive gathers a user's data into a ZIP with a JSON file for each
+// kind of data, and returns it with the user
+func (s *Service) buildArchive(ctx context.Context, userID string) (*model.AdminUser, []byte, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+
+	sessions, err := s.users.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accounts, err := s.users.ListLinkedAccounts(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	auditLogs, err := s.listAuditLogs(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, err := s.listSecurityEvents(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := s.security.ListLoginAttempts(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"sessions.json", sessions},
+		{"oauth_accounts.json", accounts},
+		{"audit_logs.json", auditLogs},
+		{"security_events.json", events},
+		{"login_attempts.json", attempts},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	modified := time.Now()
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add %s to data export: %w", file.name, err)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to write data export: %w", err)
+	}
+
+	return user, buf.Bytes(), nil
+}
+
+// listAuditLogs gets every audit log entry made by a user, newest first
+func (s *Service) listAuditLogs(ctx context.Context, userID string) ([]*models.AuditLog, error) {
+	logs := []*models.AuditLog{}
+	filter := model.AuditLogFilter{UserID: userID, Limit: pageSize}
+	for {
+		page, err := s.auditLogs.ListAuditLogs(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, page...)
+		if len(page) < pageSize {
+			return logs, nil
+		}
+
+		last := page[len(page)-1]
+		filter.After = &model.AuditLogCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// listSecurityEvents gets every security event of a user, newest first
+func (s *Service) listSecurityEvents(ctx context.Context, userID string) ([]*model.SecurityEvent, error) {
+	events := []*model.SecurityEvent{}
+	filter := model.SecurityEventFilter{Limit: pageSize}
+	for {
+		page, err := s.security.ListUserSecurityEvents(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+		if len(page) < pageSize {
+			return events, nil
+		}
+
+		last := page[len(page)-1]
+		filter.After = &model.SecurityEventCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// record records something that happened to an export in the audit log. The
+// export job and download links act without a user, so the user the export
+// belongs to is kept in the metadata.
+func (s *Service) record(ctx context.Context, action string, export *model.DataExport, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["user_id"] = export.UserID
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     action,
+		EntityType: "data_export",
+		EntityID:   export.ID,
+		Metadata:   metadata,
+	})
+}
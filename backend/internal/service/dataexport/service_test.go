@@ -0,0 +1,322 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository
+type fakeRepository struct {
+	exports  map[string]*model.DataExport
+	archives map[string][]byte
+	nextID   int
+}
+
+func (f *fakeRepository) CreateExport(ctx context.Context, userID string) (*model.DataExport, error) {
+	for _, export := range f.exports {
+		if export.UserID == userID && export.Status == model.DataExportPending {
+			return nil, nil
+		}
+	}
+	f.nextID++
+	export := &model.DataExport{
+		ID:        strconv.Itoa(f.nextID),
+		UserID:    userID,
+		Status:    model.DataExportPending,
+		CreatedAt: time.Now(),
+	}
+	f.exports[export.ID] = export
+	copied := *export
+	return &copied, nil
+}
+
+func (f *fakeRepository) GetExport(ctx context.Context, id string) (*model.DataExport, error) {
+	export, ok := f.exports[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *export
+	return &copied, nil
+}
+
+func (f *fakeRepository) GetArchive(ctx context.Context, id string) ([]byte, error) {
+	return f.archives[id], nil
+}
+
+func (f *fakeRepository) ListPendingExports(ctx context.Context, limit int) ([]*model.DataExport, error) {
+	var exports []*model.DataExport
+	for _, export := range f.exports {
+		if export.Status == model.DataExportPending && len(exports) < limit {
+			copied := *export
+			exports = append(exports, &copied)
+		}
+	}
+	return exports, nil
+}
+
+func (f *fakeRepository) MarkExportReady(ctx context.Context, id string, archive []byte, expiresAt, now time.Time) error {
+	export := f.exports[id]
+	export.Status = model.DataExportReady
+	export.SizeBytes = int64(len(archive))
+	export.ExpiresAt = &expiresAt
+	export.CompletedAt = &now
+	f.archives[id] = archive
+	return nil
+}
+
+func (f *fakeRepository) MarkExportFailed(ctx context.Context, id, message string, now time.Time) error {
+	export := f.exports[id]
+	export.Status = model.DataExportFailed
+	export.Error = &message
+	export.CompletedAt = &now
+	return nil
+}
+
+func (f *fakeRepository) DeleteExpiredExports(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for id, export := range f.exports {
+		if export.ExpiresAt != nil && export.ExpiresAt.Before(before) {
+			delete(f.exports, id)
+			delete(f.archives, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// fakeUserData serves the data of user-1 and fails for other users
+type fakeUserData struct {
+	events []*model.SecurityEvent
+}
+
+func (f *fakeUserData) GetUser(ctx context.Context, id string) (*model.AdminUser, error) {
+	if id != "user-1" {
+		return nil, errors.New("database is unavailable")
+	}
+	return &model.AdminUser{ID: id, Email: "user@example.com", FullName: "Jane Doe", Locale: "fr"}, nil
+}
+
+func (f *fakeUserData) ListUserSessions(ctx context.Context, userID string) ([]*model.UserSession, error) {
+	return []*model.UserSession{{ID: "session-1"}}, nil
+}
+
+func (f *fakeUserData) ListLinkedAccounts(ctx context.Context, userID string) ([]*model.LinkedAccount, error) {
+	return []*model.LinkedAccount{{Provider: "github", ProviderUserID: "42"}}, nil
+}
+
+func (f *fakeUserData) ListAuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*models.AuditLog, error) {
+	return []*models.AuditLog{{ID: "log-1", UserID: filter.UserID, Action: "api.request"}}, nil
+}
+
+func (f *fakeUserData) ListUserSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) ([]*model.SecurityEvent, error) {
+	// Pages through the events with the cursor of the last one
+	start := 0
+	if filter.After != nil {
+		for i, event := range f.events {
+			if event.ID == filter.After.ID {
+				start = i + 1
+			}
+		}
+	}
+	end := start + filter.Limit
+	if end > len(f.events) {
+		end = len(f.events)
+	}
+	return f.events[start:end], nil
+}
+
+func (f *fakeUserData) ListLoginAttempts(ctx context.Context, userID string) ([]*model.LoginAttempt, error) {
+	return []*model.LoginAttempt{{ID: "attempt-1", UserID: userID, Successful: true}}, nil
+}
+
+// fakeEmailService keeps the download tokens it sends
+type fakeEmailService struct {
+	service.EmailService
+	tokens  map[string]string
+	locales map[string]string
+}
+
+func (f *fakeEmailService) SendDataExportReadyEmail(ctx context.Context, to, token string) error {
+	f.tokens[to] = token
+	f.locales[to] = i18n.FromContext(ctx)
+	return nil
+}
+
+func newTestService() (*Service, *fakeRepository, *fakeUserData, *fakeEmailService, *servicetest.AuditLog) {
+	repo := &fakeRepository{exports: map[string]*model.DataExport{}, archives: map[string][]byte{}}
+	data := &fakeUserData{}
+	emails := &fakeEmailService{tokens: map[string]string{}, locales: map[string]string{}}
+	audit := &servicetest.AuditLog{}
+	cfg := &config.DataExportConfig{TTL: time.Hour, Secret: "test-secret"}
+	return NewService(repo, data, data, data, emails, cfg, audit, logger.DefaultLogger()), repo, data, emails, audit
+}
+
+// readArchive returns the files of a ZIP archive
+func readArchive(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[file.Name] = content
+	}
+	return files
+}
+
+func TestRequestExport(t *testing.T) {
+	s, _, _, _, audit := newTestService()
+	ctx := context.Background()
+
+	export, err := s.RequestExport(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, model.DataExportPending, export.Status)
+	assert.Equal(t, []string{"data_export.requested"}, audit.Actions())
+	assert.Equal(t, export.ID, audit.Events[0].EntityID)
+
+	// Only one export is built at a time
+	_, err = s.RequestExport(ctx, "user-1")
+	var appErr *apperrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+}
+
+func TestProcessPendingExports(t *testing.T) {
+	s, repo, data, emails, audit := newTestService()
+	ctx := context.Background()
+
+	// More events than fit in a page
+	for i := 0; i < pageSize+1; i++ {
+		data.events = append(data.events, &model.SecurityEvent{ID: strconv.Itoa(i), UserID: "user-1"})
+	}
+
+	export, err := s.RequestExport(ctx, "user-1")
+	require.NoError(t, err)
+	require.NoError(t, s.ProcessPendingExports(ctx))
+
+	stored := repo.exports[export.ID]
+	assert.Equal(t, model.DataExportReady, stored.Status)
+	require.NotNil(t, stored.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *stored.ExpiresAt, time.Minute)
+
+	files := readArchive(t, repo.archives[export.ID])
+	assert.Len(t, files, 6)
+	for _, name := range []string{"profile.json", "sessions.json", "oauth_accounts.json", "audit_logs.json", "security_events.json", "login_attempts.json"} {
+		assert.Contains(t, files, name)
+	}
+
+	var profile model.AdminUser
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "user@example.com", profile.Email)
+
+	var events []*model.SecurityEvent
+	require.NoError(t, json.Unmarshal(files["security_events.json"], &events))
+	assert.Len(t, events, pageSize+1)
+
+	// The user is emailed a link to the export, in their language
+	token := emails.tokens["user@example.com"]
+	require.NotEmpty(t, token)
+	assert.Equal(t, "fr", emails.locales["user@example.com"])
+	assert.Equal(t, []string{"data_export.requested", "data_export.completed"}, audit.Actions())
+
+	downloaded, archive, err := s.DownloadExport(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, export.ID, downloaded.ID)
+	assert.Equal(t, repo.archives[export.ID], archive)
+	assert.Equal(t, "data_export.downloaded", audit.Events[len(audit.Events)-1].Action)
+	assert.Equal(t, "user-1", audit.Events[len(audit.Events)-1].Metadata["user_id"])
+}
+
+func TestProcessPendingExportsFailure(t *testing.T) {
+	s, repo, _, emails, audit := newTestService()
+	ctx := context.Background()
+
+	export, err := s.RequestExport(ctx, "user-2")
+	require.NoError(t, err)
+	require.NoError(t, s.ProcessPendingExports(ctx))
+
+	stored := repo.exports[export.ID]
+	assert.Equal(t, model.DataExportFailed, stored.Status)
+	assert.Equal(t, "database is unavailable", *stored.Error)
+	assert.Empty(t, emails.tokens)
+	assert.Equal(t, []string{"data_export.requested", "data_export.failed"}, audit.Actions())
+
+	// A failed export doesn't keep the user from trying again
+	_, err = s.RequestExport(ctx, "user-2")
+	assert.NoError(t, err)
+}
+
+func TestDownloadExportRejectsInvalidLinks(t *testing.T) {
+	s, _, _, _, _ := newTestService()
+	ctx := context.Background()
+
+	export, err := s.RequestExport(ctx, "user-1")
+	require.NoError(t, err)
+	require.NoError(t, s.ProcessPendingExports(ctx))
+
+	other, _, _, _, _ := newTestService()
+	other.secret = []byte("other-secret")
+
+	tests := []struct {
+		name    string
+		token   string
+		message string
+	}{
+		{"malformed", "not-a-token", "invalid download link"},
+		{"signed with another secret", other.DownloadToken(export.ID, time.Now().Add(time.Hour)), "invalid download link"},
+		{"expired", s.DownloadToken(export.ID, time.Now().Add(-time.Second)), "download link has expired"},
+		{"unknown export", s.DownloadToken("missing", time.Now().Add(time.Hour)), "data export not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.DownloadExport(ctx, tt.token)
+			var appErr *apperrors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, tt.message, appErr.Message)
+		})
+	}
+}
+
+func TestDeleteExpiredExports(t *testing.T) {
+	s, repo, _, _, _ := newTestService()
+	ctx := context.Background()
+
+	export, err := s.RequestExport(ctx, "user-1")
+	require.NoError(t, err)
+	require.NoError(t, s.ProcessPendingExports(ctx))
+
+	require.NoError(t, s.DeleteExpiredExports(ctx))
+	assert.Contains(t, repo.exports, export.ID)
+
+	expired := time.Now().Add(-time.Minute)
+	repo.exports[export.ID].ExpiresAt = &expired
+	require.NoError(t, s.DeleteExpiredExports(ctx))
+	assert.NotContains(t, repo.exports, export.ID)
+	assert.NotContains(t, repo.archives, export.ID)
+}
@@ -0,0 +1,232 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nanayaw/fullstack/internal/config"
+)
+
+// maxCapturedEmails is the number of emails kept by an in-memory CaptureService
+const maxCapturedEmails = 1000
+
+// linkPattern matches the links in a rendered email
+var linkPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// CapturedEmail is an email stored by the CaptureService
+type CapturedEmail struct {
+	ID        string            `json:"id"`
+	Template  string            `json:"template"`
+	From      string            `json:"from"`
+	To        []string          `json:"to"`
+	Subject   string            `json:"subject"`
+	HTML      string            `json:"html"`
+	Text      string            `json:"text"`
+	Links     []string          `json:"links"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// CaptureService implements the EmailService interface by storing emails
+// instead of sending them, either in memory or as JSON files in a directory.
+// It is meant for local development and end-to-end tests, which can read the
+// emails back through the dev mailbox API.
+type CaptureService struct {
+	*mailer
+	dir      string
+	mu       sync.RWMutex
+	captured []*CapturedEmail
+}
+
+// NewCaptureService creates a new CaptureService
+func NewCaptureService(cfg *config.EmailConfig, appCfg *config.AppConfig) (*CaptureService, error) {
+	s := &CaptureService{
+		dir: cfg.CaptureDir,
+	}
+
+	if s.dir != "" {
+		if err := os.MkdirAll(s.dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create capture directory: %w", err)
+		}
+	}
+
+	m, err := newMailer(cfg, appCfg, s)
+	if err != nil {
+		return nil, err
+	}
+	s.mailer = m
+
+	return s, nil
+}
+
+// Send stores a message
+func (s *CaptureService) Send(ctx context.Context, msg *Message) error {
+	captured := &CapturedEmail{
+		ID:        uuid.New().String(),
+		Template:  msg.Template,
+		From:      msg.From,
+		To:        msg.To,
+		Subject:   msg.Subject,
+		HTML:      msg.HTML,
+		Text:      msg.Text,
+		Links:     extractLinks(msg.HTML, msg.Text),
+		Headers:   msg.Headers,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if s.dir != "" {
+		data, err := json.MarshalIndent(captured, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode captured email: %w", err)
+		}
+
+		// Prefix the file name with the time so files sort in capture order
+		name := fmt.Sprintf("%d-%s.json", captured.CreatedAt.UnixNano(), captured.ID)
+		if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o600); err != nil {
+			return fmt.Errorf("failed to write captured email: %w", err)
+		}
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.captured = append(s.captured, captured)
+	if len(s.captured) > maxCapturedEmails {
+		s.captured = s.captured[len(s.captured)-maxCapturedEmails:]
+	}
+
+	return nil
+}
+
+// Messages returns the captured emails, newest first, optionally only those
+// sent to recipient
+func (s *CaptureService) Messages(recipient string) ([]*CapturedEmail, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*CapturedEmail, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if recipient == "" || sentTo(all[i], recipient) {
+			messages = append(messages, all[i])
+		}
+	}
+
+	return messages, nil
+}
+
+// Message returns a captured email, or nil if there is none with the ID
+func (s *CaptureService) Message(id string) (*CapturedEmail, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range all {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Clear deletes all captured emails
+func (s *CaptureService) Clear() error {
+	if s.dir != "" {
+		files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+		if err != nil {
+			return fmt.Errorf("failed to list captured emails: %w", err)
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete captured email: %w", err)
+			}
+		}
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.captured = nil
+
+	return nil
+}
+
+// all returns every captured email, oldest first
+func (s *CaptureService) all() ([]*CapturedEmail, error) {
+	if s.dir == "" {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return append([]*CapturedEmail(nil), s.captured...), nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list captured emails: %w", err)
+	}
+	sort.Strings(files)
+
+	all := make([]*CapturedEmail, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file) // #nosec G304 - files come from the configured capture directory
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Cleared while we were reading
+				continue
+			}
+			return nil, fmt.Errorf("failed to read captured email: %w", err)
+		}
+
+		var captured CapturedEmail
+		if err := json.Unmarshal(data, &captured); err != nil {
+			return nil, fmt.Errorf("failed to decode captured email %s: %w", filepath.Base(file), err)
+		}
+		all = append(all, &captured)
+	}
+
+	return all, nil
+}
+
+// sentTo reports whether an email was sent to recipient
+func sentTo(m *CapturedEmail, recipient string) bool {
+	for _, to := range m.To {
+		if strings.EqualFold(to, recipient) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractLinks returns the distinct links in the HTML and text bodies, in the
+// order they first appear
+func extractLinks(htmlBody, textBody string) []string {
+	links := []string{}
+	seen := make(map[string]bool)
+
+	for _, body := range []string{htmlBody, textBody} {
+		for _, link := range linkPattern.FindAllString(body, -1) {
+			link = html.UnescapeString(link)
+			if !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+
+	return links
+}
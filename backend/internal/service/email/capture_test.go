@@ -0,0 +1,48 @@
+package email
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureService(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		cfg := &config.EmailConfig{
+			FromEmail:       "noreply@example.com",
+			FromName:        "Go+Next",
+			VerificationURL: "http://localhost:3000/verify",
+			CaptureDir:      dir,
+		}
+		s, err := NewCaptureService(cfg, &config.AppConfig{Name: "Go+Next"})
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, s.SendVerificationEmail(ctx, "alice@example.com", "abc&123"))
+		require.NoError(t, s.SendVerificationEmail(ctx, "bob@example.com", "def456"))
+
+		// Newest first, and filtered by recipient
+		all, err := s.Messages("")
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, []string{"bob@example.com"}, all[0].To)
+
+		alice, err := s.Messages("Alice@example.com")
+		require.NoError(t, err)
+		require.Len(t, alice, 1)
+		assert.Equal(t, "verification", alice[0].Template)
+		assert.Contains(t, alice[0].Links, "http://localhost:3000/verify?token=abc&123")
+
+		m, err := s.Message(alice[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, alice[0].Subject, m.Subject)
+
+		require.NoError(t, s.Clear())
+		all, err = s.Messages("")
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	}
+}
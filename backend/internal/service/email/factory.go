@@ -5,18 +5,77 @@ import (
 
 	"github.com/nanayaw/fullstack/internal/config"
 	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
 )
 
+// Email providers that can be selected with EMAIL_PROVIDER
+const (
+	ProviderResend  = "resend"
+	ProviderUpstash = "upstash"
+	ProviderSMTP    = "smtp"
+	ProviderCapture = "capture"
+)
+
+// Provider is an email service that can also deliver pre-rendered messages
+type Provider interface {
+	service.EmailService
+	Transport
+}
+
 // NewEmailService creates a new email service based on configuration
-func NewEmailService(cfg *config.EmailConfig) (service.EmailService, error) {
+func NewEmailService(cfg *config.EmailConfig, appCfg *config.AppConfig) (service.EmailService, error) {
+	return NewProvider(cfg, appCfg)
+}
+
+// NewQueuedEmailService creates an email service that writes every email to the
+// outbox, and the worker that delivers them through provider. Emails to
+// addresses on the suppression list are not sent, unless suppressions is nil.
+// Optional emails are only sent to recipients who didn't opt out of them,
+// unless preferences is nil. Emails can only be scheduled when scheduled is
+// not nil. The worker must be started with Run for any email to be sent.
+func NewQueuedEmailService(cfg *config.EmailConfig, appCfg *config.AppConfig, provider Provider, repo OutboxRepository, suppressions SuppressionChecker, preferences PreferenceChecker, scheduled *ScheduledEmails, log logger.Logger) (service.EmailService, *Outbox, *OutboxWorker, error) {
+	outbox := NewOutbox(repo)
+	m, err := newMailer(cfg, appCfg, outbox)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	m.suppressions = suppressions
+	m.preferences = preferences
+	m.scheduled = scheduled
+
+	return m, outbox, NewOutboxWorker(repo, provider, cfg, log), nil
+}
+
+// NewProvider creates the provider selected by the configuration
+func NewProvider(cfg *config.EmailConfig, appCfg *config.AppConfig) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderResend:
+		return NewResendService(cfg, appCfg)
+	case ProviderUpstash:
+		return NewUpstashWorkflowService(cfg, appCfg)
+	case ProviderSMTP:
+		return NewSMTPService(cfg, appCfg)
+	case ProviderCapture:
+		return NewCaptureService(cfg, appCfg)
+	case "":
+		// Detect the provider below
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+	}
+
 	// If Upstash Workflow is configured, use it
 	if cfg.UpstashWorkflowURL != "" && cfg.UpstashWorkflowToken != "" {
-		return NewUpstashWorkflowService(cfg)
+		return NewUpstashWorkflowService(cfg, appCfg)
 	}
 
-	// Otherwise, use Resend
+	// Then Resend
 	if cfg.ResendAPIKey != "" {
-		return NewResendService(cfg)
+		return NewResendService(cfg, appCfg)
+	}
+
+	// Then SMTP
+	if cfg.SMTPHost != "" {
+		return NewSMTPService(cfg, appCfg)
 	}
 
 	return nil, fmt.Errorf("no email service configured")
@@ -0,0 +1,432 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/email/templates"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+)
+
+// Message is a rendered email ready to be delivered by a Transport
+type Message struct {
+	// Template the message was rendered from
+	Template string
+	From     string
+	To       []string
+	Subject  string
+	HTML     string
+	Text     string
+	// IdempotencyKey is passed to providers that support it so that retried
+	// deliveries of the same message are only sent once
+	IdempotencyKey string
+	// Headers are extra headers, such as List-Unsubscribe
+	Headers map[string]string
+}
+
+// Transport delivers rendered emails through an email provider
+type Transport interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SuppressionChecker checks whether an address is on the suppression list
+type SuppressionChecker interface {
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+}
+
+// PreferenceChecker checks whether recipients opted out of optional emails
+type PreferenceChecker interface {
+	// CheckEmail reports whether recipient accepts emails of a notification
+	// category, and returns a token that unsubscribes them from it. The token
+	// is empty when the recipient has no account or links are disabled.
+	CheckEmail(ctx context.Context, recipient, category string) (allowed bool, unsubscribeToken string, err error)
+}
+
+// optionalEmails maps the templates of emails users can opt out of to their
+// notification category. Every other email is about the security of the
+// account, and always sent.
+var optionalEmails = map[string]string{
+	templates.TemplateLoginNotification:  model.NotificationCategoryLoginAlerts,
+	templates.TemplateSecurityDigest:     model.NotificationCategorySecurityDigest,
+	templates.TemplateOnboardingReminder: model.NotificationCategoryOnboarding,
+}
+
+// mailer implements the EmailService interface by rendering every email with
+// pkg/email/templates and handing it to a Transport. Provider services embed
+// it and only implement Send. Emails are rendered in the locale carried by the
+// context (see i18n.WithLocale). Emails to suppressed addresses are dropped
+// with ErrRecipientSuppressed when a SuppressionChecker is set. Optional emails
+// are skipped for recipients who opted out when a PreferenceChecker is set,
+// and sent with unsubscribe links otherwise. Scheduling emails requires
+// ScheduledEmails.
+type mailer struct {
+	config       *config.EmailConfig
+	appConfig    *config.AppConfig
+	renderer     *templates.Renderer
+	transport    Transport
+	suppressions SuppressionChecker
+	preferences  PreferenceChecker
+	scheduled    *ScheduledEmails
+}
+
+// newMailer creates a mailer, loading template overrides from the configured directory
+func newMailer(cfg *config.EmailConfig, appCfg *config.AppConfig, transport Transport) (*mailer, error) {
+	renderer, err := templates.NewRenderer(cfg.TemplatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	return &mailer{
+		config:    cfg,
+		appConfig: appCfg,
+		renderer:  renderer,
+		transport: transport,
+	}, nil
+}
+
+// SendVerificationEmail sends a verification email to the user
+func (m *mailer) SendVerificationEmail(ctx context.Context, to string, token string) error {
+	return m.send(ctx, to, templates.TemplateVerification, templates.VerificationData{
+		TemplateData:    m.templateData(ctx),
+		VerificationURL: fmt.Sprintf("%s?token=%s", m.config.VerificationURL, token),
+		ExpiresIn:       m.formatDuration(ctx, m.config.VerificationTTL, 24*time.Hour),
+	})
+}
+
+// SendPasswordResetEmail sends a password reset email to the user
+func (m *mailer) SendPasswordResetEmail(ctx context.Context, to string, token string) error {
+	return m.send(ctx, to, templates.TemplatePasswordReset, templates.PasswordResetData{
+		TemplateData: m.templateData(ctx),
+		ResetURL:     fmt.Sprintf("%s?token=%s", m.config.PasswordResetURL, token),
+		ExpiresIn:    m.formatDuration(ctx, m.config.PasswordResetTTL, time.Hour),
+		RequestTime:  formatTime(time.Now()),
+	})
+}
+
+// SendWelcomeEmail sends a welcome email
+func (m *mailer) SendWelcomeEmail(ctx context.Context, to, userName string) error {
+	return m.send(ctx, to, templates.TemplateWelcome, templates.WelcomeData{
+		TemplateData: m.templateData(ctx),
+		UserName:     userName,
+	})
+}
+
+// SendLoginNotificationEmail sends a login notification email
+func (m *mailer) SendLoginNotificationEmail(ctx context.Context, to, deviceInfo, location string) error {
+	if !m.config.LoginNotification {
+		return nil
+	}
+
+	return m.send(ctx, to, templates.TemplateLoginNotification, templates.LoginNotificationData{
+		TemplateData: m.templateData(ctx),
+		DeviceInfo:   deviceInfo,
+		Location:     location,
+		Time:         formatTime(time.Now()),
+	})
+}
+
+// SendPasswordChangedEmail sends a password changed notification email
+func (m *mailer) SendPasswordChangedEmail(ctx context.Context, to string) error {
+	return m.send(ctx, to, templates.TemplatePasswordChanged, templates.PasswordChangedData{
+		TemplateData: m.templateData(ctx),
+		Time:         formatTime(time.Now()),
+	})
+}
+
+// SendAccountLockedEmail notifies the user that their account has been locked
+func (m *mailer) SendAccountLockedEmail(ctx context.Context, to string, unlockTime time.Time, failedAttempts int, unlockToken string) error {
+	data := templates.AccountLockedData{
+		TemplateData: m.templateData(ctx),
+		UnlockTime:   formatTime(unlockTime),
+		FailedLogins: failedAttempts,
+	}
+
+	// Only offer an early unlock when we have both a token and somewhere to send it
+	if unlockToken != "" && m.config.AccountUnlockURL != "" {
+		data.UnlockURL = fmt.Sprintf("%s?token=%s", m.config.AccountUnlockURL, unlockToken)
+	}
+
+	return m.send(ctx, to, templates.TemplateAccountLocked, data)
+}
+
+// SendSuspiciousActivityEmail warns the user about suspicious activity on
+// their account. activityType is the type of the security event, which is
+// translated into the user's language.
+func (m *mailer) SendSuspiciousActivityEmail(ctx context.Context, to, activityType, deviceInfo, location, ipAddress string) error {
+	return m.send(ctx, to, templates.TemplateSuspiciousActivity, templates.SuspiciousActivityData{
+		TemplateData: m.templateData(ctx),
+		ActivityType: eventLabel(ctx, activityType),
+		DeviceInfo:   deviceInfo,
+		Location:     location,
+		IPAddress:    ipAddress,
+		Time:         formatTime(time.Now()),
+	})
+}
+
+// SendOnboardingReminderEmail reminds a user who hasn't verified their email address to do so
+func (m *mailer) SendOnboardingReminderEmail(ctx context.Context, to, userName, token string) error {
+	return m.send(ctx, to, templates.TemplateOnboardingReminder, templates.OnboardingReminderData{
+		TemplateData:    m.templateData(ctx),
+		UserName:        userName,
+		VerificationURL: fmt.Sprintf("%s?token=%s", m.config.VerificationURL, token),
+		ExpiresIn:       m.formatDuration(ctx, m.config.VerificationTTL, 24*time.Hour),
+	})
+}
+
+// SendSecurityDigestEmail sends a summary of the security events on the user's account
+func (m *mailer) SendSecurityDigestEmail(ctx context.Context, to string, digest *model.SecurityDigest) error {
+	data := templates.SecurityDigestData{
+		TemplateData: m.templateData(ctx),
+		From:         formatDate(digest.From),
+		To:           formatDate(digest.To),
+	}
+
+	eventTypes := make([]string, 0, len(digest.Counts))
+	for eventType := range digest.Counts {
+		eventTypes = append(eventTypes, eventType)
+	}
+	// Most frequent first, so the summary reads the same way every week
+	sort.Slice(eventTypes, func(i, j int) bool {
+		a, b := eventTypes[i], eventTypes[j]
+		if digest.Counts[a] != digest.Counts[b] {
+			return digest.Counts[a] > digest.Counts[b]
+		}
+		return a < b
+	})
+	for _, eventType := range eventTypes {
+		data.Summary = append(data.Summary, templates.SecurityDigestItem{
+			Label: eventLabel(ctx, eventType),
+			Count: digest.Counts[eventType],
+		})
+	}
+
+	for _, event := range digest.Events {
+		item := templates.SecurityDigestEvent{
+			Label:     event.Description,
+			Time:      formatTime(event.CreatedAt),
+			Location:  event.Location,
+			IPAddress: event.IPAddress,
+		}
+		if item.Label == "" {
+			item.Label = eventLabel(ctx, event.EventType)
+		}
+		data.Events = append(data.Events, item)
+	}
+
+	return m.send(ctx, to, templates.TemplateSecurityDigest, data)
+}
+
+// SendOrganizationInvitationEmail invites someone to join an organization
+func (m *mailer) SendOrganizationInvitationEmail(ctx context.Context, to, organizationName, inviterName, token string) error {
+	return m.send(ctx, to, templates.TemplateOrganizationInvitation, templates.OrganizationInvitationData{
+		TemplateData:     m.templateData(ctx),
+		OrganizationName: organizationName,
+		InviterName:      inviterName,
+		AcceptURL:        fmt.Sprintf("%s?token=%s", m.config.InvitationURL, token),
+		ExpiresIn:        m.formatDuration(ctx, m.config.InvitationTTL, 7*24*time.Hour),
+	})
+}
+
+// SendDataExportReadyEmail sends the link to download a copy of the user's data
+func (m *mailer) SendDataExportReadyEmail(ctx context.Context, to, token string) error {
+	return m.send(ctx, to, templates.TemplateDataExportReady, templates.DataExportReadyData{
+		TemplateData: m.templateData(ctx),
+		DownloadURL:  fmt.Sprintf("%s?token=%s", m.config.DataExportURL, token),
+		ExpiresIn:    m.formatDuration(ctx, m.config.DataExportTTL, 7*24*time.Hour),
+	})
+}
+
+// ScheduleEmail schedules an email of a kind (see model.ScheduledEmail*) to a
+// user at sendAt, replacing any email of the same kind already scheduled
+func (m *mailer) ScheduleEmail(ctx context.Context, userID, kind, to string, sendAt time.Time) error {
+	if m.scheduled == nil {
+		return ErrSchedulingUnavailable
+	}
+	return m.scheduled.Schedule(ctx, userID, kind, to, sendAt)
+}
+
+// CancelScheduledEmails cancels a user's scheduled emails of the given kinds,
+// or of every kind if none are given
+func (m *mailer) CancelScheduledEmails(ctx context.Context, userID string, kinds ...string) error {
+	if m.scheduled == nil {
+		return ErrSchedulingUnavailable
+	}
+	return m.scheduled.Cancel(ctx, userID, kinds...)
+}
+
+// ParseTemplate renders the HTML body of the named template with data in the default locale
+func (m *mailer) ParseTemplate(templateName string, data interface{}) (string, error) {
+	tmpl, err := m.renderer.Render(templateName, i18n.DefaultLocale, data)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.HTML, nil
+}
+
+// ValidateEmailAddress checks if the email address is valid
+func (m *mailer) ValidateEmailAddress(email string) bool {
+	return isValidEmail(email)
+}
+
+// send renders the named template and delivers it to a single recipient
+func (m *mailer) send(ctx context.Context, to, templateName string, data interface{}) error {
+	description := strings.ReplaceAll(templateName, "_", " ")
+
+	if m.suppressions != nil {
+		suppressed, err := m.suppressions.IsSuppressed(ctx, to)
+		if err != nil {
+			return fmt.Errorf("failed to check suppression list: %w", err)
+		}
+		if suppressed {
+			return fmt.Errorf("not sending %s email: %w", description, ErrRecipientSuppressed)
+		}
+	}
+
+	var headers map[string]string
+	if category, ok := optionalEmails[templateName]; ok && m.preferences != nil {
+		allowed, token, err := m.preferences.CheckEmail(ctx, to, category)
+		if err != nil {
+			return fmt.Errorf("failed to check notification preferences: %w", err)
+		}
+		if !allowed {
+			return nil
+		}
+		if token != "" {
+			data = withUnsubscribeURL(data, m.unsubscribeURL(m.config.UnsubscribeURL, token))
+			headers = m.unsubscribeHeaders(token)
+		}
+	}
+
+	tmpl, err := m.renderer.Render(templateName, i18n.FromContext(ctx), data)
+	if err != nil {
+		return fmt.Errorf("failed to render %s email: %w", description, err)
+	}
+
+	msg := &Message{
+		Template: templateName,
+		From:     m.from(),
+		To:       []string{to},
+		Subject:  tmpl.Subject,
+		HTML:     tmpl.HTML,
+		Text:     tmpl.Text,
+		Headers:  headers,
+	}
+
+	if err := m.transport.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send %s email: %w", description, err)
+	}
+
+	return nil
+}
+
+// unsubscribeURL returns the URL that unsubscribes with a token, or "" when
+// the base URL is not configured
+func (m *mailer) unsubscribeURL(base, token string) string {
+	if base == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s?token=%s", base, url.QueryEscape(token))
+}
+
+// unsubscribeHeaders returns the List-Unsubscribe headers of an optional
+// email, which let mail clients unsubscribe with a single POST (RFC 8058)
+func (m *mailer) unsubscribeHeaders(token string) map[string]string {
+	link := m.unsubscribeURL(m.config.UnsubscribeAPIURL, token)
+	if link == "" {
+		return nil
+	}
+
+	return map[string]string{
+		"List-Unsubscribe":      "<" + link + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// withUnsubscribeURL sets the unsubscribe link of the data of an optional email
+func withUnsubscribeURL(data interface{}, link string) interface{} {
+	switch d := data.(type) {
+	case templates.LoginNotificationData:
+		d.UnsubscribeURL = link
+		return d
+	case templates.SecurityDigestData:
+		d.UnsubscribeURL = link
+		return d
+	case templates.OnboardingReminderData:
+		d.UnsubscribeURL = link
+		return d
+	default:
+		return data
+	}
+}
+
+// from returns the sender address, including the sender name if configured
+func (m *mailer) from() string {
+	if m.config.FromName != "" {
+		return fmt.Sprintf("%s <%s>", m.config.FromName, m.config.FromEmail)
+	}
+	return m.config.FromEmail
+}
+
+// templateData builds the data shared by every email template from the app
+// configuration and the locale carried by ctx
+func (m *mailer) templateData(ctx context.Context) templates.TemplateData {
+	supportEmail := m.appConfig.SupportEmail
+	if supportEmail == "" {
+		supportEmail = m.config.FromEmail
+	}
+
+	data := templates.NewTemplateData(m.appConfig.Name, supportEmail, m.appConfig.URL)
+	data.Locale = i18n.FromContext(ctx)
+	return data
+}
+
+// isValidEmail checks if the email string is valid
+func isValidEmail(email string) bool {
+	email = strings.TrimSpace(strings.ToLower(email))
+	parts := strings.Split(email, "@")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
+
+// formatTime formats a time for display in an email
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC1123)
+}
+
+// formatDate formats a date for display in an email
+func formatDate(t time.Time) string {
+	return t.UTC().Format("January 2, 2006")
+}
+
+// eventLabel translates the type of a security event, such as "login_failed",
+// into the locale carried by ctx
+func eventLabel(ctx context.Context, eventType string) string {
+	return i18n.Default().T(i18n.FromContext(ctx), "security_event."+eventType)
+}
+
+// formatDuration formats a link lifetime such as "24 hours" or "30 minutes" in
+// the locale carried by ctx, using fallback when the duration is not configured
+func (m *mailer) formatDuration(ctx context.Context, d, fallback time.Duration) string {
+	if d <= 0 {
+		d = fallback
+	}
+
+	catalog, locale := i18n.Default(), i18n.FromContext(ctx)
+	switch {
+	case d%(24*time.Hour) == 0 && d >= 48*time.Hour:
+		return catalog.T(locale, "duration.days", int(d/(24*time.Hour)))
+	case d == time.Hour:
+		return catalog.T(locale, "duration.hour")
+	case d%time.Hour == 0:
+		return catalog.T(locale, "duration.hours", int(d/time.Hour))
+	case d == time.Minute:
+		return catalog.T(locale, "duration.minute")
+	default:
+		return catalog.T(locale, "duration.minutes", int(d/time.Minute))
+	}
+}
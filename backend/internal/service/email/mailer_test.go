@@ -0,0 +1,92 @@
+package email
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockPreferenceChecker is a mock implementation of PreferenceChecker
+type mockPreferenceChecker struct {
+	mock.Mock
+}
+
+func (m *mockPreferenceChecker) CheckEmail(ctx context.Context, recipient, category string) (bool, string, error) {
+	args := m.Called(ctx, recipient, category)
+	return args.Bool(0), args.String(1), args.Error(2)
+}
+
+func TestMailerNotificationPreferences(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.EmailConfig{
+		FromEmail:         "noreply@example.com",
+		LoginNotification: true,
+		UnsubscribeURL:    "https://example.com/unsubscribe",
+		UnsubscribeAPIURL: "https://api.example.com/api/v1/notifications/unsubscribe",
+	}
+
+	newTestMailer := func(t *testing.T) (*mailer, *mockTransport, *mockPreferenceChecker) {
+		transport := new(mockTransport)
+		preferences := new(mockPreferenceChecker)
+		m, err := newMailer(cfg, &config.AppConfig{Name: "Go+Next"}, transport)
+		require.NoError(t, err)
+		m.preferences = preferences
+		return m, transport, preferences
+	}
+
+	t.Run("skips optional emails the user opted out of", func(t *testing.T) {
+		m, transport, preferences := newTestMailer(t)
+		preferences.On("CheckEmail", ctx, "user@example.com", model.NotificationCategoryLoginAlerts).Return(false, "", nil)
+
+		require.NoError(t, m.SendLoginNotificationEmail(ctx, "user@example.com", "Chrome on macOS", "Accra, Ghana"))
+		transport.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("adds unsubscribe links to optional emails", func(t *testing.T) {
+		m, transport, preferences := newTestMailer(t)
+		preferences.On("CheckEmail", ctx, "user@example.com", model.NotificationCategoryOnboarding).Return(true, "abc.def", nil)
+		transport.On("Send", ctx, mock.Anything).Return(nil).Once()
+
+		require.NoError(t, m.SendOnboardingReminderEmail(ctx, "user@example.com", "Jane Doe", "token"))
+
+		msg := transport.Calls[0].Arguments.Get(1).(*Message)
+		assert.Equal(t, map[string]string{
+			"List-Unsubscribe":      "<https://api.example.com/api/v1/notifications/unsubscribe?token=abc.def>",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}, msg.Headers)
+		assert.Contains(t, msg.Text, "https://example.com/unsubscribe?token=abc.def")
+		assert.Contains(t, msg.HTML, `href="https://example.com/unsubscribe?token=abc.def"`)
+	})
+
+	t.Run("always sends account emails", func(t *testing.T) {
+		m, transport, preferences := newTestMailer(t)
+		transport.On("Send", ctx, mock.Anything).Return(nil).Once()
+
+		require.NoError(t, m.SendPasswordChangedEmail(ctx, "user@example.com"))
+
+		preferences.AssertNotCalled(t, "CheckEmail", mock.Anything, mock.Anything, mock.Anything)
+		msg := transport.Calls[0].Arguments.Get(1).(*Message)
+		assert.Empty(t, msg.Headers)
+		assert.NotContains(t, msg.Text, "unsubscribe")
+	})
+}
+
+func TestMailerTranslatesSuspiciousActivity(t *testing.T) {
+	ctx := i18n.WithLocale(context.Background(), "fr")
+	transport := new(mockTransport)
+	m, err := newMailer(&config.EmailConfig{FromEmail: "noreply@example.com"}, &config.AppConfig{Name: "Go+Next"}, transport)
+	require.NoError(t, err)
+	transport.On("Send", ctx, mock.Anything).Return(nil).Once()
+
+	require.NoError(t, m.SendSuspiciousActivityEmail(ctx, "user@example.com", model.EventPasswordReset, "Chrome on macOS", "Paris, France", "203.0.113.10"))
+
+	msg := transport.Calls[0].Arguments.Get(1).(*Message)
+	assert.Contains(t, msg.Text, "Mot de passe réinitialisé")
+	assert.NotContains(t, msg.Text, model.EventPasswordReset)
+}
@@ -0,0 +1,169 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// Outbox listing page sizes
+const (
+	DefaultOutboxPageSize = 50
+	MaxOutboxPageSize     = 200
+)
+
+// OutboxRepository defines the interface for email outbox database operations
+type OutboxRepository interface {
+	// EnqueueEmail adds an email to the outbox, ignoring duplicate idempotency keys
+	EnqueueEmail(ctx context.Context, email *model.OutboxEmail) error
+
+	// ClaimDueEmails claims emails that are due for delivery until lockedUntil
+	ClaimDueEmails(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.OutboxEmail, error)
+
+	// MarkEmailSent records that a claimed email was accepted by the provider
+	MarkEmailSent(ctx context.Context, id string, sentAt time.Time) error
+
+	// MarkEmailFailed records a failed delivery attempt and schedules the next one
+	MarkEmailFailed(ctx context.Context, id, lastError string, nextAttemptAt, now time.Time) error
+
+	// MarkEmailDead dead-letters a claimed email after its last failed attempt
+	MarkEmailDead(ctx context.Context, id, lastError string, now time.Time) error
+
+	// RetryEmail moves a dead-lettered email back to the queue
+	RetryEmail(ctx context.Context, id string, now time.Time) (bool, error)
+
+	// GetEmail gets a single outbox email
+	GetEmail(ctx context.Context, id string) (*model.OutboxEmail, error)
+
+	// ListEmails gets a filtered page of outbox emails
+	ListEmails(ctx context.Context, filter model.OutboxFilter) ([]*model.OutboxEmail, error)
+
+	// CountEmailsByStatus counts the outbox emails in each status
+	CountEmailsByStatus(ctx context.Context) (map[string]int, error)
+}
+
+// Outbox is a Transport that writes messages to the email outbox instead of
+// sending them. An OutboxWorker delivers them through the real provider, so a
+// provider outage delays emails rather than losing them.
+type Outbox struct {
+	repo OutboxRepository
+}
+
+// NewOutbox creates a new Outbox
+func NewOutbox(repo OutboxRepository) *Outbox {
+	return &Outbox{
+		repo: repo,
+	}
+}
+
+// Send queues a message for delivery
+func (o *Outbox) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+
+	key := msg.IdempotencyKey
+	if key == "" {
+		key = uuid.New().String()
+	}
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode email headers: %w", err)
+	}
+
+	email := &model.OutboxEmail{
+		IdempotencyKey: key,
+		Template:       msg.Template,
+		FromAddress:    msg.From,
+		ToAddresses:    strings.Join(msg.To, ","),
+		Subject:        msg.Subject,
+		HTMLBody:       msg.HTML,
+		TextBody:       msg.Text,
+		Headers:        string(headers),
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := o.repo.EnqueueEmail(ctx, email); err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+
+	return nil
+}
+
+// ListEmails lists outbox emails, newest first
+func (o *Outbox) ListEmails(ctx context.Context, filter model.OutboxFilter) ([]*model.OutboxEmail, error) {
+	switch filter.Status {
+	case "", model.OutboxStatusPending, model.OutboxStatusSending, model.OutboxStatusSent, model.OutboxStatusDead:
+	default:
+		return nil, errors.NewValidationError("invalid status")
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultOutboxPageSize
+	}
+	if filter.Limit > MaxOutboxPageSize {
+		filter.Limit = MaxOutboxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return o.repo.ListEmails(ctx, filter)
+}
+
+// GetEmail gets a single outbox email
+func (o *Outbox) GetEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	email, err := o.repo.GetEmail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if email == nil {
+		return nil, errors.NewNotFoundError("email not found")
+	}
+
+	return email, nil
+}
+
+// RetryEmail queues a dead-lettered email for immediate delivery with a fresh
+// set of attempts
+func (o *Outbox) RetryEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	retried, err := o.repo.RetryEmail(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := o.GetEmail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !retried {
+		return nil, errors.NewConflictError(fmt.Sprintf("only dead-lettered emails can be retried, email is %s", email.Status))
+	}
+
+	return email, nil
+}
+
+// Stats counts the outbox emails in each status
+func (o *Outbox) Stats(ctx context.Context) (map[string]int, error) {
+	counts, err := o.repo.CountEmailsByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Always report every status so dashboards don't have to special-case zero
+	for _, status := range []string{model.OutboxStatusPending, model.OutboxStatusSending, model.OutboxStatusSent, model.OutboxStatusDead} {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,332 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	emailclient "github.com/nanayaw/fullstack/pkg/email"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// scheduledEmailSendTimeout bounds rendering and queueing a single scheduled email
+	scheduledEmailSendTimeout = 30 * time.Second
+	// scheduledEmailClaimLease is how long a claimed scheduled email is reserved
+	// for a worker. A claim that outlives it is assumed lost and claimed again.
+	scheduledEmailClaimLease = 2 * time.Minute
+	// scheduledEmailGracePeriod is how long the worker leaves a due email to the
+	// external scheduler before sending it itself
+	scheduledEmailGracePeriod = 15 * time.Minute
+	// scheduledEmailWorkflowType is the email type of scheduled email callbacks
+	// requested from Upstash Workflow
+	scheduledEmailWorkflowType = "scheduled_email_callback"
+)
+
+// ErrSchedulingUnavailable is returned when scheduling an email with an email
+// service that has no scheduled emails store
+var ErrSchedulingUnavailable = errors.New("email scheduling is not configured")
+
+// ScheduledEmailRepository defines the interface for scheduled email database operations
+type ScheduledEmailRepository interface {
+	// CreateScheduledEmail adds a scheduled email
+	CreateScheduledEmail(ctx context.Context, email *model.ScheduledEmail) error
+
+	// ClaimDueScheduledEmails claims scheduled emails that were due at or before dueBefore until lockedUntil
+	ClaimDueScheduledEmails(ctx context.Context, dueBefore, now, lockedUntil time.Time, limit int) ([]*model.ScheduledEmail, error)
+
+	// ClaimScheduledEmail claims a single due scheduled email until lockedUntil, or returns nil
+	ClaimScheduledEmail(ctx context.Context, id string, now, lockedUntil time.Time) (*model.ScheduledEmail, error)
+
+	// MarkScheduledEmailSent records that a claimed scheduled email was sent
+	MarkScheduledEmailSent(ctx context.Context, id string, sentAt time.Time) error
+
+	// MarkScheduledEmailFailed records that a claimed scheduled email could not be sent
+	MarkScheduledEmailFailed(ctx context.Context, id, lastError string, now time.Time) error
+
+	// CancelScheduledEmails cancels a user's scheduled emails of the given kinds, or all of them
+	CancelScheduledEmails(ctx context.Context, userID string, kinds []string, now time.Time) (int, error)
+}
+
+// Scheduler wakes the application up when a scheduled email is due, by calling
+// ScheduledEmails.Dispatch with its ID
+type Scheduler interface {
+	Schedule(ctx context.Context, email *model.ScheduledEmail) error
+}
+
+// ScheduledEmailHandler sends a scheduled email that is due. It is called with
+// the locale of the email in ctx, and decides what to send based on the user's
+// state at that time, which may be nothing at all.
+type ScheduledEmailHandler func(ctx context.Context, email *model.ScheduledEmail) error
+
+// ScheduledEmails stores emails to send at a later time and sends them through
+// the handler registered for their kind once they are due. Due emails are found
+// by a ScheduledEmailWorker polling the database, or dispatched as soon as an
+// external Scheduler calls back.
+type ScheduledEmails struct {
+	repo      ScheduledEmailRepository
+	scheduler Scheduler
+	logger    logger.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]ScheduledEmailHandler
+}
+
+// NewScheduledEmails creates a new ScheduledEmails. scheduler may be nil, in
+// which case emails are only sent by a ScheduledEmailWorker.
+func NewScheduledEmails(repo ScheduledEmailRepository, scheduler Scheduler, log logger.Logger) *ScheduledEmails {
+	return &ScheduledEmails{
+		repo:      repo,
+		scheduler: scheduler,
+		logger:    log,
+		handlers:  make(map[string]ScheduledEmailHandler),
+	}
+}
+
+// RegisterHandler sets the handler that sends scheduled emails of a kind
+func (s *ScheduledEmails) RegisterHandler(kind string, handler ScheduledEmailHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[kind] = handler
+}
+
+// Schedule schedules an email of a kind to a user at sendAt, replacing any
+// email of the same kind already scheduled for the user. The email is rendered
+// in the locale carried by ctx.
+func (s *ScheduledEmails) Schedule(ctx context.Context, userID, kind, to string, sendAt time.Time) error {
+	if userID == "" || kind == "" || to == "" {
+		return fmt.Errorf("user ID, kind and recipient are required to schedule an email")
+	}
+
+	now := time.Now()
+	if _, err := s.repo.CancelScheduledEmails(ctx, userID, []string{kind}, now); err != nil {
+		return fmt.Errorf("failed to replace scheduled %s email: %w", kind, err)
+	}
+
+	email := &model.ScheduledEmail{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Kind:      kind,
+		Recipient: to,
+		Locale:    i18n.FromContext(ctx),
+		SendAt:    sendAt,
+		Status:    model.ScheduledEmailStatusScheduled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.repo.CreateScheduledEmail(ctx, email); err != nil {
+		return fmt.Errorf("failed to schedule %s email: %w", kind, err)
+	}
+
+	// The worker sends the email anyway if the scheduler never calls back
+	if s.scheduler != nil {
+		if err := s.scheduler.Schedule(ctx, email); err != nil {
+			s.logger.Warn("Failed to schedule email callback, falling back to polling",
+				"scheduled_email_id", email.ID, "kind", kind, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Cancel cancels a user's scheduled emails of the given kinds, or of every
+// kind if none are given
+func (s *ScheduledEmails) Cancel(ctx context.Context, userID string, kinds ...string) error {
+	if _, err := s.repo.CancelScheduledEmails(ctx, userID, kinds, time.Now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Dispatch sends a scheduled email if it is due and still scheduled. It does
+// nothing if the email was cancelled, already sent or is not due yet, so it
+// is safe to call more than once for the same email.
+func (s *ScheduledEmails) Dispatch(ctx context.Context, id string) error {
+	now := time.Now()
+	email, err := s.repo.ClaimScheduledEmail(ctx, id, now, now.Add(scheduledEmailClaimLease))
+	if err != nil {
+		return err
+	}
+	if email == nil {
+		return nil
+	}
+
+	s.send(ctx, email)
+	return nil
+}
+
+// ProcessDue claims up to limit emails that were due at or before dueBefore
+// and sends them, returning the number of emails claimed
+func (s *ScheduledEmails) ProcessDue(ctx context.Context, dueBefore time.Time, limit int) (int, error) {
+	now := time.Now()
+	emails, err := s.repo.ClaimDueScheduledEmails(ctx, dueBefore, now, now.Add(scheduledEmailClaimLease), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, email := range emails {
+		s.send(ctx, email)
+	}
+
+	return len(emails), nil
+}
+
+// send runs the handler for a claimed email and records the result. Handlers
+// queue their emails in the outbox, which takes care of delivery retries, so
+// a failed scheduled email is not retried.
+func (s *ScheduledEmails) send(ctx context.Context, email *model.ScheduledEmail) {
+	s.mu.RLock()
+	handler, ok := s.handlers[email.Kind]
+	s.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for scheduled %s emails", email.Kind)
+	} else {
+		err = s.runHandler(ctx, handler, email)
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to send scheduled email",
+			"scheduled_email_id", email.ID, "kind", email.Kind, "user_id", email.UserID, "error", err)
+		if markErr := s.repo.MarkScheduledEmailFailed(ctx, email.ID, err.Error(), time.Now()); markErr != nil {
+			s.logger.Error("Failed to mark scheduled email as failed", "scheduled_email_id", email.ID, "error", markErr)
+		}
+		return
+	}
+
+	if err := s.repo.MarkScheduledEmailSent(ctx, email.ID, time.Now()); err != nil {
+		s.logger.Error("Failed to mark scheduled email as sent", "scheduled_email_id", email.ID, "error", err)
+	}
+}
+
+// runHandler runs handler in the locale of the email. A handler that panics
+// fails the email instead of taking the worker down with it.
+func (s *ScheduledEmails) runHandler(ctx context.Context, handler ScheduledEmailHandler, email *model.ScheduledEmail) (err error) {
+	ctx, cancel := context.WithTimeout(i18n.WithLocale(ctx, email.Locale), scheduledEmailSendTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scheduled %s email handler panicked: %v", email.Kind, r)
+		}
+	}()
+
+	return handler(ctx, email)
+}
+
+// UpstashScheduler schedules a callback through Upstash Workflow for every
+// scheduled email. The workflow sleeps until the email is due and then POSTs
+// {"scheduled_email_id": "..."} to the callback URL with the callback token as
+// a bearer token.
+type UpstashScheduler struct {
+	client        *emailclient.UpstashWorkflowClient
+	callbackURL   string
+	callbackToken string
+}
+
+// NewUpstashScheduler creates an UpstashScheduler, or returns nil if Upstash
+// Workflow or the scheduled emails callback is not configured
+func NewUpstashScheduler(cfg *config.EmailConfig) *UpstashScheduler {
+	if cfg.UpstashWorkflowURL == "" || cfg.UpstashWorkflowToken == "" ||
+		cfg.ScheduleCallbackURL == "" || cfg.ScheduleCallbackToken == "" {
+		return nil
+	}
+
+	return &UpstashScheduler{
+		client:        emailclient.NewUpstashWorkflowClient(cfg.UpstashWorkflowURL, cfg.UpstashWorkflowToken, cfg.FromEmail, cfg.FromName),
+		callbackURL:   cfg.ScheduleCallbackURL,
+		callbackToken: cfg.ScheduleCallbackToken,
+	}
+}
+
+// Schedule asks Upstash Workflow to call back when email is due. Only the ID
+// of the email is sent, as the email is rendered when the callback arrives.
+func (s *UpstashScheduler) Schedule(ctx context.Context, email *model.ScheduledEmail) error {
+	data := map[string]interface{}{
+		"kind":               email.Kind,
+		"scheduled_email_id": email.ID,
+		"callback_url":       s.callbackURL,
+		"callback_token":     s.callbackToken,
+	}
+
+	return s.client.ScheduleEmail(ctx, scheduledEmailWorkflowType, email.Recipient, email.Kind, data, email.SendAt)
+}
+
+// ScheduledEmailWorker sends scheduled emails once they are due. It is the
+// in-process scheduler when no external Scheduler is configured; otherwise it
+// only sends emails the external scheduler has not called back for within a
+// grace period. Several workers can safely share the scheduled emails table.
+type ScheduledEmailWorker struct {
+	emails       *ScheduledEmails
+	logger       logger.Logger
+	pollInterval time.Duration
+	batchSize    int
+	gracePeriod  time.Duration
+}
+
+// NewScheduledEmailWorker creates a worker sending due scheduled emails,
+// polling as often as the outbox worker
+func NewScheduledEmailWorker(emails *ScheduledEmails, cfg *config.EmailConfig, log logger.Logger) *ScheduledEmailWorker {
+	w := &ScheduledEmailWorker{
+		emails:       emails,
+		logger:       log,
+		pollInterval: cfg.OutboxPollInterval,
+		batchSize:    cfg.OutboxBatchSize,
+	}
+
+	if w.pollInterval <= 0 {
+		w.pollInterval = defaultOutboxPollInterval
+	}
+	if w.batchSize <= 0 {
+		w.batchSize = defaultOutboxBatchSize
+	}
+	if emails.scheduler != nil {
+		w.gracePeriod = scheduledEmailGracePeriod
+	}
+
+	return w
+}
+
+// Run sends due scheduled emails every poll interval until ctx is cancelled
+func (w *ScheduledEmailWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while batches come back full, so a backlog drains quickly
+		for {
+			n, err := w.ProcessBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.logger.Error("Failed to process scheduled emails", "error", err)
+				}
+				break
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch sends one batch of due scheduled emails, returning the number
+// of emails claimed
+func (w *ScheduledEmailWorker) ProcessBatch(ctx context.Context) (int, error) {
+	return w.emails.ProcessDue(ctx, time.Now().Add(-w.gracePeriod), w.batchSize)
+}
@@ -0,0 +1,177 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockScheduledEmailRepository is a mock implementation of ScheduledEmailRepository
+type mockScheduledEmailRepository struct {
+	mock.Mock
+}
+
+func (m *mockScheduledEmailRepository) CreateScheduledEmail(ctx context.Context, email *model.ScheduledEmail) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *mockScheduledEmailRepository) ClaimDueScheduledEmails(ctx context.Context, dueBefore, now, lockedUntil time.Time, limit int) ([]*model.ScheduledEmail, error) {
+	args := m.Called(ctx, dueBefore, now, lockedUntil, limit)
+	return args.Get(0).([]*model.ScheduledEmail), args.Error(1)
+}
+
+func (m *mockScheduledEmailRepository) ClaimScheduledEmail(ctx context.Context, id string, now, lockedUntil time.Time) (*model.ScheduledEmail, error) {
+	args := m.Called(ctx, id, now, lockedUntil)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ScheduledEmail), args.Error(1)
+}
+
+func (m *mockScheduledEmailRepository) MarkScheduledEmailSent(ctx context.Context, id string, sentAt time.Time) error {
+	args := m.Called(ctx, id, sentAt)
+	return args.Error(0)
+}
+
+func (m *mockScheduledEmailRepository) MarkScheduledEmailFailed(ctx context.Context, id, lastError string, now time.Time) error {
+	args := m.Called(ctx, id, lastError, now)
+	return args.Error(0)
+}
+
+func (m *mockScheduledEmailRepository) CancelScheduledEmails(ctx context.Context, userID string, kinds []string, now time.Time) (int, error) {
+	args := m.Called(ctx, userID, kinds, now)
+	return args.Int(0), args.Error(1)
+}
+
+// mockScheduler is a mock implementation of Scheduler
+type mockScheduler struct {
+	mock.Mock
+}
+
+func (m *mockScheduler) Schedule(ctx context.Context, email *model.ScheduledEmail) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func TestScheduleEmail(t *testing.T) {
+	repo := new(mockScheduledEmailRepository)
+	scheduler := new(mockScheduler)
+	emails := NewScheduledEmails(repo, scheduler, logger.DefaultLogger())
+	ctx := i18n.WithLocale(context.Background(), "fr")
+	sendAt := time.Now().Add(72 * time.Hour)
+
+	// An email already scheduled for the user is replaced
+	repo.On("CancelScheduledEmails", ctx, "user-1", []string{model.ScheduledEmailOnboardingReminder}, mock.Anything).Return(1, nil).Once()
+	repo.On("CreateScheduledEmail", ctx, mock.MatchedBy(func(e *model.ScheduledEmail) bool {
+		return e.UserID == "user-1" && e.Kind == model.ScheduledEmailOnboardingReminder &&
+			e.Recipient == "user@example.com" && e.Locale == "fr" && e.SendAt.Equal(sendAt) &&
+			e.Status == model.ScheduledEmailStatusScheduled
+	})).Return(nil).Once()
+
+	// The worker sends the email if the scheduler fails
+	scheduler.On("Schedule", ctx, mock.Anything).Return(errors.New("upstash unavailable")).Once()
+
+	err := emails.Schedule(ctx, "user-1", model.ScheduledEmailOnboardingReminder, "user@example.com", sendAt)
+	require.NoError(t, err)
+
+	repo.AssertExpectations(t)
+	scheduler.AssertExpectations(t)
+}
+
+func TestDispatchScheduledEmail(t *testing.T) {
+	ctx := context.Background()
+	scheduled := &model.ScheduledEmail{
+		ID:        "sched-1",
+		UserID:    "user-1",
+		Kind:      model.ScheduledEmailOnboardingReminder,
+		Recipient: "user@example.com",
+		Locale:    "fr",
+	}
+
+	t.Run("sends with the registered handler", func(t *testing.T) {
+		repo := new(mockScheduledEmailRepository)
+		emails := NewScheduledEmails(repo, nil, logger.DefaultLogger())
+
+		var locale string
+		emails.RegisterHandler(model.ScheduledEmailOnboardingReminder, func(ctx context.Context, email *model.ScheduledEmail) error {
+			locale = i18n.FromContext(ctx)
+			return nil
+		})
+
+		repo.On("ClaimScheduledEmail", ctx, "sched-1", mock.Anything, mock.Anything).Return(scheduled, nil).Once()
+		repo.On("MarkScheduledEmailSent", ctx, "sched-1", mock.Anything).Return(nil).Once()
+
+		require.NoError(t, emails.Dispatch(ctx, "sched-1"))
+		assert.Equal(t, "fr", locale)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("ignores emails that are not due", func(t *testing.T) {
+		repo := new(mockScheduledEmailRepository)
+		emails := NewScheduledEmails(repo, nil, logger.DefaultLogger())
+
+		// Cancelled, already sent or not due yet
+		repo.On("ClaimScheduledEmail", ctx, "sched-1", mock.Anything, mock.Anything).Return(nil, nil).Once()
+
+		require.NoError(t, emails.Dispatch(ctx, "sched-1"))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("marks failed emails", func(t *testing.T) {
+		repo := new(mockScheduledEmailRepository)
+		emails := NewScheduledEmails(repo, nil, logger.DefaultLogger())
+
+		emails.RegisterHandler(model.ScheduledEmailOnboardingReminder, func(ctx context.Context, email *model.ScheduledEmail) error {
+			panic("user service is nil")
+		})
+
+		repo.On("ClaimScheduledEmail", ctx, "sched-1", mock.Anything, mock.Anything).Return(scheduled, nil).Once()
+		repo.On("MarkScheduledEmailFailed", ctx, "sched-1", mock.MatchedBy(func(lastError string) bool {
+			return lastError == "scheduled onboarding_reminder email handler panicked: user service is nil"
+		}), mock.Anything).Return(nil).Once()
+
+		require.NoError(t, emails.Dispatch(ctx, "sched-1"))
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestScheduledEmailWorkerGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.EmailConfig{OutboxBatchSize: 10}
+
+	for name, tc := range map[string]struct {
+		scheduler Scheduler
+		lag       time.Duration
+	}{
+		// Without an external scheduler, the worker is the scheduler
+		"in-process": {scheduler: nil, lag: 0},
+		// With one, the worker only sends emails it didn't call back for
+		"upstash": {scheduler: new(mockScheduler), lag: scheduledEmailGracePeriod},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := new(mockScheduledEmailRepository)
+			worker := NewScheduledEmailWorker(NewScheduledEmails(repo, tc.scheduler, logger.DefaultLogger()), cfg, logger.DefaultLogger())
+
+			before := time.Now()
+			repo.On("ClaimDueScheduledEmails", ctx, mock.MatchedBy(func(dueBefore time.Time) bool {
+				lag := before.Sub(dueBefore)
+				return lag <= tc.lag && lag > tc.lag-time.Second
+			}), mock.Anything, mock.Anything, 10).Return([]*model.ScheduledEmail{}, nil).Once()
+
+			n, err := worker.ProcessBatch(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 0, n)
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/pkg/email/dkim"
+)
+
+// SMTPService implements the EmailService interface by delivering email to an
+// SMTP server, optionally signing it with DKIM
+type SMTPService struct {
+	*mailer
+	host     string
+	port     int
+	username string
+	password string
+	startTLS bool
+	signer   *dkim.Signer
+}
+
+// NewSMTPService creates a new SMTPService
+func NewSMTPService(cfg *config.EmailConfig, appCfg *config.AppConfig) (*SMTPService, error) {
+	if cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+
+	s := &SMTPService{
+		host:     cfg.SMTPHost,
+		port:     cfg.SMTPPort,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		startTLS: cfg.SMTPStartTLS,
+	}
+	if s.port == 0 {
+		s.port = 587
+	}
+
+	// DKIM signing is enabled when a key is configured
+	if cfg.DKIMPrivateKeyPath != "" {
+		key, err := os.ReadFile(cfg.DKIMPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DKIM private key: %w", err)
+		}
+
+		s.signer, err = dkim.NewSigner(cfg.DKIMDomain, cfg.DKIMSelector, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := newMailer(cfg, appCfg, s)
+	if err != nil {
+		return nil, err
+	}
+	s.mailer = m
+
+	return s, nil
+}
+
+// Send delivers a message to the SMTP server
+func (s *SMTPService) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+
+	raw, err := buildMIMEMessage(msg, from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if s.signer != nil {
+		raw, err = s.signer.Sign(raw)
+		if err != nil {
+			return fmt.Errorf("failed to sign message: %w", err)
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to set SMTP deadline: %w", err)
+		}
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if s.startTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if s.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection,
+		// except to localhost
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient: %w", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP data: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMIMEMessage encodes a message as a multipart/alternative MIME message
+// with CRLF line endings. The Message-ID is derived from the idempotency key,
+// so a retried delivery can be recognized as a duplicate by the recipient.
+func buildMIMEMessage(msg *Message, from *mail.Address, date time.Time) ([]byte, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	id := msg.IdempotencyKey
+	if id == "" {
+		id = uuid.New().String()
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+
+	writeHeader("From", from.String())
+	writeHeader("To", strings.Join(msg.To, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", date.Format(time.RFC1123Z))
+	writeHeader("Message-ID", fmt.Sprintf("<%s@%s>", id, domain))
+
+	// Extra headers are written in a fixed order so messages are reproducible,
+	// without line breaks that would inject more headers
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(name, strings.NewReplacer("\r", "", "\n", "").Replace(msg.Headers[name]))
+	}
+
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", boundary))
+	buf.WriteString("\r\n")
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+
+		buf.WriteString("--" + boundary + "\r\n")
+		writeHeader("Content-Type", part.contentType)
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("failed to encode message body: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode message body: %w", err)
+		}
+		buf.WriteString("\r\n")
+	}
+
+	buf.WriteString("--" + boundary + "--\r\n")
+
+	return buf.Bytes(), nil
+}
+
+// randomBoundary returns a random MIME multipart boundary
+func randomBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate MIME boundary: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,68 @@
+package email
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMIMEMessage(t *testing.T) {
+	msg := &Message{
+		From:           "Go+Next <noreply@example.com>",
+		To:             []string{"user@example.com"},
+		Subject:        "Vérifiez votre adresse e-mail",
+		HTML:           "<p>Bonjour, <a href=\"http://localhost:3000/verify?token=abc\">vérifier</a></p>",
+		Text:           "Bonjour,\nhttp://localhost:3000/verify?token=abc",
+		IdempotencyKey: "verification-123",
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<https://api.example.com/api/v1/notifications/unsubscribe?token=abc>",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click\r\nBcc: attacker@example.com",
+		},
+	}
+	from, err := mail.ParseAddress(msg.From)
+	require.NoError(t, err)
+
+	raw, err := buildMIMEMessage(msg, from, time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	assert.Equal(t, "<verification-123@example.com>", parsed.Header.Get("Message-ID"))
+	assert.Equal(t, "<https://api.example.com/api/v1/notifications/unsubscribe?token=abc>", parsed.Header.Get("List-Unsubscribe"))
+	// Line breaks can't inject headers
+	assert.Equal(t, "List-Unsubscribe=One-ClickBcc: attacker@example.com", parsed.Header.Get("List-Unsubscribe-Post"))
+	assert.Empty(t, parsed.Header.Get("Bcc"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	// multipart.Reader decodes quoted-printable parts
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}
+
+	require.Len(t, bodies, 2)
+	assert.Equal(t, "Bonjour,\r\nhttp://localhost:3000/verify?token=abc", bodies[0])
+	assert.Equal(t, msg.HTML, bodies[1])
+}
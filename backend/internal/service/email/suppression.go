@@ -0,0 +1,172 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// Delivery event types sent by Resend
+const (
+	EventTypeBounced    = "email.bounced"
+	EventTypeComplained = "email.complained"
+)
+
+// bounceTypePermanent is the bounce type of hard bounces
+const bounceTypePermanent = "Permanent"
+
+// ErrRecipientSuppressed is returned when an email is not sent because its
+// recipient is on the suppression list
+var ErrRecipientSuppressed = errors.New("recipient is on the suppression list")
+
+// SuppressionRepository defines the interface for email suppression database operations
+type SuppressionRepository interface {
+	// AddSuppression adds an address to the suppression list, keeping an existing entry
+	AddSuppression(ctx context.Context, suppression *model.EmailSuppression) error
+
+	// GetSuppression gets the suppression of an address, or nil if it is not suppressed
+	GetSuppression(ctx context.Context, email string) (*model.EmailSuppression, error)
+
+	// ListSuppressions gets a page of suppressed addresses
+	ListSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, error)
+
+	// DeleteSuppression removes an address from the suppression list
+	DeleteSuppression(ctx context.Context, email string) (bool, error)
+}
+
+// DeliveryEvent is a delivery event received from the email provider's webhook
+type DeliveryEvent struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      struct {
+		EmailID string   `json:"email_id"`
+		To      []string `json:"to"`
+		Bounce  *struct {
+			Type    string `json:"type"`
+			SubType string `json:"subType"`
+			Message string `json:"message"`
+		} `json:"bounce,omitempty"`
+	} `json:"data"`
+}
+
+// SuppressionList keeps track of addresses that must not be emailed: addresses
+// that hard-bounced, and recipients that marked an email as spam. Sending to
+// them anyway hurts the sender reputation of every other email.
+type SuppressionList struct {
+	repo SuppressionRepository
+	log  logger.Logger
+}
+
+// NewSuppressionList creates a new SuppressionList
+func NewSuppressionList(repo SuppressionRepository, log logger.Logger) *SuppressionList {
+	return &SuppressionList{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// IsSuppressed reports whether an address is on the suppression list
+func (l *SuppressionList) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	suppression, err := l.GetSuppression(ctx, email)
+	if err != nil {
+		return false, err
+	}
+
+	return suppression != nil, nil
+}
+
+// GetSuppression gets the suppression of an address, or nil if it is not suppressed
+func (l *SuppressionList) GetSuppression(ctx context.Context, email string) (*model.EmailSuppression, error) {
+	return l.repo.GetSuppression(ctx, normalizeAddress(email))
+}
+
+// Suppress adds an address to the suppression list
+func (l *SuppressionList) Suppress(ctx context.Context, email, reason, details, eventID string) error {
+	suppression := &model.EmailSuppression{
+		Email:     normalizeAddress(email),
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if details != "" {
+		suppression.Details = &details
+	}
+	if eventID != "" {
+		suppression.EventID = &eventID
+	}
+
+	return l.repo.AddSuppression(ctx, suppression)
+}
+
+// ListSuppressions lists suppressed addresses, newest first
+func (l *SuppressionList) ListSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, error) {
+	if limit <= 0 {
+		limit = DefaultOutboxPageSize
+	}
+	if limit > MaxOutboxPageSize {
+		limit = MaxOutboxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return l.repo.ListSuppressions(ctx, limit, offset)
+}
+
+// RemoveSuppression removes an address from the suppression list, e.g. after
+// its owner fixed their mailbox
+func (l *SuppressionList) RemoveSuppression(ctx context.Context, email string) error {
+	removed, err := l.repo.DeleteSuppression(ctx, normalizeAddress(email))
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return apperrors.NewNotFoundError("email address is not suppressed")
+	}
+
+	return nil
+}
+
+// HandleDeliveryEvent suppresses the recipients of hard-bounced and
+// complained-about emails. Other events, including soft bounces, are ignored.
+func (l *SuppressionList) HandleDeliveryEvent(ctx context.Context, eventID string, event *DeliveryEvent) error {
+	var reason, details string
+	switch event.Type {
+	case EventTypeBounced:
+		// Older events carry no bounce details, and were only sent for hard bounces
+		if bounce := event.Data.Bounce; bounce != nil {
+			if bounce.Type != "" && bounce.Type != bounceTypePermanent {
+				l.log.Info("Ignoring soft bounce", "event_id", eventID, "email_id", event.Data.EmailID, "bounce_type", bounce.Type)
+				return nil
+			}
+			details = bounce.Message
+			if bounce.SubType != "" {
+				details = bounce.SubType + ": " + details
+			}
+		}
+		reason = model.SuppressionReasonBounce
+	case EventTypeComplained:
+		reason = model.SuppressionReasonComplaint
+	default:
+		return nil
+	}
+
+	for _, to := range event.Data.To {
+		if err := l.Suppress(ctx, to, reason, details, eventID); err != nil {
+			return fmt.Errorf("failed to suppress recipient: %w", err)
+		}
+		l.log.Info("Suppressed email address", "event_id", eventID, "email_id", event.Data.EmailID, "reason", reason)
+	}
+
+	return nil
+}
+
+// normalizeAddress lowercases an address so lookups are case-insensitive
+func normalizeAddress(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,96 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSuppressionRepository is a mock implementation of SuppressionRepository
+type mockSuppressionRepository struct {
+	mock.Mock
+}
+
+func (m *mockSuppressionRepository) AddSuppression(ctx context.Context, suppression *model.EmailSuppression) error {
+	args := m.Called(ctx, suppression)
+	return args.Error(0)
+}
+
+func (m *mockSuppressionRepository) GetSuppression(ctx context.Context, email string) (*model.EmailSuppression, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.EmailSuppression), args.Error(1)
+}
+
+func (m *mockSuppressionRepository) ListSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]*model.EmailSuppression), args.Error(1)
+}
+
+func (m *mockSuppressionRepository) DeleteSuppression(ctx context.Context, email string) (bool, error) {
+	args := m.Called(ctx, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func decodeEvent(t *testing.T, payload string) *DeliveryEvent {
+	var event DeliveryEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	return &event
+}
+
+func TestHandleDeliveryEvent(t *testing.T) {
+	repo := new(mockSuppressionRepository)
+	list := NewSuppressionList(repo, logger.DefaultLogger())
+	ctx := context.Background()
+
+	// Hard bounces suppress the lowercased recipient
+	repo.On("AddSuppression", ctx, mock.MatchedBy(func(s *model.EmailSuppression) bool {
+		return s.Email == "user@example.com" && s.Reason == model.SuppressionReasonBounce &&
+			*s.Details == "Suppressed: Mailbox does not exist" && *s.EventID == "msg_1"
+	})).Return(nil).Once()
+
+	err := list.HandleDeliveryEvent(ctx, "msg_1", decodeEvent(t, `{"type":"email.bounced","data":{"email_id":"re_1","to":["User@Example.com"],"bounce":{"type":"Permanent","subType":"Suppressed","message":"Mailbox does not exist"}}}`))
+	require.NoError(t, err)
+
+	// Soft bounces and other events are ignored
+	err = list.HandleDeliveryEvent(ctx, "msg_2", decodeEvent(t, `{"type":"email.bounced","data":{"to":["user@example.com"],"bounce":{"type":"Transient","message":"Mailbox full"}}}`))
+	require.NoError(t, err)
+	err = list.HandleDeliveryEvent(ctx, "msg_3", decodeEvent(t, `{"type":"email.delivered","data":{"to":["user@example.com"]}}`))
+	require.NoError(t, err)
+
+	// Complaints suppress the recipient
+	repo.On("AddSuppression", ctx, mock.MatchedBy(func(s *model.EmailSuppression) bool {
+		return s.Email == "other@example.com" && s.Reason == model.SuppressionReasonComplaint
+	})).Return(nil).Once()
+
+	err = list.HandleDeliveryEvent(ctx, "msg_4", decodeEvent(t, `{"type":"email.complained","data":{"to":["other@example.com"]}}`))
+	require.NoError(t, err)
+
+	repo.AssertExpectations(t)
+}
+
+func TestMailerSkipsSuppressedRecipients(t *testing.T) {
+	repo := new(mockSuppressionRepository)
+	transport := new(mockTransport)
+
+	m, err := newMailer(&config.EmailConfig{FromEmail: "noreply@example.com"}, &config.AppConfig{Name: "Go+Next"}, transport)
+	require.NoError(t, err)
+	m.suppressions = NewSuppressionList(repo, logger.DefaultLogger())
+
+	ctx := context.Background()
+	repo.On("GetSuppression", ctx, "user@example.com").Return(&model.EmailSuppression{Email: "user@example.com"}, nil)
+
+	err = m.SendWelcomeEmail(ctx, "user@example.com", "John Doe")
+	assert.True(t, errors.Is(err, ErrRecipientSuppressed))
+	transport.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
@@ -14,11 +14,9 @@ import (
 
 // UpstashWorkflowService implements the EmailService interface using Upstash Workflow
 type UpstashWorkflowService struct {
-	config           *config.EmailConfig
-	workflowURL      string
-	workflowToken    string
-	verificationTTL  time.Duration
-	passwordResetTTL time.Duration
+	*mailer
+	workflowURL   string
+	workflowToken string
 }
 
 // WorkflowRequest represents a request to the Upstash Workflow API
@@ -31,7 +29,7 @@ type WorkflowRequest struct {
 }
 
 // NewUpstashWorkflowService creates a new UpstashWorkflowService
-func NewUpstashWorkflowService(cfg *config.EmailConfig) (*UpstashWorkflowService, error) {
+func NewUpstashWorkflowService(cfg *config.EmailConfig, appCfg *config.AppConfig) (*UpstashWorkflowService, error) {
 	if cfg.UpstashWorkflowURL == "" {
 		return nil, fmt.Errorf("upstash workflow URL is required")
 	}
@@ -39,47 +37,41 @@ func NewUpstashWorkflowService(cfg *config.EmailConfig) (*UpstashWorkflowService
 		return nil, fmt.Errorf("upstash workflow token is required")
 	}
 
-	return &UpstashWorkflowService{
-		config:           cfg,
-		workflowURL:      cfg.UpstashWorkflowURL,
-		workflowToken:    cfg.UpstashWorkflowToken,
-		verificationTTL:  cfg.VerificationTTL,
-		passwordResetTTL: cfg.PasswordResetTTL,
-	}, nil
-}
-
-// SendVerificationEmail sends a verification email to the user
-func (s *UpstashWorkflowService) SendVerificationEmail(ctx context.Context, to string, token string) error {
-	verificationURL := fmt.Sprintf("%s?token=%s", s.config.VerificationURL, token)
+	s := &UpstashWorkflowService{
+		workflowURL:   cfg.UpstashWorkflowURL,
+		workflowToken: cfg.UpstashWorkflowToken,
+	}
 
-	data := map[string]interface{}{
-		"to":      to,
-		"from":    s.config.FromEmail,
-		"subject": "Verify your email address",
-		"body":    fmt.Sprintf("Please verify your email by clicking on the following link: %s", verificationURL),
-		"html":    fmt.Sprintf("<p>Please verify your email by clicking on the following link: <a href=\"%s\">Verify Email</a></p>", verificationURL),
+	m, err := newMailer(cfg, appCfg, s)
+	if err != nil {
+		return nil, err
 	}
+	s.mailer = m
 
-	return s.triggerWorkflow("send-email", data)
+	return s, nil
 }
 
-// SendPasswordResetEmail sends a password reset email to the user
-func (s *UpstashWorkflowService) SendPasswordResetEmail(ctx context.Context, to string, token string) error {
-	resetURL := fmt.Sprintf("%s?token=%s", s.config.PasswordResetURL, token)
-
+// Send delivers a message through the send-email workflow
+func (s *UpstashWorkflowService) Send(ctx context.Context, msg *Message) error {
 	data := map[string]interface{}{
-		"to":      to,
-		"from":    s.config.FromEmail,
-		"subject": "Reset your password",
-		"body":    fmt.Sprintf("Please reset your password by clicking on the following link: %s", resetURL),
-		"html":    fmt.Sprintf("<p>Please reset your password by clicking on the following link: <a href=\"%s\">Reset Password</a></p>", resetURL),
+		"to":      strings.Join(msg.To, ","),
+		"from":    msg.From,
+		"subject": msg.Subject,
+		"body":    msg.Text,
+		"html":    msg.HTML,
+	}
+	if msg.IdempotencyKey != "" {
+		data["idempotency_key"] = msg.IdempotencyKey
+	}
+	if len(msg.Headers) > 0 {
+		data["headers"] = msg.Headers
 	}
 
-	return s.triggerWorkflow("send-email", data)
+	return s.triggerWorkflow(ctx, "send-email", data)
 }
 
 // triggerWorkflow sends a request to the Upstash Workflow API
-func (s *UpstashWorkflowService) triggerWorkflow(name string, data map[string]interface{}) error {
+func (s *UpstashWorkflowService) triggerWorkflow(ctx context.Context, name string, data map[string]interface{}) error {
 	workflowReq := WorkflowRequest{
 		Name: name,
 		Data: data,
@@ -90,7 +82,7 @@ func (s *UpstashWorkflowService) triggerWorkflow(name string, data map[string]in
 		return fmt.Errorf("failed to marshal workflow request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", s.workflowURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.workflowURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -111,53 +103,3 @@ func (s *UpstashWorkflowService) triggerWorkflow(name string, data map[string]in
 
 	return nil
 }
-
-// SendWelcomeEmail sends a welcome email
-func (s *UpstashWorkflowService) SendWelcomeEmail(ctx context.Context, to, userName string) error {
-	data := map[string]interface{}{
-		"userName": userName,
-	}
-
-	return s.triggerWorkflow("welcome", data)
-}
-
-// SendLoginNotificationEmail sends a login notification email
-func (s *UpstashWorkflowService) SendLoginNotificationEmail(ctx context.Context, to, deviceInfo, location string) error {
-	if !s.config.LoginNotification {
-		return nil
-	}
-
-	data := map[string]interface{}{
-		"deviceInfo": deviceInfo,
-		"location":   location,
-		"time":       time.Now().Format(time.RFC1123),
-	}
-
-	return s.triggerWorkflow("login_notification", data)
-}
-
-// SendPasswordChangedEmail sends a password changed notification email
-func (s *UpstashWorkflowService) SendPasswordChangedEmail(ctx context.Context, to string) error {
-	data := map[string]interface{}{
-		"time": time.Now().Format(time.RFC1123),
-	}
-
-	return s.triggerWorkflow("password_changed", data)
-}
-
-// ValidateEmailAddress validates an email address
-func (s *UpstashWorkflowService) ValidateEmailAddress(email string) bool {
-	email = strings.TrimSpace(strings.ToLower(email))
-	if email == "" {
-		return false
-	}
-
-	parts := strings.Split(email, "@")
-	return len(parts) == 2
-}
-
-// ParseTemplate parses a template with data
-func (s *UpstashWorkflowService) ParseTemplate(templateName string, data interface{}) (string, error) {
-	// Not needed for Upstash Workflow as templates are managed by the workflow
-	return "", nil
-}
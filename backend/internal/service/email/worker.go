@@ -0,0 +1,170 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/backoff"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// outboxSendTimeout bounds a single delivery attempt
+	outboxSendTimeout = 30 * time.Second
+	// outboxClaimLease is how long a claimed email is reserved for a worker. A
+	// claim that outlives it is assumed lost and the email is claimed again.
+	outboxClaimLease = 2 * time.Minute
+)
+
+// Defaults used when the outbox settings are not configured
+const (
+	defaultOutboxPollInterval   = 5 * time.Second
+	defaultOutboxBatchSize      = 20
+	defaultOutboxMaxAttempts    = 10
+	defaultOutboxRetryBaseDelay = 30 * time.Second
+	defaultOutboxRetryMaxDelay  = time.Hour
+)
+
+// OutboxWorker delivers emails from the outbox through a Transport, retrying
+// failed deliveries with exponential backoff and dead-lettering emails that
+// still fail after the maximum number of attempts. Several workers, e.g. one
+// per API replica, can safely share an outbox.
+type OutboxWorker struct {
+	repo           OutboxRepository
+	transport      Transport
+	logger         logger.Logger
+	pollInterval   time.Duration
+	batchSize      int
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+// NewOutboxWorker creates a worker delivering outbox emails through transport
+func NewOutboxWorker(repo OutboxRepository, transport Transport, cfg *config.EmailConfig, log logger.Logger) *OutboxWorker {
+	w := &OutboxWorker{
+		repo:           repo,
+		transport:      transport,
+		logger:         log,
+		pollInterval:   cfg.OutboxPollInterval,
+		batchSize:      cfg.OutboxBatchSize,
+		maxAttempts:    cfg.OutboxMaxAttempts,
+		retryBaseDelay: cfg.OutboxRetryBaseDelay,
+		retryMaxDelay:  cfg.OutboxRetryMaxDelay,
+	}
+
+	if w.pollInterval <= 0 {
+		w.pollInterval = defaultOutboxPollInterval
+	}
+	if w.batchSize <= 0 {
+		w.batchSize = defaultOutboxBatchSize
+	}
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = defaultOutboxMaxAttempts
+	}
+	if w.retryBaseDelay <= 0 {
+		w.retryBaseDelay = defaultOutboxRetryBaseDelay
+	}
+	if w.retryMaxDelay <= 0 {
+		w.retryMaxDelay = defaultOutboxRetryMaxDelay
+	}
+
+	return w
+}
+
+// Run delivers due emails every poll interval until ctx is cancelled
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while batches come back full, so a backlog drains quickly
+		for {
+			n, err := w.ProcessBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.logger.Error("Failed to process email outbox", "error", err)
+				}
+				break
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims one batch of due emails and attempts to deliver them,
+// returning the number of emails claimed
+func (w *OutboxWorker) ProcessBatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	emails, err := w.repo.ClaimDueEmails(ctx, now, now.Add(outboxClaimLease), w.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, email := range emails {
+		w.deliver(ctx, email)
+	}
+
+	return len(emails), nil
+}
+
+// deliver attempts to send a claimed email and records the outcome
+func (w *OutboxWorker) deliver(ctx context.Context, email *model.OutboxEmail) {
+	msg := &Message{
+		Template:       email.Template,
+		From:           email.FromAddress,
+		To:             strings.Split(email.ToAddresses, ","),
+		Subject:        email.Subject,
+		HTML:           email.HTMLBody,
+		Text:           email.TextBody,
+		IdempotencyKey: email.IdempotencyKey,
+	}
+	if email.Headers != "" {
+		if err := json.Unmarshal([]byte(email.Headers), &msg.Headers); err != nil {
+			w.logger.Warn("Ignoring invalid email headers", "email_id", email.ID, "error", err)
+		}
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	sendErr := w.transport.Send(sendCtx, msg)
+	cancel()
+
+	// Record the outcome even if we are shutting down, otherwise the email
+	// would stay claimed until its lease runs out
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	log := w.logger.With("email_id", email.ID, "template", email.Template, "attempt", email.Attempts)
+
+	if sendErr == nil {
+		if err := w.repo.MarkEmailSent(ctx, email.ID, now); err != nil {
+			log.Error("Failed to mark email as sent", "error", err)
+		}
+		return
+	}
+
+	if email.Attempts >= w.maxAttempts {
+		log.Error("Email dead-lettered after final delivery attempt", "error", sendErr)
+		if err := w.repo.MarkEmailDead(ctx, email.ID, sendErr.Error(), now); err != nil {
+			log.Error("Failed to dead-letter email", "error", err)
+		}
+		return
+	}
+
+	nextAttemptAt := now.Add(backoff.Delay(email.Attempts, w.retryBaseDelay, w.retryMaxDelay))
+	log.Warn("Email delivery failed, will retry", "error", sendErr, "next_attempt_at", nextAttemptAt)
+	if err := w.repo.MarkEmailFailed(ctx, email.ID, sendErr.Error(), nextAttemptAt, now); err != nil {
+		log.Error("Failed to record email delivery failure", "error", err)
+	}
+}
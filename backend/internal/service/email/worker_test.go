@@ -0,0 +1,116 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockOutboxRepository is a mock implementation of OutboxRepository
+type mockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *mockOutboxRepository) EnqueueEmail(ctx context.Context, email *model.OutboxEmail) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepository) ClaimDueEmails(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.OutboxEmail, error) {
+	args := m.Called(ctx, now, lockedUntil, limit)
+	return args.Get(0).([]*model.OutboxEmail), args.Error(1)
+}
+
+func (m *mockOutboxRepository) MarkEmailSent(ctx context.Context, id string, sentAt time.Time) error {
+	args := m.Called(ctx, id, sentAt)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepository) MarkEmailFailed(ctx context.Context, id, lastError string, nextAttemptAt, now time.Time) error {
+	args := m.Called(ctx, id, lastError, nextAttemptAt, now)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepository) MarkEmailDead(ctx context.Context, id, lastError string, now time.Time) error {
+	args := m.Called(ctx, id, lastError, now)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepository) RetryEmail(ctx context.Context, id string, now time.Time) (bool, error) {
+	args := m.Called(ctx, id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockOutboxRepository) GetEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.OutboxEmail), args.Error(1)
+}
+
+func (m *mockOutboxRepository) ListEmails(ctx context.Context, filter model.OutboxFilter) ([]*model.OutboxEmail, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*model.OutboxEmail), args.Error(1)
+}
+
+func (m *mockOutboxRepository) CountEmailsByStatus(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+// mockTransport is a mock implementation of Transport
+type mockTransport struct {
+	mock.Mock
+}
+
+func (m *mockTransport) Send(ctx context.Context, msg *Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func TestOutboxWorkerProcessBatch(t *testing.T) {
+	cfg := &config.EmailConfig{
+		OutboxBatchSize:      10,
+		OutboxMaxAttempts:    3,
+		OutboxRetryBaseDelay: time.Minute,
+		OutboxRetryMaxDelay:  time.Hour,
+	}
+
+	sent := &model.OutboxEmail{ID: "sent", IdempotencyKey: "key-sent", ToAddresses: "a@example.com", Attempts: 1}
+	retried := &model.OutboxEmail{ID: "retried", IdempotencyKey: "key-retried", ToAddresses: "b@example.com", Attempts: 2}
+	dead := &model.OutboxEmail{ID: "dead", IdempotencyKey: "key-dead", ToAddresses: "c@example.com", Attempts: 3}
+
+	repo := new(mockOutboxRepository)
+	repo.On("ClaimDueEmails", mock.Anything, mock.Anything, mock.Anything, 10).
+		Return([]*model.OutboxEmail{sent, retried, dead}, nil)
+	repo.On("MarkEmailSent", mock.Anything, "sent", mock.Anything).Return(nil)
+	repo.On("MarkEmailFailed", mock.Anything, "retried", "provider unavailable", mock.MatchedBy(func(next time.Time) bool {
+		// Second failure: base delay doubled, plus up to 20% jitter
+		delay := time.Until(next)
+		return delay > time.Minute && delay <= 2*time.Minute+24*time.Second
+	}), mock.Anything).Return(nil)
+	repo.On("MarkEmailDead", mock.Anything, "dead", "provider unavailable", mock.Anything).Return(nil)
+
+	transport := new(mockTransport)
+	transport.On("Send", mock.Anything, mock.MatchedBy(func(msg *Message) bool {
+		return msg.IdempotencyKey == "key-sent"
+	})).Return(nil)
+	transport.On("Send", mock.Anything, mock.Anything).Return(errors.New("provider unavailable"))
+
+	worker := NewOutboxWorker(repo, transport, cfg, logger.DefaultLogger())
+
+	n, err := worker.ProcessBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	repo.AssertExpectations(t)
+	transport.AssertExpectations(t)
+}
@@ -0,0 +1,123 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// FileSink appends events to a file as JSON lines. When the file would grow
+// past its maximum size it is rotated: path becomes path.1, path.1 becomes
+// path.2 and so on, keeping a maximum number of rotated files.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileSink creates a sink appending events to the file at path, rotated
+// at maxSize bytes (never when 0)
+func NewFileSink(path string, maxSize int64, maxBackups int) *FileSink {
+	return &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+}
+
+// Name identifies the sink
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+// Write appends a batch of events to the file
+func (s *FileSink) Write(ctx context.Context, events []*model.StreamEvent) error {
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		line = append(line, '\n')
+
+		if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+
+		n, err := s.file.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write event file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open opens the file for appending, creating it if needed
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open event file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open event file: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files, dropping the oldest one, and starts a
+// new file
+func (s *FileSink) rotate() error {
+	if err := s.Close(); err != nil {
+		return fmt.Errorf("failed to close event file: %w", err)
+	}
+
+	if s.maxBackups > 0 {
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate event file: %w", err)
+			}
+		}
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate event file: %w", err)
+		}
+	} else if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate event file: %w", err)
+	}
+
+	return s.open()
+}
+
+// backupPath returns the path of the nth rotated file
+func (s *FileSink) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", s.path, n)
+}
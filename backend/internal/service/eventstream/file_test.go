@@ -0,0 +1,68 @@
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents reads the events of a JSON lines file
+func readEvents(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event model.StreamEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event.ID)
+	}
+	require.NoError(t, scanner.Err())
+	return ids
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	line, err := json.Marshal(testEvent(0))
+	require.NoError(t, err)
+	// Room for two events per file
+	sink := NewFileSink(path, int64(2*(len(line)+1)), 2)
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, sink.Write(context.Background(), []*model.StreamEvent{testEvent(i)}))
+	}
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, []string{"event-6"}, readEvents(t, path))
+	assert.Equal(t, []string{"event-4", "event-5"}, readEvents(t, path+".1"))
+	assert.Equal(t, []string{"event-2", "event-3"}, readEvents(t, path+".2"))
+	assert.NoFileExists(t, path+".3")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	for i := 0; i < 2; i++ {
+		sink := NewFileSink(path, 0, 0)
+		require.NoError(t, sink.Write(context.Background(), []*model.StreamEvent{testEvent(i)}))
+		require.NoError(t, sink.Close())
+	}
+
+	assert.Equal(t, []string{"event-0", "event-1"}, readEvents(t, path))
+}
@@ -0,0 +1,125 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/audit"
+	"github.com/nanayaw/fullstack/internal/service/security"
+)
+
+// Publisher publishes events to a stream
+type Publisher interface {
+	Publish(event *model.StreamEvent)
+}
+
+// AuditRepository is an audit repository publishing every entry it records
+type AuditRepository struct {
+	audit.Repository
+	stream Publisher
+}
+
+// NewAuditRepository wraps an audit repository to publish the entries it
+// records to a stream
+func NewAuditRepository(repo audit.Repository, stream Publisher) *AuditRepository {
+	return &AuditRepository{Repository: repo, stream: stream}
+}
+
+// CreateAuditLog records an entry and publishes it once it is stored
+func (r *AuditRepository) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	if err := r.Repository.CreateAuditLog(ctx, log); err != nil {
+		return err
+	}
+	r.stream.Publish(AuditLogEvent(log))
+	return nil
+}
+
+// SecurityRepository is a security repository publishing every security
+// event it records
+type SecurityRepository struct {
+	security.Repository
+	stream Publisher
+}
+
+// NewSecurityRepository wraps a security repository to publish the security
+// events it records to a stream
+func NewSecurityRepository(repo security.Repository, stream Publisher) *SecurityRepository {
+	return &SecurityRepository{Repository: repo, stream: stream}
+}
+
+// RecordSecurityEvent records a security event and publishes it once it is
+// stored
+func (r *SecurityRepository) RecordSecurityEvent(ctx context.Context, event *model.SecurityEvent) error {
+	if err := r.Repository.RecordSecurityEvent(ctx, event); err != nil {
+		return err
+	}
+	r.stream.Publish(SecurityEventEvent(event))
+	return nil
+}
+
+// AuditLogEvent returns the stream event of an audit log entry
+func AuditLogEvent(log *models.AuditLog) *model.StreamEvent {
+	data := map[string]interface{}{
+		"sequence":    log.Sequence,
+		"entity_type": log.EntityType,
+		"entity_id":   log.EntityID,
+		"hash":        log.Hash,
+		"prev_hash":   log.PrevHash,
+	}
+	if log.ServiceAccountID != "" {
+		data["service_account_id"] = log.ServiceAccountID
+	}
+	if log.RequestID != "" {
+		data["request_id"] = log.RequestID
+	}
+	if log.Metadata != "" {
+		if json.Valid([]byte(log.Metadata)) {
+			data["metadata"] = json.RawMessage(log.Metadata)
+		} else {
+			data["metadata"] = log.Metadata
+		}
+	}
+
+	return &model.StreamEvent{
+		ID:        log.ID,
+		Kind:      model.StreamEventAuditLog,
+		Type:      log.Action,
+		Time:      log.CreatedAt,
+		Actor:     log.Actor,
+		UserID:    log.UserID,
+		IPAddress: log.IPAddress,
+		Data:      data,
+	}
+}
+
+// SecurityEventEvent returns the stream event of a security event
+func SecurityEventEvent(event *model.SecurityEvent) *model.StreamEvent {
+	data := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"user_agent":  event.UserAgent,
+		"location":    event.Location,
+		"description": event.Description,
+	} {
+		if value != "" {
+			data[key] = value
+		}
+	}
+
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return &model.StreamEvent{
+		ID:        event.ID,
+		Kind:      model.StreamEventSecurityEvent,
+		Type:      event.EventType,
+		Time:      createdAt,
+		UserID:    event.UserID,
+		IPAddress: event.IPAddress,
+		Data:      data,
+	}
+}
@@ -0,0 +1,32 @@
+package eventstream
+
+import (
+	"github.com/nanayaw/fullstack/internal/config"
+)
+
+// NewSinks creates the sinks enabled in the configuration
+func NewSinks(cfg *config.EventStreamConfig) ([]Sink, error) {
+	var sinks []Sink
+
+	if cfg.SyslogAddress != "" {
+		sink, err := NewSyslogSink(cfg.SyslogNetwork, cfg.SyslogAddress, cfg.SyslogAppName)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if cfg.FilePath != "" {
+		sinks = append(sinks, NewFileSink(cfg.FilePath, cfg.FileMaxSize, cfg.FileMaxBackups))
+	}
+
+	if cfg.WebhookURL != "" {
+		sink, err := NewWebhookSink(cfg.WebhookURL, cfg.WebhookSecret)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
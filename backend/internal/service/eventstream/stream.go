@@ -0,0 +1,284 @@
+// Package eventstream streams audit log entries and security events to
+// external sinks, such as the syslog collector or webhook of a SIEM.
+package eventstream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/backoff"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// writeTimeout bounds a single write to a sink
+const writeTimeout = 10 * time.Second
+
+// Defaults used when the event stream settings are not configured
+const (
+	defaultBufferSize     = 10000
+	defaultBatchSize      = 100
+	defaultMaxAttempts    = 5
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// Sink is a destination events are exported to
+type Sink interface {
+	// Name identifies the sink in logs and health reports
+	Name() string
+
+	// Write delivers a batch of events, in order. A batch that fails is
+	// written again, so sinks may receive events more than once.
+	Write(ctx context.Context, events []*model.StreamEvent) error
+
+	// Close releases the resources held by the sink
+	Close() error
+}
+
+// Stream publishes events to sinks. Every sink has its own buffer and
+// worker, so a slow or failing sink doesn't hold up the others, nor the
+// requests publishing events: when a buffer is full, new events are dropped
+// for that sink and counted in its health.
+type Stream struct {
+	sinks  []*sinkWorker
+	logger logger.Logger
+}
+
+// NewStream creates a stream publishing to sinks. It only delivers events
+// once Run is called.
+func NewStream(sinks []Sink, cfg *config.EventStreamConfig, log logger.Logger) *Stream {
+	opts := sinkOptions{
+		bufferSize:     cfg.BufferSize,
+		batchSize:      cfg.BatchSize,
+		maxAttempts:    cfg.MaxAttempts,
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+	}
+
+	if opts.bufferSize <= 0 {
+		opts.bufferSize = defaultBufferSize
+	}
+	if opts.batchSize <= 0 {
+		opts.batchSize = defaultBatchSize
+	}
+	if opts.maxAttempts <= 0 {
+		opts.maxAttempts = defaultMaxAttempts
+	}
+	if opts.retryBaseDelay <= 0 {
+		opts.retryBaseDelay = defaultRetryBaseDelay
+	}
+	if opts.retryMaxDelay <= 0 {
+		opts.retryMaxDelay = defaultRetryMaxDelay
+	}
+
+	s := &Stream{logger: log}
+	for _, sink := range sinks {
+		s.sinks = append(s.sinks, &sinkWorker{
+			sink:   sink,
+			opts:   opts,
+			queue:  make(chan *model.StreamEvent, opts.bufferSize),
+			logger: log.With("sink", sink.Name()),
+			health: model.EventSinkHealth{Name: sink.Name(), Healthy: true},
+		})
+	}
+
+	return s
+}
+
+// Publish queues an event for every sink without blocking
+func (s *Stream) Publish(event *model.StreamEvent) {
+	for _, w := range s.sinks {
+		w.enqueue(event)
+	}
+}
+
+// Run delivers published events until ctx is cancelled, then delivers the
+// events still buffered, making a single attempt for each batch, and closes
+// the sinks
+func (s *Stream) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, w := range s.sinks {
+		wg.Add(1)
+		go func(w *sinkWorker) {
+			defer wg.Done()
+			w.run(ctx)
+		}(w)
+	}
+	wg.Wait()
+}
+
+// Health reports the health of every sink
+func (s *Stream) Health() []model.EventSinkHealth {
+	health := make([]model.EventSinkHealth, len(s.sinks))
+	for i, w := range s.sinks {
+		health[i] = w.snapshot()
+	}
+	return health
+}
+
+// sinkOptions are the buffering and retry settings of a sink worker
+type sinkOptions struct {
+	bufferSize     int
+	batchSize      int
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+// sinkWorker delivers the events buffered for a sink
+type sinkWorker struct {
+	sink   Sink
+	opts   sinkOptions
+	queue  chan *model.StreamEvent
+	logger logger.Logger
+
+	mu     sync.Mutex
+	health model.EventSinkHealth
+	// Whether events are being dropped because the buffer is full, so it is
+	// only logged once while it lasts
+	overflowing bool
+}
+
+// enqueue buffers an event, dropping it if the buffer is full
+func (w *sinkWorker) enqueue(event *model.StreamEvent) {
+	select {
+	case w.queue <- event:
+		w.mu.Lock()
+		w.overflowing = false
+		w.mu.Unlock()
+	default:
+		w.mu.Lock()
+		w.health.Dropped++
+		logOverflow := !w.overflowing
+		w.overflowing = true
+		w.mu.Unlock()
+
+		if logOverflow {
+			w.logger.Warn("Event sink buffer is full, dropping events", "buffer_size", w.opts.bufferSize)
+		}
+	}
+}
+
+// run delivers buffered events in batches until ctx is cancelled
+func (w *sinkWorker) run(ctx context.Context) {
+	defer func() {
+		if err := w.sink.Close(); err != nil {
+			w.logger.Warn("Failed to close event sink", "error", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.drain(ctx)
+			return
+		case event := <-w.queue:
+			w.deliver(ctx, w.fill([]*model.StreamEvent{event}))
+		}
+	}
+}
+
+// drain delivers the events left in the buffer
+func (w *sinkWorker) drain(ctx context.Context) {
+	for {
+		select {
+		case event := <-w.queue:
+			w.deliver(ctx, w.fill([]*model.StreamEvent{event}))
+		default:
+			return
+		}
+	}
+}
+
+// fill adds the events already buffered to a batch, up to the batch size
+func (w *sinkWorker) fill(batch []*model.StreamEvent) []*model.StreamEvent {
+	for len(batch) < w.opts.batchSize {
+		select {
+		case event := <-w.queue:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// deliver writes a batch to the sink, retrying with exponential backoff and
+// dropping the batch after the maximum number of attempts. Once ctx is
+// cancelled, the next attempt is made right away and is the last one.
+func (w *sinkWorker) deliver(ctx context.Context, batch []*model.StreamEvent) {
+	for attempt := 1; ; attempt++ {
+		// Let a write in progress finish when shutting down
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+		err := w.sink.Write(writeCtx, batch)
+		cancel()
+
+		if err == nil {
+			w.succeeded(len(batch))
+			return
+		}
+		w.failed(err)
+
+		if attempt >= w.opts.maxAttempts || ctx.Err() != nil {
+			w.mu.Lock()
+			w.health.Dropped += int64(len(batch))
+			w.mu.Unlock()
+			w.logger.Error("Dropping events after failed delivery attempts", "events", len(batch), "attempts", attempt, "error", err)
+			return
+		}
+
+		timer := time.NewTimer(backoff.Delay(attempt, w.opts.retryBaseDelay, w.opts.retryMaxDelay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// succeeded records a successful delivery
+func (w *sinkWorker) succeeded(n int) {
+	now := time.Now()
+
+	w.mu.Lock()
+	recovered := !w.health.Healthy
+	w.health.Healthy = true
+	w.health.Delivered += int64(n)
+	w.health.ConsecutiveFailures = 0
+	w.health.LastSuccessAt = &now
+	w.mu.Unlock()
+
+	if recovered {
+		w.logger.Info("Event sink recovered")
+	}
+}
+
+// failed records a failed delivery attempt
+func (w *sinkWorker) failed(err error) {
+	now := time.Now()
+
+	w.mu.Lock()
+	healthy := w.health.Healthy
+	w.health.Healthy = false
+	w.health.ConsecutiveFailures++
+	w.health.LastError = err.Error()
+	w.health.LastFailureAt = &now
+	w.mu.Unlock()
+
+	if healthy {
+		w.logger.Warn("Event sink is unhealthy", "error", err)
+	}
+}
+
+// snapshot returns a copy of the health of the sink
+func (w *sinkWorker) snapshot() model.EventSinkHealth {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	health := w.health
+	health.Buffered = len(w.queue)
+	return health
+}
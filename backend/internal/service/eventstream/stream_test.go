@@ -0,0 +1,281 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/internal/service/audit"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink records the events written to it, failing the first writes
+type fakeSink struct {
+	mu       sync.Mutex
+	failures int
+	events   []*model.StreamEvent
+	writes   int
+	closed   bool
+	// Closed when a write is attempted, if set
+	writing chan struct{}
+	// Blocks writes until closed, if set
+	release chan struct{}
+}
+
+func (f *fakeSink) Name() string {
+	return "fake"
+}
+
+func (f *fakeSink) Write(ctx context.Context, events []*model.StreamEvent) error {
+	if f.writing != nil {
+		select {
+		case f.writing <- struct{}{}:
+		default:
+		}
+	}
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writes++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("sink unavailable")
+	}
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	return nil
+}
+
+func (f *fakeSink) received() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.events)
+}
+
+func testConfig() *config.EventStreamConfig {
+	return &config.EventStreamConfig{
+		BufferSize:     100,
+		BatchSize:      10,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay:  5 * time.Millisecond,
+	}
+}
+
+func testEvent(i int) *model.StreamEvent {
+	return &model.StreamEvent{
+		ID:   fmt.Sprintf("event-%d", i),
+		Kind: model.StreamEventAuditLog,
+		Type: "api.request",
+		Time: time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC),
+	}
+}
+
+// runStream runs a stream until the returned function is called
+func runStream(stream *Stream) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stream.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestStreamRetriesFailedWrites(t *testing.T) {
+	sink := &fakeSink{failures: 2}
+	stream := NewStream([]Sink{sink}, testConfig(), logger.DefaultLogger())
+	stop := runStream(stream)
+
+	for i := 0; i < 5; i++ {
+		stream.Publish(testEvent(i))
+	}
+
+	require.Eventually(t, func() bool { return sink.received() == 5 }, time.Second, time.Millisecond)
+	stop()
+
+	health := stream.Health()
+	require.Len(t, health, 1)
+	assert.True(t, health[0].Healthy)
+	assert.Equal(t, int64(5), health[0].Delivered)
+	assert.Zero(t, health[0].Dropped)
+	assert.Zero(t, health[0].ConsecutiveFailures)
+	assert.Equal(t, "sink unavailable", health[0].LastError)
+	assert.NotNil(t, health[0].LastFailureAt)
+	assert.True(t, sink.closed)
+
+	// Events are delivered in order
+	for i, event := range sink.events {
+		assert.Equal(t, fmt.Sprintf("event-%d", i), event.ID)
+	}
+}
+
+func TestStreamDropsAfterMaxAttempts(t *testing.T) {
+	sink := &fakeSink{failures: 100}
+	stream := NewStream([]Sink{sink}, testConfig(), logger.DefaultLogger())
+	stop := runStream(stream)
+
+	stream.Publish(testEvent(1))
+
+	require.Eventually(t, func() bool { return stream.Health()[0].Dropped == 1 }, time.Second, time.Millisecond)
+	stop()
+
+	health := stream.Health()[0]
+	assert.False(t, health.Healthy)
+	assert.Equal(t, 3, health.ConsecutiveFailures)
+	assert.Zero(t, health.Delivered)
+	assert.Equal(t, 3, sink.writes)
+}
+
+func TestStreamDropsWhenBufferIsFull(t *testing.T) {
+	cfg := testConfig()
+	cfg.BufferSize = 2
+	cfg.BatchSize = 1
+	sink := &fakeSink{writing: make(chan struct{}), release: make(chan struct{})}
+	stream := NewStream([]Sink{sink}, cfg, logger.DefaultLogger())
+	stop := runStream(stream)
+
+	// The first event is being written while the next two fill the buffer
+	stream.Publish(testEvent(0))
+	<-sink.writing
+	for i := 1; i < 5; i++ {
+		stream.Publish(testEvent(i))
+	}
+
+	health := stream.Health()[0]
+	assert.Equal(t, 2, health.Buffered)
+	assert.Equal(t, int64(2), health.Dropped)
+
+	close(sink.release)
+	stop()
+
+	assert.Equal(t, 3, sink.received())
+	assert.Equal(t, int64(3), stream.Health()[0].Delivered)
+}
+
+func TestStreamDeliversBufferedEventsOnShutdown(t *testing.T) {
+	sink := &fakeSink{}
+	stream := NewStream([]Sink{sink}, testConfig(), logger.DefaultLogger())
+
+	// Published before the stream runs, and delivered when it stops
+	for i := 0; i < 25; i++ {
+		stream.Publish(testEvent(i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream.Run(ctx)
+
+	assert.Equal(t, 25, sink.received())
+	assert.Equal(t, 3, sink.writes)
+	assert.True(t, sink.closed)
+}
+
+func TestStreamIsolatesSinks(t *testing.T) {
+	failing := &fakeSink{failures: 100}
+	working := &fakeSink{}
+	stream := NewStream([]Sink{failing, working}, testConfig(), logger.DefaultLogger())
+	stop := runStream(stream)
+	defer stop()
+
+	stream.Publish(testEvent(1))
+
+	require.Eventually(t, func() bool { return working.received() == 1 }, time.Second, time.Millisecond)
+	assert.True(t, stream.Health()[1].Healthy)
+}
+
+// recordingPublisher records the events published to it
+type recordingPublisher struct {
+	events []*model.StreamEvent
+}
+
+func (p *recordingPublisher) Publish(event *model.StreamEvent) {
+	p.events = append(p.events, event)
+}
+
+// fakeAuditRepository stores entries, failing when asked to
+type fakeAuditRepository struct {
+	audit.Repository
+	err error
+}
+
+func (f *fakeAuditRepository) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	if f.err != nil {
+		return f.err
+	}
+	log.ID = "log-1"
+	log.Sequence = 1
+	log.Hash = log.ComputeHash()
+	return nil
+}
+
+func TestAuditRepositoryPublishesStoredEntries(t *testing.T) {
+	publisher := &recordingPublisher{}
+	repo := NewAuditRepository(&fakeAuditRepository{}, publisher)
+
+	require.NoError(t, repo.CreateAuditLog(context.Background(), &models.AuditLog{
+		Actor:      "user:user-1",
+		UserID:     "user-1",
+		Action:     "organization.renamed",
+		EntityType: "organization",
+		EntityID:   "org-1",
+		Metadata:   `{"slug":"acme"}`,
+	}))
+
+	require.Len(t, publisher.events, 1)
+	event := publisher.events[0]
+	assert.Equal(t, "log-1", event.ID)
+	assert.Equal(t, model.StreamEventAuditLog, event.Kind)
+	assert.Equal(t, "organization.renamed", event.Type)
+	assert.Equal(t, "user:user-1", event.Actor)
+	assert.Equal(t, int64(1), event.Data["sequence"])
+	assert.JSONEq(t, `{"slug":"acme"}`, string(event.Data["metadata"].(json.RawMessage)))
+
+	// Entries that failed to be stored are not published
+	repo = NewAuditRepository(&fakeAuditRepository{err: errors.New("database is down")}, publisher)
+	assert.Error(t, repo.CreateAuditLog(context.Background(), &models.AuditLog{Action: "organization.created"}))
+	assert.Len(t, publisher.events, 1)
+}
+
+func TestSecurityEventEvent(t *testing.T) {
+	createdAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := SecurityEventEvent(&model.SecurityEvent{
+		ID:          "event-1",
+		UserID:      "user-1",
+		EventType:   model.EventLoginFailed,
+		IPAddress:   "203.0.113.7",
+		UserAgent:   "curl/8.0",
+		Description: "Invalid password",
+		CreatedAt:   createdAt,
+	})
+
+	assert.Equal(t, model.StreamEventSecurityEvent, event.Kind)
+	assert.Equal(t, model.EventLoginFailed, event.Type)
+	assert.Equal(t, createdAt, event.Time)
+	assert.Equal(t, map[string]interface{}{"user_agent": "curl/8.0", "description": "Invalid password"}, event.Data)
+}
@@ -0,0 +1,194 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// Syslog facilities and severities of the exported events (RFC 5424 6.2.1)
+const (
+	facilityAuthPriv = 10
+	facilityLogAudit = 13
+
+	severityWarning       = 4
+	severityInformational = 6
+)
+
+// syslogSDID is the ID of the structured data element carrying the fields
+// of an event, under the enterprise number reserved for documentation
+const syslogSDID = "event@32473"
+
+// syslogDialTimeout bounds connecting to the syslog server
+const syslogDialTimeout = 5 * time.Second
+
+// warningSecurityEvents are the security events logged with the warning
+// severity, the others being informational
+var warningSecurityEvents = map[string]bool{
+	model.EventLoginFailed:        true,
+	model.EventAccountLocked:      true,
+	model.EventSuspiciousActivity: true,
+	model.EventActivityReported:   true,
+}
+
+// SyslogSink sends events to a syslog server as RFC 5424 messages, over TCP
+// with octet-counting framing (RFC 6587) or as one UDP datagram per event.
+// It connects on the first write and reconnects after a failed one.
+type SyslogSink struct {
+	network  string
+	address  string
+	appName  string
+	hostname string
+	procID   string
+	conn     net.Conn
+}
+
+// NewSyslogSink creates a sink sending events to the syslog server at
+// address, over network ("tcp" or "udp")
+func NewSyslogSink(network, address, appName string) (*SyslogSink, error) {
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		network:  network,
+		address:  address,
+		appName:  headerField(appName, 48),
+		hostname: headerField(hostname, 255),
+		procID:   strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Name identifies the sink
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+// Write sends a batch of events to the syslog server
+func (s *SyslogSink) Write(ctx context.Context, events []*model.StreamEvent) error {
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: syslogDialTimeout}
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog server: %w", err)
+		}
+		s.conn = conn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, event := range events {
+		msg, err := s.format(event)
+		if err != nil {
+			return err
+		}
+		if s.network == "tcp" {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+
+		if _, err := s.conn.Write(msg); err != nil {
+			// The connection may be half-written, start over with a new one
+			s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("failed to send syslog message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connection to the syslog server
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format returns an event as an RFC 5424 message. The fields a SIEM filters
+// on are in its structured data, and the whole event is the JSON message.
+func (s *SyslogSink) format(event *model.StreamEvent) ([]byte, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	facility, severity := facilityLogAudit, severityInformational
+	if event.Kind == model.StreamEventSecurityEvent {
+		facility = facilityAuthPriv
+		if warningSecurityEvents[event.Type] {
+			severity = severityWarning
+		}
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, param := range [][2]string{
+		{"id", event.ID},
+		{"kind", event.Kind},
+		{"type", event.Type},
+		{"actor", event.Actor},
+		{"user_id", event.UserID},
+		{"ip", event.IPAddress},
+	} {
+		if param[1] != "" {
+			sd.WriteString(" " + param[0] + `="` + sdEscaper.Replace(param[1]) + `"`)
+		}
+	}
+	sd.WriteString("]")
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s ",
+		facility*8+severity,
+		event.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname,
+		s.appName,
+		s.procID,
+		headerField(event.Type, 32),
+	)
+
+	// The BOM marks the message as UTF-8
+	msg := make([]byte, 0, len(header)+sd.Len()+4+len(body))
+	msg = append(msg, header...)
+	msg = append(msg, sd.String()...)
+	msg = append(msg, " \xEF\xBB\xBF"...)
+	return append(msg, body...), nil
+}
+
+// sdEscaper escapes the characters that must be escaped in structured data
+// parameter values
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// headerField returns a value fit for a header field of a syslog message:
+// printable ASCII without spaces, at most max characters long, and "-" when
+// empty
+func headerField(value string, max int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(field) > max {
+		field = field[:max]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
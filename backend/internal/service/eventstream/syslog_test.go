@@ -0,0 +1,129 @@
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readFrame reads a message framed with octet counting
+func readFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	length, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	require.NoError(t, err)
+
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	require.NoError(t, err)
+	return string(msg)
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sink, err := NewSyslogSink("tcp", listener.Addr().String(), "fullstack")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, sink.Write(ctx, []*model.StreamEvent{
+		{
+			ID:     "log-1",
+			Kind:   model.StreamEventAuditLog,
+			Type:   "organization.renamed",
+			Time:   time.Date(2026, 1, 2, 3, 4, 5, 600000000, time.UTC),
+			Actor:  `user:"quoted]`,
+			UserID: "user-1",
+		},
+		{
+			ID:        "event-1",
+			Kind:      model.StreamEventSecurityEvent,
+			Type:      model.EventAccountLocked,
+			Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			UserID:    "user-1",
+			IPAddress: "203.0.113.7",
+		},
+	}))
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// log audit, informational
+	msg := readFrame(t, r)
+	assert.True(t, strings.HasPrefix(msg, "<110>1 2026-01-02T03:04:05.600000Z "), msg)
+	assert.Contains(t, msg, " fullstack ")
+	assert.Contains(t, msg, ` organization.renamed [event@32473 id="log-1" kind="audit_log" type="organization.renamed" actor="user:\"quoted\]" user_id="user-1"] `+"\xEF\xBB\xBF{")
+	assert.Contains(t, msg, `"id":"log-1"`)
+
+	// authpriv, warning
+	msg = readFrame(t, r)
+	assert.True(t, strings.HasPrefix(msg, "<84>1 2026-01-02T03:04:05.000000Z "), msg)
+	assert.Contains(t, msg, `ip="203.0.113.7"]`)
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "fullstack")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(context.Background(), []*model.StreamEvent{
+		{ID: "event-1", Kind: model.StreamEventSecurityEvent, Type: model.EventLoginSuccess, Time: time.Now()},
+	}))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	// One datagram per message, without framing
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<86>1 "), msg)
+	assert.Contains(t, msg, " login_success [event@32473 id=\"event-1\"")
+}
+
+func TestSyslogSinkReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	sink, err := NewSyslogSink("tcp", address, "fullstack")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	event := []*model.StreamEvent{{ID: "log-1", Kind: model.StreamEventAuditLog, Type: "api.request", Time: time.Now()}}
+	assert.Error(t, sink.Write(context.Background(), event))
+
+	// The server comes back
+	listener, err = net.Listen("tcp", address)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	assert.NoError(t, sink.Write(context.Background(), event))
+}
+
+func TestNewSyslogSinkRejectsUnknownNetwork(t *testing.T) {
+	_, err := NewSyslogSink("unix", "/dev/log", "fullstack")
+	assert.Error(t, err)
+}
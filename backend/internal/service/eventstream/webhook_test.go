@@ -0,0 +1,69 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+
+func TestWebhookSinkSignsEvents(t *testing.T) {
+	verifier, err := webhook.NewVerifier(testWebhookSecret)
+	require.NoError(t, err)
+
+	var received webhookPayload
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if err := verifier.Verify(r.Header, body); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.NoError(t, json.Unmarshal(body, &received))
+		ids = append(ids, r.Header.Get("webhook-id"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(server.URL, testWebhookSecret)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	events := []*model.StreamEvent{testEvent(1), testEvent(2)}
+	require.NoError(t, sink.Write(context.Background(), events))
+	require.NoError(t, sink.Write(context.Background(), events))
+
+	require.Len(t, received.Events, 2)
+	assert.Equal(t, "event-1", received.Events[0].ID)
+	// A batch that is sent again keeps its ID
+	require.Len(t, ids, 2)
+	assert.Equal(t, ids[0], ids[1])
+}
+
+func TestWebhookSinkFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(server.URL, testWebhookSecret)
+	require.NoError(t, err)
+
+	err = sink.Write(context.Background(), []*model.StreamEvent{testEvent(1)})
+	assert.EqualError(t, err, "webhook responded with status 503")
+}
+
+func TestNewWebhookSinkRejectsInvalidSecret(t *testing.T) {
+	_, err := NewWebhookSink("https://siem.example.com/events", "not a secret")
+	assert.Error(t, err)
+}
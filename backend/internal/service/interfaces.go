@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/models"
 )
 
@@ -57,15 +58,33 @@ type EmailService interface {
 	SendWelcomeEmail(ctx context.Context, to string, userName string) error
 	SendLoginNotificationEmail(ctx context.Context, to string, deviceInfo string, location string) error
 	SendPasswordChangedEmail(ctx context.Context, to string) error
+	SendAccountLockedEmail(ctx context.Context, to string, unlockTime time.Time, failedAttempts int, unlockToken string) error
+	SendSuspiciousActivityEmail(ctx context.Context, to string, activityType string, deviceInfo string, location string, ipAddress string) error
+	SendOnboardingReminderEmail(ctx context.Context, to string, userName string, token string) error
+	SendSecurityDigestEmail(ctx context.Context, to string, digest *model.SecurityDigest) error
+	SendOrganizationInvitationEmail(ctx context.Context, to string, organizationName string, inviterName string, token string) error
+	SendDataExportReadyEmail(ctx context.Context, to string, token string) error
+
+	// Scheduling
+	ScheduleEmail(ctx context.Context, userID string, kind string, to string, sendAt time.Time) error
+	CancelScheduledEmails(ctx context.Context, userID string, kinds ...string) error
 
 	// Template management
 	ParseTemplate(templateName string, data interface{}) (string, error)
 	ValidateEmailAddress(email string) bool
 }
 
+// AuditLog records changes in the audit log
+type AuditLog interface {
+	// Record records a change made on behalf of the actor of ctx
+	Record(ctx context.Context, event *model.AuditEvent)
+}
+
 type CacheService interface {
 	// Rate limiting
 	CheckRateLimit(ctx context.Context, key string, limit int, duration int) (bool, error)
+	SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error)
+	TokenBucketRateLimit(ctx context.Context, key string, capacity int, window time.Duration) (*model.RateLimitResult, error)
 	ResetRateLimit(ctx context.Context, key string) error
 
 	// Caching
@@ -79,3 +98,31 @@ type CacheService interface {
 	GetSession(ctx context.Context, sessionID string) (string, error)
 	InvalidateSession(ctx context.Context, sessionID string) error
 }
+
+// Lock is a lock held on a key of a LockService. It is renewed in the
+// background until it is released or lost.
+type Lock interface {
+	// Key returns the locked key
+	Key() string
+	// Token returns the fencing token of the lock, which is greater than the
+	// token of every earlier lock of the key. Writes guarded by the lock can
+	// pass it along so storage rejects writes of holders that lost the lock.
+	Token() int64
+	// Lost is closed when the lock could not be renewed and may have been
+	// taken by someone else
+	Lost() <-chan struct{}
+	// Release stops renewing the lock and releases it if it is still held
+	Release(ctx context.Context) error
+}
+
+type LockService interface {
+	// TryLock locks a key for ttl, renewing it until it is released. It
+	// returns a nil lock when the key is already locked.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// LockingCacheService is a cache service that also provides locks
+type LockingCacheService interface {
+	CacheService
+	LockService
+}
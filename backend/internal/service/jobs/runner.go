@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// defaultCheckInterval is how often a job is checked for being due. Jobs
+	// are picked up by another instance within this interval when the instance
+	// that ran them last stops.
+	defaultCheckInterval = time.Minute
+	// defaultLockTTL is the TTL of the lock of a running job, which is renewed
+	// while the job runs. A job whose instance dies is unlocked after the TTL.
+	defaultLockTTL = 30 * time.Second
+)
+
+// Func is the work of a job. Its context is cancelled when the job's lock is
+// lost, and carries the lock's fencing token.
+type Func func(ctx context.Context) error
+
+// job is a registered job
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Func
+}
+
+// lastRun records the last successful run of a job
+type lastRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Token      int64     `json:"token"`
+}
+
+// Runner runs periodic jobs on every API instance. A job runs on the instance
+// that locks it first, and only when no instance ran it successfully within
+// its interval, so each run happens once however many instances there are.
+type Runner struct {
+	cache         service.LockingCacheService
+	logger        logger.Logger
+	checkInterval time.Duration
+	lockTTL       time.Duration
+
+	mu   sync.Mutex
+	jobs []*job
+}
+
+// NewRunner creates a runner locking its jobs and recording their runs in the
+// cache
+func NewRunner(cache service.LockingCacheService, log logger.Logger) *Runner {
+	return &Runner{
+		cache:         cache,
+		logger:        log,
+		checkInterval: defaultCheckInterval,
+		lockTTL:       defaultLockTTL,
+	}
+}
+
+// Register adds a job running fn every interval. Jobs must be registered
+// before the runner is started.
+func (r *Runner) Register(name string, interval time.Duration, fn Func) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("job name and function are required")
+	}
+	if interval < time.Second {
+		return fmt.Errorf("invalid interval for job %s: %s", name, interval)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, j := range r.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %s is already registered", name)
+		}
+	}
+	r.jobs = append(r.jobs, &job{name: name, interval: interval, fn: fn})
+
+	return nil
+}
+
+// Run checks whether the jobs are due until ctx is cancelled, then waits for
+// the running jobs to finish
+func (r *Runner) Run(ctx context.Context) {
+	r.mu.Lock()
+	jobs := append([]*job(nil), r.jobs...)
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			r.loop(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+// loop runs a job whenever it is due until ctx is cancelled
+func (r *Runner) loop(ctx context.Context, j *job) {
+	interval := r.checkInterval
+	if j.interval < interval {
+		interval = j.interval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.runJob(ctx, j); err != nil && ctx.Err() == nil {
+			r.logger.Error("Failed to run job", "job", j.name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob runs a job if it is due and no other instance is running it, and
+// reports whether it ran
+func (r *Runner) runJob(ctx context.Context, j *job) (bool, error) {
+	lock, err := r.cache.TryLock(ctx, jobLockKey(j.name), r.lockTTL)
+	if err != nil {
+		return false, err
+	}
+	if lock == nil {
+		// Another instance is running the job
+		return false, nil
+	}
+	defer func() {
+		if err := lock.Release(context.Background()); err != nil {
+			r.logger.Warn("Failed to release job lock", "job", j.name, "error", err)
+		}
+	}()
+
+	// The last run is read under the lock, so it can't change before this run
+	// is recorded
+	var last lastRun
+	if err := r.cache.GetCachedData(ctx, jobLastRunKey(j.name), &last); err != nil {
+		return false, fmt.Errorf("failed to read last run: %w", err)
+	}
+	startedAt := time.Now()
+	if !last.StartedAt.IsZero() && startedAt.Sub(last.StartedAt) < j.interval {
+		return false, nil
+	}
+
+	jobCtx, cancel := context.WithCancel(withFencingToken(ctx, lock.Token()))
+	defer cancel()
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	if err := j.fn(jobCtx); err != nil {
+		// Failed runs are retried at the next check, by any instance
+		return true, err
+	}
+	select {
+	case <-lock.Lost():
+		return true, errors.New("lost the job lock while running")
+	default:
+	}
+
+	run := lastRun{StartedAt: startedAt, FinishedAt: time.Now(), Token: lock.Token()}
+	// Runs are remembered for two intervals, long enough to know the next run
+	// isn't due yet
+	ttl := int(math.Ceil((2 * j.interval).Seconds()))
+	if err := r.cache.CacheData(ctx, jobLastRunKey(j.name), run, ttl); err != nil {
+		return true, fmt.Errorf("failed to record run: %w", err)
+	}
+
+	r.logger.Info("Ran job", "job", j.name, "duration", run.FinishedAt.Sub(run.StartedAt), "token", run.Token)
+	return true, nil
+}
+
+// jobLockKey returns the key of the lock of a job
+func jobLockKey(name string) string {
+	return "job:" + name
+}
+
+// jobLastRunKey returns the key recording the last run of a job
+func jobLastRunKey(name string) string {
+	return "jobs:" + name + ":last_run"
+}
+
+type fencingTokenKey struct{}
+
+// withFencingToken returns a context carrying the fencing token of a job run
+func withFencingToken(ctx context.Context, token int64) context.Context {
+	return context.WithValue(ctx, fencingTokenKey{}, token)
+}
+
+// FencingToken returns the fencing token of the job run of ctx. Jobs writing
+// to storage that can check it should pass it along, so writes of an instance
+// that lost the job's lock are rejected.
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
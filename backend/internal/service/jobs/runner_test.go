@@ -0,0 +1,207 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nanayaw/fullstack/internal/service/cache"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+func newTestCache(t *testing.T) *cache.MemoryService {
+	s := cache.NewMemoryService()
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRunner(newTestCache(t), logger.DefaultLogger())
+	noop := func(ctx context.Context) error { return nil }
+
+	require.NoError(t, r.Register("cleanup", time.Hour, noop))
+	assert.Error(t, r.Register("cleanup", time.Hour, noop))
+	assert.Error(t, r.Register("digest", 0, noop))
+	assert.Error(t, r.Register("", time.Hour, noop))
+	assert.Error(t, r.Register("digest", time.Hour, nil))
+}
+
+func TestRunJobOncePerInterval(t *testing.T) {
+	ctx := context.Background()
+	shared := newTestCache(t)
+
+	var runs atomic.Int32
+	j := &job{name: "cleanup", interval: time.Hour, fn: func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}}
+
+	// Two instances sharing the cache run the job once
+	first := NewRunner(shared, logger.DefaultLogger())
+	second := NewRunner(shared, logger.DefaultLogger())
+
+	ran, err := first.runJob(ctx, j)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	ran, err = second.runJob(ctx, j)
+	require.NoError(t, err)
+	assert.False(t, ran)
+	assert.Equal(t, int32(1), runs.Load())
+
+	// Until the interval has passed
+	require.NoError(t, shared.CacheData(ctx, jobLastRunKey("cleanup"), lastRun{StartedAt: time.Now().Add(-time.Hour)}, 0))
+	ran, err = second.runJob(ctx, j)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestRunJobExclusive(t *testing.T) {
+	ctx := context.Background()
+	shared := newTestCache(t)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	var runs atomic.Int32
+	j := &job{name: "cleanup", interval: time.Hour, fn: func(ctx context.Context) error {
+		runs.Add(1)
+		close(started)
+		<-finish
+		return nil
+	}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ran, err := NewRunner(shared, logger.DefaultLogger()).runJob(ctx, j)
+		assert.NoError(t, err)
+		assert.True(t, ran)
+	}()
+	<-started
+
+	// The job is locked while it runs
+	ran, err := NewRunner(shared, logger.DefaultLogger()).runJob(ctx, j)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	close(finish)
+	wg.Wait()
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestRunJobFailure(t *testing.T) {
+	ctx := context.Background()
+	r := NewRunner(newTestCache(t), logger.DefaultLogger())
+
+	var runs atomic.Int32
+	j := &job{name: "cleanup", interval: time.Hour, fn: func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			return errors.New("database is down")
+		}
+		return nil
+	}}
+
+	// Failed runs are retried at the next check
+	ran, err := r.runJob(ctx, j)
+	assert.Error(t, err)
+	assert.True(t, ran)
+	ran, err = r.runJob(ctx, j)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	ran, err = r.runJob(ctx, j)
+	require.NoError(t, err)
+	assert.False(t, ran)
+}
+
+func TestRunJobFencingToken(t *testing.T) {
+	ctx := context.Background()
+	shared := newTestCache(t)
+	r := NewRunner(shared, logger.DefaultLogger())
+
+	var tokens []int64
+	j := &job{name: "cleanup", interval: time.Hour, fn: func(ctx context.Context) error {
+		token, ok := FencingToken(ctx)
+		assert.True(t, ok)
+		tokens = append(tokens, token)
+		return nil
+	}}
+	for i := 0; i < 2; i++ {
+		ran, err := r.runJob(ctx, j)
+		require.NoError(t, err)
+		assert.True(t, ran)
+		// Forget the run so the job is due again
+		require.NoError(t, shared.InvalidateCache(ctx, jobLastRunKey("cleanup")))
+	}
+
+	require.Len(t, tokens, 2)
+	assert.Greater(t, tokens[1], tokens[0])
+
+	_, ok := FencingToken(ctx)
+	assert.False(t, ok)
+}
+
+func TestRunJobLostLock(t *testing.T) {
+	ctx := context.Background()
+	shared := newTestCache(t)
+	r := NewRunner(shared, logger.DefaultLogger())
+	r.lockTTL = 300 * time.Millisecond
+
+	// The job's context is cancelled once another instance took the lock
+	j := &job{name: "cleanup", interval: time.Hour, fn: func(ctx context.Context) error {
+		require.NoError(t, shared.InvalidateCache(ctx, "lock:"+jobLockKey("cleanup")))
+		other, err := shared.TryLock(ctx, jobLockKey("cleanup"), r.lockTTL)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		defer other.Release(context.Background())
+
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	ran, err := r.runJob(ctx, j)
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The run isn't recorded
+	var last lastRun
+	require.NoError(t, shared.GetCachedData(ctx, jobLastRunKey("cleanup"), &last))
+	assert.True(t, last.StartedAt.IsZero())
+}
+
+func TestRun(t *testing.T) {
+	r := NewRunner(newTestCache(t), logger.DefaultLogger())
+
+	ran := make(chan struct{})
+	require.NoError(t, r.Register("cleanup", time.Hour, func(ctx context.Context) error {
+		close(ran)
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+
+	// Due jobs run as soon as the runner starts
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job didn't run")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runner didn't stop")
+	}
+}
@@ -0,0 +1,226 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// Repository defines the interface for notification preference database operations
+type Repository interface {
+	// ListPreferences gets the notification preferences a user changed
+	ListPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error)
+
+	// SetPreference creates or updates a notification preference
+	SetPreference(ctx context.Context, pref *model.NotificationPreference) error
+
+	// GetPreferenceByEmail gets the ID of the user with an email address ("" if
+	// there is none), and whether they receive notifications of a category on a channel
+	GetPreferenceByEmail(ctx context.Context, email, category, channel string) (string, bool, error)
+
+	// GetUserEmail gets a user's email address ("" if the user does not exist)
+	GetUserEmail(ctx context.Context, userID string) (string, error)
+}
+
+// ScheduledEmails defines the interface for managing a user's scheduled emails
+type ScheduledEmails interface {
+	Schedule(ctx context.Context, userID, kind, to string, sendAt time.Time) error
+	Cancel(ctx context.Context, userID string, kinds ...string) error
+}
+
+// scheduledEmailKinds lists the scheduled emails of each notification
+// category, which are cancelled when the user opts out of it
+var scheduledEmailKinds = map[string][]string{
+	model.NotificationCategorySecurityDigest: {model.ScheduledEmailSecurityDigest},
+	model.NotificationCategoryOnboarding:     {model.ScheduledEmailOnboardingReminder},
+}
+
+// Service manages users' notification preferences. It implements
+// email.PreferenceChecker, so optional emails are only sent to users who
+// didn't opt out of them.
+type Service struct {
+	repo      Repository
+	scheduled ScheduledEmails
+	config    *config.Config
+	secret    []byte
+	logger    logger.Logger
+}
+
+// NewService creates a new notification service. Unsubscribe tokens are only
+// issued when an unsubscribe secret is configured.
+func NewService(repo Repository, scheduled ScheduledEmails, cfg *config.Config, log logger.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		scheduled: scheduled,
+		config:    cfg,
+		secret:    []byte(cfg.Email.UnsubscribeSecret),
+		logger:    log,
+	}
+}
+
+// GetPreferences gets a user's preference for every notification category and
+// channel, including the ones they never changed
+func (s *Service) GetPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error) {
+	stored, err := s.repo.ListPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]*model.NotificationPreference, len(stored))
+	for _, pref := range stored {
+		changed[pref.Category+"/"+pref.Channel] = pref
+	}
+
+	prefs := make([]*model.NotificationPreference, 0, len(model.NotificationCategories)*len(model.NotificationChannels))
+	for _, category := range model.NotificationCategories {
+		for _, channel := range model.NotificationChannels {
+			pref := &model.NotificationPreference{
+				UserID:   userID,
+				Category: category,
+				Channel:  channel,
+				Enabled:  true,
+				Required: model.IsRequiredNotificationCategory(category),
+			}
+			if stored, ok := changed[category+"/"+channel]; ok && !pref.Required {
+				pref.Enabled = stored.Enabled
+				pref.UpdatedAt = stored.UpdatedAt
+			}
+			prefs = append(prefs, pref)
+		}
+	}
+
+	return prefs, nil
+}
+
+// UpdatePreferences changes some of a user's notification preferences and
+// returns all of them. Required categories can't be turned off.
+func (s *Service) UpdatePreferences(ctx context.Context, userID string, updates []*model.NotificationPreference) ([]*model.NotificationPreference, error) {
+	for _, update := range updates {
+		if err := validatePreference(update); err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool, len(current))
+	for _, pref := range current {
+		enabled[pref.Category+"/"+pref.Channel] = pref.Enabled
+	}
+
+	for _, update := range updates {
+		if enabled[update.Category+"/"+update.Channel] == update.Enabled {
+			continue
+		}
+		if err := s.setPreference(ctx, userID, update.Category, update.Channel, update.Enabled); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+// CheckEmail reports whether the user with an email address accepts emails of
+// a notification category, and returns a token that unsubscribes them from
+// it. Addresses that don't belong to a user always accept emails.
+func (s *Service) CheckEmail(ctx context.Context, recipient, category string) (bool, string, error) {
+	userID, enabled, err := s.repo.GetPreferenceByEmail(ctx, recipient, category, model.NotificationChannelEmail)
+	if err != nil {
+		return false, "", err
+	}
+	if userID == "" {
+		return true, "", nil
+	}
+	if !enabled {
+		return false, "", nil
+	}
+
+	return true, s.UnsubscribeToken(userID, category), nil
+}
+
+// setPreference stores a changed preference, and keeps the user's scheduled
+// emails in line with it
+func (s *Service) setPreference(ctx context.Context, userID, category, channel string, enabled bool) error {
+	now := time.Now()
+	err := s.repo.SetPreference(ctx, &model.NotificationPreference{
+		UserID:    userID,
+		Category:  category,
+		Channel:   channel,
+		Enabled:   enabled,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if channel != model.NotificationChannelEmail {
+		return nil
+	}
+
+	// The preference is saved, so a scheduling failure is only logged; the
+	// mailer skips emails the user opted out of anyway
+	if err := s.rescheduleEmails(ctx, userID, category, enabled); err != nil {
+		s.logger.Error("Failed to update scheduled emails", "user_id", userID, "category", category, "error", err)
+	}
+
+	return nil
+}
+
+// rescheduleEmails cancels the scheduled emails of a category the user opted
+// out of, and restarts the security digest when they opt back in. The
+// onboarding reminder is only ever scheduled at registration.
+func (s *Service) rescheduleEmails(ctx context.Context, userID, category string, enabled bool) error {
+	kinds := scheduledEmailKinds[category]
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	if !enabled {
+		return s.scheduled.Cancel(ctx, userID, kinds...)
+	}
+
+	interval := s.config.Auth.SecurityDigestInterval
+	if category != model.NotificationCategorySecurityDigest || interval <= 0 {
+		return nil
+	}
+
+	email, err := s.repo.GetUserEmail(ctx, userID)
+	if err != nil || email == "" {
+		return err
+	}
+
+	return s.scheduled.Schedule(ctx, userID, model.ScheduledEmailSecurityDigest, email, time.Now().Add(interval))
+}
+
+// validatePreference checks that a preference update names a known category
+// and channel, and doesn't turn off a required category
+func validatePreference(pref *model.NotificationPreference) error {
+	if !contains(model.NotificationCategories, pref.Category) {
+		return errors.NewValidationError(fmt.Sprintf("unknown notification category %q", pref.Category))
+	}
+	if !contains(model.NotificationChannels, pref.Channel) {
+		return errors.NewValidationError(fmt.Sprintf("unknown notification channel %q", pref.Channel))
+	}
+	if model.IsRequiredNotificationCategory(pref.Category) && !pref.Enabled {
+		return errors.NewValidationError(fmt.Sprintf("%s notifications can't be turned off", pref.Category))
+	}
+
+	return nil
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRepository is a mock implementation of Repository
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) ListPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*model.NotificationPreference), args.Error(1)
+}
+
+func (m *mockRepository) SetPreference(ctx context.Context, pref *model.NotificationPreference) error {
+	args := m.Called(ctx, pref)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetPreferenceByEmail(ctx context.Context, email, category, channel string) (string, bool, error) {
+	args := m.Called(ctx, email, category, channel)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+func (m *mockRepository) GetUserEmail(ctx context.Context, userID string) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+// mockScheduledEmails is a mock implementation of ScheduledEmails
+type mockScheduledEmails struct {
+	mock.Mock
+}
+
+func (m *mockScheduledEmails) Schedule(ctx context.Context, userID, kind, to string, sendAt time.Time) error {
+	args := m.Called(ctx, userID, kind, to, sendAt)
+	return args.Error(0)
+}
+
+func (m *mockScheduledEmails) Cancel(ctx context.Context, userID string, kinds ...string) error {
+	args := m.Called(ctx, userID, kinds)
+	return args.Error(0)
+}
+
+func newTestService(secret string) (*Service, *mockRepository, *mockScheduledEmails) {
+	cfg := config.DefaultConfig()
+	cfg.Email.UnsubscribeSecret = secret
+
+	repo := new(mockRepository)
+	scheduled := new(mockScheduledEmails)
+	return NewService(repo, scheduled, cfg, logger.DefaultLogger()), repo, scheduled
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	s, _, _ := newTestService("unsubscribe-secret")
+
+	token := s.UnsubscribeToken("user-1", model.NotificationCategorySecurityDigest)
+	category, err := s.UnsubscribeCategory(token)
+	require.NoError(t, err)
+	assert.Equal(t, model.NotificationCategorySecurityDigest, category)
+
+	// Tokens signed with another secret, tampered with or for a required
+	// category are rejected
+	other, _, _ := newTestService("another-secret")
+	for _, token := range []string{
+		other.UnsubscribeToken("user-1", model.NotificationCategorySecurityDigest),
+		token[:len(token)-1],
+		s.UnsubscribeToken("user-1", model.NotificationCategoryAccount),
+		"",
+	} {
+		_, err := s.UnsubscribeCategory(token)
+		assert.Error(t, err, token)
+	}
+
+	// Without a secret there are no unsubscribe links
+	disabled, _, _ := newTestService("")
+	assert.Empty(t, disabled.UnsubscribeToken("user-1", model.NotificationCategorySecurityDigest))
+}
+
+func TestUnsubscribe(t *testing.T) {
+	s, repo, scheduled := newTestService("unsubscribe-secret")
+	ctx := context.Background()
+
+	repo.On("GetUserEmail", ctx, "user-1").Return("user@example.com", nil)
+	repo.On("SetPreference", ctx, mock.MatchedBy(func(pref *model.NotificationPreference) bool {
+		return pref.UserID == "user-1" && pref.Category == model.NotificationCategorySecurityDigest &&
+			pref.Channel == model.NotificationChannelEmail && !pref.Enabled
+	})).Return(nil).Once()
+	// The next digest is cancelled
+	scheduled.On("Cancel", ctx, "user-1", []string{model.ScheduledEmailSecurityDigest}).Return(nil).Once()
+
+	pref, err := s.Unsubscribe(ctx, s.UnsubscribeToken("user-1", model.NotificationCategorySecurityDigest))
+	require.NoError(t, err)
+	assert.False(t, pref.Enabled)
+
+	repo.AssertExpectations(t)
+	scheduled.AssertExpectations(t)
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("account emails can't be turned off", func(t *testing.T) {
+		s, repo, _ := newTestService("")
+
+		_, err := s.UpdatePreferences(ctx, "user-1", []*model.NotificationPreference{
+			{Category: model.NotificationCategoryAccount, Channel: model.NotificationChannelEmail, Enabled: false},
+		})
+
+		var appErr *apperrors.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+		repo.AssertNotCalled(t, "SetPreference", mock.Anything, mock.Anything)
+	})
+
+	t.Run("turning the digest back on schedules it", func(t *testing.T) {
+		s, repo, scheduled := newTestService("")
+		updatedAt := time.Now()
+
+		repo.On("ListPreferences", ctx, "user-1").Return([]*model.NotificationPreference{
+			{UserID: "user-1", Category: model.NotificationCategorySecurityDigest, Channel: model.NotificationChannelEmail, Enabled: false, UpdatedAt: &updatedAt},
+		}, nil).Once()
+		repo.On("SetPreference", ctx, mock.Anything).Return(nil).Once()
+		repo.On("GetUserEmail", ctx, "user-1").Return("user@example.com", nil).Once()
+		scheduled.On("Schedule", ctx, "user-1", model.ScheduledEmailSecurityDigest, "user@example.com", mock.Anything).Return(nil).Once()
+		repo.On("ListPreferences", ctx, "user-1").Return([]*model.NotificationPreference{
+			{UserID: "user-1", Category: model.NotificationCategorySecurityDigest, Channel: model.NotificationChannelEmail, Enabled: true, UpdatedAt: &updatedAt},
+		}, nil).Once()
+
+		prefs, err := s.UpdatePreferences(ctx, "user-1", []*model.NotificationPreference{
+			{Category: model.NotificationCategorySecurityDigest, Channel: model.NotificationChannelEmail, Enabled: true},
+			// Unchanged, so not saved again
+			{Category: model.NotificationCategoryLoginAlerts, Channel: model.NotificationChannelEmail, Enabled: true},
+		})
+		require.NoError(t, err)
+
+		// Every category is listed, and account emails are required
+		require.Len(t, prefs, len(model.NotificationCategories))
+		assert.Equal(t, model.NotificationCategoryAccount, prefs[0].Category)
+		assert.True(t, prefs[0].Required)
+		for _, pref := range prefs {
+			assert.True(t, pref.Enabled, pref.Category)
+		}
+
+		repo.AssertExpectations(t)
+		scheduled.AssertExpectations(t)
+	})
+}
+
+func TestCheckEmail(t *testing.T) {
+	s, repo, _ := newTestService("unsubscribe-secret")
+	ctx := context.Background()
+
+	repo.On("GetPreferenceByEmail", ctx, "user@example.com", model.NotificationCategoryLoginAlerts, model.NotificationChannelEmail).Return("user-1", true, nil)
+	repo.On("GetPreferenceByEmail", ctx, "optout@example.com", model.NotificationCategoryLoginAlerts, model.NotificationChannelEmail).Return("user-2", false, nil)
+	repo.On("GetPreferenceByEmail", ctx, "guest@example.com", model.NotificationCategoryLoginAlerts, model.NotificationChannelEmail).Return("", true, nil)
+
+	allowed, token, err := s.CheckEmail(ctx, "user@example.com", model.NotificationCategoryLoginAlerts)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, s.UnsubscribeToken("user-1", model.NotificationCategoryLoginAlerts), token)
+
+	allowed, _, err = s.CheckEmail(ctx, "optout@example.com", model.NotificationCategoryLoginAlerts)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Addresses without an account have nothing to unsubscribe from
+	allowed, token, err = s.CheckEmail(ctx, "guest@example.com", model.NotificationCategoryLoginAlerts)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Empty(t, token)
+}
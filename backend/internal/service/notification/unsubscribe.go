@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// UnsubscribeToken returns a token that turns off email notifications of a
+// category for a user, or "" when no unsubscribe secret is configured. Tokens
+// don't expire, so links in old emails keep working; changing the secret
+// revokes them all.
+func (s *Service) UnsubscribeToken(userID, category string) string {
+	if len(s.secret) == 0 {
+		return ""
+	}
+
+	payload := userID + ":" + category
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// UnsubscribeCategory returns the notification category an unsubscribe token
+// turns off, without turning it off, so it can be confirmed with the user
+func (s *Service) UnsubscribeCategory(token string) (string, error) {
+	_, category, err := s.parseUnsubscribeToken(token)
+	return category, err
+}
+
+// Unsubscribe turns off the email notifications of the category of an
+// unsubscribe token
+func (s *Service) Unsubscribe(ctx context.Context, token string) (*model.NotificationPreference, error) {
+	userID, category, err := s.parseUnsubscribeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account may have been deleted since the email was sent
+	email, err := s.repo.GetUserEmail(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if email == "" {
+		return nil, errors.NewNotFoundError("user not found")
+	}
+
+	if err := s.setPreference(ctx, userID, category, model.NotificationChannelEmail, false); err != nil {
+		return nil, err
+	}
+
+	return &model.NotificationPreference{
+		UserID:   userID,
+		Category: category,
+		Channel:  model.NotificationChannelEmail,
+		Enabled:  false,
+	}, nil
+}
+
+// parseUnsubscribeToken verifies an unsubscribe token and returns the user ID
+// and notification category it was issued for
+func (s *Service) parseUnsubscribeToken(token string) (string, string, error) {
+	invalid := errors.NewValidationError("invalid unsubscribe token")
+	if len(s.secret) == 0 {
+		return "", "", invalid
+	}
+
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", "", invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.sign(string(payload))) {
+		return "", "", invalid
+	}
+
+	userID, category, ok := strings.Cut(string(payload), ":")
+	if !ok || userID == "" || model.IsRequiredNotificationCategory(category) || !contains(model.NotificationCategories, category) {
+		return "", "", invalid
+	}
+
+	return userID, category, nil
+}
+
+// sign returns the HMAC of an unsubscribe token payload
+func (s *Service) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("unsubscribe:" + payload))
+	return mac.Sum(nil)
+}
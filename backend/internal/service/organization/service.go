@@ -0,0 +1,520 @@
+package organization
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// maxNameLength is the longest organization name, in characters
+const maxNameLength = 100
+
+// Repository defines the interface for organization database operations
+type Repository interface {
+	// CreateOrganization creates an organization owned by a user
+	CreateOrganization(ctx context.Context, org *model.Organization, ownerID string) error
+
+	// GetOrganization gets an organization by ID, or nil if there is none
+	GetOrganization(ctx context.Context, id string) (*model.Organization, error)
+
+	// RenameOrganization renames an organization and reports whether it exists
+	RenameOrganization(ctx context.Context, id, name string) (bool, error)
+
+	// DeleteOrganization deletes an organization and reports whether it existed
+	DeleteOrganization(ctx context.Context, id string) (bool, error)
+
+	// ListUserOrganizations gets the organizations a user is a member of
+	ListUserOrganizations(ctx context.Context, userID string) ([]*model.UserOrganization, error)
+
+	// GetMembership gets the membership of a user in an organization, or nil if they aren't a member
+	GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error)
+
+	// ListMembers gets the members of an organization
+	ListMembers(ctx context.Context, orgID string) ([]*model.Member, error)
+
+	// IsMember reports whether the user with an email address is a member of an organization
+	IsMember(ctx context.Context, orgID, email string) (bool, error)
+
+	// CountOwners counts the owners of an organization
+	CountOwners(ctx context.Context, orgID string) (int, error)
+
+	// SetMemberRole changes the role of a member and reports whether they are one
+	SetMemberRole(ctx context.Context, orgID, userID, role string) (bool, error)
+
+	// DeleteMembership removes a user from an organization and reports whether they were a member
+	DeleteMembership(ctx context.Context, orgID, userID string) (bool, error)
+
+	// CreateInvitation stores an invitation, replacing the pending one of the same address
+	CreateInvitation(ctx context.Context, invitation *model.Invitation) error
+
+	// ListInvitations gets the pending invitations of an organization
+	ListInvitations(ctx context.Context, orgID string) ([]*model.Invitation, error)
+
+	// GetInvitationByToken gets the pending invitation with a token hash, or nil if there is none
+	GetInvitationByToken(ctx context.Context, tokenHash string) (*model.Invitation, error)
+
+	// DeleteInvitation deletes a pending invitation and reports whether there was one
+	DeleteInvitation(ctx context.Context, orgID, id string) (bool, error)
+
+	// AcceptInvitation adds a user to the organization of a pending invitation
+	// and reports whether the invitation was still pending
+	AcceptInvitation(ctx context.Context, id, userID string) (bool, error)
+
+	// DeleteExpiredInvitations deletes expired invitations and returns how many there were
+	DeleteExpiredInvitations(ctx context.Context) (int64, error)
+}
+
+// Users defines the interface for looking up users
+type Users interface {
+	// GetUser gets a user by ID, or nil if there is none
+	GetUser(ctx context.Context, id string) (*model.AdminUser, error)
+}
+
+// Service manages organizations, their members and the invitations to join
+// them. Members are owners, admins or members: admins manage members and
+// invitations, and only owners can delete the organization or make other
+// owners. Every organization keeps at least one owner.
+type Service struct {
+	repo     Repository
+	users    Users
+	emailSvc service.EmailService
+	config   *config.AuthConfig
+	audit    service.AuditLog
+	logger   logger.Logger
+}
+
+// NewService creates a new organization service
+func NewService(repo Repository, users Users, emailSvc service.EmailService, cfg *config.AuthConfig, audit service.AuditLog, log logger.Logger) *Service {
+	return &Service{
+		repo:     repo,
+		users:    users,
+		emailSvc: emailSvc,
+		config:   cfg,
+		audit:    audit,
+		logger:   log,
+	}
+}
+
+// CreateOrganization creates an organization owned by a user
+func (s *Service) CreateOrganization(ctx context.Context, userID, name string) (*model.Organization, error) {
+	name, err := validateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &model.Organization{Name: name}
+	if err := s.repo.CreateOrganization(ctx, org, userID); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, "organization.created", org.ID, nil, map[string]interface{}{"name": org.Name})
+	s.logger.Info("Organization created", "organization_id", org.ID, "user_id", userID)
+	return org, nil
+}
+
+// ListOrganizations gets the organizations a user is a member of
+func (s *Service) ListOrganizations(ctx context.Context, userID string) ([]*model.UserOrganization, error) {
+	return s.repo.ListUserOrganizations(ctx, userID)
+}
+
+// GetOrganization gets an organization
+func (s *Service) GetOrganization(ctx context.Context, orgID string) (*model.Organization, error) {
+	org, err := s.repo.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, errors.NewNotFoundError("organization not found")
+	}
+	return org, nil
+}
+
+// GetMembership gets the membership of a user in an organization, or nil if
+// they aren't a member
+func (s *Service) GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	return s.repo.GetMembership(ctx, orgID, userID)
+}
+
+// RenameOrganization renames an organization. It needs the admin role.
+func (s *Service) RenameOrganization(ctx context.Context, actor *model.Membership, name string) (*model.Organization, error) {
+	if err := requireRole(actor, model.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	name, err := validateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.GetOrganization(ctx, actor.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	oldName := org.Name
+
+	ok, err := s.repo.RenameOrganization(ctx, actor.OrganizationID, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.NewNotFoundError("organization not found")
+	}
+
+	s.record(ctx, "organization.renamed", actor.OrganizationID, model.AuditDiff(
+		map[string]interface{}{"name": oldName},
+		map[string]interface{}{"name": name},
+	), nil)
+	return s.GetOrganization(ctx, actor.OrganizationID)
+}
+
+// DeleteOrganization deletes an organization with its memberships and
+// invitations. It needs the owner role.
+func (s *Service) DeleteOrganization(ctx context.Context, actor *model.Membership) error {
+	if err := requireRole(actor, model.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	ok, err := s.repo.DeleteOrganization(ctx, actor.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.NewNotFoundError("organization not found")
+	}
+
+	s.record(ctx, "organization.deleted", actor.OrganizationID, nil, nil)
+	s.logger.Info("Organization deleted", "organization_id", actor.OrganizationID, "user_id", actor.UserID)
+	return nil
+}
+
+// ListMembers gets the members of an organization
+func (s *Service) ListMembers(ctx context.Context, orgID string) ([]*model.Member, error) {
+	return s.repo.ListMembers(ctx, orgID)
+}
+
+// ChangeMemberRole changes the role of a member. It needs the admin role, and
+// only owners can make or unmake owners.
+func (s *Service) ChangeMemberRole(ctx context.Context, actor *model.Membership, userID, role string) error {
+	if !model.IsOrgRole(role) {
+		return errors.NewValidationError("role must be owner, admin or member")
+	}
+	if err := requireRole(actor, model.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	member, err := s.requireMember(ctx, actor.OrganizationID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == role {
+		return nil
+	}
+	if member.Role == model.OrgRoleOwner || role == model.OrgRoleOwner {
+		if err := requireRole(actor, model.OrgRoleOwner); err != nil {
+			return err
+		}
+	}
+	if member.Role == model.OrgRoleOwner {
+		if err := s.requireAnotherOwner(ctx, actor.OrganizationID); err != nil {
+			return err
+		}
+	}
+
+	oldRole := member.Role
+	if _, err := s.repo.SetMemberRole(ctx, actor.OrganizationID, userID, role); err != nil {
+		return err
+	}
+
+	s.record(ctx, "organization.member_role_changed", actor.OrganizationID, model.AuditDiff(
+		map[string]interface{}{"role": oldRole},
+		map[string]interface{}{"role": role},
+	), map[string]interface{}{"user_id": userID})
+	s.logger.Info("Member role changed", "organization_id", actor.OrganizationID, "user_id", userID, "role", role, "actor", actor.UserID)
+	return nil
+}
+
+// RemoveMember removes a user from an organization. Members can always leave,
+// removing others needs the admin role, and only owners can remove owners.
+func (s *Service) RemoveMember(ctx context.Context, actor *model.Membership, userID string) error {
+	member, err := s.requireMember(ctx, actor.OrganizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if userID != actor.UserID {
+		minRole := model.OrgRoleAdmin
+		if member.Role == model.OrgRoleOwner {
+			minRole = model.OrgRoleOwner
+		}
+		if err := requireRole(actor, minRole); err != nil {
+			return err
+		}
+	}
+	if member.Role == model.OrgRoleOwner {
+		if err := s.requireAnotherOwner(ctx, actor.OrganizationID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.repo.DeleteMembership(ctx, actor.OrganizationID, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, "organization.member_removed", actor.OrganizationID, nil, map[string]interface{}{
+		"user_id": userID,
+		"role":    member.Role,
+	})
+	s.logger.Info("Member removed", "organization_id", actor.OrganizationID, "user_id", userID, "actor", actor.UserID)
+	return nil
+}
+
+// InviteMember emails someone an invitation to join an organization as an
+// admin or member. It needs the admin role. Inviting an address again
+// replaces its pending invitation.
+func (s *Service) InviteMember(ctx context.Context, actor *model.Membership, email, role string) (*model.Invitation, error) {
+	if err := requireRole(actor, model.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	if role != model.OrgRoleAdmin && role != model.OrgRoleMember {
+		return nil, errors.NewValidationError("role must be admin or member")
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !s.emailSvc.ValidateEmailAddress(email) {
+		return nil, errors.NewValidationError("invalid email address")
+	}
+
+	member, err := s.repo.IsMember(ctx, actor.OrganizationID, email)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, errors.NewConflictError("user is already a member")
+	}
+
+	org, err := s.GetOrganization(ctx, actor.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invitations sent by service accounts come from the organization itself
+	inviterName := ""
+	var invitedBy *string
+	if actor.ServiceAccount {
+		inviterName = org.Name
+	} else {
+		inviter, err := s.users.GetUser(ctx, actor.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if inviter != nil {
+			inviterName = inviter.FullName
+			if inviterName == "" {
+				inviterName = inviter.Email
+			}
+		}
+		invitedBy = &actor.UserID
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+
+	invitation := &model.Invitation{
+		OrganizationID: actor.OrganizationID,
+		Email:          email,
+		Role:           role,
+		TokenHash:      hashInvitationToken(token),
+		InvitedBy:      invitedBy,
+		ExpiresAt:      time.Now().Add(s.config.InvitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	if err := s.emailSvc.SendOrganizationInvitationEmail(ctx, email, org.Name, inviterName, token); err != nil {
+		return nil, fmt.Errorf("failed to send invitation email: %w", err)
+	}
+
+	s.record(ctx, "organization.member_invited", actor.OrganizationID, nil, map[string]interface{}{
+		"invitation_id": invitation.ID,
+		"email":         email,
+		"role":          role,
+	})
+	s.logger.Info("Member invited", "organization_id", actor.OrganizationID, "invitation_id", invitation.ID, "actor", actor.UserID)
+	return invitation, nil
+}
+
+// ListInvitations gets the pending invitations of an organization. It needs
+// the admin role.
+func (s *Service) ListInvitations(ctx context.Context, actor *model.Membership) ([]*model.Invitation, error) {
+	if err := requireRole(actor, model.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvitations(ctx, actor.OrganizationID)
+}
+
+// RevokeInvitation deletes a pending invitation, so its link stops working.
+// It needs the admin role.
+func (s *Service) RevokeInvitation(ctx context.Context, actor *model.Membership, id string) error {
+	if err := requireRole(actor, model.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	ok, err := s.repo.DeleteInvitation(ctx, actor.OrganizationID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.NewNotFoundError("invitation not found")
+	}
+
+	s.record(ctx, "organization.invitation_revoked", actor.OrganizationID, nil, map[string]interface{}{"invitation_id": id})
+	return nil
+}
+
+// AcceptInvitation adds a user to an organization with the token of an
+// invitation sent to their email address, and returns the organization
+func (s *Service) AcceptInvitation(ctx context.Context, userID, token string) (*model.UserOrganization, error) {
+	if token == "" {
+		return nil, errors.NewValidationError("token is required")
+	}
+
+	invitation, err := s.repo.GetInvitationByToken(ctx, hashInvitationToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil {
+		return nil, errors.NewNotFoundError("invitation not found or expired")
+	}
+
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, errors.NewAuthorizationError("invitation was sent to another email address")
+	}
+
+	ok, err := s.repo.AcceptInvitation(ctx, invitation.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.NewNotFoundError("invitation not found or expired")
+	}
+
+	org, err := s.GetOrganization(ctx, invitation.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	membership, err := s.repo.GetMembership(ctx, invitation.OrganizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, errors.NewNotFoundError("organization not found")
+	}
+
+	s.record(ctx, "organization.invitation_accepted", org.ID, nil, map[string]interface{}{
+		"invitation_id": invitation.ID,
+		"role":          membership.Role,
+	})
+	s.logger.Info("Invitation accepted", "organization_id", org.ID, "invitation_id", invitation.ID, "user_id", userID)
+	return &model.UserOrganization{Organization: *org, Role: membership.Role}, nil
+}
+
+// DeleteExpiredInvitations deletes the invitations that expired without being
+// accepted. Expired invitations can't be accepted, so this only keeps the
+// table small.
+func (s *Service) DeleteExpiredInvitations(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredInvitations(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.logger.Info("Deleted expired invitations", "count", deleted)
+	}
+	return nil
+}
+
+// record records a change to an organization in the audit log
+func (s *Service) record(ctx context.Context, action, orgID string, changes map[string]model.AuditChange, metadata map[string]interface{}) {
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     action,
+		EntityType: "organization",
+		EntityID:   orgID,
+		Changes:    changes,
+		Metadata:   metadata,
+	})
+}
+
+// requireMember gets the membership of a user, failing if they aren't a member
+func (s *Service) requireMember(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	member, err := s.repo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, errors.NewNotFoundError("member not found")
+	}
+	return member, nil
+}
+
+// requireAnotherOwner fails unless an organization has more than one owner,
+// before an owner leaves or stops being one
+func (s *Service) requireAnotherOwner(ctx context.Context, orgID string) error {
+	owners, err := s.repo.CountOwners(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errors.NewConflictError("an organization needs at least one owner")
+	}
+	return nil
+}
+
+// requireRole fails unless a membership has at least a role
+func requireRole(membership *model.Membership, role string) error {
+	if membership == nil || !model.OrgRoleAtLeast(membership.Role, role) {
+		return errors.NewAuthorizationError("requires the " + role + " role")
+	}
+	return nil
+}
+
+// validateName trims an organization name and checks its length
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.NewValidationError("name is required")
+	}
+	if len([]rune(name)) > maxNameLength {
+		return "", errors.NewValidationError(fmt.Sprintf("name must be at most %d characters", maxNameLength))
+	}
+	return name, nil
+}
+
+// generateInvitationToken generates a random token for accepting an invitation
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashInvitationToken hashes an invitation token for storage
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
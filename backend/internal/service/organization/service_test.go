@@ -0,0 +1,395 @@
+package organization
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository
+type fakeRepository struct {
+	orgs        map[string]*model.Organization
+	memberships map[string]map[string]*model.Membership
+	emails      map[string]string
+	invitations map[string]*model.Invitation
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		orgs:        map[string]*model.Organization{},
+		memberships: map[string]map[string]*model.Membership{},
+		emails:      map[string]string{},
+		invitations: map[string]*model.Invitation{},
+	}
+}
+
+func (f *fakeRepository) CreateOrganization(ctx context.Context, org *model.Organization, ownerID string) error {
+	org.ID = "org-" + org.Name
+	f.orgs[org.ID] = org
+	f.memberships[org.ID] = map[string]*model.Membership{
+		ownerID: {OrganizationID: org.ID, UserID: ownerID, Role: model.OrgRoleOwner},
+	}
+	return nil
+}
+
+func (f *fakeRepository) GetOrganization(ctx context.Context, id string) (*model.Organization, error) {
+	return f.orgs[id], nil
+}
+
+func (f *fakeRepository) RenameOrganization(ctx context.Context, id, name string) (bool, error) {
+	org := f.orgs[id]
+	if org == nil {
+		return false, nil
+	}
+	org.Name = name
+	return true, nil
+}
+
+func (f *fakeRepository) DeleteOrganization(ctx context.Context, id string) (bool, error) {
+	_, ok := f.orgs[id]
+	delete(f.orgs, id)
+	delete(f.memberships, id)
+	return ok, nil
+}
+
+func (f *fakeRepository) ListUserOrganizations(ctx context.Context, userID string) ([]*model.UserOrganization, error) {
+	orgs := []*model.UserOrganization{}
+	for id, members := range f.memberships {
+		if m := members[userID]; m != nil {
+			orgs = append(orgs, &model.UserOrganization{Organization: *f.orgs[id], Role: m.Role})
+		}
+	}
+	return orgs, nil
+}
+
+func (f *fakeRepository) GetMembership(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	return f.memberships[orgID][userID], nil
+}
+
+func (f *fakeRepository) ListMembers(ctx context.Context, orgID string) ([]*model.Member, error) {
+	members := []*model.Member{}
+	for _, m := range f.memberships[orgID] {
+		members = append(members, &model.Member{UserID: m.UserID, Email: f.emails[m.UserID], Role: m.Role})
+	}
+	return members, nil
+}
+
+func (f *fakeRepository) IsMember(ctx context.Context, orgID, email string) (bool, error) {
+	for userID := range f.memberships[orgID] {
+		if strings.EqualFold(f.emails[userID], email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRepository) CountOwners(ctx context.Context, orgID string) (int, error) {
+	owners := 0
+	for _, m := range f.memberships[orgID] {
+		if m.Role == model.OrgRoleOwner {
+			owners++
+		}
+	}
+	return owners, nil
+}
+
+func (f *fakeRepository) SetMemberRole(ctx context.Context, orgID, userID, role string) (bool, error) {
+	m := f.memberships[orgID][userID]
+	if m == nil {
+		return false, nil
+	}
+	m.Role = role
+	return true, nil
+}
+
+func (f *fakeRepository) DeleteMembership(ctx context.Context, orgID, userID string) (bool, error) {
+	_, ok := f.memberships[orgID][userID]
+	delete(f.memberships[orgID], userID)
+	return ok, nil
+}
+
+func (f *fakeRepository) CreateInvitation(ctx context.Context, invitation *model.Invitation) error {
+	invitation.ID = "inv-" + invitation.Email
+	invitation.CreatedAt = time.Now()
+	f.invitations[invitation.ID] = invitation
+	return nil
+}
+
+func (f *fakeRepository) ListInvitations(ctx context.Context, orgID string) ([]*model.Invitation, error) {
+	invitations := []*model.Invitation{}
+	for _, invitation := range f.invitations {
+		if invitation.OrganizationID == orgID {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (f *fakeRepository) GetInvitationByToken(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	for _, invitation := range f.invitations {
+		if invitation.TokenHash == tokenHash && invitation.AcceptedAt == nil && invitation.ExpiresAt.After(time.Now()) {
+			return invitation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) DeleteInvitation(ctx context.Context, orgID, id string) (bool, error) {
+	invitation := f.invitations[id]
+	if invitation == nil || invitation.OrganizationID != orgID {
+		return false, nil
+	}
+	delete(f.invitations, id)
+	return true, nil
+}
+
+func (f *fakeRepository) AcceptInvitation(ctx context.Context, id, userID string) (bool, error) {
+	invitation := f.invitations[id]
+	if invitation == nil || invitation.AcceptedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	if f.memberships[invitation.OrganizationID][userID] == nil {
+		f.memberships[invitation.OrganizationID][userID] = &model.Membership{
+			OrganizationID: invitation.OrganizationID, UserID: userID, Role: invitation.Role,
+		}
+	}
+	return true, nil
+}
+
+func (f *fakeRepository) DeleteExpiredInvitations(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// addMember adds a user with an email address to an organization
+func (f *fakeRepository) addMember(orgID, userID, email, role string) *model.Membership {
+	m := &model.Membership{OrganizationID: orgID, UserID: userID, Role: role}
+	f.memberships[orgID][userID] = m
+	f.emails[userID] = email
+	return m
+}
+
+// fakeUsers finds users in the emails of the fake repository
+type fakeUsers struct {
+	repo *fakeRepository
+}
+
+func (f *fakeUsers) GetUser(ctx context.Context, id string) (*model.AdminUser, error) {
+	email, ok := f.repo.emails[id]
+	if !ok {
+		return nil, nil
+	}
+	return &model.AdminUser{ID: id, Email: email}, nil
+}
+
+// fakeEmailService keeps the tokens of the invitations it sends
+type fakeEmailService struct {
+	service.EmailService
+	tokens map[string]string
+}
+
+func (f *fakeEmailService) ValidateEmailAddress(email string) bool {
+	return strings.Contains(email, "@")
+}
+
+func (f *fakeEmailService) SendOrganizationInvitationEmail(ctx context.Context, to, organizationName, inviterName, token string) error {
+	f.tokens[to] = token
+	return nil
+}
+
+// newTestService creates a service with an organization owned by user-1
+func newTestService(t *testing.T) (*Service, *fakeRepository, *fakeEmailService, *model.Membership) {
+	t.Helper()
+
+	repo := newFakeRepository()
+	emails := &fakeEmailService{tokens: map[string]string{}}
+	svc := NewService(repo, &fakeUsers{repo: repo}, emails, &config.AuthConfig{InvitationTTL: time.Hour}, &servicetest.AuditLog{}, logger.DefaultLogger())
+
+	org, err := svc.CreateOrganization(context.Background(), "user-1", "Acme")
+	require.NoError(t, err)
+	repo.emails["user-1"] = "owner@example.com"
+
+	return svc, repo, emails, repo.memberships[org.ID]["user-1"]
+}
+
+func TestChangeMemberRole(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("admins can promote members to admin", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		admin := repo.addMember(owner.OrganizationID, "user-2", "admin@example.com", model.OrgRoleAdmin)
+		repo.addMember(owner.OrganizationID, "user-3", "member@example.com", model.OrgRoleMember)
+
+		require.NoError(t, svc.ChangeMemberRole(ctx, admin, "user-3", model.OrgRoleAdmin))
+		assert.Equal(t, model.OrgRoleAdmin, repo.memberships[owner.OrganizationID]["user-3"].Role)
+	})
+
+	t.Run("only owners make owners", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		admin := repo.addMember(owner.OrganizationID, "user-2", "admin@example.com", model.OrgRoleAdmin)
+		repo.addMember(owner.OrganizationID, "user-3", "member@example.com", model.OrgRoleMember)
+
+		servicetest.AssertStatus(t, svc.ChangeMemberRole(ctx, admin, "user-3", model.OrgRoleOwner), http.StatusForbidden)
+		require.NoError(t, svc.ChangeMemberRole(ctx, owner, "user-3", model.OrgRoleOwner))
+	})
+
+	t.Run("members can't change roles", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		member := repo.addMember(owner.OrganizationID, "user-2", "member@example.com", model.OrgRoleMember)
+
+		servicetest.AssertStatus(t, svc.ChangeMemberRole(ctx, member, "user-2", model.OrgRoleAdmin), http.StatusForbidden)
+	})
+
+	t.Run("the last owner can't be demoted", func(t *testing.T) {
+		svc, _, _, owner := newTestService(t)
+
+		servicetest.AssertStatus(t, svc.ChangeMemberRole(ctx, owner, "user-1", model.OrgRoleAdmin), http.StatusConflict)
+	})
+
+	t.Run("rejects unknown roles", func(t *testing.T) {
+		svc, _, _, owner := newTestService(t)
+
+		servicetest.AssertStatus(t, svc.ChangeMemberRole(ctx, owner, "user-1", "superuser"), http.StatusBadRequest)
+	})
+}
+
+func TestRemoveMember(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("members can leave", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		member := repo.addMember(owner.OrganizationID, "user-2", "member@example.com", model.OrgRoleMember)
+
+		require.NoError(t, svc.RemoveMember(ctx, member, "user-2"))
+		assert.Nil(t, repo.memberships[owner.OrganizationID]["user-2"])
+	})
+
+	t.Run("admins can't remove owners", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		admin := repo.addMember(owner.OrganizationID, "user-2", "admin@example.com", model.OrgRoleAdmin)
+
+		servicetest.AssertStatus(t, svc.RemoveMember(ctx, admin, "user-1"), http.StatusForbidden)
+	})
+
+	t.Run("the last owner can't leave", func(t *testing.T) {
+		svc, _, _, owner := newTestService(t)
+
+		servicetest.AssertStatus(t, svc.RemoveMember(ctx, owner, "user-1"), http.StatusConflict)
+	})
+
+	t.Run("owners can leave when there is another owner", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		repo.addMember(owner.OrganizationID, "user-2", "other@example.com", model.OrgRoleOwner)
+
+		require.NoError(t, svc.RemoveMember(ctx, owner, "user-1"))
+	})
+}
+
+func TestInvitations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invited users join with the invitation's role", func(t *testing.T) {
+		svc, repo, emails, owner := newTestService(t)
+		repo.emails["user-2"] = "new@example.com"
+
+		invitation, err := svc.InviteMember(ctx, owner, " New@Example.com ", model.OrgRoleAdmin)
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", invitation.Email)
+
+		token := emails.tokens["new@example.com"]
+		require.NotEmpty(t, token)
+		assert.NotEqual(t, token, invitation.TokenHash)
+
+		org, err := svc.AcceptInvitation(ctx, "user-2", token)
+		require.NoError(t, err)
+		assert.Equal(t, owner.OrganizationID, org.ID)
+		assert.Equal(t, model.OrgRoleAdmin, org.Role)
+
+		_, err = svc.AcceptInvitation(ctx, "user-2", token)
+		servicetest.AssertStatus(t, err, http.StatusNotFound)
+	})
+
+	t.Run("invitations can't be accepted by other addresses", func(t *testing.T) {
+		svc, repo, emails, owner := newTestService(t)
+		repo.emails["user-2"] = "someone-else@example.com"
+
+		_, err := svc.InviteMember(ctx, owner, "new@example.com", model.OrgRoleMember)
+		require.NoError(t, err)
+
+		_, err = svc.AcceptInvitation(ctx, "user-2", emails.tokens["new@example.com"])
+		servicetest.AssertStatus(t, err, http.StatusForbidden)
+	})
+
+	t.Run("expired invitations can't be accepted", func(t *testing.T) {
+		svc, repo, emails, owner := newTestService(t)
+		repo.emails["user-2"] = "new@example.com"
+
+		invitation, err := svc.InviteMember(ctx, owner, "new@example.com", model.OrgRoleMember)
+		require.NoError(t, err)
+		invitation.ExpiresAt = time.Now().Add(-time.Minute)
+
+		_, err = svc.AcceptInvitation(ctx, "user-2", emails.tokens["new@example.com"])
+		servicetest.AssertStatus(t, err, http.StatusNotFound)
+	})
+
+	t.Run("members can't invite", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		member := repo.addMember(owner.OrganizationID, "user-2", "member@example.com", model.OrgRoleMember)
+
+		_, err := svc.InviteMember(ctx, member, "new@example.com", model.OrgRoleMember)
+		servicetest.AssertStatus(t, err, http.StatusForbidden)
+	})
+
+	t.Run("owners can't be invited", func(t *testing.T) {
+		svc, _, _, owner := newTestService(t)
+
+		_, err := svc.InviteMember(ctx, owner, "new@example.com", model.OrgRoleOwner)
+		servicetest.AssertStatus(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("members can't be invited again", func(t *testing.T) {
+		svc, repo, _, owner := newTestService(t)
+		repo.addMember(owner.OrganizationID, "user-2", "member@example.com", model.OrgRoleMember)
+
+		_, err := svc.InviteMember(ctx, owner, "MEMBER@example.com", model.OrgRoleMember)
+		servicetest.AssertStatus(t, err, http.StatusConflict)
+	})
+}
+
+func TestAuditsChanges(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _, owner := newTestService(t)
+	audit := svc.audit.(*servicetest.AuditLog)
+	repo.addMember(owner.OrganizationID, "user-2", "member@example.com", model.OrgRoleMember)
+
+	_, err := svc.RenameOrganization(ctx, owner, "Acme Inc")
+	require.NoError(t, err)
+	require.NoError(t, svc.ChangeMemberRole(ctx, owner, "user-2", model.OrgRoleAdmin))
+	require.NoError(t, svc.RemoveMember(ctx, owner, "user-2"))
+
+	require.Len(t, audit.Events, 4)
+	assert.Equal(t, "organization.created", audit.Events[0].Action)
+
+	renamed := audit.Events[1]
+	assert.Equal(t, "organization.renamed", renamed.Action)
+	assert.Equal(t, owner.OrganizationID, renamed.EntityID)
+	assert.Equal(t, model.AuditChange{Old: "Acme", New: "Acme Inc"}, renamed.Changes["name"])
+
+	roleChanged := audit.Events[2]
+	assert.Equal(t, "organization.member_role_changed", roleChanged.Action)
+	assert.Equal(t, model.AuditChange{Old: model.OrgRoleMember, New: model.OrgRoleAdmin}, roleChanged.Changes["role"])
+	assert.Equal(t, "user-2", roleChanged.Metadata["user_id"])
+
+	assert.Equal(t, "organization.member_removed", audit.Events[3].Action)
+}
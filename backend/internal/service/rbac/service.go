@@ -0,0 +1,245 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+// authorizationTTL is how long the roles and permissions of a user are cached.
+// Changes made through the service invalidate them right away, so this only
+// bounds how stale they get when the tables are changed directly.
+const authorizationTTL = time.Minute
+
+// Repository defines the interface for role and permission database operations
+type Repository interface {
+	// ListRoles gets every role with its permissions
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+
+	// GetRole gets a role by name, or nil if there is none
+	GetRole(ctx context.Context, name string) (*model.Role, error)
+
+	// ListPermissions gets every permission
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+
+	// GetPermission gets a permission by name, or nil if there is none
+	GetPermission(ctx context.Context, name string) (*model.Permission, error)
+
+	// GetUserAuthorization gets the roles of a user and the permissions they grant
+	GetUserAuthorization(ctx context.Context, userID string) (*model.Authorization, error)
+
+	// AssignRole assigns a role to a user
+	AssignRole(ctx context.Context, userID, role, assignedBy string) error
+
+	// RevokeRole removes a role from a user and reports whether they had it
+	RevokeRole(ctx context.Context, userID, role string) (bool, error)
+
+	// GrantPermission grants a permission to a role
+	GrantPermission(ctx context.Context, role, permission string) error
+
+	// RevokePermission removes a permission from a role and reports whether it had it
+	RevokePermission(ctx context.Context, role, permission string) (bool, error)
+}
+
+// Service decides what users are allowed to do from the roles assigned to
+// them, and manages those assignments
+type Service struct {
+	repo   Repository
+	cache  service.CacheService
+	audit  service.AuditLog
+	logger logger.Logger
+}
+
+// NewService creates a new authorization service caching the roles and
+// permissions of users
+func NewService(repo Repository, cache service.CacheService, audit service.AuditLog, log logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		cache:  cache,
+		audit:  audit,
+		logger: log,
+	}
+}
+
+// GetAuthorization gets the roles of a user and the permissions they grant
+func (s *Service) GetAuthorization(ctx context.Context, userID string) (*model.Authorization, error) {
+	var cached *model.Authorization
+	if err := s.cache.GetCachedData(ctx, authorizationKey(userID), &cached); err != nil {
+		s.logger.Warn("Failed to read cached authorization", "user_id", userID, "error", err)
+	} else if cached != nil {
+		return cached, nil
+	}
+
+	authz, err := s.repo.GetUserAuthorization(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.CacheData(ctx, authorizationKey(userID), authz, int(authorizationTTL.Seconds())); err != nil {
+		s.logger.Warn("Failed to cache authorization", "user_id", userID, "error", err)
+	}
+
+	return authz, nil
+}
+
+// Can reports whether a user has a permission
+func (s *Service) Can(ctx context.Context, userID, permission string) (bool, error) {
+	authz, err := s.GetAuthorization(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return authz.Can(permission), nil
+}
+
+// HasRole reports whether a user has a role
+func (s *Service) HasRole(ctx context.Context, userID, role string) (bool, error) {
+	authz, err := s.GetAuthorization(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return authz.HasRole(role), nil
+}
+
+// ListRoles gets every role with its permissions
+func (s *Service) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	return s.repo.ListRoles(ctx)
+}
+
+// ListPermissions gets every permission
+func (s *Service) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	return s.repo.ListPermissions(ctx)
+}
+
+// AssignRole assigns a role to a user. assignedBy identifies who made the
+// change, for auditing.
+func (s *Service) AssignRole(ctx context.Context, userID, role, assignedBy string) error {
+	if err := s.requireRole(ctx, role); err != nil {
+		return err
+	}
+
+	if err := s.repo.AssignRole(ctx, userID, role, assignedBy); err != nil {
+		return err
+	}
+	s.invalidateUser(ctx, userID)
+
+	s.record(ctx, "role.assigned", "user", userID, map[string]interface{}{"role": role, "actor": assignedBy})
+	s.logger.Info("Assigned role", "user_id", userID, "role", role, "assigned_by", assignedBy)
+	return nil
+}
+
+// RevokeRole removes a role from a user. revokedBy identifies who made the
+// change, for auditing.
+func (s *Service) RevokeRole(ctx context.Context, userID, role, revokedBy string) error {
+	revoked, err := s.repo.RevokeRole(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.NewNotFoundError("user does not have this role")
+	}
+	s.invalidateUser(ctx, userID)
+
+	s.record(ctx, "role.revoked", "user", userID, map[string]interface{}{"role": role, "actor": revokedBy})
+	s.logger.Info("Revoked role", "user_id", userID, "role", role, "revoked_by", revokedBy)
+	return nil
+}
+
+// GrantPermission grants a permission to a role
+func (s *Service) GrantPermission(ctx context.Context, role, permission, grantedBy string) error {
+	if err := s.requireRole(ctx, role); err != nil {
+		return err
+	}
+	if err := s.requirePermission(ctx, permission); err != nil {
+		return err
+	}
+
+	if err := s.repo.GrantPermission(ctx, role, permission); err != nil {
+		return err
+	}
+	s.invalidateAll(ctx)
+
+	s.record(ctx, "permission.granted", "role", role, map[string]interface{}{"permission": permission, "actor": grantedBy})
+	s.logger.Info("Granted permission", "role", role, "permission", permission, "granted_by", grantedBy)
+	return nil
+}
+
+// RevokePermission removes a permission from a role. The permissions of the
+// admin role can't be revoked, so there is always a role that can manage the
+// others.
+func (s *Service) RevokePermission(ctx context.Context, role, permission, revokedBy string) error {
+	if role == model.RoleAdmin {
+		return errors.NewValidationError("permissions of the admin role can't be revoked")
+	}
+
+	revoked, err := s.repo.RevokePermission(ctx, role, permission)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.NewNotFoundError("role does not have this permission")
+	}
+	s.invalidateAll(ctx)
+
+	s.record(ctx, "permission.revoked", "role", role, map[string]interface{}{"permission": permission, "actor": revokedBy})
+	s.logger.Info("Revoked permission", "role", role, "permission", permission, "revoked_by", revokedBy)
+	return nil
+}
+
+// record records a change to roles in the audit log
+func (s *Service) record(ctx context.Context, action, entityType, entityID string, metadata map[string]interface{}) {
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Metadata:   metadata,
+	})
+}
+
+// requireRole returns a not found error if a role doesn't exist
+func (s *Service) requireRole(ctx context.Context, name string) error {
+	role, err := s.repo.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errors.NewNotFoundError("role not found")
+	}
+	return nil
+}
+
+// requirePermission returns a not found error if a permission doesn't exist
+func (s *Service) requirePermission(ctx context.Context, name string) error {
+	permission, err := s.repo.GetPermission(ctx, name)
+	if err != nil {
+		return err
+	}
+	if permission == nil {
+		return errors.NewNotFoundError("permission not found")
+	}
+	return nil
+}
+
+// invalidateUser forgets the cached authorization of a user
+func (s *Service) invalidateUser(ctx context.Context, userID string) {
+	if err := s.cache.InvalidateCache(ctx, authorizationKey(userID)); err != nil {
+		s.logger.Error("Failed to invalidate cached authorization", "user_id", userID, "error", err)
+	}
+}
+
+// invalidateAll forgets the cached authorization of every user, after the
+// permissions of a role changed
+func (s *Service) invalidateAll(ctx context.Context) {
+	if err := s.cache.InvalidateCachePattern(ctx, authorizationKey("*")); err != nil {
+		s.logger.Error("Failed to invalidate cached authorizations", "error", err)
+	}
+}
+
+// authorizationKey returns the cache key of a user's authorization
+func authorizationKey(userID string) string {
+	return fmt.Sprintf("rbac:v1:authorization:%s", userID)
+}
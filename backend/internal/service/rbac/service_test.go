@@ -0,0 +1,182 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/cache"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository counting authorization lookups
+type fakeRepository struct {
+	// Permissions of each role
+	roles map[string][]string
+	// Roles of each user
+	users   map[string][]string
+	lookups int
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		roles: map[string][]string{
+			model.RoleAdmin:   {model.PermissionRolesManage, model.PermissionRolesRead, model.PermissionUsersRead},
+			model.RoleSupport: {model.PermissionUsersRead},
+		},
+		users: make(map[string][]string),
+	}
+}
+
+func (f *fakeRepository) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	var roles []*model.Role
+	for name, permissions := range f.roles {
+		roles = append(roles, &model.Role{Name: name, Permissions: permissions})
+	}
+	return roles, nil
+}
+
+func (f *fakeRepository) GetRole(ctx context.Context, name string) (*model.Role, error) {
+	permissions, ok := f.roles[name]
+	if !ok {
+		return nil, nil
+	}
+	return &model.Role{Name: name, Permissions: permissions}, nil
+}
+
+func (f *fakeRepository) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	return nil, nil
+}
+
+func (f *fakeRepository) GetPermission(ctx context.Context, name string) (*model.Permission, error) {
+	switch name {
+	case model.PermissionRolesManage, model.PermissionRolesRead, model.PermissionUsersRead, model.PermissionUsersManage:
+		return &model.Permission{Name: name}, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) GetUserAuthorization(ctx context.Context, userID string) (*model.Authorization, error) {
+	f.lookups++
+	authz := &model.Authorization{Roles: []string{}, Permissions: []string{}}
+	for _, role := range f.users[userID] {
+		authz.Roles = append(authz.Roles, role)
+		authz.Permissions = append(authz.Permissions, f.roles[role]...)
+	}
+	return authz, nil
+}
+
+func (f *fakeRepository) AssignRole(ctx context.Context, userID, role, assignedBy string) error {
+	for _, r := range f.users[userID] {
+		if r == role {
+			return nil
+		}
+	}
+	f.users[userID] = append(f.users[userID], role)
+	return nil
+}
+
+func (f *fakeRepository) RevokeRole(ctx context.Context, userID, role string) (bool, error) {
+	return remove(f.users, userID, role), nil
+}
+
+func (f *fakeRepository) GrantPermission(ctx context.Context, role, permission string) error {
+	f.roles[role] = append(f.roles[role], permission)
+	return nil
+}
+
+func (f *fakeRepository) RevokePermission(ctx context.Context, role, permission string) (bool, error) {
+	return remove(f.roles, role, permission), nil
+}
+
+// remove removes a value from a list of a map and reports whether it was there
+func remove(lists map[string][]string, key, value string) bool {
+	for i, v := range lists[key] {
+		if v == value {
+			lists[key] = append(lists[key][:i:i], lists[key][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func newTestService(t *testing.T) (*Service, *fakeRepository) {
+	memory := cache.NewMemoryService()
+	t.Cleanup(func() { memory.Close() })
+
+	repo := newFakeRepository()
+	return NewService(repo, memory, &servicetest.AuditLog{}, logger.DefaultLogger()), repo
+}
+
+func TestCan(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+
+	ok, err := s.Can(ctx, "user-1", model.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Assigning a role takes effect right away
+	require.NoError(t, s.AssignRole(ctx, "user-1", model.RoleSupport, "admin_key"))
+	ok, err = s.Can(ctx, "user-1", model.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Can(ctx, "user-1", model.PermissionRolesManage)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Authorizations are cached
+	lookups := repo.lookups
+	ok, err = s.HasRole(ctx, "user-1", model.RoleSupport)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, lookups, repo.lookups)
+
+	// So is revoking it
+	require.NoError(t, s.RevokeRole(ctx, "user-1", model.RoleSupport, "admin_key"))
+	ok, err = s.Can(ctx, "user-1", model.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRolePermissions(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+
+	require.NoError(t, s.AssignRole(ctx, "user-1", model.RoleSupport, "admin_key"))
+	ok, err := s.Can(ctx, "user-1", model.PermissionUsersManage)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Permission changes reach every user with the role
+	require.NoError(t, s.GrantPermission(ctx, model.RoleSupport, model.PermissionUsersManage, "admin_key"))
+	ok, err = s.Can(ctx, "user-1", model.PermissionUsersManage)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, s.RevokePermission(ctx, model.RoleSupport, model.PermissionUsersManage, "admin_key"))
+	ok, err = s.Can(ctx, "user-1", model.PermissionUsersManage)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The admin role keeps its permissions
+	servicetest.AssertStatus(t, s.RevokePermission(ctx, model.RoleAdmin, model.PermissionRolesManage, "admin_key"), http.StatusBadRequest)
+
+	// Changes are audited, failed ones aren't
+	assert.Equal(t, []string{"role.assigned", "permission.granted", "permission.revoked"}, s.audit.(*servicetest.AuditLog).Actions())
+}
+
+func TestUnknownRolesAndPermissions(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+
+	servicetest.AssertStatus(t, s.AssignRole(ctx, "user-1", "owner", "admin_key"), http.StatusNotFound)
+	servicetest.AssertStatus(t, s.RevokeRole(ctx, "user-1", model.RoleSupport, "admin_key"), http.StatusNotFound)
+	servicetest.AssertStatus(t, s.GrantPermission(ctx, "owner", model.PermissionUsersRead, "admin_key"), http.StatusNotFound)
+	servicetest.AssertStatus(t, s.GrantPermission(ctx, model.RoleSupport, "billing:manage", "admin_key"), http.StatusNotFound)
+	servicetest.AssertStatus(t, s.RevokePermission(ctx, model.RoleSupport, model.PermissionRolesManage, "admin_key"), http.StatusNotFound)
+}
@@ -0,0 +1,69 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+// maxDigestEvents is the number of events listed for review in a security digest
+const maxDigestEvents = 10
+
+// digestEventTypes are the events listed individually in a security digest, as
+// the ones the user should check they recognize
+var digestEventTypes = []string{
+	model.EventNewDeviceLogin,
+	model.EventNewLocationLogin,
+	model.EventAccountLocked,
+	model.EventPasswordChanged,
+	model.EventPasswordReset,
+	model.EventEmailChanged,
+	model.EventSuspiciousActivity,
+}
+
+// SendSecurityDigest sends the scheduled security digest to a user, summarizing
+// their security events since the previous digest, and schedules the next one.
+// Nothing is sent for a period without any events.
+func (s *Service) SendSecurityDigest(ctx context.Context, userID, email string) error {
+	interval := s.config.Auth.SecurityDigestInterval
+	if interval <= 0 {
+		return nil
+	}
+
+	to := time.Now()
+	from := to.Add(-interval)
+
+	// Schedule the next digest first, so a failure below doesn't end the series
+	if err := s.emailSvc.ScheduleEmail(ctx, userID, model.ScheduledEmailSecurityDigest, email, to.Add(interval)); err != nil {
+		s.logger.Error("Failed to schedule next security digest", "user_id", userID, "error", err)
+	}
+
+	counts, err := s.repo.CountUserSecurityEventsByType(ctx, userID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to count security events: %w", err)
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	events, err := s.repo.ListUserSecurityEvents(ctx, userID, model.SecurityEventFilter{
+		EventTypes: digestEventTypes,
+		From:       from,
+		To:         to,
+		Limit:      maxDigestEvents,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list security events: %w", err)
+	}
+
+	digest := &model.SecurityDigest{
+		From:   from,
+		To:     to,
+		Counts: counts,
+		Events: events,
+	}
+
+	return s.emailSvc.SendSecurityDigestEmail(ctx, email, digest)
+}
@@ -0,0 +1,146 @@
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+)
+
+const (
+	// DefaultSecurityEventPageSize is used when no page size is requested
+	DefaultSecurityEventPageSize = 20
+	// MaxSecurityEventPageSize is the largest page size that can be requested
+	MaxSecurityEventPageSize = 100
+
+	// reportedActivityLockDuration is how long an account stays locked after the
+	// user reports activity they don't recognize. It is long enough for the user
+	// to complete the password reset that is started alongside the lock.
+	reportedActivityLockDuration = 24 * time.Hour
+)
+
+// ListSecurityEvents returns a page of security events for a user, newest first
+func (s *Service) ListSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) (*model.SecurityEventPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSecurityEventPageSize
+	}
+	if filter.Limit > MaxSecurityEventPageSize {
+		filter.Limit = MaxSecurityEventPageSize
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, errors.NewValidationError("from must be before to")
+	}
+
+	// Fetch one extra event to find out whether there is another page
+	pageSize := filter.Limit
+	filter.Limit++
+
+	events, err := s.repo.ListUserSecurityEvents(ctx, userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+
+	page := &model.SecurityEventPage{Events: events}
+	if len(events) > pageSize {
+		page.Events = events[:pageSize]
+		last := page.Events[pageSize-1]
+		page.NextCursor = EncodeSecurityEventCursor(&model.SecurityEventCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	return page, nil
+}
+
+// ReportUnrecognizedActivity handles a user flagging one of their security events
+// as "this wasn't me". The account is locked so that whoever caused the event can
+// no longer use it; the caller is expected to revoke sessions and start a password reset.
+func (s *Service) ReportUnrecognizedActivity(ctx context.Context, userID, eventID, ipAddress, userAgent string) error {
+	event, err := s.repo.GetSecurityEvent(ctx, userID, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to get security event: %w", err)
+	}
+	if event == nil {
+		return errors.NewNotFoundError("security event not found")
+	}
+
+	location, err := s.getLocationString(ipAddress)
+	if err != nil {
+		s.logger.Warn("Failed to get location for IP", "ip", ipAddress, "error", err)
+		location = "Unknown location"
+	}
+
+	// Record the report itself
+	report := &model.SecurityEvent{
+		UserID:      userID,
+		EventType:   model.EventActivityReported,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Location:    location,
+		Description: fmt.Sprintf("User reported event %s (%s) as unrecognized", event.ID, event.EventType),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.repo.RecordSecurityEvent(ctx, report); err != nil {
+		s.logger.Error("Failed to record security event", "error", err)
+	}
+
+	// Lock the account
+	unlockTime := time.Now().Add(reportedActivityLockDuration)
+	reason := fmt.Sprintf("User reported %s activity from %s as unrecognized", event.EventType, event.Location)
+
+	if err := s.LockAccount(ctx, userID, unlockTime, reason); err != nil {
+		return fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	lockEvent := &model.SecurityEvent{
+		UserID:      userID,
+		EventType:   model.EventAccountLocked,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Location:    location,
+		Description: reason,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.repo.RecordSecurityEvent(ctx, lockEvent); err != nil {
+		s.logger.Error("Failed to record security event", "error", err)
+	}
+
+	return nil
+}
+
+// EncodeSecurityEventCursor encodes a cursor into an opaque string for clients
+func EncodeSecurityEventCursor(cursor *model.SecurityEventCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeSecurityEventCursor decodes a cursor previously returned by EncodeSecurityEventCursor
+func DecodeSecurityEventCursor(encoded string) (*model.SecurityEventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.NewValidationError("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.NewValidationError("invalid cursor")
+	}
+
+	return &model.SecurityEventCursor{
+		CreatedAt: createdAt,
+		ID:        parts[1],
+	}, nil
+}
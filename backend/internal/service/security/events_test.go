@@ -0,0 +1,74 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestReportUnrecognizedActivity tests locking an account when the user
+// reports one of their security events
+func TestReportUnrecognizedActivity(t *testing.T) {
+	ctx := context.Background()
+	reported := &model.SecurityEvent{
+		ID:        "evt-1",
+		UserID:    "user-1",
+		EventType: model.EventNewDeviceLogin,
+		Location:  "Accra, Ghana",
+	}
+
+	t.Run("locks the account", func(t *testing.T) {
+		s, repo, _, _ := newTestService()
+		repo.On("GetSecurityEvent", ctx, "user-1", "evt-1").Return(reported, nil)
+		repo.On("RecordSecurityEvent", ctx, mock.Anything).Return(nil)
+		repo.On("LockAccount", ctx, "user-1", mock.Anything, mock.Anything).Return(nil)
+
+		before := time.Now()
+		err := s.ReportUnrecognizedActivity(ctx, "user-1", "evt-1", "203.0.113.10", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		repo.AssertCalled(t, "LockAccount", ctx, "user-1", mock.MatchedBy(func(until time.Time) bool {
+			return !until.Before(before.Add(reportedActivityLockDuration))
+		}), "User reported new_device_login activity from Accra, Ghana as unrecognized")
+
+		// Both the report and the lock are recorded
+		var recorded []string
+		for _, call := range repo.Calls {
+			if call.Method == "RecordSecurityEvent" {
+				event := call.Arguments.Get(1).(*model.SecurityEvent)
+				assert.Equal(t, "user-1", event.UserID)
+				assert.Equal(t, "203.0.113.10", event.IPAddress)
+				recorded = append(recorded, event.EventType)
+			}
+		}
+		assert.Equal(t, []string{model.EventActivityReported, model.EventAccountLocked}, recorded)
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		s, repo, _, _ := newTestService()
+		repo.On("GetSecurityEvent", ctx, "user-1", "evt-2").Return(nil, nil)
+
+		err := s.ReportUnrecognizedActivity(ctx, "user-1", "evt-2", "203.0.113.10", "Mozilla/5.0")
+
+		servicetest.AssertStatus(t, err, http.StatusNotFound)
+		repo.AssertNotCalled(t, "LockAccount", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lock failure", func(t *testing.T) {
+		s, repo, _, _ := newTestService()
+		repo.On("GetSecurityEvent", ctx, "user-1", "evt-1").Return(reported, nil)
+		repo.On("RecordSecurityEvent", ctx, mock.Anything).Return(nil)
+		repo.On("LockAccount", ctx, "user-1", mock.Anything, mock.Anything).Return(errors.New("database is down"))
+
+		err := s.ReportUnrecognizedActivity(ctx, "user-1", "evt-1", "203.0.113.10", "Mozilla/5.0")
+
+		assert.ErrorContains(t, err, "failed to lock account")
+	})
+}
@@ -2,14 +2,19 @@ package security
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strings"
 	"time"
 
 	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/errors"
 	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/i18n"
 	"github.com/nanayaw/fullstack/pkg/logger"
 )
 
@@ -27,14 +32,32 @@ type Repository interface {
 	// UnlockAccount unlocks a user account
 	UnlockAccount(ctx context.Context, userID string) error
 
+	// SetUnlockToken stores the hash of a token that can be used to unlock an account early
+	SetUnlockToken(ctx context.Context, userID, tokenHash string) error
+
+	// UnlockAccountByToken unlocks the account matching an unlock token hash and returns its user ID
+	UnlockAccountByToken(ctx context.Context, tokenHash string) (string, error)
+
 	// IsAccountLocked checks if a user account is locked
 	IsAccountLocked(ctx context.Context, userID string) (bool, time.Time, string, error)
 
+	// DeleteExpiredAccountLocks deletes the account locks that ended before a time
+	DeleteExpiredAccountLocks(ctx context.Context, before time.Time) (int64, error)
+
 	// RecordSecurityEvent records a security event
 	RecordSecurityEvent(ctx context.Context, event *model.SecurityEvent) error
 
 	// GetUserSecurityEvents gets security events for a user
 	GetUserSecurityEvents(ctx context.Context, userID string, limit int) ([]*model.SecurityEvent, error)
+
+	// ListUserSecurityEvents gets a filtered page of security events for a user
+	ListUserSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) ([]*model.SecurityEvent, error)
+
+	// CountUserSecurityEventsByType counts a user's security events of each type in a period
+	CountUserSecurityEventsByType(ctx context.Context, userID string, from, to time.Time) (map[string]int, error)
+
+	// GetSecurityEvent gets a single security event belonging to a user
+	GetSecurityEvent(ctx context.Context, userID, eventID string) (*model.SecurityEvent, error)
 }
 
 // Service provides security-related functionality
@@ -44,6 +67,20 @@ type Service struct {
 	config      *config.Config
 	logger      logger.Logger
 	geoIPLookup GeoIPLookup
+	webhooks    Webhooks
+	users       Users
+}
+
+// Users looks up the accounts security emails are sent to
+type Users interface {
+	// GetUser gets a user by ID, or nil if there is none
+	GetUser(ctx context.Context, id string) (*model.AdminUser, error)
+}
+
+// Webhooks notifies the webhook endpoints of other services of identity
+// lifecycle events
+type Webhooks interface {
+	Dispatch(ctx context.Context, eventType string, data map[string]interface{})
 }
 
 // GeoIPLookup defines the interface for IP geolocation
@@ -80,6 +117,37 @@ func (s *Service) SetGeoIPLookup(lookup GeoIPLookup) {
 	s.geoIPLookup = lookup
 }
 
+// SetWebhooks sets the webhooks notified when accounts are locked and
+// unlocked. No webhooks are sent without them.
+func (s *Service) SetWebhooks(webhooks Webhooks) {
+	s.webhooks = webhooks
+}
+
+// SetUsers sets the users looked up to send security emails in each user's
+// locale. Emails are sent in the locale carried by the context without them.
+func (s *Service) SetUsers(users Users) {
+	s.users = users
+}
+
+// withUserLocale renders emails to a user in their preferred locale, keeping
+// the locale already carried by ctx if they don't have one or can't be found
+func (s *Service) withUserLocale(ctx context.Context, userID string) context.Context {
+	if s.users == nil || userID == "" {
+		return ctx
+	}
+
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to get user locale", "user_id", userID, "error", err)
+		return ctx
+	}
+	if user == nil || user.Locale == "" {
+		return ctx
+	}
+
+	return i18n.WithLocale(ctx, user.Locale)
+}
+
 // RecordLoginAttempt records a login attempt and handles security measures
 func (s *Service) RecordLoginAttempt(ctx context.Context, userID, email, ipAddress, userAgent string, successful bool) error {
 	// Create login attempt record
@@ -216,7 +284,7 @@ func (s *Service) handleFailedLogin(ctx context.Context, userID, email string, a
 		unlockTime := time.Now().Add(lockDuration)
 		reason := fmt.Sprintf("Too many failed login attempts (%d)", failedCount)
 
-		if err := s.repo.LockAccount(ctx, userID, unlockTime, reason); err != nil {
+		if err := s.LockAccount(ctx, userID, unlockTime, reason); err != nil {
 			return fmt.Errorf("failed to lock account: %w", err)
 		}
 
@@ -236,7 +304,7 @@ func (s *Service) handleFailedLogin(ctx context.Context, userID, email string, a
 		}
 
 		// Send account locked email
-		if err := s.sendAccountLockedEmail(ctx, email, unlockTime, failedCount); err != nil {
+		if err := s.sendAccountLockedEmail(ctx, userID, email, unlockTime, failedCount); err != nil {
 			s.logger.Error("Failed to send account locked email", "error", err)
 		}
 	}
@@ -249,9 +317,115 @@ func (s *Service) IsAccountLocked(ctx context.Context, userID string) (bool, tim
 	return s.repo.IsAccountLocked(ctx, userID)
 }
 
+// LockAccount locks a user account until a time, replacing any existing lock
+func (s *Service) LockAccount(ctx context.Context, userID string, until time.Time, reason string) error {
+	if err := s.repo.LockAccount(ctx, userID, until, reason); err != nil {
+		return err
+	}
+
+	s.dispatch(ctx, model.WebhookEventUserLocked, map[string]interface{}{
+		"user_id":      userID,
+		"locked_until": until.UTC(),
+		"reason":       reason,
+	})
+	return nil
+}
+
 // UnlockAccount unlocks a user account
 func (s *Service) UnlockAccount(ctx context.Context, userID string) error {
-	return s.repo.UnlockAccount(ctx, userID)
+	if err := s.repo.UnlockAccount(ctx, userID); err != nil {
+		return err
+	}
+
+	s.dispatch(ctx, model.WebhookEventUserUnlocked, map[string]interface{}{"user_id": userID})
+	return nil
+}
+
+// dispatch notifies webhooks of an event, if there are any
+func (s *Service) dispatch(ctx context.Context, eventType string, data map[string]interface{}) {
+	if s.webhooks != nil {
+		s.webhooks.Dispatch(ctx, eventType, data)
+	}
+}
+
+// RecordAdminAction records an action an admin took on a user's account in
+// the user's security events
+func (s *Service) RecordAdminAction(ctx context.Context, userID string, actor model.AdminActor, description string) error {
+	return s.RecordAdminEvent(ctx, userID, model.EventAdminAction, actor, description)
+}
+
+// RecordAdminEvent records a security event of a user caused by an admin
+func (s *Service) RecordAdminEvent(ctx context.Context, userID, eventType string, actor model.AdminActor, description string) error {
+	location, err := s.getLocationString(actor.IPAddress)
+	if err != nil {
+		s.logger.Warn("Failed to get location for IP", "ip", actor.IPAddress, "error", err)
+		location = "Unknown location"
+	}
+
+	event := &model.SecurityEvent{
+		UserID:      userID,
+		EventType:   eventType,
+		IPAddress:   actor.IPAddress,
+		UserAgent:   actor.UserAgent,
+		Location:    location,
+		Description: fmt.Sprintf("%s (by %s)", description, actor.ID),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.repo.RecordSecurityEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to record security event: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredAccountLocks deletes the account locks that have ended. Ended
+// locks no longer lock anything, so this only keeps the table small.
+func (s *Service) DeleteExpiredAccountLocks(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredAccountLocks(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.logger.Info("Deleted expired account locks", "count", deleted)
+	}
+	return nil
+}
+
+// UnlockAccountWithToken unlocks an account using the token from an account locked email
+func (s *Service) UnlockAccountWithToken(ctx context.Context, token, ipAddress, userAgent string) error {
+	userID, err := s.repo.UnlockAccountByToken(ctx, hashUnlockToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if userID == "" {
+		return errors.NewValidationError("invalid or expired unlock token")
+	}
+	s.dispatch(ctx, model.WebhookEventUserUnlocked, map[string]interface{}{"user_id": userID})
+
+	location, err := s.getLocationString(ipAddress)
+	if err != nil {
+		s.logger.Warn("Failed to get location for IP", "ip", ipAddress, "error", err)
+		location = "Unknown location"
+	}
+
+	// Record security event
+	event := &model.SecurityEvent{
+		UserID:      userID,
+		EventType:   model.EventAccountUnlocked,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Location:    location,
+		Description: "Account unlocked from email link",
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.repo.RecordSecurityEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to record security event", "error", err)
+	}
+
+	return nil
 }
 
 // DetectSuspiciousActivity detects suspicious activity for a user
@@ -313,8 +487,8 @@ func (s *Service) DetectSuspiciousActivity(ctx context.Context, userID, email, i
 			s.logger.Error("Failed to record suspicious activity event", "error", err)
 		}
 
-		// Send suspicious activity email
-		if err := s.sendSuspiciousActivityEmail(ctx, email, event); err != nil {
+		// Send suspicious activity email about the activity itself
+		if err := s.sendSuspiciousActivityEmail(ctx, email, activityType, event); err != nil {
 			s.logger.Error("Failed to send suspicious activity email", "error", err)
 		}
 	}
@@ -347,7 +521,7 @@ func (s *Service) NotifyPasswordChanged(ctx context.Context, userID, email, ipAd
 	}
 
 	// Send password changed email
-	return s.emailSvc.SendPasswordChangedEmail(ctx, email)
+	return s.emailSvc.SendPasswordChangedEmail(s.withUserLocale(ctx, userID), email)
 }
 
 // sendLoginNotification sends a login notification email
@@ -356,29 +530,47 @@ func (s *Service) sendLoginNotification(ctx context.Context, email string, attem
 	location := attempt.Location
 
 	// Use the EmailService interface method
-	return s.emailSvc.SendLoginNotificationEmail(ctx, email, deviceInfo, location)
+	return s.emailSvc.SendLoginNotificationEmail(s.withUserLocale(ctx, attempt.UserID), email, deviceInfo, location)
 }
 
-// sendAccountLockedEmail sends an account locked email
-func (s *Service) sendAccountLockedEmail(ctx context.Context, email string, unlockTime time.Time, failedAttempts int) error {
-	// Currently there's no specific method for account locked emails in the interface
-	// We could add one or use a more generic method
-	// For now, we'll just log it
-	s.logger.Info("Account locked", "email", email, "unlockTime", unlockTime, "failedAttempts", failedAttempts)
-	return nil
+// sendAccountLockedEmail sends an account locked email with a link to unlock the account early
+func (s *Service) sendAccountLockedEmail(ctx context.Context, userID, email string, unlockTime time.Time, failedAttempts int) error {
+	token, err := generateUnlockToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate unlock token: %w", err)
+	}
+
+	// Only the hash is stored, so a leaked database can't be used to unlock accounts
+	if err := s.repo.SetUnlockToken(ctx, userID, hashUnlockToken(token)); err != nil {
+		s.logger.Error("Failed to store unlock token", "error", err)
+		// Still tell the user about the lock, just without the unlock link
+		token = ""
+	}
+
+	return s.emailSvc.SendAccountLockedEmail(s.withUserLocale(ctx, userID), email, unlockTime, failedAttempts, token)
 }
 
-// sendSuspiciousActivityEmail sends a suspicious activity email
-func (s *Service) sendSuspiciousActivityEmail(ctx context.Context, email string, event *model.SecurityEvent) error {
-	// Currently there's no specific method for suspicious activity emails in the interface
-	// We could add one or use a more generic method
-	// For now, we'll just log it
-	s.logger.Info("Suspicious activity detected",
-		"email", email,
-		"eventType", event.EventType,
-		"location", event.Location,
-		"ipAddress", event.IPAddress)
-	return nil
+// sendSuspiciousActivityEmail sends a suspicious activity email about an
+// event of type activityType, which the email service translates
+func (s *Service) sendSuspiciousActivityEmail(ctx context.Context, email, activityType string, event *model.SecurityEvent) error {
+	deviceInfo := s.getDeviceInfo(event.UserAgent)
+
+	return s.emailSvc.SendSuspiciousActivityEmail(s.withUserLocale(ctx, event.UserID), email, activityType, deviceInfo, event.Location, event.IPAddress)
+}
+
+// generateUnlockToken generates a random token for unlocking an account
+func generateUnlockToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashUnlockToken hashes an unlock token for storage
+func hashUnlockToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // getDeviceInfo extracts device information from user agent
@@ -0,0 +1,297 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/i18n"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRepository is a mock implementation of Repository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) RecordLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error {
+	args := m.Called(ctx, attempt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetRecentLoginAttempts(ctx context.Context, userID string, limit int) ([]*model.LoginAttempt, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.LoginAttempt), args.Error(1)
+}
+
+func (m *MockRepository) LockAccount(ctx context.Context, userID string, until time.Time, reason string) error {
+	args := m.Called(ctx, userID, until, reason)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UnlockAccount(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetUnlockToken(ctx context.Context, userID, tokenHash string) error {
+	args := m.Called(ctx, userID, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UnlockAccountByToken(ctx context.Context, tokenHash string) (string, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) IsAccountLocked(ctx context.Context, userID string) (bool, time.Time, string, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Get(1).(time.Time), args.String(2), args.Error(3)
+}
+
+func (m *MockRepository) DeleteExpiredAccountLocks(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) RecordSecurityEvent(ctx context.Context, event *model.SecurityEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetUserSecurityEvents(ctx context.Context, userID string, limit int) ([]*model.SecurityEvent, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.SecurityEvent), args.Error(1)
+}
+
+func (m *MockRepository) ListUserSecurityEvents(ctx context.Context, userID string, filter model.SecurityEventFilter) ([]*model.SecurityEvent, error) {
+	args := m.Called(ctx, userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.SecurityEvent), args.Error(1)
+}
+
+func (m *MockRepository) CountUserSecurityEventsByType(ctx context.Context, userID string, from, to time.Time) (map[string]int, error) {
+	args := m.Called(ctx, userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockRepository) GetSecurityEvent(ctx context.Context, userID, eventID string) (*model.SecurityEvent, error) {
+	args := m.Called(ctx, userID, eventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SecurityEvent), args.Error(1)
+}
+
+// MockEmailService is a mock implementation of service.EmailService
+type MockEmailService struct {
+	mock.Mock
+}
+
+func (m *MockEmailService) SendVerificationEmail(ctx context.Context, to string, token string) error {
+	args := m.Called(ctx, to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendPasswordResetEmail(ctx context.Context, to string, token string) error {
+	args := m.Called(ctx, to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendWelcomeEmail(ctx context.Context, to string, userName string) error {
+	args := m.Called(ctx, to, userName)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendLoginNotificationEmail(ctx context.Context, to string, deviceInfo string, location string) error {
+	args := m.Called(ctx, to, deviceInfo, location)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendPasswordChangedEmail(ctx context.Context, to string) error {
+	args := m.Called(ctx, to)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendAccountLockedEmail(ctx context.Context, to string, unlockTime time.Time, failedAttempts int, unlockToken string) error {
+	args := m.Called(ctx, to, unlockTime, failedAttempts, unlockToken)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendSuspiciousActivityEmail(ctx context.Context, to string, activityType string, deviceInfo string, location string, ipAddress string) error {
+	args := m.Called(ctx, to, activityType, deviceInfo, location, ipAddress)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendOnboardingReminderEmail(ctx context.Context, to string, userName string, token string) error {
+	args := m.Called(ctx, to, userName, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendSecurityDigestEmail(ctx context.Context, to string, digest *model.SecurityDigest) error {
+	args := m.Called(ctx, to, digest)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendOrganizationInvitationEmail(ctx context.Context, to string, organizationName string, inviterName string, token string) error {
+	args := m.Called(ctx, to, organizationName, inviterName, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendDataExportReadyEmail(ctx context.Context, to string, token string) error {
+	args := m.Called(ctx, to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) ScheduleEmail(ctx context.Context, userID string, kind string, to string, sendAt time.Time) error {
+	args := m.Called(ctx, userID, kind, to, sendAt)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) CancelScheduledEmails(ctx context.Context, userID string, kinds ...string) error {
+	args := m.Called(ctx, userID, kinds)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) ParseTemplate(templateName string, data interface{}) (string, error) {
+	args := m.Called(templateName, data)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockEmailService) ValidateEmailAddress(email string) bool {
+	args := m.Called(email)
+	return args.Bool(0)
+}
+
+// MockUsers is a mock implementation of Users
+type MockUsers struct {
+	mock.Mock
+}
+
+func (m *MockUsers) GetUser(ctx context.Context, id string) (*model.AdminUser, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.AdminUser), args.Error(1)
+}
+
+// newTestService creates a security service with mock dependencies
+func newTestService() (*Service, *MockRepository, *MockEmailService, *MockUsers) {
+	repo := new(MockRepository)
+	emailSvc := new(MockEmailService)
+	users := new(MockUsers)
+
+	cfg := &config.Config{Security: config.SecurityConfig{MaxLoginAttempts: 3, AccountLockDuration: 30 * time.Minute}}
+	s := NewService(repo, emailSvc, cfg, logger.DefaultLogger())
+	s.SetUsers(users)
+
+	return s, repo, emailSvc, users
+}
+
+// inLocale matches a context carrying locale
+func inLocale(locale string) interface{} {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		return i18n.FromContext(ctx) == locale
+	})
+}
+
+// TestSecurityEmailsUseUserLocale tests that security emails are sent in the
+// locale of the user they are about
+func TestSecurityEmailsUseUserLocale(t *testing.T) {
+	const userID = "user-1"
+	const email = "user@example.com"
+	french := &model.AdminUser{ID: userID, Email: email, Locale: "fr"}
+
+	t.Run("login notification", func(t *testing.T) {
+		s, repo, emailSvc, users := newTestService()
+		users.On("GetUser", mock.Anything, userID).Return(french, nil)
+		repo.On("RecordLoginAttempt", mock.Anything, mock.Anything).Return(nil)
+		repo.On("GetRecentLoginAttempts", mock.Anything, userID, 5).Return([]*model.LoginAttempt{}, nil)
+		repo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil)
+		emailSvc.On("SendLoginNotificationEmail", inLocale("fr"), email, mock.Anything, mock.Anything).Return(nil)
+
+		err := s.RecordLoginAttempt(context.Background(), userID, email, "127.0.0.1", "Mozilla/5.0", true)
+
+		assert.NoError(t, err)
+		emailSvc.AssertExpectations(t)
+	})
+
+	t.Run("account locked", func(t *testing.T) {
+		s, repo, emailSvc, users := newTestService()
+		failed := []*model.LoginAttempt{
+			{ID: "1", UserID: userID, AttemptedAt: time.Now()},
+			{ID: "2", UserID: userID, AttemptedAt: time.Now()},
+			{ID: "3", UserID: userID, AttemptedAt: time.Now()},
+		}
+		users.On("GetUser", mock.Anything, userID).Return(french, nil)
+		repo.On("RecordLoginAttempt", mock.Anything, mock.Anything).Return(nil)
+		repo.On("GetRecentLoginAttempts", mock.Anything, userID, 10).Return(failed, nil)
+		repo.On("LockAccount", mock.Anything, userID, mock.Anything, mock.Anything).Return(nil)
+		repo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil)
+		repo.On("SetUnlockToken", mock.Anything, userID, mock.Anything).Return(nil)
+		emailSvc.On("SendAccountLockedEmail", inLocale("fr"), email, mock.Anything, 3, mock.Anything).Return(nil)
+
+		err := s.RecordLoginAttempt(context.Background(), userID, email, "127.0.0.1", "Mozilla/5.0", false)
+
+		assert.NoError(t, err)
+		emailSvc.AssertExpectations(t)
+	})
+
+	t.Run("suspicious activity", func(t *testing.T) {
+		s, repo, emailSvc, users := newTestService()
+		recent := []*model.SecurityEvent{
+			{UserID: userID, Location: "Accra, Ghana", CreatedAt: time.Now()},
+			{UserID: userID, Location: "Paris, France", CreatedAt: time.Now()},
+			{UserID: userID, Location: "Lagos, Nigeria", CreatedAt: time.Now()},
+		}
+		users.On("GetUser", mock.Anything, userID).Return(french, nil)
+		repo.On("GetUserSecurityEvents", mock.Anything, userID, 10).Return(recent, nil)
+		repo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil)
+		emailSvc.On("SendSuspiciousActivityEmail", inLocale("fr"), email, model.EventPasswordReset, mock.Anything, mock.Anything, "127.0.0.1").Return(nil)
+
+		err := s.DetectSuspiciousActivity(context.Background(), userID, email, "127.0.0.1", "Mozilla/5.0", "password_reset")
+
+		assert.NoError(t, err)
+		emailSvc.AssertExpectations(t)
+	})
+
+	t.Run("password changed", func(t *testing.T) {
+		s, repo, emailSvc, users := newTestService()
+		users.On("GetUser", mock.Anything, userID).Return(french, nil)
+		repo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil)
+		emailSvc.On("SendPasswordChangedEmail", inLocale("fr"), email).Return(nil)
+
+		err := s.NotifyPasswordChanged(context.Background(), userID, email, "127.0.0.1", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		emailSvc.AssertExpectations(t)
+	})
+
+	t.Run("keeps the context locale for users without one", func(t *testing.T) {
+		s, repo, emailSvc, users := newTestService()
+		users.On("GetUser", mock.Anything, userID).Return(&model.AdminUser{ID: userID, Email: email}, nil)
+		repo.On("RecordSecurityEvent", mock.Anything, mock.Anything).Return(nil)
+		emailSvc.On("SendPasswordChangedEmail", inLocale("fr"), email).Return(nil)
+
+		err := s.NotifyPasswordChanged(i18n.WithLocale(context.Background(), "fr"), userID, email, "127.0.0.1", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		emailSvc.AssertExpectations(t)
+	})
+}
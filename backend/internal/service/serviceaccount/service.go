@@ -0,0 +1,452 @@
+package serviceaccount
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// maxNameLength is the longest service account name, in characters
+	maxNameLength = 100
+	// clientIDLength is the number of random bytes of a client ID
+	clientIDLength = 8
+	// secretLength is the number of random bytes of a client secret
+	secretLength = 32
+	// adminKeyActor is the actor ID of requests made with the admin API key
+	adminKeyActor = "admin_key"
+)
+
+// Repository defines the interface for service account database operations
+type Repository interface {
+	// CreateServiceAccount stores a service account, filling in its ID and creation time
+	CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error
+
+	// GetServiceAccount gets a service account by ID, or nil if there is none
+	GetServiceAccount(ctx context.Context, id string) (*model.ServiceAccount, error)
+
+	// GetServiceAccountByClientID gets a service account by client ID, or nil if there is none
+	GetServiceAccountByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error)
+
+	// ListServiceAccounts gets the service accounts of an organization, or
+	// those owned by the admins when orgID is empty
+	ListServiceAccounts(ctx context.Context, orgID string) ([]*model.ServiceAccount, error)
+
+	// SetServiceAccountSecret replaces the secret of a service account and reports whether there is one
+	SetServiceAccountSecret(ctx context.Context, id, secretHash string) (bool, error)
+
+	// TouchServiceAccount records that a service account was just issued a token
+	TouchServiceAccount(ctx context.Context, id string) error
+
+	// DeleteServiceAccount deletes a service account and reports whether there was one
+	DeleteServiceAccount(ctx context.Context, id string) (bool, error)
+}
+
+// Tokens issues and revokes the access tokens of service accounts
+type Tokens interface {
+	// IssueServiceToken issues an access token for a service account
+	IssueServiceToken(ctx context.Context, account *model.ServiceAccount) (*model.ServiceToken, error)
+
+	// InvalidateAllSessions revokes every token issued to a subject until now
+	InvalidateAllSessions(ctx context.Context, subject string) error
+}
+
+// Permissions checks the permissions admins can give service accounts
+type Permissions interface {
+	Can(ctx context.Context, userID, permission string) (bool, error)
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+}
+
+// AuditLog records what is done to and by service accounts
+type AuditLog interface {
+	CreateAuditLog(ctx context.Context, log *models.AuditLog) error
+}
+
+// Service manages service accounts, the non-human identities of backend
+// jobs. They exchange their client ID and secret for short-lived access
+// tokens with the OAuth 2.0 client credentials grant (RFC 6749 section 4.4).
+// Accounts owned by an organization act in it with a role; accounts owned by
+// the admins act on the admin API with the permissions they were given. Only
+// a hash of the secret is stored.
+type Service struct {
+	repo        Repository
+	tokens      Tokens
+	permissions Permissions
+	audit       AuditLog
+	logger      logger.Logger
+}
+
+// NewService creates a new service account service
+func NewService(repo Repository, tokens Tokens, permissions Permissions, audit AuditLog, log logger.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		tokens:      tokens,
+		permissions: permissions,
+		audit:       audit,
+		logger:      log,
+	}
+}
+
+// CreateAdminServiceAccount creates a service account owned by the admins
+// with permissions as its scopes, and returns its client secret, which can't
+// be read again. Users can only give permissions they have, and only people
+// can create service accounts.
+func (s *Service) CreateAdminServiceAccount(ctx context.Context, name string, scopes []string, actor model.AdminActor) (string, *model.ServiceAccount, error) {
+	if actor.UserID == "" && actor.ID != adminKeyActor {
+		return "", nil, errors.NewAuthorizationError("service accounts can't create service accounts")
+	}
+
+	scopes, err := s.validateScopes(ctx, scopes, actor.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	account := &model.ServiceAccount{Scopes: scopes}
+	if actor.UserID != "" {
+		account.CreatedBy = &actor.UserID
+	}
+
+	return s.create(ctx, account, name, actor.UserID, actor.ID)
+}
+
+// ListAdminServiceAccounts gets the service accounts owned by the admins
+func (s *Service) ListAdminServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	return s.repo.ListServiceAccounts(ctx, "")
+}
+
+// RotateAdminServiceAccountSecret replaces the client secret of a service
+// account owned by the admins, revoking its access tokens, and returns the
+// new secret
+func (s *Service) RotateAdminServiceAccountSecret(ctx context.Context, id string, actor model.AdminActor) (string, error) {
+	account, err := s.requireServiceAccount(ctx, "", id)
+	if err != nil {
+		return "", err
+	}
+	return s.rotateSecret(ctx, account, actor.UserID, actor.ID)
+}
+
+// DeleteAdminServiceAccount deletes a service account owned by the admins,
+// revoking its access tokens
+func (s *Service) DeleteAdminServiceAccount(ctx context.Context, id string, actor model.AdminActor) error {
+	account, err := s.requireServiceAccount(ctx, "", id)
+	if err != nil {
+		return err
+	}
+	return s.delete(ctx, account, actor.UserID, actor.ID)
+}
+
+// CreateOrganizationServiceAccount creates a service account owned by an
+// organization, acting in it with a role, and returns its client secret,
+// which can't be read again. It needs the admin role.
+func (s *Service) CreateOrganizationServiceAccount(ctx context.Context, actor *model.Membership, name, role string) (string, *model.ServiceAccount, error) {
+	if err := requireAdmin(actor); err != nil {
+		return "", nil, err
+	}
+	if actor.ServiceAccount {
+		return "", nil, errors.NewAuthorizationError("service accounts can't create service accounts")
+	}
+	if role != model.OrgRoleAdmin && role != model.OrgRoleMember {
+		return "", nil, errors.NewValidationError("role must be admin or member")
+	}
+
+	account := &model.ServiceAccount{
+		OrganizationID: &actor.OrganizationID,
+		Role:           role,
+		CreatedBy:      &actor.UserID,
+	}
+
+	return s.create(ctx, account, name, actor.UserID, "user:"+actor.UserID)
+}
+
+// ListOrganizationServiceAccounts gets the service accounts of an
+// organization. It needs the admin role.
+func (s *Service) ListOrganizationServiceAccounts(ctx context.Context, actor *model.Membership) ([]*model.ServiceAccount, error) {
+	if err := requireAdmin(actor); err != nil {
+		return nil, err
+	}
+	return s.repo.ListServiceAccounts(ctx, actor.OrganizationID)
+}
+
+// RotateOrganizationServiceAccountSecret replaces the client secret of a
+// service account of an organization, revoking its access tokens, and
+// returns the new secret. It needs the admin role.
+func (s *Service) RotateOrganizationServiceAccountSecret(ctx context.Context, actor *model.Membership, id string) (string, error) {
+	if err := requireAdmin(actor); err != nil {
+		return "", err
+	}
+	account, err := s.requireServiceAccount(ctx, actor.OrganizationID, id)
+	if err != nil {
+		return "", err
+	}
+
+	userID, actorID := membershipActor(actor)
+	return s.rotateSecret(ctx, account, userID, actorID)
+}
+
+// DeleteOrganizationServiceAccount deletes a service account of an
+// organization, revoking its access tokens. It needs the admin role.
+func (s *Service) DeleteOrganizationServiceAccount(ctx context.Context, actor *model.Membership, id string) error {
+	if err := requireAdmin(actor); err != nil {
+		return err
+	}
+	account, err := s.requireServiceAccount(ctx, actor.OrganizationID, id)
+	if err != nil {
+		return err
+	}
+
+	userID, actorID := membershipActor(actor)
+	return s.delete(ctx, account, userID, actorID)
+}
+
+// IssueToken exchanges the client credentials of a service account for an
+// access token. Every token issued is recorded in the audit log, and none is
+// issued if that fails.
+func (s *Service) IssueToken(ctx context.Context, clientID, clientSecret, ipAddress string) (*model.ServiceToken, error) {
+	if !strings.HasPrefix(clientID, model.ServiceAccountClientIDPrefix) || clientSecret == "" {
+		return nil, errors.NewAuthenticationError("invalid client credentials")
+	}
+
+	account, err := s.repo.GetServiceAccountByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || subtle.ConstantTimeCompare([]byte(hashSecret(clientSecret)), []byte(account.SecretHash)) != 1 {
+		return nil, errors.NewAuthenticationError("invalid client credentials")
+	}
+
+	token, err := s.tokens.IssueServiceToken(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.record(ctx, "service_account.token_issued", account, "", map[string]interface{}{
+		"client_id":  account.ClientID,
+		"scopes":     token.Scopes,
+		"expires_at": token.ExpiresAt,
+		"ip_address": ipAddress,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.TouchServiceAccount(ctx, account.ID); err != nil {
+		s.logger.Warn("Failed to record service account use", "service_account_id", account.ID, "error", err)
+	}
+
+	return token, nil
+}
+
+// create names a new service account, generates its credentials and stores it
+func (s *Service) create(ctx context.Context, account *model.ServiceAccount, name, userID, actorID string) (string, *model.ServiceAccount, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.NewValidationError("name is required")
+	}
+	if len([]rune(name)) > maxNameLength {
+		return "", nil, errors.NewValidationError(fmt.Sprintf("name must be at most %d characters", maxNameLength))
+	}
+	account.Name = name
+
+	clientID, err := randomHex(clientIDLength)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate client ID: %w", err)
+	}
+	secret, err := randomHex(secretLength)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate client secret: %w", err)
+	}
+	account.ClientID = model.ServiceAccountClientIDPrefix + clientID
+	account.SecretHash = hashSecret(secret)
+
+	if err := s.repo.CreateServiceAccount(ctx, account); err != nil {
+		return "", nil, err
+	}
+
+	s.recordChange(ctx, "service_account.created", account, userID, actorID)
+	return secret, account, nil
+}
+
+// rotateSecret replaces the client secret of a service account and revokes
+// the access tokens issued with the old one
+func (s *Service) rotateSecret(ctx context.Context, account *model.ServiceAccount, userID, actorID string) (string, error) {
+	secret, err := randomHex(secretLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+
+	ok, err := s.repo.SetServiceAccountSecret(ctx, account.ID, hashSecret(secret))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.NewNotFoundError("service account not found")
+	}
+	if err := s.tokens.InvalidateAllSessions(ctx, account.ID); err != nil {
+		return "", fmt.Errorf("failed to revoke service account tokens: %w", err)
+	}
+
+	s.recordChange(ctx, "service_account.secret_rotated", account, userID, actorID)
+	return secret, nil
+}
+
+// delete deletes a service account and revokes its access tokens
+func (s *Service) delete(ctx context.Context, account *model.ServiceAccount, userID, actorID string) error {
+	ok, err := s.repo.DeleteServiceAccount(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.NewNotFoundError("service account not found")
+	}
+	if err := s.tokens.InvalidateAllSessions(ctx, account.ID); err != nil {
+		return fmt.Errorf("failed to revoke service account tokens: %w", err)
+	}
+
+	s.recordChange(ctx, "service_account.deleted", account, userID, actorID)
+	return nil
+}
+
+// requireServiceAccount gets a service account of an organization, or one
+// owned by the admins when orgID is empty, failing if there is none
+func (s *Service) requireServiceAccount(ctx context.Context, orgID, id string) (*model.ServiceAccount, error) {
+	account, err := s.repo.GetServiceAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || ownerID(account) != orgID {
+		return nil, errors.NewNotFoundError("service account not found")
+	}
+	return account, nil
+}
+
+// validateScopes checks that scopes are known permissions the user has, and
+// removes duplicates. Any permission can be given with the admin API key,
+// when userID is empty.
+func (s *Service) validateScopes(ctx context.Context, scopes []string, userID string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.NewValidationError("at least one scope is required")
+	}
+
+	permissions, err := s.permissions.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	known := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		known[p.Name] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		if !known[scope] {
+			return nil, errors.NewValidationError("invalid scope: " + scope)
+		}
+		if userID != "" {
+			ok, err := s.permissions.Can(ctx, userID, scope)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check permission: %w", err)
+			}
+			if !ok {
+				return nil, errors.NewAuthorizationError("can't give a permission you don't have: " + scope)
+			}
+		}
+		valid = append(valid, scope)
+	}
+
+	return valid, nil
+}
+
+// recordChange records a change to a service account in the audit log. The
+// change is already made, so failures are only logged.
+func (s *Service) recordChange(ctx context.Context, action string, account *model.ServiceAccount, userID, actorID string) {
+	if err := s.record(ctx, action, account, userID, map[string]interface{}{
+		"name":            account.Name,
+		"client_id":       account.ClientID,
+		"organization_id": ownerID(account),
+		"role":            account.Role,
+		"scopes":          account.Scopes,
+		"actor":           actorID,
+	}); err != nil {
+		s.logger.Error("Failed to audit service account change", "service_account_id", account.ID, "action", action, "error", err)
+	}
+
+	s.logger.Info("Service account changed", "service_account_id", account.ID, "action", action, "actor", actorID)
+}
+
+// record writes an audit log entry about a service account, made by a user
+// when userID isn't empty
+func (s *Service) record(ctx context.Context, action string, account *model.ServiceAccount, userID string, metadata map[string]interface{}) error {
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit metadata: %w", err)
+	}
+
+	if err := s.audit.CreateAuditLog(ctx, &models.AuditLog{
+		UserID:           userID,
+		ServiceAccountID: account.ID,
+		Action:           action,
+		EntityType:       "service_account",
+		EntityID:         account.ID,
+		Metadata:         string(encoded),
+	}); err != nil {
+		return fmt.Errorf("failed to audit service account: %w", err)
+	}
+
+	return nil
+}
+
+// requireAdmin fails unless a membership has at least the admin role
+func requireAdmin(membership *model.Membership) error {
+	if membership == nil || !model.OrgRoleAtLeast(membership.Role, model.OrgRoleAdmin) {
+		return errors.NewAuthorizationError("requires the admin role")
+	}
+	return nil
+}
+
+// membershipActor returns the user acting with a membership, empty for
+// service accounts, and who acts for the audit log
+func membershipActor(membership *model.Membership) (string, string) {
+	if membership.ServiceAccount {
+		return "", "service_account:" + membership.UserID
+	}
+	return membership.UserID, "user:" + membership.UserID
+}
+
+// ownerID returns the organization owning a service account, empty for
+// accounts owned by the admins
+func ownerID(account *model.ServiceAccount) string {
+	if account.OrganizationID == nil {
+		return ""
+	}
+	return *account.OrganizationID
+}
+
+// randomHex generates n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret hashes a client secret for storage
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
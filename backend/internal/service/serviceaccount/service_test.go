@@ -0,0 +1,255 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository
+type fakeRepository struct {
+	accounts map[string]*model.ServiceAccount
+	touched  []string
+}
+
+func (f *fakeRepository) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error {
+	account.ID = "sa-" + account.ClientID
+	account.CreatedAt = time.Now()
+	f.accounts[account.ID] = account
+	return nil
+}
+
+func (f *fakeRepository) GetServiceAccount(ctx context.Context, id string) (*model.ServiceAccount, error) {
+	return f.accounts[id], nil
+}
+
+func (f *fakeRepository) GetServiceAccountByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error) {
+	for _, account := range f.accounts {
+		if account.ClientID == clientID {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) ListServiceAccounts(ctx context.Context, orgID string) ([]*model.ServiceAccount, error) {
+	accounts := []*model.ServiceAccount{}
+	for _, account := range f.accounts {
+		if ownerID(account) == orgID {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (f *fakeRepository) SetServiceAccountSecret(ctx context.Context, id, secretHash string) (bool, error) {
+	account := f.accounts[id]
+	if account == nil {
+		return false, nil
+	}
+	account.SecretHash = secretHash
+	return true, nil
+}
+
+func (f *fakeRepository) TouchServiceAccount(ctx context.Context, id string) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+func (f *fakeRepository) DeleteServiceAccount(ctx context.Context, id string) (bool, error) {
+	if f.accounts[id] == nil {
+		return false, nil
+	}
+	delete(f.accounts, id)
+	return true, nil
+}
+
+// fakeTokens issues tokens named after their service account and remembers
+// whose tokens were revoked
+type fakeTokens struct {
+	revoked []string
+}
+
+func (f *fakeTokens) IssueServiceToken(ctx context.Context, account *model.ServiceAccount) (*model.ServiceToken, error) {
+	return &model.ServiceToken{
+		AccessToken: "token:" + account.ID,
+		ExpiresAt:   time.Now().Add(time.Hour),
+		Scopes:      account.Scopes,
+	}, nil
+}
+
+func (f *fakeTokens) InvalidateAllSessions(ctx context.Context, subject string) error {
+	f.revoked = append(f.revoked, subject)
+	return nil
+}
+
+// fakePermissions grants the permissions listed for each user
+type fakePermissions map[string][]string
+
+func (f fakePermissions) Can(ctx context.Context, userID, permission string) (bool, error) {
+	for _, p := range f[userID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakePermissions) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	return []*model.Permission{
+		{Name: model.PermissionUsersRead},
+		{Name: model.PermissionRolesManage},
+	}, nil
+}
+
+var testAdmin = model.AdminActor{ID: "user:admin-1", UserID: "admin-1"}
+
+func newTestService() (*Service, *fakeRepository, *fakeTokens, *servicetest.AuditLog) {
+	repo := &fakeRepository{accounts: map[string]*model.ServiceAccount{}}
+	tokens := &fakeTokens{}
+	audit := &servicetest.AuditLog{}
+	permissions := fakePermissions{"admin-1": {model.PermissionUsersRead}}
+	return NewService(repo, tokens, permissions, audit, logger.DefaultLogger()), repo, tokens, audit
+}
+
+func TestCreateAdminServiceAccountAndIssueToken(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _, audit := newTestService()
+
+	secret, account, err := svc.CreateAdminServiceAccount(ctx, " Billing sync ", []string{model.PermissionUsersRead, model.PermissionUsersRead}, testAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, "Billing sync", account.Name)
+	assert.Equal(t, []string{model.PermissionUsersRead}, account.Scopes)
+	assert.Nil(t, account.OrganizationID)
+	assert.Equal(t, "admin-1", *account.CreatedBy)
+	assert.Regexp(t, `^sa_[0-9a-f]{16}$`, account.ClientID)
+	assert.Equal(t, hashSecret(secret), account.SecretHash)
+
+	token, err := svc.IssueToken(ctx, account.ClientID, secret, "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, "token:"+account.ID, token.AccessToken)
+	assert.Equal(t, []string{account.ID}, repo.touched)
+	assert.Equal(t, []string{"service_account.created", "service_account.token_issued"}, audit.Actions())
+	assert.Equal(t, "admin-1", audit.Entries[0].UserID)
+	assert.Equal(t, account.ID, audit.Entries[1].ServiceAccountID)
+	assert.Empty(t, audit.Entries[1].UserID)
+
+	// Wrong and unknown credentials are rejected alike
+	_, err = svc.IssueToken(ctx, account.ClientID, "wrong", "203.0.113.7")
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+	_, err = svc.IssueToken(ctx, "sa_unknown", secret, "203.0.113.7")
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestIssueTokenFailsWithoutAudit(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _, audit := newTestService()
+
+	secret, account, err := svc.CreateAdminServiceAccount(ctx, "Billing sync", []string{model.PermissionUsersRead}, testAdmin)
+	require.NoError(t, err)
+
+	// No token is issued if it can't be audited
+	audit.Err = errors.New("database is down")
+	_, err = svc.IssueToken(ctx, account.ClientID, secret, "203.0.113.7")
+	assert.Error(t, err)
+	assert.Empty(t, repo.touched)
+}
+
+func TestCreateAdminServiceAccountScopes(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _, _ := newTestService()
+
+	_, _, err := svc.CreateAdminServiceAccount(ctx, "Sync", nil, testAdmin)
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+	_, _, err = svc.CreateAdminServiceAccount(ctx, "Sync", []string{"unknown:scope"}, testAdmin)
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+
+	// Admins can only give permissions they have
+	_, _, err = svc.CreateAdminServiceAccount(ctx, "Sync", []string{model.PermissionRolesManage}, testAdmin)
+	servicetest.AssertStatus(t, err, http.StatusForbidden)
+
+	// The admin API key can give any permission
+	_, account, err := svc.CreateAdminServiceAccount(ctx, "Sync", []string{model.PermissionRolesManage}, model.AdminActor{ID: "admin_key"})
+	require.NoError(t, err)
+	assert.Nil(t, account.CreatedBy)
+
+	// Service accounts can't make more service accounts
+	_, _, err = svc.CreateAdminServiceAccount(ctx, "Sync", []string{model.PermissionUsersRead}, model.AdminActor{ID: "service_account:" + account.ID})
+	servicetest.AssertStatus(t, err, http.StatusForbidden)
+}
+
+func TestRotateAndDeleteAdminServiceAccount(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, tokens, audit := newTestService()
+
+	oldSecret, account, err := svc.CreateAdminServiceAccount(ctx, "Sync", []string{model.PermissionUsersRead}, testAdmin)
+	require.NoError(t, err)
+
+	newSecret, err := svc.RotateAdminServiceAccountSecret(ctx, account.ID, testAdmin)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldSecret, newSecret)
+	assert.Equal(t, []string{account.ID}, tokens.revoked)
+
+	_, err = svc.IssueToken(ctx, account.ClientID, oldSecret, "")
+	servicetest.AssertStatus(t, err, http.StatusUnauthorized)
+	_, err = svc.IssueToken(ctx, account.ClientID, newSecret, "")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteAdminServiceAccount(ctx, account.ID, testAdmin))
+	assert.Empty(t, repo.accounts)
+	assert.Equal(t, []string{account.ID, account.ID}, tokens.revoked)
+	assert.Equal(t, []string{
+		"service_account.created",
+		"service_account.secret_rotated",
+		"service_account.token_issued",
+		"service_account.deleted",
+	}, audit.Actions())
+
+	err = svc.DeleteAdminServiceAccount(ctx, account.ID, testAdmin)
+	servicetest.AssertStatus(t, err, http.StatusNotFound)
+}
+
+func TestOrganizationServiceAccounts(t *testing.T) {
+	ctx := context.Background()
+	svc, _, tokens, _ := newTestService()
+
+	admin := &model.Membership{OrganizationID: "org-1", UserID: "user-1", Role: model.OrgRoleAdmin}
+	member := &model.Membership{OrganizationID: "org-1", UserID: "user-2", Role: model.OrgRoleMember}
+	other := &model.Membership{OrganizationID: "org-2", UserID: "user-3", Role: model.OrgRoleOwner}
+
+	_, _, err := svc.CreateOrganizationServiceAccount(ctx, member, "Import", model.OrgRoleMember)
+	servicetest.AssertStatus(t, err, http.StatusForbidden)
+	_, _, err = svc.CreateOrganizationServiceAccount(ctx, admin, "Import", model.OrgRoleOwner)
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+
+	_, account, err := svc.CreateOrganizationServiceAccount(ctx, admin, "Import", model.OrgRoleMember)
+	require.NoError(t, err)
+	assert.Equal(t, "org-1", *account.OrganizationID)
+	assert.Equal(t, model.OrgRoleMember, account.Role)
+
+	accounts, err := svc.ListOrganizationServiceAccounts(ctx, admin)
+	require.NoError(t, err)
+	assert.Len(t, accounts, 1)
+
+	// Service accounts of other organizations and of the admins look missing
+	_, err = svc.RotateOrganizationServiceAccountSecret(ctx, other, account.ID)
+	servicetest.AssertStatus(t, err, http.StatusNotFound)
+	err = svc.DeleteAdminServiceAccount(ctx, account.ID, testAdmin)
+	servicetest.AssertStatus(t, err, http.StatusNotFound)
+
+	// Service accounts acting as admins can't make more service accounts
+	serviceAdmin := &model.Membership{OrganizationID: "org-1", UserID: account.ID, Role: model.OrgRoleAdmin, ServiceAccount: true}
+	_, _, err = svc.CreateOrganizationServiceAccount(ctx, serviceAdmin, "Import", model.OrgRoleMember)
+	servicetest.AssertStatus(t, err, http.StatusForbidden)
+
+	require.NoError(t, svc.DeleteOrganizationServiceAccount(ctx, admin, account.ID))
+	assert.Equal(t, []string{account.ID}, tokens.revoked)
+}
@@ -0,0 +1,61 @@
+// Package servicetest provides the fakes and assertions shared by the tests of
+// the services.
+package servicetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertStatus asserts that err is an app error with an HTTP status code
+func AssertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+
+	var appErr *apperrors.AppError
+	require.True(t, errors.As(err, &appErr), "expected an app error, got %v", err)
+	assert.Equal(t, status, appErr.StatusCode)
+}
+
+// AuditLog is an audit log remembering what was recorded. It implements
+// service.AuditLog, and CreateAuditLog for services writing entries directly.
+type AuditLog struct {
+	// Events recorded with Record
+	Events []*model.AuditEvent
+	// Entries written with CreateAuditLog
+	Entries []*models.AuditLog
+	// Err is returned by CreateAuditLog when set
+	Err error
+}
+
+// Record remembers event
+func (a *AuditLog) Record(ctx context.Context, event *model.AuditEvent) {
+	a.Events = append(a.Events, event)
+}
+
+// CreateAuditLog remembers log, or fails with Err when it is set
+func (a *AuditLog) CreateAuditLog(ctx context.Context, log *models.AuditLog) error {
+	if a.Err != nil {
+		return a.Err
+	}
+	a.Entries = append(a.Entries, log)
+	return nil
+}
+
+// Actions returns the actions of the events and entries recorded, in order
+func (a *AuditLog) Actions() []string {
+	actions := make([]string, 0, len(a.Events)+len(a.Entries))
+	for _, event := range a.Events {
+		actions = append(actions, event.Action)
+	}
+	for _, entry := range a.Entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
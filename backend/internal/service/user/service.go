@@ -4,29 +4,52 @@ import (
 	"context"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
 	"github.com/nanayaw/fullstack/internal/models"
 )
 
 // Repository defines the interface for user repository
 type Repository interface {
+	// GetUser gets a user by ID, or nil if there is none
 	GetUser(ctx context.Context, id string) (*models.User, error)
+	// UpdateUser updates a user, or returns nil if there is none
 	UpdateUser(ctx context.Context, id string, user *models.UpdateUserRequest) (*models.User, error)
 	DeleteUser(ctx context.Context, id string) error
+}
+
+// Activity defines the interface for reading what users did from the audit log
+type Activity interface {
 	GetUserActivity(ctx context.Context, userID string, page, pageSize int) ([]*models.AuditLog, int, error)
 }
 
+// Webhooks notifies the webhook endpoints of other services of identity
+// lifecycle events
+type Webhooks interface {
+	Dispatch(ctx context.Context, eventType string, data map[string]interface{})
+}
+
 // Service handles user-related business logic
 type Service struct {
-	repo Repository
+	repo     Repository
+	activity Activity
+	webhooks Webhooks
 }
 
 // NewService creates a new user service
-func NewService(repo Repository) *Service {
+func NewService(repo Repository, activity Activity) *Service {
 	return &Service{
-		repo: repo,
+		repo:     repo,
+		activity: activity,
 	}
 }
 
+// SetWebhooks sets the webhooks notified when users change their email
+// address or delete their account. No webhooks are sent without them.
+func (s *Service) SetWebhooks(webhooks Webhooks) {
+	s.webhooks = webhooks
+}
+
 // GetUser retrieves a user by ID
 func (s *Service) GetUser(c echo.Context, id string) (*models.User, error) {
 	if s.repo == nil {
@@ -40,7 +63,15 @@ func (s *Service) GetUser(c echo.Context, id string) (*models.User, error) {
 		}, nil
 	}
 
-	return s.repo.GetUser(c.Request().Context(), id)
+	user, err := s.repo.GetUser(c.Request().Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.NewNotFoundError("user not found")
+	}
+
+	return user, nil
 }
 
 // UpdateUser updates a user
@@ -56,7 +87,34 @@ func (s *Service) UpdateUser(c echo.Context, id string, req *models.UpdateUserRe
 		}, nil
 	}
 
-	return s.repo.UpdateUser(c.Request().Context(), id, req)
+	ctx := c.Request().Context()
+
+	var previousEmail string
+	if req.Email != nil {
+		current, err := s.GetUser(c, id)
+		if err != nil {
+			return nil, err
+		}
+		previousEmail = current.Email
+	}
+
+	user, err := s.repo.UpdateUser(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.NewNotFoundError("user not found")
+	}
+
+	if req.Email != nil && user.Email != previousEmail {
+		s.dispatch(ctx, model.WebhookEventUserEmailChanged, map[string]interface{}{
+			"user_id":        user.ID,
+			"email":          user.Email,
+			"previous_email": previousEmail,
+		})
+	}
+
+	return user, nil
 }
 
 // DeleteUser deletes a user
@@ -66,30 +124,29 @@ func (s *Service) DeleteUser(c echo.Context, id string) error {
 		return nil
 	}
 
-	return s.repo.DeleteUser(c.Request().Context(), id)
+	ctx := c.Request().Context()
+	if err := s.repo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.dispatch(ctx, model.WebhookEventUserDeleted, map[string]interface{}{"user_id": id})
+	return nil
 }
 
-// GetUserActivity retrieves user activity
+// GetUserActivity retrieves the audit log entries of what a user did,
+// newest first
 func (s *Service) GetUserActivity(c echo.Context, userID string, page, pageSize int) ([]*models.AuditLog, int, error) {
-	if s.repo == nil {
-		// Return mock data for now
-		activities := []*models.AuditLog{
-			{
-				ID:         "1",
-				UserID:     userID,
-				Action:     "login",
-				EntityType: "session",
-				EntityID:   "session-1",
-				Metadata:   "Login from Chrome on Windows",
-			},
-		}
-		return activities, 1, nil
-	}
-
-	return s.repo.GetUserActivity(c.Request().Context(), userID, page, pageSize)
+	return s.activity.GetUserActivity(c.Request().Context(), userID, page, pageSize)
 }
 
 // UpdateProfile updates a user's profile
 func (s *Service) UpdateProfile(c echo.Context, userID string, req *models.UpdateUserRequest) (*models.User, error) {
 	return s.UpdateUser(c, userID, req)
 }
+
+// dispatch notifies webhooks of an event, if there are any
+func (s *Service) dispatch(ctx context.Context, eventType string, data map[string]interface{}) {
+	if s.webhooks != nil {
+		s.webhooks.Dispatch(ctx, eventType, data)
+	}
+}
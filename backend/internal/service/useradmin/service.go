@@ -0,0 +1,371 @@
+package useradmin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/models"
+	"github.com/nanayaw/fullstack/pkg/logger"
+)
+
+const (
+	// DefaultPageSize is the number of users returned when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize is the largest number of users returned at once
+	MaxPageSize = 200
+)
+
+// Repository defines the interface for user administration database operations
+type Repository interface {
+	// SearchUsers gets a filtered page of users, newest first
+	SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.AdminUser, error)
+
+	// GetUser gets a user by ID, or nil if there is none
+	GetUser(ctx context.Context, id string) (*model.AdminUser, error)
+
+	// ListUserSessions gets the active sessions of a user
+	ListUserSessions(ctx context.Context, userID string) ([]*model.UserSession, error)
+
+	// ListLinkedAccounts gets the OAuth accounts linked to a user
+	ListLinkedAccounts(ctx context.Context, userID string) ([]*model.LinkedAccount, error)
+
+	// DeleteUserSessions deletes every session of a user and returns how many there were
+	DeleteUserSessions(ctx context.Context, userID string) (int64, error)
+
+	// SetUserDisabled disables or enables a user and reports whether that changed anything
+	SetUserDisabled(ctx context.Context, userID string, disabled bool) (bool, error)
+}
+
+// Security defines the security operations admins take on accounts
+type Security interface {
+	LockAccount(ctx context.Context, userID string, until time.Time, reason string) error
+	UnlockAccount(ctx context.Context, userID string) error
+	RecordAdminAction(ctx context.Context, userID string, actor model.AdminActor, description string) error
+	RecordAdminEvent(ctx context.Context, userID, eventType string, actor model.AdminActor, description string) error
+}
+
+// Auth defines the authentication operations admins take on accounts
+type Auth interface {
+	InvalidateAllSessions(ctx context.Context, userID string) error
+	SendVerificationEmail(ctx context.Context, userID string) error
+	StartImpersonation(ctx context.Context, userID, actorID, reason string) (string, *model.Impersonation, error)
+	StopImpersonation(ctx context.Context, id string) (*model.Impersonation, error)
+}
+
+// AuditLog defines the interface for recording audit log entries
+type AuditLog interface {
+	CreateAuditLog(ctx context.Context, log *models.AuditLog) error
+}
+
+// Service lets admins find users and manage their accounts. Every change is
+// recorded in the user's security events.
+type Service struct {
+	repo     Repository
+	security Security
+	auth     Auth
+	audit    AuditLog
+	logger   logger.Logger
+}
+
+// NewService creates a new user administration service
+func NewService(repo Repository, security Security, auth Auth, audit AuditLog, log logger.Logger) *Service {
+	return &Service{
+		repo:     repo,
+		security: security,
+		auth:     auth,
+		audit:    audit,
+		logger:   log,
+	}
+}
+
+// SearchUsers searches users, newest first
+func (s *Service) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.AdminUser, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return nil, errors.NewValidationError("created_after must be before created_before")
+	}
+
+	return s.repo.SearchUsers(ctx, filter)
+}
+
+// GetUser gets a user with their active sessions and linked accounts
+func (s *Service) GetUser(ctx context.Context, userID string) (*model.UserDetail, error) {
+	user, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repo.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.repo.ListLinkedAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserDetail{
+		User:           user,
+		Sessions:       sessions,
+		LinkedAccounts: accounts,
+	}, nil
+}
+
+// ForceLogout signs a user out of every session
+func (s *Service) ForceLogout(ctx context.Context, userID string, actor model.AdminActor) error {
+	if _, err := s.requireUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.signOut(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, userID, actor, "Signed out of every session")
+	return nil
+}
+
+// LockUser locks a user's account until a time and signs them out
+func (s *Service) LockUser(ctx context.Context, userID string, until time.Time, reason string, actor model.AdminActor) error {
+	if !until.After(time.Now()) {
+		return errors.NewValidationError("until must be in the future")
+	}
+	if reason == "" {
+		return errors.NewValidationError("reason is required")
+	}
+	if _, err := s.requireUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.security.LockAccount(ctx, userID, until, reason); err != nil {
+		return fmt.Errorf("failed to lock account: %w", err)
+	}
+	if err := s.signOut(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, userID, actor, fmt.Sprintf("Account locked until %s: %s", until.UTC().Format(time.RFC3339), reason))
+	return nil
+}
+
+// UnlockUser unlocks a user's account
+func (s *Service) UnlockUser(ctx context.Context, userID string, actor model.AdminActor) error {
+	user, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.LockedUntil == nil {
+		return errors.NewConflictError("account is not locked")
+	}
+
+	if err := s.security.UnlockAccount(ctx, userID); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	s.record(ctx, userID, actor, "Account unlocked")
+	return nil
+}
+
+// ResendVerification sends a new verification email to a user who hasn't
+// verified their email yet
+func (s *Service) ResendVerification(ctx context.Context, userID string, actor model.AdminActor) error {
+	user, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return errors.NewConflictError("email is already verified")
+	}
+
+	if err := s.auth.SendVerificationEmail(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, userID, actor, "Verification email sent")
+	return nil
+}
+
+// DisableUser disables a user's account and signs them out. Disabled users
+// keep their data but can't sign in until they are enabled again.
+func (s *Service) DisableUser(ctx context.Context, userID, reason string, actor model.AdminActor) error {
+	if reason == "" {
+		return errors.NewValidationError("reason is required")
+	}
+
+	if err := s.setDisabled(ctx, userID, true); err != nil {
+		return err
+	}
+	if err := s.signOut(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, userID, actor, "Account disabled: "+reason)
+	return nil
+}
+
+// EnableUser enables a disabled account
+func (s *Service) EnableUser(ctx context.Context, userID string, actor model.AdminActor) error {
+	if err := s.setDisabled(ctx, userID, false); err != nil {
+		return err
+	}
+
+	s.record(ctx, userID, actor, "Account enabled")
+	return nil
+}
+
+// StartImpersonation lets an admin act as a user with a short-lived access
+// token, which is returned. Impersonations are recorded in the audit log and
+// the user's security events, and only users can impersonate, so every
+// impersonation is tied to a person.
+func (s *Service) StartImpersonation(ctx context.Context, userID, reason string, actor model.AdminActor) (string, *model.Impersonation, error) {
+	if actor.UserID == "" {
+		return "", nil, errors.NewValidationError("sign in as a user to impersonate users")
+	}
+	if actor.UserID == userID {
+		return "", nil, errors.NewValidationError("you can't impersonate yourself")
+	}
+	if reason == "" {
+		return "", nil, errors.NewValidationError("reason is required")
+	}
+
+	user, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.DisabledAt != nil {
+		return "", nil, errors.NewConflictError("account is disabled")
+	}
+
+	token, impersonation, err := s.auth.StartImpersonation(ctx, userID, actor.UserID, reason)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Impersonations must not go unaudited
+	if err := s.auditImpersonation(ctx, "impersonation.started", impersonation, actor); err != nil {
+		if _, stopErr := s.auth.StopImpersonation(ctx, impersonation.ID); stopErr != nil {
+			s.logger.Error("Failed to stop unaudited impersonation", "impersonation_id", impersonation.ID, "error", stopErr)
+		}
+		return "", nil, err
+	}
+
+	s.recordEvent(ctx, userID, model.EventImpersonationStarted, actor, "Impersonation started: "+reason)
+	return token, impersonation, nil
+}
+
+// StopImpersonation ends an impersonation, revoking its access token
+func (s *Service) StopImpersonation(ctx context.Context, id string, actor model.AdminActor) (*model.Impersonation, error) {
+	impersonation, err := s.auth.StopImpersonation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditImpersonation(ctx, "impersonation.stopped", impersonation, actor); err != nil {
+		s.logger.Error("Failed to audit stopped impersonation", "impersonation_id", id, "error", err)
+	}
+
+	s.recordEvent(ctx, impersonation.UserID, model.EventImpersonationStopped, actor, "Impersonation stopped")
+	return impersonation, nil
+}
+
+// auditImpersonation records an impersonation change in the audit log
+func (s *Service) auditImpersonation(ctx context.Context, action string, impersonation *model.Impersonation, actor model.AdminActor) error {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"impersonation_id": impersonation.ID,
+		"impersonator_id":  impersonation.ActorID,
+		"reason":           impersonation.Reason,
+		"expires_at":       impersonation.ExpiresAt,
+		"actor":            actor.ID,
+		"ip_address":       actor.IPAddress,
+		"user_agent":       actor.UserAgent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode audit metadata: %w", err)
+	}
+
+	// Entries belong to the admin who acted, who might not be the one
+	// impersonating when they stop someone else's impersonation
+	userID := actor.UserID
+	if userID == "" {
+		userID = impersonation.ActorID
+	}
+
+	return s.audit.CreateAuditLog(ctx, &models.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "user",
+		EntityID:   impersonation.UserID,
+		Metadata:   string(metadata),
+	})
+}
+
+// setDisabled disables or enables a user, returning a conflict error if they
+// already were
+func (s *Service) setDisabled(ctx context.Context, userID string, disabled bool) error {
+	changed, err := s.repo.SetUserDisabled(ctx, userID, disabled)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return nil
+	}
+
+	if _, err := s.requireUser(ctx, userID); err != nil {
+		return err
+	}
+	if disabled {
+		return errors.NewConflictError("account is already disabled")
+	}
+	return errors.NewConflictError("account is not disabled")
+}
+
+// signOut revokes every token and session of a user
+func (s *Service) signOut(ctx context.Context, userID string) error {
+	if err := s.auth.InvalidateAllSessions(ctx, userID); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+	if _, err := s.repo.DeleteUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// requireUser gets a user, returning a not found error if there is none
+func (s *Service) requireUser(ctx context.Context, userID string) (*model.AdminUser, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.NewNotFoundError("user not found")
+	}
+	return user, nil
+}
+
+// record records an admin action in the user's security events. The action
+// already happened, so failing to record it is logged rather than returned.
+func (s *Service) record(ctx context.Context, userID string, actor model.AdminActor, description string) {
+	s.recordEvent(ctx, userID, model.EventAdminAction, actor, description)
+}
+
+// recordEvent records a security event of a user caused by an admin
+func (s *Service) recordEvent(ctx context.Context, userID, eventType string, actor model.AdminActor, description string) {
+	s.logger.Info("Admin action", "user_id", userID, "actor", actor.ID, "event_type", eventType, "action", description)
+
+	if err := s.security.RecordAdminEvent(ctx, userID, eventType, actor, description); err != nil {
+		s.logger.Error("Failed to record admin action", "user_id", userID, "actor", actor.ID, "error", err)
+	}
+}
@@ -0,0 +1,278 @@
+package useradmin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository
+type fakeRepository struct {
+	users    map[string]*model.AdminUser
+	sessions map[string][]*model.UserSession
+	filter   model.UserFilter
+}
+
+func (f *fakeRepository) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.AdminUser, error) {
+	f.filter = filter
+	return nil, nil
+}
+
+func (f *fakeRepository) GetUser(ctx context.Context, id string) (*model.AdminUser, error) {
+	return f.users[id], nil
+}
+
+func (f *fakeRepository) ListUserSessions(ctx context.Context, userID string) ([]*model.UserSession, error) {
+	return f.sessions[userID], nil
+}
+
+func (f *fakeRepository) ListLinkedAccounts(ctx context.Context, userID string) ([]*model.LinkedAccount, error) {
+	return []*model.LinkedAccount{}, nil
+}
+
+func (f *fakeRepository) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	deleted := len(f.sessions[userID])
+	delete(f.sessions, userID)
+	return int64(deleted), nil
+}
+
+func (f *fakeRepository) SetUserDisabled(ctx context.Context, userID string, disabled bool) (bool, error) {
+	user := f.users[userID]
+	if user == nil || (user.DisabledAt != nil) == disabled {
+		return false, nil
+	}
+	if disabled {
+		now := time.Now()
+		user.DisabledAt = &now
+	} else {
+		user.DisabledAt = nil
+	}
+	return true, nil
+}
+
+// fakeSecurity locks accounts of the fake repository and keeps the recorded
+// admin actions
+type fakeSecurity struct {
+	repo    *fakeRepository
+	actions []string
+	events  []string
+}
+
+func (f *fakeSecurity) LockAccount(ctx context.Context, userID string, until time.Time, reason string) error {
+	f.repo.users[userID].LockedUntil = &until
+	f.repo.users[userID].LockReason = &reason
+	return nil
+}
+
+func (f *fakeSecurity) UnlockAccount(ctx context.Context, userID string) error {
+	f.repo.users[userID].LockedUntil = nil
+	f.repo.users[userID].LockReason = nil
+	return nil
+}
+
+func (f *fakeSecurity) RecordAdminAction(ctx context.Context, userID string, actor model.AdminActor, description string) error {
+	return f.RecordAdminEvent(ctx, userID, model.EventAdminAction, actor, description)
+}
+
+func (f *fakeSecurity) RecordAdminEvent(ctx context.Context, userID, eventType string, actor model.AdminActor, description string) error {
+	f.actions = append(f.actions, userID+": "+description)
+	f.events = append(f.events, eventType)
+	return nil
+}
+
+// fakeAuth counts the users signed out and the verification emails sent, and
+// keeps the active impersonations
+type fakeAuth struct {
+	invalidated    []string
+	verifications  []string
+	impersonations map[string]*model.Impersonation
+}
+
+func (f *fakeAuth) InvalidateAllSessions(ctx context.Context, userID string) error {
+	f.invalidated = append(f.invalidated, userID)
+	return nil
+}
+
+func (f *fakeAuth) SendVerificationEmail(ctx context.Context, userID string) error {
+	f.verifications = append(f.verifications, userID)
+	return nil
+}
+
+func (f *fakeAuth) StartImpersonation(ctx context.Context, userID, actorID, reason string) (string, *model.Impersonation, error) {
+	now := time.Now()
+	impersonation := &model.Impersonation{
+		ID:        "impersonation-1",
+		UserID:    userID,
+		ActorID:   actorID,
+		Reason:    reason,
+		StartedAt: now,
+		ExpiresAt: now.Add(10 * time.Minute),
+	}
+	f.impersonations[impersonation.ID] = impersonation
+	return "token", impersonation, nil
+}
+
+func (f *fakeAuth) StopImpersonation(ctx context.Context, id string) (*model.Impersonation, error) {
+	impersonation := f.impersonations[id]
+	if impersonation == nil {
+		return nil, apperrors.NewNotFoundError("impersonation not found")
+	}
+	delete(f.impersonations, id)
+	return impersonation, nil
+}
+
+var testActor = model.AdminActor{ID: "user:admin-1", UserID: "admin-1", IPAddress: "127.0.0.1", UserAgent: "test"}
+
+func newTestService() (*Service, *fakeRepository, *fakeSecurity, *fakeAuth) {
+	repo := &fakeRepository{
+		users: map[string]*model.AdminUser{
+			"user-1": {ID: "user-1", Email: "user@example.com"},
+		},
+		sessions: map[string][]*model.UserSession{
+			"user-1": {{ID: "session-1"}},
+		},
+	}
+	security := &fakeSecurity{repo: repo}
+	auth := &fakeAuth{impersonations: map[string]*model.Impersonation{}}
+	return NewService(repo, security, auth, &servicetest.AuditLog{}, logger.DefaultLogger()), repo, security, auth
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	s, repo, _, _ := newTestService()
+
+	_, err := s.SearchUsers(ctx, model.UserFilter{Limit: 1000, Offset: -1})
+	require.NoError(t, err)
+	assert.Equal(t, MaxPageSize, repo.filter.Limit)
+	assert.Equal(t, 0, repo.filter.Offset)
+
+	_, err = s.SearchUsers(ctx, model.UserFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPageSize, repo.filter.Limit)
+
+	now := time.Now()
+	_, err = s.SearchUsers(ctx, model.UserFilter{CreatedFrom: now, CreatedTo: now.Add(-time.Hour)})
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestLockUser(t *testing.T) {
+	ctx := context.Background()
+	s, repo, security, auth := newTestService()
+
+	until := time.Now().Add(time.Hour)
+	require.NoError(t, s.LockUser(ctx, "user-1", until, "chargeback", testActor))
+	assert.Equal(t, until, *repo.users["user-1"].LockedUntil)
+	assert.Equal(t, []string{"user-1"}, auth.invalidated)
+	assert.Empty(t, repo.sessions["user-1"])
+
+	require.NoError(t, s.UnlockUser(ctx, "user-1", testActor))
+	assert.Nil(t, repo.users["user-1"].LockedUntil)
+	servicetest.AssertStatus(t, s.UnlockUser(ctx, "user-1", testActor), http.StatusConflict)
+
+	servicetest.AssertStatus(t, s.LockUser(ctx, "user-1", time.Now().Add(-time.Minute), "chargeback", testActor), http.StatusBadRequest)
+	servicetest.AssertStatus(t, s.LockUser(ctx, "user-2", until, "chargeback", testActor), http.StatusNotFound)
+
+	// Only the actions that were taken are recorded
+	assert.Len(t, security.actions, 2)
+	assert.Contains(t, security.actions[0], "Account locked until")
+	assert.Equal(t, "user-1: Account unlocked", security.actions[1])
+}
+
+func TestDisableUser(t *testing.T) {
+	ctx := context.Background()
+	s, repo, security, auth := newTestService()
+
+	require.NoError(t, s.DisableUser(ctx, "user-1", "spam", testActor))
+	assert.NotNil(t, repo.users["user-1"].DisabledAt)
+	assert.Equal(t, []string{"user-1"}, auth.invalidated)
+	servicetest.AssertStatus(t, s.DisableUser(ctx, "user-1", "spam", testActor), http.StatusConflict)
+
+	require.NoError(t, s.EnableUser(ctx, "user-1", testActor))
+	assert.Nil(t, repo.users["user-1"].DisabledAt)
+	servicetest.AssertStatus(t, s.EnableUser(ctx, "user-1", testActor), http.StatusConflict)
+
+	servicetest.AssertStatus(t, s.DisableUser(ctx, "user-2", "spam", testActor), http.StatusNotFound)
+	servicetest.AssertStatus(t, s.DisableUser(ctx, "user-1", "", testActor), http.StatusBadRequest)
+
+	assert.Equal(t, []string{"user-1: Account disabled: spam", "user-1: Account enabled"}, security.actions)
+}
+
+func TestResendVerification(t *testing.T) {
+	ctx := context.Background()
+	s, repo, security, auth := newTestService()
+
+	require.NoError(t, s.ResendVerification(ctx, "user-1", testActor))
+	assert.Equal(t, []string{"user-1"}, auth.verifications)
+	assert.Equal(t, []string{"user-1: Verification email sent"}, security.actions)
+
+	repo.users["user-1"].EmailVerified = true
+	servicetest.AssertStatus(t, s.ResendVerification(ctx, "user-1", testActor), http.StatusConflict)
+}
+
+func TestForceLogout(t *testing.T) {
+	ctx := context.Background()
+	s, repo, security, auth := newTestService()
+
+	detail, err := s.GetUser(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Len(t, detail.Sessions, 1)
+
+	require.NoError(t, s.ForceLogout(ctx, "user-1", testActor))
+	assert.Equal(t, []string{"user-1"}, auth.invalidated)
+	assert.Empty(t, repo.sessions["user-1"])
+	assert.Equal(t, []string{"user-1: Signed out of every session"}, security.actions)
+
+	servicetest.AssertStatus(t, s.ForceLogout(ctx, "user-2", testActor), http.StatusNotFound)
+}
+
+func TestStartImpersonation(t *testing.T) {
+	ctx := context.Background()
+	s, repo, security, auth := newTestService()
+
+	_, _, err := s.StartImpersonation(ctx, "user-1", "", testActor)
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+
+	_, _, err = s.StartImpersonation(ctx, "user-1", "Support ticket", model.AdminActor{ID: "admin_key"})
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+
+	_, _, err = s.StartImpersonation(ctx, "admin-1", "Support ticket", testActor)
+	servicetest.AssertStatus(t, err, http.StatusBadRequest)
+
+	_, _, err = s.StartImpersonation(ctx, "missing", "Support ticket", testActor)
+	servicetest.AssertStatus(t, err, http.StatusNotFound)
+
+	token, impersonation, err := s.StartImpersonation(ctx, "user-1", "Support ticket", testActor)
+	require.NoError(t, err)
+	assert.Equal(t, "token", token)
+	assert.Equal(t, "admin-1", impersonation.ActorID)
+	assert.Equal(t, []string{model.EventImpersonationStarted}, security.events)
+	audit := s.audit.(*servicetest.AuditLog)
+	require.Len(t, audit.Entries, 1)
+	assert.Equal(t, "admin-1", audit.Entries[0].UserID)
+	assert.Equal(t, "impersonation.started", audit.Entries[0].Action)
+	assert.Equal(t, "user-1", audit.Entries[0].EntityID)
+	assert.Contains(t, audit.Entries[0].Metadata, "Support ticket")
+
+	_, err = s.StopImpersonation(ctx, impersonation.ID, testActor)
+	require.NoError(t, err)
+	assert.Empty(t, auth.impersonations)
+	assert.Equal(t, model.EventImpersonationStopped, security.events[1])
+	require.Len(t, audit.Entries, 2)
+	assert.Equal(t, "impersonation.stopped", audit.Entries[1].Action)
+
+	_, err = s.StopImpersonation(ctx, impersonation.ID, testActor)
+	servicetest.AssertStatus(t, err, http.StatusNotFound)
+
+	now := time.Now()
+	repo.users["user-1"].DisabledAt = &now
+	_, _, err = s.StartImpersonation(ctx, "user-1", "Support ticket", testActor)
+	servicetest.AssertStatus(t, err, http.StatusConflict)
+}
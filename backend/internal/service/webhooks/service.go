@@ -0,0 +1,451 @@
+// Package webhooks notifies the endpoints other services register of
+// identity lifecycle events, such as users signing up or being deleted.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/nanayaw/fullstack/pkg/webhook"
+)
+
+const (
+	// maxDescriptionLength is the longest endpoint description, in characters
+	maxDescriptionLength = 200
+	// secretLength is the number of random bytes of a generated secret
+	secretLength = 32
+)
+
+// Delivery listing page sizes
+const (
+	DefaultDeliveryPageSize = 50
+	MaxDeliveryPageSize     = 200
+)
+
+// Repository defines the interface for webhook database operations
+type Repository interface {
+	// CreateEndpoint stores a webhook endpoint, filling in its ID and creation time
+	CreateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error
+
+	// GetEndpoint gets a webhook endpoint by ID, or nil if there is none
+	GetEndpoint(ctx context.Context, id string) (*model.WebhookEndpoint, error)
+
+	// ListEndpoints gets every webhook endpoint
+	ListEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error)
+
+	// UpdateEndpoint saves the settings of a webhook endpoint and reports whether it exists
+	UpdateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) (bool, error)
+
+	// DeleteEndpoint deletes a webhook endpoint with its deliveries and reports whether it existed
+	DeleteEndpoint(ctx context.Context, id string) (bool, error)
+
+	// EnqueueDeliveries adds deliveries to the queue, all or none of them
+	EnqueueDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error
+
+	// ClaimDueDeliveries claims deliveries that are due until lockedUntil
+	ClaimDueDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.WebhookDelivery, error)
+
+	// RecordDeliveryAttempt adds an attempt to the delivery log
+	RecordDeliveryAttempt(ctx context.Context, attempt *model.WebhookDeliveryAttempt) error
+
+	// MarkDeliveryDelivered records that a claimed delivery was accepted by its endpoint
+	MarkDeliveryDelivered(ctx context.Context, id string, statusCode int, now time.Time) error
+
+	// MarkDeliveryFailed records a failed attempt and schedules the next one
+	MarkDeliveryFailed(ctx context.Context, id string, statusCode *int, lastError string, nextAttemptAt, now time.Time) error
+
+	// MarkDeliveryDead dead-letters a claimed delivery after its last failed attempt
+	MarkDeliveryDead(ctx context.Context, id string, statusCode *int, lastError string, now time.Time) error
+
+	// GetDelivery gets a webhook delivery by ID, or nil if there is none
+	GetDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error)
+
+	// ListDeliveries gets a filtered page of the deliveries of an endpoint
+	ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, error)
+
+	// ListDeliveryAttempts gets the attempts made for a delivery
+	ListDeliveryAttempts(ctx context.Context, deliveryID string) ([]*model.WebhookDeliveryAttempt, error)
+}
+
+// Service manages webhook endpoints and queues the events they subscribe to.
+// A Worker delivers the queued events, so a slow or unavailable endpoint
+// never slows down the requests that trigger them.
+type Service struct {
+	repo   Repository
+	audit  service.AuditLog
+	logger logger.Logger
+}
+
+// NewService creates a new webhook service
+func NewService(repo Repository, audit service.AuditLog, log logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		audit:  audit,
+		logger: log,
+	}
+}
+
+// CreateEndpoint registers a webhook endpoint and returns the secret its
+// requests are signed with, which can't be read again
+func (s *Service) CreateEndpoint(ctx context.Context, input model.WebhookEndpointInput, actor model.AdminActor) (string, *model.WebhookEndpoint, error) {
+	endpoint := &model.WebhookEndpoint{
+		URL:         strings.TrimSpace(input.URL),
+		EventTypes:  input.EventTypes,
+		Description: strings.TrimSpace(input.Description),
+		Secret:      input.Secret,
+		Enabled:     true,
+	}
+	if actor.UserID != "" {
+		endpoint.CreatedBy = &actor.UserID
+	}
+
+	if err := validateEndpoint(endpoint); err != nil {
+		return "", nil, err
+	}
+
+	if endpoint.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return "", nil, err
+		}
+		endpoint.Secret = secret
+	} else if _, err := webhook.NewVerifier(endpoint.Secret); err != nil {
+		return "", nil, errors.NewValidationError("secret must be a whsec_ secret")
+	}
+
+	if err := s.repo.CreateEndpoint(ctx, endpoint); err != nil {
+		return "", nil, err
+	}
+
+	s.record(ctx, "webhook_endpoint.created", endpoint.ID, map[string]interface{}{
+		"url":         endpoint.URL,
+		"event_types": endpoint.EventTypes,
+		"actor":       actor.ID,
+	}, nil)
+	s.logger.Info("Created webhook endpoint", "webhook_endpoint_id", endpoint.ID, "actor", actor.ID)
+
+	return endpoint.Secret, endpoint, nil
+}
+
+// ListEndpoints gets every webhook endpoint
+func (s *Service) ListEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error) {
+	return s.repo.ListEndpoints(ctx)
+}
+
+// GetEndpoint gets a webhook endpoint
+func (s *Service) GetEndpoint(ctx context.Context, id string) (*model.WebhookEndpoint, error) {
+	endpoint, err := s.repo.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil {
+		return nil, errors.NewNotFoundError("webhook endpoint not found")
+	}
+
+	return endpoint, nil
+}
+
+// UpdateEndpoint changes the settings of a webhook endpoint. Disabling it
+// dead-letters its queued deliveries when they come up.
+func (s *Service) UpdateEndpoint(ctx context.Context, id string, update model.WebhookEndpointUpdate, actor model.AdminActor) (*model.WebhookEndpoint, error) {
+	endpoint, err := s.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := endpointFields(endpoint)
+
+	if update.URL != nil {
+		endpoint.URL = strings.TrimSpace(*update.URL)
+	}
+	if update.EventTypes != nil {
+		endpoint.EventTypes = update.EventTypes
+	}
+	if update.Description != nil {
+		endpoint.Description = strings.TrimSpace(*update.Description)
+	}
+	if update.Enabled != nil {
+		endpoint.Enabled = *update.Enabled
+	}
+
+	if err := validateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	endpoint.UpdatedAt = time.Now()
+	updated, err := s.repo.UpdateEndpoint(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.NewNotFoundError("webhook endpoint not found")
+	}
+
+	changes := model.AuditDiff(before, endpointFields(endpoint))
+	if len(changes) > 0 {
+		s.record(ctx, "webhook_endpoint.updated", endpoint.ID, map[string]interface{}{"actor": actor.ID}, changes)
+		s.logger.Info("Updated webhook endpoint", "webhook_endpoint_id", endpoint.ID, "actor", actor.ID)
+	}
+
+	return endpoint, nil
+}
+
+// DeleteEndpoint deletes a webhook endpoint, dropping its queued deliveries
+// and its delivery log
+func (s *Service) DeleteEndpoint(ctx context.Context, id string, actor model.AdminActor) error {
+	endpoint, err := s.GetEndpoint(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteEndpoint(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.NewNotFoundError("webhook endpoint not found")
+	}
+
+	s.record(ctx, "webhook_endpoint.deleted", id, map[string]interface{}{
+		"url":   endpoint.URL,
+		"actor": actor.ID,
+	}, nil)
+	s.logger.Info("Deleted webhook endpoint", "webhook_endpoint_id", id, "actor", actor.ID)
+
+	return nil
+}
+
+// Dispatch queues an event for every enabled endpoint subscribed to it.
+// Failing to queue it is logged rather than returned, so it never fails the
+// change that triggered the event.
+func (s *Service) Dispatch(ctx context.Context, eventType string, data map[string]interface{}) {
+	log := s.logger.With("event_type", eventType)
+
+	endpoints, err := s.repo.ListEndpoints(ctx)
+	if err != nil {
+		log.Error("Failed to list webhook endpoints", "error", err)
+		return
+	}
+
+	now := time.Now()
+	event := &model.WebhookEvent{
+		ID:        "evt_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Type:      eventType,
+		CreatedAt: now.UTC(),
+		Data:      data,
+	}
+
+	var deliveries []*model.WebhookDelivery
+	var payload []byte
+	for _, endpoint := range endpoints {
+		if !endpoint.Enabled || !endpoint.Subscribes(eventType) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				log.Error("Failed to encode webhook event", "error", err)
+				return
+			}
+		}
+
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return
+	}
+
+	if err := s.repo.EnqueueDeliveries(ctx, deliveries); err != nil {
+		log.Error("Failed to queue webhook deliveries", "event_id", event.ID, "error", err)
+		return
+	}
+
+	log.Debug("Queued webhook deliveries", "event_id", event.ID, "deliveries", len(deliveries))
+}
+
+// ListDeliveries lists the deliveries of an endpoint, newest first
+func (s *Service) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, error) {
+	switch filter.Status {
+	case "", model.WebhookDeliveryPending, model.WebhookDeliverySending, model.WebhookDeliveryDelivered, model.WebhookDeliveryDead:
+	default:
+		return nil, errors.NewValidationError("invalid status")
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultDeliveryPageSize
+	}
+	if filter.Limit > MaxDeliveryPageSize {
+		filter.Limit = MaxDeliveryPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	if _, err := s.GetEndpoint(ctx, filter.EndpointID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListDeliveries(ctx, filter)
+}
+
+// GetDelivery gets a delivery of an endpoint with the attempts made to
+// deliver it
+func (s *Service) GetDelivery(ctx context.Context, endpointID, id string) (*model.WebhookDelivery, []*model.WebhookDeliveryAttempt, error) {
+	delivery, err := s.requireDelivery(ctx, endpointID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := s.repo.ListDeliveryAttempts(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return delivery, attempts, nil
+}
+
+// ReplayDelivery queues a delivered or dead-lettered delivery again, with a
+// fresh set of attempts. The replay sends the same event, with the same ID,
+// so receivers that already processed it can tell.
+func (s *Service) ReplayDelivery(ctx context.Context, endpointID, id string, actor model.AdminActor) (*model.WebhookDelivery, error) {
+	original, err := s.requireDelivery(ctx, endpointID, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status != model.WebhookDeliveryDelivered && original.Status != model.WebhookDeliveryDead {
+		return nil, errors.NewConflictError(fmt.Sprintf("only delivered or dead-lettered deliveries can be replayed, delivery is %s", original.Status))
+	}
+
+	now := time.Now()
+	replay := &model.WebhookDelivery{
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: now,
+		ReplayOf:      &original.ID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.repo.EnqueueDeliveries(ctx, []*model.WebhookDelivery{replay}); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, "webhook_delivery.replayed", endpointID, map[string]interface{}{
+		"delivery_id": original.ID,
+		"replay_id":   replay.ID,
+		"event_id":    original.EventID,
+		"actor":       actor.ID,
+	}, nil)
+	s.logger.Info("Replayed webhook delivery", "webhook_endpoint_id", endpointID, "delivery_id", original.ID, "replay_id", replay.ID, "actor", actor.ID)
+
+	return replay, nil
+}
+
+// requireDelivery gets a delivery of an endpoint, or returns a not found
+// error
+func (s *Service) requireDelivery(ctx context.Context, endpointID, id string) (*model.WebhookDelivery, error) {
+	delivery, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil || delivery.EndpointID != endpointID {
+		return nil, errors.NewNotFoundError("webhook delivery not found")
+	}
+
+	return delivery, nil
+}
+
+// record records a change to a webhook endpoint in the audit log
+func (s *Service) record(ctx context.Context, action, endpointID string, metadata map[string]interface{}, changes map[string]model.AuditChange) {
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     action,
+		EntityType: "webhook_endpoint",
+		EntityID:   endpointID,
+		Changes:    changes,
+		Metadata:   metadata,
+	})
+}
+
+// validateEndpoint checks the settings of an endpoint, removing duplicate
+// event types
+func validateEndpoint(endpoint *model.WebhookEndpoint) error {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.NewValidationError("url must be an http or https URL")
+	}
+
+	if len(endpoint.EventTypes) == 0 {
+		return errors.NewValidationError("event_types must list at least one event type")
+	}
+	seen := make(map[string]bool, len(endpoint.EventTypes))
+	eventTypes := make([]string, 0, len(endpoint.EventTypes))
+	for _, eventType := range endpoint.EventTypes {
+		if !isEventType(eventType) {
+			return errors.NewValidationError(fmt.Sprintf("unknown event type %q", eventType))
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	endpoint.EventTypes = eventTypes
+
+	if utf8.RuneCountInString(endpoint.Description) > maxDescriptionLength {
+		return errors.NewValidationError(fmt.Sprintf("description must be at most %d characters", maxDescriptionLength))
+	}
+
+	return nil
+}
+
+// isEventType reports whether endpoints can subscribe to an event type
+func isEventType(eventType string) bool {
+	for _, t := range model.WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointFields returns the audited fields of an endpoint
+func endpointFields(endpoint *model.WebhookEndpoint) map[string]interface{} {
+	return map[string]interface{}{
+		"url":         endpoint.URL,
+		"event_types": strings.Join(endpoint.EventTypes, " "),
+		"description": endpoint.Description,
+		"enabled":     endpoint.Enabled,
+	}
+}
+
+// generateSecret returns a new random "whsec_" secret
+func generateSecret() (string, error) {
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,344 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/nanayaw/fullstack/internal/errors"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/internal/service/servicetest"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory Repository
+type fakeRepository struct {
+	endpoints  map[string]*model.WebhookEndpoint
+	deliveries map[string]*model.WebhookDelivery
+	attempts   []*model.WebhookDeliveryAttempt
+	nextID     int
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		endpoints:  make(map[string]*model.WebhookEndpoint),
+		deliveries: make(map[string]*model.WebhookDelivery),
+	}
+}
+
+func (f *fakeRepository) id() string {
+	f.nextID++
+	return strconv.Itoa(f.nextID)
+}
+
+func (f *fakeRepository) CreateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error {
+	endpoint.ID = f.id()
+	endpoint.CreatedAt = time.Now()
+	endpoint.UpdatedAt = endpoint.CreatedAt
+	stored := *endpoint
+	f.endpoints[endpoint.ID] = &stored
+	return nil
+}
+
+func (f *fakeRepository) GetEndpoint(ctx context.Context, id string) (*model.WebhookEndpoint, error) {
+	endpoint, ok := f.endpoints[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *endpoint
+	return &copied, nil
+}
+
+func (f *fakeRepository) ListEndpoints(ctx context.Context) ([]*model.WebhookEndpoint, error) {
+	var endpoints []*model.WebhookEndpoint
+	for _, endpoint := range f.endpoints {
+		copied := *endpoint
+		endpoints = append(endpoints, &copied)
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].ID < endpoints[j].ID })
+	return endpoints, nil
+}
+
+func (f *fakeRepository) UpdateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) (bool, error) {
+	if _, ok := f.endpoints[endpoint.ID]; !ok {
+		return false, nil
+	}
+	stored := *endpoint
+	f.endpoints[endpoint.ID] = &stored
+	return true, nil
+}
+
+func (f *fakeRepository) DeleteEndpoint(ctx context.Context, id string) (bool, error) {
+	if _, ok := f.endpoints[id]; !ok {
+		return false, nil
+	}
+	delete(f.endpoints, id)
+	return true, nil
+}
+
+func (f *fakeRepository) EnqueueDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		delivery.ID = f.id()
+		stored := *delivery
+		f.deliveries[delivery.ID] = &stored
+	}
+	return nil
+}
+
+func (f *fakeRepository) ClaimDueDeliveries(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	var claimed []*model.WebhookDelivery
+	for _, delivery := range f.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != model.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.Status = model.WebhookDeliverySending
+		delivery.Attempts++
+		delivery.LockedUntil = &lockedUntil
+		copied := *delivery
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (f *fakeRepository) RecordDeliveryAttempt(ctx context.Context, attempt *model.WebhookDeliveryAttempt) error {
+	f.attempts = append(f.attempts, attempt)
+	return nil
+}
+
+func (f *fakeRepository) MarkDeliveryDelivered(ctx context.Context, id string, statusCode int, now time.Time) error {
+	delivery := f.deliveries[id]
+	delivery.Status = model.WebhookDeliveryDelivered
+	delivery.LastStatusCode = &statusCode
+	delivery.DeliveredAt = &now
+	return nil
+}
+
+func (f *fakeRepository) MarkDeliveryFailed(ctx context.Context, id string, statusCode *int, lastError string, nextAttemptAt, now time.Time) error {
+	delivery := f.deliveries[id]
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = &lastError
+	delivery.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (f *fakeRepository) MarkDeliveryDead(ctx context.Context, id string, statusCode *int, lastError string, now time.Time) error {
+	delivery := f.deliveries[id]
+	delivery.Status = model.WebhookDeliveryDead
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = &lastError
+	return nil
+}
+
+func (f *fakeRepository) GetDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	delivery, ok := f.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+func (f *fakeRepository) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	for _, delivery := range f.deliveries {
+		if delivery.EndpointID == filter.EndpointID && (filter.Status == "" || delivery.Status == filter.Status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (f *fakeRepository) ListDeliveryAttempts(ctx context.Context, deliveryID string) ([]*model.WebhookDeliveryAttempt, error) {
+	var attempts []*model.WebhookDeliveryAttempt
+	for _, attempt := range f.attempts {
+		if attempt.DeliveryID == deliveryID {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
+
+// deliveriesOf returns the deliveries of an endpoint
+func (f *fakeRepository) deliveriesOf(endpointID string) []*model.WebhookDelivery {
+	deliveries, _ := f.ListDeliveries(context.Background(), model.WebhookDeliveryFilter{EndpointID: endpointID})
+	return deliveries
+}
+
+func newTestService() (*Service, *fakeRepository, *servicetest.AuditLog) {
+	repo := newFakeRepository()
+	audit := &servicetest.AuditLog{}
+	return NewService(repo, audit, logger.DefaultLogger()), repo, audit
+}
+
+var testActor = model.AdminActor{ID: "user:admin", UserID: "admin"}
+
+func TestCreateEndpoint(t *testing.T) {
+	s, repo, audit := newTestService()
+	ctx := context.Background()
+
+	secret, endpoint, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        " https://example.com/hooks ",
+		EventTypes: []string{model.WebhookEventUserRegistered, model.WebhookEventUserDeleted, model.WebhookEventUserRegistered},
+	}, testActor)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(secret, "whsec_"))
+	assert.Equal(t, secret, repo.endpoints[endpoint.ID].Secret)
+	assert.Equal(t, "https://example.com/hooks", endpoint.URL)
+	assert.Equal(t, []string{model.WebhookEventUserRegistered, model.WebhookEventUserDeleted}, endpoint.EventTypes)
+	assert.True(t, endpoint.Enabled)
+	assert.Equal(t, "admin", *endpoint.CreatedBy)
+	assert.Equal(t, []string{"webhook_endpoint.created"}, audit.Actions())
+
+	// A secret can be given, e.g. when moving endpoints from another provider
+	secret, _, err = s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        "https://example.com/other",
+		EventTypes: []string{model.WebhookEventUserLocked},
+		Secret:     "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw",
+	}, testActor)
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", secret)
+}
+
+func TestCreateEndpointValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input model.WebhookEndpointInput
+	}{
+		{"relative URL", model.WebhookEndpointInput{URL: "/hooks", EventTypes: []string{model.WebhookEventUserVerified}}},
+		{"unsupported scheme", model.WebhookEndpointInput{URL: "ftp://example.com", EventTypes: []string{model.WebhookEventUserVerified}}},
+		{"no event types", model.WebhookEndpointInput{URL: "https://example.com"}},
+		{"unknown event type", model.WebhookEndpointInput{URL: "https://example.com", EventTypes: []string{"user.renamed"}}},
+		{"long description", model.WebhookEndpointInput{URL: "https://example.com", EventTypes: []string{model.WebhookEventUserVerified}, Description: strings.Repeat("a", 201)}},
+		{"invalid secret", model.WebhookEndpointInput{URL: "https://example.com", EventTypes: []string{model.WebhookEventUserVerified}, Secret: "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, _ := newTestService()
+
+			_, _, err := s.CreateEndpoint(context.Background(), tt.input, testActor)
+
+			var appErr *apperrors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+			assert.Empty(t, repo.endpoints)
+		})
+	}
+}
+
+func TestUpdateEndpoint(t *testing.T) {
+	s, _, audit := newTestService()
+	ctx := context.Background()
+
+	_, endpoint, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        "https://example.com/hooks",
+		EventTypes: []string{model.WebhookEventUserRegistered},
+	}, testActor)
+	require.NoError(t, err)
+
+	disabled := false
+	updated, err := s.UpdateEndpoint(ctx, endpoint.ID, model.WebhookEndpointUpdate{Enabled: &disabled}, testActor)
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, "https://example.com/hooks", updated.URL)
+
+	// Changing nothing isn't audited
+	_, err = s.UpdateEndpoint(ctx, endpoint.ID, model.WebhookEndpointUpdate{Enabled: &disabled}, testActor)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webhook_endpoint.created", "webhook_endpoint.updated"}, audit.Actions())
+
+	_, err = s.UpdateEndpoint(ctx, endpoint.ID, model.WebhookEndpointUpdate{EventTypes: []string{}}, testActor)
+	var appErr *apperrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+
+	_, err = s.UpdateEndpoint(ctx, "missing", model.WebhookEndpointUpdate{Enabled: &disabled}, testActor)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusNotFound, appErr.StatusCode)
+}
+
+func TestDispatch(t *testing.T) {
+	s, repo, _ := newTestService()
+	ctx := context.Background()
+
+	_, subscribed, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        "https://example.com/subscribed",
+		EventTypes: []string{model.WebhookEventUserRegistered},
+	}, testActor)
+	require.NoError(t, err)
+	_, other, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        "https://example.com/other",
+		EventTypes: []string{model.WebhookEventUserDeleted},
+	}, testActor)
+	require.NoError(t, err)
+	_, disabled, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        "https://example.com/disabled",
+		EventTypes: []string{model.WebhookEventUserRegistered},
+	}, testActor)
+	require.NoError(t, err)
+	enabled := false
+	_, err = s.UpdateEndpoint(ctx, disabled.ID, model.WebhookEndpointUpdate{Enabled: &enabled}, testActor)
+	require.NoError(t, err)
+
+	s.Dispatch(ctx, model.WebhookEventUserRegistered, map[string]interface{}{"user_id": "user-1"})
+
+	assert.Empty(t, repo.deliveriesOf(other.ID))
+	assert.Empty(t, repo.deliveriesOf(disabled.ID))
+
+	deliveries := repo.deliveriesOf(subscribed.ID)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, model.WebhookEventUserRegistered, deliveries[0].EventType)
+	assert.True(t, strings.HasPrefix(deliveries[0].EventID, "evt_"))
+	assert.Contains(t, deliveries[0].Payload, `"id":"`+deliveries[0].EventID+`"`)
+	assert.Contains(t, deliveries[0].Payload, `"type":"user.registered"`)
+	assert.Contains(t, deliveries[0].Payload, `"data":{"user_id":"user-1"}`)
+}
+
+func TestReplayDelivery(t *testing.T) {
+	s, repo, audit := newTestService()
+	ctx := context.Background()
+
+	_, endpoint, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        "https://example.com/hooks",
+		EventTypes: []string{model.WebhookEventUserDeleted},
+	}, testActor)
+	require.NoError(t, err)
+
+	s.Dispatch(ctx, model.WebhookEventUserDeleted, map[string]interface{}{"user_id": "user-1"})
+	original := repo.deliveriesOf(endpoint.ID)[0]
+
+	// Queued deliveries can't be replayed
+	_, err = s.ReplayDelivery(ctx, endpoint.ID, original.ID, testActor)
+	var appErr *apperrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+
+	original.Status = model.WebhookDeliveryDead
+	replay, err := s.ReplayDelivery(ctx, endpoint.ID, original.ID, testActor)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, original.ID, replay.ID)
+	assert.Equal(t, original.ID, *replay.ReplayOf)
+	assert.Equal(t, original.EventID, replay.EventID)
+	assert.Equal(t, original.Payload, replay.Payload)
+	assert.Equal(t, model.WebhookDeliveryPending, replay.Status)
+	assert.Zero(t, replay.Attempts)
+	assert.Contains(t, audit.Actions(), "webhook_delivery.replayed")
+
+	// Deliveries are only found under their endpoint
+	_, err = s.ReplayDelivery(ctx, "other", original.ID, testActor)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusNotFound, appErr.StatusCode)
+}
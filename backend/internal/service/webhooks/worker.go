@@ -0,0 +1,247 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/backoff"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/nanayaw/fullstack/pkg/webhook"
+)
+
+// maxResponseBody is how much of a response body is kept in the delivery log
+const maxResponseBody = 1024
+
+// Defaults used when the webhook settings are not configured
+const (
+	defaultPollInterval   = 5 * time.Second
+	defaultBatchSize      = 20
+	defaultMaxAttempts    = 8
+	defaultRetryBaseDelay = 30 * time.Second
+	defaultRetryMaxDelay  = time.Hour
+	defaultTimeout        = 10 * time.Second
+)
+
+// Worker delivers queued webhook events, retrying failed deliveries with
+// exponential backoff and dead-lettering those that still fail after the
+// maximum number of attempts. Every attempt is kept in the delivery log.
+// Several workers, e.g. one per API replica, can safely share the queue.
+type Worker struct {
+	repo           Repository
+	client         *http.Client
+	logger         logger.Logger
+	pollInterval   time.Duration
+	batchSize      int
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	// How long a claimed delivery is reserved for the worker. A claim that
+	// outlives it is assumed lost and the delivery is claimed again.
+	claimLease time.Duration
+}
+
+// NewWorker creates a worker delivering queued webhook events
+func NewWorker(repo Repository, cfg *config.WebhooksConfig, log logger.Logger) *Worker {
+	w := &Worker{
+		repo:           repo,
+		logger:         log,
+		pollInterval:   cfg.PollInterval,
+		batchSize:      cfg.BatchSize,
+		maxAttempts:    cfg.MaxAttempts,
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if w.pollInterval <= 0 {
+		w.pollInterval = defaultPollInterval
+	}
+	if w.batchSize <= 0 {
+		w.batchSize = defaultBatchSize
+	}
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = defaultMaxAttempts
+	}
+	if w.retryBaseDelay <= 0 {
+		w.retryBaseDelay = defaultRetryBaseDelay
+	}
+	if w.retryMaxDelay <= 0 {
+		w.retryMaxDelay = defaultRetryMaxDelay
+	}
+
+	w.client = &http.Client{
+		Timeout: timeout,
+		// A redirect is a failed delivery, the endpoint must be registered
+		// with its final URL
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	// Leave time to record the outcome of the batch's requests
+	w.claimLease = time.Duration(w.batchSize)*timeout + time.Minute
+
+	return w
+}
+
+// Run delivers due events every poll interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while batches come back full, so a backlog drains quickly
+		for {
+			n, err := w.ProcessBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.logger.Error("Failed to process webhook deliveries", "error", err)
+				}
+				break
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims one batch of due deliveries and attempts them,
+// returning the number of deliveries claimed
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	deliveries, err := w.repo.ClaimDueDeliveries(ctx, now, now.Add(w.claimLease), w.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Deliveries of the same event often go to the same endpoints
+	endpoints := map[string]*model.WebhookEndpoint{}
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = w.repo.GetEndpoint(ctx, delivery.EndpointID)
+			if err != nil {
+				w.logger.Error("Failed to get webhook endpoint", "webhook_endpoint_id", delivery.EndpointID, "error", err)
+				continue
+			}
+			endpoints[delivery.EndpointID] = endpoint
+		}
+
+		w.deliver(ctx, endpoint, delivery)
+	}
+
+	return len(deliveries), nil
+}
+
+// deliver attempts to send a claimed delivery to its endpoint and records
+// the outcome
+func (w *Worker) deliver(ctx context.Context, endpoint *model.WebhookEndpoint, delivery *model.WebhookDelivery) {
+	log := w.logger.With("delivery_id", delivery.ID, "webhook_endpoint_id", delivery.EndpointID, "event_type", delivery.EventType, "attempt", delivery.Attempts)
+
+	// Record the outcome even if we are shutting down, otherwise the delivery
+	// would stay claimed until its lease runs out
+	recordCtx := context.WithoutCancel(ctx)
+
+	if endpoint == nil || !endpoint.Enabled {
+		if err := w.repo.MarkDeliveryDead(recordCtx, delivery.ID, nil, "endpoint is disabled", time.Now()); err != nil {
+			log.Error("Failed to dead-letter webhook delivery", "error", err)
+		}
+		return
+	}
+
+	attempt := w.send(ctx, endpoint, delivery)
+	if err := w.repo.RecordDeliveryAttempt(recordCtx, attempt); err != nil {
+		log.Error("Failed to record webhook delivery attempt", "error", err)
+	}
+
+	now := time.Now()
+	if attempt.Error == nil {
+		if err := w.repo.MarkDeliveryDelivered(recordCtx, delivery.ID, *attempt.StatusCode, now); err != nil {
+			log.Error("Failed to mark webhook delivery as delivered", "error", err)
+		}
+		return
+	}
+
+	if delivery.Attempts >= w.maxAttempts {
+		log.Error("Webhook delivery dead-lettered after final attempt", "error", *attempt.Error)
+		if err := w.repo.MarkDeliveryDead(recordCtx, delivery.ID, attempt.StatusCode, *attempt.Error, now); err != nil {
+			log.Error("Failed to dead-letter webhook delivery", "error", err)
+		}
+		return
+	}
+
+	nextAttemptAt := now.Add(backoff.Delay(delivery.Attempts, w.retryBaseDelay, w.retryMaxDelay))
+	log.Warn("Webhook delivery failed, will retry", "error", *attempt.Error, "next_attempt_at", nextAttemptAt)
+	if err := w.repo.MarkDeliveryFailed(recordCtx, delivery.ID, attempt.StatusCode, *attempt.Error, nextAttemptAt, now); err != nil {
+		log.Error("Failed to record webhook delivery failure", "error", err)
+	}
+}
+
+// send posts a delivery's payload to its endpoint, signed like Standard
+// Webhooks, and returns the attempt for the delivery log. It succeeded when
+// its error is nil, which takes a 2xx response.
+func (w *Worker) send(ctx context.Context, endpoint *model.WebhookEndpoint, delivery *model.WebhookDelivery) *model.WebhookDeliveryAttempt {
+	start := time.Now()
+	attempt := &model.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		CreatedAt:  start,
+	}
+	fail := func(err error) *model.WebhookDeliveryAttempt {
+		message := err.Error()
+		attempt.Error = &message
+		attempt.DurationMS = time.Since(start).Milliseconds()
+		return attempt
+	}
+
+	verifier, err := webhook.NewVerifier(endpoint.Secret)
+	if err != nil {
+		return fail(fmt.Errorf("invalid endpoint secret: %w", err))
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(fmt.Errorf("failed to create webhook request: %w", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("webhook-id", delivery.EventID)
+	req.Header.Set("webhook-timestamp", strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set("webhook-signature", verifier.Sign(delivery.EventID, start, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fail(fmt.Errorf("failed to send webhook: %w", err))
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt.StatusCode = &resp.StatusCode
+	// Text columns only take valid UTF-8 without NUL characters
+	attempt.ResponseBody = strings.ToValidUTF8(strings.ReplaceAll(string(response), "\x00", ""), "\uFFFD")
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fail(fmt.Errorf("endpoint responded with status %d", resp.StatusCode))
+	}
+
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	return attempt
+}
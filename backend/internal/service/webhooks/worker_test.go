@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nanayaw/fullstack/internal/config"
+	"github.com/nanayaw/fullstack/internal/model"
+	"github.com/nanayaw/fullstack/pkg/logger"
+	"github.com/nanayaw/fullstack/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWorker returns a worker delivering to url the events of a single
+// endpoint, with one event queued
+func newTestWorker(t *testing.T, url string, maxAttempts int) (*Worker, *fakeRepository, *model.WebhookEndpoint) {
+	s, repo, _ := newTestService()
+	ctx := context.Background()
+
+	_, endpoint, err := s.CreateEndpoint(ctx, model.WebhookEndpointInput{
+		URL:        url,
+		EventTypes: []string{model.WebhookEventUserVerified},
+	}, testActor)
+	require.NoError(t, err)
+	s.Dispatch(ctx, model.WebhookEventUserVerified, map[string]interface{}{"user_id": "user-1"})
+
+	cfg := &config.WebhooksConfig{
+		MaxAttempts:    maxAttempts,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Hour,
+		Timeout:        time.Second,
+	}
+	return NewWorker(repo, cfg, logger.DefaultLogger()), repo, endpoint
+}
+
+func TestWorkerDeliversSignedEvents(t *testing.T) {
+	// The secret is only known once the endpoint is created
+	var secret string
+	var verifyErr error
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		verifier, err := webhook.NewVerifier(secret)
+		if err == nil {
+			err = verifier.Verify(r.Header, body)
+		}
+		verifyErr = err
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	worker, repo, endpoint := newTestWorker(t, server.URL, 3)
+	secret = repo.endpoints[endpoint.ID].Secret
+
+	n, err := worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, verifyErr)
+	delivery := repo.deliveriesOf(endpoint.ID)[0]
+	assert.Equal(t, model.WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, *delivery.LastStatusCode)
+	assert.Equal(t, delivery.Payload, string(body))
+
+	require.Len(t, repo.attempts, 1)
+	assert.Equal(t, 1, repo.attempts[0].Attempt)
+	assert.Nil(t, repo.attempts[0].Error)
+}
+
+func TestWorkerRetriesAndDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("try again later"))
+	}))
+	defer server.Close()
+
+	worker, repo, endpoint := newTestWorker(t, server.URL, 2)
+	ctx := context.Background()
+
+	_, err := worker.ProcessBatch(ctx)
+	require.NoError(t, err)
+
+	delivery := repo.deliveriesOf(endpoint.ID)[0]
+	assert.Equal(t, model.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, *delivery.LastStatusCode)
+	assert.True(t, delivery.NextAttemptAt.After(time.Now().Add(59*time.Second)))
+
+	require.Len(t, repo.attempts, 1)
+	assert.Equal(t, "try again later", repo.attempts[0].ResponseBody)
+	assert.Equal(t, "endpoint responded with status 503", *repo.attempts[0].Error)
+
+	// The final attempt dead-letters the delivery
+	delivery.NextAttemptAt = time.Now()
+	_, err = worker.ProcessBatch(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, model.WebhookDeliveryDead, delivery.Status)
+	assert.Len(t, repo.attempts, 2)
+}
+
+func TestWorkerDoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	worker, repo, endpoint := newTestWorker(t, server.URL, 1)
+
+	_, err := worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	delivery := repo.deliveriesOf(endpoint.ID)[0]
+	assert.Equal(t, model.WebhookDeliveryDead, delivery.Status)
+	assert.Equal(t, http.StatusTemporaryRedirect, *delivery.LastStatusCode)
+}
+
+func TestWorkerDeadLettersDisabledEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("disabled endpoint was called")
+	}))
+	defer server.Close()
+
+	worker, repo, endpoint := newTestWorker(t, server.URL, 3)
+	repo.endpoints[endpoint.ID].Enabled = false
+
+	_, err := worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	delivery := repo.deliveriesOf(endpoint.ID)[0]
+	assert.Equal(t, model.WebhookDeliveryDead, delivery.Status)
+	assert.Equal(t, "endpoint is disabled", *delivery.LastError)
+	assert.Empty(t, repo.attempts)
+}
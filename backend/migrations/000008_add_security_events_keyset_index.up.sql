@@ -0,0 +1,2 @@
+-- Support keyset pagination of a user's security events, newest first
+CREATE INDEX IF NOT EXISTS idx_security_events_user_created_id ON security_events(user_id, created_at DESC, id DESC);
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_account_locks_unlock_token_hash;
+
+-- Drop columns
+ALTER TABLE account_locks DROP COLUMN IF EXISTS unlock_token_hash;
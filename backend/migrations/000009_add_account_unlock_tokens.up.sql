@@ -0,0 +1,4 @@
+-- Allow users to unlock their own account early from the account locked email
+ALTER TABLE account_locks ADD COLUMN IF NOT EXISTS unlock_token_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_locks_unlock_token_hash ON account_locks(unlock_token_hash);
@@ -0,0 +1,2 @@
+-- Language used for emails and other user-facing text (see pkg/i18n)
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT 'en';
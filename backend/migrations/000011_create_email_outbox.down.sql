@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_email_outbox_created_at;
+DROP INDEX IF EXISTS idx_email_outbox_status_next_attempt_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS email_outbox;
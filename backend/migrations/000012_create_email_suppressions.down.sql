@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_email_suppressions_created_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS email_suppressions;
@@ -0,0 +1,11 @@
+-- Create email_suppressions table
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR(255) NOT NULL UNIQUE,
+    reason VARCHAR(20) NOT NULL,
+    details TEXT,
+    event_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_suppressions_created_at ON email_suppressions(created_at);
@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_scheduled_emails_user_id_kind;
+DROP INDEX IF EXISTS idx_scheduled_emails_status_send_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS scheduled_emails;
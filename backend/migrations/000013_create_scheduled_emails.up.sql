@@ -0,0 +1,18 @@
+-- Create scheduled_emails table
+CREATE TABLE IF NOT EXISTS scheduled_emails (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    locale VARCHAR(10) NOT NULL DEFAULT 'en',
+    send_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    last_error TEXT,
+    locked_until TIMESTAMP,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_emails_status_send_at ON scheduled_emails(status, send_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_emails_user_id_kind ON scheduled_emails(user_id, kind);
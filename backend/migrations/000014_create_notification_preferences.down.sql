@@ -0,0 +1,5 @@
+-- Drop columns
+ALTER TABLE email_outbox DROP COLUMN headers;
+
+-- Drop tables
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,12 @@
+-- Create notification_preferences table
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, category, channel)
+);
+
+-- Headers such as List-Unsubscribe, as a JSON object
+ALTER TABLE email_outbox ADD COLUMN headers TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Disabled accounts can't sign in until an admin enables them again
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
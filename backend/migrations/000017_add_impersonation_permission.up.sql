@@ -0,0 +1,9 @@
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Sign in as another user')
+ON CONFLICT (name) DO NOTHING;
+
+-- The admin role has every permission
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name = 'users:impersonate'
+ON CONFLICT DO NOTHING;
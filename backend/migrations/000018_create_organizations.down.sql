@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_organization_invitations_expires_at;
+DROP INDEX IF EXISTS idx_organization_invitations_pending;
+DROP INDEX IF EXISTS idx_memberships_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,17 @@
+-- Create api_keys table
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- Public part of the key, used to look it up
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    -- SHA-256 of the secret part of the key
+    secret_hash VARCHAR(64) NOT NULL,
+    -- Space-separated scopes
+    scopes TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,16 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'service_accounts:manage');
+
+DELETE FROM permissions WHERE name = 'service_accounts:manage';
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_logs_service_account_id;
+DROP INDEX IF EXISTS idx_service_accounts_organization_id;
+
+-- Entries without a user can't be kept once user_id is required again
+DELETE FROM audit_logs WHERE user_id IS NULL;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS service_account_id;
+ALTER TABLE audit_logs ALTER COLUMN user_id SET NOT NULL;
+
+-- Drop tables
+DROP TABLE IF EXISTS service_accounts;
@@ -0,0 +1,38 @@
+-- Create service_accounts table
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    -- Owning organization, NULL for accounts owned by the admins
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Role in the owning organization, empty for accounts owned by the admins
+    role VARCHAR(20) NOT NULL DEFAULT '' CHECK (role IN ('', 'admin', 'member')),
+    -- Space-separated permissions of accounts owned by the admins
+    scopes TEXT NOT NULL DEFAULT '',
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    -- SHA-256 of the client secret
+    secret_hash VARCHAR(64) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((organization_id IS NULL) = (role = ''))
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_accounts_organization_id ON service_accounts(organization_id);
+
+-- Audit log entries are made by a user, a service account, or the admin API key
+ALTER TABLE audit_logs ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS service_account_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_service_account_id ON audit_logs(service_account_id);
+
+-- Permission to manage the service accounts of the admins
+INSERT INTO permissions (name, description) VALUES
+    ('service_accounts:manage', 'Create and delete service accounts')
+ON CONFLICT (name) DO NOTHING;
+
+-- The admin role has every permission
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name = 'service_accounts:manage'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'audit_logs:read');
+DELETE FROM permissions WHERE name = 'audit_logs:read';
+
+DROP INDEX IF EXISTS idx_audit_logs_action;
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_audit_logs_sequence;
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS sequence;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS request_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS actor;
+
+-- Entries of users deleted since are kept rather than checked
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE NOT VALID;
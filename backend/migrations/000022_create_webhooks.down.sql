@@ -0,0 +1,14 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'webhooks:manage');
+
+DELETE FROM permissions WHERE name = 'webhooks:manage';
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_webhook_delivery_attempts_delivery_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_endpoint_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_status_next_attempt_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
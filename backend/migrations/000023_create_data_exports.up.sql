@@ -0,0 +1,18 @@
+-- Create data_exports table, the archives users request of their data
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- pending until the export job builds the archive, then ready or failed
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    -- ZIP of JSON files, kept until the download link expires
+    archive BYTEA,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports(status);
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at);
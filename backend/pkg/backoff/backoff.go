@@ -0,0 +1,24 @@
+// Package backoff computes the delays between retries of failed work, such as
+// sending an email or delivering a webhook.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Delay returns how long to wait after the given number of failed attempts:
+// base doubled for every attempt after the first, capped at max, plus up to
+// 20% jitter so work that failed together is not retried together
+func Delay(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	// #nosec G404 - jitter does not need a secure random source
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,28 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	base, max := 30*time.Second, time.Hour
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		delay := Delay(tt.attempts, base, max)
+		assert.GreaterOrEqual(t, delay, tt.want, "attempts %d", tt.attempts)
+		assert.LessOrEqual(t, delay, tt.want+tt.want/5, "attempts %d", tt.attempts)
+	}
+}
@@ -0,0 +1,208 @@
+// Package dkim signs outgoing email with DomainKeys Identified Mail (RFC 6376)
+// signatures, using relaxed/relaxed canonicalization and either RSA-SHA256 or
+// Ed25519-SHA256 (RFC 8463) keys.
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHeaders are the headers signed when they are present in a message
+var DefaultHeaders = []string{
+	"From", "To", "Cc", "Subject", "Date", "Message-ID", "Reply-To",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// Signer adds DKIM-Signature headers to messages
+type Signer struct {
+	domain    string
+	selector  string
+	key       crypto.Signer
+	algorithm string
+	headers   []string
+	now       func() time.Time
+}
+
+// NewSigner creates a signer for domain and selector from a PEM-encoded RSA
+// (PKCS #1 or PKCS #8) or Ed25519 (PKCS #8) private key
+func NewSigner(domain, selector string, privateKeyPEM []byte) (*Signer, error) {
+	if domain == "" || selector == "" {
+		return nil, fmt.Errorf("DKIM domain and selector are required")
+	}
+
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode DKIM private key PEM")
+	}
+
+	var key interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DKIM private key: %w", err)
+	}
+
+	s := &Signer{
+		domain:   domain,
+		selector: selector,
+		headers:  DefaultHeaders,
+		now:      time.Now,
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s.key, s.algorithm = k, "rsa-sha256"
+	case ed25519.PrivateKey:
+		s.key, s.algorithm = k, "ed25519-sha256"
+	default:
+		return nil, fmt.Errorf("unsupported DKIM private key type %T", key)
+	}
+
+	return s, nil
+}
+
+// Sign returns message, which must use CRLF line endings, with a
+// DKIM-Signature header prepended
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	header, body := splitMessage(message)
+
+	bodyHash := sha256.Sum256(canonicalBody(body))
+	fields := parseHeader(header)
+
+	// Sign the last occurrence of every header we want that is present
+	var signed []string
+	var hashInput bytes.Buffer
+	for _, name := range s.headers {
+		if field, ok := lastField(fields, name); ok {
+			signed = append(signed, strings.ToLower(name))
+			hashInput.WriteString(canonicalHeader(field))
+		}
+	}
+	if len(signed) == 0 {
+		return nil, fmt.Errorf("message has none of the headers to sign")
+	}
+
+	value := fmt.Sprintf(
+		"v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		s.algorithm, s.domain, s.selector, s.now().Unix(),
+		strings.Join(signed, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+
+	// The signature header itself is hashed last, without its trailing CRLF
+	hashInput.WriteString(strings.TrimSuffix(canonicalHeader("DKIM-Signature: "+value), "\r\n"))
+	digest := sha256.Sum256(hashInput.Bytes())
+
+	var signature []byte
+	var err error
+	switch s.key.(type) {
+	case ed25519.PrivateKey:
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	default:
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	signedMessage := make([]byte, 0, len(message)+512)
+	signedMessage = append(signedMessage, "DKIM-Signature: "+value+base64.StdEncoding.EncodeToString(signature)+"\r\n"...)
+	signedMessage = append(signedMessage, message...)
+
+	return signedMessage, nil
+}
+
+// splitMessage splits a message into its header (including the final CRLF of
+// the last field) and body
+func splitMessage(message []byte) (string, []byte) {
+	if i := bytes.Index(message, []byte("\r\n\r\n")); i >= 0 {
+		return string(message[:i+2]), message[i+4:]
+	}
+	return string(message), nil
+}
+
+// parseHeader splits a header into its fields, keeping folded lines together
+func parseHeader(header string) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+// lastField returns the last field with the given name
+func lastField(fields []string, name string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if colon := strings.IndexByte(fields[i], ':'); colon > 0 {
+			if strings.EqualFold(strings.TrimSpace(fields[i][:colon]), name) {
+				return fields[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// canonicalHeader applies the "relaxed" header canonicalization to a field
+func canonicalHeader(field string) string {
+	colon := strings.IndexByte(field, ':')
+	name := strings.ToLower(strings.TrimSpace(field[:colon]))
+
+	value := strings.NewReplacer("\r\n", "").Replace(field[colon+1:])
+	value = strings.Join(strings.Fields(value), " ")
+
+	return name + ":" + value + "\r\n"
+}
+
+// canonicalBody applies the "relaxed" body canonicalization
+func canonicalBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	var out bytes.Buffer
+	for _, line := range lines {
+		// Collapse whitespace runs and drop trailing whitespace
+		var b strings.Builder
+		space := false
+		for _, r := range line {
+			if r == ' ' || r == '\t' {
+				space = true
+				continue
+			}
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+		out.WriteString(b.String())
+		out.WriteString("\r\n")
+	}
+
+	// Ignore empty lines at the end of the body
+	canonical := bytes.TrimRight(out.Bytes(), "\r\n")
+	if len(canonical) == 0 {
+		return nil
+	}
+	return append(canonical, '\r', '\n')
+}
@@ -0,0 +1,108 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMessage = "From: App <noreply@example.com>\r\n" +
+	"To: user@example.com\r\n" +
+	"Subject: Verify your\r\n\temail address\r\n" +
+	"Date: Sun, 01 Jan 2023 12:00:00 +0000\r\n" +
+	"X-Unsigned: not signed\r\n" +
+	"\r\n" +
+	"Hello  there \r\n" +
+	"\r\n" +
+	"\r\n"
+
+// verify checks a signed message against the signing key, recomputing the
+// body hash and header hash from the DKIM-Signature tags
+func verify(t *testing.T, signed []byte, public crypto.PublicKey) {
+	t.Helper()
+
+	header, body := splitMessage(signed)
+	fields := parseHeader(header)
+	require.True(t, strings.HasPrefix(fields[0], "DKIM-Signature: "))
+
+	tags := map[string]string{}
+	for _, tag := range strings.Split(strings.TrimSpace(strings.TrimPrefix(fields[0], "DKIM-Signature: ")), "; ") {
+		kv := strings.SplitN(tag, "=", 2)
+		tags[kv[0]] = kv[1]
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(body))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(bodyHash[:]), tags["bh"])
+
+	var hashInput strings.Builder
+	for _, name := range strings.Split(tags["h"], ":") {
+		field, ok := lastField(fields[1:], name)
+		require.True(t, ok, "signed header %s missing", name)
+		hashInput.WriteString(canonicalHeader(field))
+	}
+	unsigned := strings.TrimSuffix(fields[0], "\r\n")
+	unsigned = unsigned[:strings.LastIndex(unsigned, "b=")+2]
+	hashInput.WriteString(strings.TrimSuffix(canonicalHeader(unsigned), "\r\n"))
+	digest := sha256.Sum256([]byte(hashInput.String()))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	require.NoError(t, err)
+
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		assert.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+	case ed25519.PublicKey:
+		assert.True(t, ed25519.Verify(key, digest[:], signature))
+	}
+}
+
+func TestSignRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	signer, err := NewSigner("example.com", "mail", keyPEM)
+	require.NoError(t, err)
+	signer.now = func() time.Time { return time.Unix(1672574400, 0) }
+
+	signed, err := signer.Sign([]byte(testMessage))
+	require.NoError(t, err)
+
+	assert.Contains(t, string(signed), "a=rsa-sha256; c=relaxed/relaxed; d=example.com; s=mail; t=1672574400; h=from:to:subject:date;")
+	assert.True(t, strings.HasSuffix(string(signed), testMessage))
+	verify(t, signed, &key.PublicKey)
+}
+
+func TestSignEd25519(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	signer, err := NewSigner("example.com", "mail", keyPEM)
+	require.NoError(t, err)
+
+	signed, err := signer.Sign([]byte(testMessage))
+	require.NoError(t, err)
+
+	assert.Contains(t, string(signed), "a=ed25519-sha256;")
+	verify(t, signed, public)
+}
+
+func TestCanonicalization(t *testing.T) {
+	assert.Equal(t, "subject:Verify your email address\r\n", canonicalHeader("Subject : Verify  your\r\n\temail address \r\n"))
+	assert.Equal(t, " Hello there\r\n", string(canonicalBody([]byte("\t Hello  there \r\n\r\n\r\n"))))
+	assert.Nil(t, canonicalBody([]byte("\r\n\r\n")))
+}
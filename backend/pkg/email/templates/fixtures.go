@@ -0,0 +1,108 @@
+package templates
+
+// FixtureData returns sample data for every template, keyed by template name,
+// for rendering previews and golden files. The data is fixed so renders are
+// reproducible.
+func FixtureData(locale string) map[string]interface{} {
+	base := TemplateData{
+		Locale:       locale,
+		AppName:      "Go+Next",
+		Year:         2024,
+		SupportEmail: "support@example.com",
+		BaseURL:      "https://example.com",
+	}
+
+	// Emails the recipient can opt out of link to the unsubscribe page
+	optional := base
+	optional.UnsubscribeURL = "https://example.com/unsubscribe?token=abc123"
+
+	return map[string]interface{}{
+		TemplateVerification: VerificationData{
+			TemplateData:    base,
+			UserName:        "Jane Doe",
+			VerificationURL: "https://example.com/verify?token=abc123",
+			ExpiresIn:       "24 hours",
+		},
+		TemplatePasswordReset: PasswordResetData{
+			TemplateData:  base,
+			UserName:      "Jane Doe",
+			ResetURL:      "https://example.com/reset?token=abc123",
+			ExpiresIn:     "1 hour",
+			RequestedFrom: "Chrome on macOS",
+			RequestTime:   "January 2, 2024 at 3:04 PM UTC",
+		},
+		TemplateWelcome: WelcomeData{
+			TemplateData: base,
+			UserName:     "Jane Doe",
+		},
+		TemplateLoginNotification: LoginNotificationData{
+			TemplateData: optional,
+			UserName:     "Jane Doe",
+			DeviceInfo:   "Chrome on macOS",
+			Location:     "Accra, Ghana",
+			IPAddress:    "203.0.113.42",
+			Time:         "January 2, 2024 at 3:04 PM UTC",
+			UserAgent:    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		},
+		TemplatePasswordChanged: PasswordChangedData{
+			TemplateData: base,
+			UserName:     "Jane Doe",
+			DeviceInfo:   "Chrome on macOS",
+			Location:     "Accra, Ghana",
+			Time:         "January 2, 2024 at 3:04 PM UTC",
+		},
+		TemplateAccountLocked: AccountLockedData{
+			TemplateData: base,
+			UserName:     "Jane Doe",
+			UnlockTime:   "15 minutes",
+			FailedLogins: 5,
+			UnlockURL:    "https://example.com/unlock-account?token=abc123",
+		},
+		TemplateSuspiciousActivity: SuspiciousActivityData{
+			TemplateData: base,
+			UserName:     "Jane Doe",
+			ActivityType: "Login from a new country",
+			DeviceInfo:   "Firefox on Windows",
+			Location:     "Lagos, Nigeria",
+			Time:         "January 2, 2024 at 3:04 PM UTC",
+			IPAddress:    "198.51.100.7",
+		},
+		TemplateOnboardingReminder: OnboardingReminderData{
+			TemplateData:    optional,
+			UserName:        "Jane Doe",
+			VerificationURL: "https://example.com/verify?token=abc123",
+			ExpiresIn:       "24 hours",
+		},
+		TemplateSecurityDigest: SecurityDigestData{
+			TemplateData: optional,
+			UserName:     "Jane Doe",
+			From:         "December 26, 2023",
+			To:           "January 2, 2024",
+			Summary: []SecurityDigestItem{
+				{Label: "Successful sign-ins", Count: 12},
+				{Label: "Failed sign-in attempts", Count: 3},
+				{Label: "Sign-ins from a new device", Count: 1},
+			},
+			Events: []SecurityDigestEvent{
+				{
+					Label:     "Sign-in from a new device",
+					Time:      "January 2, 2024 at 3:04 PM UTC",
+					Location:  "Lagos, Nigeria",
+					IPAddress: "198.51.100.7",
+				},
+			},
+		},
+		TemplateOrganizationInvitation: OrganizationInvitationData{
+			TemplateData:     base,
+			OrganizationName: "Acme",
+			InviterName:      "John Smith",
+			AcceptURL:        "https://example.com/accept-invitation?token=abc123",
+			ExpiresIn:        "7 days",
+		},
+		TemplateDataExportReady: DataExportReadyData{
+			TemplateData: base,
+			DownloadURL:  "https://example.com/api/v1/exports/download?token=abc123",
+			ExpiresIn:    "7 days",
+		},
+	}
+}
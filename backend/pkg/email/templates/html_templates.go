@@ -3,11 +3,11 @@ package templates
 // HTML email templates with modern, responsive design
 
 const verificationHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Verify Your Email</title>
+    <title>{{t "verification.title"}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -68,30 +68,30 @@ const verificationHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Verify Your Email Address</h1>
+            <h1>{{t "verification.title"}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p>Thank you for signing up for {{.AppName}}. To complete your registration and verify your email address, please click the button below:</p>
+        <p>{{t "verification.intro" .AppName}}</p>
         
         <div style="text-align: center;">
-            <a href="{{.VerificationURL}}" class="button">Verify Email Address</a>
+            <a href="{{.VerificationURL}}" class="button">{{t "verification.button"}}</a>
         </div>
         
-        <p class="expires">This verification link will expire in {{.ExpiresIn}}.</p>
+        <p class="expires">{{t "verification.expires" .ExpiresIn}}</p>
         
-        <p>If you didn't create an account with {{.AppName}}, you can safely ignore this email.</p>
+        <p>{{t "verification.ignore" .AppName}}</p>
         
-        <p>If you're having trouble clicking the button, copy and paste the following URL into your web browser:</p>
+        <p>{{t "common.button_trouble"}}</p>
         <p style="word-break: break-all; font-size: 14px;">{{.VerificationURL}}</p>
         
         <div class="help">
-            <p>Need help? Contact our support team at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "common.need_help" (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>
         </div>
     </div>
 </body>
@@ -99,11 +99,11 @@ const verificationHTMLTemplate = `<!DOCTYPE html>
 
 // #nosec G101 - This is a template for password reset emails, not a hardcoded credential
 const passwordResetHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Reset Your Password</title>
+    <title>{{t "password_reset.title"}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -171,47 +171,49 @@ const passwordResetHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Reset Your Password</h1>
+            <h1>{{t "password_reset.title"}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p>We received a request to reset your password for your {{.AppName}} account. To reset your password, please click the button below:</p>
+        <p>{{t "password_reset.intro" .AppName}}</p>
         
         <div style="text-align: center;">
-            <a href="{{.ResetURL}}" class="button">Reset Password</a>
+            <a href="{{.ResetURL}}" class="button">{{t "password_reset.button"}}</a>
         </div>
         
-        <p class="expires">This password reset link will expire in {{.ExpiresIn}}.</p>
+        <p class="expires">{{t "password_reset.expires" .ExpiresIn}}</p>
         
+        {{if .RequestedFrom}}
         <div class="security-info">
-            <p><strong>Security Information:</strong></p>
-            <p>This request was made from: {{.RequestedFrom}}</p>
-            <p>Time of request: {{.RequestTime}}</p>
+            <p><strong>{{t "password_reset.security_info"}}</strong></p>
+            <p>{{t "password_reset.requested_from" .RequestedFrom}}</p>
+            <p>{{t "password_reset.request_time" .RequestTime}}</p>
         </div>
+        {{end}}
         
-        <p>If you didn't request a password reset, please ignore this email or contact our support team if you have concerns about your account security.</p>
+        <p>{{t "password_reset.ignore"}}</p>
         
-        <p>If you're having trouble clicking the button, copy and paste the following URL into your web browser:</p>
+        <p>{{t "common.button_trouble"}}</p>
         <p style="word-break: break-all; font-size: 14px;">{{.ResetURL}}</p>
         
         <div class="help">
-            <p>Need help? Contact our support team at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "common.need_help" (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>
         </div>
     </div>
 </body>
 </html>`
 
 const welcomeHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Welcome to {{.AppName}}</title>
+    <title>{{t "welcome.subject" .AppName}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -273,47 +275,47 @@ const welcomeHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Welcome to {{.AppName}}!</h1>
+            <h1>{{t "welcome.title" .AppName}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p>Thank you for joining {{.AppName}}! We're excited to have you on board.</p>
+        <p>{{t "welcome.intro" .AppName}}</p>
         
         <div class="feature-list">
-            <h3>Here are a few things you can do with your new account:</h3>
+            <h3>{{t "welcome.features"}}</h3>
             <div class="feature-item">
-                <p>✅ <strong>Complete your profile</strong> - Add your information to get the most out of our platform.</p>
+                <p>✅ <strong>{{t "welcome.feature_profile_title"}}</strong> - {{t "welcome.feature_profile"}}</p>
             </div>
             <div class="feature-item">
-                <p>✅ <strong>Explore our features</strong> - Discover all the tools and services we offer.</p>
+                <p>✅ <strong>{{t "welcome.feature_explore_title"}}</strong> - {{t "welcome.feature_explore"}}</p>
             </div>
             <div class="feature-item">
-                <p>✅ <strong>Connect with others</strong> - Build your network and collaborate with like-minded individuals.</p>
+                <p>✅ <strong>{{t "welcome.feature_connect_title"}}</strong> - {{t "welcome.feature_connect"}}</p>
             </div>
         </div>
         
         <div style="text-align: center;">
-            <a href="{{.BaseURL}}/dashboard" class="button">Go to Dashboard</a>
+            <a href="{{.BaseURL}}/dashboard" class="button">{{t "welcome.button"}}</a>
         </div>
         
         <div class="help">
-            <p>Need help getting started? Check out our <a href="{{.BaseURL}}/help">Help Center</a> or contact our support team at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "welcome.help" (link (printf "%s/help" .BaseURL) (t "welcome.help_center")) (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>
         </div>
     </div>
 </body>
 </html>`
 
 const loginNotificationHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>New Login Detected</title>
+    <title>{{t "login_notification.title"}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -383,48 +385,57 @@ const loginNotificationHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>New Login to Your Account</h1>
+            <h1>{{t "login_notification.title"}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p>We detected a new login to your {{.AppName}} account. If this was you, no action is needed.</p>
+        <p>{{t "login_notification.intro" .AppName}}</p>
         
         <div class="login-details">
-            <h3>Login Details:</h3>
+            <h3>{{t "login_notification.details"}}</h3>
+            {{if .DeviceInfo}}
             <div class="detail-row">
-                <div class="detail-label">Device:</div>
+                <div class="detail-label">{{t "common.device"}}</div>
                 <div>{{.DeviceInfo}}</div>
             </div>
+            {{end}}
+            {{if .Location}}
             <div class="detail-row">
-                <div class="detail-label">Location:</div>
+                <div class="detail-label">{{t "common.location"}}</div>
                 <div>{{.Location}}</div>
             </div>
+            {{end}}
+            {{if .IPAddress}}
             <div class="detail-row">
-                <div class="detail-label">IP Address:</div>
+                <div class="detail-label">{{t "common.ip_address"}}</div>
                 <div>{{.IPAddress}}</div>
             </div>
+            {{end}}
             <div class="detail-row">
-                <div class="detail-label">Time:</div>
+                <div class="detail-label">{{t "common.time"}}</div>
                 <div>{{.Time}}</div>
             </div>
+            {{if .UserAgent}}
             <div class="detail-row">
-                <div class="detail-label">Browser:</div>
+                <div class="detail-label">{{t "common.browser"}}</div>
                 <div>{{.UserAgent}}</div>
             </div>
+            {{end}}
         </div>
         
-        <p><strong>If you don't recognize this activity:</strong></p>
+        <p><strong>{{t "login_notification.unrecognized"}}</strong></p>
         <div style="text-align: center;">
-            <a href="{{.BaseURL}}/account/security" class="button">Secure Your Account</a>
+            <a href="{{.BaseURL}}/account/security" class="button">{{t "common.secure_account"}}</a>
         </div>
         
         <div class="help">
-            <p>If you didn't authorize this login, please change your password immediately and contact our support team at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "login_notification.help" (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>{{if .UnsubscribeURL}}
+            <p>{{t "common.unsubscribe" (link .UnsubscribeURL (t "common.unsubscribe_link"))}}</p>{{end}}
         </div>
     </div>
 </body>
@@ -432,11 +443,11 @@ const loginNotificationHTMLTemplate = `<!DOCTYPE html>
 
 // #nosec G101 - This is a template for password changed emails, not a hardcoded credential
 const passwordChangedHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Password Changed</title>
+    <title>{{t "password_changed.title"}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -506,51 +517,55 @@ const passwordChangedHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Your Password Has Been Changed</h1>
+            <h1>{{t "password_changed.title"}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p>This email confirms that your password for your {{.AppName}} account has been successfully changed.</p>
+        <p>{{t "password_changed.intro" .AppName}}</p>
         
         <div class="change-details">
-            <h3>Change Details:</h3>
+            <h3>{{t "password_changed.details"}}</h3>
+            {{if .DeviceInfo}}
             <div class="detail-row">
-                <div class="detail-label">Device:</div>
+                <div class="detail-label">{{t "common.device"}}</div>
                 <div>{{.DeviceInfo}}</div>
             </div>
+            {{end}}
+            {{if .Location}}
             <div class="detail-row">
-                <div class="detail-label">Location:</div>
+                <div class="detail-label">{{t "common.location"}}</div>
                 <div>{{.Location}}</div>
             </div>
+            {{end}}
             <div class="detail-row">
-                <div class="detail-label">Time:</div>
+                <div class="detail-label">{{t "common.time"}}</div>
                 <div>{{.Time}}</div>
             </div>
         </div>
         
-        <p><strong>If you didn't make this change:</strong></p>
+        <p><strong>{{t "password_changed.not_you"}}</strong></p>
         <div style="text-align: center;">
-            <a href="{{.BaseURL}}/account/security" class="button">Secure Your Account</a>
+            <a href="{{.BaseURL}}/account/security" class="button">{{t "common.secure_account"}}</a>
         </div>
         
         <div class="help">
-            <p>If you didn't authorize this password change, please contact our support team immediately at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "password_changed.help" (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>
         </div>
     </div>
 </body>
 </html>`
 
 const accountLockedHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Account Temporarily Locked</title>
+    <title>{{t "account_locked.title"}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -612,42 +627,48 @@ const accountLockedHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Your Account Has Been Temporarily Locked</h1>
+            <h1>{{t "account_locked.title"}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p>For your security, we've temporarily locked your {{.AppName}} account due to multiple failed login attempts.</p>
+        <p>{{t "account_locked.intro" .AppName}}</p>
         
         <div class="lock-details">
-            <h3>Lock Details:</h3>
-            <p><strong>Failed Login Attempts:</strong> {{.FailedLogins}}</p>
-            <p><strong>Account Will Unlock:</strong> {{.UnlockTime}}</p>
+            <h3>{{t "account_locked.details"}}</h3>
+            <p><strong>{{t "account_locked.failed_logins"}}</strong> {{.FailedLogins}}</p>
+            <p><strong>{{t "account_locked.unlocks_at"}}</strong> {{.UnlockTime}}</p>
         </div>
+        {{if .UnlockURL}}
+        <p>{{t "account_locked.unlock_intro"}}</p>
         
-        <p>If you were trying to log in, please wait until the account unlocks and try again with the correct password. If you've forgotten your password, you can reset it using the button below:</p>
+        <div style="text-align: center;">
+            <a href="{{.UnlockURL}}" class="button">{{t "account_locked.unlock_button"}}</a>
+        </div>
+        {{end}}
+        <p>{{t "account_locked.wait"}}</p>
         
         <div style="text-align: center;">
-            <a href="{{.BaseURL}}/reset-password" class="button">Reset Password</a>
+            <a href="{{.BaseURL}}/reset-password" class="button">{{t "password_reset.button"}}</a>
         </div>
         
         <div class="help">
-            <p>If you didn't attempt to log in and believe someone else might be trying to access your account, please contact our support team immediately at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "account_locked.help" (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>
         </div>
     </div>
 </body>
 </html>`
 
 const suspiciousActivityHTMLTemplate = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Suspicious Activity Detected</title>
+    <title>{{t "suspicious_activity.title"}}</title>
     <style>
         body {
             font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
@@ -721,48 +742,458 @@ const suspiciousActivityHTMLTemplate = `<!DOCTYPE html>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Suspicious Activity Detected</h1>
+            <h1>{{t "suspicious_activity.title"}}</h1>
         </div>
         
-        <p>Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},</p>
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
         
-        <p class="warning">We've detected suspicious activity on your {{.AppName}} account that requires your immediate attention.</p>
+        <p class="warning">{{t "suspicious_activity.intro" .AppName}}</p>
         
         <div class="activity-details">
-            <h3>Activity Details:</h3>
+            <h3>{{t "suspicious_activity.details"}}</h3>
             <div class="detail-row">
-                <div class="detail-label">Activity:</div>
+                <div class="detail-label">{{t "common.activity"}}</div>
                 <div>{{.ActivityType}}</div>
             </div>
+            {{if .DeviceInfo}}
             <div class="detail-row">
-                <div class="detail-label">Device:</div>
+                <div class="detail-label">{{t "common.device"}}</div>
                 <div>{{.DeviceInfo}}</div>
             </div>
+            {{end}}
+            {{if .Location}}
             <div class="detail-row">
-                <div class="detail-label">Location:</div>
+                <div class="detail-label">{{t "common.location"}}</div>
                 <div>{{.Location}}</div>
             </div>
+            {{end}}
+            {{if .IPAddress}}
             <div class="detail-row">
-                <div class="detail-label">IP Address:</div>
+                <div class="detail-label">{{t "common.ip_address"}}</div>
                 <div>{{.IPAddress}}</div>
             </div>
+            {{end}}
             <div class="detail-row">
-                <div class="detail-label">Time:</div>
+                <div class="detail-label">{{t "common.time"}}</div>
                 <div>{{.Time}}</div>
             </div>
         </div>
         
-        <p><strong>For your security, we recommend taking immediate action:</strong></p>
+        <p><strong>{{t "suspicious_activity.action"}}</strong></p>
+        <div style="text-align: center;">
+            <a href="{{.BaseURL}}/account/security" class="button">{{t "common.secure_account"}}</a>
+        </div>
+        
+        <div class="help">
+            <p>{{t "suspicious_activity.help" (mailto .SupportEmail)}}</p>
+        </div>
+        
+        <div class="footer">
+            <p>{{t "common.copyright" .Year .AppName}}</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+const onboardingReminderHTMLTemplate = `<!DOCTYPE html>
+<html lang="{{locale}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{t "onboarding_reminder.title"}}</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0, 0, 0, 0.1);
+            padding: 30px;
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        h1 {
+            color: #2563eb;
+            margin-bottom: 20px;
+        }
+        .button {
+            display: inline-block;
+            background-color: #2563eb;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            font-weight: bold;
+            margin: 20px 0;
+        }
+        .button:hover {
+            background-color: #1d4ed8;
+        }
+        .footer {
+            margin-top: 30px;
+            text-align: center;
+            font-size: 12px;
+            color: #6b7280;
+        }
+        .expires {
+            font-style: italic;
+            margin: 20px 0;
+            color: #6b7280;
+        }
+        .help {
+            margin-top: 20px;
+            font-size: 14px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{t "onboarding_reminder.title"}}</h1>
+        </div>
+        
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
+        
+        <p>{{t "onboarding_reminder.intro" .AppName}}</p>
+        
+        <div style="text-align: center;">
+            <a href="{{.VerificationURL}}" class="button">{{t "verification.button"}}</a>
+        </div>
+        
+        <p class="expires">{{t "verification.expires" .ExpiresIn}}</p>
+        
+        <p>{{t "verification.ignore" .AppName}}</p>
+        
+        <p>{{t "common.button_trouble"}}</p>
+        <p style="word-break: break-all; font-size: 14px;">{{.VerificationURL}}</p>
+        
+        <div class="help">
+            <p>{{t "common.need_help" (mailto .SupportEmail)}}</p>
+        </div>
+        
+        <div class="footer">
+            <p>{{t "common.copyright" .Year .AppName}}</p>{{if .UnsubscribeURL}}
+            <p>{{t "common.unsubscribe" (link .UnsubscribeURL (t "common.unsubscribe_link"))}}</p>{{end}}
+        </div>
+    </div>
+</body>
+</html>`
+
+const securityDigestHTMLTemplate = `<!DOCTYPE html>
+<html lang="{{locale}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{t "security_digest.title"}}</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0, 0, 0, 0.1);
+            padding: 30px;
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        h1 {
+            color: #2563eb;
+            margin-bottom: 20px;
+        }
+        .button {
+            display: inline-block;
+            background-color: #2563eb;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            font-weight: bold;
+            margin: 20px 0;
+        }
+        .button:hover {
+            background-color: #1d4ed8;
+        }
+        .footer {
+            margin-top: 30px;
+            text-align: center;
+            font-size: 12px;
+            color: #6b7280;
+        }
+        .summary {
+            background-color: #f9fafb;
+            padding: 20px;
+            border-radius: 4px;
+            margin: 20px 0;
+        }
+        .detail-row {
+            display: flex;
+            margin-bottom: 10px;
+        }
+        .detail-label {
+            font-weight: bold;
+            width: 120px;
+        }
+        .event {
+            border-left: 3px solid #f59e0b;
+            padding-left: 15px;
+            margin-bottom: 15px;
+        }
+        .help {
+            margin-top: 20px;
+            font-size: 14px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{t "security_digest.title"}}</h1>
+        </div>
+        
+        <p>{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}</p>
+        
+        <p>{{t "security_digest.intro" .AppName .From .To}}</p>
+        
+        <div class="summary">
+            <h3>{{t "security_digest.summary"}}</h3>
+            {{range .Summary}}
+            <div class="detail-row">
+                <div class="detail-label">{{.Count}}</div>
+                <div>{{.Label}}</div>
+            </div>
+            {{end}}
+        </div>
+        
+        {{if .Events}}
+        <h3>{{t "security_digest.review"}}</h3>
+        {{range .Events}}
+        <div class="event">
+            <strong>{{.Label}}</strong><br>
+            {{t "common.time"}} {{.Time}}<br>
+            {{if .Location}}{{t "common.location"}} {{.Location}}<br>{{end}}
+            {{if .IPAddress}}{{t "common.ip_address"}} {{.IPAddress}}{{end}}
+        </div>
+        {{end}}
+        {{end}}
+        
+        <p>{{t "security_digest.action"}}</p>
+        <div style="text-align: center;">
+            <a href="{{.BaseURL}}/account/security" class="button">{{t "security_digest.button"}}</a>
+        </div>
+        
+        <div class="help">
+            <p>{{t "common.need_help" (mailto .SupportEmail)}}</p>
+        </div>
+        
+        <div class="footer">
+            <p>{{t "common.copyright" .Year .AppName}}</p>{{if .UnsubscribeURL}}
+            <p>{{t "common.unsubscribe" (link .UnsubscribeURL (t "common.unsubscribe_link"))}}</p>{{end}}
+        </div>
+    </div>
+</body>
+</html>`
+
+const organizationInvitationHTMLTemplate = `<!DOCTYPE html>
+<html lang="{{locale}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{t "organization_invitation.title" .OrganizationName}}</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0, 0, 0, 0.1);
+            padding: 30px;
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 20px;
+        }
+        h1 {
+            color: #2563eb;
+            margin-bottom: 20px;
+        }
+        .button {
+            display: inline-block;
+            background-color: #2563eb;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            font-weight: bold;
+            margin: 20px 0;
+        }
+        .button:hover {
+            background-color: #1d4ed8;
+        }
+        .footer {
+            margin-top: 30px;
+            text-align: center;
+            font-size: 12px;
+            color: #6b7280;
+        }
+        .expires {
+            font-style: italic;
+            margin: 20px 0;
+            color: #6b7280;
+        }
+        .help {
+            margin-top: 20px;
+            font-size: 14px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{t "organization_invitation.title" .OrganizationName}}</h1>
+        </div>
+        
+        <p>{{t "common.greeting"}}</p>
+        
+        <p>{{t "organization_invitation.intro" .InviterName .OrganizationName .AppName}}</p>
+        
+        <div style="text-align: center;">
+            <a href="{{.AcceptURL}}" class="button">{{t "organization_invitation.button"}}</a>
+        </div>
+        
+        <p class="expires">{{t "organization_invitation.expires" .ExpiresIn}}</p>
+        
+        <p>{{t "organization_invitation.ignore"}}</p>
+        
+        <p>{{t "common.button_trouble"}}</p>
+        <p style="word-break: break-all; font-size: 14px;">{{.AcceptURL}}</p>
+        
+        <div class="help">
+            <p>{{t "common.need_help" (mailto .SupportEmail)}}</p>
+        </div>
+        
+        <div class="footer">
+            <p>{{t "common.copyright" .Year .AppName}}</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+const dataExportReadyHTMLTemplate = `<!DOCTYPE html>
+<html lang="{{locale}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{t "data_export_ready.title"}}</title>
+    <style>
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0, 0, 0, 0.1);
+            padding: 30px;
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        .logo {
+            max-width: 150px;
+            margin-bottom: 20px;
+        }
+        h1 {
+            color: #2563eb;
+            margin-bottom: 20px;
+        }
+        .button {
+            display: inline-block;
+            background-color: #2563eb;
+            color: white;
+            text-decoration: none;
+            padding: 12px 24px;
+            border-radius: 4px;
+            font-weight: bold;
+            margin: 20px 0;
+        }
+        .button:hover {
+            background-color: #1d4ed8;
+        }
+        .footer {
+            margin-top: 30px;
+            text-align: center;
+            font-size: 12px;
+            color: #6b7280;
+        }
+        .expires {
+            font-style: italic;
+            margin: 20px 0;
+            color: #6b7280;
+        }
+        .help {
+            margin-top: 20px;
+            font-size: 14px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{t "data_export_ready.title"}}</h1>
+        </div>
+        
+        <p>{{t "common.greeting"}}</p>
+        
+        <p>{{t "data_export_ready.intro" .AppName}}</p>
+        
         <div style="text-align: center;">
-            <a href="{{.BaseURL}}/account/security" class="button">Secure Your Account</a>
+            <a href="{{.DownloadURL}}" class="button">{{t "data_export_ready.button"}}</a>
         </div>
         
+        <p class="expires">{{t "data_export_ready.expires" .ExpiresIn}}</p>
+        
+        <p>{{t "data_export_ready.ignore"}}</p>
+        
+        <p>{{t "common.button_trouble"}}</p>
+        <p style="word-break: break-all; font-size: 14px;">{{.DownloadURL}}</p>
+        
         <div class="help">
-            <p>If you recognize this activity, you can safely ignore this email. If not, please change your password immediately and contact our support team at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a>.</p>
+            <p>{{t "common.need_help" (mailto .SupportEmail)}}</p>
         </div>
         
         <div class="footer">
-            <p>&copy; {{.Year}} {{.AppName}}. All rights reserved.</p>
+            <p>{{t "common.copyright" .Year .AppName}}</p>
         </div>
     </div>
 </body>
@@ -0,0 +1,318 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/nanayaw/fullstack/pkg/i18n"
+)
+
+// Template names accepted by Renderer.Render
+const (
+	TemplateVerification           = "verification"
+	TemplatePasswordReset          = "password_reset"
+	TemplateWelcome                = "welcome"
+	TemplateLoginNotification      = "login_notification"
+	TemplatePasswordChanged        = "password_changed"
+	TemplateAccountLocked          = "account_locked"
+	TemplateSuspiciousActivity     = "suspicious_activity"
+	TemplateOnboardingReminder     = "onboarding_reminder"
+	TemplateSecurityDigest         = "security_digest"
+	TemplateOrganizationInvitation = "organization_invitation"
+	TemplateDataExportReady        = "data_export_ready"
+)
+
+// Override file extensions, e.g. "welcome.html", "welcome.txt" and "welcome.subject"
+const (
+	htmlExt    = ".html"
+	textExt    = ".txt"
+	subjectExt = ".subject"
+)
+
+// definition holds the built-in sources for one email
+type definition struct {
+	subject string
+	html    string
+	text    string
+}
+
+// definitions contains the built-in templates, keyed by template name
+var definitions = map[string]definition{
+	TemplateVerification: {
+		subject: `{{t "verification.subject" .AppName}}`,
+		html:    verificationHTMLTemplate,
+		text:    verificationTextTemplate,
+	},
+	TemplatePasswordReset: {
+		subject: `{{t "password_reset.subject" .AppName}}`,
+		html:    passwordResetHTMLTemplate,
+		text:    passwordResetTextTemplate,
+	},
+	TemplateWelcome: {
+		subject: `{{t "welcome.subject" .AppName}}`,
+		html:    welcomeHTMLTemplate,
+		text:    welcomeTextTemplate,
+	},
+	TemplateLoginNotification: {
+		subject: `{{t "login_notification.subject" .AppName}}`,
+		html:    loginNotificationHTMLTemplate,
+		text:    loginNotificationTextTemplate,
+	},
+	TemplatePasswordChanged: {
+		subject: `{{t "password_changed.subject" .AppName}}`,
+		html:    passwordChangedHTMLTemplate,
+		text:    passwordChangedTextTemplate,
+	},
+	TemplateAccountLocked: {
+		subject: `{{t "account_locked.subject" .AppName}}`,
+		html:    accountLockedHTMLTemplate,
+		text:    accountLockedTextTemplate,
+	},
+	TemplateSuspiciousActivity: {
+		subject: `{{t "suspicious_activity.subject" .AppName}}`,
+		html:    suspiciousActivityHTMLTemplate,
+		text:    suspiciousActivityTextTemplate,
+	},
+	TemplateOnboardingReminder: {
+		subject: `{{t "onboarding_reminder.subject" .AppName}}`,
+		html:    onboardingReminderHTMLTemplate,
+		text:    onboardingReminderTextTemplate,
+	},
+	TemplateSecurityDigest: {
+		subject: `{{t "security_digest.subject" .AppName}}`,
+		html:    securityDigestHTMLTemplate,
+		text:    securityDigestTextTemplate,
+	},
+	TemplateOrganizationInvitation: {
+		subject: `{{t "organization_invitation.subject" .InviterName .OrganizationName .AppName}}`,
+		html:    organizationInvitationHTMLTemplate,
+		text:    organizationInvitationTextTemplate,
+	},
+	TemplateDataExportReady: {
+		subject: `{{t "data_export_ready.subject" .AppName}}`,
+		html:    dataExportReadyHTMLTemplate,
+		text:    dataExportReadyTextTemplate,
+	},
+}
+
+// compiled holds the parsed templates for one email
+type compiled struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// Renderer renders emails from the built-in templates, optionally overridden by
+// files on disk, in any locale of the i18n catalog
+type Renderer struct {
+	catalog   *i18n.Catalog
+	templates map[string]*compiled
+}
+
+// defaultRenderer renders the built-in templates without overrides
+var defaultRenderer = mustNewRenderer("")
+
+// NewRenderer parses all email templates. If overrideDir is not empty, any of
+// "<name>.html", "<name>.txt" and "<name>.subject" found in it replace the
+// corresponding built-in template; missing files fall back to the built-in one.
+// Overrides are read once, so the service must be restarted to pick up changes.
+func NewRenderer(overrideDir string) (*Renderer, error) {
+	r := &Renderer{
+		catalog:   i18n.Default(),
+		templates: make(map[string]*compiled, len(definitions)),
+	}
+	textFuncs := r.textFuncs(r.catalog.DefaultLocale())
+	htmlFuncs := r.htmlFuncs(r.catalog.DefaultLocale())
+
+	for name, def := range definitions {
+		subjectSrc, err := loadOverride(overrideDir, name+subjectExt, def.subject)
+		if err != nil {
+			return nil, err
+		}
+		htmlSrc, err := loadOverride(overrideDir, name+htmlExt, def.html)
+		if err != nil {
+			return nil, err
+		}
+		textSrc, err := loadOverride(overrideDir, name+textExt, def.text)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &compiled{}
+		if c.subject, err = texttemplate.New(name + "_subject").Funcs(textFuncs).Parse(strings.TrimSpace(subjectSrc)); err != nil {
+			return nil, fmt.Errorf("failed to parse subject template %q: %w", name, err)
+		}
+		if c.html, err = htmltemplate.New(name + "_html").Funcs(htmlFuncs).Parse(htmlSrc); err != nil {
+			return nil, fmt.Errorf("failed to parse HTML template %q: %w", name, err)
+		}
+		if c.text, err = texttemplate.New(name + "_text").Funcs(textFuncs).Parse(textSrc); err != nil {
+			return nil, fmt.Errorf("failed to parse text template %q: %w", name, err)
+		}
+
+		r.templates[name] = c
+	}
+
+	return r, nil
+}
+
+// mustNewRenderer is like NewRenderer but panics on error
+func mustNewRenderer(overrideDir string) *Renderer {
+	r, err := NewRenderer(overrideDir)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// loadOverride returns the contents of file in dir, or fallback if dir is empty
+// or the file does not exist
+func loadOverride(dir, file, fallback string) (string, error) {
+	if dir == "" {
+		return fallback, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fallback, nil
+		}
+		return "", fmt.Errorf("failed to read template override %s: %w", file, err)
+	}
+
+	return string(content), nil
+}
+
+// Names returns the names of all templates, sorted
+func (r *Renderer) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales returns the locales emails can be rendered in
+func (r *Renderer) Locales() []string {
+	return r.catalog.Locales()
+}
+
+// Render renders the named email in locale with the given data. Unsupported
+// locales and missing translations fall back to the default locale.
+func (r *Renderer) Render(name, locale string, data interface{}) (EmailTemplate, error) {
+	c, ok := r.templates[name]
+	if !ok {
+		return EmailTemplate{}, fmt.Errorf("unknown template: %s", name)
+	}
+
+	if !r.catalog.Supports(locale) {
+		locale = r.catalog.DefaultLocale()
+	}
+
+	// Bind the translation functions to the locale on a copy of the parsed templates
+	subjectTmpl, err := c.subject.Clone()
+	if err != nil {
+		return EmailTemplate{}, fmt.Errorf("failed to clone subject template: %w", err)
+	}
+	htmlTmpl, err := c.html.Clone()
+	if err != nil {
+		return EmailTemplate{}, fmt.Errorf("failed to clone HTML template: %w", err)
+	}
+	textTmpl, err := c.text.Clone()
+	if err != nil {
+		return EmailTemplate{}, fmt.Errorf("failed to clone text template: %w", err)
+	}
+
+	textFuncs := r.textFuncs(locale)
+	subjectTmpl.Funcs(textFuncs)
+	htmlTmpl.Funcs(r.htmlFuncs(locale))
+	textTmpl.Funcs(textFuncs)
+
+	var subject, text bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return EmailTemplate{}, fmt.Errorf("failed to render subject: %w", err)
+	}
+
+	html, err := RenderTemplate(htmlTmpl, data)
+	if err != nil {
+		return EmailTemplate{}, err
+	}
+
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return EmailTemplate{}, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return EmailTemplate{
+		Subject: subject.String(),
+		HTML:    html,
+		Text:    text.String(),
+	}, nil
+}
+
+// textFuncs returns the functions available to subject and text templates:
+//
+//	{{t "key" args...}}  translated message
+//	{{locale}}           locale being rendered
+func (r *Renderer) textFuncs(locale string) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return r.catalog.T(locale, key, args...)
+		},
+		"locale": func() string {
+			return locale
+		},
+	}
+}
+
+// htmlFuncs returns the functions available to HTML templates. In addition to
+// those of textFuncs:
+//
+//	{{mailto address}}   mailto link for an email address
+//	{{link url label}}   link with a (translated) label
+//
+// Messages are HTML-escaped, as are string arguments; arguments produced by
+// mailto, link or t are inserted as is so they can be placed inside a message.
+func (r *Renderer) htmlFuncs(locale string) htmltemplate.FuncMap {
+	return htmltemplate.FuncMap{
+		"t": func(key string, args ...interface{}) htmltemplate.HTML {
+			format := htmltemplate.HTMLEscapeString(r.catalog.Message(locale, key))
+			if len(args) == 0 {
+				return htmltemplate.HTML(format)
+			}
+
+			escaped := make([]interface{}, len(args))
+			for i, arg := range args {
+				switch v := arg.(type) {
+				case htmltemplate.HTML:
+					escaped[i] = v
+				case string:
+					escaped[i] = htmltemplate.HTMLEscapeString(v)
+				default:
+					escaped[i] = v
+				}
+			}
+
+			// #nosec G203 - the message and all string arguments are escaped above
+			return htmltemplate.HTML(fmt.Sprintf(format, escaped...))
+		},
+		"locale": func() string {
+			return locale
+		},
+		"mailto": func(address string) htmltemplate.HTML {
+			address = htmltemplate.HTMLEscapeString(address)
+			// #nosec G203 - the address is escaped above
+			return htmltemplate.HTML(fmt.Sprintf(`<a href="mailto:%s">%s</a>`, address, address))
+		},
+		"link": func(url string, label htmltemplate.HTML) htmltemplate.HTML {
+			// #nosec G203 - the URL is escaped below and the label is already HTML
+			return htmltemplate.HTML(fmt.Sprintf(`<a href="%s">%s</a>`, htmltemplate.HTMLEscapeString(url), label))
+		},
+	}
+}
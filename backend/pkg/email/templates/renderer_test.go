@@ -0,0 +1,173 @@
+package templates
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files with the current output:
+//
+//	go test ./pkg/email/templates -update
+var update = flag.Bool("update", false, "update golden files")
+
+// TestRenderGolden renders every template in every locale with the fixture
+// data and compares the result with testdata/golden/<locale>/<name>.*
+func TestRenderGolden(t *testing.T) {
+	r, err := NewRenderer("")
+	require.NoError(t, err)
+
+	for _, locale := range r.Locales() {
+		fixtures := FixtureData(locale)
+
+		for _, name := range r.Names() {
+			data, ok := fixtures[name]
+			require.True(t, ok, "template %q has no fixture data", name)
+
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				email, err := r.Render(name, locale, data)
+				require.NoError(t, err)
+
+				dir := filepath.Join("testdata", "golden", locale)
+				checkGolden(t, filepath.Join(dir, name+subjectExt), email.Subject)
+				checkGolden(t, filepath.Join(dir, name+htmlExt), email.HTML)
+				checkGolden(t, filepath.Join(dir, name+textExt), email.Text)
+			})
+		}
+	}
+}
+
+// checkGolden compares got with the golden file at path, or rewrites the file
+// when running with -update
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file, run go test ./pkg/email/templates -update")
+	assert.Equal(t, string(want), got, "%s is out of date; if the change is intended, run go test ./pkg/email/templates -update", path)
+}
+
+// TestNewRendererOverrides tests replacing built-in templates with files from
+// an override directory
+func TestNewRendererOverrides(t *testing.T) {
+	builtin, err := NewRenderer("")
+	require.NoError(t, err)
+
+	fixtures := FixtureData("en")
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		dir     func(t *testing.T, dir string) string
+		wantErr string
+		check   func(t *testing.T, r *Renderer)
+	}{
+		{
+			name: "partial override",
+			files: map[string]string{
+				TemplateWelcome + subjectExt: `Hi from {{.AppName}}`,
+				TemplateWelcome + textExt:    `{{t "common.greeting_name" .UserName}} custom copy`,
+			},
+			check: func(t *testing.T, r *Renderer) {
+				got, err := r.Render(TemplateWelcome, "en", fixtures[TemplateWelcome])
+				require.NoError(t, err)
+				want, err := builtin.Render(TemplateWelcome, "en", fixtures[TemplateWelcome])
+				require.NoError(t, err)
+
+				assert.Equal(t, "Hi from Go+Next", got.Subject)
+				assert.Equal(t, "Hello Jane Doe, custom copy", got.Text)
+				// The HTML body wasn't overridden
+				assert.Equal(t, want.HTML, got.HTML)
+
+				// Other templates fall back to the built-ins
+				got, err = r.Render(TemplatePasswordReset, "en", fixtures[TemplatePasswordReset])
+				require.NoError(t, err)
+				want, err = builtin.Render(TemplatePasswordReset, "en", fixtures[TemplatePasswordReset])
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			},
+		},
+		{
+			name: "overrides are localized",
+			files: map[string]string{
+				TemplateWelcome + subjectExt: `{{t "welcome.subject" .AppName}}!`,
+			},
+			check: func(t *testing.T, r *Renderer) {
+				got, err := r.Render(TemplateWelcome, "fr", FixtureData("fr")[TemplateWelcome])
+				require.NoError(t, err)
+				want, err := builtin.Render(TemplateWelcome, "fr", FixtureData("fr")[TemplateWelcome])
+				require.NoError(t, err)
+				assert.Equal(t, want.Subject+"!", got.Subject)
+			},
+		},
+		{
+			name: "missing directory",
+			dir: func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "missing")
+			},
+			check: func(t *testing.T, r *Renderer) {
+				for _, name := range builtin.Names() {
+					got, err := r.Render(name, "en", fixtures[name])
+					require.NoError(t, err)
+					want, err := builtin.Render(name, "en", fixtures[name])
+					require.NoError(t, err)
+					assert.Equal(t, want, got, name)
+				}
+			},
+		},
+		{
+			name: "invalid HTML template",
+			files: map[string]string{
+				TemplateWelcome + htmlExt: `<p>{{.UserName</p>`,
+			},
+			wantErr: `failed to parse HTML template "welcome"`,
+		},
+		{
+			name: "invalid subject template",
+			files: map[string]string{
+				TemplatePasswordReset + subjectExt: `{{if .AppName}}Reset`,
+			},
+			wantErr: `failed to parse subject template "password_reset"`,
+		},
+		{
+			name: "unreadable override",
+			dir: func(t *testing.T, dir string) string {
+				// A directory where a template file is expected can't be read
+				require.NoError(t, os.Mkdir(filepath.Join(dir, TemplateWelcome+textExt), 0o755))
+				return dir
+			},
+			wantErr: "failed to read template override welcome.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for file, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644))
+			}
+			if tt.dir != nil {
+				dir = tt.dir(t, dir)
+			}
+
+			r, err := NewRenderer(dir)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, r)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"html/template"
 	"time"
+
+	"github.com/nanayaw/fullstack/pkg/i18n"
 )
 
 // EmailTemplate represents an email template with subject and body
@@ -16,10 +18,15 @@ type EmailTemplate struct {
 
 // TemplateData contains common data for all email templates
 type TemplateData struct {
+	// Locale the email is rendered in (see pkg/i18n)
+	Locale       string
 	AppName      string
 	Year         int
 	SupportEmail string
 	BaseURL      string
+	// Unsubscribes the recipient from emails like this one, set on emails
+	// they can opt out of
+	UnsubscribeURL string
 }
 
 // VerificationData contains data for verification email
@@ -72,6 +79,7 @@ type AccountLockedData struct {
 	UserName     string
 	UnlockTime   string
 	FailedLogins int
+	UnlockURL    string
 }
 
 // SuspiciousActivityData contains data for suspicious activity email
@@ -85,9 +93,63 @@ type SuspiciousActivityData struct {
 	IPAddress    string
 }
 
+// OnboardingReminderData contains data for the reminder sent to users who
+// haven't verified their email address a few days after signing up
+type OnboardingReminderData struct {
+	TemplateData
+	UserName        string
+	VerificationURL string
+	ExpiresIn       string
+}
+
+// SecurityDigestData contains data for the weekly security digest email
+type SecurityDigestData struct {
+	TemplateData
+	UserName string
+	From     string
+	To       string
+	// Number of events of each type in the period
+	Summary []SecurityDigestItem
+	// Most recent events worth reviewing
+	Events []SecurityDigestEvent
+}
+
+// SecurityDigestItem is the number of security events of one type
+type SecurityDigestItem struct {
+	Label string
+	Count int
+}
+
+// SecurityDigestEvent is a security event listed in the security digest
+type SecurityDigestEvent struct {
+	Label     string
+	Time      string
+	Location  string
+	IPAddress string
+}
+
+// OrganizationInvitationData contains data for the email inviting someone to
+// join an organization
+type OrganizationInvitationData struct {
+	TemplateData
+	OrganizationName string
+	InviterName      string
+	AcceptURL        string
+	ExpiresIn        string
+}
+
+// DataExportReadyData contains data for the email with the link to download
+// a copy of the user's data
+type DataExportReadyData struct {
+	TemplateData
+	DownloadURL string
+	ExpiresIn   string
+}
+
 // NewTemplateData creates a new TemplateData with default values
 func NewTemplateData(appName, supportEmail, baseURL string) TemplateData {
 	return TemplateData{
+		Locale:       i18n.DefaultLocale,
 		AppName:      appName,
 		Year:         time.Now().Year(),
 		SupportEmail: supportEmail,
@@ -106,203 +168,55 @@ func RenderTemplate(tmpl *template.Template, data interface{}) (string, error) {
 
 // GetVerificationEmail returns the verification email template
 func GetVerificationEmail(data VerificationData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("verification_html").Parse(verificationHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
-
-	textTmpl, err := template.New("verification_text").Parse(verificationTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
-
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	return EmailTemplate{
-		Subject: fmt.Sprintf("Verify your email address for %s", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+	return defaultRenderer.Render(TemplateVerification, data.Locale, data)
 }
 
 // GetPasswordResetEmail returns the password reset email template
 func GetPasswordResetEmail(data PasswordResetData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("password_reset_html").Parse(passwordResetHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
-
-	textTmpl, err := template.New("password_reset_text").Parse(passwordResetTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
-
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	return EmailTemplate{
-		Subject: fmt.Sprintf("Reset your password for %s", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+	return defaultRenderer.Render(TemplatePasswordReset, data.Locale, data)
 }
 
 // GetWelcomeEmail returns the welcome email template
 func GetWelcomeEmail(data WelcomeData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("welcome_html").Parse(welcomeHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
-
-	textTmpl, err := template.New("welcome_text").Parse(welcomeTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
-
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	return EmailTemplate{
-		Subject: fmt.Sprintf("Welcome to %s", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+	return defaultRenderer.Render(TemplateWelcome, data.Locale, data)
 }
 
 // GetLoginNotificationEmail returns the login notification email template
 func GetLoginNotificationEmail(data LoginNotificationData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("login_notification_html").Parse(loginNotificationHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
-
-	textTmpl, err := template.New("login_notification_text").Parse(loginNotificationTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
-
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	return EmailTemplate{
-		Subject: fmt.Sprintf("New login to your %s account", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+	return defaultRenderer.Render(TemplateLoginNotification, data.Locale, data)
 }
 
 // GetPasswordChangedEmail returns the password changed email template
 func GetPasswordChangedEmail(data PasswordChangedData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("password_changed_html").Parse(passwordChangedHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
-
-	textTmpl, err := template.New("password_changed_text").Parse(passwordChangedTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
-
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	return EmailTemplate{
-		Subject: fmt.Sprintf("Your %s password has been changed", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+	return defaultRenderer.Render(TemplatePasswordChanged, data.Locale, data)
 }
 
 // GetAccountLockedEmail returns the account locked email template
 func GetAccountLockedEmail(data AccountLockedData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("account_locked_html").Parse(accountLockedHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
-
-	textTmpl, err := template.New("account_locked_text").Parse(accountLockedTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
-
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
-
-	return EmailTemplate{
-		Subject: fmt.Sprintf("Your %s account has been temporarily locked", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+	return defaultRenderer.Render(TemplateAccountLocked, data.Locale, data)
 }
 
 // GetSuspiciousActivityEmail returns the suspicious activity email template
 func GetSuspiciousActivityEmail(data SuspiciousActivityData) (EmailTemplate, error) {
-	htmlTmpl, err := template.New("suspicious_activity_html").Parse(suspiciousActivityHTMLTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse HTML template: %w", err)
-	}
+	return defaultRenderer.Render(TemplateSuspiciousActivity, data.Locale, data)
+}
 
-	textTmpl, err := template.New("suspicious_activity_text").Parse(suspiciousActivityTextTemplate)
-	if err != nil {
-		return EmailTemplate{}, fmt.Errorf("failed to parse text template: %w", err)
-	}
+// GetOnboardingReminderEmail returns the onboarding reminder email template
+func GetOnboardingReminderEmail(data OnboardingReminderData) (EmailTemplate, error) {
+	return defaultRenderer.Render(TemplateOnboardingReminder, data.Locale, data)
+}
 
-	html, err := RenderTemplate(htmlTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
+// GetSecurityDigestEmail returns the security digest email template
+func GetSecurityDigestEmail(data SecurityDigestData) (EmailTemplate, error) {
+	return defaultRenderer.Render(TemplateSecurityDigest, data.Locale, data)
+}
 
-	text, err := RenderTemplate(textTmpl, data)
-	if err != nil {
-		return EmailTemplate{}, err
-	}
+// GetOrganizationInvitationEmail returns the organization invitation email template
+func GetOrganizationInvitationEmail(data OrganizationInvitationData) (EmailTemplate, error) {
+	return defaultRenderer.Render(TemplateOrganizationInvitation, data.Locale, data)
+}
 
-	return EmailTemplate{
-		Subject: fmt.Sprintf("Suspicious activity detected on your %s account", data.AppName),
-		HTML:    html,
-		Text:    text,
-	}, nil
+// GetDataExportReadyEmail returns the data export ready email template
+func GetDataExportReadyEmail(data DataExportReadyData) (EmailTemplate, error) {
+	return defaultRenderer.Render(TemplateDataExportReady, data.Locale, data)
 }
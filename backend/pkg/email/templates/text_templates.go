@@ -2,117 +2,189 @@ package templates
 
 // Plain text email templates for better accessibility and plain text email clients
 
-const verificationTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const verificationTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-Thank you for signing up for {{.AppName}}. To complete your registration and verify your email address, please visit the following link:
+{{t "verification.intro_text" .AppName}}
 
 {{.VerificationURL}}
 
-This verification link will expire in {{.ExpiresIn}}.
+{{t "verification.expires" .ExpiresIn}}
 
-If you didn't create an account with {{.AppName}}, you can safely ignore this email.
+{{t "verification.ignore" .AppName}}
 
-Need help? Contact our support team at {{.SupportEmail}}.
+{{t "common.need_help" .SupportEmail}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{t "common.copyright" .Year .AppName}}`
 
 // #nosec G101 - This is a template for password reset emails, not a hardcoded credential
-const passwordResetTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const passwordResetTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-We received a request to reset your password for your {{.AppName}} account. To reset your password, please visit the following link:
+{{t "password_reset.intro_text" .AppName}}
 
 {{.ResetURL}}
 
-This password reset link will expire in {{.ExpiresIn}}.
+{{t "password_reset.expires" .ExpiresIn}}
 
-Security Information:
-- This request was made from: {{.RequestedFrom}}
-- Time of request: {{.RequestTime}}
+{{if .RequestedFrom}}{{t "password_reset.security_info"}}
+- {{t "password_reset.requested_from" .RequestedFrom}}
+- {{t "password_reset.request_time" .RequestTime}}
 
-If you didn't request a password reset, please ignore this email or contact our support team if you have concerns about your account security.
+{{end}}{{t "password_reset.ignore"}}
 
-Need help? Contact our support team at {{.SupportEmail}}.
+{{t "common.need_help" .SupportEmail}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{t "common.copyright" .Year .AppName}}`
 
-const welcomeTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const welcomeTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-Thank you for joining {{.AppName}}! We're excited to have you on board.
+{{t "welcome.intro" .AppName}}
 
-Here are a few things you can do with your new account:
-- Complete your profile - Add your information to get the most out of our platform.
-- Explore our features - Discover all the tools and services we offer.
-- Connect with others - Build your network and collaborate with like-minded individuals.
+{{t "welcome.features"}}
+- {{t "welcome.feature_profile_title"}} - {{t "welcome.feature_profile"}}
+- {{t "welcome.feature_explore_title"}} - {{t "welcome.feature_explore"}}
+- {{t "welcome.feature_connect_title"}} - {{t "welcome.feature_connect"}}
 
-Visit your dashboard: {{.BaseURL}}/dashboard
+{{t "welcome.dashboard_text" (printf "%s/dashboard" .BaseURL)}}
 
-Need help getting started? Check out our Help Center at {{.BaseURL}}/help or contact our support team at {{.SupportEmail}}.
+{{t "welcome.help_text" (printf "%s/help" .BaseURL) .SupportEmail}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{t "common.copyright" .Year .AppName}}`
 
-const loginNotificationTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const loginNotificationTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-We detected a new login to your {{.AppName}} account. If this was you, no action is needed.
+{{t "login_notification.intro" .AppName}}
 
-Login Details:
-- Device: {{.DeviceInfo}}
-- Location: {{.Location}}
-- IP Address: {{.IPAddress}}
-- Time: {{.Time}}
-- Browser: {{.UserAgent}}
+{{t "login_notification.details"}}
+{{if .DeviceInfo}}- {{t "common.device"}} {{.DeviceInfo}}
+{{end}}{{if .Location}}- {{t "common.location"}} {{.Location}}
+{{end}}{{if .IPAddress}}- {{t "common.ip_address"}} {{.IPAddress}}
+{{end}}- {{t "common.time"}} {{.Time}}
+{{if .UserAgent}}- {{t "common.browser"}} {{.UserAgent}}
+{{end}}
+{{t "login_notification.unrecognized_text" (printf "%s/account/security" .BaseURL)}}
 
-If you don't recognize this activity, please visit {{.BaseURL}}/account/security to secure your account.
+{{t "login_notification.help" .SupportEmail}}
 
-If you didn't authorize this login, please change your password immediately and contact our support team at {{.SupportEmail}}.
+{{if .UnsubscribeURL}}{{t "common.unsubscribe_text" .UnsubscribeURL}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{end}}{{t "common.copyright" .Year .AppName}}`
 
 // #nosec G101 - This is a template for password changed emails, not a hardcoded credential
-const passwordChangedTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const passwordChangedTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-This email confirms that your password for your {{.AppName}} account has been successfully changed.
+{{t "password_changed.intro" .AppName}}
 
-Change Details:
-- Device: {{.DeviceInfo}}
-- Location: {{.Location}}
-- Time: {{.Time}}
+{{t "password_changed.details"}}
+{{if .DeviceInfo}}- {{t "common.device"}} {{.DeviceInfo}}
+{{end}}{{if .Location}}- {{t "common.location"}} {{.Location}}
+{{end}}- {{t "common.time"}} {{.Time}}
 
-If you didn't make this change, please visit {{.BaseURL}}/account/security to secure your account.
+{{t "password_changed.not_you_text" (printf "%s/account/security" .BaseURL)}}
 
-If you didn't authorize this password change, please contact our support team immediately at {{.SupportEmail}}.
+{{t "password_changed.help" .SupportEmail}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{t "common.copyright" .Year .AppName}}`
 
-const accountLockedTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const accountLockedTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-For your security, we've temporarily locked your {{.AppName}} account due to multiple failed login attempts.
+{{t "account_locked.intro" .AppName}}
 
-Lock Details:
-- Failed Login Attempts: {{.FailedLogins}}
-- Account Will Unlock: {{.UnlockTime}}
+{{t "account_locked.details"}}
+- {{t "account_locked.failed_logins"}} {{.FailedLogins}}
+- {{t "account_locked.unlocks_at"}} {{.UnlockTime}}
+{{if .UnlockURL}}
+{{t "account_locked.unlock_intro_text"}}
 
-If you were trying to log in, please wait until the account unlocks and try again with the correct password. If you've forgotten your password, you can reset it by visiting:
+{{.UnlockURL}}
+{{end}}
+{{t "account_locked.wait_text"}}
 
 {{.BaseURL}}/reset-password
 
-If you didn't attempt to log in and believe someone else might be trying to access your account, please contact our support team immediately at {{.SupportEmail}}.
+{{t "account_locked.help" .SupportEmail}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{t "common.copyright" .Year .AppName}}`
 
-const suspiciousActivityTextTemplate = `Hello {{if .UserName}}{{.UserName}}{{else}}there{{end}},
+const suspiciousActivityTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
 
-We've detected suspicious activity on your {{.AppName}} account that requires your immediate attention.
+{{t "suspicious_activity.intro" .AppName}}
 
-Activity Details:
-- Activity: {{.ActivityType}}
-- Device: {{.DeviceInfo}}
-- Location: {{.Location}}
-- IP Address: {{.IPAddress}}
-- Time: {{.Time}}
+{{t "suspicious_activity.details"}}
+- {{t "common.activity"}} {{.ActivityType}}
+{{if .DeviceInfo}}- {{t "common.device"}} {{.DeviceInfo}}
+{{end}}{{if .Location}}- {{t "common.location"}} {{.Location}}
+{{end}}{{if .IPAddress}}- {{t "common.ip_address"}} {{.IPAddress}}
+{{end}}- {{t "common.time"}} {{.Time}}
 
-For your security, we recommend taking immediate action by visiting:
+{{t "suspicious_activity.action_text"}}
 {{.BaseURL}}/account/security
 
-If you recognize this activity, you can safely ignore this email. If not, please change your password immediately and contact our support team at {{.SupportEmail}}.
+{{t "suspicious_activity.help" .SupportEmail}}
 
-© {{.Year}} {{.AppName}}. All rights reserved.`
+{{t "common.copyright" .Year .AppName}}`
+
+const onboardingReminderTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
+
+{{t "onboarding_reminder.intro_text" .AppName}}
+
+{{.VerificationURL}}
+
+{{t "verification.expires" .ExpiresIn}}
+
+{{t "verification.ignore" .AppName}}
+
+{{t "common.need_help" .SupportEmail}}
+
+{{if .UnsubscribeURL}}{{t "common.unsubscribe_text" .UnsubscribeURL}}
+
+{{end}}{{t "common.copyright" .Year .AppName}}`
+
+const securityDigestTextTemplate = `{{if .UserName}}{{t "common.greeting_name" .UserName}}{{else}}{{t "common.greeting"}}{{end}}
+
+{{t "security_digest.intro" .AppName .From .To}}
+
+{{t "security_digest.summary"}}
+{{range .Summary}}- {{.Label}}: {{.Count}}
+{{end}}{{if .Events}}
+{{t "security_digest.review"}}
+{{range .Events}}- {{.Label}}
+  {{t "common.time"}} {{.Time}}
+{{if .Location}}  {{t "common.location"}} {{.Location}}
+{{end}}{{if .IPAddress}}  {{t "common.ip_address"}} {{.IPAddress}}
+{{end}}{{end}}{{end}}
+{{t "security_digest.action_text"}}
+{{.BaseURL}}/account/security
+
+{{t "common.need_help" .SupportEmail}}
+
+{{if .UnsubscribeURL}}{{t "common.unsubscribe_text" .UnsubscribeURL}}
+
+{{end}}{{t "common.copyright" .Year .AppName}}`
+
+const organizationInvitationTextTemplate = `{{t "common.greeting"}}
+
+{{t "organization_invitation.intro_text" .InviterName .OrganizationName .AppName}}
+
+{{.AcceptURL}}
+
+{{t "organization_invitation.expires" .ExpiresIn}}
+
+{{t "organization_invitation.ignore"}}
+
+{{t "common.need_help" .SupportEmail}}
+
+{{t "common.copyright" .Year .AppName}}`
+
+const dataExportReadyTextTemplate = `{{t "common.greeting"}}
+
+{{t "data_export_ready.intro_text" .AppName}}
+
+{{.DownloadURL}}
+
+{{t "data_export_ready.expires" .ExpiresIn}}
+
+{{t "data_export_ready.ignore"}}
+
+{{t "common.need_help" .SupportEmail}}
+
+{{t "common.copyright" .Year .AppName}}`
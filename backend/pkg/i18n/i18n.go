@@ -0,0 +1,244 @@
+// Package i18n provides the message catalog used to localize user-facing
+// text, locale negotiation from Accept-Language headers, and helpers for
+// carrying a locale through a context.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when no supported locale can be determined and as the
+// fallback for keys missing from other locales
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// aliases maps language codes to the locale we serve them with
+var aliases = map[string]string{
+	// Browsers may report Twi under the Akan macrolanguage code
+	"ak": "tw",
+}
+
+// Catalog holds translated messages for a set of locales
+type Catalog struct {
+	defaultLocale string
+	messages      map[string]map[string]string
+}
+
+var (
+	defaultCatalog     *Catalog
+	defaultCatalogErr  error
+	defaultCatalogOnce sync.Once
+)
+
+// Default returns the catalog built from the embedded locale files
+func Default() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		sub, err := fs.Sub(localeFiles, "locales")
+		if err != nil {
+			defaultCatalogErr = err
+			return
+		}
+		defaultCatalog, defaultCatalogErr = NewCatalog(sub, DefaultLocale)
+	})
+
+	// The embedded files are validated by the tests, so this only fails on a broken build
+	if defaultCatalogErr != nil {
+		panic(fmt.Sprintf("i18n: failed to load embedded catalog: %v", defaultCatalogErr))
+	}
+
+	return defaultCatalog
+}
+
+// NewCatalog loads every "<locale>.json" file in fsys. Each file is a flat JSON
+// object mapping message keys to fmt format strings.
+func NewCatalog(fsys fs.FS, defaultLocale string) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locale files: %w", err)
+	}
+
+	c := &Catalog{
+		defaultLocale: defaultLocale,
+		messages:      make(map[string]map[string]string, len(files)),
+	}
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale file %s: %w", file, err)
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(content, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse locale file %s: %w", file, err)
+		}
+
+		c.messages[strings.TrimSuffix(path.Base(file), ".json")] = messages
+	}
+
+	if _, ok := c.messages[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no messages for default locale %q", defaultLocale)
+	}
+
+	return c, nil
+}
+
+// DefaultLocale returns the catalog's fallback locale
+func (c *Catalog) DefaultLocale() string {
+	return c.defaultLocale
+}
+
+// Locales returns the locales in the catalog, sorted
+func (c *Catalog) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supports reports whether the catalog has messages for the locale
+func (c *Catalog) Supports(locale string) bool {
+	_, ok := c.messages[locale]
+	return ok
+}
+
+// Keys returns the message keys defined for a locale, sorted
+func (c *Catalog) Keys(locale string) []string {
+	keys := make([]string, 0, len(c.messages[locale]))
+	for key := range c.messages[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Missing returns the keys defined for the default locale but not for locale
+func (c *Catalog) Missing(locale string) []string {
+	var missing []string
+	for _, key := range c.Keys(c.defaultLocale) {
+		if _, ok := c.messages[locale][key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// Extra returns the keys defined for locale but not for the default locale.
+// These are usually left over from renamed or removed messages.
+func (c *Catalog) Extra(locale string) []string {
+	var extra []string
+	for _, key := range c.Keys(locale) {
+		if _, ok := c.messages[c.defaultLocale][key]; !ok {
+			extra = append(extra, key)
+		}
+	}
+	return extra
+}
+
+// Message returns the unformatted message for key in locale. Keys missing from
+// locale fall back to the default locale, and unknown keys are returned as is.
+func (c *Catalog) Message(locale, key string) string {
+	if message, ok := c.messages[locale][key]; ok {
+		return message
+	}
+	if message, ok := c.messages[c.defaultLocale][key]; ok {
+		return message
+	}
+	return key
+}
+
+// T translates key into locale, formatting the message with args
+func (c *Catalog) T(locale, key string, args ...interface{}) string {
+	format := c.Message(locale, key)
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Normalize turns a language tag such as "fr-CA" into the base locale we store
+// for users, such as "fr". It returns an empty string for an empty tag.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if alias, ok := aliases[tag]; ok {
+		return alias
+	}
+	return tag
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header,
+// returning the catalog's default locale if none of the requested languages
+// are supported
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	type preference struct {
+		locale string
+		q      float64
+	}
+
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if _, err := fmt.Sscanf(param[2:], "%g", &q); err != nil {
+					q = 0
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		prefs = append(prefs, preference{locale: Normalize(tag), q: q})
+	}
+
+	// Highest quality first, keeping the header's order for equal weights
+	sort.SliceStable(prefs, func(i, j int) bool {
+		return prefs[i].q > prefs[j].q
+	})
+
+	for _, pref := range prefs {
+		if c.Supports(pref.locale) {
+			return pref.locale
+		}
+	}
+
+	return c.defaultLocale
+}
+
+type contextKey struct{}
+
+// WithLocale returns a copy of ctx carrying locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale carried by ctx, or DefaultLocale if there is none
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDefaultCatalog checks that the embedded locale files load
+func TestDefaultCatalog(t *testing.T) {
+	catalog := Default()
+
+	assert.Equal(t, DefaultLocale, catalog.DefaultLocale())
+	assert.ElementsMatch(t, []string{"en", "fr", "tw"}, catalog.Locales())
+
+	// Every locale must translate exactly the keys the default locale knows about
+	for _, locale := range catalog.Locales() {
+		assert.Empty(t, catalog.Missing(locale), "missing keys in %s", locale)
+		assert.Empty(t, catalog.Extra(locale), "unknown keys in %s", locale)
+	}
+}
+
+// TestTranslate tests formatting and fallback to the default locale
+func TestTranslate(t *testing.T) {
+	catalog, err := NewCatalog(fstest.MapFS{
+		"en.json": {Data: []byte(`{"greeting": "Hello %s", "farewell": "Goodbye"}`)},
+		"fr.json": {Data: []byte(`{"greeting": "Bonjour %s"}`)},
+	}, "en")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bonjour Ama", catalog.T("fr", "greeting", "Ama"))
+	assert.Equal(t, "Goodbye", catalog.T("fr", "farewell"))
+	assert.Equal(t, "Hello Ama", catalog.T("de", "greeting", "Ama"))
+	assert.Equal(t, "unknown", catalog.T("fr", "unknown"))
+	assert.Equal(t, []string{"farewell"}, catalog.Missing("fr"))
+}
+
+// TestNegotiate tests picking a locale from an Accept-Language header
+func TestNegotiate(t *testing.T) {
+	catalog := Default()
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr-CA,fr;q=0.9,en;q=0.8", "fr"},
+		{"de-DE,tw;q=0.5", "tw"},
+		{"ak", "tw"},
+		{"en;q=0.5,fr;q=0.8", "fr"},
+		{"fr;q=0,de", "en"},
+		{"*", "en"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, catalog.Negotiate(tt.header), "Accept-Language: %q", tt.header)
+	}
+}
+
+// TestContext tests carrying a locale through a context
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, DefaultLocale, FromContext(ctx))
+	assert.Equal(t, "fr", FromContext(WithLocale(ctx, "fr")))
+}
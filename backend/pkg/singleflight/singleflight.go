@@ -0,0 +1,76 @@
+// Package singleflight collapses concurrent calls for the same key into one,
+// so a burst of cache misses reaches the database once.
+package singleflight
+
+import (
+	"fmt"
+	"sync"
+)
+
+// call is a call in flight or completed
+type call struct {
+	wg   sync.WaitGroup
+	val  interface{}
+	err  error
+	dups int
+}
+
+// Group runs calls for distinct keys independently. The zero value is ready
+// to use.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do runs fn and returns its results, unless a call for the same key is
+// already in flight, in which case it waits for that call and returns its
+// results. shared reports whether the results were given to several callers.
+// A panic in fn is returned as an error to every caller.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.run(c, fn)
+
+	g.mu.Lock()
+	// The key may have been forgotten and reused while fn ran
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	shared = c.dups > 0
+	g.mu.Unlock()
+
+	return c.val, c.err, shared
+}
+
+// Forget makes the next call for key run fn instead of waiting for a call in
+// flight, e.g. after the data it loads changed
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
+
+// run runs fn for a call, and releases its waiters even if fn panics
+func (g *Group) run(c *call, fn func() (interface{}, error)) {
+	defer c.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: panic: %v", r)
+		}
+	}()
+
+	c.val, c.err = fn()
+}
@@ -0,0 +1,73 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	var g Group
+
+	v, err, shared := g.Do("key", func() (interface{}, error) {
+		return "value", nil
+	})
+	assert.Equal(t, "value", v)
+	assert.NoError(t, err)
+	assert.False(t, shared)
+
+	_, err, _ = g.Do("key", func() (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+}
+
+func TestDoCollapsesConcurrentCalls(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = g.Do("key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value", nil
+			})
+		}(i)
+	}
+
+	// Let every goroutine join the call in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, result := range results {
+		assert.Equal(t, "value", result)
+	}
+}
+
+func TestDoRecoversPanics(t *testing.T) {
+	var g Group
+
+	_, err, _ := g.Do("key", func() (interface{}, error) {
+		panic("boom")
+	})
+	assert.ErrorContains(t, err, "boom")
+
+	// The key can be used again
+	v, err, _ := g.Do("key", func() (interface{}, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
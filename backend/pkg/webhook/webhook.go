@@ -0,0 +1,115 @@
+// Package webhook verifies webhook signatures in the Standard Webhooks format
+// used by Svix and, through it, by Resend. A payload is signed with
+// HMAC-SHA256 over "<id>.<timestamp>.<body>" and the signatures are sent as a
+// space-separated list of "v1,<base64>" values.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far a webhook timestamp may be from the current time
+const DefaultTolerance = 5 * time.Minute
+
+// secretPrefix is the prefix of Svix signing secrets
+const secretPrefix = "whsec_"
+
+var (
+	// ErrMissingHeaders is returned when a request lacks the signature headers
+	ErrMissingHeaders = errors.New("missing webhook signature headers")
+	// ErrInvalidTimestamp is returned when the timestamp is malformed or too far
+	// from the current time, which protects against replayed requests
+	ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+	// ErrInvalidSignature is returned when no signature matches the payload
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Verifier verifies signed webhook requests
+type Verifier struct {
+	secret    []byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewVerifier creates a verifier from a base64-encoded signing secret, with or
+// without the "whsec_" prefix
+func NewVerifier(secret string) (*Verifier, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, secretPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook secret: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("webhook secret is empty")
+	}
+
+	return &Verifier{
+		secret:    key,
+		tolerance: DefaultTolerance,
+		now:       time.Now,
+	}, nil
+}
+
+// Verify checks the signature headers of a request against its body. Both the
+// "svix-" and the "webhook-" header prefixes are accepted.
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	id, timestamp, signatures := signatureHeaders(header, "svix-")
+	if id == "" {
+		id, timestamp, signatures = signatureHeaders(header, "webhook-")
+	}
+	if id == "" || timestamp == "" || signatures == "" {
+		return ErrMissingHeaders
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	sent := time.Unix(seconds, 0)
+	if now := v.now(); sent.Before(now.Add(-v.tolerance)) || sent.After(now.Add(v.tolerance)) {
+		return ErrInvalidTimestamp
+	}
+
+	expected := v.sign(id, timestamp, body)
+	for _, signature := range strings.Fields(signatures) {
+		version, value, ok := strings.Cut(signature, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// Sign returns the "v1,<base64>" signature of a payload
+func (v *Verifier) Sign(id string, timestamp time.Time, body []byte) string {
+	mac := v.sign(id, strconv.FormatInt(timestamp.Unix(), 10), body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac)
+}
+
+// sign computes the HMAC of a payload
+func (v *Verifier) sign(id, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// signatureHeaders returns the id, timestamp and signature headers with a prefix
+func signatureHeaders(header http.Header, prefix string) (string, string, string) {
+	return header.Get(prefix + "id"), header.Get(prefix + "timestamp"), header.Get(prefix + "signature")
+}
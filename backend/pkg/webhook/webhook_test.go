@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	// Example from the Svix documentation
+	v, err := NewVerifier("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+	require.NoError(t, err)
+	v.now = func() time.Time { return time.Unix(1614265330, 0) }
+
+	body := []byte(`{"test": 2432232314}`)
+	header := http.Header{}
+	header.Set("svix-id", "msg_p5jXN8AQM9LWM0D4loKWxJek")
+	header.Set("svix-timestamp", "1614265330")
+	header.Set("svix-signature", "v1,invalid v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=")
+
+	assert.NoError(t, v.Verify(header, body))
+
+	// Tampered body
+	assert.ErrorIs(t, v.Verify(header, []byte(`{"test": 1}`)), ErrInvalidSignature)
+
+	// Replayed outside the tolerance
+	v.now = func() time.Time { return time.Unix(1614265330, 0).Add(10 * time.Minute) }
+	assert.ErrorIs(t, v.Verify(header, body), ErrInvalidTimestamp)
+
+	// Missing headers
+	assert.ErrorIs(t, v.Verify(http.Header{}, body), ErrMissingHeaders)
+}
+
+func TestSign(t *testing.T) {
+	v, err := NewVerifier("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+	require.NoError(t, err)
+
+	now := time.Now()
+	body := []byte(`{"type":"email.bounced"}`)
+	header := http.Header{}
+	header.Set("webhook-id", "msg_1")
+	header.Set("webhook-timestamp", strconv.FormatInt(now.Unix(), 10))
+	header.Set("webhook-signature", v.Sign("msg_1", now, body))
+
+	assert.NoError(t, v.Verify(header, body))
+}
@@ -0,0 +1,14 @@
+import { Suspense } from "react";
+import UnlockAccountContent from "@/components/auth/unlock-account-content";
+
+export default function UnlockAccountPage() {
+  return (
+    <div className="container flex h-screen w-screen flex-col items-center justify-center">
+      <div className="mx-auto flex w-full flex-col justify-center space-y-6 sm:w-[350px]">
+        <Suspense fallback={<div>Loading...</div>}>
+          <UnlockAccountContent />
+        </Suspense>
+      </div>
+    </div>
+  );
+}
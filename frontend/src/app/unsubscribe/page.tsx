@@ -0,0 +1,14 @@
+import { Suspense } from "react";
+import UnsubscribeContent from "@/components/notifications/unsubscribe-content";
+
+export default function UnsubscribePage() {
+  return (
+    <div className="container flex h-screen w-screen flex-col items-center justify-center">
+      <div className="mx-auto flex w-full flex-col justify-center space-y-6 sm:w-[350px]">
+        <Suspense fallback={<div>Loading...</div>}>
+          <UnsubscribeContent />
+        </Suspense>
+      </div>
+    </div>
+  );
+}
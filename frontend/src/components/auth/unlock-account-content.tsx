@@ -0,0 +1,96 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { useRouter, useSearchParams } from "next/navigation";
+import Link from "next/link";
+import { Button } from "@/components/ui/button";
+import { useToast } from "@/components/ui/use-toast";
+import { api } from "@/lib/api/client";
+
+export default function UnlockAccountContent() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token");
+  const { toast } = useToast();
+  const [isLoading, setIsLoading] = useState(true);
+  const [isUnlocked, setIsUnlocked] = useState(false);
+  const [error, setError] = useState("");
+
+  useEffect(() => {
+    if (!token) {
+      setIsLoading(false);
+      setError("Unlock token is missing");
+      return;
+    }
+
+    const unlockAccount = async () => {
+      try {
+        await api.post("/api/v1/auth/unlock-account", { token });
+
+        setIsUnlocked(true);
+        toast({
+          title: "Success",
+          description: "Your account has been unlocked",
+        });
+      } catch (error) {
+        setError(
+          error instanceof Error ? error.message : "Failed to unlock account"
+        );
+        toast({
+          title: "Error",
+          description:
+            error instanceof Error ? error.message : "Failed to unlock account",
+          variant: "destructive",
+        });
+      } finally {
+        setIsLoading(false);
+      }
+    };
+
+    unlockAccount();
+  }, [token, toast]);
+
+  return (
+    <>
+      <div className="flex flex-col space-y-2 text-center">
+        <h1 className="text-2xl font-semibold tracking-tight">
+          Unlock Account
+        </h1>
+        {isLoading ? (
+          <p className="text-sm text-muted-foreground">
+            Unlocking your account...
+          </p>
+        ) : isUnlocked ? (
+          <p className="text-sm text-muted-foreground">
+            Your account has been unlocked. You can now log in.
+          </p>
+        ) : (
+          <p className="text-sm text-muted-foreground text-red-500">
+            {error || "Failed to unlock account"}
+          </p>
+        )}
+      </div>
+
+      {!isLoading && (
+        <div className="flex flex-col space-y-4">
+          <Button
+            variant={isUnlocked ? "default" : "outline"}
+            onClick={() => router.push("/auth/login")}
+          >
+            Go to Login
+          </Button>
+          {!isUnlocked && (
+            <p className="px-8 text-center text-sm text-muted-foreground">
+              <Link
+                href="/auth/forgot-password"
+                className="hover:text-brand underline underline-offset-4"
+              >
+                Reset your password instead
+              </Link>
+            </p>
+          )}
+        </div>
+      )}
+    </>
+  );
+}
@@ -0,0 +1,123 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { useSearchParams } from "next/navigation";
+import Link from "next/link";
+import { Button } from "@/components/ui/button";
+import { useToast } from "@/components/ui/use-toast";
+import { api } from "@/lib/api/client";
+
+interface UnsubscribeResponse {
+  category: string;
+  channel: string;
+  enabled: boolean;
+}
+
+const categoryLabels: Record<string, string> = {
+  login_alerts: "login alerts",
+  security_digest: "security digest",
+  onboarding: "onboarding reminders",
+};
+
+export default function UnsubscribeContent() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token");
+  const { toast } = useToast();
+  const [category, setCategory] = useState("");
+  const [isLoading, setIsLoading] = useState(true);
+  const [isUnsubscribing, setIsUnsubscribing] = useState(false);
+  const [isUnsubscribed, setIsUnsubscribed] = useState(false);
+  const [error, setError] = useState("");
+
+  // Only look the link up; link scanners must not unsubscribe anyone
+  useEffect(() => {
+    if (!token) {
+      setIsLoading(false);
+      setError("Unsubscribe token is missing");
+      return;
+    }
+
+    const checkLink = async () => {
+      try {
+        const response = await api.get<UnsubscribeResponse>(
+          "/api/v1/notifications/unsubscribe",
+          { params: { token } }
+        );
+        setCategory(response.category);
+      } catch (error) {
+        setError(
+          error instanceof Error ? error.message : "Invalid unsubscribe link"
+        );
+      } finally {
+        setIsLoading(false);
+      }
+    };
+
+    checkLink();
+  }, [token]);
+
+  const unsubscribe = async () => {
+    setIsUnsubscribing(true);
+    try {
+      await api.post("/api/v1/notifications/unsubscribe", null, {
+        params: { token },
+      });
+
+      setIsUnsubscribed(true);
+      toast({
+        title: "Success",
+        description: "You have been unsubscribed",
+      });
+    } catch (error) {
+      toast({
+        title: "Error",
+        description:
+          error instanceof Error ? error.message : "Failed to unsubscribe",
+        variant: "destructive",
+      });
+    } finally {
+      setIsUnsubscribing(false);
+    }
+  };
+
+  const label = categoryLabels[category] || "these";
+
+  return (
+    <>
+      <div className="flex flex-col space-y-2 text-center">
+        <h1 className="text-2xl font-semibold tracking-tight">Unsubscribe</h1>
+        {isLoading ? (
+          <p className="text-sm text-muted-foreground">Checking your link...</p>
+        ) : error ? (
+          <p className="text-sm text-muted-foreground text-red-500">{error}</p>
+        ) : isUnsubscribed ? (
+          <p className="text-sm text-muted-foreground">
+            You will no longer receive {label} emails.
+          </p>
+        ) : (
+          <p className="text-sm text-muted-foreground">
+            Stop receiving {label} emails? Emails about the security of your
+            account will still be sent.
+          </p>
+        )}
+      </div>
+
+      {!isLoading && !error && !isUnsubscribed && (
+        <Button onClick={unsubscribe} disabled={isUnsubscribing}>
+          {isUnsubscribing ? "Unsubscribing..." : "Unsubscribe"}
+        </Button>
+      )}
+
+      {!isLoading && (
+        <p className="px-8 text-center text-sm text-muted-foreground">
+          <Link
+            href="/profile"
+            className="hover:text-brand underline underline-offset-4"
+          >
+            Go to your profile
+          </Link>
+        </p>
+      )}
+    </>
+  );
+}